@@ -1,6 +1,6 @@
 #!/bin/bash
 
-release=("badger"  "core"  "etcd"  "go-redis"  "nats"  "nuts"  "olric"  "otter"  "redis"  "simplefs")
+release=("badger"  "bolt"  "core"  "etcd"  "fs"  "go-redis"  "memcached"  "nats"  "nuts"  "olric"  "otter"  "peers"  "postgres"  "redis"  "remote"  "rest"  "ristretto"  "s3"  "shardedmap"  "simplefs"  "sqlite")
 
 IFS= read -r -d '' tpl <<EOF
 name: Tag submodules on release
@@ -49,4 +49,30 @@ for i in ${!release[@]}; do
 EOF
   workflow+="$tpl"
 done
+
+IFS= read -d '' tpl <<EOF
+      -
+        name: Create Core metrics tag
+        uses: actions/github-script@v7
+        with:
+          script: |
+            github.rest.git.createRef({
+              owner: context.repo.owner,
+              repo: context.repo.repo,
+              ref: 'refs/tags/core/metrics/\${{ github.ref_name }}',
+              sha: context.sha
+            })
+      -
+        name: Create Benchmarks tag
+        uses: actions/github-script@v7
+        with:
+          script: |
+            github.rest.git.createRef({
+              owner: context.repo.owner,
+              repo: context.repo.repo,
+              ref: 'refs/tags/benchmarks/\${{ github.ref_name }}',
+              sha: context.sha
+            })
+EOF
+workflow+="$tpl"
 echo "${workflow%$'\n'}" >  "$( dirname -- "$0"; )/release.yml"
\ No newline at end of file
@@ -3,11 +3,16 @@
 package badger
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
@@ -16,50 +21,184 @@ import (
 	"dario.cat/mergo"
 	"github.com/darkweak/storages/core"
 	"github.com/dgraph-io/badger/v4"
-	"github.com/pierrec/lz4/v4"
-	"go.uber.org/zap"
+	"github.com/dgraph-io/badger/v4/options"
+	"github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"google.golang.org/protobuf/proto"
 )
 
 // Badger provider type.
 type Badger struct {
 	*badger.DB
 
-	stale  time.Duration
-	logger core.Logger
+	stale          time.Duration
+	uuid           string
+	instanceKey    string
+	logger         core.Logger
+	compressor     core.Compressor
+	gcInterval     time.Duration
+	gcDiscardRatio float64
+	limiter        *core.BackgroundLimiter
+	gcStop         chan struct{}
+	gcDone         sync.WaitGroup
+	hits           core.HitCounter
+	evictionScan   time.Duration
+	evictions      core.EvictionCallbacks
+	evictionMu     sync.Mutex
+	evictionStop   chan struct{}
+	evictionDone   sync.WaitGroup
 }
 
+const (
+	defaultGCInterval     = 5 * time.Minute
+	defaultGCDiscardRatio = 0.5
+	// defaultIndexCacheSize is required by badger once the encryption is
+	// enabled.
+	defaultIndexCacheSize = 64 << 20
+)
+
 var (
 	enabledBadgerInstances               = sync.Map{}
 	_                      badger.Logger = (*badgerLogger)(nil)
 )
 
+// badgerLogger forwards the badger logs to the storer logger.
 type badgerLogger struct {
-	*zap.SugaredLogger
+	core.Logger
 }
 
 func (b *badgerLogger) Warningf(msg string, params ...interface{}) {
 	b.Warnf(msg, params...)
 }
 
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("badger", Factory)
+}
+
+// configuration is the typed Badger provider configuration, the other keys
+// are forwarded to the badger.Options.
+type configuration struct {
+	InMemory      bool `json:"in_memory"`
+	NumCompactors int  `json:"num_compactors"`
+	// Compression is one of none, snappy or zstd.
+	Compression string `json:"compression"`
+	// EncryptionKey is the 16, 24 or 32 bytes AES key encrypting the files.
+	EncryptionKey string `json:"encryption_key"`
+	// GCInterval is the delay between two value log garbage collections,
+	// zero disables them.
+	GCInterval time.Duration `json:"gc_interval"`
+	// GCDiscardRatio is the ratio of discardable data above which a value
+	// log file is rewritten.
+	GCDiscardRatio float64 `json:"gc_discard_ratio"`
+	// EvictionScanInterval is the delay between two scans detecting the
+	// expired entries for the OnEvicted callbacks.
+	EvictionScanInterval time.Duration          `json:"eviction_scan_interval"`
+	Options              map[string]interface{} `json:",remain"`
+}
+
+func parseCompression(compression string) (options.CompressionType, error) {
+	switch strings.ToLower(compression) {
+	case "", "none", "0":
+		return options.None, nil
+	case "snappy", "1":
+		return options.Snappy, nil
+	case "zstd", "2":
+		return options.ZSTD, nil
+	}
+
+	return options.None, fmt.Errorf("invalid badger configuration: unknown compression %s, expected none, snappy or zstd", compression)
+}
+
+func parseConfiguration(badgerConfiguration any) (configuration, badger.Options, error) {
+	cfg := configuration{GCInterval: defaultGCInterval, GCDiscardRatio: defaultGCDiscardRatio, EvictionScanInterval: core.DefaultEvictionScanInterval}
+
+	var parsedBadger badger.Options
+
+	if err := core.DecodeConfiguration(badgerConfiguration, &cfg); err != nil {
+		return cfg, parsedBadger, fmt.Errorf("invalid badger configuration: %w", err)
+	}
+
+	if cfg.GCInterval < 0 {
+		return cfg, parsedBadger, fmt.Errorf("invalid badger configuration: the gc_interval must be positive, %s given", cfg.GCInterval)
+	}
+
+	if cfg.GCDiscardRatio <= 0 || cfg.GCDiscardRatio >= 1 {
+		return cfg, parsedBadger, fmt.Errorf("invalid badger configuration: the gc_discard_ratio must be between 0 and 1, %v given", cfg.GCDiscardRatio)
+	}
+
+	if cfg.EvictionScanInterval <= 0 {
+		return cfg, parsedBadger, fmt.Errorf("invalid badger configuration: the eviction_scan_interval must be positive, %s given", cfg.EvictionScanInterval)
+	}
+
+	if cfg.NumCompactors < 0 {
+		return cfg, parsedBadger, fmt.Errorf("invalid badger configuration: the num_compactors must be positive, %d given", cfg.NumCompactors)
+	}
+
+	if size := len(cfg.EncryptionKey); size != 0 && size != 16 && size != 24 && size != 32 {
+		return cfg, parsedBadger, fmt.Errorf("invalid badger configuration: the encryption_key must be 16, 24 or 32 bytes long, %d given", size)
+	}
+
+	if _, err := parseCompression(cfg.Compression); err != nil {
+		return cfg, parsedBadger, err
+	}
+
+	b, err := json.Marshal(cfg.Options)
+	if err == nil {
+		err = json.Unmarshal(b, &parsedBadger)
+	}
+
+	if err != nil {
+		return cfg, parsedBadger, fmt.Errorf("invalid badger configuration: %w", err)
+	}
+
+	return cfg, parsedBadger, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(badgerConfiguration any) error {
+	_, _, err := parseConfiguration(badgerConfiguration)
+
+	return err
+}
+
 // Factory function create new Badger instance.
 func Factory(badgerConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
 	badgerOptions := badger.DefaultOptions(badgerConfiguration.Path)
 	badgerOptions.SyncWrites = true
 	badgerOptions.MemTableSize = 64 << 22
 
-	if badgerConfiguration.Configuration != nil {
-		var parsedBadger badger.Options
+	cfg, parsedBadger, err := parseConfiguration(badgerConfiguration.Configuration)
+	if err != nil {
+		logger.Error("Impossible to parse the configuration for the default provider (Badger)", err)
 
-		if b, e := json.Marshal(badgerConfiguration.Configuration); e == nil {
-			if e = json.Unmarshal(b, &parsedBadger); e != nil {
-				logger.Error("Impossible to parse the configuration for the default provider (Badger)", e)
-			}
-		}
+		return nil, err
+	}
 
+	if badgerConfiguration.Configuration != nil {
 		if err := mergo.Merge(&badgerOptions, parsedBadger, mergo.WithOverride); err != nil {
 			logger.Error("An error occurred during the badgerOptions merge from the default options with your configuration.")
 		}
 
+		if cfg.InMemory {
+			badgerOptions = badgerOptions.WithDir("").WithValueDir("").WithInMemory(true)
+		}
+
+		if cfg.NumCompactors > 0 {
+			badgerOptions.NumCompactors = cfg.NumCompactors
+		}
+
+		if cfg.Compression != "" {
+			badgerOptions.Compression, _ = parseCompression(cfg.Compression)
+		}
+
+		if cfg.EncryptionKey != "" {
+			badgerOptions.EncryptionKey = []byte(cfg.EncryptionKey)
+			if badgerOptions.IndexCacheSize == 0 {
+				badgerOptions.IndexCacheSize = defaultIndexCacheSize
+			}
+		}
+
 		if !badgerOptions.InMemory {
 			if badgerOptions.Dir == "" {
 				badgerOptions.Dir = "souin_dir"
@@ -73,11 +212,18 @@ func Factory(badgerConfiguration core.CacheProvider, logger core.Logger, stale t
 		badgerOptions = badgerOptions.WithInMemory(true)
 	}
 
-	zapLogger, ok := logger.(*zap.SugaredLogger)
-	if ok {
-		badgerOptions.Logger = &badgerLogger{SugaredLogger: zapLogger}
+	compressor, err := core.CompressorFromConfiguration(badgerConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(badgerConfiguration.Configuration)
+	if err != nil {
+		return nil, err
 	}
 
+	badgerOptions.Logger = &badgerLogger{Logger: logger}
+
 	uid := badgerOptions.Dir + badgerOptions.ValueDir + stale.String()
 
 	if instance, ok := enabledBadgerInstances.Load(uid); ok {
@@ -89,7 +235,18 @@ func Factory(badgerConfiguration core.CacheProvider, logger core.Logger, stale t
 		logger.Error("Impossible to open the Badger DB.", e)
 	}
 
-	i := &Badger{DB: db, logger: logger, stale: stale}
+	i := &Badger{
+		DB:             db,
+		logger:         logger,
+		stale:          stale,
+		uuid:           core.InstanceID("badger", badgerConfiguration, stale),
+		instanceKey:    uid,
+		compressor:     compressor,
+		gcInterval:     cfg.GCInterval,
+		gcDiscardRatio: cfg.GCDiscardRatio,
+		limiter:        limiter,
+		evictionScan:   cfg.EvictionScanInterval,
+	}
 	enabledBadgerInstances.Store(uid, i)
 
 	return i, nil
@@ -102,12 +259,7 @@ func (provider *Badger) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Badger) Uuid() string {
-	return fmt.Sprintf(
-		"%s-%s-%s",
-		provider.DB.Opts().Dir,
-		provider.DB.Opts().ValueDir,
-		provider.stale,
-	)
+	return provider.uuid
 }
 
 // MapKeys method returns a map with the key and value.
@@ -134,31 +286,152 @@ func (provider *Badger) MapKeys(prefix string) map[string]string {
 	return keys
 }
 
-// ListKeys method returns the list of existing keys.
-func (provider *Badger) ListKeys() []string {
-	keys := []string{}
+// WalkEntries streams every entry with its remaining TTL, zero when the
+// entry never expires.
+func (provider *Badger) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	return provider.View(func(txn *badger.Txn) error {
+		iterator := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
 
-	err := provider.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		opts.PrefetchValues = false
-		it := txn.NewIterator(opts)
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
 
-		defer it.Close()
+			var ttl time.Duration
+			if expiresAt := item.ExpiresAt(); expiresAt != 0 {
+				if ttl = time.Until(time.Unix(int64(expiresAt), 0)); ttl <= 0 {
+					continue
+				}
+			}
 
-		for it.Seek([]byte(core.MappingKeyPrefix)); it.ValidForPrefix([]byte(core.MappingKeyPrefix)); it.Next() {
-			val, _ := it.Item().ValueCopy(nil)
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
 
-			mapping, err := core.DecodeMapping(val)
-			if err == nil {
-				for _, v := range mapping.GetMapping() {
-					keys = append(keys, v.GetRealKey())
-				}
+			if !walkFn(string(item.Key()), value, ttl) {
+				return nil
 			}
 		}
 
 		return nil
 	})
-	if err != nil {
+}
+
+// Snapshot writes a full backup of the database to w with the native badger
+// backup format, the expired and deleted entries are skipped.
+func (provider *Badger) Snapshot(w io.Writer) error {
+	if _, err := provider.Backup(w, 0); err != nil {
+		provider.logger.Errorf("Impossible to snapshot the Badger database, %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Restore stores the entries of a Snapshot with their remaining TTL. Unlike
+// the badger Load, the entries are written as new versions so they're never
+// shadowed by the deletions made since the snapshot. The current entries
+// are kept, overwritten by the restored ones.
+func (provider *Badger) Restore(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	batch := provider.NewWriteBatch()
+
+	defer batch.Cancel()
+
+	for {
+		var size uint64
+
+		err := binary.Read(reader, binary.LittleEndian, &size)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid badger snapshot: %w", err)
+		}
+
+		buf := make([]byte, size)
+		if _, err = io.ReadFull(reader, buf); err != nil {
+			return fmt.Errorf("invalid badger snapshot: %w", err)
+		}
+
+		list := &pb.KVList{}
+		if err = proto.Unmarshal(buf, list); err != nil {
+			return fmt.Errorf("invalid badger snapshot: %w", err)
+		}
+
+		for _, kv := range list.GetKv() {
+			entry := badger.NewEntry(kv.GetKey(), kv.GetValue())
+
+			if expiresAt := kv.GetExpiresAt(); expiresAt != 0 {
+				ttl := time.Until(time.Unix(int64(expiresAt), 0))
+				if ttl <= 0 {
+					continue
+				}
+
+				entry = entry.WithTTL(ttl)
+			}
+
+			if userMeta := kv.GetUserMeta(); len(userMeta) > 0 {
+				entry = entry.WithMeta(userMeta[0])
+			}
+
+			if err = batch.SetEntry(entry); err != nil {
+				provider.logger.Errorf("Impossible to restore the Badger database, %v", err)
+
+				return err
+			}
+		}
+	}
+
+	return batch.Flush()
+}
+
+// ListKeys method returns the list of existing keys. The mappings are
+// decoded concurrently by the badger Stream framework.
+func (provider *Badger) ListKeys() []string {
+	keys := []string{}
+
+	stream := provider.NewStream()
+	stream.Prefix = []byte(core.MappingKeyPrefix)
+	stream.LogPrefix = "Badger.ListKeys"
+	stream.ChooseKey = func(item *badger.Item) bool {
+		return !item.IsDeletedOrExpired()
+	}
+	stream.KeyToList = func(_ []byte, itr *badger.Iterator) (*pb.KVList, error) {
+		val, err := itr.Item().ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		list := &pb.KVList{}
+
+		mapping, err := core.DecodeMapping(val)
+		if err == nil {
+			for _, v := range mapping.GetMapping() {
+				list.Kv = append(list.Kv, &pb.KV{Key: []byte(v.GetRealKey())})
+			}
+		}
+
+		return list, nil
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		list, err := badger.BufferToKVList(buf)
+		if err != nil {
+			return err
+		}
+
+		for _, kv := range list.GetKv() {
+			keys = append(keys, string(kv.GetKey()))
+		}
+
+		return nil
+	}
+
+	if err := stream.Orchestrate(context.Background()); err != nil {
+		provider.logger.Errorf("Impossible to list the keys from Badger, %v", err)
+
 		return []string{}
 	}
 
@@ -167,26 +440,69 @@ func (provider *Badger) ListKeys() []string {
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Badger) Get(key string) []byte {
-	var item *badger.Item
+	result, _ := provider.Lookup(key)
 
+	return result
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Badger) Lookup(key string) ([]byte, error) {
 	var result []byte
 
 	err := provider.View(func(txn *badger.Txn) error {
-		i, err := txn.Get([]byte(key))
-		item = i
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		result, err = item.ValueCopy(nil)
 
 		return err
 	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		provider.hits.Record(false)
+
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider.hits.Record(true)
+
+	return result, nil
+}
+
+// GetTTL returns the remaining lifetime of the key from its item expiration,
+// with a second granularity.
+func (provider *Badger) GetTTL(key string) (time.Duration, error) {
+	var expiresAt time.Time
 
+	err := provider.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		if item.ExpiresAt() > 0 {
+			expiresAt = time.Unix(int64(item.ExpiresAt()), 0)
+		}
+
+		return nil
+	})
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return result
+		return 0, core.ErrKeyNotFound
 	}
 
-	if item != nil {
-		result, _ = item.ValueCopy(nil)
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the TTL of the key %s in Badger, %v", key, err)
+
+		return 0, err
 	}
 
-	return result
+	return core.RemainingTTL(expiresAt, time.Now())
 }
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
@@ -201,9 +517,11 @@ func (provider *Badger) GetMultiLevel(key string, req *http.Request, validator *
 
 		if result != nil {
 			val, _ = result.ValueCopy(nil)
+		} else {
+			provider.hits.Record(false)
 		}
 
-		fresh, stale, err = core.MappingElection(provider, val, req, validator, provider.logger)
+		fresh, stale, err = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
 
 		return err
 	})
@@ -215,64 +533,69 @@ func (provider *Badger) GetMultiLevel(key string, req *http.Request, validator *
 func (provider *Badger) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
 		provider.logger.Errorf("Impossible to compress the key %s into Badger, %v", variedKey, err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Badger, %v", variedKey, err)
+	var evicted []string
 
-		return err
-	}
+	// The concurrent updates of the mapping conflict on commit, the whole
+	// transaction is retried.
+	err = core.UpdateMapping(func() error {
+		err := provider.Update(func(btx *badger.Txn) error {
+			var err error
 
-	err := provider.Update(func(btx *badger.Txn) error {
-		var err error
+			err = btx.SetEntry(badger.NewEntry([]byte(variedKey), compressed).WithTTL(duration + provider.stale))
+			if err != nil {
+				provider.logger.Errorf("Impossible to set the key %s into Badger, %v", variedKey, err)
 
-		err = btx.SetEntry(badger.NewEntry([]byte(variedKey), compressed.Bytes()).WithTTL(duration + provider.stale))
-		if err != nil {
-			provider.logger.Errorf("Impossible to set the key %s into Badger, %v", variedKey, err)
+				return err
+			}
 
-			return err
-		}
+			mappingKey := core.MappingKeyPrefix + baseKey
+			item, err := btx.Get([]byte(mappingKey))
 
-		mappingKey := core.MappingKeyPrefix + baseKey
-		item, err := btx.Get([]byte(mappingKey))
+			if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				provider.logger.Errorf("Impossible to get the base key %s in Badger, %v", mappingKey, err)
 
-		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
-			provider.logger.Errorf("Impossible to get the base key %s in Badger, %v", mappingKey, err)
+				return err
+			}
 
-			return err
-		}
+			var val []byte
 
-		var val []byte
+			if item != nil {
+				val, _ = item.ValueCopy(nil)
+			}
 
-		if item != nil {
-			val, _ = item.ValueCopy(nil)
-		}
+			val, evicted, err = core.MappingUpdaterWithEvictions(variedKey, val, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+			if err != nil {
+				provider.logger.Errorf("Impossible to update the mapping for the key %s in Badger, %v", variedKey, err)
 
-		val, err = core.MappingUpdater(variedKey, val, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
-		if err != nil {
-			provider.logger.Errorf("Impossible to update the mapping for the key %s in Badger, %v", variedKey, err)
+				return err
+			}
 
-			return err
-		}
+			provider.logger.Debugf("Store the new mapping for the key %s in Badger", variedKey)
 
-		provider.logger.Debugf("Store the new mapping for the key %s in Badger", variedKey)
+			return btx.SetEntry(badger.NewEntry([]byte(mappingKey), val))
+		})
+		if errors.Is(err, badger.ErrConflict) {
+			return core.ErrMappingConflict
+		}
 
-		return btx.SetEntry(badger.NewEntry([]byte(mappingKey), val))
+		return err
 	})
 	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Badger, %v", err)
+
+		return err
 	}
 
-	return err
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
 }
 
 // Set method will store the response in Badger provider.
@@ -287,6 +610,113 @@ func (provider *Badger) Set(key string, value []byte, duration time.Duration) er
 	return err
 }
 
+// SetNX stores the value in a transaction only if the key doesn't exist.
+func (provider *Badger) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	return provider.setIf(key, value, ttl, func(item *badger.Item) (bool, error) {
+		return item == nil, nil
+	})
+}
+
+// CompareAndSwap replaces the value in a transaction only if the stored one
+// equals old.
+func (provider *Badger) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return provider.setIf(key, value, ttl, func(item *badger.Item) (bool, error) {
+		if item == nil {
+			return false, nil
+		}
+
+		current, err := item.ValueCopy(nil)
+
+		return bytes.Equal(current, old), err
+	})
+}
+
+// setIf stores the value only if the condition holds on the stored item, nil
+// when the key doesn't exist. A transaction conflicting with a concurrent
+// write means the condition no longer holds.
+func (provider *Badger) setIf(key string, value []byte, ttl time.Duration, condition func(item *badger.Item) (bool, error)) (bool, error) {
+	stored := false
+
+	err := provider.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			item, err = nil, nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if stored, err = condition(item); err != nil || !stored {
+			return err
+		}
+
+		entry := badger.NewEntry([]byte(key), value)
+		if ttl > 0 {
+			entry = entry.WithTTL(ttl)
+		}
+
+		return txn.SetEntry(entry)
+	})
+	if errors.Is(err, badger.ErrConflict) {
+		return false, nil
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to conditionally set the key %s into Badger, %v", key, err)
+
+		return false, err
+	}
+
+	return stored, nil
+}
+
+// Transact runs fn in a Badger transaction, retried when it conflicts with
+// a concurrent write.
+func (provider *Badger) Transact(fn func(tx core.Tx) error) error {
+	return core.RetryTransaction(func() error {
+		err := provider.Update(func(txn *badger.Txn) error {
+			return fn(badgerTx{txn})
+		})
+		if errors.Is(err, badger.ErrConflict) {
+			return core.ErrTransactionConflict
+		}
+
+		return err
+	})
+}
+
+// badgerTx is the core.Tx of a Badger transaction.
+type badgerTx struct {
+	txn *badger.Txn
+}
+
+func (b badgerTx) Get(key string) ([]byte, error) {
+	item, err := b.txn.Get([]byte(key))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return item.ValueCopy(nil)
+}
+
+func (b badgerTx) Set(key string, value []byte, ttl time.Duration) error {
+	entry := badger.NewEntry([]byte(key), value)
+	if ttl > 0 {
+		entry = entry.WithTTL(ttl)
+	}
+
+	return b.txn.SetEntry(entry)
+}
+
+func (b badgerTx) Delete(key string) error {
+	return b.txn.Delete([]byte(key))
+}
+
 // Delete method will delete the response in Badger provider if exists corresponding to key param.
 func (provider *Badger) Delete(key string) {
 	_ = provider.Update(func(txn *badger.Txn) error {
@@ -296,43 +726,307 @@ func (provider *Badger) Delete(key string) {
 
 // DeleteMany method will delete the responses in Badger provider if exists corresponding to the regex key param.
 func (provider *Badger) DeleteMany(key string) {
-	rgKey, e := regexp.Compile(key)
-	if e != nil {
-		return
+	_, _ = provider.DeleteManyCount(key, false)
+}
+
+// DeleteManyCount deletes the keys matching the regular expression, only the
+// keys starting with its literal prefix are iterated and their values are
+// never read. The deletions are written by a WriteBatch, committed each time
+// a transaction grows too big, so purging many keys never fails with
+// badger.ErrTxnTooBig. In dry-run the matching keys are listed without being
+// deleted.
+func (provider *Badger) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result := core.DeleteManyResult{}
+
+	rgKey, err := regexp.Compile(pattern)
+	if err != nil {
+		return result, err
 	}
 
-	_ = provider.View(func(txn *badger.Txn) error {
+	batch := provider.NewWriteBatch()
+	defer batch.Cancel()
+
+	err = provider.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = false
+		opts.Prefix = []byte(core.PatternPrefix(pattern))
 		it := txn.NewIterator(opts)
 
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			k := string(it.Item().Key())
-			if rgKey.MatchString(k) {
-				provider.Delete(k)
+			if !rgKey.MatchString(k) {
+				continue
 			}
+
+			if !dryRun {
+				if err := batch.Delete(it.Item().KeyCopy(nil)); err != nil {
+					return err
+				}
+			}
+
+			result.Add(k, dryRun)
 		}
 
 		return nil
 	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to delete the keys %s from Badger, %v", pattern, err)
+
+		return result, err
+	}
+
+	if err = batch.Flush(); err != nil {
+		provider.logger.Errorf("Impossible to delete the keys %s from Badger, %v", pattern, err)
+	}
+
+	return result, err
+}
+
+// Stats returns the LSM tree and value log sizes with the count of the live
+// keys, the values aren't read to count them.
+func (provider *Badger) Stats() core.StorerStats {
+	stats := provider.hits.Stats()
+
+	lsm, vlog := provider.Size()
+	stats.Bytes = lsm + vlog
+
+	_ = provider.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			stats.Entries++
+		}
+
+		return nil
+	})
+
+	return stats
+}
+
+// Healthy checks the database is open and its directories are still
+// available on the disk.
+func (provider *Badger) Healthy(_ context.Context) error {
+	if provider.IsClosed() {
+		return errors.New("the Badger database is closed")
+	}
+
+	opts := provider.Opts()
+	if opts.InMemory {
+		return nil
+	}
+
+	for _, dir := range []string{opts.Dir, opts.ValueDir} {
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("the Badger directory is unavailable, %w", err)
+		}
+	}
+
+	return nil
 }
 
 // Init method will.
 func (provider *Badger) Init() error {
+	if provider.gcStop != nil || provider.gcInterval == 0 || provider.DB == nil || provider.Opts().InMemory {
+		return nil
+	}
+
+	provider.gcStop = make(chan struct{})
+	provider.gcDone.Add(1)
+
+	go func(stop chan struct{}) {
+		defer provider.gcDone.Done()
+
+		ticker := time.NewTicker(provider.gcInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				provider.runValueLogGC(stop)
+			}
+		}
+	}(provider.gcStop)
+
 	return nil
 }
 
+// runValueLogGC rewrites the value log files while enough space is
+// reclaimed, badger only processes one file per call. Each file waits for
+// the background limiter.
+func (provider *Badger) runValueLogGC(stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if !provider.limiter.Wait(stop, "value_log_gc") {
+			return
+		}
+
+		err := provider.RunValueLogGC(provider.gcDiscardRatio)
+		if err == nil {
+			continue
+		}
+
+		if !errors.Is(err, badger.ErrNoRewrite) && !errors.Is(err, badger.ErrRejected) {
+			provider.logger.Errorf("Impossible to run the value log GC in Badger, %v", err)
+		}
+
+		return
+	}
+}
+
 // Reset method will reset or close provider.
 func (provider *Badger) Reset() error {
+	provider.evictionMu.Lock()
+	if provider.evictionStop != nil {
+		close(provider.evictionStop)
+		provider.evictionStop = nil
+		provider.evictionDone.Wait()
+	}
+	provider.evictionMu.Unlock()
+
+	if provider.gcStop != nil {
+		close(provider.gcStop)
+		provider.gcStop = nil
+		// Never close the DB while a value log GC is running.
+		provider.gcDone.Wait()
+	}
+
 	var err error
 	// Close the DB connection
 	if provider.DB != nil {
 		err = provider.Close()
 	}
 	// Only delete this instance from the cache
-	enabledBadgerInstances.Delete(provider.Uuid())
+	enabledBadgerInstances.Delete(provider.instanceKey)
 
 	return err
 }
+
+// Watch streams the changes of the keys starting with the prefix with the
+// Badger Subscribe, the set values are sent as stored. Badger doesn't notify
+// the expirations and the subscription starts asynchronously, the changes
+// made right after Watch returns may be missed.
+func (provider *Badger) Watch(ctx context.Context, prefix string) (<-chan core.Event, error) {
+	events := make(chan core.Event, core.EventsBufferSize)
+
+	go func() {
+		defer close(events)
+
+		err := provider.DB.Subscribe(ctx, func(kvs *badger.KVList) error {
+			for _, kv := range kvs.GetKv() {
+				event := core.Event{Kind: core.EventSet, Key: string(kv.GetKey()), Value: kv.GetValue()}
+				if len(event.Value) == 0 {
+					event.Kind = core.EventDelete
+				}
+
+				if !core.SendEvent(ctx, events, event) {
+					return ctx.Err()
+				}
+			}
+
+			return nil
+		}, []pb.Match{{Prefix: []byte(prefix)}})
+		if err != nil && ctx.Err() == nil {
+			provider.logger.Errorf("Impossible to watch the Badger keys %s, %v", prefix, err)
+		}
+	}()
+
+	return events, nil
+}
+
+// OnEvicted registers fn for the expired entries, they're detected by a scan
+// every eviction_scan_interval started with the first callback.
+// The values are the stored ones, the entries discarded by a compaction
+// before the scan are missed.
+func (provider *Badger) OnEvicted(fn func(key string, value []byte)) {
+	provider.evictions.Add(fn)
+
+	provider.evictionMu.Lock()
+	defer provider.evictionMu.Unlock()
+
+	if provider.evictionStop != nil {
+		return
+	}
+
+	provider.evictionStop = make(chan struct{})
+	provider.evictionDone.Add(1)
+
+	go func(stop chan struct{}) {
+		defer provider.evictionDone.Done()
+
+		ticker := time.NewTicker(provider.evictionScan)
+		defer ticker.Stop()
+
+		since := uint64(time.Now().Unix())
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(stop, "eviction_scan") {
+					return
+				}
+
+				since = provider.notifyExpired(since)
+			}
+		}
+	}(provider.evictionStop)
+}
+
+// notifyExpired notifies the entries expired since the given unix time, it
+// returns the time of the scan.
+func (provider *Badger) notifyExpired(since uint64) uint64 {
+	now := uint64(time.Now().Unix())
+
+	err := provider.View(func(txn *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.AllVersions = true
+
+		iterator := txn.NewIterator(options)
+		defer iterator.Close()
+
+		var previous []byte
+
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+
+			// The versions of a key are sorted from the latest.
+			if bytes.Equal(item.Key(), previous) {
+				continue
+			}
+
+			previous = item.KeyCopy(previous[:0])
+
+			if expiresAt := item.ExpiresAt(); expiresAt <= since || expiresAt > now || !item.IsDeletedOrExpired() {
+				continue
+			}
+
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			provider.evictions.Notify(string(item.Key()), value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to scan the expired Badger entries, %v", err)
+	}
+
+	return now
+}
@@ -4,12 +4,17 @@ package badger_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/badger"
 	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
 	"github.com/pierrec/lz4/v4"
 	"go.uber.org/zap"
 )
@@ -182,3 +187,393 @@ func TestBadger_SetMultiLevel_LargeValue(t *testing.T) {
 			len(largeValue), len(retrieved), float64(len(retrieved))/float64(len(largeValue))*100)
 	}
 }
+
+func TestBadger_TuningOptions(t *testing.T) {
+	client, err := badger.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"Dir":              t.TempDir(),
+			"compression":      "zstd",
+			"num_compactors":   "2",
+			"encryption_key":   "0123456789abcdef",
+			"gc_interval":      "10ms",
+			"gc_discard_ratio": 0.7,
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create badger instance: %v", err)
+	}
+
+	_ = client.Init()
+
+	opts := client.(*badger.Badger).Opts()
+	if opts.NumCompactors != 2 || len(opts.EncryptionKey) != 16 {
+		t.Errorf("The tuning options should be applied, %d compactors and %d bytes key given", opts.NumCompactors, len(opts.EncryptionKey))
+	}
+
+	if err = client.Set(byteKey, []byte(baseValue), time.Minute); err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	// Let the GC loop tick a few times before stopping it.
+	time.Sleep(50 * time.Millisecond)
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("%s not corresponding to %s", client.Get(byteKey), baseValue)
+	}
+
+	if err = client.Reset(); err != nil {
+		t.Errorf("Impossible to reset Badger, %v", err)
+	}
+}
+
+func TestBadger_Validate(t *testing.T) {
+	if err := badger.Validate(map[string]interface{}{"compression": "lz4"}); err == nil {
+		t.Error("An unknown compression should be invalid")
+	}
+
+	if err := badger.Validate(map[string]interface{}{"gc_discard_ratio": 1.5}); err == nil {
+		t.Error("A gc_discard_ratio above 1 should be invalid")
+	}
+
+	if err := badger.Validate(map[string]interface{}{"encryption_key": "short"}); err == nil {
+		t.Error("A short encryption_key should be invalid")
+	}
+}
+
+func TestBadger_Healthy(t *testing.T) {
+	client, _ := getBadgerInstance()
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The opened database should be healthy, %v given", err)
+	}
+}
+
+func TestBadger_GetTTL(t *testing.T) {
+	client, _ := getBadgerInstance()
+	_ = client.Set("ttl", []byte(baseValue), time.Minute)
+
+	if ttl, err := core.GetTTL(client, "ttl"); err != nil || ttl <= 58*time.Second || ttl > time.Minute {
+		t.Errorf("The remaining lifetime should be returned, %s and %v given", ttl, err)
+	}
+
+	if _, err := core.GetTTL(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	client.Delete("ttl")
+}
+
+func TestBadger_Lookup(t *testing.T) {
+	client, _ := getBadgerInstance()
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestBadger_ConditionalSet(t *testing.T) {
+	client, _ := getBadgerInstance()
+	setter, ok := client.(core.ConditionalSetter)
+	if !ok {
+		t.Fatal("Badger should implement core.ConditionalSetter")
+	}
+
+	client.Delete("leader")
+
+	if stored, err := setter.SetNX("leader", []byte("first"), 5*time.Second); err != nil || !stored {
+		t.Fatalf("The absent key should be stored, %v", err)
+	}
+
+	if stored, _ := setter.SetNX("leader", []byte("second"), 5*time.Second); stored {
+		t.Error("The existing key shouldn't be replaced")
+	}
+
+	if swapped, _ := setter.CompareAndSwap("leader", []byte("second"), []byte("third"), 5*time.Second); swapped {
+		t.Error("The value shouldn't be swapped when the old one differs")
+	}
+
+	if swapped, err := setter.CompareAndSwap("leader", []byte("first"), []byte("third"), 5*time.Second); err != nil || !swapped {
+		t.Errorf("The value should be swapped, %v", err)
+	}
+
+	if value := client.Get("leader"); string(value) != "third" {
+		t.Errorf("The swapped value should be stored, %s given", value)
+	}
+
+	client.Delete("leader")
+}
+
+func TestBadger_Transact(t *testing.T) {
+	client, _ := getBadgerInstance()
+	client.Delete("tx-value")
+	client.Delete("tx-mapping")
+
+	err := core.Transact(client, func(tx core.Tx) error {
+		if _, err := tx.Get("tx-value"); !errors.Is(err, core.ErrKeyNotFound) {
+			t.Errorf("The absent key should be reported, %v given", err)
+		}
+
+		if err := tx.Set("tx-value", []byte("body"), time.Minute); err != nil {
+			return err
+		}
+
+		return tx.Set("tx-mapping", []byte("index"), 0)
+	})
+	if err != nil || string(client.Get("tx-value")) != "body" || string(client.Get("tx-mapping")) != "index" {
+		t.Fatalf("Both keys should be written, %v", err)
+	}
+
+	failure := errors.New("rollback")
+
+	err = core.Transact(client, func(tx core.Tx) error {
+		_ = tx.Delete("tx-value")
+		_ = tx.Set("tx-mapping", []byte("updated"), 0)
+
+		return failure
+	})
+	if !errors.Is(err, failure) || client.Get("tx-value") == nil || string(client.Get("tx-mapping")) != "index" {
+		t.Errorf("The writes should be discarded on error, %v", err)
+	}
+
+	client.Delete("tx-value")
+	client.Delete("tx-mapping")
+}
+
+func TestBadger_Increment(t *testing.T) {
+	client, _ := getBadgerInstance()
+	client.Delete("hits")
+
+	if value, err := core.Increment(client, "hits", 2, time.Minute); err != nil || value != 2 {
+		t.Fatalf("The missing counter should start at zero, %d given, %v", value, err)
+	}
+
+	if value, err := core.Increment(client, "hits", -3, time.Minute); err != nil || value != -1 {
+		t.Errorf("The counter should be decremented through the compare and swap, %d given, %v", value, err)
+	}
+
+	client.Delete("hits")
+}
+
+func TestBadger_Stats(t *testing.T) {
+	client, _ := getBadgerInstance()
+	before, _ := core.Stats(client)
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Get(byteKey)
+	_ = client.Get(nonExistentKey)
+	_ = client.Get(nonExistentKey)
+
+	stats, found := core.Stats(client)
+	if !found {
+		t.Fatal("The badger storer should report its stats")
+	}
+
+	if stats.Entries < 1 || stats.Hits-before.Hits != 1 || stats.Misses-before.Misses != 2 {
+		t.Errorf("Unexpected stats, %+v given", stats)
+	}
+}
+
+func TestBadger_DumpWarmup(t *testing.T) {
+	client, _ := getBadgerInstance()
+	_ = client.Set("WARMUP_KEY", []byte(baseValue), time.Minute)
+
+	var dump bytes.Buffer
+
+	if _, err := core.Dump(client, &dump); err != nil {
+		t.Fatalf("Impossible to dump the entries, %v", err)
+	}
+
+	if !bytes.Contains(dump.Bytes(), []byte(`"key":"WARMUP_KEY"`)) {
+		t.Fatalf("The plain key should be dumped, %s given", dump.String())
+	}
+
+	client.Delete("WARMUP_KEY")
+
+	if _, err := core.Warmup(client, &dump); err != nil {
+		t.Fatalf("Impossible to warm up the entries, %v", err)
+	}
+
+	if string(client.Get("WARMUP_KEY")) != baseValue {
+		t.Errorf("The dumped key should be restored, %s given", client.Get("WARMUP_KEY"))
+	}
+}
+
+func TestBadger_DeleteManyCount(t *testing.T) {
+	client, _ := getBadgerInstance()
+	_ = client.Set("PURGE_first", []byte(baseValue), time.Minute)
+	_ = client.Set("PURGE_second", []byte(baseValue), time.Minute)
+	_ = client.Set("KEEP_PURGE_third", []byte(baseValue), time.Minute)
+
+	result, err := core.DeleteManyCount(client, "^PURGE_", true)
+	if err != nil || result.Count != 2 || len(result.Keys) != 2 {
+		t.Fatalf("The dry-run should list the two matching keys, %+v given, %v", result, err)
+	}
+
+	if len(client.Get("PURGE_first")) == 0 {
+		t.Error("The dry-run shouldn't delete the matching keys")
+	}
+
+	if result, _ = core.DeleteManyCount(client, "^PURGE_", false); result.Count != 2 || len(result.Keys) != 0 {
+		t.Errorf("The two matching keys should be deleted, %+v given", result)
+	}
+
+	if len(client.Get("PURGE_first")) != 0 || len(client.Get("KEEP_PURGE_third")) == 0 {
+		t.Error("Only the matching keys should be deleted")
+	}
+}
+
+func TestBadger_DeleteManyCount_Batched(t *testing.T) {
+	client, _ := getBadgerInstance()
+
+	for i := range 2000 {
+		_ = client.Set("BATCH_PURGE_"+strconv.Itoa(i), []byte(baseValue), time.Minute)
+	}
+
+	if result, err := core.DeleteManyCount(client, "^BATCH_PURGE_", false); err != nil || result.Count != 2000 {
+		t.Fatalf("The matching keys should be deleted, %+v given, %v", result, err)
+	}
+
+	if keys := client.MapKeys("BATCH_PURGE_"); len(keys) != 0 {
+		t.Errorf("No matching key should remain, %d given", len(keys))
+	}
+}
+
+func TestBadger_ListKeys(t *testing.T) {
+	client, _ := getBadgerInstance()
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+
+	for _, key := range []string{"LIST_first", "LIST_second"} {
+		_ = client.SetMultiLevel(key, key+"-varied", value, http.Header{}, "", time.Minute, key+"-real")
+	}
+
+	keys := client.ListKeys()
+	if !slices.Contains(keys, "LIST_first-real") || !slices.Contains(keys, "LIST_second-real") {
+		t.Errorf("The real keys should be listed, %v given", keys)
+	}
+}
+
+func TestBadger_SnapshotRestore(t *testing.T) {
+	client, _ := getBadgerInstance()
+	_ = client.Set("SNAPSHOT_KEY", []byte(baseValue), time.Minute)
+
+	var snapshot bytes.Buffer
+
+	if err := core.Snapshot(client, &snapshot); err != nil {
+		t.Fatalf("Impossible to snapshot the database, %v", err)
+	}
+
+	client.Delete("SNAPSHOT_KEY")
+
+	if err := core.Restore(client, &snapshot); err != nil {
+		t.Fatalf("Impossible to restore the database, %v", err)
+	}
+
+	if string(client.Get("SNAPSHOT_KEY")) != baseValue {
+		t.Errorf("The snapshot key should be restored, %s given", client.Get("SNAPSHOT_KEY"))
+	}
+}
+
+func TestBadger_MaxVariants(t *testing.T) {
+	core.SetMaxVariants(1)
+	defer core.SetMaxVariants(0)
+
+	client, _ := getBadgerInstance()
+
+	_ = client.SetMultiLevel("VARIANTS_BASE", "VARIANTS_FIRST", []byte(baseValue), http.Header{}, "", time.Minute, "VARIANTS_FIRST")
+	time.Sleep(time.Millisecond)
+	_ = client.SetMultiLevel("VARIANTS_BASE", "VARIANTS_SECOND", []byte(baseValue), http.Header{}, "", time.Minute, "VARIANTS_SECOND")
+
+	if len(client.Get("VARIANTS_FIRST")) != 0 {
+		t.Error("The value of the evicted variant should be deleted")
+	}
+
+	if len(client.Get("VARIANTS_SECOND")) == 0 {
+		t.Error("The value of the newest variant should be kept")
+	}
+}
+
+func TestBadger_Watch(t *testing.T) {
+	client, _ := getBadgerInstance()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.(core.Watcher).Watch(ctx, "WATCHED_")
+	if err != nil {
+		t.Fatalf("Impossible to watch the keys: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	_ = client.Set("UNWATCHED_"+byteKey, []byte(baseValue), time.Minute)
+	_ = client.Set("WATCHED_"+byteKey, []byte(baseValue), time.Minute)
+	client.Delete("WATCHED_" + byteKey)
+
+	for _, expected := range []core.EventKind{core.EventSet, core.EventDelete} {
+		select {
+		case event := <-events:
+			if event.Kind != expected || event.Key != "WATCHED_"+byteKey {
+				t.Errorf("The %s event of the watched key should be received, %+v given", expected, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("The %s event wasn't received", expected)
+		}
+	}
+
+	cancel()
+
+	for range events {
+	}
+}
+
+func TestBadger_OnEvicted(t *testing.T) {
+	client, err := badger.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{"in_memory": true, "eviction_scan_interval": "100ms"},
+	}, zap.NewNop().Sugar(), time.Second)
+	if err != nil {
+		t.Fatalf("Impossible to create the Badger provider: %v", err)
+	}
+
+	evicted := make(chan string, 2)
+
+	client.(core.EvictionNotifier).OnEvicted(func(key string, value []byte) {
+		if string(value) != baseValue {
+			t.Errorf("The stored value should be notified, %s given", value)
+		}
+
+		select {
+		case evicted <- key:
+		default:
+		}
+	})
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Second)
+	_ = client.Set(nonExistentKey, []byte(baseValue), time.Second)
+	client.Delete(nonExistentKey)
+
+	select {
+	case key := <-evicted:
+		if key != byteKey {
+			t.Errorf("Only the expired key should be notified, %s given", key)
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("The expired entry should be notified")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := badger.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
@@ -41,6 +41,11 @@ func (b *Badger) Provision(ctx caddy.Context) error {
 		return err
 	}
 
+	storer, err = core.EncryptedStorerFromConfiguration(storer, b.Configuration.Provider, b.Configuration.Stale, logger.Sugar())
+	if err != nil {
+		return err
+	}
+
 	core.RegisterStorage(storer)
 
 	return nil
@@ -0,0 +1,428 @@
+// Package benchmarks generates a provider-agnostic load on any storer and
+// reports its latency percentiles and allocations, so the storages can be
+// compared with the same workload.
+package benchmarks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const (
+	defaultKeys       = 1000
+	defaultValueSize  = 1024
+	defaultReadRatio  = 0.9
+	defaultVariants   = 1
+	defaultOperations = 100000
+	defaultTTL        = time.Minute
+
+	// VariedHeader is the request header the variants of a key vary on.
+	VariedHeader = "Accept-Language"
+)
+
+// Options describes the generated workload.
+type Options struct {
+	// Keys is the number of distinct base keys, 1000 by default.
+	Keys int `json:"keys"`
+	// MinValueSize and MaxValueSize bound the stored body sizes in bytes,
+	// 1KB by default. Each write picks a size in between.
+	MinValueSize int `json:"min_value_size"`
+	MaxValueSize int `json:"max_value_size"`
+	// ReadRatio is the share of reads between 0 and 1, 0.9 by default.
+	ReadRatio float64 `json:"read_ratio"`
+	// Variants is the number of responses stored per key, varying on the
+	// VariedHeader, 1 by default.
+	Variants int `json:"variants"`
+	// Operations is the number of operations to run, 100000 by default. It's
+	// ignored when Duration is set.
+	Operations int `json:"operations"`
+	// Duration runs the workload for that long instead, for soak tests.
+	Duration time.Duration `json:"duration"`
+	// Concurrency is the number of workers, GOMAXPROCS by default.
+	Concurrency int `json:"concurrency"`
+	// TTL is the lifetime of the stored responses, 1m by default.
+	TTL time.Duration `json:"ttl"`
+	// Prefill stores every variant of every key before the measures, the
+	// reads miss until the keys are written otherwise.
+	Prefill bool `json:"prefill"`
+	// Seed makes the workload reproducible.
+	Seed uint64 `json:"seed"`
+}
+
+func (o Options) withDefaults() Options {
+	if o.Keys == 0 {
+		o.Keys = defaultKeys
+	}
+
+	if o.MinValueSize == 0 && o.MaxValueSize == 0 {
+		o.MinValueSize, o.MaxValueSize = defaultValueSize, defaultValueSize
+	}
+
+	if o.MaxValueSize < o.MinValueSize {
+		o.MaxValueSize = o.MinValueSize
+	}
+
+	if o.ReadRatio == 0 {
+		o.ReadRatio = defaultReadRatio
+	}
+
+	if o.Variants == 0 {
+		o.Variants = defaultVariants
+	}
+
+	if o.Operations == 0 {
+		o.Operations = defaultOperations
+	}
+
+	if o.Concurrency == 0 {
+		o.Concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	if o.TTL == 0 {
+		o.TTL = defaultTTL
+	}
+
+	return o
+}
+
+func (o Options) validate() error {
+	switch {
+	case o.Keys < 0, o.Variants < 0, o.Operations < 0, o.Concurrency < 0:
+		return errors.New("the keys, variants, operations and concurrency can't be negative")
+	case o.MinValueSize < 0:
+		return errors.New("the value sizes can't be negative")
+	case o.ReadRatio < 0 || o.ReadRatio > 1:
+		return fmt.Errorf("the read_ratio must be between 0 and 1, %v given", o.ReadRatio)
+	case o.Duration < 0 || o.TTL < 0:
+		return errors.New("the duration and the ttl can't be negative")
+	}
+
+	return nil
+}
+
+// Percentiles summarizes the latencies of an operation.
+type Percentiles struct {
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	P999 time.Duration `json:"p999"`
+	Max  time.Duration `json:"max"`
+}
+
+// OperationReport describes the reads or the writes of a run.
+type OperationReport struct {
+	Count   int         `json:"count"`
+	Errors  int         `json:"errors"`
+	Latency Percentiles `json:"latency"`
+}
+
+// Report is the result of a run.
+type Report struct {
+	Storer     string          `json:"storer"`
+	Duration   time.Duration   `json:"duration"`
+	Operations int             `json:"operations"`
+	Throughput float64         `json:"throughput"`
+	Reads      OperationReport `json:"reads"`
+	Writes     OperationReport `json:"writes"`
+	Hits       int             `json:"hits"`
+	HitRatio   float64         `json:"hit_ratio"`
+	// AllocsPerOp and BytesPerOp include the allocations of the load
+	// generator itself, compare them between storers only.
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+}
+
+// String formats the report on a few lines.
+func (r Report) String() string {
+	return fmt.Sprintf(
+		"%s: %d ops in %s (%.0f ops/s), %.1f allocs/op, %.0f B/op\n"+
+			"  reads  %d (%d errors, %.1f%% hits) p50=%s p90=%s p99=%s p99.9=%s max=%s\n"+
+			"  writes %d (%d errors) p50=%s p90=%s p99=%s p99.9=%s max=%s",
+		r.Storer, r.Operations, r.Duration, r.Throughput, r.AllocsPerOp, r.BytesPerOp,
+		r.Reads.Count, r.Reads.Errors, r.HitRatio*100, r.Reads.Latency.P50, r.Reads.Latency.P90, r.Reads.Latency.P99, r.Reads.Latency.P999, r.Reads.Latency.Max,
+		r.Writes.Count, r.Writes.Errors, r.Writes.Latency.P50, r.Writes.Latency.P90, r.Writes.Latency.P99, r.Writes.Latency.P999, r.Writes.Latency.Max,
+	)
+}
+
+// workload holds the prebuilt keys and values shared by the workers.
+type workload struct {
+	storer  core.Storer
+	options Options
+	values  [][]byte
+}
+
+func newWorkload(storer core.Storer, options Options, rng *rand.Rand) *workload {
+	w := &workload{storer: storer, options: options}
+
+	// A few prebuilt values are enough to vary the sizes without allocating
+	// on each write.
+	for range 16 {
+		size := options.MinValueSize
+		if options.MaxValueSize > options.MinValueSize {
+			size += rng.IntN(options.MaxValueSize - options.MinValueSize + 1)
+		}
+
+		body := make([]byte, size)
+		for i := range body {
+			body[i] = byte('a' + rng.IntN(26))
+		}
+
+		w.values = append(w.values, append([]byte("HTTP/1.1 200 OK\r\nContent-Length: "+strconv.Itoa(size)+"\r\n\r\n"), body...))
+	}
+
+	return w
+}
+
+func baseKey(key int) string {
+	return "GET-http-example.com-/benchmarks/" + strconv.Itoa(key)
+}
+
+func variantValue(variant int) string {
+	return "lang-" + strconv.Itoa(variant)
+}
+
+func (w *workload) write(rng *rand.Rand, key, variant int) error {
+	base := baseKey(key)
+	variedKey := base + "-" + variantValue(variant)
+
+	return w.storer.SetMultiLevel(
+		base,
+		variedKey,
+		w.values[rng.IntN(len(w.values))],
+		http.Header{VariedHeader: []string{variantValue(variant)}},
+		"",
+		w.options.TTL,
+		variedKey,
+	)
+}
+
+func (w *workload) read(key, variant int) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/benchmarks/"+strconv.Itoa(key), nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set(VariedHeader, variantValue(variant))
+
+	fresh, stale := w.storer.GetMultiLevel(baseKey(key), req, &core.Revalidator{})
+
+	hit := false
+
+	for _, res := range []*http.Response{fresh, stale} {
+		if res != nil {
+			hit = true
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+	}
+
+	return hit, nil
+}
+
+// prefill writes every variant of every key.
+func (w *workload) prefill(rng *rand.Rand) error {
+	for key := range w.options.Keys {
+		for variant := range w.options.Variants {
+			if err := w.write(rng, key, variant); err != nil {
+				return fmt.Errorf("impossible to prefill the key %d: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// result is what a worker measured.
+type result struct {
+	reads, writes           []time.Duration
+	readErrors, writeErrors int
+	hits                    int
+}
+
+func (w *workload) worker(rng *rand.Rand, next func() bool) result {
+	var r result
+
+	for next() {
+		key := rng.IntN(w.options.Keys)
+		variant := rng.IntN(w.options.Variants)
+
+		if rng.Float64() < w.options.ReadRatio {
+			start := time.Now()
+			hit, err := w.read(key, variant)
+			r.reads = append(r.reads, time.Since(start))
+
+			if err != nil {
+				r.readErrors++
+			}
+
+			if hit {
+				r.hits++
+			}
+
+			continue
+		}
+
+		start := time.Now()
+		err := w.write(rng, key, variant)
+		r.writes = append(r.writes, time.Since(start))
+
+		if err != nil {
+			r.writeErrors++
+		}
+	}
+
+	return r
+}
+
+func percentiles(latencies []time.Duration) Percentiles {
+	if len(latencies) == 0 {
+		return Percentiles{}
+	}
+
+	slices.Sort(latencies)
+
+	at := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+
+	return Percentiles{P50: at(0.5), P90: at(0.9), P99: at(0.99), P999: at(0.999), Max: latencies[len(latencies)-1]}
+}
+
+// Run generates the workload on the storer and reports what was measured.
+func Run(storer core.Storer, options Options) (Report, error) {
+	if err := options.validate(); err != nil {
+		return Report{}, fmt.Errorf("invalid benchmark options: %w", err)
+	}
+
+	options = options.withDefaults()
+
+	//nolint:gosec // The workload doesn't need a cryptographic source.
+	seed := rand.New(rand.NewPCG(options.Seed, options.Seed^0x9e3779b97f4a7c15))
+	w := newWorkload(storer, options, seed)
+
+	if options.Prefill {
+		if err := w.prefill(seed); err != nil {
+			return Report{}, err
+		}
+	}
+
+	var (
+		next     func() bool
+		deadline time.Time
+	)
+
+	if options.Duration > 0 {
+		next = func() bool {
+			return time.Now().Before(deadline)
+		}
+	} else {
+		var (
+			mu        sync.Mutex
+			remaining = options.Operations
+		)
+
+		next = func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+
+			remaining--
+
+			return remaining >= 0
+		}
+	}
+
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	results := make([]result, options.Concurrency)
+	start := time.Now()
+	deadline = start.Add(options.Duration)
+
+	var wg sync.WaitGroup
+
+	for i := range options.Concurrency {
+		//nolint:gosec // The workload doesn't need a cryptographic source.
+		rng := rand.New(rand.NewPCG(seed.Uint64(), seed.Uint64()))
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results[i] = w.worker(rng, next)
+		}()
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	var (
+		reads, writes []time.Duration
+		report        = Report{Storer: storer.Name(), Duration: elapsed}
+	)
+
+	for _, r := range results {
+		reads = append(reads, r.reads...)
+		writes = append(writes, r.writes...)
+		report.Reads.Errors += r.readErrors
+		report.Writes.Errors += r.writeErrors
+		report.Hits += r.hits
+	}
+
+	report.Reads.Count = len(reads)
+	report.Writes.Count = len(writes)
+	report.Reads.Latency = percentiles(reads)
+	report.Writes.Latency = percentiles(writes)
+	report.Operations = len(reads) + len(writes)
+
+	if report.Operations > 0 {
+		report.Throughput = float64(report.Operations) / elapsed.Seconds()
+		report.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(report.Operations)
+		report.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / float64(report.Operations)
+	}
+
+	if len(reads) > 0 {
+		report.HitRatio = float64(report.Hits) / float64(len(reads))
+	}
+
+	return report, nil
+}
+
+// Benchmark runs b.N operations of the workload on the storer and reports
+// the percentiles and the hit ratio as custom metrics, the Operations and
+// Duration options are ignored.
+func Benchmark(b *testing.B, storer core.Storer, options Options) {
+	b.Helper()
+
+	options.Operations = b.N
+	options.Duration = 0
+
+	b.ResetTimer()
+
+	report, err := Run(storer, options)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.StopTimer()
+
+	b.ReportMetric(float64(report.Reads.Latency.P99.Nanoseconds()), "read-p99-ns")
+	b.ReportMetric(float64(report.Writes.Latency.P99.Nanoseconds()), "write-p99-ns")
+	b.ReportMetric(report.HitRatio, "hit-ratio")
+}
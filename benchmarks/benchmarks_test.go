@@ -0,0 +1,148 @@
+package benchmarks_test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/benchmarks"
+	"github.com/darkweak/storages/core"
+)
+
+type nopLogger struct {
+	core.Logger
+}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// memoryStorer is a minimal map based Storer.
+type memoryStorer struct {
+	core.Storer
+
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+func newMemoryStorer() *memoryStorer {
+	return &memoryStorer{values: map[string][]byte{}}
+}
+
+func (m *memoryStorer) Name() string {
+	return "MEMORY"
+}
+
+func (m *memoryStorer) Get(key string) []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.values[key]
+}
+
+func (m *memoryStorer) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale, _ = core.MappingElection(m, m.Get(core.MappingKeyPrefix+key), req, validator, nopLogger{})
+
+	return
+}
+
+func (m *memoryStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[variedKey] = value
+
+	mapping, err := core.MappingUpdater(variedKey, m.values[core.MappingKeyPrefix+baseKey], nopLogger{}, now, now.Add(duration), now.Add(duration), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	m.values[core.MappingKeyPrefix+baseKey] = mapping
+
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	storer := newMemoryStorer()
+
+	report, err := benchmarks.Run(storer, benchmarks.Options{
+		Keys:         50,
+		MinValueSize: 64,
+		MaxValueSize: 256,
+		ReadRatio:    0.8,
+		Variants:     3,
+		Operations:   2000,
+		Concurrency:  4,
+		Prefill:      true,
+	})
+	if err != nil {
+		t.Fatalf("The run shouldn't fail, %v given", err)
+	}
+
+	if report.Storer != "MEMORY" || report.Operations != 2000 || report.Reads.Count+report.Writes.Count != 2000 {
+		t.Errorf("The report should count every operation, %+v given", report)
+	}
+
+	if report.Reads.Count == 0 || report.Writes.Count == 0 {
+		t.Errorf("The workload should mix reads and writes, %+v given", report)
+	}
+
+	if report.HitRatio != 1 {
+		t.Errorf("Every read should hit once prefilled, %v given", report.HitRatio)
+	}
+
+	if report.Reads.Latency.P50 > report.Reads.Latency.P99 || report.Reads.Latency.P99 > report.Reads.Latency.Max || report.Reads.Latency.Max == 0 {
+		t.Errorf("The percentiles should be ordered, %+v given", report.Reads.Latency)
+	}
+
+	if report.AllocsPerOp <= 0 || report.Throughput <= 0 {
+		t.Errorf("The allocations and the throughput should be measured, %+v given", report)
+	}
+
+	if !strings.HasPrefix(report.String(), "MEMORY: 2000 ops") {
+		t.Errorf("Unexpected report format, %s given", report)
+	}
+
+	// Every variant of a key shares the same mapping.
+	if metadata, _ := core.DecodeMetadata(storer.Get(core.MappingKeyPrefix + "GET-http-example.com-/benchmarks/0")); len(metadata) != 3 {
+		t.Errorf("The key should have 3 variants, %d given", len(metadata))
+	}
+}
+
+func TestRun_Duration(t *testing.T) {
+	report, err := benchmarks.Run(newMemoryStorer(), benchmarks.Options{
+		Keys:     10,
+		Duration: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("The run shouldn't fail, %v given", err)
+	}
+
+	if report.Duration < 50*time.Millisecond || report.Operations == 0 {
+		t.Errorf("The run should last the given duration, %+v given", report)
+	}
+
+	if report.HitRatio == 1 {
+		t.Errorf("The reads should miss until the keys are written, %v given", report.HitRatio)
+	}
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	for _, options := range []benchmarks.Options{
+		{ReadRatio: 2},
+		{Keys: -1},
+		{MinValueSize: -1},
+		{Duration: -time.Second},
+	} {
+		if _, err := benchmarks.Run(newMemoryStorer(), options); err == nil {
+			t.Errorf("The options %+v should be rejected", options)
+		}
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	benchmarks.Benchmark(b, newMemoryStorer(), benchmarks.Options{Variants: 2, Prefill: true})
+}
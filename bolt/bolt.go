@@ -0,0 +1,497 @@
+//go:build !wasm && !wasi
+
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"go.etcd.io/bbolt"
+)
+
+// Bolt provider type.
+type Bolt struct {
+	*bbolt.DB
+
+	stale         time.Duration
+	uuid          string
+	logger        core.Logger
+	compressor    core.Compressor
+	path          string
+	sweepInterval time.Duration
+	limiter       *core.BackgroundLimiter
+	stop          chan struct{}
+}
+
+const (
+	defaultBucket        = "souin-bucket"
+	ttlBucket            = "souin-ttl"
+	defaultSweepInterval = time.Minute
+	expirationSize       = 8
+)
+
+var (
+	enabledBoltInstances = sync.Map{}
+	prefixedBuckets      = []string{core.MappingKeyPrefix, core.SurrogateKeyPrefix}
+)
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("bolt", Factory)
+}
+
+// configuration is the typed Bolt provider configuration.
+type configuration struct {
+	Path string `json:"path"`
+	// SweepInterval is the delay between two removals of the expired keys.
+	SweepInterval time.Duration `json:"sweep_interval"`
+}
+
+func parseConfiguration(boltConfiguration any) (configuration, error) {
+	cfg := configuration{SweepInterval: defaultSweepInterval}
+
+	if err := core.DecodeConfiguration(boltConfiguration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid bolt configuration: %w", err)
+	}
+
+	if cfg.SweepInterval <= 0 {
+		return cfg, fmt.Errorf("invalid bolt configuration: the sweep_interval must be positive, %s given", cfg.SweepInterval)
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(boltConfiguration any) error {
+	_, err := parseConfiguration(boltConfiguration)
+
+	return err
+}
+
+// Factory function create new Bolt instance.
+func Factory(boltConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(boltConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	path := boltConfiguration.Path
+	if cfg.Path != "" {
+		path = cfg.Path
+	}
+
+	sweepInterval := cfg.SweepInterval
+
+	if path == "" {
+		path = "souin.db"
+	}
+
+	compressor, err := core.CompressorFromConfiguration(boltConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(boltConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := path + stale.String()
+
+	if instance, ok := enabledBoltInstances.Load(uid); ok {
+		return instance.(*Bolt), nil
+	}
+
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		logger.Error("Impossible to open the Bolt DB.", err)
+
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range append([]string{defaultBucket, ttlBucket}, prefixedBuckets...) {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.Error("Impossible to create the Bolt buckets.", err)
+
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	instance := &Bolt{
+		DB:            db,
+		stale:         stale,
+		uuid:          core.InstanceID("bolt", boltConfiguration, stale),
+		logger:        logger,
+		compressor:    compressor,
+		limiter:       limiter,
+		path:          path,
+		sweepInterval: sweepInterval,
+	}
+	enabledBoltInstances.Store(uid, instance)
+
+	return instance, nil
+}
+
+// bucketFor returns the bucket that owns the given key, each known prefix
+// has its own bucket to keep the prefix scans small.
+func bucketFor(key string) []byte {
+	for _, prefix := range prefixedBuckets {
+		if strings.HasPrefix(key, prefix) {
+			return []byte(prefix)
+		}
+	}
+
+	return []byte(defaultBucket)
+}
+
+// encode prepends the expiration timestamp to the value, a zero timestamp
+// means the value never expires.
+func encode(value []byte, duration time.Duration) ([]byte, int64) {
+	var expiration int64
+
+	if duration > 0 {
+		expiration = time.Now().Add(duration).UnixNano()
+	}
+
+	encoded := make([]byte, expirationSize+len(value))
+	//nolint:gosec
+	binary.BigEndian.PutUint64(encoded, uint64(expiration))
+	copy(encoded[expirationSize:], value)
+
+	return encoded, expiration
+}
+
+// decode returns a copy of the stored value if it is not expired yet.
+func decode(encoded []byte) ([]byte, bool) {
+	if len(encoded) < expirationSize {
+		return nil, false
+	}
+
+	//nolint:gosec
+	expiration := int64(binary.BigEndian.Uint64(encoded))
+	if expiration != 0 && expiration < time.Now().UnixNano() {
+		return nil, false
+	}
+
+	return bytes.Clone(encoded[expirationSize:]), true
+}
+
+// ttlIndexKey sorts the TTL index by expiration so the sweeper can stop at
+// the first entry that is still alive.
+func ttlIndexKey(expiration int64, key string) []byte {
+	indexKey := make([]byte, expirationSize+len(key))
+	//nolint:gosec
+	binary.BigEndian.PutUint64(indexKey, uint64(expiration))
+	copy(indexKey[expirationSize:], key)
+
+	return indexKey
+}
+
+func (provider *Bolt) put(tx *bbolt.Tx, key string, value []byte, duration time.Duration) error {
+	encoded, expiration := encode(value, duration)
+
+	if err := tx.Bucket(bucketFor(key)).Put([]byte(key), encoded); err != nil {
+		return err
+	}
+
+	if expiration == 0 {
+		return nil
+	}
+
+	return tx.Bucket([]byte(ttlBucket)).Put(ttlIndexKey(expiration, key), nil)
+}
+
+// Name returns the storer name.
+func (provider *Bolt) Name() string {
+	return "BOLT"
+}
+
+// Uuid returns an unique identifier.
+func (provider *Bolt) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *Bolt) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	_ = provider.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(bucketFor(prefix)).Cursor()
+		p := []byte(prefix)
+
+		for k, v := cursor.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = cursor.Next() {
+			if value, valid := decode(v); valid {
+				nk, _ := strings.CutPrefix(string(k), prefix)
+				keys[nk] = string(value)
+			}
+		}
+
+		return nil
+	})
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Bolt) ListKeys() []string {
+	keys := []string{}
+
+	err := provider.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(core.MappingKeyPrefix)).ForEach(func(_, v []byte) error {
+			value, valid := decode(v)
+			if !valid {
+				return nil
+			}
+
+			mapping, err := core.DecodeMapping(value)
+			if err == nil {
+				for _, v := range mapping.GetMapping() {
+					keys = append(keys, v.GetRealKey())
+				}
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return []string{}
+	}
+
+	return keys
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Bolt) Get(key string) []byte {
+	result, _ := provider.Lookup(key)
+
+	return result
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Bolt) Lookup(key string) ([]byte, error) {
+	var (
+		result []byte
+		found  bool
+	)
+
+	err := provider.View(func(tx *bbolt.Tx) error {
+		result, found = decode(tx.Bucket(bucketFor(key)).Get([]byte(key)))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return result, nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Bolt) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	val := provider.Get(core.MappingKeyPrefix + key)
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
+
+	return fresh, stale
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *Bolt) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into Bolt, %v", variedKey, err)
+
+		return err
+	}
+
+	var evicted []string
+
+	err = provider.Update(func(tx *bbolt.Tx) error {
+		if err := provider.put(tx, variedKey, compressed, duration+provider.stale); err != nil {
+			provider.logger.Errorf("Impossible to set the key %s into Bolt, %v", variedKey, err)
+
+			return err
+		}
+
+		mappingKey := core.MappingKeyPrefix + baseKey
+		val, _ := decode(tx.Bucket(bucketFor(mappingKey)).Get([]byte(mappingKey)))
+
+		var err error
+
+		val, evicted, err = core.MappingUpdaterWithEvictions(variedKey, val, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+		if err != nil {
+			provider.logger.Errorf("Impossible to update the mapping for the key %s in Bolt, %v", variedKey, err)
+
+			return err
+		}
+
+		provider.logger.Debugf("Store the new mapping for the key %s in Bolt", variedKey)
+
+		return provider.put(tx, mappingKey, val, 0)
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to set value into Bolt, %v", err)
+
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in Bolt provider.
+func (provider *Bolt) Set(key string, value []byte, duration time.Duration) error {
+	err := provider.Update(func(tx *bbolt.Tx) error {
+		return provider.put(tx, key, value, duration)
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to set value into Bolt, %v", err)
+	}
+
+	return err
+}
+
+// Delete method will delete the response in Bolt provider if exists corresponding to key param.
+func (provider *Bolt) Delete(key string) {
+	_ = provider.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketFor(key)).Delete([]byte(key))
+	})
+}
+
+// DeleteMany method will delete the responses in Bolt provider if exists corresponding to the regex key param.
+func (provider *Bolt) DeleteMany(key string) {
+	rgKey, e := regexp.Compile(key)
+	if e != nil {
+		return
+	}
+
+	_ = provider.Update(func(tx *bbolt.Tx) error {
+		for _, name := range append([]string{defaultBucket}, prefixedBuckets...) {
+			cursor := tx.Bucket([]byte(name)).Cursor()
+
+			for k, _ := cursor.First(); k != nil; {
+				if !rgKey.Match(k) {
+					k, _ = cursor.Next()
+
+					continue
+				}
+
+				deleted := bytes.Clone(k)
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+
+				k, _ = cursor.Seek(deleted)
+			}
+		}
+
+		return nil
+	})
+}
+
+// sweep removes the expired entries referenced by the TTL index.
+func (provider *Bolt) sweep() error {
+	return provider.Update(func(tx *bbolt.Tx) error {
+		index := tx.Bucket([]byte(ttlBucket))
+		cursor := index.Cursor()
+		now := time.Now().UnixNano()
+
+		for k, _ := cursor.First(); k != nil && len(k) >= expirationSize; k, _ = cursor.First() {
+			//nolint:gosec
+			expiration := int64(binary.BigEndian.Uint64(k))
+			if expiration > now {
+				break
+			}
+
+			key := bytes.Clone(k[expirationSize:])
+			bucket := tx.Bucket(bucketFor(string(key)))
+
+			// The key may have been overwritten with a later expiration, only
+			// drop it when the stored expiration is the indexed one.
+			if stored := bucket.Get(key); len(stored) >= expirationSize && bytes.Equal(stored[:expirationSize], k[:expirationSize]) {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Init method will start the background sweeper.
+func (provider *Bolt) Init() error {
+	if provider.stop != nil {
+		return nil
+	}
+
+	provider.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(provider.sweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(stop, "sweep") {
+					return
+				}
+
+				if err := provider.sweep(); err != nil && !errors.Is(err, bbolt.ErrDatabaseNotOpen) {
+					provider.logger.Errorf("Impossible to sweep the expired keys in Bolt, %v", err)
+				}
+			}
+		}
+	}(provider.stop)
+
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *Bolt) Reset() error {
+	if provider.stop != nil {
+		close(provider.stop)
+		provider.stop = nil
+	}
+
+	var err error
+	// Close the DB connection
+	if provider.DB != nil {
+		err = provider.Close()
+	}
+	// Only delete this instance from the cache
+	enabledBoltInstances.Delete(provider.path + provider.stale.String())
+
+	return err
+}
@@ -0,0 +1,208 @@
+//go:build !wasm && !wasi
+
+package bolt_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/bolt"
+	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getBoltInstance(t *testing.T, configuration any) core.Storer {
+	t.Helper()
+
+	instance, err := bolt.Factory(core.CacheProvider{
+		Path:          filepath.Join(t.TempDir(), "souin.db"),
+		Configuration: configuration,
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Bolt should be instanciated: %v", err)
+	}
+
+	_ = instance.Init()
+
+	t.Cleanup(func() {
+		_ = instance.Reset()
+	})
+
+	return instance
+}
+
+func TestBoltConnectionFactory(t *testing.T) {
+	instance := getBoltInstance(t, nil)
+
+	if instance.Name() != "BOLT" {
+		t.Errorf("The storer name should be BOLT, %s given", instance.Name())
+	}
+}
+
+func TestIShouldBeAbleToReadAndWriteDataInBolt(t *testing.T) {
+	client := getBoltInstance(t, nil)
+
+	_ = client.Set("Test", []byte(baseValue), 20*time.Second)
+
+	res := client.Get("Test")
+	if baseValue != string(res) {
+		t.Errorf("%s not corresponding to %s", string(res), baseValue)
+	}
+}
+
+func TestBolt_GetRequestInCache(t *testing.T) {
+	client := getBoltInstance(t, nil)
+
+	if res := client.Get(nonExistentKey); 0 < len(res) {
+		t.Errorf("Key %s should not exist", nonExistentKey)
+	}
+}
+
+func TestBolt_SetRequestInCache_TTL(t *testing.T) {
+	client := getBoltInstance(t, nil)
+	value := []byte("Hello world")
+
+	_ = client.Set(byteKey, value, time.Second)
+
+	if newValue := client.Get(byteKey); !bytes.Equal(newValue, value) {
+		t.Errorf("Key %s should be equals to %s, %s provided", byteKey, value, newValue)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	if newValue := client.Get(byteKey); len(newValue) != 0 {
+		t.Errorf("Key %s should be expired, %s provided", byteKey, newValue)
+	}
+}
+
+func TestBolt_SweepExpiredKeys(t *testing.T) {
+	client := getBoltInstance(t, map[string]interface{}{"sweep_interval": "100ms"})
+
+	_ = client.Set(byteKey, []byte("A"), 50*time.Millisecond)
+	_ = client.Set("persistent", []byte("B"), 0)
+
+	time.Sleep(300 * time.Millisecond)
+
+	keys := client.MapKeys("")
+	if _, found := keys[byteKey]; found {
+		t.Errorf("Key %s should have been swept", byteKey)
+	}
+
+	if keys["persistent"] != "B" {
+		t.Errorf("The persistent key should still exist, %v given", keys)
+	}
+}
+
+func TestBolt_DeleteRequestInCache(t *testing.T) {
+	client := getBoltInstance(t, nil)
+
+	_ = client.Set(byteKey, []byte("A"), 20*time.Second)
+	client.Delete(byteKey)
+
+	if 0 < len(client.Get(byteKey)) {
+		t.Errorf("Key %s should not exist", byteKey)
+	}
+}
+
+func TestBolt_DeleteMany(t *testing.T) {
+	client := getBoltInstance(t, nil)
+
+	for _, key := range []string{"first", "second", "third", core.MappingKeyPrefix + "first"} {
+		_ = client.Set(key, []byte(key), 20*time.Second)
+	}
+
+	client.DeleteMany("^.*(first|second)$")
+
+	if keys := client.MapKeys(""); len(keys) != 1 || keys["third"] != "third" {
+		t.Errorf("Only the third key should remain, %v given", keys)
+	}
+
+	if keys := client.MapKeys(core.MappingKeyPrefix); len(keys) != 0 {
+		t.Errorf("The mapping key should have been deleted, %v given", keys)
+	}
+}
+
+func TestBolt_SetMultiLevel(t *testing.T) {
+	client := getBoltInstance(t, nil)
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+
+	err := client.SetMultiLevel("base", "varied", value, http.Header{}, "", time.Minute, "real")
+	if err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	stored := client.Get("varied")
+
+	reader, err := core.DetectCompressor(stored).Decompress(stored)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	decompressed := new(bytes.Buffer)
+	if _, err := decompressed.ReadFrom(reader); err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	if !bytes.Equal(decompressed.Bytes(), value) {
+		t.Errorf("The stored value doesn't match, %s given", decompressed.Bytes())
+	}
+
+	if keys := client.ListKeys(); len(keys) != 1 || keys[0] != "real" {
+		t.Errorf("The listed keys should only contain real, %v given", keys)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com", nil)
+
+	fresh, _ := client.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The fresh response should exist")
+	}
+
+	_ = fresh.Body.Close()
+}
+
+func TestBolt_Validate(t *testing.T) {
+	if err := bolt.Validate(map[string]interface{}{"path": "/tmp/bolt", "sweep_interval": "1m"}); err != nil {
+		t.Errorf("The configuration should be valid, %v given", err)
+	}
+
+	if err := bolt.Validate(map[string]interface{}{"sweep_interval": "every minute"}); err == nil {
+		t.Error("A malformed sweep_interval should be invalid")
+	}
+}
+
+func TestBolt_Lookup(t *testing.T) {
+	client := getBoltInstance(t, nil)
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := bolt.Factory(core.CacheProvider{Path: filepath.Join(t.TempDir(), "souin.db")}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
@@ -0,0 +1,62 @@
+//go:build !wasm && !wasi
+
+package caddy
+
+import (
+	"net/http"
+
+	caddy "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/darkweak/storages/bolt"
+	"github.com/darkweak/storages/core"
+)
+
+const moduleName = "bolt"
+
+// Bolt storage.
+type Bolt struct {
+	// Keep the handler configuration.
+	core.Configuration
+}
+
+//nolint:gochecknoinits
+func init() {
+	caddy.RegisterModule(Bolt{})
+}
+
+// CaddyModule returns the Caddy module information.
+func (Bolt) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "storages.cache.bolt",
+		New: func() caddy.Module { return new(Bolt) },
+	}
+}
+
+// Provision to do the provisioning part.
+func (b *Bolt) Provision(ctx caddy.Context) error {
+	logger := ctx.Logger(b)
+	storer, err := bolt.Factory(b.Configuration.Provider, logger.Sugar(), b.Configuration.Stale)
+
+	if err != nil {
+		return err
+	}
+
+	storer, err = core.EncryptedStorerFromConfiguration(storer, b.Configuration.Provider, b.Configuration.Stale, logger.Sugar())
+	if err != nil {
+		return err
+	}
+
+	core.RegisterStorage(storer)
+
+	return nil
+}
+
+func (b *Bolt) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyhttp.Handler) error {
+	return next.ServeHTTP(rw, rq)
+}
+
+// Interface guards.
+var (
+	_ caddy.Provisioner           = (*Bolt)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Bolt)(nil)
+)
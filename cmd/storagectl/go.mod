@@ -0,0 +1,154 @@
+module github.com/darkweak/storages/cmd/storagectl
+
+go 1.24.9
+
+replace (
+	github.com/darkweak/storages/badger => ../../badger
+	github.com/darkweak/storages/bolt => ../../bolt
+	github.com/darkweak/storages/core => ../../core
+	github.com/darkweak/storages/etcd => ../../etcd
+	github.com/darkweak/storages/fs => ../../fs
+	github.com/darkweak/storages/go-redis => ../../go-redis
+	github.com/darkweak/storages/memcached => ../../memcached
+	github.com/darkweak/storages/nats => ../../nats
+	github.com/darkweak/storages/nuts => ../../nuts
+	github.com/darkweak/storages/olric => ../../olric
+	github.com/darkweak/storages/otter => ../../otter
+	github.com/darkweak/storages/peers => ../../peers
+	github.com/darkweak/storages/postgres => ../../postgres
+	github.com/darkweak/storages/redis => ../../redis
+	github.com/darkweak/storages/remote => ../../remote
+	github.com/darkweak/storages/rest => ../../rest
+	github.com/darkweak/storages/ristretto => ../../ristretto
+	github.com/darkweak/storages/s3 => ../../s3
+	github.com/darkweak/storages/shardedmap => ../../shardedmap
+	github.com/darkweak/storages/simplefs => ../../simplefs
+	github.com/darkweak/storages/sqlite => ../../sqlite
+)
+
+require (
+	github.com/darkweak/storages/badger v0.0.19
+	github.com/darkweak/storages/bolt v0.0.19
+	github.com/darkweak/storages/core v0.0.19
+	github.com/darkweak/storages/etcd v0.0.19
+	github.com/darkweak/storages/fs v0.0.19
+	github.com/darkweak/storages/go-redis v0.0.19
+	github.com/darkweak/storages/memcached v0.0.19
+	github.com/darkweak/storages/nats v0.0.19
+	github.com/darkweak/storages/nuts v0.0.19
+	github.com/darkweak/storages/olric v0.0.19
+	github.com/darkweak/storages/otter v0.0.19
+	github.com/darkweak/storages/peers v0.0.19
+	github.com/darkweak/storages/postgres v0.0.19
+	github.com/darkweak/storages/redis v0.0.19
+	github.com/darkweak/storages/remote v0.0.19
+	github.com/darkweak/storages/rest v0.0.19
+	github.com/darkweak/storages/ristretto v0.0.19
+	github.com/darkweak/storages/s3 v0.0.19
+	github.com/darkweak/storages/shardedmap v0.0.19
+	github.com/darkweak/storages/simplefs v0.0.19
+	github.com/darkweak/storages/sqlite v0.0.19
+)
+
+require (
+	dario.cat/mergo v1.0.2 // indirect
+	github.com/RoaringBitmap/roaring v1.2.1 // indirect
+	github.com/antlabs/stl v0.0.1 // indirect
+	github.com/antlabs/timer v0.0.11 // indirect
+	github.com/armon/go-metrics v0.0.0-20180917152333-f0300d1749da // indirect
+	github.com/bits-and-blooms/bitset v1.2.0 // indirect
+	github.com/bradfitz/gomemcache v0.0.0-20260422231931-4d751bb6e37c // indirect
+	github.com/buraksezer/consistent v0.10.0 // indirect
+	github.com/buraksezer/olric v0.5.7 // indirect
+	github.com/bwmarrin/snowflake v0.3.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/coreos/go-semver v0.3.0 // indirect
+	github.com/coreos/go-systemd/v22 v22.3.2 // indirect
+	github.com/dgraph-io/badger/v4 v4.9.1 // indirect
+	github.com/dgraph-io/ristretto/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/dolthub/maphash v0.1.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/gammazero/deque v0.2.1 // indirect
+	github.com/go-ini/ini v1.67.0 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/go-redis/redis/v8 v8.11.5 // indirect
+	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/goccy/go-json v0.10.4 // indirect
+	github.com/gofrs/flock v0.8.1 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/google/btree v0.0.0-20180813153112-4030bb1f1f0c // indirect
+	github.com/google/flatbuffers v25.2.10+incompatible // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/hashicorp/go-immutable-radix v1.0.0 // indirect
+	github.com/hashicorp/go-msgpack v0.5.3 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	github.com/hashicorp/go-sockaddr v1.0.2 // indirect
+	github.com/hashicorp/golang-lru v0.5.0 // indirect
+	github.com/hashicorp/logutils v1.0.0 // indirect
+	github.com/hashicorp/memberlist v0.5.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/pgx/v5 v5.7.5 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/jellydator/ttlcache/v3 v3.3.0 // indirect
+	github.com/klauspost/compress v1.18.4 // indirect
+	github.com/klauspost/cpuid/v2 v2.2.9 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/maypok86/otter v1.2.4 // indirect
+	github.com/miekg/dns v1.1.45 // indirect
+	github.com/minio/md5-simd v1.1.2 // indirect
+	github.com/minio/minio-go/v7 v7.0.84 // indirect
+	github.com/mschoch/smat v0.2.0 // indirect
+	github.com/nats-io/nats.go v1.39.1 // indirect
+	github.com/nats-io/nkeys v0.4.9 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/nutsdb/nutsdb v1.0.4 // indirect
+	github.com/pierrec/lz4/v4 v4.1.26 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/redis/go-redis/v9 v9.18.0 // indirect
+	github.com/redis/rueidis v1.0.73 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	github.com/rs/xid v1.6.0 // indirect
+	github.com/sean-/seed v0.0.0-20170313163322-e2103e2c3529 // indirect
+	github.com/tidwall/btree v1.6.0 // indirect
+	github.com/tidwall/match v1.1.1 // indirect
+	github.com/tidwall/redcon v1.6.2 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/xujiajun/mmap-go v1.0.1 // indirect
+	github.com/xujiajun/utils v0.0.0-20220904132955-5f7c5b914235 // indirect
+	go.etcd.io/bbolt v1.3.10 // indirect
+	go.etcd.io/etcd/api/v3 v3.5.18 // indirect
+	go.etcd.io/etcd/client/pkg/v3 v3.5.18 // indirect
+	go.etcd.io/etcd/client/v3 v3.5.18 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel v1.37.0 // indirect
+	go.opentelemetry.io/otel/metric v1.37.0 // indirect
+	go.opentelemetry.io/otel/trace v1.37.0 // indirect
+	go.uber.org/atomic v1.11.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.28.0 // indirect
+	golang.org/x/crypto v0.48.0 // indirect
+	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
+	golang.org/x/mod v0.32.0 // indirect
+	golang.org/x/net v0.49.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+	golang.org/x/text v0.34.0 // indirect
+	golang.org/x/tools v0.41.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20241202173237-19429a94021a // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+	google.golang.org/grpc v1.70.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	modernc.org/libc v1.66.10 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
+	modernc.org/sqlite v1.40.0 // indirect
+)
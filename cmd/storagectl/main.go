@@ -0,0 +1,443 @@
+// Command storagectl inspects and maintains any storage from a provider
+// configuration file.
+//
+//	storagectl -config storage.json get <key>
+//	storagectl -config storage.json ls [prefix]
+//	storagectl -config storage.json purge <key>... | -prefix|-glob|-regex <pattern> [-dry-run]
+//	storagectl -config storage.json stats
+//	storagectl -config storage.json dump [file]
+//	storagectl -config storage.json restore [file]
+//
+// The configuration file holds the storage name, its provider and its stale
+// duration:
+//
+//	{"storage": "redis", "stale": "1h", "provider": {"url": "127.0.0.1:6379"}}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const healthTimeout = time.Second
+
+var errUsage = errors.New("usage: storagectl -config <file> get|ls|purge|stats|dump|restore [arguments]")
+
+// configuration is the content of the file given with the -config flag.
+type configuration struct {
+	Storage  string             `json:"storage"`
+	Stale    string             `json:"stale"`
+	Provider core.CacheProvider `json:"provider"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("storagectl", flag.ContinueOnError)
+	path := flags.String("config", "storage.json", "the provider configuration file")
+	verbose := flags.Bool("v", false, "log the storage debug messages")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+
+	level := slog.LevelWarn
+	if *verbose {
+		level = slog.LevelDebug
+	}
+
+	storer, err := openStorer(*path, core.NewSlogLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))))
+	if err != nil {
+		return err
+	}
+
+	command, arguments := flags.Arg(0), flags.Args()[1:]
+
+	switch command {
+	case "get":
+		return get(storer, arguments, stdout)
+	case "ls":
+		return ls(storer, arguments, stdout)
+	case "purge":
+		return purge(storer, arguments, stdout)
+	case "stats":
+		return stats(storer, stdout)
+	case "dump":
+		return dump(storer, arguments, stdout)
+	case "restore":
+		return restore(storer, arguments, stdin, stdout)
+	default:
+		return fmt.Errorf("unknown command %s, %w", command, errUsage)
+	}
+}
+
+// openStorer reads the configuration file and creates its storage.
+func openStorer(path string, logger core.Logger) (core.Storer, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("impossible to read the configuration file: %w", err)
+	}
+
+	var config configuration
+	if err = json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	if config.Storage == "" {
+		return nil, fmt.Errorf("invalid configuration file %s: the storage is required, the registered ones are %v", path, core.RegisteredFactories())
+	}
+
+	var stale time.Duration
+	if config.Stale != "" {
+		if stale, err = time.ParseDuration(config.Stale); err != nil {
+			return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+	}
+
+	storer, err := core.NewStorer(config.Storage, config.Provider, logger, stale)
+	if err != nil {
+		return nil, err
+	}
+
+	return storer, storer.Init()
+}
+
+// get prints the variants of a base key with their metadata and their
+// decompressed responses, the decompressed value of a plain key otherwise.
+func get(storer core.Storer, arguments []string, stdout io.Writer) error {
+	if len(arguments) != 1 {
+		return errors.New("usage: storagectl get <key>")
+	}
+
+	key := strings.TrimPrefix(arguments[0], core.MappingKeyPrefix)
+
+	metadata, err := core.GetMetadata(storer, key)
+	if err != nil {
+		return fmt.Errorf("impossible to decode the mapping of %s: %w", key, err)
+	}
+
+	if len(metadata) == 0 {
+		value := storer.Get(arguments[0])
+		if len(value) == 0 {
+			return fmt.Errorf("no value stored for the key %s", arguments[0])
+		}
+
+		return printValue(stdout, arguments[0], value)
+	}
+
+	now := time.Now()
+
+	for _, m := range metadata {
+		freshness := core.ComputeFreshness(m, now)
+
+		fmt.Fprintf(stdout, "Key: %s\n", m.Key)
+		fmt.Fprintf(stdout, "Real key: %s\n", m.RealKey)
+		fmt.Fprintf(stdout, "State: %s\n", state(m, now))
+		fmt.Fprintf(stdout, "Stored at: %s (age %s)\n", m.StoredAt.Format(time.RFC3339), freshness.Age.Round(time.Second))
+		fmt.Fprintf(stdout, "Fresh until: %s\n", m.FreshUntil.Format(time.RFC3339))
+		fmt.Fprintf(stdout, "Stale until: %s\n", m.StaleUntil.Format(time.RFC3339))
+
+		if ttl, err := core.GetTTL(storer, m.Key); err == nil {
+			fmt.Fprintf(stdout, "TTL: %s\n", ttl.Round(time.Second))
+		}
+
+		if m.Etag != "" {
+			fmt.Fprintf(stdout, "ETag: %s\n", m.Etag)
+		}
+
+		for _, name := range sortedNames(m.VariedHeaders) {
+			fmt.Fprintf(stdout, "Varies on %s: %s\n", name, strings.Join(m.VariedHeaders[name], ", "))
+		}
+
+		fmt.Fprintln(stdout)
+
+		if value := storer.Get(m.Key); len(value) > 0 {
+			if err = printValue(stdout, m.Key, value); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(stdout, "(missing value)")
+		}
+
+		fmt.Fprintln(stdout)
+	}
+
+	return nil
+}
+
+// printValue writes the value decompressed, the mappings are printed as
+// their metadata.
+func printValue(stdout io.Writer, key string, value []byte) error {
+	if strings.HasPrefix(key, core.MappingKeyPrefix) {
+		metadata, err := core.DecodeMetadata(value)
+		if err != nil {
+			return fmt.Errorf("impossible to decode the mapping of %s: %w", key, err)
+		}
+
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(metadata)
+	}
+
+	reader, err := core.DetectCompressor(value).Decompress(value)
+	if err != nil {
+		return fmt.Errorf("impossible to decompress the value: %w", err)
+	}
+
+	_, err = io.Copy(stdout, reader)
+
+	return err
+}
+
+func state(m core.KeyMetadata, now time.Time) string {
+	switch {
+	case m.Fresh(now):
+		return "fresh"
+	case m.Stale(now):
+		return "stale"
+	default:
+		return "expired"
+	}
+}
+
+func sortedNames(headers http.Header) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ls prints the cached keys starting with the optional prefix, the base
+// keys of the stored responses.
+func ls(storer core.Storer, arguments []string, stdout io.Writer) error {
+	if len(arguments) > 1 {
+		return errors.New("usage: storagectl ls [prefix]")
+	}
+
+	prefix := ""
+	if len(arguments) == 1 {
+		prefix = arguments[0]
+	}
+
+	keys := []string{}
+
+	err := core.WalkKeys(storer, func(key string) bool {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return true
+	})
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintln(stdout, key)
+	}
+
+	return err
+}
+
+// purge deletes the given keys with their mapping and their variants, or the
+// keys selected by the prefix, glob or regex matcher.
+func purge(storer core.Storer, arguments []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	prefix := flags.String("prefix", "", "purge the keys starting with the prefix")
+	glob := flags.String("glob", "", "purge the keys matching the glob")
+	regex := flags.String("regex", "", "purge the keys matching the regular expression")
+	dryRun := flags.Bool("dry-run", false, "list the matching keys without purging them")
+
+	if err := flags.Parse(arguments); err != nil {
+		return err
+	}
+
+	var (
+		matcher  core.KeyMatcher
+		matching bool
+	)
+
+	for _, parameter := range []struct {
+		pattern string
+		mode    core.MatchMode
+	}{
+		{pattern: *prefix, mode: core.MatchPrefix},
+		{pattern: *glob, mode: core.MatchGlob},
+		{pattern: *regex, mode: core.MatchRegex},
+	} {
+		if parameter.pattern == "" {
+			continue
+		}
+
+		if matching {
+			return errors.New("only one of the -prefix, -glob and -regex flags can be given")
+		}
+
+		var err error
+		if matcher, err = core.NewKeyMatcher(parameter.mode, parameter.pattern); err != nil {
+			return err
+		}
+
+		matching = true
+	}
+
+	if !matching && flags.NArg() == 0 {
+		return errors.New("usage: storagectl purge <key>... | -prefix|-glob|-regex <pattern> [-dry-run]")
+	}
+
+	if *dryRun && !matching {
+		return errors.New("the -dry-run flag requires the -prefix, -glob or -regex one")
+	}
+
+	if matching {
+		result, err := core.DeleteMatching(storer, matcher, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range result.Keys {
+			fmt.Fprintln(stdout, key)
+		}
+
+		verb := "purged"
+		if *dryRun {
+			verb = "matched"
+		}
+
+		fmt.Fprintf(stdout, "%d keys %s\n", result.Count, verb)
+	}
+
+	for _, key := range flags.Args() {
+		if metadata, err := core.GetMetadata(storer, key); err == nil {
+			for _, m := range metadata {
+				storer.Delete(m.Key)
+			}
+		}
+
+		storer.Delete(core.MappingKeyPrefix + key)
+		storer.Delete(key)
+	}
+
+	return nil
+}
+
+type statsOutput struct {
+	Name    string            `json:"name"`
+	Uuid    string            `json:"uuid"`
+	Keys    int               `json:"keys"`
+	Healthy bool              `json:"healthy"`
+	Error   string            `json:"error,omitempty"`
+	Storage *core.StorerStats `json:"storage,omitempty"`
+}
+
+// stats prints the storage name, its keys count, its health and its stats
+// when reported, like the admin API.
+func stats(storer core.Storer, stdout io.Writer) error {
+	output := statsOutput{Name: storer.Name(), Uuid: storer.Uuid(), Healthy: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+
+	if err := core.Healthy(ctx, storer); err != nil {
+		output.Healthy = false
+		output.Error = err.Error()
+	}
+
+	_ = core.WalkKeys(storer, func(string) bool {
+		output.Keys++
+
+		return true
+	})
+
+	if storage, found := core.Stats(storer); found {
+		output.Storage = &storage
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(output)
+}
+
+// dump writes the entries as a core.Dump export to the file, to stdout when
+// omitted.
+func dump(storer core.Storer, arguments []string, stdout io.Writer) error {
+	if len(arguments) > 1 {
+		return errors.New("usage: storagectl dump [file]")
+	}
+
+	if len(arguments) == 0 {
+		_, err := core.Dump(storer, stdout)
+
+		return err
+	}
+
+	file, err := os.Create(arguments[0])
+	if err != nil {
+		return err
+	}
+
+	count, err := core.Dump(storer, file)
+	if err = errors.Join(err, file.Close()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d entries dumped to %s\n", count, arguments[0])
+
+	return nil
+}
+
+// restore stores the entries of a core.Dump export read from the file, from
+// stdin when omitted.
+func restore(storer core.Storer, arguments []string, stdin io.Reader, stdout io.Writer) error {
+	if len(arguments) > 1 {
+		return errors.New("usage: storagectl restore [file]")
+	}
+
+	source := stdin
+
+	if len(arguments) == 1 {
+		file, err := os.Open(arguments[0])
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		source = file
+	}
+
+	count, err := core.Warmup(storer, source)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d entries restored\n", count)
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+type nopLogger struct {
+	core.Logger
+}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+func (nopLogger) Debug(...interface{})          {}
+func (nopLogger) Info(...interface{})           {}
+func (nopLogger) Warn(...interface{})           {}
+func (nopLogger) Error(...interface{})          {}
+
+func newStorer(t *testing.T) core.Storer {
+	t.Helper()
+
+	storer, err := core.NewStorer("otter", core.CacheProvider{}, nopLogger{}, time.Hour)
+	if err != nil {
+		t.Fatalf("The otter storage should be created, %v given", err)
+	}
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	if err = storer.SetMultiLevel("GET-domain.com-/", "GET-domain.com-/-fr", value, http.Header{"Accept-Language": []string{"fr"}}, `"etag"`, time.Minute, "GET-domain.com-/"); err != nil {
+		t.Fatalf("The response should be stored, %v given", err)
+	}
+
+	_ = storer.Set("plain", []byte("plain value"), time.Minute)
+
+	return storer
+}
+
+func TestGet(t *testing.T) {
+	storer := newStorer(t)
+	output := &bytes.Buffer{}
+
+	if err := get(storer, []string{"GET-domain.com-/"}, output); err != nil {
+		t.Fatalf("The key should be printed, %v given", err)
+	}
+
+	for _, expected := range []string{"Key: GET-domain.com-/-fr", "State: fresh", `ETag: "etag"`, "Varies on Accept-Language: fr", "Hello"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("The output should contain %s, %s given", expected, output.String())
+		}
+	}
+
+	output.Reset()
+
+	if err := get(storer, []string{"plain"}, output); err != nil || output.String() != "plain value" {
+		t.Errorf("The plain value should be printed, %s and %v given", output.String(), err)
+	}
+
+	if err := get(storer, []string{"missing"}, output); err == nil {
+		t.Error("A missing key should return an error")
+	}
+}
+
+func TestLsAndPurge(t *testing.T) {
+	storer := newStorer(t)
+	output := &bytes.Buffer{}
+
+	if err := ls(storer, []string{"GET-"}, output); err != nil || output.String() != "GET-domain.com-/\n" {
+		t.Errorf("The keys starting with the prefix should be listed, %q and %v given", output.String(), err)
+	}
+
+	output.Reset()
+
+	if err := purge(storer, []string{"-prefix", "pla", "-dry-run"}, output); err != nil || !strings.Contains(output.String(), "1 keys matched") || storer.Get("plain") == nil {
+		t.Errorf("The dry-run should list the matching keys only, %q and %v given", output.String(), err)
+	}
+
+	if err := purge(storer, []string{"GET-domain.com-/"}, output); err != nil {
+		t.Fatalf("The key should be purged, %v given", err)
+	}
+
+	if storer.Get("GET-domain.com-/-fr") != nil || storer.Get(core.MappingKeyPrefix+"GET-domain.com-/") != nil {
+		t.Error("The mapping and its variants should be purged")
+	}
+
+	if err := purge(storer, []string{"-prefix", "a", "-regex", "b"}, output); err == nil {
+		t.Error("Several matchers should be rejected")
+	}
+}
+
+func TestDumpAndRestore(t *testing.T) {
+	storer := newStorer(t)
+	path := filepath.Join(t.TempDir(), "dump.ndjson")
+	output := &bytes.Buffer{}
+
+	if err := dump(storer, []string{path}, output); err != nil {
+		t.Fatalf("The entries should be dumped, %v given", err)
+	}
+
+	target, _ := core.NewStorer("otter", core.CacheProvider{}, nopLogger{}, time.Hour)
+	if err := restore(target, []string{path}, nil, output); err != nil {
+		t.Fatalf("The entries should be restored, %v given", err)
+	}
+
+	if fresh, _ := target.GetMultiLevel("GET-domain.com-/", newRequest("fr"), &core.Revalidator{}); fresh == nil {
+		t.Error("The restored response should be elected")
+	}
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	_ = os.WriteFile(path, []byte(`{"storage": "otter", "stale": "1h"}`), 0o600)
+	output := &bytes.Buffer{}
+
+	if err := run([]string{"-config", path, "stats"}, nil, output); err != nil || !strings.Contains(output.String(), `"healthy": true`) {
+		t.Errorf("The stats should be printed, %s and %v given", output.String(), err)
+	}
+
+	if err := run([]string{"-config", path, "unknown"}, nil, output); err == nil {
+		t.Error("An unknown command should be rejected")
+	}
+
+	_ = os.WriteFile(path, []byte(`{"stale": "1h"}`), 0o600)
+
+	if err := run([]string{"-config", path, "stats"}, nil, output); err == nil {
+		t.Error("A configuration without storage should be rejected")
+	}
+}
+
+func newRequest(language string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	req.Header.Set("Accept-Language", language)
+
+	return req
+}
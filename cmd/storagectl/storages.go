@@ -0,0 +1,26 @@
+package main
+
+// The storages registered in the core registry, resolved by the storage
+// name of the configuration file.
+import (
+	_ "github.com/darkweak/storages/badger"
+	_ "github.com/darkweak/storages/bolt"
+	_ "github.com/darkweak/storages/etcd"
+	_ "github.com/darkweak/storages/fs"
+	_ "github.com/darkweak/storages/go-redis"
+	_ "github.com/darkweak/storages/memcached"
+	_ "github.com/darkweak/storages/nats"
+	_ "github.com/darkweak/storages/nuts"
+	_ "github.com/darkweak/storages/olric"
+	_ "github.com/darkweak/storages/otter"
+	_ "github.com/darkweak/storages/peers"
+	_ "github.com/darkweak/storages/postgres"
+	_ "github.com/darkweak/storages/redis"
+	_ "github.com/darkweak/storages/remote"
+	_ "github.com/darkweak/storages/rest"
+	_ "github.com/darkweak/storages/ristretto"
+	_ "github.com/darkweak/storages/s3"
+	_ "github.com/darkweak/storages/shardedmap"
+	_ "github.com/darkweak/storages/simplefs"
+	_ "github.com/darkweak/storages/sqlite"
+)
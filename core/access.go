@@ -0,0 +1,325 @@
+package core
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// AccessTrackingConfigurationKey is the key read from the provider
+	// configuration to count the hits and the misses of the keys in their
+	// mapping.
+	AccessTrackingConfigurationKey = "access_tracking"
+
+	defaultAccessFlushInterval = 30 * time.Second
+	// maxPendingAccesses bounds the mappings whose accesses wait for the next
+	// flush, the accesses to the other ones are dropped meanwhile.
+	maxPendingAccesses = 10000
+)
+
+// pendingAccesses are the accesses to a mapping counted since the last
+// flush.
+type pendingAccesses struct {
+	storer     Storer
+	storerID   string
+	mappingKey string
+	hits       map[string]uint64
+	lastAccess map[string]time.Time
+	misses     uint64
+}
+
+// trackedStorer is the flush loop of a storer whose accesses are tracked.
+type trackedStorer struct {
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// accessTracker buffers the accesses in memory and adds them to the mappings
+// of each tracked storer every interval, so a read never writes its mapping.
+type accessTracker struct {
+	mu      sync.Mutex
+	pending map[string]*pendingAccesses
+	storers map[string]*trackedStorer
+}
+
+var accesses = &accessTracker{pending: map[string]*pendingAccesses{}, storers: map[string]*trackedStorer{}}
+
+func accessStorerID(storer Storer) string {
+	return storer.Name() + "-" + storer.Uuid()
+}
+
+// SetAccessTracking counts the hits and the misses of the mappings of the
+// storer elected by MappingElectionFor and adds them to the mappings every
+// interval. Zero stops the tracking of the storer once its pending accesses
+// are flushed.
+func SetAccessTracking(storer Storer, interval time.Duration) {
+	id := accessStorerID(storer)
+
+	accesses.mu.Lock()
+	previous := accesses.storers[id]
+	delete(accesses.storers, id)
+
+	if interval > 0 {
+		tracked := &trackedStorer{interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+		accesses.storers[id] = tracked
+
+		go accesses.run(id, tracked)
+	}
+	accesses.mu.Unlock()
+
+	if previous != nil {
+		close(previous.stop)
+		<-previous.done
+	}
+
+	if interval <= 0 {
+		_ = accesses.flush(id)
+	}
+}
+
+// accessTrackingConfiguration is the typed access_tracking key.
+type accessTrackingConfiguration struct {
+	// FlushInterval is the delay between two writes of the counted accesses
+	// to the mappings, 30s by default.
+	FlushInterval time.Duration `json:"flush_interval"`
+}
+
+// SetAccessTrackingFromConfiguration applies the access_tracking key of the
+// provider configuration to the storer, if any.
+func SetAccessTrackingFromConfiguration(storer Storer, provider CacheProvider) error {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	value, ok := cfg[AccessTrackingConfigurationKey]
+	if !ok {
+		return nil
+	}
+
+	tracking := accessTrackingConfiguration{FlushInterval: defaultAccessFlushInterval}
+
+	if enabled, isBool := value.(bool); isBool {
+		if !enabled {
+			return nil
+		}
+	} else if err := DecodeConfiguration(value, &tracking); err != nil {
+		return fmt.Errorf("invalid access_tracking configuration: %w", err)
+	}
+
+	if tracking.FlushInterval <= 0 {
+		return fmt.Errorf("invalid access_tracking configuration: the flush_interval must be positive, %s given", tracking.FlushInterval)
+	}
+
+	SetAccessTracking(storer, tracking.FlushInterval)
+
+	return nil
+}
+
+func (t *accessTracker) run(id string, tracked *trackedStorer) {
+	defer close(tracked.done)
+
+	ticker := time.NewTicker(tracked.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tracked.stop:
+			_ = t.flush(id)
+
+			return
+		case <-ticker.C:
+			_ = t.flush(id)
+		}
+	}
+}
+
+// record counts the access to the varied key of the mapping at now, a miss
+// when the varied key is empty.
+func (t *accessTracker) record(storer Storer, mappingKey, variedKey string, now time.Time) {
+	storerID := accessStorerID(storer)
+	id := storerID + "-" + mappingKey
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, tracked := t.storers[storerID]; !tracked {
+		return
+	}
+
+	pending, found := t.pending[id]
+	if !found {
+		if len(t.pending) >= maxPendingAccesses {
+			return
+		}
+
+		pending = &pendingAccesses{storer: storer, storerID: storerID, mappingKey: mappingKey, hits: map[string]uint64{}, lastAccess: map[string]time.Time{}}
+		t.pending[id] = pending
+	}
+
+	if variedKey == "" {
+		pending.misses++
+
+		return
+	}
+
+	pending.hits[variedKey]++
+	pending.lastAccess[variedKey] = now
+}
+
+// recordElection counts the outcome of the election of the mapping, the
+// fresh, the not modified or the stale key is hit, the mapping misses when
+// none is elected.
+func recordElection(storer Storer, mappingKey string, item []byte, fresh, stale *http.Response, validator *Revalidator) {
+	if len(item) == 0 || validator == nil {
+		return
+	}
+
+	variedKey := ""
+
+	switch {
+	case fresh != nil:
+		variedKey = validator.FreshKey
+	case validator.NotModified:
+		variedKey = validator.NotModifiedKey
+	case stale != nil:
+		variedKey = validator.StaleKey
+	}
+
+	accesses.record(storer, mappingKey, variedKey, time.Now())
+}
+
+// FlushAccesses adds the accesses counted since the last flush to their
+// mapping. The accesses to the expired mappings and the varied keys no longer
+// referenced are dropped.
+func FlushAccesses() error {
+	return accesses.flush("")
+}
+
+// flush adds the pending accesses of the storer to their mapping, those of
+// every storer when the id is empty.
+func (t *accessTracker) flush(id string) error {
+	pending := []*pendingAccesses{}
+
+	t.mu.Lock()
+	for key, p := range t.pending {
+		if id == "" || p.storerID == id {
+			pending = append(pending, p)
+			delete(t.pending, key)
+		}
+	}
+	t.mu.Unlock()
+
+	errs := []error{}
+
+	for _, p := range pending {
+		errs = append(errs, UpdateMapping(func() error {
+			return flushAccesses(p)
+		}))
+	}
+
+	return errors.Join(errs...)
+}
+
+func flushAccesses(p *pendingAccesses) error {
+	item, err := Lookup(p.storer, p.mappingKey)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	mapping, err := DecodeMapping(item)
+	if err != nil {
+		return fmt.Errorf("impossible to decode the mapping %s: %w", p.mappingKey, err)
+	}
+
+	now := time.Now()
+	if !mappingStaleUntil(mapping, now).After(now) {
+		return nil
+	}
+
+	for key, index := range mapping.GetMapping() {
+		if hits, found := p.hits[key]; found {
+			index.Hits += hits
+
+			if lastAccess := p.lastAccess[key]; index.GetLastAccess() == nil || lastAccess.After(index.GetLastAccess().AsTime()) {
+				index.LastAccess = timestamppb.New(lastAccess)
+			}
+		}
+	}
+
+	mapping.Misses += p.misses
+	mapping.Version++
+
+	value, err := EncodeMapping(mapping)
+	if err != nil {
+		return err
+	}
+
+	// The mapping keeps its lifetime, the furthest stale time of its entries
+	// when the storer can't tell it.
+	ttl, err := GetTTL(p.storer, p.mappingKey)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		ttl = mappingStaleUntil(mapping, now).Sub(now)
+	}
+
+	if err = swapMapping(p.storer, p.mappingKey, item, value, ttl); err != nil && !errors.Is(err, ErrMappingConflict) {
+		return fmt.Errorf("impossible to store the accesses of the mapping %s: %w", p.mappingKey, err)
+	}
+
+	return err
+}
+
+// PurgeLeastUsed deletes the count varied keys with the fewest hits, the
+// least recently accessed first among the equal ones. Their entry stays in
+// the mapping until it expires, the election skips it meanwhile. In dry-run
+// nothing is deleted and the keys are listed in the result.
+func PurgeLeastUsed(storer Storer, count int, dryRun bool) (DeleteManyResult, error) {
+	result := DeleteManyResult{}
+	if count <= 0 {
+		return result, nil
+	}
+
+	candidates := []KeyMetadata{}
+
+	err := walkMappings(storer, func(_ string, item []byte) bool {
+		metadata, decodeErr := DecodeMetadata(item)
+		if decodeErr == nil {
+			candidates = append(candidates, metadata...)
+		}
+
+		return true
+	})
+	if err != nil {
+		return result, err
+	}
+
+	slices.SortFunc(candidates, func(a, b KeyMetadata) int {
+		return cmp.Or(cmp.Compare(a.Hits, b.Hits), a.LastAccess.Compare(b.LastAccess), cmp.Compare(a.Key, b.Key))
+	})
+
+	for _, candidate := range candidates[:min(count, len(candidates))] {
+		if !dryRun {
+			storer.Delete(candidate.Key)
+		}
+
+		result.Add(candidate.Key, dryRun)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package core_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestAccessTracking(t *testing.T) {
+	memory := newMemoryStorer()
+
+	core.SetAccessTracking(memory, time.Hour)
+	defer core.SetAccessTracking(memory, 0)
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	mappingKey := core.MappingKeyPrefix + "base"
+
+	_ = memory.SetMultiLevel("base", "hot", value, nil, "", time.Hour, "hot")
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+
+	for range 3 {
+		fresh, _, _ := core.MappingElectionFor(memory, mappingKey, memory.Get(mappingKey), req, &core.Revalidator{}, nopLogger{})
+		if fresh == nil {
+			t.Fatal("the stored response should be elected")
+		}
+	}
+
+	memory.Delete("hot")
+	_, _, _ = core.MappingElectionFor(memory, mappingKey, memory.Get(mappingKey), req, &core.Revalidator{}, nopLogger{})
+
+	if metadata, _ := core.GetMetadata(memory, "base"); metadata[0].Hits != 0 {
+		t.Errorf("the accesses shouldn't be written before the flush, %+v given", metadata)
+	}
+
+	if err := core.FlushAccesses(); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, _ := core.GetMetadata(memory, "base")
+	if metadata[0].Hits != 3 || metadata[0].LastAccess.IsZero() {
+		t.Errorf("the hits should be added to the mapping, %+v given", metadata)
+	}
+
+	if mapping, _ := core.DecodeMapping(memory.Get(mappingKey)); mapping.GetMisses() != 1 {
+		t.Errorf("the miss should be added to the mapping, %d given", mapping.GetMisses())
+	}
+
+	_ = memory.SetMultiLevel("base", "hot", value, nil, "", time.Hour, "hot")
+	_ = memory.SetMultiLevel("base", "cold", value, nil, "", time.Hour, "cold")
+
+	if metadata, _ = core.GetMetadata(memory, "base"); metadata[1].Key != "hot" || metadata[1].Hits != 3 {
+		t.Errorf("the hits should be kept when the key is stored again, %+v given", metadata)
+	}
+
+	result, err := core.PurgeLeastUsed(memory, 1, true)
+	if err != nil || result.Count != 1 || result.Keys[0] != "cold" {
+		t.Errorf("the least used key should be selected, %+v and %v given", result, err)
+	}
+
+	if _, err = core.PurgeLeastUsed(memory, 1, false); err != nil || memory.Get("cold") != nil || memory.Get("hot") == nil {
+		t.Errorf("only the least used key should be purged, %v given", err)
+	}
+}
+
+func TestSetAccessTrackingFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+	defer core.SetAccessTracking(memory, 0)
+
+	for _, value := range []any{true, map[string]interface{}{"flush_interval": "10s"}} {
+		if err := core.SetAccessTrackingFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+			"access_tracking": value,
+		}}); err != nil {
+			t.Errorf("the access tracking %v should be enabled, %v given", value, err)
+		}
+	}
+
+	if err := core.SetAccessTrackingFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"access_tracking": map[string]interface{}{"flush_interval": "-1s"},
+	}}); err == nil {
+		t.Error("a negative flush interval should be rejected")
+	}
+}
+
+// trackedStorer is a ttlStorer with its own identity recording the TTLs it
+// stores.
+type trackedStorer struct {
+	ttlStorer
+	uuid string
+}
+
+func (s trackedStorer) Uuid() string {
+	return s.uuid
+}
+
+func (s trackedStorer) Set(key string, value []byte, ttl time.Duration) error {
+	s.ttls[key] = ttl
+
+	return s.memoryStorer.Set(key, value, ttl)
+}
+
+func TestAccessTracking_PerStorer(t *testing.T) {
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	mappingKey := core.MappingKeyPrefix + "base"
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+
+	tracked := trackedStorer{ttlStorer{newMemoryStorer(), map[string]time.Duration{}}, "tracked"}
+	untracked := trackedStorer{ttlStorer{newMemoryStorer(), map[string]time.Duration{}}, "untracked"}
+
+	core.SetAccessTracking(tracked, time.Hour)
+	defer core.SetAccessTracking(tracked, 0)
+
+	for _, storer := range []trackedStorer{tracked, untracked} {
+		_ = storer.SetMultiLevel("base", "key", value, nil, "", time.Hour, "key")
+		storer.ttls[mappingKey] = 2 * time.Hour
+
+		_, _, _ = core.MappingElectionFor(storer, mappingKey, storer.Get(mappingKey), req, &core.Revalidator{}, nopLogger{})
+	}
+
+	if err := core.FlushAccesses(); err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata, _ := core.GetMetadata(tracked, "base"); metadata[0].Hits != 1 {
+		t.Errorf("the accesses to the tracked storer should be counted, %+v given", metadata)
+	}
+
+	if metadata, _ := core.GetMetadata(untracked, "base"); metadata[0].Hits != 0 {
+		t.Errorf("the accesses to the untracked storer shouldn't be counted, %+v given", metadata)
+	}
+
+	if ttl := tracked.ttls[mappingKey]; ttl != 2*time.Hour {
+		t.Errorf("the mapping should keep its TTL, %s given", ttl)
+	}
+}
@@ -0,0 +1,459 @@
+// Package admin exposes an HTTP API to inspect and purge any storer.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// DeletedHeader holds the number of keys purged by a regex.
+const DeletedHeader = "Storages-Deleted"
+
+const (
+	defaultLimit         = 100
+	defaultHealthTimeout = time.Second
+)
+
+// ErrUnauthorized is returned by the BearerToken authorization when the
+// request doesn't hold the expected token.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Options tunes the admin Handler.
+type Options struct {
+	// Authorize is called before each request, the request is rejected with
+	// a 401 status when it returns an error. Every request is allowed when
+	// nil.
+	Authorize func(r *http.Request) error
+	// DefaultLimit is the page size used when the limit query parameter is
+	// omitted, 100 by default.
+	DefaultLimit int
+	// HealthTimeout bounds the health check run by the stats endpoint, 1s by
+	// default.
+	HealthTimeout time.Duration
+}
+
+// BearerToken returns an Authorize hook accepting the requests sending the
+// token in their Authorization header.
+func BearerToken(token string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		given, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return ErrUnauthorized
+		}
+
+		return nil
+	}
+}
+
+// Handler serves the admin API of a storer:
+//   - GET /keys?cursor=&limit= lists the keys page by page.
+//   - GET /metadata?key= returns the metadata of the varied keys of a base key.
+//   - DELETE /keys?key= purges the keys and their varied keys.
+//   - DELETE /keys?regex= purges the keys matching the regular expression,
+//     prefix= the keys starting with the prefix and glob= the keys matching
+//     the glob, see core.KeyMatcher. Their count is sent in the
+//     DeletedHeader. With dry_run=true the matching keys are returned as a
+//     core.DeleteManyResult, nothing is purged.
+//   - DELETE /tags?tag= purges the keys tagged with the surrogate keys.
+//   - GET /stats returns the storer name, its keys count, its health and the
+//     storage stats when reported.
+//   - GET /analytics returns the size histogram with the largest and the
+//     hottest keys when the storer tracks them, see core.AnalyticsStorer.
+//   - GET /dump streams the entries as ndjson, see core.Dump.
+//   - POST /warmup stores the entries of a dump sent as the request body.
+//
+// Mount it under a prefix with http.StripPrefix.
+type Handler struct {
+	storer  core.Storer
+	options Options
+	mux     *http.ServeMux
+}
+
+// NewHandler creates the admin Handler of the storer.
+func NewHandler(storer core.Storer, options Options) *Handler {
+	if options.DefaultLimit <= 0 {
+		options.DefaultLimit = defaultLimit
+	}
+
+	if options.HealthTimeout <= 0 {
+		options.HealthTimeout = defaultHealthTimeout
+	}
+
+	handler := &Handler{storer: storer, options: options, mux: http.NewServeMux()}
+
+	handler.mux.HandleFunc("GET /keys", handler.listKeys)
+	handler.mux.HandleFunc("DELETE /keys", handler.purgeKeys)
+	handler.mux.HandleFunc("GET /metadata", handler.metadata)
+	handler.mux.HandleFunc("DELETE /tags", handler.purgeTags)
+	handler.mux.HandleFunc("GET /stats", handler.stats)
+	handler.mux.HandleFunc("GET /analytics", handler.analytics)
+	handler.mux.HandleFunc("GET /dump", handler.dump)
+	handler.mux.HandleFunc("POST /warmup", handler.warmup)
+
+	return handler
+}
+
+// ServeHTTP authorizes then routes the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.options.Authorize != nil {
+		if err := h.options.Authorize(r); err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+
+			return
+		}
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+type keysResponse struct {
+	Keys []string `json:"keys"`
+	Next string   `json:"next,omitempty"`
+}
+
+func (h *Handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	limit := h.options.DefaultLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("the limit must be a positive integer"))
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	keys, next := core.ListKeysPaginated(h.storer, r.URL.Query().Get("cursor"), limit)
+	if keys == nil {
+		keys = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, keysResponse{Keys: keys, Next: next})
+}
+
+type metadataResponse struct {
+	Key           string      `json:"key"`
+	RealKey       string      `json:"real_key"`
+	StoredAt      time.Time   `json:"stored_at"`
+	FreshUntil    time.Time   `json:"fresh_until"`
+	StaleUntil    time.Time   `json:"stale_until"`
+	Etag          string      `json:"etag,omitempty"`
+	VariedHeaders http.Header `json:"varied_headers,omitempty"`
+	State         string      `json:"state"`
+	// Hits and LastAccess are counted when the access tracking is enabled.
+	Hits       uint64     `json:"hits"`
+	LastAccess *time.Time `json:"last_access,omitempty"`
+	// TTL is the remaining lifetime of the stored variant, 0s when it never
+	// expires. It's omitted when the storer can't tell.
+	TTL string `json:"ttl,omitempty"`
+}
+
+func (h *Handler) metadata(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, errors.New("the key query parameter is required"))
+
+		return
+	}
+
+	metadata, err := core.GetMetadata(h.storer, key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if len(metadata) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("no mapping stored for the key "+key))
+
+		return
+	}
+
+	now := time.Now()
+	response := make([]metadataResponse, 0, len(metadata))
+
+	for _, m := range metadata {
+		state := "expired"
+
+		switch {
+		case m.Fresh(now):
+			state = "fresh"
+		case m.Stale(now):
+			state = "stale"
+		}
+
+		item := metadataResponse{
+			Key:           m.Key,
+			RealKey:       m.RealKey,
+			StoredAt:      m.StoredAt,
+			FreshUntil:    m.FreshUntil,
+			StaleUntil:    m.StaleUntil,
+			Etag:          m.Etag,
+			VariedHeaders: m.VariedHeaders,
+			State:         state,
+			Hits:          m.Hits,
+		}
+
+		if !m.LastAccess.IsZero() {
+			item.LastAccess = &m.LastAccess
+		}
+
+		if ttl, err := core.GetTTL(h.storer, m.Key); err == nil {
+			item.TTL = ttl.String()
+		}
+
+		response = append(response, item)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// purgeKey deletes the key, its mapping and the varied keys it references.
+func (h *Handler) purgeKey(key string) {
+	if metadata, err := core.GetMetadata(h.storer, key); err == nil {
+		for _, m := range metadata {
+			h.storer.Delete(m.Key)
+		}
+	}
+
+	h.storer.Delete(core.MappingKeyPrefix + key)
+	h.storer.Delete(key)
+}
+
+func (h *Handler) purgeKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	matcher, matching, err := matcherFromQuery(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if !matching && len(query["key"]) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("the key, prefix, glob or regex query parameter is required"))
+
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+	if dryRun && !matching {
+		writeError(w, http.StatusBadRequest, errors.New("the dry_run query parameter requires the prefix, glob or regex one"))
+
+		return
+	}
+
+	if soft, _ := strconv.ParseBool(query.Get("soft")); soft {
+		if dryRun {
+			writeError(w, http.StatusBadRequest, errors.New("the dry_run query parameter can't be combined with the soft one"))
+
+			return
+		}
+
+		h.softPurgeKeys(w, query["key"], matcher, matching)
+
+		return
+	}
+
+	if matching {
+		result, err := core.DeleteMatching(h.storer, matcher, dryRun)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+
+			return
+		}
+
+		if dryRun {
+			writeJSON(w, http.StatusOK, result)
+
+			return
+		}
+
+		w.Header().Set(DeletedHeader, strconv.Itoa(result.Count))
+	}
+
+	for _, key := range query["key"] {
+		h.purgeKey(key)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// softPurgeKeys marks stale the responses of the keys and the ones selected
+// by the matcher, instead of deleting them.
+func (h *Handler) softPurgeKeys(w http.ResponseWriter, keys []string, matcher core.KeyMatcher, matching bool) {
+	purged := 0
+
+	if matching {
+		result, err := core.SoftDeleteMany(h.storer, matcher)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+
+			return
+		}
+
+		purged += result.Count
+	}
+
+	for _, key := range keys {
+		result, err := core.SoftDelete(h.storer, key)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+
+			return
+		}
+
+		purged += result.Count
+	}
+
+	w.Header().Set(DeletedHeader, strconv.Itoa(purged))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// matcherFromQuery builds the key matcher of the prefix, glob or regex query
+// parameter, at most one of them can be given.
+func matcherFromQuery(query url.Values) (core.KeyMatcher, bool, error) {
+	var (
+		matcher core.KeyMatcher
+		found   bool
+	)
+
+	for _, parameter := range []struct {
+		name string
+		mode core.MatchMode
+	}{
+		{name: "prefix", mode: core.MatchPrefix},
+		{name: "glob", mode: core.MatchGlob},
+		{name: "regex", mode: core.MatchRegex},
+	} {
+		pattern := query.Get(parameter.name)
+		if pattern == "" {
+			continue
+		}
+
+		if found {
+			return matcher, false, errors.New("only one of the prefix, glob and regex query parameters can be given")
+		}
+
+		var err error
+		if matcher, err = core.NewKeyMatcher(parameter.mode, pattern); err != nil {
+			return matcher, false, err
+		}
+
+		found = true
+	}
+
+	return matcher, found, nil
+}
+
+// purgeTags purges the keys listed by the surrogate keys, stored as comma
+// separated escaped keys.
+func (h *Handler) purgeTags(w http.ResponseWriter, r *http.Request) {
+	tags := r.URL.Query()["tag"]
+	if len(tags) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("the tag query parameter is required"))
+
+		return
+	}
+
+	for _, tag := range tags {
+		for _, key := range core.SurrogateKeys(h.storer, tag) {
+			h.purgeKey(key)
+		}
+
+		h.storer.Delete(core.SurrogateKeyPrefix + tag)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type statsResponse struct {
+	Name    string `json:"name"`
+	Uuid    string `json:"uuid"`
+	Keys    int    `json:"keys"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	// Storage is filled when the storer reports its stats.
+	Storage *core.StorerStats `json:"storage,omitempty"`
+}
+
+func (h *Handler) stats(w http.ResponseWriter, r *http.Request) {
+	response := statsResponse{Name: h.storer.Name(), Uuid: h.storer.Uuid(), Healthy: true}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.options.HealthTimeout)
+	defer cancel()
+
+	if err := core.Healthy(ctx, h.storer); err != nil {
+		response.Healthy = false
+		response.Error = err.Error()
+	}
+
+	_ = core.WalkKeys(h.storer, func(string) bool {
+		response.Keys++
+
+		return true
+	})
+
+	if stats, found := core.Stats(h.storer); found {
+		response.Storage = &stats
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) analytics(w http.ResponseWriter, _ *http.Request) {
+	analytics, found := core.Analytics(h.storer)
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("the analytics are disabled, set the analytics configuration"))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, analytics)
+}
+
+// dump streams the export, an error can't be reported once the first entry
+// is written so the response is truncated.
+func (h *Handler) dump(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	_, _ = core.Dump(h.storer, w)
+}
+
+type warmupResponse struct {
+	Entries int    `json:"entries"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (h *Handler) warmup(w http.ResponseWriter, r *http.Request) {
+	count, err := core.Warmup(h.storer, r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, warmupResponse{Entries: count, Error: err.Error()})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, warmupResponse{Entries: count})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
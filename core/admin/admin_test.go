@@ -0,0 +1,360 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/admin"
+)
+
+type nopLogger struct {
+	core.Logger
+}
+
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// memoryStorer is a minimal map based Storer.
+type memoryStorer struct {
+	core.Storer
+
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+func newMemoryStorer() *memoryStorer {
+	return &memoryStorer{values: map[string][]byte{}}
+}
+
+func (m *memoryStorer) Name() string {
+	return "MEMORY"
+}
+
+func (m *memoryStorer) Uuid() string {
+	return "memory"
+}
+
+func (m *memoryStorer) ListKeys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := []string{}
+
+	for key, value := range m.values {
+		if strings.HasPrefix(key, core.MappingKeyPrefix) {
+			keys = append(keys, core.MappingRealKeys(value, time.Now())...)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (m *memoryStorer) MapKeys(prefix string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := map[string]string{}
+
+	for key, value := range m.values {
+		if k, found := strings.CutPrefix(key, prefix); found {
+			keys[k] = string(value)
+		}
+	}
+
+	return keys
+}
+
+func (m *memoryStorer) Get(key string) []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.values[key]
+}
+
+func (m *memoryStorer) Set(key string, value []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = value
+
+	return nil
+}
+
+func (m *memoryStorer) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+}
+
+func (m *memoryStorer) DeleteMany(key string) {
+	rgKey := regexp.MustCompile(key)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.values {
+		if rgKey.MatchString(k) {
+			delete(m.values, k)
+		}
+	}
+}
+
+func (m *memoryStorer) store(baseKey string, variedKeys ...string) {
+	now := time.Now()
+
+	var mapping []byte
+
+	for _, variedKey := range variedKeys {
+		mapping, _ = core.MappingUpdater(variedKey, mapping, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", variedKey)
+		_ = m.Set(variedKey, []byte("value"), time.Minute)
+	}
+
+	_ = m.Set(core.MappingKeyPrefix+baseKey, mapping, time.Hour)
+}
+
+func serve(t *testing.T, handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func TestHandler_ListKeys(t *testing.T) {
+	storer := newMemoryStorer()
+	storer.store("first", "first-a", "first-b")
+	storer.store("second", "second-a")
+
+	handler := admin.NewHandler(storer, admin.Options{})
+
+	var page struct {
+		Keys []string `json:"keys"`
+		Next string   `json:"next"`
+	}
+
+	res := serve(t, handler, http.MethodGet, "/keys?limit=2")
+	_ = json.NewDecoder(res.Body).Decode(&page)
+
+	if res.Code != http.StatusOK || len(page.Keys) != 2 || page.Next != "first-b" {
+		t.Fatalf("The first page should hold 2 keys, %d %+v given", res.Code, page)
+	}
+
+	res = serve(t, handler, http.MethodGet, "/keys?limit=2&cursor="+page.Next)
+	page.Next = ""
+	_ = json.NewDecoder(res.Body).Decode(&page)
+
+	if len(page.Keys) != 1 || page.Keys[0] != "second-a" || page.Next != "" {
+		t.Errorf("The last page should hold the remaining key, %+v given", page)
+	}
+
+	if res = serve(t, handler, http.MethodGet, "/keys?limit=zero"); res.Code != http.StatusBadRequest {
+		t.Errorf("An invalid limit should be rejected, %d given", res.Code)
+	}
+}
+
+func TestHandler_Metadata(t *testing.T) {
+	storer := newMemoryStorer()
+	storer.store("base", "base-varied")
+
+	handler := admin.NewHandler(storer, admin.Options{})
+
+	var metadata []struct {
+		Key   string `json:"key"`
+		State string `json:"state"`
+	}
+
+	res := serve(t, handler, http.MethodGet, "/metadata?key=base")
+	_ = json.NewDecoder(res.Body).Decode(&metadata)
+
+	if res.Code != http.StatusOK || len(metadata) != 1 || metadata[0].Key != "base-varied" || metadata[0].State != "fresh" {
+		t.Errorf("The varied key metadata should be returned, %d %+v given", res.Code, metadata)
+	}
+
+	if res = serve(t, handler, http.MethodGet, "/metadata?key=missing"); res.Code != http.StatusNotFound {
+		t.Errorf("A missing mapping should return a 404, %d given", res.Code)
+	}
+}
+
+func TestHandler_Purge(t *testing.T) {
+	storer := newMemoryStorer()
+	storer.store("first", "first-varied")
+	storer.store("second", "second-varied")
+	storer.store("third", "third-varied")
+	storer.store("fourth", "fourth-varied")
+	_ = storer.Set(core.SurrogateKeyPrefix+"tag", []byte(",second"), time.Hour)
+
+	handler := admin.NewHandler(storer, admin.Options{})
+
+	if res := serve(t, handler, http.MethodDelete, "/keys?key=first"); res.Code != http.StatusNoContent {
+		t.Errorf("The key purge should succeed, %d given", res.Code)
+	}
+
+	if storer.Get("first-varied") != nil || storer.Get(core.MappingKeyPrefix+"first") != nil {
+		t.Error("The key, its mapping and its varied keys should be purged")
+	}
+
+	if res := serve(t, handler, http.MethodDelete, "/tags?tag=tag"); res.Code != http.StatusNoContent {
+		t.Errorf("The tag purge should succeed, %d given", res.Code)
+	}
+
+	if storer.Get("second-varied") != nil || storer.Get(core.SurrogateKeyPrefix+"tag") != nil {
+		t.Error("The tagged keys and the tag should be purged")
+	}
+
+	res := serve(t, handler, http.MethodDelete, "/keys?regex=^third&dry_run=true")
+
+	var result core.DeleteManyResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil || res.Code != http.StatusOK {
+		t.Fatalf("The dry-run should list the matching keys, %d given, %v", res.Code, err)
+	}
+
+	if result.Count != 1 || result.Keys[0] != "third-varied" || storer.Get("third-varied") == nil {
+		t.Errorf("The dry-run should list the matching keys without purging them, got %+v", result)
+	}
+
+	if res := serve(t, handler, http.MethodDelete, "/keys?regex=^third"); res.Code != http.StatusNoContent || storer.Get("third-varied") != nil {
+		t.Errorf("The keys matching the regex should be purged, %d given", res.Code)
+	} else if res.Header().Get(admin.DeletedHeader) != "1" {
+		t.Errorf("The purged keys should be counted, %s given", res.Header().Get(admin.DeletedHeader))
+	}
+
+	if res := serve(t, handler, http.MethodDelete, "/keys?key=first&dry_run=true"); res.Code != http.StatusBadRequest {
+		t.Errorf("The dry-run should require a regex, %d given", res.Code)
+	}
+
+	if res := serve(t, handler, http.MethodDelete, "/keys?regex=("); res.Code != http.StatusBadRequest {
+		t.Errorf("An invalid regex should be rejected, %d given", res.Code)
+	}
+
+	if res := serve(t, handler, http.MethodDelete, "/keys?glob=fourth-*"); res.Code != http.StatusNoContent || storer.Get("fourth-varied") != nil {
+		t.Errorf("The keys matching the glob should be purged, %d given", res.Code)
+	}
+
+	if res := serve(t, handler, http.MethodDelete, "/keys?prefix=fourth&regex=^fourth"); res.Code != http.StatusBadRequest {
+		t.Errorf("A single matcher should be accepted, %d given", res.Code)
+	}
+}
+
+func TestHandler_SoftPurge(t *testing.T) {
+	storer := newMemoryStorer()
+	storer.store("first", "first-varied")
+	storer.store("second", "second-varied")
+
+	handler := admin.NewHandler(storer, admin.Options{})
+
+	res := serve(t, handler, http.MethodDelete, "/keys?key=first&prefix=second&soft=true")
+	if res.Code != http.StatusNoContent || res.Header().Get(admin.DeletedHeader) != "2" {
+		t.Fatalf("The keys should be marked stale, %d %s given", res.Code, res.Header().Get(admin.DeletedHeader))
+	}
+
+	if storer.Get("first-varied") == nil || storer.Get("second-varied") == nil {
+		t.Error("The soft purged responses should be kept")
+	}
+
+	if metadata, _ := core.GetMetadata(storer, "first"); len(metadata) != 1 || !metadata[0].Stale(time.Now()) {
+		t.Errorf("The soft purged response should be stale, %+v given", metadata)
+	}
+
+	if res := serve(t, handler, http.MethodDelete, "/keys?prefix=first&soft=true&dry_run=true"); res.Code != http.StatusBadRequest {
+		t.Errorf("The dry-run shouldn't be combined with the soft purge, %d given", res.Code)
+	}
+}
+
+func TestHandler_Stats(t *testing.T) {
+	storer := newMemoryStorer()
+	storer.store("base", "base-a", "base-b")
+
+	var stats struct {
+		Name    string `json:"name"`
+		Keys    int    `json:"keys"`
+		Healthy bool   `json:"healthy"`
+	}
+
+	res := serve(t, admin.NewHandler(storer, admin.Options{}), http.MethodGet, "/stats")
+	_ = json.NewDecoder(res.Body).Decode(&stats)
+
+	if stats.Name != "MEMORY" || stats.Keys != 2 || !stats.Healthy {
+		t.Errorf("The storer stats should be returned, %+v given", stats)
+	}
+}
+
+func TestHandler_Analytics(t *testing.T) {
+	storer := newMemoryStorer()
+
+	if res := serve(t, admin.NewHandler(storer, admin.Options{}), http.MethodGet, "/analytics"); res.Code != http.StatusNotFound {
+		t.Errorf("The analytics should be disabled, %d given", res.Code)
+	}
+
+	analyzed, _ := core.NewAnalyticsStorer(storer, core.AnalyticsOptions{})
+	_ = analyzed.Set("big", make([]byte, 2048), time.Minute)
+	_ = analyzed.Set("small", []byte("value"), time.Minute)
+	_ = analyzed.Get("small")
+
+	var analytics core.KeyAnalytics
+
+	res := serve(t, admin.NewHandler(analyzed, admin.Options{}), http.MethodGet, "/analytics")
+	_ = json.NewDecoder(res.Body).Decode(&analytics)
+
+	if len(analytics.Largest) != 2 || analytics.Largest[0].Key != "big" || len(analytics.Hottest) != 1 || analytics.Hottest[0].Key != "small" {
+		t.Errorf("The largest and the hottest keys should be returned, %+v given", analytics)
+	}
+}
+
+func TestHandler_Authorize(t *testing.T) {
+	handler := admin.NewHandler(newMemoryStorer(), admin.Options{Authorize: admin.BearerToken("other")})
+
+	if res := serve(t, handler, http.MethodGet, "/stats"); res.Code != http.StatusUnauthorized {
+		t.Errorf("A wrong token should be rejected, %d given", res.Code)
+	}
+
+	handler = admin.NewHandler(newMemoryStorer(), admin.Options{Authorize: admin.BearerToken("secret")})
+
+	if res := serve(t, handler, http.MethodGet, "/stats"); res.Code != http.StatusOK {
+		t.Errorf("The right token should be accepted, %d given", res.Code)
+	}
+}
+
+func TestHandler_DumpWarmup(t *testing.T) {
+	source := newMemoryStorer()
+	source.store("base", "base-a", "base-b")
+
+	handler := admin.NewHandler(source, admin.Options{})
+
+	res := serve(t, handler, http.MethodGet, "/dump")
+	if res.Code != http.StatusOK || strings.Count(res.Body.String(), "\n") != 3 {
+		t.Fatalf("The varied keys and their mapping should be dumped, %d and %s given", res.Code, res.Body.String())
+	}
+
+	target := newMemoryStorer()
+	req := httptest.NewRequest(http.MethodPost, "/warmup", res.Body)
+	recorder := httptest.NewRecorder()
+	admin.NewHandler(target, admin.Options{}).ServeHTTP(recorder, req)
+
+	var warmup struct {
+		Entries int `json:"entries"`
+	}
+
+	_ = json.NewDecoder(recorder.Body).Decode(&warmup)
+
+	if recorder.Code != http.StatusOK || warmup.Entries != 3 {
+		t.Errorf("The dump should be stored, %d and %+v given", recorder.Code, warmup)
+	}
+
+	if keys := target.ListKeys(); len(keys) != 2 {
+		t.Errorf("The warmed up storer should list the varied keys, %v given", keys)
+	}
+}
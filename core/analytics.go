@@ -0,0 +1,457 @@
+package core
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/maphash"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnalyticsConfigurationKey is the key read from the provider configuration
+// to track the largest and the hottest keys of the storage.
+const AnalyticsConfigurationKey = "analytics"
+
+const (
+	defaultAnalyticsTopN        = 10
+	defaultAnalyticsSketchWidth = 2048
+	defaultAnalyticsSketchDepth = 4
+)
+
+// analyticsSizeBounds are the upper bounds of the size histogram buckets,
+// the last bucket holds the bigger values.
+var analyticsSizeBounds = []int64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+
+// AnalyticsOptions tunes the AnalyticsStorer.
+type AnalyticsOptions struct {
+	// TopN is the number of the largest and the hottest keys tracked, 10 by
+	// default.
+	TopN int `json:"top_n"`
+	// SketchWidth is the number of counters of each row of the count-min
+	// sketch estimating the reads of the keys, 2048 by default. A wider
+	// sketch overestimates less.
+	SketchWidth int `json:"sketch_width"`
+	// SketchDepth is the number of rows of the sketch, 4 by default.
+	SketchDepth int `json:"sketch_depth"`
+}
+
+func (o *AnalyticsOptions) setDefaults() error {
+	for _, option := range []struct {
+		name  string
+		value *int
+		def   int
+	}{
+		{name: "top_n", value: &o.TopN, def: defaultAnalyticsTopN},
+		{name: "sketch_width", value: &o.SketchWidth, def: defaultAnalyticsSketchWidth},
+		{name: "sketch_depth", value: &o.SketchDepth, def: defaultAnalyticsSketchDepth},
+	} {
+		if *option.value < 0 {
+			return fmt.Errorf("the %s can't be negative, %d given", option.name, *option.value)
+		}
+
+		if *option.value == 0 {
+			*option.value = option.def
+		}
+	}
+
+	return nil
+}
+
+// SizeBucket counts the values written with a size up to UpperBound bytes,
+// and above the previous bucket bound.
+type SizeBucket struct {
+	// UpperBound is zero for the last bucket, it's unbounded.
+	UpperBound int64 `json:"le,omitempty"`
+	Count      int64 `json:"count"`
+}
+
+// RankedKey is a key with the value ranking it: its size in bytes among the
+// largest keys, its estimated reads count among the hottest keys.
+type RankedKey struct {
+	Key   string `json:"key"`
+	Value int64  `json:"value"`
+}
+
+// KeyAnalytics describes the keys dominating the storage or the traffic.
+type KeyAnalytics struct {
+	// Sizes is the histogram of the sizes of the written values.
+	Sizes []SizeBucket `json:"sizes"`
+	// Largest are the biggest stored values, the largest first.
+	Largest []RankedKey `json:"largest"`
+	// Hottest are the most read keys, the hottest first. Their reads are
+	// estimated and may be overestimated, never underestimated.
+	Hottest []RankedKey `json:"hottest"`
+}
+
+// AnalyticsReporter is an optional interface a Storer can implement to
+// report its largest and hottest keys.
+type AnalyticsReporter interface {
+	Analytics() KeyAnalytics
+}
+
+// Analytics returns the analytics of the first storer implementing
+// AnalyticsReporter, walking down the decorators. The boolean is false when
+// none does.
+func Analytics(storer Storer) (KeyAnalytics, bool) {
+	for storer != nil {
+		if reporter, ok := storer.(AnalyticsReporter); ok {
+			return reporter.Analytics(), true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return KeyAnalytics{}, false
+}
+
+// countMinSketch estimates the occurrences of the keys in a fixed memory,
+// each row counts the key under a differently seeded hash and the smallest
+// counter is the estimation.
+type countMinSketch struct {
+	seeds    []maphash.Seed
+	counters [][]uint32
+}
+
+func newCountMinSketch(width, depth int) *countMinSketch {
+	sketch := &countMinSketch{seeds: make([]maphash.Seed, depth), counters: make([][]uint32, depth)}
+
+	for row := range depth {
+		sketch.seeds[row] = maphash.MakeSeed()
+		sketch.counters[row] = make([]uint32, width)
+	}
+
+	return sketch
+}
+
+// increment counts an occurrence of the key and returns its estimation.
+func (s *countMinSketch) increment(key string) int64 {
+	estimation := uint32(0)
+
+	for row, counters := range s.counters {
+		index := maphash.String(s.seeds[row], key) % uint64(len(counters))
+		if counters[index] < ^uint32(0) {
+			counters[index]++
+		}
+
+		if row == 0 || counters[index] < estimation {
+			estimation = counters[index]
+		}
+	}
+
+	return int64(estimation)
+}
+
+func (s *countMinSketch) reset() {
+	for _, counters := range s.counters {
+		clear(counters)
+	}
+}
+
+// topKeys is a min-heap of the n keys with the highest values, the root is
+// the first one replaced.
+type topKeys struct {
+	n         int
+	keys      []RankedKey
+	positions map[string]int
+}
+
+func newTopKeys(n int) *topKeys {
+	return &topKeys{n: n, positions: map[string]int{}}
+}
+
+func (t *topKeys) Len() int {
+	return len(t.keys)
+}
+
+func (t *topKeys) Less(i, j int) bool {
+	return t.keys[i].Value < t.keys[j].Value
+}
+
+func (t *topKeys) Swap(i, j int) {
+	t.keys[i], t.keys[j] = t.keys[j], t.keys[i]
+	t.positions[t.keys[i].Key] = i
+	t.positions[t.keys[j].Key] = j
+}
+
+func (t *topKeys) Push(x any) {
+	ranked, _ := x.(RankedKey)
+	t.positions[ranked.Key] = len(t.keys)
+	t.keys = append(t.keys, ranked)
+}
+
+func (t *topKeys) Pop() any {
+	last := t.keys[len(t.keys)-1]
+	t.keys = t.keys[:len(t.keys)-1]
+	delete(t.positions, last.Key)
+
+	return last
+}
+
+// update sets the value of the key, it enters the top when it beats the
+// lowest tracked value.
+func (t *topKeys) update(key string, value int64) {
+	if position, found := t.positions[key]; found {
+		t.keys[position].Value = value
+		heap.Fix(t, position)
+
+		return
+	}
+
+	if len(t.keys) < t.n {
+		heap.Push(t, RankedKey{Key: key, Value: value})
+
+		return
+	}
+
+	if value <= t.keys[0].Value {
+		return
+	}
+
+	delete(t.positions, t.keys[0].Key)
+	t.keys[0] = RankedKey{Key: key, Value: value}
+	t.positions[key] = 0
+	heap.Fix(t, 0)
+}
+
+func (t *topKeys) remove(key string) {
+	if position, found := t.positions[key]; found {
+		heap.Remove(t, position)
+	}
+}
+
+// ranked returns the tracked keys, the highest value first.
+func (t *topKeys) ranked() []RankedKey {
+	ranked := slices.Clone(t.keys)
+	slices.SortFunc(ranked, func(a, b RankedKey) int {
+		if a.Value != b.Value {
+			if a.Value > b.Value {
+				return -1
+			}
+
+			return 1
+		}
+
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return ranked
+}
+
+func (t *topKeys) reset() {
+	t.keys = nil
+	clear(t.positions)
+}
+
+// AnalyticsStorer decorates any Storer to find the keys dominating the
+// storage or the traffic. It builds the histogram of the written sizes,
+// keeps the n largest values and estimates the reads of each key with a
+// count-min sketch to keep the n hottest ones, in a fixed memory whatever
+// the number of keys. The mappings, the surrogate keys and the locks aren't
+// tracked, a response stored with SetMultiLevel is read under its base key
+// and sized under its varied key.
+//
+// The analytics are kept in memory: they only cover the reads and the writes
+// of this instance since its creation.
+type AnalyticsStorer struct {
+	Storer
+
+	mu      sync.Mutex
+	sizes   []int64
+	largest *topKeys
+	hottest *topKeys
+	sketch  *countMinSketch
+}
+
+// NewAnalyticsStorer wraps the storer with empty analytics.
+func NewAnalyticsStorer(storer Storer, options AnalyticsOptions) (*AnalyticsStorer, error) {
+	if err := options.setDefaults(); err != nil {
+		return nil, fmt.Errorf("invalid analytics configuration: %w", err)
+	}
+
+	return &AnalyticsStorer{
+		Storer:  storer,
+		sizes:   make([]int64, len(analyticsSizeBounds)+1),
+		largest: newTopKeys(options.TopN),
+		hottest: newTopKeys(options.TopN),
+		sketch:  newCountMinSketch(options.SketchWidth, options.SketchDepth),
+	}, nil
+}
+
+// AnalyticsStorerFromConfiguration wraps the storer when the analytics key
+// is set in the provider configuration, it returns the storer untouched
+// otherwise.
+func AnalyticsStorerFromConfiguration(storer Storer, provider CacheProvider) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	analyticsCfg, ok := cfg[AnalyticsConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	var options AnalyticsOptions
+	if err := DecodeConfiguration(analyticsCfg, &options); err != nil {
+		return nil, fmt.Errorf("invalid analytics configuration: %w", err)
+	}
+
+	return NewAnalyticsStorer(storer, options)
+}
+
+// Unwrap returns the decorated storer.
+func (s *AnalyticsStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// Analytics returns the size histogram with the largest and the hottest keys.
+func (s *AnalyticsStorer) Analytics() KeyAnalytics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analytics := KeyAnalytics{
+		Sizes:   make([]SizeBucket, len(s.sizes)),
+		Largest: s.largest.ranked(),
+		Hottest: s.hottest.ranked(),
+	}
+
+	for index, count := range s.sizes {
+		analytics.Sizes[index].Count = count
+
+		if index < len(analyticsSizeBounds) {
+			analytics.Sizes[index].UpperBound = analyticsSizeBounds[index]
+		}
+	}
+
+	return analytics
+}
+
+// Stats returns the stats of the decorated storer with the analytics.
+func (s *AnalyticsStorer) Stats() StorerStats {
+	stats, _ := Stats(s.Storer)
+	analytics := s.Analytics()
+	stats.Analytics = &analytics
+
+	return stats
+}
+
+func (s *AnalyticsStorer) recordRead(key string) {
+	if untracked(key) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hottest.update(key, s.sketch.increment(key))
+}
+
+func (s *AnalyticsStorer) recordWrite(key string, size int64) {
+	if untracked(key) {
+		return
+	}
+
+	bucket, _ := slices.BinarySearch(analyticsSizeBounds, size)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sizes[bucket]++
+	s.largest.update(key, size)
+}
+
+// Get method returns the stored value and counts the read.
+func (s *AnalyticsStorer) Get(key string) []byte {
+	s.recordRead(key)
+
+	return s.Storer.Get(key)
+}
+
+// Lookup method returns the stored value and counts the read.
+func (s *AnalyticsStorer) Lookup(key string) ([]byte, error) {
+	s.recordRead(key)
+
+	return Lookup(s.Storer, key)
+}
+
+// GetMultiLevel returns the fresh and stale candidates and counts the read
+// of the base key.
+func (s *AnalyticsStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	s.recordRead(key)
+
+	return s.Storer.GetMultiLevel(key, req, validator)
+}
+
+// Set method stores the value and records its size.
+func (s *AnalyticsStorer) Set(key string, value []byte, duration time.Duration) error {
+	if err := s.Storer.Set(key, value, duration); err != nil {
+		return err
+	}
+
+	s.recordWrite(key, int64(len(value)))
+
+	return nil
+}
+
+// SetMultiLevel stores the response and records its size under the varied
+// key.
+func (s *AnalyticsStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if err := s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey); err != nil {
+		return err
+	}
+
+	s.recordWrite(variedKey, int64(len(value)))
+
+	return nil
+}
+
+// Delete method deletes the key and drops it from the largest keys.
+func (s *AnalyticsStorer) Delete(key string) {
+	s.Storer.Delete(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.largest.remove(key)
+}
+
+// DeleteMany method deletes the matching keys and drops them from the
+// largest keys.
+func (s *AnalyticsStorer) DeleteMany(key string) {
+	s.Storer.DeleteMany(key)
+
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ranked := range slices.Clone(s.largest.keys) {
+		if rgKey.MatchString(ranked.Key) {
+			s.largest.remove(ranked.Key)
+		}
+	}
+}
+
+// Reset method resets the decorated storer and empties the analytics.
+func (s *AnalyticsStorer) Reset() error {
+	s.mu.Lock()
+	clear(s.sizes)
+	s.largest.reset()
+	s.hottest.reset()
+	s.sketch.reset()
+	s.mu.Unlock()
+
+	return s.Storer.Reset()
+}
@@ -0,0 +1,80 @@
+package core_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestAnalyticsStorer(t *testing.T) {
+	storer, err := core.NewAnalyticsStorer(newMemoryStorer(), core.AnalyticsOptions{TopN: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index := range 10 {
+		_ = storer.Set(fmt.Sprintf("key-%d", index), make([]byte, index*1024), time.Minute)
+
+		for range index {
+			_ = storer.Get(fmt.Sprintf("key-%d", index))
+		}
+	}
+
+	_ = storer.Set(core.MappingKeyPrefix+"base", make([]byte, 1<<20), time.Minute)
+	_ = storer.Set("huge", make([]byte, 5<<20), time.Minute)
+
+	analytics := storer.Analytics()
+
+	if len(analytics.Largest) != 3 || analytics.Largest[0].Key != "huge" || analytics.Largest[1].Key != "key-9" || analytics.Largest[2].Key != "key-8" {
+		t.Errorf("The 3 largest keys should be tracked, the mappings excluded, %+v given", analytics.Largest)
+	}
+
+	if len(analytics.Hottest) != 3 || analytics.Hottest[0] != (core.RankedKey{Key: "key-9", Value: 9}) || analytics.Hottest[2].Key != "key-7" {
+		t.Errorf("The 3 hottest keys should be tracked, %+v given", analytics.Hottest)
+	}
+
+	counts := []int64{2, 3, 5, 0, 0, 0, 0, 1}
+	for index, bucket := range analytics.Sizes {
+		if bucket.Count != counts[index] {
+			t.Errorf("The bucket %d should count %d values, %d given", bucket.UpperBound, counts[index], bucket.Count)
+		}
+	}
+
+	storer.Delete("huge")
+	storer.DeleteMany("^key-9$")
+
+	if largest := storer.Analytics().Largest; len(largest) != 1 || largest[0].Key != "key-8" {
+		t.Errorf("The deleted keys should be dropped from the largest ones, %+v given", largest)
+	}
+
+	stats, found := core.Stats(storer)
+	if !found || stats.Analytics == nil || len(stats.Analytics.Hottest) != 3 {
+		t.Errorf("The analytics should be reported with the stats, %+v given", stats)
+	}
+
+	if analytics, found := core.Analytics(core.NewReadOnlyStorer(storer, false, nopLogger{})); !found || len(analytics.Hottest) != 3 {
+		t.Errorf("The analytics should be found under the decorators, %+v given", analytics)
+	}
+}
+
+func TestAnalyticsStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	if storer, err := core.AnalyticsStorerFromConfiguration(memory, core.CacheProvider{}); err != nil || storer != memory {
+		t.Errorf("The storer should be untouched without configuration, %v given", err)
+	}
+
+	if storer, err := core.AnalyticsStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"analytics": map[string]interface{}{"top_n": "5"},
+	}}); err != nil || storer == memory {
+		t.Errorf("The storer should be decorated, %v given", err)
+	}
+
+	if _, err := core.AnalyticsStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"analytics": map[string]interface{}{"sketch_width": -1},
+	}}); err == nil {
+		t.Error("A negative sketch width should be rejected")
+	}
+}
@@ -0,0 +1,294 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// AsyncConfigurationKey is the key read from the provider configuration
+	// to write the values in background.
+	AsyncConfigurationKey = "async"
+
+	// AsyncOverflowDrop drops the writes once the queue is full.
+	AsyncOverflowDrop = "drop"
+	// AsyncOverflowBlock waits for a free slot in the queue.
+	AsyncOverflowBlock = "block"
+
+	defaultAsyncQueueLength   = 1024
+	defaultAsyncWorkers       = 4
+	defaultAsyncMaxRetries    = 3
+	defaultAsyncRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrAsyncQueueFull is returned by the AsyncStorer writes dropped because
+	// the queue is full.
+	ErrAsyncQueueFull = errors.New("the async write queue is full")
+	// ErrAsyncStorerClosed is returned by the AsyncStorer writes once Reset
+	// is called.
+	ErrAsyncStorerClosed = errors.New("the async storer is closed")
+)
+
+// AsyncOptions tunes the AsyncStorer.
+type AsyncOptions struct {
+	// QueueLength is the number of writes waiting for a worker.
+	QueueLength int `json:"queue_length"`
+	Workers     int `json:"workers"`
+	// Overflow is AsyncOverflowDrop or AsyncOverflowBlock.
+	Overflow string `json:"overflow"`
+	// MaxRetries is the number of retries of a failed write.
+	MaxRetries int `json:"max_retries"`
+	// RetryInterval is the delay before the first retry, multiplied by the
+	// attempt number for the next ones.
+	RetryInterval time.Duration `json:"retry_interval"`
+}
+
+func defaultAsyncOptions() AsyncOptions {
+	return AsyncOptions{
+		QueueLength:   defaultAsyncQueueLength,
+		Workers:       defaultAsyncWorkers,
+		Overflow:      AsyncOverflowDrop,
+		MaxRetries:    defaultAsyncMaxRetries,
+		RetryInterval: defaultAsyncRetryInterval,
+	}
+}
+
+func (o AsyncOptions) validate() error {
+	if o.QueueLength <= 0 {
+		return fmt.Errorf("the queue_length must be positive, %d given", o.QueueLength)
+	}
+
+	if o.Workers <= 0 {
+		return fmt.Errorf("the workers must be positive, %d given", o.Workers)
+	}
+
+	if o.Overflow != AsyncOverflowDrop && o.Overflow != AsyncOverflowBlock {
+		return fmt.Errorf("the overflow must be %s or %s, %s given", AsyncOverflowDrop, AsyncOverflowBlock, o.Overflow)
+	}
+
+	if o.MaxRetries < 0 {
+		return fmt.Errorf("the max_retries must be positive, %d given", o.MaxRetries)
+	}
+
+	return nil
+}
+
+type asyncWrite struct {
+	// key and value are only set by Set, so Get and Delete see the queued
+	// value.
+	key   string
+	value []byte
+	write func() error
+}
+
+// AsyncStorer decorates any Storer to queue the Set and SetMultiLevel calls
+// and write them in background, so the caller never waits for a slow remote
+// storage. A failed write is retried MaxRetries times before being dropped.
+// The values queued by Set are returned by Get until they are written, the
+// SetMultiLevel ones are only readable once written.
+type AsyncStorer struct {
+	Storer
+
+	options AsyncOptions
+	logger  Logger
+	queue   chan *asyncWrite
+	pending sync.Map
+	workers sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+}
+
+// NewAsyncStorer wraps the storer and starts the workers.
+func NewAsyncStorer(storer Storer, options AsyncOptions, logger Logger) (*AsyncStorer, error) {
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid async configuration: %w", err)
+	}
+
+	s := &AsyncStorer{
+		Storer:  storer,
+		options: options,
+		logger:  logger,
+		queue:   make(chan *asyncWrite, options.QueueLength),
+	}
+
+	s.workers.Add(options.Workers)
+
+	for range options.Workers {
+		go s.work()
+	}
+
+	return s, nil
+}
+
+// AsyncStorerFromConfiguration wraps the storer when the async key is set in
+// the provider configuration, it returns the storer untouched otherwise.
+func AsyncStorerFromConfiguration(storer Storer, provider CacheProvider, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	asyncCfg, ok := cfg[AsyncConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	options := defaultAsyncOptions()
+	if err := DecodeConfiguration(asyncCfg, &options); err != nil {
+		return nil, fmt.Errorf("invalid async configuration: %w", err)
+	}
+
+	return NewAsyncStorer(storer, options, logger)
+}
+
+// Unwrap returns the decorated storer.
+func (s *AsyncStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+func (s *AsyncStorer) work() {
+	defer s.workers.Done()
+
+	for operation := range s.queue {
+		s.flush(operation)
+	}
+}
+
+func (s *AsyncStorer) flush(operation *asyncWrite) {
+	if operation.key != "" {
+		// A later Set or Delete of the key superseded this write.
+		if current, ok := s.pending.Load(operation.key); !ok || current != operation {
+			return
+		}
+
+		defer s.pending.CompareAndDelete(operation.key, operation)
+	}
+
+	var err error
+
+	for attempt := 0; attempt <= s.options.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * s.options.RetryInterval)
+		}
+
+		if err = operation.write(); err == nil {
+			return
+		}
+	}
+
+	s.logger.Errorf("Impossible to write in background into %s after %d attempts, %v", s.Storer.Name(), s.options.MaxRetries+1, err)
+}
+
+func (s *AsyncStorer) enqueue(operation *asyncWrite) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrAsyncStorerClosed
+	}
+
+	if operation.key != "" {
+		s.pending.Store(operation.key, operation)
+	}
+
+	if s.options.Overflow == AsyncOverflowBlock {
+		s.queue <- operation
+
+		return nil
+	}
+
+	select {
+	case s.queue <- operation:
+		return nil
+	default:
+		if operation.key != "" {
+			s.pending.CompareAndDelete(operation.key, operation)
+		}
+
+		s.logger.Warnf("The async write queue of %s is full, the write is dropped", s.Storer.Name())
+
+		return ErrAsyncQueueFull
+	}
+}
+
+// Get method returns the value waiting to be written if any, the stored one
+// otherwise.
+func (s *AsyncStorer) Get(key string) []byte {
+	if operation, ok := s.pending.Load(key); ok {
+		return operation.(*asyncWrite).value
+	}
+
+	return s.Storer.Get(key)
+}
+
+// Lookup method returns the value waiting to be written if any, the stored
+// one otherwise.
+func (s *AsyncStorer) Lookup(key string) ([]byte, error) {
+	if operation, ok := s.pending.Load(key); ok {
+		return operation.(*asyncWrite).value, nil
+	}
+
+	return Lookup(s.Storer, key)
+}
+
+// Set method will queue the write.
+func (s *AsyncStorer) Set(key string, value []byte, duration time.Duration) error {
+	return s.enqueue(&asyncWrite{
+		key:   key,
+		value: value,
+		write: func() error {
+			return s.Storer.Set(key, value, duration)
+		},
+	})
+}
+
+// SetMultiLevel method will queue the write.
+func (s *AsyncStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	return s.enqueue(&asyncWrite{
+		write: func() error {
+			return s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+		},
+	})
+}
+
+// Delete method cancels the pending write of the key and deletes it.
+func (s *AsyncStorer) Delete(key string) {
+	s.pending.Delete(key)
+	s.Storer.Delete(key)
+}
+
+// DeleteMany method cancels the pending writes of the matching keys and
+// deletes them.
+func (s *AsyncStorer) DeleteMany(key string) {
+	if rgKey, err := regexp.Compile(key); err == nil {
+		s.pending.Range(func(k, _ any) bool {
+			if rgKey.MatchString(k.(string)) {
+				s.pending.Delete(k)
+			}
+
+			return true
+		})
+	}
+
+	s.Storer.DeleteMany(key)
+}
+
+// Reset method writes the queued values then resets the decorated storer.
+func (s *AsyncStorer) Reset() error {
+	s.mu.Lock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+
+	s.mu.Unlock()
+	s.workers.Wait()
+
+	return s.Storer.Reset()
+}
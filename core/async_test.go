@@ -0,0 +1,165 @@
+package core_test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// gatedStorer blocks its writes until the gate is closed.
+type gatedStorer struct {
+	*memoryStorer
+
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (g *gatedStorer) Set(key string, value []byte, duration time.Duration) error {
+	g.started <- struct{}{}
+	<-g.gate
+
+	return g.memoryStorer.Set(key, value, duration)
+}
+
+// flakyStorer fails its first writes.
+type flakyStorer struct {
+	*memoryStorer
+
+	failures atomic.Int32
+}
+
+func (f *flakyStorer) Set(key string, value []byte, duration time.Duration) error {
+	if f.failures.Add(-1) >= 0 {
+		return errors.New("unavailable")
+	}
+
+	return f.memoryStorer.Set(key, value, duration)
+}
+
+func TestAsyncStorer_ResetFlushesTheQueue(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.NewAsyncStorer(memory, core.AsyncOptions{
+		QueueLength: 100,
+		Workers:     2,
+		Overflow:    core.AsyncOverflowBlock,
+	}, nopLogger{})
+	if err != nil {
+		t.Fatalf("Impossible to create the async storer, %v", err)
+	}
+
+	for i := range 100 {
+		if err := storer.Set(fmt.Sprintf("key-%d", i), []byte(baseValue), time.Minute); err != nil {
+			t.Errorf("The write %d shouldn't fail, %v", i, err)
+		}
+	}
+
+	_ = storer.Reset()
+
+	if len(memory.ListKeys()) != 100 {
+		t.Errorf("The queued writes should be flushed on reset, %d written", len(memory.ListKeys()))
+	}
+
+	if err := storer.Set(byteKey, []byte(baseValue), time.Minute); !errors.Is(err, core.ErrAsyncStorerClosed) {
+		t.Errorf("The writes after reset should fail, %v given", err)
+	}
+}
+
+func TestAsyncStorer_DropOverflow(t *testing.T) {
+	gated := &gatedStorer{memoryStorer: newMemoryStorer(), started: make(chan struct{}, 1), gate: make(chan struct{})}
+
+	storer, _ := core.NewAsyncStorer(gated, core.AsyncOptions{
+		QueueLength: 1,
+		Workers:     1,
+		Overflow:    core.AsyncOverflowDrop,
+	}, nopLogger{})
+
+	_ = storer.Set("first", []byte(baseValue), time.Minute)
+	<-gated.started
+
+	if err := storer.Set("second", []byte(baseValue), time.Minute); err != nil {
+		t.Errorf("The second write should be queued, %v", err)
+	}
+
+	if err := storer.Set("third", []byte(baseValue), time.Minute); !errors.Is(err, core.ErrAsyncQueueFull) {
+		t.Errorf("The third write should be dropped, %v given", err)
+	}
+
+	if string(storer.Get("second")) != baseValue {
+		t.Errorf("The queued value should be readable, %s given", storer.Get("second"))
+	}
+
+	storer.Delete("second")
+
+	if storer.Get("second") != nil {
+		t.Error("The deleted queued value shouldn't be readable")
+	}
+
+	close(gated.gate)
+	_ = storer.Reset()
+
+	if gated.memoryStorer.Get("first") == nil {
+		t.Error("The first write should be stored")
+	}
+
+	if gated.memoryStorer.Get("second") != nil || gated.memoryStorer.Get("third") != nil {
+		t.Error("The deleted and the dropped writes shouldn't be stored")
+	}
+}
+
+func TestAsyncStorer_Retry(t *testing.T) {
+	flaky := &flakyStorer{memoryStorer: newMemoryStorer()}
+	flaky.failures.Store(2)
+
+	storer, _ := core.NewAsyncStorer(flaky, core.AsyncOptions{
+		QueueLength:   1,
+		Workers:       1,
+		Overflow:      core.AsyncOverflowBlock,
+		MaxRetries:    2,
+		RetryInterval: time.Millisecond,
+	}, nopLogger{})
+
+	_ = storer.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = storer.Reset()
+
+	if string(flaky.memoryStorer.Get(byteKey)) != baseValue {
+		t.Error("The write should succeed after the retries")
+	}
+}
+
+func TestAsyncStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.AsyncStorerFromConfiguration(memory, core.CacheProvider{}, nopLogger{})
+	if err != nil || storer != memory {
+		t.Error("The storer shouldn't be wrapped without the async configuration")
+	}
+
+	storer, err = core.AsyncStorerFromConfiguration(memory, core.CacheProvider{
+		Configuration: map[string]interface{}{
+			core.AsyncConfigurationKey: map[string]interface{}{"workers": "2", "retry_interval": "10ms"},
+		},
+	}, nopLogger{})
+	if err != nil {
+		t.Fatalf("The async configuration should be valid, %v", err)
+	}
+
+	if _, ok := storer.(*core.AsyncStorer); !ok {
+		t.Errorf("The storer should be wrapped, %T given", storer)
+	}
+
+	_ = storer.Reset()
+
+	_, err = core.AsyncStorerFromConfiguration(memory, core.CacheProvider{
+		Configuration: map[string]interface{}{
+			core.AsyncConfigurationKey: map[string]interface{}{"overflow": "wait"},
+		},
+	}, nopLogger{})
+	if err == nil {
+		t.Error("An unknown overflow policy should be invalid")
+	}
+}
@@ -0,0 +1,32 @@
+package core
+
+// BatchGetter is an optional interface a Storer can implement to read
+// several keys in one round-trip, e.g. with MGET on Redis or a single
+// transaction on Etcd. GetMany returns the values of the keys found, the
+// missing and expired keys are absent from the map.
+type BatchGetter interface {
+	GetMany(keys []string) map[string][]byte
+}
+
+// GetMany reads the keys with the storer GetMany method when it implements
+// BatchGetter, with one Get per key otherwise. The decorators aren't
+// unwrapped since they may transform the keys or the values.
+func GetMany(storer Storer, keys []string) map[string][]byte {
+	if len(keys) == 0 {
+		return map[string][]byte{}
+	}
+
+	if getter, ok := storer.(BatchGetter); ok {
+		return getter.GetMany(keys)
+	}
+
+	values := make(map[string][]byte, len(keys))
+
+	for _, key := range keys {
+		if value := storer.Get(key); len(value) > 0 {
+			values[key] = value
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,75 @@
+package core_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// batchStorer is a memoryStorer counting its Get and GetMany calls.
+type batchStorer struct {
+	*memoryStorer
+
+	gets, batches int
+}
+
+func (b *batchStorer) Get(key string) []byte {
+	b.gets++
+
+	return b.memoryStorer.Get(key)
+}
+
+func (b *batchStorer) GetMany(keys []string) map[string][]byte {
+	b.batches++
+
+	values := map[string][]byte{}
+
+	for _, key := range keys {
+		if value := b.memoryStorer.Get(key); value != nil {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+func TestGetMany(t *testing.T) {
+	memory := newMemoryStorer()
+	_ = memory.Set("first", []byte("first"), time.Minute)
+	_ = memory.Set("second", []byte("second"), time.Minute)
+
+	values := core.GetMany(memory, []string{"first", "second", "missing"})
+	if len(values) != 2 || string(values["first"]) != "first" || string(values["second"]) != "second" {
+		t.Errorf("The found keys should be returned, %v given", values)
+	}
+
+	storer := &batchStorer{memoryStorer: memory}
+	if values = core.GetMany(storer, []string{"first", "missing"}); len(values) != 1 || storer.batches != 1 || storer.gets != 0 {
+		t.Errorf("The BatchGetter should be used, %v given with %d batches and %d gets", values, storer.batches, storer.gets)
+	}
+}
+
+func TestMappingElection_GetMany(t *testing.T) {
+	storer := &batchStorer{memoryStorer: newMemoryStorer()}
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+
+	for _, language := range []string{"en", "fr", "de"} {
+		_ = storer.SetMultiLevel("base", "base-"+language, value, http.Header{"Accept-Language": []string{language}}, "", time.Minute, "base")
+	}
+
+	storer.gets = 0
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr")
+
+	fresh, _, err := core.MappingElection(storer, storer.memoryStorer.Get(core.MappingKeyPrefix+"base"), req, &core.Revalidator{}, nopLogger{})
+	if fresh == nil || err != nil {
+		t.Fatalf("The matching variant should be elected, %v given", err)
+	}
+
+	if storer.batches != 1 || storer.gets != 0 {
+		t.Errorf("The candidates should be loaded in one batch, %d batches and %d gets given", storer.batches, storer.gets)
+	}
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ChainedStorer composes a local storer, usually an in-memory one, in front
+// of a remote storer. Reads go through the local storer first and fall back
+// on the remote one, writes and deletions are applied to both.
+type ChainedStorer struct {
+	local  Storer
+	remote Storer
+	// backfill is the maximum duration a value read from the remote storer
+	// is kept in the local one. Zero disables the backfill.
+	backfill time.Duration
+}
+
+// NewChainedStorer creates a ChainedStorer. When backfill is positive, the
+// values found in the remote storer are asynchronously copied into the local
+// one for at most that duration.
+func NewChainedStorer(local, remote Storer, backfill time.Duration) *ChainedStorer {
+	return &ChainedStorer{local: local, remote: remote, backfill: backfill}
+}
+
+// Local returns the local storer.
+func (s *ChainedStorer) Local() Storer {
+	return s.local
+}
+
+// Remote returns the remote storer.
+func (s *ChainedStorer) Remote() Storer {
+	return s.remote
+}
+
+// Name returns the storer name.
+func (s *ChainedStorer) Name() string {
+	return "CHAINED"
+}
+
+// Uuid returns an unique identifier.
+func (s *ChainedStorer) Uuid() string {
+	return fmt.Sprintf("%s-%s-%s-%s", s.local.Name(), s.local.Uuid(), s.remote.Name(), s.remote.Uuid())
+}
+
+// MapKeys method returns the remote storer keys, the local one only holds a
+// subset of them.
+func (s *ChainedStorer) MapKeys(prefix string) map[string]string {
+	return s.remote.MapKeys(prefix)
+}
+
+// ListKeys method returns the remote storer keys.
+func (s *ChainedStorer) ListKeys() []string {
+	return s.remote.ListKeys()
+}
+
+// Get method returns the local value if exists, the remote one then.
+func (s *ChainedStorer) Get(key string) []byte {
+	if value := s.local.Get(key); len(value) != 0 {
+		return value
+	}
+
+	value := s.remote.Get(key)
+	if len(value) != 0 && s.backfill > 0 {
+		go func() {
+			_ = s.local.Set(key, value, s.backfill)
+		}()
+	}
+
+	return value
+}
+
+// Lookup method returns the local value, the remote one on a local miss or
+// failure. The remote result is returned when both fail.
+func (s *ChainedStorer) Lookup(key string) ([]byte, error) {
+	if value, err := Lookup(s.local, key); err == nil {
+		return value, nil
+	}
+
+	value, err := Lookup(s.remote, key)
+	if err == nil && len(value) != 0 && s.backfill > 0 {
+		go func() {
+			_ = s.local.Set(key, value, s.backfill)
+		}()
+	}
+
+	return value, err
+}
+
+// Set method will store the value in the remote storer then in the local one.
+func (s *ChainedStorer) Set(key string, value []byte, duration time.Duration) error {
+	if err := s.remote.Set(key, value, duration); err != nil {
+		return err
+	}
+
+	return s.local.Set(key, value, duration)
+}
+
+// Delete method will delete the key in both storers.
+func (s *ChainedStorer) Delete(key string) {
+	s.remote.Delete(key)
+	s.local.Delete(key)
+}
+
+// DeleteMany method will delete the keys matching the regex in both storers.
+func (s *ChainedStorer) DeleteMany(key string) {
+	s.remote.DeleteMany(key)
+	s.local.DeleteMany(key)
+}
+
+// GetMultiLevel tries to elect a fresh/stale candidate from the local storer,
+// from the remote one then.
+func (s *ChainedStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale = s.local.GetMultiLevel(key, req, validator)
+	if fresh != nil || stale != nil {
+		return fresh, stale
+	}
+
+	fresh, stale = s.remote.GetMultiLevel(key, req, validator)
+	if (fresh != nil || stale != nil) && s.backfill > 0 {
+		go s.backfillMapping(key)
+	}
+
+	return fresh, stale
+}
+
+// backfillMapping copies the mapping and its varied values from the remote
+// storer to the local one. Each varied value is kept until its stale time,
+// capped to the backfill duration.
+func (s *ChainedStorer) backfillMapping(key string) {
+	mappingKey := MappingKeyPrefix + key
+
+	item := s.remote.Get(mappingKey)
+	if len(item) == 0 {
+		return
+	}
+
+	mapping, err := DecodeMapping(item)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	for variedKey, keyItem := range mapping.GetMapping() {
+		duration := min(s.backfill, keyItem.GetStaleTime().AsTime().Sub(now))
+		if duration <= 0 {
+			continue
+		}
+
+		if value := s.remote.Get(variedKey); len(value) != 0 {
+			_ = s.local.Set(variedKey, value, duration)
+		}
+	}
+
+	_ = s.local.Set(mappingKey, item, s.backfill)
+}
+
+// SetMultiLevel tries to store the key in the remote storer then in the local one.
+func (s *ChainedStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if err := s.remote.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey); err != nil {
+		return err
+	}
+
+	return s.local.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+}
+
+// TryLock acquires the lock through the remote storer so it's shared with
+// the other instances.
+func (s *ChainedStorer) TryLock(key string, ttl time.Duration) (bool, error) {
+	return LockerFor(s.remote).TryLock(key, ttl)
+}
+
+// Unlock releases the lock acquired by TryLock.
+func (s *ChainedStorer) Unlock(key string) error {
+	return LockerFor(s.remote).Unlock(key)
+}
+
+// Init method will initialize both storers.
+func (s *ChainedStorer) Init() error {
+	return errors.Join(s.local.Init(), s.remote.Init())
+}
+
+// Reset method will reset or close both storers.
+func (s *ChainedStorer) Reset() error {
+	return errors.Join(s.local.Reset(), s.remote.Reset())
+}
+
+// Healthy probes both the local and the remote storers.
+func (s *ChainedStorer) Healthy(ctx context.Context) error {
+	return errors.Join(Healthy(ctx, s.local), Healthy(ctx, s.remote))
+}
@@ -0,0 +1,94 @@
+package core_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	for range 100 {
+		if condition() {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("The condition was never met")
+}
+
+func TestChainedStorer_ReadThrough(t *testing.T) {
+	local, remote := newMemoryStorer(), newMemoryStorer()
+	storer := core.NewChainedStorer(local, remote, 0)
+
+	_ = remote.Set("key", []byte("remote"), time.Minute)
+
+	if string(storer.Get("key")) != "remote" {
+		t.Error("The remote value should be returned on local miss")
+	}
+
+	_ = local.Set("key", []byte("local"), time.Minute)
+
+	if string(storer.Get("key")) != "local" {
+		t.Error("The local value should be returned first")
+	}
+}
+
+func TestChainedStorer_WriteThrough(t *testing.T) {
+	local, remote := newMemoryStorer(), newMemoryStorer()
+	storer := core.NewChainedStorer(local, remote, 0)
+
+	_ = storer.Set("key", []byte("value"), time.Minute)
+
+	if string(local.Get("key")) != "value" || string(remote.Get("key")) != "value" {
+		t.Error("The value should be written in both storers")
+	}
+
+	if err := storer.Set("", []byte("value"), time.Minute); err == nil {
+		t.Error("The remote error should be returned")
+	}
+
+	storer.DeleteMany("^k")
+
+	if len(local.Get("key")) != 0 || len(remote.Get("key")) != 0 {
+		t.Error("The value should be deleted from both storers")
+	}
+}
+
+func TestChainedStorer_Backfill(t *testing.T) {
+	local, remote := newMemoryStorer(), newMemoryStorer()
+	storer := core.NewChainedStorer(local, remote, time.Minute)
+
+	_ = remote.Set("key", []byte("value"), time.Minute)
+	_ = storer.Get("key")
+
+	waitFor(t, func() bool { return string(local.Get("key")) == "value" })
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	if err := remote.SetMultiLevel("base", "varied", value, http.Header{}, "", time.Minute, "real"); err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com", nil)
+
+	fresh, _ := storer.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The fresh response should be elected from the remote storer")
+	}
+
+	_ = fresh.Body.Close()
+
+	waitFor(t, func() bool { return len(local.Get("varied")) != 0 && len(local.Get(core.MappingKeyPrefix+"base")) != 0 })
+
+	fresh, _ = local.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The fresh response should be backfilled in the local storer")
+	}
+
+	_ = fresh.Body.Close()
+}
@@ -0,0 +1,387 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// CircuitBreakerConfigurationKey is the key read from the provider
+	// configuration to stop calling an unavailable storage.
+	CircuitBreakerConfigurationKey = "circuit_breaker"
+
+	defaultCircuitBreakerFailureThreshold = 5
+	defaultCircuitBreakerOpenTimeout      = 10 * time.Second
+	defaultCircuitBreakerProbeTimeout     = time.Second
+)
+
+// Circuit breaker states.
+const (
+	CircuitClosed int32 = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+// ErrCircuitOpen is returned by the CircuitBreakerStorer writes while the
+// circuit is open and no fallback is given.
+var ErrCircuitOpen = errors.New("the circuit breaker is open")
+
+// CircuitBreakerOptions tunes the CircuitBreakerStorer.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit.
+	FailureThreshold int `json:"failure_threshold"`
+	// OpenTimeout is how long the circuit stays open before a probe.
+	OpenTimeout time.Duration `json:"open_timeout"`
+	// ProbeTimeout bounds the health check run before closing the circuit.
+	ProbeTimeout time.Duration `json:"probe_timeout"`
+	// Fallback serves the calls while the circuit is open, they are served
+	// as misses when nil.
+	Fallback Storer `json:"-"`
+}
+
+func defaultCircuitBreakerOptions() CircuitBreakerOptions {
+	return CircuitBreakerOptions{
+		FailureThreshold: defaultCircuitBreakerFailureThreshold,
+		OpenTimeout:      defaultCircuitBreakerOpenTimeout,
+		ProbeTimeout:     defaultCircuitBreakerProbeTimeout,
+	}
+}
+
+func (o CircuitBreakerOptions) validate() error {
+	if o.FailureThreshold <= 0 {
+		return fmt.Errorf("the failure_threshold must be positive, %d given", o.FailureThreshold)
+	}
+
+	if o.OpenTimeout <= 0 {
+		return fmt.Errorf("the open_timeout must be positive, %s given", o.OpenTimeout)
+	}
+
+	if o.ProbeTimeout <= 0 {
+		return fmt.Errorf("the probe_timeout must be positive, %s given", o.ProbeTimeout)
+	}
+
+	return nil
+}
+
+// CircuitBreakerStorer decorates any Storer to stop calling it after
+// FailureThreshold consecutive failed writes or health checks. While open,
+// the calls are served by the fallback or as misses. After OpenTimeout, the
+// circuit is half-open: a single call probes the storer with its health
+// check and goes through, the circuit closes when it succeeds and opens
+// again otherwise. The deletions made while open are not replayed.
+type CircuitBreakerStorer struct {
+	Storer
+
+	options  CircuitBreakerOptions
+	logger   Logger
+	state    atomic.Int32
+	failures atomic.Int32
+	openedAt atomic.Int64
+}
+
+// NewCircuitBreakerStorer wraps the storer with a closed circuit.
+func NewCircuitBreakerStorer(storer Storer, options CircuitBreakerOptions, logger Logger) (*CircuitBreakerStorer, error) {
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid circuit_breaker configuration: %w", err)
+	}
+
+	return &CircuitBreakerStorer{Storer: storer, options: options, logger: logger}, nil
+}
+
+// CircuitBreakerStorerFromConfiguration wraps the storer when the
+// circuit_breaker key is set in the provider configuration, it returns the
+// storer untouched otherwise.
+func CircuitBreakerStorerFromConfiguration(storer Storer, provider CacheProvider, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	breakerCfg, ok := cfg[CircuitBreakerConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	options := defaultCircuitBreakerOptions()
+	if err := DecodeConfiguration(breakerCfg, &options); err != nil {
+		return nil, fmt.Errorf("invalid circuit_breaker configuration: %w", err)
+	}
+
+	return NewCircuitBreakerStorer(storer, options, logger)
+}
+
+// Unwrap returns the decorated storer.
+func (s *CircuitBreakerStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// State returns CircuitClosed, CircuitOpen or CircuitHalfOpen.
+func (s *CircuitBreakerStorer) State() int32 {
+	return s.state.Load()
+}
+
+func (s *CircuitBreakerStorer) open() {
+	s.openedAt.Store(time.Now().UnixNano())
+
+	if s.state.Swap(CircuitOpen) != CircuitOpen {
+		s.logger.Warnf("The circuit breaker of %s is open for %s", s.Storer.Name(), s.options.OpenTimeout)
+	}
+}
+
+func (s *CircuitBreakerStorer) success() {
+	s.failures.Store(0)
+
+	if s.state.Swap(CircuitClosed) != CircuitClosed {
+		s.logger.Infof("The circuit breaker of %s is closed", s.Storer.Name())
+	}
+}
+
+func (s *CircuitBreakerStorer) failure() {
+	if s.state.Load() == CircuitHalfOpen || int(s.failures.Add(1)) >= s.options.FailureThreshold {
+		s.open()
+	}
+}
+
+// allow returns true when the call can reach the decorated storer. Once the
+// open timeout is elapsed, only the first caller probes the storer.
+func (s *CircuitBreakerStorer) allow() bool {
+	switch s.state.Load() {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if time.Since(time.Unix(0, s.openedAt.Load())) < s.options.OpenTimeout {
+			return false
+		}
+
+		if !s.state.CompareAndSwap(CircuitOpen, CircuitHalfOpen) {
+			return false
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.options.ProbeTimeout)
+		defer cancel()
+
+		if err := Healthy(ctx, s.Storer); err != nil {
+			s.logger.Warnf("The %s health check failed, %v", s.Storer.Name(), err)
+			s.open()
+
+			return false
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
+// done records the result of a write or a health check.
+func (s *CircuitBreakerStorer) done(err error) {
+	if err != nil {
+		s.failure()
+
+		return
+	}
+
+	if s.failures.Load() > 0 || s.state.Load() != CircuitClosed {
+		s.success()
+	}
+}
+
+// read closes a half-open circuit, the reads and the deletions can't tell a
+// failure from a miss so they never count as failures nor reset them.
+func (s *CircuitBreakerStorer) read() {
+	if s.state.Load() == CircuitHalfOpen {
+		s.success()
+	}
+}
+
+// Healthy probes the decorated storer and records the result.
+func (s *CircuitBreakerStorer) Healthy(ctx context.Context) error {
+	err := Healthy(ctx, s.Storer)
+	s.done(err)
+
+	return err
+}
+
+// MapKeys method returns the decorated storer keys, the fallback ones while
+// open.
+func (s *CircuitBreakerStorer) MapKeys(prefix string) map[string]string {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return s.options.Fallback.MapKeys(prefix)
+		}
+
+		return map[string]string{}
+	}
+
+	keys := s.Storer.MapKeys(prefix)
+	s.read()
+
+	return keys
+}
+
+// ListKeys method returns the decorated storer keys, the fallback ones while
+// open.
+func (s *CircuitBreakerStorer) ListKeys() []string {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return s.options.Fallback.ListKeys()
+		}
+
+		return []string{}
+	}
+
+	keys := s.Storer.ListKeys()
+	s.read()
+
+	return keys
+}
+
+// Get method returns the stored value, the fallback one or a miss while open.
+func (s *CircuitBreakerStorer) Get(key string) []byte {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return s.options.Fallback.Get(key)
+		}
+
+		return nil
+	}
+
+	value := s.Storer.Get(key)
+	s.read()
+
+	return value
+}
+
+// Lookup method returns the stored value, the fallback one or ErrCircuitOpen
+// while open. A backend failure counts as a failure.
+func (s *CircuitBreakerStorer) Lookup(key string) ([]byte, error) {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return Lookup(s.options.Fallback, key)
+		}
+
+		return nil, ErrCircuitOpen
+	}
+
+	value, err := Lookup(s.Storer, key)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		s.failure()
+	} else {
+		s.done(nil)
+	}
+
+	return value, err
+}
+
+// GetMultiLevel returns the fresh and stale candidates, the fallback ones or
+// misses while open.
+func (s *CircuitBreakerStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return s.options.Fallback.GetMultiLevel(key, req, validator)
+		}
+
+		return nil, nil
+	}
+
+	fresh, stale = s.Storer.GetMultiLevel(key, req, validator)
+	s.read()
+
+	return fresh, stale
+}
+
+// Set method stores the value, in the fallback while open.
+func (s *CircuitBreakerStorer) Set(key string, value []byte, duration time.Duration) error {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return s.options.Fallback.Set(key, value, duration)
+		}
+
+		return ErrCircuitOpen
+	}
+
+	err := s.Storer.Set(key, value, duration)
+	s.done(err)
+
+	return err
+}
+
+// SetMultiLevel stores the value and its mapping, in the fallback while open.
+func (s *CircuitBreakerStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return s.options.Fallback.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+		}
+
+		return ErrCircuitOpen
+	}
+
+	err := s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+	s.done(err)
+
+	return err
+}
+
+// Transact runs fn in a transaction of the decorated storer, of the fallback
+// while open. The errors returned by fn abort the transaction without
+// counting as failures.
+func (s *CircuitBreakerStorer) Transact(fn func(tx Tx) error) error {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			return Transact(s.options.Fallback, fn)
+		}
+
+		return ErrCircuitOpen
+	}
+
+	var fnErr error
+
+	err := Transact(s.Storer, func(tx Tx) error {
+		fnErr = fn(tx)
+
+		return fnErr
+	})
+
+	switch {
+	case errors.Is(err, ErrTransactionNotSupported):
+	case fnErr != nil && errors.Is(err, fnErr):
+		s.done(nil)
+	default:
+		s.done(err)
+	}
+
+	return err
+}
+
+// Delete method deletes the key, from the fallback while open.
+func (s *CircuitBreakerStorer) Delete(key string) {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			s.options.Fallback.Delete(key)
+		}
+
+		return
+	}
+
+	s.Storer.Delete(key)
+	s.read()
+}
+
+// DeleteMany method deletes the matching keys, from the fallback while open.
+func (s *CircuitBreakerStorer) DeleteMany(key string) {
+	if !s.allow() {
+		if s.options.Fallback != nil {
+			s.options.Fallback.DeleteMany(key)
+		}
+
+		return
+	}
+
+	s.Storer.DeleteMany(key)
+	s.read()
+}
@@ -0,0 +1,100 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestCircuitBreakerStorer(t *testing.T) {
+	flaky := &flakyStorer{memoryStorer: newMemoryStorer()}
+	flaky.failures.Store(2)
+
+	storer, _ := core.NewCircuitBreakerStorer(flaky, core.CircuitBreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+		ProbeTimeout:     time.Second,
+	}, nopLogger{})
+
+	_ = storer.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = storer.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if storer.State() != core.CircuitOpen {
+		t.Fatal("The circuit should be open after the consecutive failures")
+	}
+
+	if err := storer.Set(byteKey, []byte(baseValue), time.Minute); !errors.Is(err, core.ErrCircuitOpen) {
+		t.Errorf("The writes should be rejected while open, %v given", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if err := storer.Set(byteKey, []byte(baseValue), time.Minute); err != nil {
+		t.Errorf("The half-open probe should reach the storer, %v given", err)
+	}
+
+	if storer.State() != core.CircuitClosed || string(storer.Get(byteKey)) != baseValue {
+		t.Error("The circuit should be closed once the probe succeeds")
+	}
+}
+
+func TestCircuitBreakerStorer_Fallback(t *testing.T) {
+	down := &probedStorer{memoryStorer: newMemoryStorer(), err: errUnavailable}
+	fallback := newMemoryStorer()
+	_ = fallback.Set(byteKey, []byte(baseValue), time.Minute)
+
+	storer, _ := core.NewCircuitBreakerStorer(down, core.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      10 * time.Millisecond,
+		ProbeTimeout:     time.Second,
+		Fallback:         fallback,
+	}, nopLogger{})
+
+	if err := storer.Healthy(context.Background()); err == nil || storer.State() != core.CircuitOpen {
+		t.Fatal("The failed health check should open the circuit")
+	}
+
+	if string(storer.Get(byteKey)) != baseValue {
+		t.Error("The fallback should serve the reads while open")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if string(storer.Get(byteKey)) != baseValue || storer.State() != core.CircuitOpen {
+		t.Error("The failed probe should keep the circuit open")
+	}
+}
+
+func TestCircuitBreakerStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.CircuitBreakerStorerFromConfiguration(memory, core.CacheProvider{}, nopLogger{})
+	if err != nil || storer != memory {
+		t.Error("The storer shouldn't be wrapped without the circuit_breaker configuration")
+	}
+
+	storer, err = core.CircuitBreakerStorerFromConfiguration(memory, core.CacheProvider{
+		Configuration: map[string]interface{}{
+			core.CircuitBreakerConfigurationKey: map[string]interface{}{"failure_threshold": "3", "open_timeout": "5s"},
+		},
+	}, nopLogger{})
+	if err != nil {
+		t.Fatalf("The circuit_breaker configuration should be valid, %v", err)
+	}
+
+	if _, ok := storer.(*core.CircuitBreakerStorer); !ok {
+		t.Errorf("The storer should be wrapped, %T given", storer)
+	}
+
+	_, err = core.CircuitBreakerStorerFromConfiguration(memory, core.CacheProvider{
+		Configuration: map[string]interface{}{
+			core.CircuitBreakerConfigurationKey: map[string]interface{}{"failure_threshold": 0},
+		},
+	}, nopLogger{})
+	if err == nil {
+		t.Error("A zero failure_threshold should be invalid")
+	}
+}
@@ -0,0 +1,307 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MappingCodec serializes the mappings before they are stored and decodes
+// them when they are read back.
+type MappingCodec interface {
+	Encode(mapping *StorageMapper) ([]byte, error)
+	Decode(item []byte, mapping *StorageMapper) error
+	Name() string
+}
+
+const (
+	// MappingCodecConfigurationKey is the key read from the provider
+	// configuration to select the codec of the mappings.
+	MappingCodecConfigurationKey = "mapping_codec"
+
+	ProtobufMappingCodec = "protobuf"
+	JSONMappingCodec     = "json"
+	MsgpackMappingCodec  = "msgpack"
+)
+
+var mappingCodecs = map[string]MappingCodec{
+	ProtobufMappingCodec: protobufCodec{},
+	JSONMappingCodec:     jsonCodec{},
+	MsgpackMappingCodec:  msgpackCodec{},
+}
+
+var (
+	mappingCodec           atomic.Pointer[MappingCodec]
+	configuredMappingCodec = sharedSetting{key: MappingCodecConfigurationKey}
+)
+
+// NewMappingCodec returns the built-in codec matching the given name. An
+// empty name returns the protobuf codec.
+func NewMappingCodec(name string) (MappingCodec, error) {
+	if name == "" {
+		name = ProtobufMappingCodec
+	}
+
+	codec, ok := mappingCodecs[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("invalid mapping_codec configuration: unknown codec %s, expected protobuf, json or msgpack", name)
+	}
+
+	return codec, nil
+}
+
+// SetMappingCodec sets the codec encoding every mapping of the process.
+// Passing nil restores the protobuf codec. It overrides the codec configured
+// by the storages.
+func SetMappingCodec(codec MappingCodec) {
+	configuredMappingCodec.reset()
+
+	if codec == nil {
+		mappingCodec.Store(nil)
+
+		return
+	}
+
+	mappingCodec.Store(&codec)
+}
+
+// CurrentMappingCodec returns the codec encoding the mappings, protobuf
+// unless another one is set.
+func CurrentMappingCodec() MappingCodec {
+	if codec := mappingCodec.Load(); codec != nil {
+		return *codec
+	}
+
+	return mappingCodecs[ProtobufMappingCodec]
+}
+
+// SetMappingCodecFromConfiguration applies the mapping_codec key of the
+// provider configuration, if any. The codec is shared by the whole process,
+// the storages declaring another codec than the configured one are rejected.
+func SetMappingCodecFromConfiguration(provider CacheProvider) error {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	value, ok := cfg[MappingCodecConfigurationKey]
+	if !ok || value == nil {
+		return nil
+	}
+
+	name, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("invalid mapping_codec configuration: a codec name is expected, %v given", value)
+	}
+
+	codec, err := NewMappingCodec(name)
+	if err != nil {
+		return err
+	}
+
+	return configuredMappingCodec.configure(codec.Name(), func() {
+		mappingCodec.Store(&codec)
+	})
+}
+
+// DetectMappingCodec returns the codec that wrote the given mapping, so the
+// mappings written before a codec switch are still read. The JSON mappings
+// are objects and the msgpack ones arrays, the protobuf ones start with the
+// tag of a StorageMapper field.
+func DetectMappingCodec(item []byte) MappingCodec {
+	if len(item) == 0 {
+		return mappingCodecs[ProtobufMappingCodec]
+	}
+
+	switch first := item[0]; {
+	case first == '{':
+		return mappingCodecs[JSONMappingCodec]
+	case first >= 0x90 && first <= 0x9f, first == 0xdc, first == 0xdd:
+		return mappingCodecs[MsgpackMappingCodec]
+	default:
+		return mappingCodecs[ProtobufMappingCodec]
+	}
+}
+
+// EncodeMapping serializes the mapping with the codec of the process.
+func EncodeMapping(mapping *StorageMapper) ([]byte, error) {
+	return CurrentMappingCodec().Encode(mapping)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string {
+	return ProtobufMappingCodec
+}
+
+func (protobufCodec) Encode(mapping *StorageMapper) ([]byte, error) {
+	return proto.Marshal(mapping)
+}
+
+func (protobufCodec) Decode(item []byte, mapping *StorageMapper) error {
+	return proto.Unmarshal(item, mapping)
+}
+
+// jsonCodec writes the protobuf JSON mapping of the StorageMapper, readable
+// with any Redis or etcd client.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return JSONMappingCodec
+}
+
+func (jsonCodec) Encode(mapping *StorageMapper) ([]byte, error) {
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(mapping)
+}
+
+func (jsonCodec) Decode(item []byte, mapping *StorageMapper) error {
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(item, mapping)
+}
+
+// msgpackKeyIndex is the KeyIndex written as an array, its times as unix
+// nanoseconds.
+type msgpackKeyIndex struct {
+	_msgpack      struct{} `msgpack:",as_array"` //nolint:unused // Read by msgpack.
+	StoredAt      int64
+	FreshTime     int64
+	StaleTime     int64
+	VariedHeaders map[string][]string
+	Etag          string
+	RealKey       string
+	Hits          uint64
+	LastAccess    int64
+}
+
+// msgpackMapping is the StorageMapper written as an array.
+type msgpackMapping struct {
+	_msgpack struct{} `msgpack:",as_array"` //nolint:unused // Read by msgpack.
+	Mapping  map[string]msgpackKeyIndex
+	Version  uint64
+	Misses   uint64
+}
+
+// DecodeMsgpack reads the KeyIndex arrays whatever their length, the fields
+// appended since a mapping was written are left to zero.
+func (i *msgpackKeyIndex) DecodeMsgpack(decoder *msgpack.Decoder) error {
+	return decodeMsgpackArray(decoder, &i.StoredAt, &i.FreshTime, &i.StaleTime, &i.VariedHeaders, &i.Etag, &i.RealKey, &i.Hits, &i.LastAccess)
+}
+
+// DecodeMsgpack reads the StorageMapper arrays whatever their length, the
+// fields appended since the mapping was written are left to zero.
+func (m *msgpackMapping) DecodeMsgpack(decoder *msgpack.Decoder) error {
+	return decodeMsgpackArray(decoder, &m.Mapping, &m.Version, &m.Misses)
+}
+
+// decodeMsgpackArray decodes the array elements into the fields in order,
+// the unknown trailing elements are skipped.
+func decodeMsgpackArray(decoder *msgpack.Decoder, fields ...any) error {
+	length, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+
+	for index := range max(length, 0) {
+		if index >= len(fields) {
+			err = decoder.Skip()
+		} else {
+			err = decoder.Decode(fields[index])
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// msgpackCodec writes the smallest mappings, without the field names.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string {
+	return MsgpackMappingCodec
+}
+
+func (msgpackCodec) Encode(mapping *StorageMapper) ([]byte, error) {
+	encoded := msgpackMapping{
+		Mapping: make(map[string]msgpackKeyIndex, len(mapping.GetMapping())),
+		Version: mapping.GetVersion(),
+		Misses:  mapping.GetMisses(),
+	}
+
+	for key, index := range mapping.GetMapping() {
+		var variedHeaders map[string][]string
+		if index.GetVariedHeaders() != nil {
+			variedHeaders = make(map[string][]string, len(index.GetVariedHeaders()))
+			for name, values := range index.GetVariedHeaders() {
+				variedHeaders[name] = values.GetHeaderValue()
+			}
+		}
+
+		encoded.Mapping[key] = msgpackKeyIndex{
+			StoredAt:      unixNano(index.GetStoredAt()),
+			FreshTime:     unixNano(index.GetFreshTime()),
+			StaleTime:     unixNano(index.GetStaleTime()),
+			VariedHeaders: variedHeaders,
+			Etag:          index.GetEtag(),
+			RealKey:       index.GetRealKey(),
+			Hits:          index.GetHits(),
+			LastAccess:    unixNano(index.GetLastAccess()),
+		}
+	}
+
+	return msgpack.Marshal(&encoded)
+}
+
+func (msgpackCodec) Decode(item []byte, mapping *StorageMapper) error {
+	var decoded msgpackMapping
+	if err := msgpack.Unmarshal(item, &decoded); err != nil {
+		return err
+	}
+
+	mapping.Version = decoded.Version
+	mapping.Misses = decoded.Misses
+	mapping.Mapping = make(map[string]*KeyIndex, len(decoded.Mapping))
+
+	for key, index := range decoded.Mapping {
+		var variedHeaders map[string]*KeyIndexStringList
+		if index.VariedHeaders != nil {
+			variedHeaders = make(map[string]*KeyIndexStringList, len(index.VariedHeaders))
+			for name, values := range index.VariedHeaders {
+				variedHeaders[name] = &KeyIndexStringList{HeaderValue: values}
+			}
+		}
+
+		var lastAccess *timestamppb.Timestamp
+		if index.LastAccess != 0 {
+			lastAccess = timestamppb.New(time.Unix(0, index.LastAccess))
+		}
+
+		mapping.Mapping[key] = &KeyIndex{
+			StoredAt:      timestamppb.New(time.Unix(0, index.StoredAt)),
+			FreshTime:     timestamppb.New(time.Unix(0, index.FreshTime)),
+			StaleTime:     timestamppb.New(time.Unix(0, index.StaleTime)),
+			VariedHeaders: variedHeaders,
+			Etag:          index.Etag,
+			RealKey:       index.RealKey,
+			Hits:          index.Hits,
+			LastAccess:    lastAccess,
+		}
+	}
+
+	return nil
+}
+
+func unixNano(timestamp *timestamppb.Timestamp) int64 {
+	if timestamp == nil {
+		return 0
+	}
+
+	return timestamp.AsTime().UnixNano()
+}
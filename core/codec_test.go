@@ -0,0 +1,140 @@
+package core_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMappingCodecs(t *testing.T) {
+	defer core.SetMappingCodec(nil)
+
+	now := time.Now().Truncate(time.Second)
+	sizes := map[string]int{}
+
+	for _, name := range []string{core.ProtobufMappingCodec, core.JSONMappingCodec, core.MsgpackMappingCodec} {
+		codec, err := core.NewMappingCodec(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		core.SetMappingCodec(codec)
+
+		mapping, err := core.MappingUpdater("varied", nil, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), http.Header{"Accept-Encoding": {"gzip"}}, "etag", "real")
+		if err != nil {
+			t.Fatalf("impossible to encode the mapping with %s, %v", name, err)
+		}
+
+		if detected := core.DetectMappingCodec(mapping).Name(); detected != name {
+			t.Errorf("the mapping written with %s should be detected, %s detected", name, detected)
+		}
+
+		// The mappings are read whatever the codec of the process.
+		core.SetMappingCodec(nil)
+
+		decoded, err := core.DecodeMapping(mapping)
+		if err != nil {
+			t.Fatalf("impossible to decode the %s mapping, %v", name, err)
+		}
+
+		index := decoded.GetMapping()["varied"]
+		if decoded.GetVersion() != 1 || index.GetRealKey() != "real" || index.GetEtag() != "etag" ||
+			!index.GetFreshTime().AsTime().Equal(now.Add(time.Minute)) ||
+			index.GetVariedHeaders()["Accept-Encoding"].GetHeaderValue()[0] != "gzip" {
+			t.Errorf("the %s mapping should be restored, got %v", name, decoded)
+		}
+
+		sizes[name] = len(mapping)
+	}
+
+	if sizes[core.MsgpackMappingCodec] >= sizes[core.JSONMappingCodec] {
+		t.Errorf("the msgpack mapping should be smaller than the JSON one, %v", sizes)
+	}
+}
+
+func TestMappingCodecs_Accesses(t *testing.T) {
+	lastAccess := time.Now().Truncate(time.Second)
+	mapping := &core.StorageMapper{
+		Mapping: map[string]*core.KeyIndex{"varied": {RealKey: "real", Hits: 42, LastAccess: timestamppb.New(lastAccess)}},
+		Version: 3,
+		Misses:  7,
+	}
+
+	for _, name := range []string{core.ProtobufMappingCodec, core.JSONMappingCodec, core.MsgpackMappingCodec} {
+		codec, _ := core.NewMappingCodec(name)
+
+		encoded, err := codec.Encode(mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, _ := core.DecodeMapping(encoded)
+		if index := decoded.GetMapping()["varied"]; decoded.GetMisses() != 7 || index.GetHits() != 42 || !index.GetLastAccess().AsTime().Equal(lastAccess) {
+			t.Errorf("the accesses should be restored by the %s codec, got %v", name, decoded)
+		}
+	}
+
+	// The msgpack mappings written before the accesses were counted are read.
+	legacy, _ := msgpack.Marshal([]any{map[string][]any{"varied": {int64(0), int64(0), int64(0), nil, "etag", "real"}}, uint64(1)})
+
+	decoded, err := core.DecodeMapping(legacy)
+	if index := decoded.GetMapping()["varied"]; err != nil || index.GetRealKey() != "real" || index.GetHits() != 0 || index.GetLastAccess() != nil {
+		t.Errorf("the legacy msgpack mapping should be read, got %v and %v", decoded, err)
+	}
+}
+
+func TestMappingCodec_JSONReadable(t *testing.T) {
+	core.SetMappingCodec(nil)
+	defer core.SetMappingCodec(nil)
+
+	now := time.Now()
+	legacy, _ := core.MappingUpdater("first", nil, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", "first")
+
+	codec, _ := core.NewMappingCodec(core.JSONMappingCodec)
+	core.SetMappingCodec(codec)
+
+	// The protobuf mapping is rewritten with the JSON codec on its update.
+	mapping, err := core.MappingUpdater("second", legacy, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(mapping, []byte("{")) || !bytes.Contains(mapping, []byte(`"real_key"`)) {
+		t.Errorf("the mapping should be written as JSON, got %s", mapping)
+	}
+
+	if decoded, _ := core.DecodeMapping(mapping); len(decoded.GetMapping()) != 2 {
+		t.Errorf("the previous variants should be kept, got %v", decoded.GetMapping())
+	}
+}
+
+func TestSetMappingCodecFromConfiguration(t *testing.T) {
+	defer core.SetMappingCodec(nil)
+
+	if err := core.SetMappingCodecFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{
+		core.MappingCodecConfigurationKey: "MsgPack",
+	}}); err != nil || core.CurrentMappingCodec().Name() != core.MsgpackMappingCodec {
+		t.Errorf("the msgpack codec should be set, %s set, %v", core.CurrentMappingCodec().Name(), err)
+	}
+
+	if err := core.SetMappingCodecFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{
+		core.MappingCodecConfigurationKey: "xml",
+	}}); err == nil {
+		t.Error("an unknown codec should be rejected")
+	}
+
+	if err := core.SetMappingCodecFromConfiguration(core.CacheProvider{}); err != nil || core.CurrentMappingCodec().Name() != core.MsgpackMappingCodec {
+		t.Error("the codec should be kept without the key")
+	}
+
+	if err := core.SetMappingCodecFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{
+		core.MappingCodecConfigurationKey: core.JSONMappingCodec,
+	}}); err == nil || core.CurrentMappingCodec().Name() != core.MsgpackMappingCodec {
+		t.Error("another codec declared by another storage should be rejected")
+	}
+}
@@ -0,0 +1,356 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Compressor compresses the responses before they are stored and
+// decompresses them when they are read back.
+type Compressor interface {
+	Compress(value []byte) ([]byte, error)
+	Decompress(value []byte) (io.Reader, error)
+	Name() string
+}
+
+const (
+	// CompressorConfigurationKey is the key read from the provider
+	// configuration to select the compressor.
+	CompressorConfigurationKey = "compressor"
+
+	LZ4Compression    = "lz4"
+	ZstdCompression   = "zstd"
+	SnappyCompression = "snappy"
+	GzipCompression   = "gzip"
+	NoCompression     = "none"
+)
+
+// Frame headers written by each codec, used to pick the right decompressor
+// for a stored value whatever the compressor configured when it was written.
+var (
+	lz4Magic    = []byte{0x04, 0x22, 0x4d, 0x18}
+	zstdMagic   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	gzipMagic   = []byte{0x1f, 0x8b}
+	snappyMagic = []byte("\xff\x06\x00\x00sNaPpY")
+)
+
+// compressors store the responses framed, their headers stay plain.
+var compressors = map[string]Compressor{
+	LZ4Compression:    observedCompressor{framedCompressor{lz4Compressor{}}},
+	ZstdCompression:   observedCompressor{framedCompressor{zstdCompressor{}}},
+	SnappyCompression: observedCompressor{framedCompressor{snappyCompressor{}}},
+	GzipCompression:   observedCompressor{framedCompressor{gzipCompressor{}}},
+	NoCompression:     observedCompressor{noneCompressor{}},
+}
+
+var compressionObserver atomic.Pointer[func(codec string, raw, compressed int)]
+
+// ObserveCompression registers the function notified with the raw and
+// compressed sizes each time a built-in compressor compresses a value.
+// Passing nil removes the observer.
+func ObserveCompression(observer func(codec string, raw, compressed int)) {
+	if observer == nil {
+		compressionObserver.Store(nil)
+
+		return
+	}
+
+	compressionObserver.Store(&observer)
+}
+
+type observedCompressor struct {
+	Compressor
+}
+
+func (o observedCompressor) Compress(value []byte) ([]byte, error) {
+	compressed, err := o.Compressor.Compress(value)
+	if observer := compressionObserver.Load(); observer != nil && err == nil {
+		(*observer)(o.Name(), len(value), len(compressed))
+	}
+
+	return compressed, err
+}
+
+// NewCompressor returns the built-in compressor matching the given name.
+// An empty name returns the LZ4 compressor.
+func NewCompressor(name string) (Compressor, error) {
+	if name == "" {
+		name = LZ4Compression
+	}
+
+	compressor, ok := compressors[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown compressor %s", name)
+	}
+
+	return compressor, nil
+}
+
+// CompressorFromConfiguration returns the compressor declared under the
+// compressor key of the provider configuration, LZ4 otherwise. The zstd
+// compressor uses the dictionary declared under the zstd_dictionary key when
+// set.
+func CompressorFromConfiguration(configuration any) (Compressor, error) {
+	if cfg, ok := configuration.(map[string]interface{}); ok {
+		name, _ := cfg[CompressorConfigurationKey].(string)
+
+		if path, _ := cfg[ZstdDictionaryConfigurationKey].(string); path != "" {
+			if name != "" && !strings.EqualFold(name, ZstdCompression) {
+				return nil, fmt.Errorf("invalid zstd_dictionary configuration: the %s compressor can't use a dictionary", name)
+			}
+
+			return zstdDictionaryCompressorFromFile(path)
+		}
+
+		if v, found := cfg[CompressorConfigurationKey]; found && v != nil {
+			return NewCompressor(name)
+		}
+	}
+
+	return NewCompressor(LZ4Compression)
+}
+
+// DetectCompressor returns the compressor that wrote the given value based
+// on its frame header, the one of the body frame for the framed responses.
+// Values without a known header are considered stored without compression.
+func DetectCompressor(value []byte) Compressor {
+	if _, bodyFrame, ok := readFrames(value); ok {
+		return framedCompressor{DetectCompressor(bodyFrame)}
+	}
+
+	switch {
+	case bytes.HasPrefix(value, lz4Magic):
+		return compressors[LZ4Compression]
+	case bytes.HasPrefix(value, zstdMagic):
+		return compressors[ZstdCompression]
+	case bytes.HasPrefix(value, snappyMagic):
+		return compressors[SnappyCompression]
+	case bytes.HasPrefix(value, gzipMagic):
+		return compressors[GzipCompression]
+	default:
+		return compressors[NoCompression]
+	}
+}
+
+// maxPooledBufferSize caps the capacity of the buffers returned to the pool,
+// a buffer grown by a large value would otherwise stay allocated.
+const maxPooledBufferSize = 1 << 20
+
+// The compression buffers and writers are pooled, every write would
+// otherwise allocate a buffer grown to the compressed size and a writer with
+// its own internal buffers. Like the lz4 writers, the writers are only
+// returned to the pool once Close flushed them.
+var (
+	compressionBufferPool = sync.Pool{New: func() any {
+		return new(bytes.Buffer)
+	}}
+	snappyWriterPool = sync.Pool{New: func() any {
+		return snappy.NewBufferedWriter(nil)
+	}}
+	gzipWriterPool = sync.Pool{New: func() any {
+		return gzip.NewWriter(nil)
+	}}
+)
+
+// getCompressionBuffer returns an empty buffer from the pool.
+func getCompressionBuffer() *bytes.Buffer {
+	buffer, _ := compressionBufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+
+	return buffer
+}
+
+// putCompressionBuffer returns the buffer to the pool, the caller must not
+// use its bytes anymore.
+func putCompressionBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	compressionBufferPool.Put(buffer)
+}
+
+// bufferedCompressor is implemented by the compressors writing to a buffer,
+// they compress into the pooled buffers.
+type bufferedCompressor interface {
+	compressTo(compressed *bytes.Buffer, value []byte) error
+}
+
+// compressPooled compresses the value into a pooled buffer and returns a
+// copy of the compressed bytes sized to fit, the buffer is reused by the
+// next compression.
+func compressPooled(c bufferedCompressor, value []byte) ([]byte, error) {
+	compressed := getCompressionBuffer()
+	defer putCompressionBuffer(compressed)
+
+	if err := c.compressTo(compressed, value); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(compressed.Bytes()), nil
+}
+
+type lz4Compressor struct{}
+
+func (lz4Compressor) Name() string {
+	return LZ4Compression
+}
+
+func (c lz4Compressor) Compress(value []byte) ([]byte, error) {
+	return compressPooled(c, value)
+}
+
+func (lz4Compressor) compressTo(compressed *bytes.Buffer, value []byte) error {
+	writer, _ := Lz4WriterPool.Get().(*lz4.Writer)
+	writer.Reset(compressed)
+
+	if _, err := writer.Write(value); err != nil {
+		_ = writer.Close()
+
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	Lz4WriterPool.Put(writer)
+
+	return nil
+}
+
+func (lz4Compressor) Decompress(value []byte) (io.Reader, error) {
+	return lz4.NewReader(bytes.NewReader(value)), nil
+}
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string {
+	return ZstdCompression
+}
+
+func (zstdCompressor) Compress(value []byte) ([]byte, error) {
+	encoder, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder.EncodeAll(value, nil), nil
+}
+
+// Decompress uses the decoder knowing the registered dictionaries when any,
+// so the values written with a dictionary are read by every zstd compressor.
+func (zstdCompressor) Decompress(value []byte) (io.Reader, error) {
+	decoder := zstdDictionaryDecoder()
+	if decoder == nil {
+		var err error
+		if decoder, err = zstdDecoder(); err != nil {
+			return nil, err
+		}
+	}
+
+	decompressed, err := decoder.DecodeAll(value, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(decompressed), nil
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Name() string {
+	return SnappyCompression
+}
+
+func (c snappyCompressor) Compress(value []byte) ([]byte, error) {
+	return compressPooled(c, value)
+}
+
+func (snappyCompressor) compressTo(compressed *bytes.Buffer, value []byte) error {
+	writer, _ := snappyWriterPool.Get().(*snappy.Writer)
+	writer.Reset(compressed)
+
+	if _, err := writer.Write(value); err != nil {
+		_ = writer.Close()
+
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	snappyWriterPool.Put(writer)
+
+	return nil
+}
+
+func (snappyCompressor) Decompress(value []byte) (io.Reader, error) {
+	return snappy.NewReader(bytes.NewReader(value)), nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string {
+	return GzipCompression
+}
+
+func (c gzipCompressor) Compress(value []byte) ([]byte, error) {
+	return compressPooled(c, value)
+}
+
+func (gzipCompressor) compressTo(compressed *bytes.Buffer, value []byte) error {
+	writer, _ := gzipWriterPool.Get().(*gzip.Writer)
+	writer.Reset(compressed)
+
+	if _, err := writer.Write(value); err != nil {
+		_ = writer.Close()
+
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	gzipWriterPool.Put(writer)
+
+	return nil
+}
+
+func (gzipCompressor) Decompress(value []byte) (io.Reader, error) {
+	return gzip.NewReader(bytes.NewReader(value))
+}
+
+type noneCompressor struct{}
+
+func (noneCompressor) Name() string {
+	return NoCompression
+}
+
+func (noneCompressor) Compress(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+func (noneCompressor) Decompress(value []byte) (io.Reader, error) {
+	return bytes.NewReader(value), nil
+}
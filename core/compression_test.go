@@ -0,0 +1,119 @@
+package core_test
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestCompressor_RoundTrip(t *testing.T) {
+	value := bytes.Repeat([]byte("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\nHello world"), 64)
+
+	for _, name := range []string{
+		core.LZ4Compression,
+		core.ZstdCompression,
+		core.SnappyCompression,
+		core.GzipCompression,
+		core.NoCompression,
+	} {
+		t.Run(name, func(t *testing.T) {
+			compressor, err := core.NewCompressor(name)
+			if err != nil {
+				t.Fatalf("Impossible to get the %s compressor: %v", name, err)
+			}
+
+			if compressor.Name() != name {
+				t.Errorf("The compressor name should be %s, %s given", name, compressor.Name())
+			}
+
+			compressed, err := compressor.Compress(value)
+			if err != nil {
+				t.Fatalf("Impossible to compress with %s: %v", name, err)
+			}
+
+			detected := core.DetectCompressor(compressed)
+			if detected.Name() != name {
+				t.Errorf("The detected compressor should be %s, %s given", name, detected.Name())
+			}
+
+			reader, err := detected.Decompress(compressed)
+			if err != nil {
+				t.Fatalf("Impossible to decompress with %s: %v", name, err)
+			}
+
+			decompressed, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("Impossible to read the decompressed value with %s: %v", name, err)
+			}
+
+			if !bytes.Equal(decompressed, value) {
+				t.Errorf("The decompressed value doesn't match the original one with %s", name)
+			}
+		})
+	}
+}
+
+func TestCompressor_PooledBuffers(t *testing.T) {
+	first := []byte("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\nfirst body")
+	second := bytes.Repeat([]byte("second body"), 128)
+
+	for _, name := range []string{core.LZ4Compression, core.SnappyCompression, core.GzipCompression} {
+		t.Run(name, func(t *testing.T) {
+			compressor, _ := core.NewCompressor(name)
+
+			var wg sync.WaitGroup
+
+			for range 8 {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					compressed, err := compressor.Compress(first)
+					if err != nil {
+						t.Errorf("Impossible to compress with %s: %v", name, err)
+
+						return
+					}
+
+					// The next compressions reuse the pooled buffer.
+					for range 4 {
+						_, _ = compressor.Compress(second)
+					}
+
+					reader, err := core.DetectCompressor(compressed).Decompress(compressed)
+					if err != nil {
+						t.Errorf("Impossible to decompress with %s: %v", name, err)
+
+						return
+					}
+
+					if decompressed, _ := io.ReadAll(reader); !bytes.Equal(decompressed, first) {
+						t.Errorf("The compressed value shouldn't share the pooled buffer with %s", name)
+					}
+				}()
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func TestCompressorFromConfiguration(t *testing.T) {
+	compressor, err := core.CompressorFromConfiguration(nil)
+	if err != nil || compressor.Name() != core.LZ4Compression {
+		t.Errorf("The default compressor should be lz4, %v given with error %v", compressor, err)
+	}
+
+	compressor, err = core.CompressorFromConfiguration(map[string]interface{}{"compressor": "zstd"})
+	if err != nil || compressor.Name() != core.ZstdCompression {
+		t.Errorf("The configured compressor should be zstd, %v given with error %v", compressor, err)
+	}
+
+	if _, err = core.CompressorFromConfiguration(map[string]interface{}{"compressor": "unknown"}); err == nil {
+		t.Error("An unknown compressor should return an error")
+	}
+}
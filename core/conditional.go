@@ -0,0 +1,68 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrConditionalSetNotSupported is returned when no storer under the
+// decorators implements ConditionalSetter.
+var ErrConditionalSetNotSupported = errors.New("the storer doesn't support the conditional sets")
+
+// ConditionalSetter is an optional interface a Storer can implement to
+// store a value atomically under a condition, to build leader elections,
+// locks or idempotent fills on top of the storage shared by the instances.
+// SetNX stores the value only if the key doesn't exist, CompareAndSwap
+// replaces the value only if the stored one equals old. Both return whether
+// the value was stored, a false without error means the condition failed.
+// Unlike Set, the ttl isn't extended by the stale duration: the value
+// expires after ttl, a zero ttl keeps it until it's deleted.
+type ConditionalSetter interface {
+	SetNX(key string, value []byte, ttl time.Duration) (bool, error)
+	CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error)
+}
+
+// ConditionalSetterFor returns the ConditionalSetter implemented by the
+// storer or one of the storers it decorates. The values are compared and
+// stored as given by the first storer implementing it, the EncryptedStorer
+// compares and stores them encrypted.
+func ConditionalSetterFor(storer Storer) (ConditionalSetter, bool) {
+	for storer != nil {
+		if setter, ok := storer.(ConditionalSetter); ok {
+			return setter, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// SetNX stores the value only if the key doesn't exist, using the
+// ConditionalSetter of the storer. It returns ErrConditionalSetNotSupported
+// when there is none.
+func SetNX(storer Storer, key string, value []byte, ttl time.Duration) (bool, error) {
+	setter, ok := ConditionalSetterFor(storer)
+	if !ok {
+		return false, ErrConditionalSetNotSupported
+	}
+
+	return setter.SetNX(key, value, ttl)
+}
+
+// CompareAndSwap replaces the value only if the stored one equals old, using
+// the ConditionalSetter of the storer. It returns
+// ErrConditionalSetNotSupported when there is none.
+func CompareAndSwap(storer Storer, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	setter, ok := ConditionalSetterFor(storer)
+	if !ok {
+		return false, ErrConditionalSetNotSupported
+	}
+
+	return setter.CompareAndSwap(key, old, value, ttl)
+}
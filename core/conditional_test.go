@@ -0,0 +1,106 @@
+package core_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// conditionalStorer is a memoryStorer implementing the ConditionalSetter.
+type conditionalStorer struct {
+	*memoryStorer
+}
+
+func (c conditionalStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	if c.Get(key) != nil {
+		return false, nil
+	}
+
+	return true, c.Set(key, value, ttl)
+}
+
+func (c conditionalStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	if current := c.Get(key); current == nil || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	return true, c.Set(key, value, ttl)
+}
+
+func TestSetNX(t *testing.T) {
+	storer, err := core.NewPrefixedStorer(conditionalStorer{newMemoryStorer()}, "tenant-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, err := core.SetNX(storer, "leader", []byte("first"), time.Minute); !stored || err != nil {
+		t.Fatalf("the absent key should be stored, got %v, %v", stored, err)
+	}
+
+	if stored, _ := core.SetNX(storer, "leader", []byte("second"), time.Minute); stored {
+		t.Error("the existing key shouldn't be replaced")
+	}
+
+	if value := storer.Get("leader"); string(value) != "first" {
+		t.Errorf("the prefixed key should hold the first value, got %s", value)
+	}
+
+	if stored, _ := core.SetNX(storer, core.MappingKeyPrefix+"base", []byte("mapping"), time.Minute); !stored || storer.Unwrap().Get(core.MappingKeyPrefix+"tenant-base") == nil {
+		t.Error("the mapping key should keep the mapping prefix first")
+	}
+
+	if _, err := core.SetNX(newMemoryStorer(), "leader", []byte("first"), time.Minute); !errors.Is(err, core.ErrConditionalSetNotSupported) {
+		t.Errorf("the storer without conditional set should be reported, got %v", err)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	storer := core.NewReadOnlyStorer(conditionalStorer{newMemoryStorer()}, false, nopLogger{})
+	_ = storer.Set("counter", []byte("1"), time.Minute)
+
+	if swapped, _ := core.CompareAndSwap(storer, "counter", []byte("0"), []byte("2"), time.Minute); swapped {
+		t.Error("the value shouldn't be swapped when the old one differs")
+	}
+
+	if swapped, err := core.CompareAndSwap(storer, "counter", []byte("1"), []byte("2"), time.Minute); !swapped || err != nil {
+		t.Fatalf("the value should be swapped, got %v, %v", swapped, err)
+	}
+
+	if value := storer.Get("counter"); string(value) != "2" {
+		t.Errorf("the swapped value should be stored, got %s", value)
+	}
+
+	storer.SetReadOnly(true)
+
+	if _, err := core.CompareAndSwap(storer, "counter", []byte("2"), []byte("3"), time.Minute); !errors.Is(err, core.ErrReadOnly) {
+		t.Errorf("the read-only storer should reject the swap, got %v", err)
+	}
+}
+
+func TestConditionalSet_Decorators(t *testing.T) {
+	quota, _ := core.NewQuotaStorer(conditionalStorer{newMemoryStorer()}, core.QuotaOptions{MaxEntries: 1}, nopLogger{})
+
+	_, _ = core.SetNX(quota, "first", []byte("value"), time.Minute)
+	_, _ = core.SetNX(quota, "second", []byte("value"), time.Minute)
+
+	if entries, _ := quota.Usage(); entries != 1 || quota.Get("first") != nil {
+		t.Errorf("the conditional sets should be counted against the quota, %d entries given", entries)
+	}
+
+	secondary := newMemoryStorer()
+
+	replicated, err := core.NewReplicatedStorer(conditionalStorer{newMemoryStorer()}, []core.Storer{secondary}, core.ReplicationOptions{}, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = core.SetNX(replicated, "leader", []byte("first"), time.Minute)
+	_, _ = core.CompareAndSwap(replicated, "leader", []byte("first"), []byte("second"), time.Minute)
+
+	if value := secondary.Get("leader"); string(value) != "second" {
+		t.Errorf("the conditional sets should reach the secondaries, got %s", value)
+	}
+}
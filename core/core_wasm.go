@@ -6,11 +6,8 @@ import (
 	"bufio"
 	"bytes"
 	"net/http"
-	"strings"
 	"time"
 
-	"github.com/pierrec/lz4/v4"
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -41,16 +38,34 @@ type CacheProvider struct {
 	Configuration any `json:"configuration" yaml:"configuration"`
 }
 
-const MappingKeyPrefix = "IDX_"
+const (
+	MappingKeyPrefix   = "IDX_"
+	SurrogateKeyPrefix = "SURROGATE_"
+)
 
 func DecodeMapping(item []byte) (*StorageMapper, error) {
 	mapping := &StorageMapper{}
-	e := proto.Unmarshal(item, mapping)
+	e := DetectMappingCodec(item).Decode(item, mapping)
 
 	return mapping, e
 }
 
+func readResponse(data []byte, req *http.Request) (*http.Response, error) {
+	bufW := new(bytes.Buffer)
+	if reader, err := DetectCompressor(data).Decompress(data); err == nil {
+		_, _ = bufW.ReadFrom(reader)
+	}
+
+	return http.ReadResponse(bufio.NewReader(bufW), req)
+}
+
 func MappingElection(provider Storer, item []byte, req *http.Request, validator *Revalidator, logger Logger) (resultFresh *http.Response, resultStale *http.Response, e error) {
+	return MappingElectionAt(provider, item, req, validator, logger, time.Now())
+}
+
+// MappingElectionAt is MappingElection telling the fresh and the stale keys
+// apart at now, for the storers running on their own clock.
+func MappingElectionAt(provider Storer, item []byte, req *http.Request, validator *Revalidator, logger Logger, now time.Time) (resultFresh *http.Response, resultStale *http.Response, e error) {
 	mapping := &StorageMapper{}
 
 	if len(item) != 0 {
@@ -60,77 +75,74 @@ func MappingElection(provider Storer, item []byte, req *http.Request, validator
 		}
 	}
 
-	for keyName, keyItem := range mapping.GetMapping() {
-		valid := true
+	candidates := electionCandidates(mapping, req, validator, true, now, logger)
+	responses := loadCandidates(provider, candidates)
 
-		for hname, hval := range keyItem.GetVariedHeaders() {
-			if req.Header.Get(hname) != strings.Join(hval.GetHeaderValue(), ", ") {
-				valid = false
+	for _, candidate := range candidates {
+		if candidate.notModified {
+			*validator = candidate.state
 
-				break
-			}
+			logger.Debugf("The stored key %s matched the conditional request, its body isn't loaded", candidate.key)
+
+			return resultFresh, resultStale, e
 		}
 
-		if !valid {
+		response, found := responses[candidate.key]
+		if !found {
 			continue
 		}
 
-		ValidateETagFromHeader(keyItem.GetEtag(), validator)
+		if candidate.fresh {
+			*validator = candidate.state
+			validator.FreshKey = candidate.key
 
-		if validator.Matched {
-			// If the key is fresh enough.
-			if time.Since(keyItem.GetFreshTime().AsTime()) < 0 {
-				response := provider.Get(keyName)
-				if response != nil {
-					bufW := new(bytes.Buffer)
-					reader := lz4.NewReader(bytes.NewBuffer(response))
-					_, _ = reader.WriteTo(bufW)
+			if resultFresh, e = readResponse(response, req); e != nil {
+				logger.Errorf("An error occurred while reading response for the key %s: %v", candidate.key, e)
 
-					if resultFresh, e = http.ReadResponse(bufio.NewReader(bufW), req); e != nil {
-						logger.Errorf("An error occurred while reading response for the key %s: %v", keyName, e)
-
-						return resultFresh, resultStale, e
-					}
+				return resultFresh, resultStale, e
+			}
 
-					logger.Debugf("The stored key %s matched the current iteration key ETag %+v", keyName, validator)
+			setElectedAge(resultFresh, candidate.index, now)
 
-					return resultFresh, resultStale, e
-				}
-			}
+			logger.Debugf("The stored key %s matched the current iteration key ETag %+v", candidate.key, validator)
 
-			// If the key is still stale.
-			if time.Since(keyItem.GetStaleTime().AsTime()) < 0 {
-				response := provider.Get(keyName)
-				if response != nil {
-					bufW := new(bytes.Buffer)
-					reader := lz4.NewReader(bytes.NewBuffer(response))
-					_, _ = reader.WriteTo(bufW)
+			return resultFresh, resultStale, e
+		}
 
-					if resultStale, e = http.ReadResponse(bufio.NewReader(bufW), req); e != nil {
-						logger.Errorf("An error occurred while reading response for the key %s: %v", keyName, e)
+		if resultStale, e = readResponse(response, req); e != nil {
+			*validator = candidate.state
 
-						return resultFresh, resultStale, e
-					}
+			logger.Errorf("An error occurred while reading response for the key %s: %v", candidate.key, e)
 
-					logger.Debugf("The stored key %s matched the current iteration key ETag %+v as stale", keyName, validator)
-				}
-			}
-		} else {
-			logger.Debugf("The stored key %s didn't match the current iteration key ETag %+v", keyName, validator)
+			return resultFresh, resultStale, e
 		}
+
+		setElectedAge(resultStale, candidate.index, now)
+		validator.StaleKey = candidate.key
+
+		logger.Debugf("The stored key %s matched the current iteration key ETag %+v as stale", candidate.key, &candidate.state)
 	}
 
 	return resultFresh, resultStale, e
 }
 
 func MappingUpdater(key string, item []byte, logger Logger, now, freshTime, staleTime time.Time, variedHeaders http.Header, etag, realKey string) (val []byte, e error) {
+	val, _, e = MappingUpdaterWithEvictions(key, item, logger, now, freshTime, staleTime, variedHeaders, etag, realKey)
+
+	return val, e
+}
+
+// MappingUpdaterWithEvictions is MappingUpdater returning the keys of the
+// variants evicted to respect MaxVariants, their values must be deleted
+// once the mapping is stored, e.g. with DeleteEvictedVariants.
+func MappingUpdaterWithEvictions(key string, item []byte, logger Logger, now, freshTime, staleTime time.Time, variedHeaders http.Header, etag, realKey string) (val []byte, evicted []string, e error) {
 	mapping := &StorageMapper{}
 	if len(item) != 0 {
-		e = proto.Unmarshal(item, mapping)
+		e = DetectMappingCodec(item).Decode(item, mapping)
 		if e != nil {
 			logger.Errorf("Impossible to decode the key %s, %v", key, e)
 
-			return nil, e
+			return nil, nil, e
 		}
 	}
 
@@ -138,15 +150,20 @@ func MappingUpdater(key string, item []byte, logger Logger, now, freshTime, stal
 		mapping.Mapping = make(map[string]*KeyIndex)
 	}
 
+	// The expired entries are dropped on each update.
+	pruneMapping(mapping, now)
+
 	var pbvariedeheader map[string]*KeyIndexStringList
 	if variedHeaders != nil {
 		pbvariedeheader = make(map[string]*KeyIndexStringList)
 	}
 
-	for k, v := range variedHeaders {
+	for k, v := range normalizeVariedHeaders(variedHeaders) {
 		pbvariedeheader[k] = &KeyIndexStringList{HeaderValue: v}
 	}
 
+	// The accesses counted before the key is stored again are kept.
+	previous := mapping.GetMapping()[key]
 	mapping.Mapping[key] = &KeyIndex{
 		StoredAt:      timestamppb.New(now),
 		FreshTime:     timestamppb.New(freshTime),
@@ -154,14 +171,18 @@ func MappingUpdater(key string, item []byte, logger Logger, now, freshTime, stal
 		VariedHeaders: pbvariedeheader,
 		Etag:          etag,
 		RealKey:       realKey,
+		Hits:          previous.GetHits(),
+		LastAccess:    previous.GetLastAccess(),
 	}
+	evicted = evictVariants(mapping, MaxVariants(), key)
+	mapping.Version++
 
-	val, e = proto.Marshal(mapping)
+	val, e = EncodeMapping(mapping)
 	if e != nil {
 		logger.Errorf("Impossible to encode the mapping value for the key %s, %v", key, e)
 
-		return nil, e
+		return nil, nil, e
 	}
 
-	return val, e
+	return val, evicted, e
 }
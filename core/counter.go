@@ -0,0 +1,125 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ErrCounterNotSupported is returned when no storer under the decorators
+// implements Counter or ConditionalSetter.
+var ErrCounterNotSupported = errors.New("the storer doesn't support the counters")
+
+// Counter is an optional interface a Storer can implement to increment a
+// counter atomically, e.g. for the rate limiting or the hit counting, with
+// Redis INCRBY, Olric Incr or a lock held by the in-memory storages. The
+// counter starts at zero when the key doesn't exist and a negative delta
+// decrements it. The counter is stored as a decimal string and expires after
+// ttl, reset by each increment, a zero ttl keeps it until it's deleted.
+type Counter interface {
+	Increment(key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// CounterFor returns the Counter implemented by the storer or one of the
+// storers it decorates.
+func CounterFor(storer Storer) (Counter, bool) {
+	for storer != nil {
+		if counter, ok := storer.(Counter); ok {
+			return counter, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// Increment adds delta to the counter stored under the key and returns its
+// new value, using the Counter of the storer. It falls back on
+// CompareAndSwap retried on conflict when the storer implements the
+// ConditionalSetter only, and returns ErrCounterNotSupported otherwise.
+func Increment(storer Storer, key string, delta int64, ttl time.Duration) (int64, error) {
+	if counter, ok := CounterFor(storer); ok {
+		return counter.Increment(key, delta, ttl)
+	}
+
+	setter, ok := ConditionalSetterFor(storer)
+	if !ok {
+		return 0, ErrCounterNotSupported
+	}
+
+	// The counter is read from the storer implementing the ConditionalSetter
+	// since the decorators above it are bypassed.
+	if decorated, ok := setter.(Storer); ok {
+		storer = decorated
+	}
+
+	var counter int64
+
+	err := UpdateMapping(func() error {
+		current, err := Lookup(storer, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			if counter, err = AddToCounter(key, nil, delta); err != nil {
+				return err
+			}
+
+			stored, err := setter.SetNX(key, EncodeCounter(counter), ttl)
+			if err == nil && !stored {
+				err = ErrMappingConflict
+			}
+
+			return err
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if counter, err = AddToCounter(key, current, delta); err != nil {
+			return err
+		}
+
+		swapped, err := setter.CompareAndSwap(key, current, EncodeCounter(counter), ttl)
+		if err == nil && !swapped {
+			err = ErrMappingConflict
+		}
+
+		return err
+	})
+
+	return counter, err
+}
+
+// AddToCounter returns the counter stored in value, zero when it's empty,
+// incremented by delta. It fails when the value isn't a counter or when the
+// result overflows.
+func AddToCounter(key string, value []byte, delta int64) (int64, error) {
+	var counter int64
+
+	if len(value) > 0 {
+		parsed, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("the value of the key %s is not a counter: %w", key, err)
+		}
+
+		counter = parsed
+	}
+
+	if (delta > 0 && counter > math.MaxInt64-delta) || (delta < 0 && counter < math.MinInt64-delta) {
+		return 0, fmt.Errorf("the counter %s overflows", key)
+	}
+
+	return counter + delta, nil
+}
+
+// EncodeCounter returns the decimal string stored for the counter.
+func EncodeCounter(counter int64) []byte {
+	return strconv.AppendInt(nil, counter, 10)
+}
@@ -0,0 +1,59 @@
+package core_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestIncrement(t *testing.T) {
+	storer, err := core.NewPrefixedStorer(conditionalStorer{newMemoryStorer()}, "tenant-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counter, err := core.Increment(storer, "hits", 2, time.Minute); err != nil || counter != 2 {
+		t.Fatalf("the missing counter should start at zero, got %d, %v", counter, err)
+	}
+
+	if counter, err := core.Increment(storer, "hits", -5, time.Minute); err != nil || counter != -3 {
+		t.Errorf("the counter should be decremented, got %d, %v", counter, err)
+	}
+
+	if value := storer.Get("hits"); string(value) != "-3" {
+		t.Errorf("the counter should be stored as a decimal string, got %s", value)
+	}
+
+	_, _ = core.Increment(storer, core.MappingKeyPrefix+"hits", 1, time.Minute)
+
+	if storer.Unwrap().Get(core.MappingKeyPrefix+"tenant-hits") == nil {
+		t.Error("the mapping key should keep the mapping prefix first")
+	}
+
+	_ = storer.Set("value", []byte("not a counter"), time.Minute)
+
+	if _, err := core.Increment(storer, "value", 1, time.Minute); err == nil {
+		t.Error("a value which isn't a counter should be rejected")
+	}
+
+	if _, err := core.Increment(newMemoryStorer(), "hits", 1, time.Minute); !errors.Is(err, core.ErrCounterNotSupported) {
+		t.Errorf("the storer without counters should be reported, got %v", err)
+	}
+}
+
+func TestAddToCounter(t *testing.T) {
+	if counter, err := core.AddToCounter("hits", core.EncodeCounter(41), 1); err != nil || counter != 42 {
+		t.Errorf("the counter should be incremented, got %d, %v", counter, err)
+	}
+
+	if _, err := core.AddToCounter("hits", core.EncodeCounter(math.MaxInt64), 1); err == nil {
+		t.Error("the overflow should be rejected")
+	}
+
+	if _, err := core.AddToCounter("hits", core.EncodeCounter(math.MinInt64), -1); err == nil {
+		t.Error("the underflow should be rejected")
+	}
+}
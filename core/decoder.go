@@ -0,0 +1,95 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// SharedConfigurationKeys are read by core from every provider configuration,
+// the providers never declare them in their own configuration.
+var SharedConfigurationKeys = []string{
+	CompressorConfigurationKey,
+	ZstdDictionaryConfigurationKey,
+	TLSConfigurationKey,
+	ReconnectorConfigurationKey,
+	EncryptionConfigurationKey,
+	StreamConfigurationKey,
+	AsyncConfigurationKey,
+	CircuitBreakerConfigurationKey,
+	RetryConfigurationKey,
+	KeyPrefixConfigurationKey,
+	KeyHashingConfigurationKey,
+	QuotaConfigurationKey,
+	MaxValueBytesConfigurationKey,
+	ValueOverflowConfigurationKey,
+	SpillStorageConfigurationKey,
+	OperationTimeoutConfigurationKey,
+	ReadTimeoutConfigurationKey,
+	WriteTimeoutConfigurationKey,
+	MappingGCIntervalConfigurationKey,
+	ReadOnlyConfigurationKey,
+	TTLJitterConfigurationKey,
+	InvalidationConfigurationKey,
+	VaryNormalizationConfigurationKey,
+	MaxVariantsConfigurationKey,
+	MappingCodecConfigurationKey,
+	PoolConfigurationKey,
+	InstanceIDConfigurationKey,
+	DedupConfigurationKey,
+	DeleteManyWorkersConfigurationKey,
+	BackgroundRateLimitConfigurationKey,
+	PurgeScheduleConfigurationKey,
+	AnalyticsConfigurationKey,
+	AccessTrackingConfigurationKey,
+}
+
+// DecodeConfiguration decodes the provider configuration map into the typed
+// target, matching the keys with the json struct tags. The values are
+// converted when possible ("10" to 10, "1m" to a time.Duration, "a,b" to a
+// slice) and the error names the malformed or unknown keys. The shared keys
+// are ignored, a field tagged `json:",remain"` collects the unknown keys
+// instead of failing.
+func DecodeConfiguration(configuration any, target any) error {
+	if configuration == nil {
+		return nil
+	}
+
+	var metadata mapstructure.Metadata
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		Metadata:         &metadata,
+		Result:           target,
+		TagName:          "json",
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = decoder.Decode(configuration); err != nil {
+		return err
+	}
+
+	unknown := []string{}
+
+	for _, key := range metadata.Unused {
+		if !slices.Contains(SharedConfigurationKeys, key) {
+			unknown = append(unknown, key)
+		}
+	}
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+
+		return fmt.Errorf("unknown configuration keys %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
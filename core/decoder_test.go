@@ -0,0 +1,71 @@
+package core_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+type decodedConfiguration struct {
+	Size     int            `json:"size"`
+	Interval time.Duration  `json:"interval"`
+	Servers  []string       `json:"servers"`
+	Secure   bool           `json:"secure"`
+	Options  map[string]any `json:",remain"`
+}
+
+func TestDecodeConfiguration(t *testing.T) {
+	var cfg decodedConfiguration
+
+	err := core.DecodeConfiguration(map[string]interface{}{
+		"size":                          "10",
+		"interval":                      "1m",
+		"servers":                       "a:1,b:2",
+		"secure":                        "true",
+		"MaxRetries":                    3,
+		core.CompressorConfigurationKey: "zstd",
+	}, &cfg)
+	if err != nil {
+		t.Fatalf("The configuration should be decoded: %v", err)
+	}
+
+	if cfg.Size != 10 || cfg.Interval != time.Minute || len(cfg.Servers) != 2 || !cfg.Secure {
+		t.Errorf("The values should be converted, %+v given", cfg)
+	}
+
+	if cfg.Options["MaxRetries"] != 3 {
+		t.Errorf("The remaining keys should be collected, %+v given", cfg.Options)
+	}
+
+	if _, found := cfg.Options[core.CompressorConfigurationKey]; !found {
+		t.Error("The shared keys should be collected too")
+	}
+}
+
+func TestDecodeConfiguration_Errors(t *testing.T) {
+	var strict struct {
+		Size     int           `json:"size"`
+		Interval time.Duration `json:"interval"`
+	}
+
+	err := core.DecodeConfiguration(map[string]interface{}{"size": "ten"}, &strict)
+	if err == nil || !strings.Contains(err.Error(), "size") {
+		t.Errorf("The malformed key should be named, %v given", err)
+	}
+
+	err = core.DecodeConfiguration(map[string]interface{}{"interval": "1 minute"}, &strict)
+	if err == nil || !strings.Contains(err.Error(), "interval") {
+		t.Errorf("The malformed duration should be named, %v given", err)
+	}
+
+	err = core.DecodeConfiguration(map[string]interface{}{"sise": 10, core.TLSConfigurationKey: map[string]interface{}{}}, &strict)
+	if err == nil || err.Error() != "unknown configuration keys sise" {
+		t.Errorf("The unknown key should be named, %v given", err)
+	}
+
+	if err = core.DecodeConfiguration(nil, &strict); err != nil {
+		t.Errorf("A nil configuration should be valid, %v given", err)
+	}
+}
@@ -0,0 +1,582 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DedupConfigurationKey is the key read from the provider configuration
+	// to store the identical response bodies once.
+	DedupConfigurationKey = "dedup"
+
+	// DedupBodyKeyPrefix prefixes the keys of the bodies, named by their
+	// SHA-256 digest.
+	DedupBodyKeyPrefix = "DEDUP_BODY_"
+	// DedupRefsKeyPrefix prefixes the keys counting the references to each
+	// body.
+	DedupRefsKeyPrefix = "DEDUP_REFS_"
+
+	// BodyDigestHeader is added to the responses stored without their body,
+	// it holds the digest of the body stored under DedupBodyKeyPrefix.
+	BodyDigestHeader = "Storages-Body-Digest"
+
+	defaultDedupMinSize = 512
+)
+
+// DedupOptions tunes the DedupStorer.
+type DedupOptions struct {
+	// MinSize is the size of the bodies at least to store them apart, the
+	// smaller ones are stored within their response. 512 bytes by default.
+	MinSize int `json:"min_size"`
+	// GCInterval is the delay between two collections of the bodies no
+	// longer referenced, e.g. after a DeleteMany. Zero disables them, the
+	// bodies expire with their longest lived response anyway.
+	GCInterval time.Duration `json:"gc_interval"`
+}
+
+// errDedupRefsConflict is returned when the references count of a body was
+// updated by another instance between its read and its write.
+var errDedupRefsConflict = errors.New("the references count was updated concurrently")
+
+// dedupRefs is the references count of a body with the expiration of the
+// longest lived response referencing it.
+type dedupRefs struct {
+	Refs      int64     `json:"refs"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DedupStorer decorates any Storer to store the identical response bodies
+// once, e.g. the shared assets or the error pages served under many URLs.
+// The body of each response given to SetMultiLevel is stored under its
+// SHA-256 digest and the varied key only holds the response headers with the
+// BodyDigestHeader, the body is restored when the response is read. The
+// references to each body are counted, it's deleted with the last one.
+type DedupStorer struct {
+	Storer
+
+	options DedupOptions
+	stale   time.Duration
+	logger  Logger
+	limiter *BackgroundLimiter
+	refs    sync.Mutex
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewDedupStorer wraps the storer, the collection of the bodies no longer
+// referenced starts on Init.
+func NewDedupStorer(storer Storer, options DedupOptions, stale time.Duration, logger Logger) (*DedupStorer, error) {
+	if options.MinSize == 0 {
+		options.MinSize = defaultDedupMinSize
+	}
+
+	if options.MinSize < 0 {
+		return nil, fmt.Errorf("invalid dedup configuration: the min_size can't be negative, %d given", options.MinSize)
+	}
+
+	if options.GCInterval < 0 {
+		return nil, fmt.Errorf("invalid dedup configuration: the gc_interval can't be negative, %s given", options.GCInterval)
+	}
+
+	return &DedupStorer{Storer: storer, options: options, stale: stale, logger: logger}, nil
+}
+
+// dedupConfiguration is the typed dedup block read from the provider
+// configuration.
+type dedupConfiguration struct {
+	Dedup  DedupOptions   `json:"dedup"`
+	Others map[string]any `json:",remain"`
+}
+
+// DedupStorerFromConfiguration wraps the storer when the dedup key is set in
+// the provider configuration, it returns the storer untouched otherwise.
+func DedupStorerFromConfiguration(storer Storer, provider CacheProvider, stale time.Duration, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	if _, ok = cfg[DedupConfigurationKey]; !ok {
+		return storer, nil
+	}
+
+	var dedup dedupConfiguration
+	if err := DecodeConfiguration(cfg, &dedup); err != nil {
+		return nil, fmt.Errorf("invalid dedup configuration: %w", err)
+	}
+
+	limiter, err := BackgroundLimiterFromConfiguration(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dedupStorer, err := NewDedupStorer(storer, dedup.Dedup, stale, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	dedupStorer.limiter = limiter
+
+	return dedupStorer, nil
+}
+
+// Unwrap returns the decorated storer.
+func (s *DedupStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// bodyDigest returns the digest of the body referenced by the stored value,
+// empty when the value holds its body.
+func bodyDigest(value []byte) string {
+	reader, err := DetectCompressor(value).Decompress(value)
+	if err != nil {
+		return ""
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(reader), nil)
+	if err != nil {
+		return ""
+	}
+
+	_ = response.Body.Close()
+
+	return response.Header.Get(BodyDigestHeader)
+}
+
+// storedVariedKey returns the key the varied key is stored under, as
+// referenced by the mapping of the base key. The storages such as Redis in
+// cluster mode prefix it with a hash tag.
+func (s *DedupStorer) storedVariedKey(baseKey, variedKey string) string {
+	metadata, _ := GetMetadata(s.Storer, baseKey)
+
+	for _, entry := range metadata {
+		if entry.Key == variedKey {
+			return variedKey
+		}
+
+		if tag, found := strings.CutSuffix(entry.Key, variedKey); found && strings.HasPrefix(tag, "{") && strings.HasSuffix(tag, "}") {
+			return entry.Key
+		}
+	}
+
+	return variedKey
+}
+
+// split returns the response headers referencing the body stored under its
+// digest, false when the value isn't a response or its body is too small.
+func (s *DedupStorer) split(value []byte) (reference []byte, digest string, body []byte, ok bool) {
+	if !bytes.HasPrefix(value, []byte("HTTP/")) {
+		return nil, "", nil, false
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(value)), nil)
+	if err != nil {
+		return nil, "", nil, false
+	}
+
+	body, err = io.ReadAll(response.Body)
+	_ = response.Body.Close()
+
+	if err != nil || len(body) < s.options.MinSize {
+		return nil, "", nil, false
+	}
+
+	sum := sha256.Sum256(body)
+	digest = hex.EncodeToString(sum[:])
+
+	response.Header.Set(BodyDigestHeader, digest)
+	response.Header.Del("Content-Length")
+	response.Body = http.NoBody
+	response.ContentLength = 0
+	response.TransferEncoding = nil
+
+	var buffer bytes.Buffer
+	if err = response.Write(&buffer); err != nil {
+		return nil, "", nil, false
+	}
+
+	return buffer.Bytes(), digest, body, true
+}
+
+// restore replaces the empty body of the response by the one stored under
+// its digest, it returns nil when the body is missing.
+func (s *DedupStorer) restore(response *http.Response) *http.Response {
+	if response == nil {
+		return nil
+	}
+
+	digest := response.Header.Get(BodyDigestHeader)
+	if digest == "" {
+		return response
+	}
+
+	_ = response.Body.Close()
+
+	body, err := Lookup(s.Storer, DedupBodyKeyPrefix+digest)
+	if err != nil {
+		s.logger.Errorf("Impossible to load the body %s, %v", digest, err)
+
+		return nil
+	}
+
+	response.Header.Del(BodyDigestHeader)
+	response.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	response.ContentLength = int64(len(body))
+
+	return response
+}
+
+// resolve returns the value with its body restored, the value untouched
+// when it holds its body.
+func (s *DedupStorer) resolve(key string, value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	reader, err := DetectCompressor(value).Decompress(value)
+	if err != nil {
+		return value, nil
+	}
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil || !bytes.HasPrefix(decompressed, []byte("HTTP/")) || !bytes.Contains(decompressed, []byte(BodyDigestHeader)) {
+		return value, nil
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(decompressed)), nil)
+	if err != nil || response.Header.Get(BodyDigestHeader) == "" {
+		return value, nil
+	}
+
+	if response = s.restore(response); response == nil {
+		return nil, fmt.Errorf("impossible to restore the body of the key %s", key)
+	}
+
+	var buffer bytes.Buffer
+	if err = response.Write(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Get method returns the stored value with its body restored, nil when the
+// body is missing.
+func (s *DedupStorer) Get(key string) []byte {
+	value, err := s.resolve(key, s.Storer.Get(key))
+	if err != nil {
+		s.logger.Errorf("Impossible to get the key %s, %v", key, err)
+
+		return nil
+	}
+
+	return value
+}
+
+// Lookup method returns the stored value with its body restored.
+func (s *DedupStorer) Lookup(key string) ([]byte, error) {
+	value, err := Lookup(s.Storer, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolve(key, value)
+}
+
+// GetMultiLevel returns the fresh and stale candidates with their body
+// restored.
+func (s *DedupStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale = s.Storer.GetMultiLevel(key, req, validator)
+
+	return s.restore(fresh), s.restore(stale)
+}
+
+// SetMultiLevel stores the body apart under its digest then the response
+// headers referencing it. The body previously referenced by the varied key
+// is released.
+func (s *DedupStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	previous := bodyDigest(s.Storer.Get(s.storedVariedKey(baseKey, variedKey)))
+
+	reference, digest, body, ok := s.split(value)
+	if !ok {
+		if err := s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey); err != nil {
+			return err
+		}
+
+		s.release(previous)
+
+		return nil
+	}
+
+	if err := s.retain(digest, body, duration+s.stale); err != nil {
+		s.logger.Errorf("Impossible to store the body %s of the key %s, %v", digest, variedKey, err)
+
+		return err
+	}
+
+	if err := s.Storer.SetMultiLevel(baseKey, variedKey, reference, variedHeaders, etag, duration, realKey); err != nil {
+		s.release(digest)
+
+		return err
+	}
+
+	s.release(previous)
+
+	return nil
+}
+
+// Delete method deletes the key and releases the body it references.
+func (s *DedupStorer) Delete(key string) {
+	digest := bodyDigest(s.Storer.Get(key))
+
+	s.Storer.Delete(key)
+	s.release(digest)
+}
+
+// updateRefs applies the update to the references count of the body and
+// stores it with CompareAndSwap against the count read, retried on
+// conflict, so the instances sharing the storage don't lose their
+// references. The storers without ConditionalSetter are only guarded by the
+// lock of this instance. It returns the updated count.
+func (s *DedupStorer) updateRefs(digest string, update func(refs *dedupRefs) error) (dedupRefs, error) {
+	key := DedupRefsKeyPrefix + digest
+
+	var refs dedupRefs
+
+	err := retryOnConflict(errDedupRefsConflict, func() error {
+		refs = dedupRefs{}
+
+		current, err := Lookup(s.Storer, key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+
+		if len(current) > 0 {
+			_ = json.Unmarshal(current, &refs)
+		}
+
+		if err = update(&refs); err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(refs)
+		if err != nil {
+			return err
+		}
+
+		var swapped bool
+
+		if len(current) == 0 {
+			swapped, err = SetNX(s.Storer, key, value, time.Until(refs.ExpiresAt))
+		} else {
+			swapped, err = CompareAndSwap(s.Storer, key, current, value, time.Until(refs.ExpiresAt))
+		}
+
+		if errors.Is(err, ErrConditionalSetNotSupported) {
+			return s.Storer.Set(key, value, time.Until(refs.ExpiresAt))
+		}
+
+		if err == nil && !swapped {
+			err = errDedupRefsConflict
+		}
+
+		return err
+	})
+
+	return refs, err
+}
+
+// retain counts a new reference to the body, the body is stored when it's
+// missing and its expiration extended to the one of the reference.
+func (s *DedupStorer) retain(digest string, body []byte, ttl time.Duration) error {
+	s.refs.Lock()
+	defer s.refs.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+
+	_, err := s.updateRefs(digest, func(refs *dedupRefs) error {
+		_, err := Lookup(s.Storer, DedupBodyKeyPrefix+digest)
+		if missing := errors.Is(err, ErrKeyNotFound); missing || expiresAt.After(refs.ExpiresAt) {
+			if missing {
+				*refs = dedupRefs{}
+			}
+
+			if err = s.Storer.Set(DedupBodyKeyPrefix+digest, body, ttl); err != nil {
+				return err
+			}
+
+			refs.ExpiresAt = expiresAt
+		}
+
+		refs.Refs++
+
+		return nil
+	})
+
+	return err
+}
+
+// release drops a reference to the body, the body is deleted with its last
+// reference.
+func (s *DedupStorer) release(digest string) {
+	if digest == "" {
+		return
+	}
+
+	s.refs.Lock()
+	defer s.refs.Unlock()
+
+	refs, err := s.updateRefs(digest, func(refs *dedupRefs) error {
+		refs.Refs--
+
+		return nil
+	})
+	if err != nil {
+		s.logger.Errorf("Impossible to release the body %s, %v", digest, err)
+
+		return
+	}
+
+	if refs.Refs > 0 {
+		return
+	}
+
+	s.Storer.Delete(DedupBodyKeyPrefix + digest)
+	s.Storer.Delete(DedupRefsKeyPrefix + digest)
+}
+
+// Collect deletes the bodies no longer referenced by a stored response, e.g.
+// after a DeleteMany which can't release them, and fixes the references
+// count of the others. It returns the number of deleted bodies.
+func (s *DedupStorer) Collect() (int, error) {
+	now := time.Now()
+	referenced := map[string]int64{}
+
+	walkErr := walkMappings(s.Storer, func(_ string, mapping []byte) bool {
+		metadata, err := DecodeMetadata(mapping)
+		if err != nil {
+			return true
+		}
+
+		for _, entry := range metadata {
+			if !entry.StaleUntil.After(now) {
+				continue
+			}
+
+			if digest := bodyDigest(s.Storer.Get(entry.Key)); digest != "" {
+				referenced[digest]++
+			}
+		}
+
+		return true
+	})
+
+	s.refs.Lock()
+	defer s.refs.Unlock()
+
+	removed := 0
+
+	var errs []error
+
+	for digest, value := range s.Storer.MapKeys(DedupRefsKeyPrefix) {
+		count, found := referenced[digest]
+		if !found {
+			s.Storer.Delete(DedupBodyKeyPrefix + digest)
+			s.Storer.Delete(DedupRefsKeyPrefix + digest)
+
+			removed++
+
+			continue
+		}
+
+		var refs dedupRefs
+		if err := json.Unmarshal([]byte(value), &refs); err == nil && refs.Refs != count {
+			_, err = s.updateRefs(digest, func(refs *dedupRefs) error {
+				refs.Refs = count
+
+				return nil
+			})
+			errs = append(errs, err)
+		}
+	}
+
+	return removed, errors.Join(append(errs, walkErr)...)
+}
+
+func (s *DedupStorer) collect() {
+	removed, err := s.Collect()
+	if err != nil {
+		s.logger.Errorf("Impossible to collect the unreferenced bodies, %v", err)
+	}
+
+	if removed > 0 {
+		s.logger.Debugf("Collected %d unreferenced bodies", removed)
+	}
+}
+
+// Init method initializes the decorated storer then starts the collection
+// of the unreferenced bodies when the gc_interval is set.
+func (s *DedupStorer) Init() error {
+	if err := s.Storer.Init(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil || s.options.GCInterval == 0 {
+		return nil
+	}
+
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(s.options.GCInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if s.limiter.Wait(stop, "dedup_gc") {
+					s.collect()
+				}
+			}
+		}
+	}(s.stop, s.done)
+
+	return nil
+}
+
+// Reset method stops the collection then resets the decorated storer.
+func (s *DedupStorer) Reset() error {
+	s.mu.Lock()
+
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+
+		s.stop, s.done = nil, nil
+	}
+
+	s.mu.Unlock()
+
+	return s.Storer.Reset()
+}
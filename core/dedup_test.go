@@ -0,0 +1,206 @@
+package core_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func dedupResponse(body string) []byte {
+	return []byte("HTTP/1.1 404 Not Found\r\nContent-Type: text/html\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body)
+}
+
+func bodyKeys(storer *memoryStorer) int {
+	return len(storer.MapKeys(core.DedupBodyKeyPrefix))
+}
+
+func TestDedupStorer(t *testing.T) {
+	memory := newMemoryStorer()
+	page := strings.Repeat("not found ", 100)
+
+	storer, err := core.NewDedupStorer(memory, core.DedupOptions{}, time.Minute, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"first", "second"} {
+		if err = storer.SetMultiLevel(key, key+"-varied", dedupResponse(page), nil, "", time.Minute, key); err != nil {
+			t.Fatalf("impossible to store the response, %v", err)
+		}
+	}
+
+	if count := bodyKeys(memory); count != 1 {
+		t.Fatalf("the identical bodies should be stored once, %d stored", count)
+	}
+
+	if strings.Contains(string(memory.Get("first-varied")), page) {
+		t.Error("the varied key should only hold the response headers")
+	}
+
+	fresh, _ := storer.GetMultiLevel("second", httptest.NewRequest(http.MethodGet, "/second", nil), &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("the response should be found")
+	}
+
+	body, _ := io.ReadAll(fresh.Body)
+	if string(body) != page || fresh.StatusCode != http.StatusNotFound || fresh.Header.Get(core.BodyDigestHeader) != "" {
+		t.Errorf("the response should be restored with its body, got %d %v", fresh.StatusCode, fresh.Header)
+	}
+
+	if value := storer.Get("first-varied"); !strings.HasSuffix(string(value), page) {
+		t.Error("Get should restore the body of the response")
+	}
+
+	storer.Delete("first-varied")
+
+	if count := bodyKeys(memory); count != 1 {
+		t.Error("the body should be kept while it's referenced")
+	}
+
+	storer.Delete("second-varied")
+
+	if count := bodyKeys(memory); count != 0 {
+		t.Error("the body should be deleted with its last reference")
+	}
+}
+
+// hashTaggedStorer stores the varied keys behind the hash tag of their base
+// key, like Redis in cluster mode.
+type hashTaggedStorer struct {
+	*memoryStorer
+}
+
+func (h hashTaggedStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	return h.memoryStorer.SetMultiLevel(baseKey, "{"+baseKey+"}"+variedKey, value, variedHeaders, etag, duration, realKey)
+}
+
+func TestDedupStorer_HashTag(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, _ := core.NewDedupStorer(hashTaggedStorer{memory}, core.DedupOptions{}, time.Minute, nopLogger{})
+	_ = storer.SetMultiLevel("base", "varied", dedupResponse(strings.Repeat("first ", 200)), nil, "", time.Minute, "base")
+	_ = storer.SetMultiLevel("base", "varied", dedupResponse(strings.Repeat("second ", 200)), nil, "", time.Minute, "base")
+
+	if count := bodyKeys(memory); count != 1 {
+		t.Errorf("the body replaced behind the hash tag should be released, %d stored", count)
+	}
+}
+
+// atomicStorer is a memoryStorer whose conditional sets are atomic, like
+// those of the shared backends.
+type atomicStorer struct {
+	*memoryStorer
+	mu *sync.Mutex
+}
+
+func (a atomicStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return conditionalStorer{a.memoryStorer}.SetNX(key, value, ttl)
+}
+
+func (a atomicStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return conditionalStorer{a.memoryStorer}.CompareAndSwap(key, old, value, ttl)
+}
+
+func TestDedupStorer_SharedRefs(t *testing.T) {
+	shared := atomicStorer{newMemoryStorer(), &sync.Mutex{}}
+	page := strings.Repeat("shared ", 200)
+
+	instances := make([]*core.DedupStorer, 2)
+	for i := range instances {
+		instances[i], _ = core.NewDedupStorer(shared, core.DedupOptions{}, time.Minute, nopLogger{})
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range 20 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			key := fmt.Sprintf("key-%d", i)
+			_ = instances[i%2].SetMultiLevel(key, key+"-varied", dedupResponse(page), nil, "", time.Minute, key)
+		}()
+	}
+
+	wg.Wait()
+
+	for i := range 19 {
+		instances[i%2].Delete(fmt.Sprintf("key-%d-varied", i))
+	}
+
+	if count := bodyKeys(shared.memoryStorer); count != 1 {
+		t.Error("the references counted by both instances shouldn't be lost")
+	}
+
+	instances[1].Delete("key-19-varied")
+
+	if count := bodyKeys(shared.memoryStorer); count != 0 {
+		t.Error("the body should be deleted with its last reference")
+	}
+}
+
+func TestDedupStorer_SmallBody(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, _ := core.NewDedupStorer(memory, core.DedupOptions{MinSize: 64}, time.Minute, nopLogger{})
+	_ = storer.SetMultiLevel("small", "small-varied", dedupResponse("tiny"), nil, "", time.Minute, "small")
+
+	if bodyKeys(memory) != 0 || !strings.HasSuffix(string(memory.Get("small-varied")), "tiny") {
+		t.Error("the bodies under the min_size should be stored within their response")
+	}
+}
+
+func TestDedupStorer_Collect(t *testing.T) {
+	memory := newMemoryStorer()
+	page := strings.Repeat("asset ", 200)
+
+	storer, _ := core.NewDedupStorer(memory, core.DedupOptions{}, time.Minute, nopLogger{})
+	_ = storer.SetMultiLevel("kept", "kept-varied", dedupResponse(page), nil, "", time.Minute, "kept")
+	_ = storer.SetMultiLevel("purged", "purged-varied", dedupResponse(page+"purged"), nil, "", time.Minute, "purged")
+
+	// DeleteMany can't release the bodies of the deleted responses.
+	storer.DeleteMany("purged")
+
+	removed, err := storer.Collect()
+	if err != nil || removed != 1 {
+		t.Fatalf("the unreferenced body should be collected, %d removed, %v", removed, err)
+	}
+
+	if bodyKeys(memory) != 1 || storer.Get("kept-varied") == nil {
+		t.Error("the referenced body should be kept")
+	}
+}
+
+func TestDedupStorerFromConfiguration(t *testing.T) {
+	storer, err := core.DedupStorerFromConfiguration(newMemoryStorer(), core.CacheProvider{Configuration: map[string]interface{}{
+		core.DedupConfigurationKey: map[string]interface{}{"min_size": "1024", "gc_interval": "1m"},
+	}}, time.Minute, nopLogger{})
+	if _, ok := storer.(*core.DedupStorer); !ok || err != nil {
+		t.Errorf("the storer should be wrapped, got %T, %v", storer, err)
+	}
+
+	if _, err = core.DedupStorerFromConfiguration(newMemoryStorer(), core.CacheProvider{Configuration: map[string]interface{}{
+		core.DedupConfigurationKey: map[string]interface{}{"min_size": -1},
+	}}, time.Minute, nopLogger{}); err == nil {
+		t.Error("a negative min_size should be rejected")
+	}
+
+	if storer, _ = core.DedupStorerFromConfiguration(newMemoryStorer(), core.CacheProvider{}, time.Minute, nopLogger{}); storer == nil {
+		t.Error("the storer should be returned untouched without dedup")
+	}
+}
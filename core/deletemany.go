@@ -0,0 +1,177 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sync"
+)
+
+const (
+	// DeleteManyWorkersConfigurationKey is the key read from the provider
+	// configuration to bound the nodes or partitions scanned and purged at
+	// once by the clustered storages.
+	DeleteManyWorkersConfigurationKey = "delete_many_workers"
+	// DefaultDeleteManyWorkers is the number of concurrent scans and
+	// deletions when the configuration doesn't set it.
+	DefaultDeleteManyWorkers = 8
+	// PurgeScheduleConfigurationKey is the key read from the provider
+	// configuration to run the purge rules of the scheduler package.
+	PurgeScheduleConfigurationKey = "purge_schedule"
+)
+
+// DeleteManyResult reports the keys matched by a DeleteMany pattern.
+type DeleteManyResult struct {
+	// Count is the number of deleted keys, the number of matching keys in
+	// dry-run.
+	Count int `json:"count"`
+	// Keys are the matching keys, only listed in dry-run.
+	Keys []string `json:"keys,omitempty"`
+}
+
+// Add records a matching key, it's listed in dry-run only.
+func (r *DeleteManyResult) Add(key string, dryRun bool) {
+	r.Count++
+
+	if dryRun {
+		r.Keys = append(r.Keys, key)
+	}
+}
+
+// Merge adds the keys matched by another scan, e.g. the one of another node.
+func (r *DeleteManyResult) Merge(other DeleteManyResult) {
+	r.Count += other.Count
+	r.Keys = append(r.Keys, other.Keys...)
+}
+
+// DeleteManyWorkersFromConfiguration returns the delete_many_workers of the
+// provider configuration, DefaultDeleteManyWorkers when unset.
+func DeleteManyWorkersFromConfiguration(configuration any) (int, error) {
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok || cfg[DeleteManyWorkersConfigurationKey] == nil {
+		return DefaultDeleteManyWorkers, nil
+	}
+
+	var workers int
+	if err := DecodeConfiguration(cfg[DeleteManyWorkersConfigurationKey], &workers); err != nil {
+		return 0, fmt.Errorf("invalid delete_many_workers configuration: %w", err)
+	}
+
+	if workers <= 0 {
+		return 0, fmt.Errorf("invalid delete_many_workers configuration: the workers must be positive, %d given", workers)
+	}
+
+	return workers, nil
+}
+
+// RunBounded runs the tasks with at most workers of them at once and returns
+// their joined errors, a failing task doesn't stop the others.
+func RunBounded(workers int, tasks []func() error) error {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	queue := make(chan func() error)
+
+	for range min(workers, len(tasks)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for task := range queue {
+				if err := task(); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		queue <- task
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// CountingDeleter is an optional interface a Storer can implement to delete
+// the keys matching the regular expression like DeleteMany, reporting how
+// many keys were deleted. In dry-run the matching keys are listed without
+// being deleted.
+type CountingDeleter interface {
+	DeleteManyCount(pattern string, dryRun bool) (DeleteManyResult, error)
+}
+
+// DeleteManyCount deletes the keys matching the regular expression with the
+// storer DeleteManyCount method when it implements CountingDeleter. It
+// matches the MapKeys result and deletes the keys one by one otherwise.
+func DeleteManyCount(storer Storer, pattern string, dryRun bool) (DeleteManyResult, error) {
+	if deleter, ok := storer.(CountingDeleter); ok {
+		return deleter.DeleteManyCount(pattern, dryRun)
+	}
+
+	rgKey, err := regexp.Compile(pattern)
+	if err != nil {
+		return DeleteManyResult{}, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	prefix := PatternPrefix(pattern)
+	keys := []string{}
+
+	for key := range storer.MapKeys(prefix) {
+		key = prefix + key
+		if rgKey.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	result := DeleteManyResult{}
+
+	for _, key := range keys {
+		if !dryRun {
+			storer.Delete(key)
+		}
+
+		result.Add(key, dryRun)
+	}
+
+	return result, nil
+}
+
+// PatternPrefix returns the literal prefix of every key matched by the
+// pattern, so the storages can only scan the keys starting with it. It's
+// empty unless the pattern is anchored at the start of the key.
+func PatternPrefix(pattern string) string {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return ""
+	}
+
+	parsed = parsed.Simplify()
+
+	if parsed.Op != syntax.OpConcat || len(parsed.Sub) < 2 || parsed.Sub[0].Op != syntax.OpBeginText {
+		return ""
+	}
+
+	literal := parsed.Sub[1]
+	if literal.Op != syntax.OpLiteral || literal.Flags&syntax.FoldCase != 0 {
+		return ""
+	}
+
+	return string(literal.Rune)
+}
@@ -0,0 +1,102 @@
+package core_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestPatternPrefix(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"^IDX_foo":      "IDX_foo",
+		"^IDX_foo.*bar": "IDX_foo",
+		"^(?:abc|abd)":  "ab",
+		"IDX_foo":       "",
+		"^a|b":          "",
+		"^(?i)abc":      "",
+		".*":            "",
+		"(":             "",
+	} {
+		if prefix := core.PatternPrefix(pattern); prefix != expected {
+			t.Errorf("The %s prefix should be %q, %q given", pattern, expected, prefix)
+		}
+	}
+}
+
+func TestDeleteManyCount(t *testing.T) {
+	memory := newMemoryStorer()
+	_ = memory.Set("PURGE_first", []byte(baseValue), time.Minute)
+	_ = memory.Set("PURGE_second", []byte(baseValue), time.Minute)
+	_ = memory.Set("KEEP_PURGE_third", []byte(baseValue), time.Minute)
+
+	result, err := core.DeleteManyCount(memory, "^PURGE_", true)
+	if err != nil || result.Count != 2 || len(result.Keys) != 2 || result.Keys[0] != "PURGE_first" {
+		t.Fatalf("The dry-run should list the two matching keys, %+v given, %v", result, err)
+	}
+
+	if len(memory.Get("PURGE_first")) == 0 {
+		t.Error("The dry-run shouldn't delete the matching keys")
+	}
+
+	if result, _ = core.DeleteManyCount(memory, "PURGE_", false); result.Count != 3 || len(result.Keys) != 0 {
+		t.Errorf("The three matching keys should be deleted, %+v given", result)
+	}
+
+	if _, err = core.DeleteManyCount(memory, "(", false); err == nil {
+		t.Error("An invalid pattern should be rejected")
+	}
+}
+
+func TestRunBounded(t *testing.T) {
+	var running, peak atomic.Int32
+
+	failure := errors.New("node unavailable")
+	tasks := []func() error{}
+
+	for i := range 20 {
+		tasks = append(tasks, func() error {
+			if current := running.Add(1); current > peak.Load() {
+				peak.Store(current)
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+
+			if i%10 == 0 {
+				return failure
+			}
+
+			return nil
+		})
+	}
+
+	err := core.RunBounded(3, tasks)
+	if !errors.Is(err, failure) {
+		t.Errorf("The errors of the tasks should be joined, %v given", err)
+	}
+
+	if peak.Load() > 3 {
+		t.Errorf("At most 3 tasks should run at once, %d ran", peak.Load())
+	}
+
+	if err = core.RunBounded(3, nil); err != nil {
+		t.Errorf("No task shouldn't fail, %v given", err)
+	}
+}
+
+func TestDeleteManyWorkersFromConfiguration(t *testing.T) {
+	if workers, err := core.DeleteManyWorkersFromConfiguration(nil); err != nil || workers != core.DefaultDeleteManyWorkers {
+		t.Errorf("The default workers should be used, %d and %v given", workers, err)
+	}
+
+	if workers, err := core.DeleteManyWorkersFromConfiguration(map[string]interface{}{"delete_many_workers": "16"}); err != nil || workers != 16 {
+		t.Errorf("The configured workers should be used, %d and %v given", workers, err)
+	}
+
+	if _, err := core.DeleteManyWorkersFromConfiguration(map[string]interface{}{"delete_many_workers": 0}); err == nil {
+		t.Error("Zero workers should be rejected")
+	}
+}
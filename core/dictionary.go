@@ -0,0 +1,159 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ZstdDictionaryConfigurationKey is the key read from the provider
+	// configuration to declare the path of a trained zstd dictionary.
+	ZstdDictionaryConfigurationKey = "zstd_dictionary"
+	// DefaultZstdDictionarySize is the maximum size of a trained dictionary
+	// unless asked otherwise.
+	DefaultZstdDictionarySize = 64 << 10
+)
+
+// zstdDictionaries holds the registered dictionaries by id and the decoder
+// knowing all of them, the frames written with a dictionary carry its id in
+// their header.
+var zstdDictionaries = struct {
+	sync.RWMutex
+	byID    map[uint32][]byte
+	decoder *zstd.Decoder
+}{byID: map[uint32][]byte{}}
+
+// RegisterZstdDictionary makes the values compressed with the dictionary
+// readable by every zstd compressor and returns the dictionary id.
+// Registering a dictionary again is a no-op.
+func RegisterZstdDictionary(dictionary []byte) (uint32, error) {
+	inspected, err := zstd.InspectDictionary(dictionary)
+	if err != nil {
+		return 0, fmt.Errorf("invalid zstd dictionary: %w", err)
+	}
+
+	id := inspected.ID()
+	if id == 0 {
+		return 0, errors.New("invalid zstd dictionary: the dictionary id must be set")
+	}
+
+	zstdDictionaries.Lock()
+	defer zstdDictionaries.Unlock()
+
+	if _, ok := zstdDictionaries.byID[id]; ok {
+		return id, nil
+	}
+
+	dictionaries := make([][]byte, 0, len(zstdDictionaries.byID)+1)
+	for _, registered := range zstdDictionaries.byID {
+		dictionaries = append(dictionaries, registered)
+	}
+
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderDicts(append(dictionaries, dictionary)...))
+	if err != nil {
+		return 0, fmt.Errorf("invalid zstd dictionary: %w", err)
+	}
+
+	zstdDictionaries.byID[id] = dictionary
+	zstdDictionaries.decoder = decoder
+
+	return id, nil
+}
+
+// zstdDictionaryDecoder returns the decoder knowing the registered
+// dictionaries, nil when none is registered.
+func zstdDictionaryDecoder() *zstd.Decoder {
+	zstdDictionaries.RLock()
+	defer zstdDictionaries.RUnlock()
+
+	return zstdDictionaries.decoder
+}
+
+// TrainZstdDictionary builds a zstd dictionary of at most maxSize bytes
+// (DefaultZstdDictionarySize when not positive) from sample values, e.g.
+// collected with SampleValues. It's meant to run offline, the result is
+// written to the file declared by zstd_dictionary.
+func TrainZstdDictionary(samples [][]byte, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultZstdDictionarySize
+	}
+
+	dictionary, err := dict.BuildZstdDict(samples, dict.Options{MaxDictSize: maxSize, HashBytes: 6})
+	if err != nil {
+		return nil, fmt.Errorf("impossible to train the zstd dictionary: %w", err)
+	}
+
+	return dictionary, nil
+}
+
+// SampleValues returns up to limit decompressed values of the storer to train
+// a dictionary, the mappings and the surrogate keys are skipped.
+func SampleValues(storer Storer, limit int) ([][]byte, error) {
+	samples := [][]byte{}
+
+	err := WalkEntries(storer, func(key string, value []byte, _ time.Duration) bool {
+		if strings.HasPrefix(key, MappingKeyPrefix) || strings.HasPrefix(key, SurrogateKeyPrefix) {
+			return true
+		}
+
+		reader, err := DetectCompressor(value).Decompress(value)
+		if err != nil {
+			return true
+		}
+
+		if decompressed, err := io.ReadAll(reader); err == nil && len(decompressed) > 0 {
+			samples = append(samples, decompressed)
+		}
+
+		return len(samples) < limit
+	})
+
+	return samples, err
+}
+
+// zstdDictionaryCompressor compresses with a trained dictionary, it's
+// detected and decompressed as any zstd value.
+type zstdDictionaryCompressor struct {
+	zstdCompressor
+
+	encoder *zstd.Encoder
+}
+
+// NewZstdDictionaryCompressor registers the dictionary and returns the zstd
+// compressor using it. Small values sharing the same structure, like JSON
+// responses, compress far better than with the plain codecs.
+func NewZstdDictionaryCompressor(dictionary []byte) (Compressor, error) {
+	if _, err := RegisterZstdDictionary(dictionary); err != nil {
+		return nil, err
+	}
+
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dictionary))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zstd dictionary: %w", err)
+	}
+
+	return observedCompressor{framedCompressor{zstdDictionaryCompressor{encoder: encoder}}}, nil
+}
+
+func (c zstdDictionaryCompressor) Compress(value []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(value, nil), nil
+}
+
+// zstdDictionaryCompressorFromFile returns the compressor using the
+// dictionary stored in the file.
+func zstdDictionaryCompressorFromFile(path string) (Compressor, error) {
+	dictionary, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zstd dictionary: %w", err)
+	}
+
+	return NewZstdDictionaryCompressor(dictionary)
+}
@@ -0,0 +1,117 @@
+package core_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func jsonSample(i int) []byte {
+	return fmt.Appendf(nil, `{"id":%d,"type":"product","attributes":{"name":"Product %d","price":%d.99,"currency":"EUR","available":true},"links":{"self":"/products/%d"}}`, i, i, i%100, i)
+}
+
+func trainDictionary(t *testing.T) []byte {
+	t.Helper()
+
+	samples := make([][]byte, 0, 500)
+	for i := range 500 {
+		samples = append(samples, jsonSample(i))
+	}
+
+	dictionary, err := core.TrainZstdDictionary(samples, 0)
+	if err != nil {
+		t.Fatalf("Impossible to train the dictionary, %v", err)
+	}
+
+	return dictionary
+}
+
+func TestZstdDictionaryCompressor(t *testing.T) {
+	compressor, err := core.NewZstdDictionaryCompressor(trainDictionary(t))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	value := jsonSample(1234)
+	compressed, _ := compressor.Compress(value)
+	plain, _ := core.DetectCompressor(compressed).Compress(value)
+
+	if len(compressed) >= len(plain) {
+		t.Errorf("The dictionary should compress better, %d bytes given, %d without", len(compressed), len(plain))
+	}
+
+	if core.DetectCompressor(compressed).Name() != core.ZstdCompression {
+		t.Fatal("The dictionary values should be detected as zstd")
+	}
+
+	reader, err := core.DetectCompressor(compressed).Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Impossible to decompress the value, %v", err)
+	}
+
+	if decompressed, _ := io.ReadAll(reader); !bytes.Equal(decompressed, value) {
+		t.Errorf("The decompressed value doesn't match the original one, %s given", decompressed)
+	}
+
+	if _, err = core.NewZstdDictionaryCompressor([]byte("not a dictionary")); err == nil {
+		t.Error("The malformed dictionary should be rejected")
+	}
+}
+
+func TestZstdDictionaryFromConfiguration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.dict")
+	_ = os.WriteFile(path, trainDictionary(t), 0o600)
+
+	compressor, err := core.CompressorFromConfiguration(map[string]interface{}{"zstd_dictionary": path})
+	if err != nil || compressor.Name() != core.ZstdCompression {
+		t.Fatalf("The dictionary should select the zstd compressor, %v given", err)
+	}
+
+	plain, _ := core.NewCompressor(core.ZstdCompression)
+	withDictionary, _ := compressor.Compress(jsonSample(1))
+	withoutDictionary, _ := plain.Compress(jsonSample(1))
+
+	if len(withDictionary) >= len(withoutDictionary) {
+		t.Error("The compressor should use the dictionary")
+	}
+
+	for _, configuration := range []map[string]interface{}{
+		{"zstd_dictionary": path, "compressor": "lz4"},
+		{"zstd_dictionary": filepath.Join(t.TempDir(), "missing.dict")},
+	} {
+		if _, err = core.CompressorFromConfiguration(configuration); err == nil {
+			t.Errorf("The configuration %v should be rejected", configuration)
+		}
+	}
+
+	if err = core.DecodeConfiguration(map[string]interface{}{"zstd_dictionary": path}, &struct{}{}); err != nil {
+		t.Errorf("The zstd_dictionary key should be shared, %v given", err)
+	}
+}
+
+func TestSampleValues(t *testing.T) {
+	memory := newMemoryStorer()
+	compressor, _ := core.NewCompressor(core.LZ4Compression)
+
+	for i := range 5 {
+		compressed, _ := compressor.Compress(jsonSample(i))
+		key := fmt.Sprintf("GET-products-%d", i)
+		_ = memory.SetMultiLevel(key, key, compressed, http.Header{}, "", time.Minute, key)
+	}
+
+	samples, err := core.SampleValues(memory, 3)
+	if err != nil || len(samples) != 3 {
+		t.Fatalf("3 samples should be returned, %d given, %v", len(samples), err)
+	}
+
+	if !bytes.HasPrefix(samples[0], []byte(`{"id":`)) {
+		t.Errorf("The samples should be decompressed, %s given", samples[0])
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"net/http"
+	"time"
+)
+
+// electionCandidate is a varied key of the mapping matching the request and
+// its ETag validation. state is the validator once the key was validated,
+// restored when the key is elected.
+type electionCandidate struct {
+	key   string
+	index *KeyIndex
+	state Revalidator
+	// fresh is false for the keys only served stale.
+	fresh bool
+	// notModified ends the election without loading any body.
+	notModified bool
+}
+
+// electionCandidates walks the mapping like MappingElection reads it and
+// returns the keys worth loading, in the same order, so their bodies are
+// loaded at once with GetMany. The walk stops at the fresh key matching the
+// conditional request, the earlier fresh keys are still elected first.
+func electionCandidates(mapping *StorageMapper, req *http.Request, validator *Revalidator, checkVary bool, now time.Time, logger Logger) []electionCandidate {
+	candidates := []electionCandidate{}
+
+	for keyName, keyItem := range mapping.GetMapping() {
+		valid := true
+
+		if checkVary {
+			for hname, hval := range keyItem.GetVariedHeaders() {
+				if !varyMatches(req, hname, hval.GetHeaderValue()) {
+					valid = false
+
+					break
+				}
+			}
+		}
+
+		if !valid {
+			continue
+		}
+
+		ValidateETagFromHeader(keyItem.GetEtag(), validator)
+
+		if !validator.Matched {
+			logger.Debugf("The stored key %s didn't match the current iteration key ETag %+v", keyName, validator)
+
+			continue
+		}
+
+		candidate := electionCandidate{key: keyName, index: keyItem}
+
+		switch {
+		case now.Before(keyItem.GetFreshTime().AsTime()):
+			if validator.AllowNotModified && RequestNotModified(req, keyItem.GetEtag(), keyItem.GetStoredAt().AsTime()) {
+				validator.NotModified = true
+				validator.NotModifiedKey = keyName
+				validator.LastModified = keyItem.GetStoredAt().AsTime()
+
+				candidate.notModified = true
+				candidate.state = *validator
+
+				return append(candidates, candidate)
+			}
+
+			candidate.fresh = true
+		case now.Before(keyItem.GetStaleTime().AsTime()):
+		default:
+			continue
+		}
+
+		candidate.state = *validator
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates
+}
+
+// loadCandidates reads the bodies of the candidates in one GetMany call, or
+// without copying them from the in-memory storers.
+func loadCandidates(provider Storer, candidates []electionCandidate) map[string][]byte {
+	keys := make([]string, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		if !candidate.notModified {
+			keys = append(keys, candidate.key)
+		}
+	}
+
+	if _, ok := provider.(BatchGetter); ok {
+		return GetMany(provider, keys)
+	}
+
+	// The responses are only read, the in-memory storers needn't copy them.
+	values := make(map[string][]byte, len(keys))
+
+	for _, key := range keys {
+		if value, found := GetNoCopy(provider, key); found {
+			values[key] = value
+		}
+	}
+
+	return values
+}
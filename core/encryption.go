@@ -0,0 +1,362 @@
+package core
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// EncryptionConfigurationKey is the key read from the provider
+	// configuration to encrypt the stored values.
+	EncryptionConfigurationKey = "encryption"
+
+	AESGCMEncryption           = "aes-gcm"
+	ChaCha20Poly1305Encryption = "chacha20-poly1305"
+)
+
+var errCiphertextTooShort = errors.New("ciphertext too short")
+
+// Encryptor encrypts the values before they are stored and decrypts them
+// when they are read back.
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+type aeadEncryptor struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptor returns the AEAD encryptor for the given algorithm. AES-GCM
+// accepts 16, 24 or 32 bytes keys, ChaCha20-Poly1305 requires 32 bytes.
+func NewEncryptor(algorithm string, key []byte) (Encryptor, error) {
+	var (
+		aead cipher.AEAD
+		err  error
+	)
+
+	switch strings.ToLower(algorithm) {
+	case "", AESGCMEncryption:
+		var block cipher.Block
+
+		if block, err = aes.NewCipher(key); err == nil {
+			aead, err = cipher.NewGCM(block)
+		}
+	case ChaCha20Poly1305Encryption:
+		aead, err = chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unknown encryption algorithm %s", algorithm)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &aeadEncryptor{aead: aead}, nil
+}
+
+// Encrypt seals the plaintext, the random nonce is prepended to the output.
+func (e *aeadEncryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a value sealed by Encrypt.
+func (e *aeadEncryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < e.aead.NonceSize() {
+		return nil, errCiphertextTooShort
+	}
+
+	nonce, sealed := ciphertext[:e.aead.NonceSize()], ciphertext[e.aead.NonceSize():]
+
+	return e.aead.Open(nil, nonce, sealed, nil)
+}
+
+// EncryptorFromConfiguration returns the encryptor declared under the
+// encryption key of the provider configuration, nil when it is not set.
+// The base64 encoded key is read from the key entry or from the environment
+// variable named by key_env.
+func EncryptorFromConfiguration(configuration any) (Encryptor, error) {
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	encryptionCfg, ok := cfg[EncryptionConfigurationKey].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	algorithm, _ := encryptionCfg["algorithm"].(string)
+	encodedKey, _ := encryptionCfg["key"].(string)
+
+	if env, _ := encryptionCfg["key_env"].(string); encodedKey == "" && env != "" {
+		encodedKey = os.Getenv(env)
+	}
+
+	if encodedKey == "" {
+		return nil, errors.New("no encryption key given")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("the encryption key must be base64 encoded: %w", err)
+	}
+
+	return NewEncryptor(algorithm, key)
+}
+
+// EncryptedStorer decorates any Storer to encrypt the values before they are
+// written and decrypt them once read. The multi level methods are handled by
+// the decorator itself so the mapping and the varied values go through the
+// encryption too.
+type EncryptedStorer struct {
+	Storer
+
+	encryptor  Encryptor
+	compressor Compressor
+	stale      time.Duration
+	logger     Logger
+}
+
+// NewEncryptedStorer wraps the storer to encrypt its values.
+func NewEncryptedStorer(storer Storer, encryptor Encryptor, compressor Compressor, stale time.Duration, logger Logger) *EncryptedStorer {
+	return &EncryptedStorer{
+		Storer:     storer,
+		encryptor:  encryptor,
+		compressor: compressor,
+		stale:      stale,
+		logger:     logger,
+	}
+}
+
+// EncryptedStorerFromConfiguration wraps the storer when the encryption is
+// configured, it returns the storer untouched otherwise.
+func EncryptedStorerFromConfiguration(storer Storer, provider CacheProvider, stale time.Duration, logger Logger) (Storer, error) {
+	encryptor, err := EncryptorFromConfiguration(provider.Configuration)
+	if err != nil || encryptor == nil {
+		return storer, err
+	}
+
+	compressor, err := CompressorFromConfiguration(provider.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEncryptedStorer(storer, encryptor, compressor, stale, logger), nil
+}
+
+// Unwrap returns the decorated storer.
+func (s *EncryptedStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// GetMetadata decodes the decrypted mapping, the decorated storer only
+// holds the encrypted one.
+func (s *EncryptedStorer) GetMetadata(key string) ([]KeyMetadata, error) {
+	return DecodeMetadata(s.Get(MappingKeyPrefix + key))
+}
+
+// Get method returns the decrypted value, nil when it can't be decrypted.
+func (s *EncryptedStorer) Get(key string) []byte {
+	value := s.Storer.Get(key)
+	if len(value) == 0 {
+		return nil
+	}
+
+	plaintext, err := s.encryptor.Decrypt(value)
+	if err != nil {
+		s.logger.Errorf("Impossible to decrypt the key %s, %v", key, err)
+
+		return nil
+	}
+
+	return plaintext
+}
+
+// Lookup method returns the decrypted value, the decryption error when it
+// can't be decrypted.
+func (s *EncryptedStorer) Lookup(key string) ([]byte, error) {
+	value, err := Lookup(s.Storer, key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.encryptor.Decrypt(value)
+	if err != nil {
+		return nil, fmt.Errorf("impossible to decrypt the key %s: %w", key, err)
+	}
+
+	return plaintext, nil
+}
+
+// Set method will encrypt the value before storing it.
+func (s *EncryptedStorer) Set(key string, value []byte, duration time.Duration) error {
+	ciphertext, err := s.encryptor.Encrypt(value)
+	if err != nil {
+		s.logger.Errorf("Impossible to encrypt the key %s, %v", key, err)
+
+		return err
+	}
+
+	return s.Storer.Set(key, ciphertext, duration)
+}
+
+// SetNX method will encrypt the value before storing it only if the key
+// doesn't exist, using the ConditionalSetter of the decorated storer.
+func (s *EncryptedStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	setter, ok := ConditionalSetterFor(s.Storer)
+	if !ok {
+		return false, ErrConditionalSetNotSupported
+	}
+
+	ciphertext, err := s.encryptor.Encrypt(value)
+	if err != nil {
+		return false, err
+	}
+
+	return setter.SetNX(key, ciphertext, ttl)
+}
+
+// CompareAndSwap method compares the decrypted stored value with old and
+// swaps the ciphertext it was decrypted from, the ciphertexts of the same
+// value differ by their nonce.
+func (s *EncryptedStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	setter, ok := ConditionalSetterFor(s.Storer)
+	if !ok {
+		return false, ErrConditionalSetNotSupported
+	}
+
+	// The stored ciphertext is read from the storer implementing the
+	// ConditionalSetter, it is compared as stored there.
+	storer := s.Storer
+	if decorated, ok := setter.(Storer); ok {
+		storer = decorated
+	}
+
+	current, err := Lookup(storer, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	plaintext, err := s.encryptor.Decrypt(current)
+	if err != nil {
+		return false, fmt.Errorf("impossible to decrypt the key %s: %w", key, err)
+	}
+
+	if !bytes.Equal(plaintext, old) {
+		return false, nil
+	}
+
+	ciphertext, err := s.encryptor.Encrypt(value)
+	if err != nil {
+		return false, err
+	}
+
+	return setter.CompareAndSwap(key, current, ciphertext, ttl)
+}
+
+// MapKeys method returns the decrypted values.
+func (s *EncryptedStorer) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	for key, value := range s.Storer.MapKeys(prefix) {
+		if plaintext, err := s.encryptor.Decrypt([]byte(value)); err == nil {
+			keys[key] = string(plaintext)
+		}
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (s *EncryptedStorer) ListKeys() []string {
+	keys := []string{}
+
+	for _, value := range s.MapKeys(MappingKeyPrefix) {
+		mapping, err := DecodeMapping([]byte(value))
+		if err == nil {
+			for _, v := range mapping.GetMapping() {
+				keys = append(keys, v.GetRealKey())
+			}
+		}
+	}
+
+	return keys
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (s *EncryptedStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale, _ = MappingElectionFor(s, MappingKeyPrefix+key, s.Get(MappingKeyPrefix+key), req, validator, s.logger)
+
+	return fresh, stale
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (s *EncryptedStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := s.compressor.Compress(value)
+	if err != nil {
+		s.logger.Errorf("Impossible to compress the key %s, %v", variedKey, err)
+
+		return err
+	}
+
+	if err = s.Set(variedKey, compressed, duration+s.stale); err != nil {
+		return err
+	}
+
+	mappingKey := MappingKeyPrefix + baseKey
+
+	var evicted []string
+
+	// The mapping is kept until its latest variant is stale, a variant
+	// stored with a shorter duration doesn't expire the others.
+	err = UpdateMapping(func() error {
+		current, err := s.Lookup(mappingKey)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+
+		val, evictedKeys, err := MappingUpdaterWithEvictions(variedKey, current, s.logger, now, now.Add(duration), now.Add(duration+s.stale), variedHeaders, etag, realKey)
+		if err != nil {
+			return err
+		}
+
+		mapping, err := DecodeMapping(val)
+		if err != nil {
+			return err
+		}
+
+		evicted = evictedKeys
+
+		return swapMapping(s, mappingKey, current, val, time.Until(mappingStaleUntil(mapping, now)))
+	})
+	if err != nil {
+		return err
+	}
+
+	DeleteEvictedVariants(s, evicted)
+
+	return nil
+}
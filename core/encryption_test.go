@@ -0,0 +1,142 @@
+package core_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestEncryptor_RoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x2a}, 32)
+
+	for _, algorithm := range []string{core.AESGCMEncryption, core.ChaCha20Poly1305Encryption} {
+		t.Run(algorithm, func(t *testing.T) {
+			encryptor, err := core.NewEncryptor(algorithm, key)
+			if err != nil {
+				t.Fatalf("Impossible to create the %s encryptor: %v", algorithm, err)
+			}
+
+			ciphertext, err := encryptor.Encrypt([]byte(baseValue))
+			if err != nil {
+				t.Fatalf("Impossible to encrypt: %v", err)
+			}
+
+			if bytes.Contains(ciphertext, []byte(baseValue)) {
+				t.Error("The ciphertext should not contain the plaintext")
+			}
+
+			plaintext, err := encryptor.Decrypt(ciphertext)
+			if err != nil || string(plaintext) != baseValue {
+				t.Errorf("The decrypted value should be %s, %s given (%v)", baseValue, plaintext, err)
+			}
+
+			ciphertext[len(ciphertext)-1] ^= 0xff
+			if _, err = encryptor.Decrypt(ciphertext); err == nil {
+				t.Error("A tampered ciphertext should be rejected")
+			}
+		})
+	}
+}
+
+func TestEncryptorFromConfiguration(t *testing.T) {
+	if encryptor, err := core.EncryptorFromConfiguration(nil); encryptor != nil || err != nil {
+		t.Error("No encryptor should be returned without the encryption key")
+	}
+
+	t.Setenv("STORAGES_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+
+	encryptor, err := core.EncryptorFromConfiguration(map[string]interface{}{
+		"encryption": map[string]interface{}{
+			"algorithm": core.ChaCha20Poly1305Encryption,
+			"key_env":   "STORAGES_ENCRYPTION_KEY",
+		},
+	})
+	if err != nil || encryptor == nil {
+		t.Errorf("The encryptor should be created from the environment key: %v", err)
+	}
+
+	if _, err = core.EncryptorFromConfiguration(map[string]interface{}{
+		"encryption": map[string]interface{}{"key": base64.StdEncoding.EncodeToString([]byte("short"))},
+	}); err == nil {
+		t.Error("An invalid key size should be rejected")
+	}
+}
+
+func TestEncryptedStorer(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.EncryptedStorerFromConfiguration(memory, core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"encryption": map[string]interface{}{
+				"key": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+			},
+		},
+	}, 0, nopLogger{})
+	if err != nil {
+		t.Fatalf("The encrypted storer should be created: %v", err)
+	}
+
+	_ = storer.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if bytes.Contains(memory.Get(byteKey), []byte(baseValue)) {
+		t.Error("The wrapped storer should only hold the ciphertext")
+	}
+
+	if string(storer.Get(byteKey)) != baseValue {
+		t.Errorf("The decrypted value should be %s, %s given", baseValue, storer.Get(byteKey))
+	}
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	if err = storer.SetMultiLevel("base", "varied", value, http.Header{}, "", time.Minute, "real"); err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	if keys := storer.ListKeys(); len(keys) != 1 || keys[0] != "real" {
+		t.Errorf("The listed keys should only contain real, %v given", keys)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com", nil)
+
+	fresh, _ := storer.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The fresh response should exist")
+	}
+
+	_ = fresh.Body.Close()
+}
+
+func TestEncryptedStorer_ConditionalSet(t *testing.T) {
+	memory := conditionalStorer{newMemoryStorer()}
+
+	encryptor, _ := core.NewEncryptor(core.AESGCMEncryption, bytes.Repeat([]byte{1}, 32))
+	compressor, _ := core.NewCompressor(core.NoCompression)
+	storer := core.NewEncryptedStorer(memory, encryptor, compressor, 0, nopLogger{})
+
+	if stored, err := core.SetNX(storer, "leader", []byte("first"), time.Minute); !stored || err != nil {
+		t.Fatalf("The absent key should be stored, %v and %v given", stored, err)
+	}
+
+	if bytes.Contains(memory.Get("leader"), []byte("first")) {
+		t.Error("The conditional value should be encrypted")
+	}
+
+	if swapped, _ := core.CompareAndSwap(storer, "leader", []byte("other"), []byte("second"), time.Minute); swapped {
+		t.Error("The value shouldn't be swapped when the decrypted one differs")
+	}
+
+	if swapped, err := core.CompareAndSwap(storer, "leader", []byte("first"), []byte("second"), time.Minute); !swapped || err != nil {
+		t.Errorf("The value should be swapped when the decrypted one matches, %v given", err)
+	}
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	_ = storer.SetMultiLevel("base", "long", value, http.Header{}, "", time.Hour, "long")
+	_ = storer.SetMultiLevel("base", "short", value, http.Header{}, "", time.Second, "short")
+
+	if metadata, _ := core.GetMetadata(storer, "base"); len(metadata) != 2 {
+		t.Errorf("The mapping should reference both variants, %+v given", metadata)
+	}
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultEvictionScanInterval is the delay between two scans of the storages
+// detecting their expired entries themselves.
+const DefaultEvictionScanInterval = 10 * time.Second
+
+// EvictionNotifier is an optional interface a Storer can implement to call
+// fn with every entry it expires or evicts, e.g. to log, warm again or
+// account for them: Otter with its deletion listener, Badger and Olric with
+// periodic scans and Redis with the keyspace notifications. The value is the
+// stored one, nil when the storage doesn't keep it. The explicit deletions
+// aren't notified.
+type EvictionNotifier interface {
+	OnEvicted(fn func(key string, value []byte))
+}
+
+// EvictionNotifierFor returns the EvictionNotifier implemented by the storer
+// or one of the storers it decorates.
+func EvictionNotifierFor(storer Storer) (EvictionNotifier, bool) {
+	for storer != nil {
+		if notifier, ok := storer.(EvictionNotifier); ok {
+			return notifier, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// EvictionCallbacks holds the callbacks registered with OnEvicted, the zero
+// value is ready to use.
+type EvictionCallbacks struct {
+	mu        sync.RWMutex
+	callbacks []func(key string, value []byte)
+}
+
+// Add registers the callback.
+func (c *EvictionCallbacks) Add(fn func(key string, value []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.callbacks = append(c.callbacks, fn)
+}
+
+// Empty reports whether no callback is registered, the storages skip their
+// detection then.
+func (c *EvictionCallbacks) Empty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.callbacks) == 0
+}
+
+// Notify calls every callback with the evicted entry.
+func (c *EvictionCallbacks) Notify(key string, value []byte) {
+	c.mu.RLock()
+	callbacks := c.callbacks
+	c.mu.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(key, value)
+	}
+}
@@ -0,0 +1,69 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+// evictingStorer is a storage notifying its evictions.
+type evictingStorer struct {
+	*memoryStorer
+	core.EvictionCallbacks
+}
+
+func (s *evictingStorer) OnEvicted(fn func(key string, value []byte)) {
+	s.Add(fn)
+}
+
+func TestEvictionCallbacks(t *testing.T) {
+	var callbacks core.EvictionCallbacks
+
+	if !callbacks.Empty() {
+		t.Error("The zero EvictionCallbacks should be empty")
+	}
+
+	evicted := map[string]string{}
+
+	callbacks.Add(func(key string, value []byte) {
+		evicted[key] = string(value)
+	})
+	callbacks.Add(func(key string, _ []byte) {
+		evicted[key+"-second"] = ""
+	})
+
+	callbacks.Notify(byteKey, []byte(baseValue))
+
+	if callbacks.Empty() || evicted[byteKey] != baseValue || len(evicted) != 2 {
+		t.Errorf("Every callback should be called with the evicted entry, %v given", evicted)
+	}
+}
+
+func TestEvictionNotifierFor(t *testing.T) {
+	if _, ok := core.EvictionNotifierFor(newMemoryStorer()); ok {
+		t.Error("The memory storer doesn't implement the EvictionNotifier")
+	}
+
+	storer := &evictingStorer{memoryStorer: newMemoryStorer()}
+
+	prefixed, err := core.NewPrefixedStorer(storer, "tenant")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	notifier, ok := core.EvictionNotifierFor(prefixed)
+	if !ok {
+		t.Fatal("The decorated storer EvictionNotifier should be returned")
+	}
+
+	var evicted string
+
+	notifier.OnEvicted(func(key string, _ []byte) {
+		evicted = key
+	})
+	storer.Notify(byteKey, nil)
+
+	if evicted != byteKey {
+		t.Errorf("The callback registered on the decorator should be called, %s given", evicted)
+	}
+}
@@ -0,0 +1,263 @@
+// Package faulty wraps any storer to inject failures and latency, so the
+// embedders can test their resilience paths without a real outage.
+package faulty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// Operation groups the Storer methods sharing the same fault.
+type Operation string
+
+const (
+	// Get covers Get and GetMultiLevel, a failing read is a miss.
+	Get Operation = "get"
+	// Set covers Set and SetMultiLevel, a failing write returns the error.
+	Set Operation = "set"
+	// Delete covers Delete and DeleteMany, a failing deletion is dropped.
+	Delete Operation = "delete"
+	// List covers MapKeys and ListKeys, a failing listing is empty.
+	List Operation = "list"
+	// Health covers the Healthy probe, a failing probe returns the error.
+	Health Operation = "health"
+)
+
+// ErrInjected is returned by the failing operations when Options.Err is nil.
+var ErrInjected = errors.New("injected storage failure")
+
+// Fault describes how an operation misbehaves.
+type Fault struct {
+	// ErrorRate is the probability, between 0 and 1, the operation fails.
+	ErrorRate float64
+	// Latency is added before each call of the operation.
+	Latency time.Duration
+	// Jitter adds up to Jitter of random latency.
+	Jitter time.Duration
+}
+
+func (f Fault) validate(operation Operation) error {
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("the %s error rate must be between 0 and 1, %v given", operation, f.ErrorRate)
+	}
+
+	if f.Latency < 0 || f.Jitter < 0 {
+		return fmt.Errorf("the %s latency and jitter can't be negative, %s and %s given", operation, f.Latency, f.Jitter)
+	}
+
+	return nil
+}
+
+// Options tunes the faulty Storer.
+type Options struct {
+	// Default is the fault of the operations missing from Operations.
+	Default Fault
+	// Operations overrides the Default fault per operation, e.g. a Set
+	// always failing while the Get operations succeed.
+	Operations map[Operation]Fault
+	// Err is returned by the failing operations, ErrInjected when nil.
+	Err error
+	// Seed makes the injected failures reproducible, they're random when
+	// zero.
+	Seed uint64
+}
+
+func (o Options) validate() error {
+	if err := o.Default.validate("default"); err != nil {
+		return err
+	}
+
+	for operation, fault := range o.Operations {
+		if err := fault.validate(operation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Storer decorates any core.Storer with the configured faults. It doesn't
+// expose the decorated storer with Unwrap, so the core helpers fall back on
+// the faulty methods instead of bypassing them.
+type Storer struct {
+	core.Storer
+
+	mu       sync.Mutex
+	options  Options
+	random   *rand.Rand
+	injected map[Operation]int
+}
+
+// New wraps the storer with the faults of the options.
+func New(storer core.Storer, options Options) (*Storer, error) {
+	s := &Storer{Storer: storer, injected: map[Operation]int{}}
+
+	if err := s.SetOptions(options); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetOptions replaces the faults, e.g. to start an outage in the middle of a
+// test then recover from it.
+func (s *Storer) SetOptions(options Options) error {
+	if err := options.validate(); err != nil {
+		return fmt.Errorf("invalid faulty configuration: %w", err)
+	}
+
+	if options.Err == nil {
+		options.Err = ErrInjected
+	}
+
+	seed := options.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.options = options
+	s.random = rand.New(rand.NewPCG(seed, seed))
+
+	return nil
+}
+
+// Injected returns how many failures were injected in the operation.
+func (s *Storer) Injected(operation Operation) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.injected[operation]
+}
+
+// fail waits for the latency of the operation then returns the error to
+// return when the call must fail, nil otherwise.
+func (s *Storer) fail(ctx context.Context, operation Operation) error {
+	s.mu.Lock()
+
+	fault, ok := s.options.Operations[operation]
+	if !ok {
+		fault = s.options.Default
+	}
+
+	latency := fault.Latency
+	if fault.Jitter > 0 {
+		latency += time.Duration(s.random.Int64N(int64(fault.Jitter) + 1))
+	}
+
+	failing := fault.ErrorRate > 0 && s.random.Float64() < fault.ErrorRate
+	if failing {
+		s.injected[operation]++
+	}
+
+	err := s.options.Err
+	s.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if failing {
+		return err
+	}
+
+	return nil
+}
+
+// MapKeys method returns no key when the listing fails.
+func (s *Storer) MapKeys(prefix string) map[string]string {
+	if s.fail(context.Background(), List) != nil {
+		return map[string]string{}
+	}
+
+	return s.Storer.MapKeys(prefix)
+}
+
+// ListKeys method returns no key when the listing fails.
+func (s *Storer) ListKeys() []string {
+	if s.fail(context.Background(), List) != nil {
+		return []string{}
+	}
+
+	return s.Storer.ListKeys()
+}
+
+// Get method returns a miss when the read fails.
+func (s *Storer) Get(key string) []byte {
+	if s.fail(context.Background(), Get) != nil {
+		return nil
+	}
+
+	return s.Storer.Get(key)
+}
+
+// Set method returns the injected error when the write fails.
+func (s *Storer) Set(key string, value []byte, duration time.Duration) error {
+	if err := s.fail(context.Background(), Set); err != nil {
+		return err
+	}
+
+	return s.Storer.Set(key, value, duration)
+}
+
+// Delete method drops the deletion when it fails.
+func (s *Storer) Delete(key string) {
+	if s.fail(context.Background(), Delete) != nil {
+		return
+	}
+
+	s.Storer.Delete(key)
+}
+
+// DeleteMany method drops the deletion when it fails.
+func (s *Storer) DeleteMany(key string) {
+	if s.fail(context.Background(), Delete) != nil {
+		return
+	}
+
+	s.Storer.DeleteMany(key)
+}
+
+// GetMultiLevel method returns a miss when the read fails.
+func (s *Storer) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	if s.fail(req.Context(), Get) != nil {
+		return nil, nil
+	}
+
+	return s.Storer.GetMultiLevel(key, req, validator)
+}
+
+// SetMultiLevel method returns the injected error when the write fails.
+func (s *Storer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if err := s.fail(context.Background(), Set); err != nil {
+		return err
+	}
+
+	return s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+}
+
+// Healthy method returns the injected error when the probe fails, the health
+// of the decorated storer otherwise.
+func (s *Storer) Healthy(ctx context.Context) error {
+	if err := s.fail(ctx, Health); err != nil {
+		return err
+	}
+
+	return core.Healthy(ctx, s.Storer)
+}
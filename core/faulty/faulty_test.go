@@ -0,0 +1,150 @@
+package faulty_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/faulty"
+)
+
+// memoryStorer is a minimal map based Storer.
+type memoryStorer struct {
+	core.Storer
+
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+func newMemoryStorer() *memoryStorer {
+	return &memoryStorer{values: map[string][]byte{}}
+}
+
+func (m *memoryStorer) Get(key string) []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.values[key]
+}
+
+func (m *memoryStorer) Set(key string, value []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = value
+
+	return nil
+}
+
+func (m *memoryStorer) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+}
+
+func TestStorer_PartialFailures(t *testing.T) {
+	memory := newMemoryStorer()
+	_ = memory.Set("key", []byte("value"), time.Minute)
+
+	storer, err := faulty.New(memory, faulty.Options{Operations: map[faulty.Operation]faulty.Fault{
+		faulty.Set: {ErrorRate: 1},
+	}})
+	if err != nil {
+		t.Fatalf("The storer should be created, %v given", err)
+	}
+
+	if err = storer.Set("other", []byte("value"), time.Minute); !errors.Is(err, faulty.ErrInjected) {
+		t.Errorf("The write should fail, %v given", err)
+	}
+
+	if string(storer.Get("key")) != "value" {
+		t.Error("The read should succeed")
+	}
+
+	if storer.Injected(faulty.Set) != 1 || storer.Injected(faulty.Get) != 0 {
+		t.Errorf("The injected failures should be counted, %d writes and %d reads given", storer.Injected(faulty.Set), storer.Injected(faulty.Get))
+	}
+
+	outage := errors.New("outage")
+	_ = storer.SetOptions(faulty.Options{Default: faulty.Fault{ErrorRate: 1}, Err: outage})
+
+	storer.Delete("key")
+
+	if storer.Get("key") != nil || memory.Get("key") == nil {
+		t.Error("The read should be a miss and the deletion dropped")
+	}
+
+	if err = core.Healthy(context.Background(), storer); !errors.Is(err, outage) {
+		t.Errorf("The health check should fail with the given error, %v given", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	if fresh, stale := storer.GetMultiLevel("key", req, &core.Revalidator{}); fresh != nil || stale != nil {
+		t.Error("The multi level read should be a miss")
+	}
+}
+
+func TestStorer_ErrorRate(t *testing.T) {
+	storer, _ := faulty.New(newMemoryStorer(), faulty.Options{Default: faulty.Fault{ErrorRate: 0.5}, Seed: 42})
+
+	failures := 0
+
+	for range 1000 {
+		if storer.Set("key", []byte("value"), time.Minute) != nil {
+			failures++
+		}
+	}
+
+	if failures < 400 || failures > 600 {
+		t.Errorf("About half of the writes should fail, %d given", failures)
+	}
+
+	replayed, _ := faulty.New(newMemoryStorer(), faulty.Options{Default: faulty.Fault{ErrorRate: 0.5}, Seed: 42})
+
+	for range 1000 {
+		_ = replayed.Set("key", []byte("value"), time.Minute)
+	}
+
+	if replayed.Injected(faulty.Set) != failures {
+		t.Errorf("The same seed should inject the same failures, %d and %d given", replayed.Injected(faulty.Set), failures)
+	}
+}
+
+func TestStorer_Latency(t *testing.T) {
+	storer, _ := faulty.New(newMemoryStorer(), faulty.Options{Operations: map[faulty.Operation]faulty.Fault{
+		faulty.Get: {Latency: 20 * time.Millisecond},
+	}})
+
+	start := time.Now()
+	_ = storer.Get("key")
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("The read should be delayed, %s given", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_ = storer.SetOptions(faulty.Options{Default: faulty.Fault{Latency: time.Hour}})
+
+	if err := storer.Healthy(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("The latency should stop with the context, %v given", err)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	for _, options := range []faulty.Options{
+		{Default: faulty.Fault{ErrorRate: 1.5}},
+		{Default: faulty.Fault{Latency: -time.Second}},
+		{Operations: map[faulty.Operation]faulty.Fault{faulty.Get: {ErrorRate: -1}}},
+	} {
+		if _, err := faulty.New(newMemoryStorer(), options); err == nil {
+			t.Errorf("The options %+v should be rejected", options)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FillLockKeyPrefix prefixes the keys locked by FillWithOptions across the
+// instances.
+const FillLockKeyPrefix = "FILL_"
+
+// ErrFillNotStored is returned with the fetched value by Fill when the value
+// can't be stored.
+var ErrFillNotStored = errors.New("the filled value is not stored")
+
+const (
+	defaultFillLockTTL      = 10 * time.Second
+	defaultFillPollInterval = 50 * time.Millisecond
+)
+
+// FillOptions tunes FillWithOptions.
+type FillOptions struct {
+	// Distributed coalesces the fetches of every instance sharing the
+	// storage with its Locker, the instances missing the lock wait for the
+	// value stored by the lock holder.
+	Distributed bool
+	// LockTTL is how long the distributed lock is held at most, 10s by
+	// default. The instances waiting for the value fetch it by themselves
+	// once it's elapsed.
+	LockTTL time.Duration
+	// PollInterval is the delay between two reads of the instances waiting
+	// for the value, 50ms by default.
+	PollInterval time.Duration
+}
+
+// fillCall is a fetch in flight, shared by the concurrent misses of a key.
+type fillCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// fillGroup coalesces the concurrent fetches of a key in the process.
+type fillGroup struct {
+	mu    sync.Mutex
+	calls map[string]*fillCall
+}
+
+func (g *fillGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+
+	if call, found := g.calls[key]; found {
+		g.mu.Unlock()
+		<-call.done
+
+		return call.value, call.err
+	}
+
+	call := &fillCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	call.value, call.err = fn()
+
+	return call.value, call.err
+}
+
+var fills = &fillGroup{calls: map[string]*fillCall{}}
+
+// Fill returns the stored value of the key, on a miss it stores and returns
+// the value given by fetch. The concurrent misses of the same key in the
+// process share a single fetch.
+func Fill(storer Storer, key string, ttl time.Duration, fetch func() ([]byte, error)) ([]byte, error) {
+	return FillWithOptions(storer, key, ttl, fetch, FillOptions{})
+}
+
+// FillWithOptions is Fill, coalescing the fetches across the instances too
+// when the options are Distributed. The fetch error is returned as is and
+// nothing is stored. The fetched value is returned even when it can't be
+// stored, along with the storage error.
+func FillWithOptions(storer Storer, key string, ttl time.Duration, fetch func() ([]byte, error), options FillOptions) ([]byte, error) {
+	if value, err := Lookup(storer, key); err == nil {
+		return value, nil
+	}
+
+	return fills.do(storer.Name()+"-"+storer.Uuid()+"-"+key, func() ([]byte, error) {
+		// The value may have been stored while this call waited for its turn.
+		if value, err := Lookup(storer, key); err == nil {
+			return value, nil
+		}
+
+		if !options.Distributed {
+			return fetchAndStore(storer, key, ttl, fetch)
+		}
+
+		return fillDistributed(storer, key, ttl, fetch, options)
+	})
+}
+
+func fillDistributed(storer Storer, key string, ttl time.Duration, fetch func() ([]byte, error), options FillOptions) ([]byte, error) {
+	if options.LockTTL <= 0 {
+		options.LockTTL = defaultFillLockTTL
+	}
+
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaultFillPollInterval
+	}
+
+	locker := LockerFor(storer)
+	lockKey := FillLockKeyPrefix + key
+	deadline := time.Now().Add(options.LockTTL)
+
+	for {
+		acquired, err := locker.TryLock(lockKey, options.LockTTL)
+		if err == nil && acquired {
+			defer func() {
+				_ = locker.Unlock(lockKey)
+			}()
+
+			// The previous holder may have stored the value meanwhile.
+			if value, lookupErr := Lookup(storer, key); lookupErr == nil {
+				return value, nil
+			}
+
+			return fetchAndStore(storer, key, ttl, fetch)
+		}
+
+		// The origin is reached without coalescing when the locks can't be
+		// shared or when the holder takes too long.
+		if err != nil || time.Now().After(deadline) {
+			return fetchAndStore(storer, key, ttl, fetch)
+		}
+
+		time.Sleep(options.PollInterval)
+
+		if value, lookupErr := Lookup(storer, key); lookupErr == nil {
+			return value, nil
+		}
+	}
+}
+
+func fetchAndStore(storer Storer, key string, ttl time.Duration, fetch func() ([]byte, error)) ([]byte, error) {
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = storer.Set(key, value, ttl); err != nil {
+		return value, errors.Join(ErrFillNotStored, fmt.Errorf("impossible to store the key %s: %w", key, err))
+	}
+
+	return value, nil
+}
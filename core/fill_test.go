@@ -0,0 +1,108 @@
+package core_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestFill(t *testing.T) {
+	memory := newMemoryStorer()
+	release := make(chan struct{})
+
+	var fetches atomic.Int32
+
+	fetch := func() ([]byte, error) {
+		fetches.Add(1)
+		<-release
+
+		return []byte(baseValue), nil
+	}
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			value, err := core.Fill(memory, byteKey, time.Minute, fetch)
+			if err != nil || string(value) != baseValue {
+				t.Errorf("unexpected fill result %s, %v", value, err)
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fetches.Load() != 1 {
+		t.Errorf("the concurrent misses should share a single fetch, %d done", fetches.Load())
+	}
+
+	if string(memory.Get(byteKey)) != baseValue {
+		t.Error("the fetched value should be stored")
+	}
+
+	if _, err := core.Fill(memory, byteKey, time.Minute, func() ([]byte, error) {
+		t.Error("the stored value shouldn't be fetched")
+
+		return nil, nil
+	}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestFill_FetchError(t *testing.T) {
+	memory := newMemoryStorer()
+	errOrigin := errors.New("origin unavailable")
+
+	if _, err := core.Fill(memory, byteKey, time.Minute, func() ([]byte, error) {
+		return nil, errOrigin
+	}); !errors.Is(err, errOrigin) {
+		t.Errorf("the fetch error should be returned, got %v", err)
+	}
+
+	if len(memory.Get(byteKey)) != 0 {
+		t.Error("nothing should be stored when the fetch fails")
+	}
+}
+
+func TestFillWithOptions_Distributed(t *testing.T) {
+	storer := &lockingStorer{memoryStorer: newMemoryStorer(), MemoryLocker: core.NewMemoryLocker()}
+
+	// Another instance holds the lock and stores the value meanwhile.
+	_, _ = storer.TryLock(core.FillLockKeyPrefix+byteKey, time.Minute)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+
+		_ = storer.Set(byteKey, []byte(baseValue), time.Minute)
+		_ = storer.Unlock(core.FillLockKeyPrefix + byteKey)
+	}()
+
+	value, err := core.FillWithOptions(storer, byteKey, time.Minute, func() ([]byte, error) {
+		t.Error("the value stored by the lock holder shouldn't be fetched")
+
+		return nil, nil
+	}, core.FillOptions{Distributed: true, PollInterval: 5 * time.Millisecond})
+	if err != nil || string(value) != baseValue {
+		t.Errorf("the value stored by the lock holder should be returned, got %s, %v", value, err)
+	}
+
+	// The lock holder never stores the value.
+	_, _ = storer.TryLock(core.FillLockKeyPrefix+"other", time.Minute)
+
+	value, err = core.FillWithOptions(storer, "other", time.Minute, func() ([]byte, error) {
+		return []byte(baseValue), nil
+	}, core.FillOptions{Distributed: true, LockTTL: 30 * time.Millisecond, PollInterval: 5 * time.Millisecond})
+	if err != nil || string(value) != baseValue {
+		t.Errorf("the value should be fetched once the lock ttl elapsed, got %s, %v", value, err)
+	}
+}
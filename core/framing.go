@@ -0,0 +1,162 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// framedMagic starts the responses stored as a plain headers frame followed
+// by the compressed body frame, so their headers are read without
+// decompressing their body. The length of the headers frame is written as
+// an uvarint right after it.
+var framedMagic = []byte("\x00STGF")
+
+var errNotAResponse = errors.New("the value is not a response")
+
+// splitResponse returns the headers of the serialized response, the blank
+// line included, and its body.
+func splitResponse(value []byte) (head []byte, body []byte, ok bool) {
+	if !bytes.HasPrefix(value, []byte("HTTP/")) {
+		return nil, nil, false
+	}
+
+	end := bytes.Index(value, []byte("\r\n\r\n"))
+	if end < 0 {
+		return nil, nil, false
+	}
+
+	return value[:end+4], value[end+4:], true
+}
+
+// readFrames returns the headers frame and the body frame of a framed
+// value.
+func readFrames(value []byte) (head []byte, body []byte, ok bool) {
+	rest, found := bytes.CutPrefix(value, framedMagic)
+	if !found {
+		return nil, nil, false
+	}
+
+	length, read := binary.Uvarint(rest)
+	if read <= 0 || length > uint64(len(rest)-read) {
+		return nil, nil, false
+	}
+
+	rest = rest[read:]
+
+	return rest[:length], rest[length:], true
+}
+
+// framedCompressor stores the responses as a plain headers frame and a body
+// frame compressed by the decorated compressor, the other values are
+// compressed as a whole.
+type framedCompressor struct {
+	Compressor
+}
+
+func (f framedCompressor) Compress(value []byte) ([]byte, error) {
+	head, body, ok := splitResponse(value)
+	if !ok {
+		return f.Compressor.Compress(value)
+	}
+
+	var bodyFrame []byte
+
+	// The body frame is written to a pooled buffer then copied once into the
+	// framed value.
+	if buffered, ok := f.Compressor.(bufferedCompressor); ok {
+		compressed := getCompressionBuffer()
+		defer putCompressionBuffer(compressed)
+
+		if err := buffered.compressTo(compressed, body); err != nil {
+			return nil, err
+		}
+
+		bodyFrame = compressed.Bytes()
+	} else {
+		var err error
+		if bodyFrame, err = f.Compressor.Compress(body); err != nil {
+			return nil, err
+		}
+	}
+
+	framed := make([]byte, 0, len(framedMagic)+binary.MaxVarintLen64+len(head)+len(bodyFrame))
+	framed = append(framed, framedMagic...)
+	framed = binary.AppendUvarint(framed, uint64(len(head)))
+	framed = append(framed, head...)
+
+	return append(framed, bodyFrame...), nil
+}
+
+// Decompress returns the headers then the body, decompressed on its first
+// read only.
+func (f framedCompressor) Decompress(value []byte) (io.Reader, error) {
+	head, bodyFrame, ok := readFrames(value)
+	if !ok {
+		return f.Compressor.Decompress(value)
+	}
+
+	return io.MultiReader(bytes.NewReader(head), &lazyReader{open: func() (io.Reader, error) {
+		return DetectCompressor(bodyFrame).Decompress(bodyFrame)
+	}}), nil
+}
+
+// lazyReader opens its reader on the first read.
+type lazyReader struct {
+	open   func() (io.Reader, error)
+	reader io.Reader
+	err    error
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.reader == nil && l.err == nil {
+		l.reader, l.err = l.open()
+	}
+
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	return l.reader.Read(p)
+}
+
+// ReadHeaders returns the response stored in the value without its body.
+// The body of the framed values is never decompressed, the other values are
+// decompressed until the end of their headers.
+func ReadHeaders(value []byte) (*http.Response, error) {
+	var reader io.Reader
+
+	if head, _, ok := readFrames(value); ok {
+		reader = bytes.NewReader(head)
+	} else {
+		var err error
+		if reader, err = DetectCompressor(value).Decompress(value); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(reader), nil)
+	if err != nil {
+		return nil, errors.Join(errNotAResponse, err)
+	}
+
+	// Closing the body would read it until its end.
+	response.Body = http.NoBody
+
+	return response, nil
+}
+
+// GetHeadersOnly returns the response stored under the varied key without
+// its body, e.g. to answer a HEAD request or to revalidate it. The body of
+// the responses stored framed is never decompressed.
+func GetHeadersOnly(storer Storer, key string) (*http.Response, error) {
+	value, err := Lookup(storer, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadHeaders(value)
+}
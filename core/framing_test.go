@@ -0,0 +1,58 @@
+package core_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestFramedCompression(t *testing.T) {
+	head := "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 6000\r\n\r\n"
+	value := []byte(head + strings.Repeat("body! ", 1000))
+
+	compressor, _ := core.NewCompressor(core.ZstdCompression)
+	compressed, _ := compressor.Compress(value)
+
+	if !bytes.Contains(compressed, []byte(head)) || len(compressed) >= len(value) {
+		t.Fatal("the headers should be stored plain before the compressed body")
+	}
+
+	// A corrupted body frame is only noticed once the body is read.
+	corrupted := append(bytes.Clone(compressed[:len(compressed)-8]), bytes.Repeat([]byte{0xff}, 8)...)
+
+	response, err := core.ReadHeaders(corrupted)
+	if err != nil || response.Header.Get("Content-Type") != "text/plain" || response.Body != http.NoBody {
+		t.Fatalf("the headers should be read without the body, got %v", err)
+	}
+
+	reader, _ := core.DetectCompressor(compressed).Decompress(compressed)
+	if decompressed, _ := io.ReadAll(reader); !bytes.Equal(decompressed, value) {
+		t.Error("the framed value should be decompressed as a whole")
+	}
+
+	if _, err = core.ReadHeaders([]byte("not a response")); err == nil {
+		t.Error("the value which isn't a response should be rejected")
+	}
+}
+
+func TestGetHeadersOnly(t *testing.T) {
+	storer := newMemoryStorer()
+	compressor, _ := core.NewCompressor(core.LZ4Compression)
+
+	compressed, _ := compressor.Compress([]byte("HTTP/1.1 200 OK\r\nEtag: \"v1\"\r\nContent-Length: 5\r\n\r\nHello"))
+	_ = storer.Set("varied", compressed, time.Minute)
+
+	response, err := core.GetHeadersOnly(storer, "varied")
+	if err != nil || response.Header.Get("Etag") != `"v1"` || response.ContentLength != 5 {
+		t.Fatalf("the stored headers should be returned, got %v", err)
+	}
+
+	if _, err = core.GetHeadersOnly(storer, "missing"); err == nil {
+		t.Error("a missing key should return an error")
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AgeHeader is the RFC 9111 header holding the time in seconds since the
+// response was generated or validated by the origin.
+const AgeHeader = "Age"
+
+// maxAgeSeconds is the greatest delta-seconds sent, RFC 9111 section 1.2.2.
+const maxAgeSeconds = math.MaxInt32 + 1
+
+// Freshness is the state of a stored response at a given time.
+type Freshness struct {
+	// Age is the time elapsed since the response was stored, never
+	// negative.
+	Age time.Duration
+	// FreshRemaining is the time left before the response becomes stale,
+	// zero once it is.
+	FreshRemaining time.Duration
+	// StaleRemaining is the part of the stale window left once the response
+	// becomes stale, the whole window while it's fresh and zero once it
+	// can't be served anymore.
+	StaleRemaining time.Duration
+}
+
+// Fresh returns true while the response can be served without revalidation.
+func (f Freshness) Fresh() bool {
+	return f.FreshRemaining > 0
+}
+
+// ComputeFreshness returns the age and the remaining fresh and stale
+// durations of the varied key at now.
+func ComputeFreshness(meta KeyMetadata, now time.Time) Freshness {
+	return Freshness{
+		Age:            max(now.Sub(meta.StoredAt), 0),
+		FreshRemaining: max(meta.FreshUntil.Sub(now), 0),
+		StaleRemaining: max(meta.StaleUntil.Sub(maxTime(now, meta.FreshUntil)), 0),
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+// SetAge sets the Age header of the response served from the cache. The
+// age is added to the Age the response held when it was stored, e.g. sent
+// by an upstream cache, as the RFC 9111 corrected age. It's a whole number
+// of seconds, rounded down.
+func SetAge(res *http.Response, age time.Duration) {
+	if res == nil {
+		return
+	}
+
+	if res.Header == nil {
+		res.Header = http.Header{}
+	}
+
+	seconds := int64(max(age, 0) / time.Second)
+
+	if initial, err := strconv.ParseInt(res.Header.Get(AgeHeader), 10, 64); err == nil && initial > 0 {
+		seconds += min(initial, maxAgeSeconds)
+	}
+
+	res.Header.Set(AgeHeader, strconv.FormatInt(min(seconds, maxAgeSeconds), 10))
+}
+
+// setElectedAge sets the Age of the response elected from the mapping entry.
+func setElectedAge(res *http.Response, index *KeyIndex, now time.Time) {
+	SetAge(res, ComputeFreshness(KeyMetadata{StoredAt: index.GetStoredAt().AsTime()}, now).Age)
+}
@@ -0,0 +1,73 @@
+package core_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestComputeFreshness(t *testing.T) {
+	storedAt := time.Now()
+	meta := core.KeyMetadata{
+		StoredAt:   storedAt,
+		FreshUntil: storedAt.Add(time.Minute),
+		StaleUntil: storedAt.Add(3 * time.Minute),
+	}
+
+	freshness := core.ComputeFreshness(meta, storedAt.Add(20*time.Second))
+	if !freshness.Fresh() || freshness.Age != 20*time.Second || freshness.FreshRemaining != 40*time.Second || freshness.StaleRemaining != 2*time.Minute {
+		t.Errorf("The fresh key should have its whole stale window left, %+v given", freshness)
+	}
+
+	freshness = core.ComputeFreshness(meta, storedAt.Add(2*time.Minute))
+	if freshness.Fresh() || freshness.FreshRemaining != 0 || freshness.StaleRemaining != time.Minute {
+		t.Errorf("The stale key should have a part of its stale window left, %+v given", freshness)
+	}
+
+	freshness = core.ComputeFreshness(meta, storedAt.Add(-time.Second))
+	if freshness.Age != 0 || core.ComputeFreshness(meta, storedAt.Add(time.Hour)).StaleRemaining != 0 {
+		t.Errorf("The durations should never be negative, %+v given", freshness)
+	}
+}
+
+func TestSetAge(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	core.SetAge(res, 1500*time.Millisecond)
+
+	if age := res.Header.Get(core.AgeHeader); age != "1" {
+		t.Errorf("The age should be rounded down to the second, %s given", age)
+	}
+
+	res.Header.Set(core.AgeHeader, "30")
+	core.SetAge(res, 10*time.Second)
+
+	if age := res.Header.Get(core.AgeHeader); age != "40" {
+		t.Errorf("The age should be added to the stored one, %s given", age)
+	}
+
+	res.Header.Set(core.AgeHeader, "99999999999")
+	core.SetAge(res, time.Second)
+
+	if age := res.Header.Get(core.AgeHeader); age != "2147483648" {
+		t.Errorf("The age should be capped, %s given", age)
+	}
+}
+
+func TestMappingElection_Age(t *testing.T) {
+	memory := newMemoryStorer()
+	value := []byte("HTTP/1.1 200 OK\r\nAge: 5\r\nContent-Length: 5\r\n\r\nHello")
+
+	_ = memory.SetMultiLevel("base", "varied", value, nil, "", time.Minute, "real")
+
+	fresh, _ := memory.GetMultiLevel("base", httptest.NewRequest(http.MethodGet, "/", nil), &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The stored response should be elected")
+	}
+
+	if age := fresh.Header.Get(core.AgeHeader); age != "5" {
+		t.Errorf("The Age should be the stored one plus the resident time, %s given", age)
+	}
+}
@@ -1,8 +1,18 @@
 module github.com/darkweak/storages/core
 
-go 1.22.1
+go 1.23
 
 require (
+	github.com/cespare/xxhash/v2 v2.3.0
+	github.com/go-viper/mapstructure/v2 v2.2.1
+	github.com/klauspost/compress v1.18.4
 	github.com/pierrec/lz4/v4 v4.1.23
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	golang.org/x/crypto v0.31.0
 	google.golang.org/protobuf v1.36.5
 )
+
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+)
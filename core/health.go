@@ -0,0 +1,31 @@
+package core
+
+import "context"
+
+// HealthChecker is an optional interface a Storer can implement to probe its
+// backend, so the callers can wire readiness endpoints or stop sending
+// requests to an unavailable storage. Healthy returns nil when the backend
+// answers before the context is done.
+type HealthChecker interface {
+	Healthy(ctx context.Context) error
+}
+
+// Healthy probes the first storer implementing HealthChecker, walking down
+// the decorators. The storers without probe, usually the in-memory ones,
+// are considered healthy.
+func Healthy(ctx context.Context, storer Storer) error {
+	for storer != nil {
+		if checker, ok := storer.(HealthChecker); ok {
+			return checker.Healthy(ctx)
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+type probedStorer struct {
+	*memoryStorer
+	err error
+}
+
+func (p *probedStorer) Healthy(context.Context) error {
+	return p.err
+}
+
+func TestHealthy(t *testing.T) {
+	if err := core.Healthy(context.Background(), newMemoryStorer()); err != nil {
+		t.Errorf("A storer without probe should be healthy, %v given", err)
+	}
+
+	down := &probedStorer{memoryStorer: newMemoryStorer(), err: errUnavailable}
+
+	if err := core.Healthy(context.Background(), core.NewInstrumentedStorer(down, nil)); !errors.Is(err, errUnavailable) {
+		t.Errorf("The decorated storer should be probed, %v given", err)
+	}
+
+	chained := core.NewChainedStorer(newMemoryStorer(), down, time.Minute)
+	if err := core.Healthy(context.Background(), chained); !errors.Is(err, errUnavailable) {
+		t.Errorf("The remote storer failure should be reported, %v given", err)
+	}
+}
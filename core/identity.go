@@ -0,0 +1,186 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// InstanceIDConfigurationKey is the key read from the provider configuration
+// to name the instance, it replaces the derived Uuid.
+const InstanceIDConfigurationKey = "instance_id"
+
+// addressConfigurationKeys are the lists of addresses whose order doesn't
+// change the instance identity.
+var addressConfigurationKeys = []string{
+	"Addrs",
+	"Endpoints",
+	"InitAddress",
+	"Servers",
+	"peers",
+	"servers",
+}
+
+// credentialConfigurationKeys are the lowercased keys holding credentials,
+// left out of the identity since the Uuid exposes its unsalted hash.
+var credentialConfigurationKeys = []string{
+	"access_key",
+	"encryption_key",
+	"nkey",
+	"password",
+	"secret",
+	"secret_key",
+	"sentinelpassword",
+	"session_token",
+	"token",
+}
+
+// InstanceID returns the identity of the storage, returned by its Uuid. It's
+// the instance_id of the configuration when set, a hash of the storage name,
+// the canonicalized configuration, the URL and the stale duration otherwise.
+// The same configuration always returns the same identity, whatever the
+// order of its keys and of its addresses, and two configurations differing by
+// any other value than the credentials return distinct ones.
+func InstanceID(name string, provider CacheProvider, stale time.Duration) string {
+	cfg, _ := provider.Configuration.(map[string]interface{})
+	if id, ok := cfg[InstanceIDConfigurationKey].(string); ok && id != "" {
+		return id
+	}
+
+	canonical := canonicalConfiguration(provider.Configuration)
+
+	// encoding/json sorts the map keys.
+	configuration, err := json.Marshal(canonical)
+	if err != nil {
+		// The configurations encoding/json can't marshal, e.g. holding a
+		// function, are hashed from their fmt representation, which sorts
+		// the map keys too.
+		configuration = fmt.Appendf(nil, "%v", canonical)
+	}
+
+	hash := sha256.New()
+	for _, part := range [][]byte{
+		[]byte(strings.ToLower(name)),
+		configuration,
+		[]byte(canonicalAddresses(provider.URL)),
+		[]byte(stale.String()),
+	} {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// isCredential reports whether the key of the parent configuration holds a
+// credential.
+func isCredential(parent, key string) bool {
+	key = strings.ToLower(key)
+
+	return slices.Contains(credentialConfigurationKeys, key) || (parent == EncryptionConfigurationKey && key == "key")
+}
+
+// withoutPassword returns the address without the password of its user
+// info.
+func withoutPassword(address string) string {
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.User == nil {
+		return address
+	}
+
+	if _, ok := parsed.User.Password(); !ok {
+		return address
+	}
+
+	parsed.User = url.User(parsed.User.Username())
+
+	return parsed.String()
+}
+
+// withoutCredentials returns a copy of the nested configuration without the
+// credentials.
+func withoutCredentials(parent string, value any) any {
+	cfg, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	copied := make(map[string]interface{}, len(cfg))
+
+	for key, nested := range cfg {
+		if !isCredential(parent, key) {
+			copied[key] = withoutCredentials(key, nested)
+		}
+	}
+
+	return copied
+}
+
+// canonicalAddresses returns the comma separated addresses sorted, without
+// their password.
+func canonicalAddresses(addresses string) string {
+	if addresses == "" {
+		return ""
+	}
+
+	list := strings.Split(addresses, ",")
+	for i := range list {
+		list[i] = withoutPassword(strings.TrimSpace(list[i]))
+	}
+
+	slices.Sort(list)
+
+	return strings.Join(list, ",")
+}
+
+// canonicalConfiguration returns a copy of the configuration without the
+// instance_id and the credentials, and with the address lists sorted.
+func canonicalConfiguration(configuration any) any {
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok {
+		return configuration
+	}
+
+	canonical := make(map[string]interface{}, len(cfg))
+
+	for key, value := range cfg {
+		if key == InstanceIDConfigurationKey || isCredential("", key) {
+			continue
+		}
+
+		if !slices.Contains(addressConfigurationKeys, key) {
+			canonical[key] = withoutCredentials(key, value)
+
+			continue
+		}
+
+		switch addresses := value.(type) {
+		case string:
+			canonical[key] = canonicalAddresses(addresses)
+		case []string:
+			canonical[key] = canonicalAddresses(strings.Join(addresses, ","))
+		case []interface{}:
+			canonical[key] = value
+
+			list := make([]string, 0, len(addresses))
+			for _, address := range addresses {
+				if s, ok := address.(string); ok {
+					list = append(list, s)
+				}
+			}
+
+			if len(list) == len(addresses) {
+				canonical[key] = canonicalAddresses(strings.Join(list, ","))
+			}
+		default:
+			canonical[key] = value
+		}
+	}
+
+	return canonical
+}
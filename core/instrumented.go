@@ -0,0 +1,119 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Metrics receives the observations made by an InstrumentedStorer. The
+// provider argument is the wrapped storer name.
+type Metrics interface {
+	ObserveHit(provider string)
+	ObserveMiss(provider string)
+	ObserveSetError(provider string)
+	ObserveLatency(provider, operation string, duration time.Duration)
+}
+
+// InstrumentedStorer decorates any Storer to report its hits, misses, set
+// errors and operations latency to the given Metrics.
+type InstrumentedStorer struct {
+	Storer
+
+	metrics Metrics
+}
+
+// NewInstrumentedStorer wraps the storer to report its activity to metrics.
+func NewInstrumentedStorer(storer Storer, metrics Metrics) *InstrumentedStorer {
+	return &InstrumentedStorer{Storer: storer, metrics: metrics}
+}
+
+// Unwrap returns the decorated storer.
+func (s *InstrumentedStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+func (s *InstrumentedStorer) observe(operation string, start time.Time) {
+	s.metrics.ObserveLatency(s.Name(), operation, time.Since(start))
+}
+
+// Get reports a hit when a value is returned, a miss otherwise.
+func (s *InstrumentedStorer) Get(key string) []byte {
+	defer s.observe("get", time.Now())
+
+	value := s.Storer.Get(key)
+	if len(value) == 0 {
+		s.metrics.ObserveMiss(s.Name())
+	} else {
+		s.metrics.ObserveHit(s.Name())
+	}
+
+	return value
+}
+
+// Lookup method returns the stored value, ErrKeyNotFound counts as a miss.
+func (s *InstrumentedStorer) Lookup(key string) ([]byte, error) {
+	defer s.observe("get", time.Now())
+
+	value, err := Lookup(s.Storer, key)
+	if err == nil {
+		s.metrics.ObserveHit(s.Name())
+	} else if errors.Is(err, ErrKeyNotFound) {
+		s.metrics.ObserveMiss(s.Name())
+	}
+
+	return value, err
+}
+
+// Set reports the set errors.
+func (s *InstrumentedStorer) Set(key string, value []byte, duration time.Duration) error {
+	defer s.observe("set", time.Now())
+
+	err := s.Storer.Set(key, value, duration)
+	if err != nil {
+		s.metrics.ObserveSetError(s.Name())
+	}
+
+	return err
+}
+
+// Delete reports the delete latency.
+func (s *InstrumentedStorer) Delete(key string) {
+	defer s.observe("delete", time.Now())
+
+	s.Storer.Delete(key)
+}
+
+// DeleteMany reports the delete many latency.
+func (s *InstrumentedStorer) DeleteMany(key string) {
+	defer s.observe("delete_many", time.Now())
+
+	s.Storer.DeleteMany(key)
+}
+
+// GetMultiLevel reports a hit when a fresh or stale response is elected, a
+// miss otherwise.
+func (s *InstrumentedStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	defer s.observe("get_multi_level", time.Now())
+
+	fresh, stale = s.Storer.GetMultiLevel(key, req, validator)
+	if fresh == nil && stale == nil {
+		s.metrics.ObserveMiss(s.Name())
+	} else {
+		s.metrics.ObserveHit(s.Name())
+	}
+
+	return fresh, stale
+}
+
+// SetMultiLevel reports the set errors.
+func (s *InstrumentedStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	defer s.observe("set_multi_level", time.Now())
+
+	err := s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+	if err != nil {
+		s.metrics.ObserveSetError(s.Name())
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package core_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+type recordedMetrics struct {
+	mu         sync.Mutex
+	hits       int
+	misses     int
+	setErrors  int
+	operations map[string]int
+}
+
+func (r *recordedMetrics) ObserveHit(string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hits++
+}
+
+func (r *recordedMetrics) ObserveMiss(string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.misses++
+}
+
+func (r *recordedMetrics) ObserveSetError(string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setErrors++
+}
+
+func (r *recordedMetrics) ObserveLatency(provider, operation string, _ time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.operations[provider+"/"+operation]++
+}
+
+func TestInstrumentedStorer(t *testing.T) {
+	metrics := &recordedMetrics{operations: map[string]int{}}
+	storer := core.NewInstrumentedStorer(newMemoryStorer(), metrics)
+
+	_ = storer.Set("key", []byte("value"), time.Minute)
+	_ = storer.Set("", []byte("value"), time.Minute)
+
+	if string(storer.Get("key")) != "value" {
+		t.Error("The wrapped storer should return the stored value")
+	}
+
+	_ = storer.Get("unknown")
+	storer.Delete("key")
+	_ = storer.Get("key")
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com", nil)
+	_, _ = storer.GetMultiLevel("unknown", req, &core.Revalidator{})
+
+	if metrics.hits != 1 || metrics.misses != 3 || metrics.setErrors != 1 {
+		t.Errorf("Unexpected observations, %d hits, %d misses and %d set errors given", metrics.hits, metrics.misses, metrics.setErrors)
+	}
+
+	for operation, count := range map[string]int{
+		"MEMORY/set":             2,
+		"MEMORY/get":             3,
+		"MEMORY/delete":          1,
+		"MEMORY/get_multi_level": 1,
+	} {
+		if metrics.operations[operation] != count {
+			t.Errorf("The %s operation should be observed %d times, %d given", operation, count, metrics.operations[operation])
+		}
+	}
+}
+
+func TestObserveCompression(t *testing.T) {
+	var raw, compressed int
+
+	core.ObserveCompression(func(codec string, r, c int) {
+		if codec == core.NoCompression {
+			raw, compressed = r, c
+		}
+	})
+	defer core.ObserveCompression(nil)
+
+	compressor, _ := core.NewCompressor(core.NoCompression)
+	_, _ = compressor.Compress([]byte("value"))
+
+	if raw != 5 || compressed != 5 {
+		t.Errorf("The compression should be observed, %d raw and %d compressed given", raw, compressed)
+	}
+}
@@ -0,0 +1,346 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// InvalidationConfigurationKey is the key read from the provider
+	// configuration to declare the storage carrying the invalidations.
+	InvalidationConfigurationKey = "invalidation"
+	// InvalidationKeyPrefix prefixes the keys or the subjects the
+	// invalidations are published under by the storages without a native
+	// pub/sub.
+	InvalidationKeyPrefix = "INVALIDATION_"
+	// DefaultInvalidationChannel is the channel the invalidations are
+	// published on unless configured otherwise.
+	DefaultInvalidationChannel = "storages-invalidations"
+)
+
+// InvalidationKind is the deletion carried by an InvalidationEvent.
+type InvalidationKind string
+
+const (
+	// InvalidateKey deletes the key.
+	InvalidateKey InvalidationKind = "delete"
+	// InvalidatePattern deletes the keys matching the regular expression.
+	InvalidatePattern InvalidationKind = "delete_many"
+	// InvalidatePrefix deletes the keys starting with the prefix.
+	InvalidatePrefix InvalidationKind = "prefix"
+	// InvalidateTag deletes the keys listed by the surrogate key of the tag
+	// and the surrogate key itself.
+	InvalidateTag InvalidationKind = "tag"
+)
+
+// InvalidationEvent is a deletion made by an instance, applied by the other
+// ones to their local storer.
+type InvalidationEvent struct {
+	Kind InvalidationKind `json:"kind"`
+	// Key is the deleted key, the regular expression with InvalidatePattern,
+	// the prefix with InvalidatePrefix or the tag with InvalidateTag.
+	Key string `json:"key"`
+	// Origin identifies the publisher, it ignores its own events.
+	Origin string `json:"origin"`
+}
+
+// Encode returns the JSON payload of the event.
+func (e InvalidationEvent) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// DecodeInvalidationEvent decodes the JSON payload of an event.
+func DecodeInvalidationEvent(payload []byte) (InvalidationEvent, error) {
+	var event InvalidationEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return event, fmt.Errorf("invalid invalidation event: %w", err)
+	}
+
+	switch event.Kind {
+	case InvalidateKey, InvalidatePattern, InvalidatePrefix, InvalidateTag:
+		return event, nil
+	default:
+		return event, fmt.Errorf("invalid invalidation event: unknown kind %s", event.Kind)
+	}
+}
+
+// ApplyInvalidation applies the event to the storer.
+func ApplyInvalidation(storer Storer, event InvalidationEvent) {
+	switch event.Kind {
+	case InvalidateKey:
+		storer.Delete(event.Key)
+	case InvalidatePattern:
+		storer.DeleteMany(event.Key)
+	case InvalidatePrefix:
+		storer.DeleteMany("^" + regexp.QuoteMeta(event.Key))
+	case InvalidateTag:
+		for _, key := range SurrogateKeys(storer, event.Key) {
+			storer.Delete(key)
+		}
+
+		storer.Delete(SurrogateKeyPrefix + event.Key)
+	}
+}
+
+// SurrogateKeys returns the keys listed by the surrogate key of the tag,
+// stored as comma separated escaped keys.
+func SurrogateKeys(storer Storer, tag string) []string {
+	keys := []string{}
+
+	for _, key := range strings.Split(string(storer.Get(SurrogateKeyPrefix+tag)), ",") {
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// InvalidationBus is an optional interface a Storer can implement to carry
+// the invalidations between the instances: Etcd with a watch, Redis with
+// pub/sub, Olric with its PubSub and Nats with a subject. The subscription
+// lasts until the returned function is called.
+type InvalidationBus interface {
+	PublishInvalidation(channel string, event InvalidationEvent) error
+	SubscribeInvalidations(channel string, fn func(InvalidationEvent)) (func(), error)
+}
+
+// InvalidationBusFor returns the InvalidationBus implemented by the storer
+// or one of the storers it decorates.
+func InvalidationBusFor(storer Storer) (InvalidationBus, bool) {
+	for storer != nil {
+		if bus, ok := storer.(InvalidationBus); ok {
+			return bus, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// LocalInvalidationBus is an in-process InvalidationBus, e.g. for several
+// local storers in front of the same remote one.
+type LocalInvalidationBus struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers map[string]map[int]func(InvalidationEvent)
+}
+
+// NewLocalInvalidationBus creates an empty LocalInvalidationBus.
+func NewLocalInvalidationBus() *LocalInvalidationBus {
+	return &LocalInvalidationBus{subscribers: map[string]map[int]func(InvalidationEvent){}}
+}
+
+// PublishInvalidation calls the subscribers of the channel synchronously.
+func (b *LocalInvalidationBus) PublishInvalidation(channel string, event InvalidationEvent) error {
+	b.mu.RLock()
+	subscribers := make([]func(InvalidationEvent), 0, len(b.subscribers[channel]))
+
+	for _, fn := range b.subscribers[channel] {
+		subscribers = append(subscribers, fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(event)
+	}
+
+	return nil
+}
+
+// SubscribeInvalidations registers fn until the returned function is
+// called.
+func (b *LocalInvalidationBus) SubscribeInvalidations(channel string, fn func(InvalidationEvent)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[channel] == nil {
+		b.subscribers[channel] = map[int]func(InvalidationEvent){}
+	}
+
+	id := b.next
+	b.next++
+	b.subscribers[channel][id] = fn
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[channel], id)
+	}, nil
+}
+
+// InvalidatedStorer decorates a local Storer, usually an in-memory one like
+// Otter or Ristretto, to keep it consistent across the instances. Its
+// deletions are published on the bus and the deletions published by the
+// other instances are applied to it, without being published again.
+type InvalidatedStorer struct {
+	Storer
+
+	bus         InvalidationBus
+	channel     string
+	origin      string
+	logger      Logger
+	unsubscribe func()
+	closeOnce   sync.Once
+}
+
+// NewInvalidatedStorer wraps the storer and subscribes it to the channel of
+// the bus, DefaultInvalidationChannel when empty.
+func NewInvalidatedStorer(storer Storer, bus InvalidationBus, channel string, logger Logger) (*InvalidatedStorer, error) {
+	if bus == nil {
+		return nil, errors.New("invalid invalidation configuration: the bus is required")
+	}
+
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+
+	s := &InvalidatedStorer{Storer: storer, bus: bus, channel: channel, origin: LockToken(), logger: logger}
+
+	unsubscribe, err := bus.SubscribeInvalidations(channel, s.apply)
+	if err != nil {
+		return nil, fmt.Errorf("impossible to subscribe to the %s invalidations: %w", channel, err)
+	}
+
+	s.unsubscribe = unsubscribe
+
+	return s, nil
+}
+
+// invalidationConfiguration is the typed bus read from the provider
+// configuration.
+type invalidationConfiguration struct {
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	Path          string `json:"path"`
+	Configuration any    `json:"configuration"`
+	Channel       string `json:"channel"`
+}
+
+// InvalidatedStorerFromConfiguration wraps the storer when the invalidation
+// key is set in the provider configuration, it returns the storer untouched
+// otherwise. The bus storage is created with NewStorer and must implement
+// InvalidationBus.
+func InvalidatedStorerFromConfiguration(storer Storer, provider CacheProvider, stale time.Duration, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	invalidationCfg, ok := cfg[InvalidationConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	var busCfg invalidationConfiguration
+	if err := DecodeConfiguration(invalidationCfg, &busCfg); err != nil {
+		return nil, fmt.Errorf("invalid invalidation configuration: %w", err)
+	}
+
+	busStorer, err := NewStorer(busCfg.Name, CacheProvider{
+		URL:           busCfg.URL,
+		Path:          busCfg.Path,
+		Configuration: busCfg.Configuration,
+	}, logger, stale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invalidation configuration: %w", err)
+	}
+
+	if err = busStorer.Init(); err != nil {
+		return nil, fmt.Errorf("invalid invalidation configuration: %w", err)
+	}
+
+	bus, ok := InvalidationBusFor(busStorer)
+	if !ok {
+		return nil, fmt.Errorf("invalid invalidation configuration: the %s storage can't carry the invalidations", busStorer.Name())
+	}
+
+	return NewInvalidatedStorer(storer, bus, busCfg.Channel, logger)
+}
+
+// Unwrap returns the decorated storer.
+func (s *InvalidatedStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// Origin returns the identifier of the published events.
+func (s *InvalidatedStorer) Origin() string {
+	return s.origin
+}
+
+// apply applies the event published by another instance.
+func (s *InvalidatedStorer) apply(event InvalidationEvent) {
+	if event.Origin == s.origin {
+		return
+	}
+
+	ApplyInvalidation(s.Storer, event)
+}
+
+func (s *InvalidatedStorer) publish(kind InvalidationKind, key string) {
+	if err := s.bus.PublishInvalidation(s.channel, InvalidationEvent{Kind: kind, Key: key, Origin: s.origin}); err != nil {
+		s.logger.Errorf("Impossible to publish the invalidation of %s, %v", key, err)
+	}
+}
+
+// Delete method deletes the key and publishes its invalidation.
+func (s *InvalidatedStorer) Delete(key string) {
+	s.Storer.Delete(key)
+	s.publish(InvalidateKey, key)
+}
+
+// DeleteMany method deletes the matching keys and publishes the pattern
+// invalidation.
+func (s *InvalidatedStorer) DeleteMany(key string) {
+	s.Storer.DeleteMany(key)
+	s.publish(InvalidatePattern, key)
+}
+
+// DeleteManyCount deletes the matching keys with the decorated storer and
+// publishes the pattern invalidation unless it's a dry-run.
+func (s *InvalidatedStorer) DeleteManyCount(pattern string, dryRun bool) (DeleteManyResult, error) {
+	result, err := DeleteManyCount(s.Storer, pattern, dryRun)
+	if err == nil && !dryRun {
+		s.publish(InvalidatePattern, pattern)
+	}
+
+	return result, err
+}
+
+// InvalidateTags deletes the keys listed by the surrogate key of each tag
+// and the surrogate key itself, then publishes the tag invalidation.
+func (s *InvalidatedStorer) InvalidateTags(tags ...string) {
+	for _, tag := range tags {
+		ApplyInvalidation(s.Storer, InvalidationEvent{Kind: InvalidateTag, Key: tag})
+		s.publish(InvalidateTag, tag)
+	}
+}
+
+// Close stops applying the invalidations of the other instances.
+func (s *InvalidatedStorer) Close() {
+	s.closeOnce.Do(s.unsubscribe)
+}
+
+// Reset method stops the subscription and resets the decorated storer.
+func (s *InvalidatedStorer) Reset() error {
+	s.Close()
+
+	return s.Storer.Reset()
+}
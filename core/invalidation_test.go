@@ -0,0 +1,176 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// busStorer is a storage carrying the invalidations in the process.
+type busStorer struct {
+	*memoryStorer
+	*core.LocalInvalidationBus
+}
+
+var sharedBus = core.NewLocalInvalidationBus()
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("bus", func(core.CacheProvider, core.Logger, time.Duration) (core.Storer, error) {
+		return &busStorer{memoryStorer: newMemoryStorer(), LocalInvalidationBus: sharedBus}, nil
+	})
+}
+
+func TestInvalidationEvent(t *testing.T) {
+	event := core.InvalidationEvent{Kind: core.InvalidatePattern, Key: "^GET-.+", Origin: "node"}
+
+	payload, err := event.Encode()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if decoded, err := core.DecodeInvalidationEvent(payload); err != nil || decoded != event {
+		t.Errorf("The event should be decoded as encoded, %+v given, %v", decoded, err)
+	}
+
+	for _, payload := range []string{"", "{", `{"kind":"set","key":"k"}`} {
+		if _, err = core.DecodeInvalidationEvent([]byte(payload)); err == nil {
+			t.Errorf("The payload %q should be rejected", payload)
+		}
+	}
+}
+
+func TestInvalidatedStorer(t *testing.T) {
+	bus := core.NewLocalInvalidationBus()
+	first, second := newMemoryStorer(), newMemoryStorer()
+
+	firstNode, err := core.NewInvalidatedStorer(first, bus, "", nopLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	secondNode, _ := core.NewInvalidatedStorer(second, bus, "", nopLogger{})
+
+	for _, node := range []core.Storer{firstNode, secondNode} {
+		_ = node.Set(byteKey, []byte(baseValue), time.Minute)
+		_ = node.Set("GET-products-1", []byte(baseValue), time.Minute)
+		_ = node.Set("GET-products-2", []byte(baseValue), time.Minute)
+	}
+
+	firstNode.Delete(byteKey)
+
+	if len(second.Get(byteKey)) != 0 || len(first.Get(byteKey)) != 0 {
+		t.Error("The deletion should reach the local storer of every node")
+	}
+
+	if _, err = core.DeleteManyCount(secondNode, "^GET-products-1$", true); err != nil || len(first.Get("GET-products-1")) == 0 {
+		t.Error("The dry-runs shouldn't be published")
+	}
+
+	secondNode.DeleteMany("^GET-products-")
+
+	if len(first.Get("GET-products-1")) != 0 || len(first.Get("GET-products-2")) != 0 {
+		t.Error("The pattern deletion should reach the other nodes")
+	}
+
+	firstNode.Close()
+	_ = first.Set(byteKey, []byte(baseValue), time.Minute)
+	secondNode.Delete(byteKey)
+
+	if len(first.Get(byteKey)) == 0 {
+		t.Error("The closed storer shouldn't apply the invalidations anymore")
+	}
+
+	if _, err = core.NewInvalidatedStorer(first, nil, "", nopLogger{}); err == nil {
+		t.Error("The bus should be required")
+	}
+}
+
+func TestInvalidatedStorer_InvalidateTags(t *testing.T) {
+	bus := core.NewLocalInvalidationBus()
+	first, second := newMemoryStorer(), newMemoryStorer()
+
+	firstNode, _ := core.NewInvalidatedStorer(first, bus, "", nopLogger{})
+	defer firstNode.Close()
+
+	secondNode, _ := core.NewInvalidatedStorer(second, bus, "", nopLogger{})
+	defer secondNode.Close()
+
+	for _, memory := range []*memoryStorer{first, second} {
+		_ = memory.Set("tagged-1", []byte(baseValue), time.Minute)
+		_ = memory.Set("untagged", []byte(baseValue), time.Minute)
+		_ = memory.Set(core.SurrogateKeyPrefix+"products", []byte("tagged-1"), time.Minute)
+	}
+
+	firstNode.InvalidateTags("products")
+
+	for _, memory := range []*memoryStorer{first, second} {
+		if len(memory.Get("tagged-1")) != 0 || len(memory.Get(core.SurrogateKeyPrefix+"products")) != 0 {
+			t.Error("The tagged keys should be deleted on every node")
+		}
+
+		if len(memory.Get("untagged")) == 0 {
+			t.Error("The untagged keys should be kept")
+		}
+	}
+}
+
+func TestInvalidatedStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.InvalidatedStorerFromConfiguration(memory, core.CacheProvider{}, 0, nopLogger{})
+	if err != nil || storer != core.Storer(memory) {
+		t.Error("The storer should be returned untouched without invalidation")
+	}
+
+	for _, name := range []string{"unknown", "memory"} {
+		if _, err = core.InvalidatedStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+			"invalidation": map[string]interface{}{"name": name},
+		}}, 0, nopLogger{}); err == nil {
+			t.Errorf("The %s storage can't carry the invalidations", name)
+		}
+	}
+
+	storer, err = core.InvalidatedStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"invalidation": map[string]interface{}{"name": "bus", "channel": "products"},
+	}}, 0, nopLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	defer storer.(*core.InvalidatedStorer).Close()
+
+	_ = memory.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = sharedBus.PublishInvalidation("products", core.InvalidationEvent{Kind: core.InvalidateKey, Key: byteKey, Origin: "other"})
+
+	if len(memory.Get(byteKey)) != 0 {
+		t.Error("The invalidations published on the configured channel should be applied")
+	}
+}
+
+func TestApplyInvalidation(t *testing.T) {
+	memory := newMemoryStorer()
+
+	for _, key := range []string{"GET-products-1", "GET-products-2", "GET-users-1", "tagged-1", "tagged 2"} {
+		_ = memory.Set(key, []byte(baseValue), time.Minute)
+	}
+
+	_ = memory.Set(core.SurrogateKeyPrefix+"products", []byte("tagged-1,tagged%202"), time.Minute)
+
+	if keys := core.SurrogateKeys(memory, "products"); len(keys) != 2 || keys[1] != "tagged 2" {
+		t.Errorf("The surrogate keys should be unescaped, %v given", keys)
+	}
+
+	core.ApplyInvalidation(memory, core.InvalidationEvent{Kind: core.InvalidatePrefix, Key: "GET-products-"})
+
+	if len(memory.Get("GET-products-1")) != 0 || len(memory.Get("GET-products-2")) != 0 || len(memory.Get("GET-users-1")) == 0 {
+		t.Error("The keys starting with the prefix should be deleted only")
+	}
+
+	core.ApplyInvalidation(memory, core.InvalidationEvent{Kind: core.InvalidateTag, Key: "products"})
+
+	if len(memory.Get("tagged-1")) != 0 || len(memory.Get("tagged 2")) != 0 || len(memory.Get(core.SurrogateKeyPrefix+"products")) != 0 {
+		t.Error("The tagged keys and the surrogate key should be deleted")
+	}
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TTLJitterConfigurationKey is the key read from the provider configuration
+// to randomize the durations given to the writes. Its value is a ratio (0.1)
+// or a percentage ("10%").
+const TTLJitterConfigurationKey = "ttl_jitter"
+
+// Jitter returns the duration randomized by up to ±ratio of itself. The
+// durations without expiration and the null ratios are returned untouched.
+func Jitter(duration time.Duration, ratio float64) time.Duration {
+	return jitter(duration, ratio, rand.Float64)
+}
+
+func jitter(duration time.Duration, ratio float64, random func() float64) time.Duration {
+	if duration <= 0 || ratio <= 0 {
+		return duration
+	}
+
+	jittered := duration + time.Duration((random()*2-1)*ratio*float64(duration))
+	if jittered <= 0 {
+		return duration
+	}
+
+	return jittered
+}
+
+// ParseJitterRatio parses a ratio (0.1) or a percentage ("10%"), it must be
+// in [0, 1).
+func ParseJitterRatio(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	percentage := strings.HasSuffix(value, "%")
+
+	ratio, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("the ratio %s is not a number", value)
+	}
+
+	if percentage {
+		ratio /= 100
+	}
+
+	if ratio < 0 || ratio >= 1 {
+		return 0, fmt.Errorf("the ratio must be in [0, 1), %s given", value)
+	}
+
+	return ratio, nil
+}
+
+// JitterStorer decorates any Storer to randomize the durations of Set and
+// SetMultiLevel, so the entries written by a burst with the same TTL don't
+// expire at once and hammer the origin. A 0.1 ratio spreads a 1h TTL from
+// 54m to 66m.
+type JitterStorer struct {
+	Storer
+
+	ratio  float64
+	random func() float64
+}
+
+// NewJitterStorer wraps the storer with the given ratio.
+func NewJitterStorer(storer Storer, ratio float64) (*JitterStorer, error) {
+	if ratio < 0 || ratio >= 1 {
+		return nil, fmt.Errorf("invalid ttl_jitter configuration: the ratio must be in [0, 1), %v given", ratio)
+	}
+
+	return &JitterStorer{Storer: storer, ratio: ratio, random: rand.Float64}, nil
+}
+
+// jitterConfiguration is the typed ratio read from the provider
+// configuration.
+type jitterConfiguration struct {
+	TTLJitter string         `json:"ttl_jitter"`
+	Others    map[string]any `json:",remain"`
+}
+
+// JitterStorerFromConfiguration wraps the storer when the ttl_jitter key is
+// set in the provider configuration, it returns the storer untouched
+// otherwise.
+func JitterStorerFromConfiguration(storer Storer, provider CacheProvider) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	if _, ok = cfg[TTLJitterConfigurationKey]; !ok {
+		return storer, nil
+	}
+
+	var jitterCfg jitterConfiguration
+	if err := DecodeConfiguration(cfg, &jitterCfg); err != nil {
+		return nil, fmt.Errorf("invalid ttl_jitter configuration: %w", err)
+	}
+
+	ratio, err := ParseJitterRatio(jitterCfg.TTLJitter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ttl_jitter configuration: %w", err)
+	}
+
+	return NewJitterStorer(storer, ratio)
+}
+
+// Unwrap returns the decorated storer.
+func (s *JitterStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// Ratio returns the jitter ratio.
+func (s *JitterStorer) Ratio() float64 {
+	return s.ratio
+}
+
+// Set method stores the value with the randomized duration.
+func (s *JitterStorer) Set(key string, value []byte, duration time.Duration) error {
+	return s.Storer.Set(key, value, jitter(duration, s.ratio, s.random))
+}
+
+// SetMultiLevel method stores the response with the randomized duration.
+func (s *JitterStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	return s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, jitter(duration, s.ratio, s.random), realKey)
+}
@@ -0,0 +1,130 @@
+package core_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// durationStorer records the durations given to the writes.
+type durationStorer struct {
+	*memoryStorer
+
+	mu        sync.Mutex
+	durations []time.Duration
+}
+
+func (d *durationStorer) Set(key string, value []byte, duration time.Duration) error {
+	d.mu.Lock()
+	d.durations = append(d.durations, duration)
+	d.mu.Unlock()
+
+	return d.memoryStorer.Set(key, value, duration)
+}
+
+func (d *durationStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	return d.Set(variedKey, value, duration)
+}
+
+func TestJitter(t *testing.T) {
+	if core.Jitter(0, 0.5) != 0 || core.Jitter(time.Hour, 0) != time.Hour {
+		t.Error("The durations without expiration and the null ratios should be untouched")
+	}
+
+	distinct := map[time.Duration]bool{}
+
+	for range 1000 {
+		jittered := core.Jitter(time.Hour, 0.1)
+		if jittered < 54*time.Minute || jittered > 66*time.Minute {
+			t.Fatalf("The jittered duration should be within ±10%%, %v given", jittered)
+		}
+
+		distinct[jittered] = true
+	}
+
+	if len(distinct) < 2 {
+		t.Error("The durations should be randomized")
+	}
+}
+
+func TestParseJitterRatio(t *testing.T) {
+	for value, expected := range map[string]float64{"0.1": 0.1, "10%": 0.1, " 25% ": 0.25, "0": 0} {
+		if ratio, err := core.ParseJitterRatio(value); err != nil || ratio != expected {
+			t.Errorf("The ratio %q should be %v, %v given, %v", value, expected, ratio, err)
+		}
+	}
+
+	for _, value := range []string{"", "abc", "-0.1", "1", "150%"} {
+		if _, err := core.ParseJitterRatio(value); err == nil {
+			t.Errorf("The ratio %q should be rejected", value)
+		}
+	}
+}
+
+func TestJitterStorer(t *testing.T) {
+	recorder := &durationStorer{memoryStorer: newMemoryStorer()}
+
+	storer, err := core.NewJitterStorer(recorder, 0.2)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for range 50 {
+		_ = storer.Set(byteKey, []byte(baseValue), time.Minute)
+		_ = storer.SetMultiLevel(byteKey, byteKey, []byte(baseValue), http.Header{}, "", time.Minute, byteKey)
+	}
+
+	distinct := map[time.Duration]bool{}
+
+	for _, duration := range recorder.durations {
+		if duration < 48*time.Second || duration > 72*time.Second {
+			t.Fatalf("The stored duration should be within ±20%%, %v given", duration)
+		}
+
+		distinct[duration] = true
+	}
+
+	if len(recorder.durations) != 100 || len(distinct) < 2 {
+		t.Errorf("Every write should get a randomized duration, %d writes and %d durations given", len(recorder.durations), len(distinct))
+	}
+
+	if string(storer.Get(byteKey)) != baseValue || storer.Unwrap() != core.Storer(recorder) {
+		t.Error("The value should be stored in the decorated storer")
+	}
+
+	if _, err = core.NewJitterStorer(recorder, 1); err == nil {
+		t.Error("A ratio of 1 should be rejected")
+	}
+}
+
+func TestJitterStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.JitterStorerFromConfiguration(memory, core.CacheProvider{})
+	if err != nil || storer != core.Storer(memory) {
+		t.Error("The storer should be returned untouched without ttl_jitter")
+	}
+
+	if _, err = core.JitterStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"ttl_jitter": "lots",
+	}}); err == nil {
+		t.Error("An invalid ttl_jitter value should be rejected")
+	}
+
+	for _, value := range []any{0.1, "10%"} {
+		storer, err = core.JitterStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+			"ttl_jitter": value,
+		}})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		jittered, ok := storer.(*core.JitterStorer)
+		if !ok || jittered.Ratio() != 0.1 {
+			t.Errorf("The %v ttl_jitter should wrap the storer with a 0.1 ratio", value)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// KeyHashingConfigurationKey is the key read from the provider
+	// configuration to hash the keys over a length.
+	KeyHashingConfigurationKey = "key_hashing"
+	// HashedKeyPrefix prefixes the hashed keys.
+	HashedKeyPrefix = "HASHED_"
+	// KeyHashIndexPrefix prefixes the keys holding the original key of a
+	// hashed one.
+	KeyHashIndexPrefix = "KEYHASH_"
+
+	// KeyHashingSHA256 hashes the keys with SHA-256.
+	KeyHashingSHA256 = "sha256"
+	// KeyHashingXXHash hashes the keys with the 64 bits xxHash, faster but
+	// more likely to collide.
+	KeyHashingXXHash = "xxhash"
+
+	// defaultKeyHashingMaxLength is the memcached key length limit.
+	defaultKeyHashingMaxLength = 250
+)
+
+// KeyHashingOptions tunes the HashedKeyStorer.
+type KeyHashingOptions struct {
+	// MaxLength is the length of the keys stored as is at most.
+	MaxLength int `json:"max_length"`
+	// Algorithm is KeyHashingSHA256 or KeyHashingXXHash.
+	Algorithm string `json:"algorithm"`
+}
+
+func defaultKeyHashingOptions() KeyHashingOptions {
+	return KeyHashingOptions{MaxLength: defaultKeyHashingMaxLength, Algorithm: KeyHashingSHA256}
+}
+
+func (o KeyHashingOptions) digest(key string) string {
+	if o.Algorithm == KeyHashingXXHash {
+		return HashedKeyPrefix + fmt.Sprintf("%016x", xxhash.Sum64String(key))
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return HashedKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+func (o KeyHashingOptions) validate() error {
+	switch o.Algorithm {
+	case KeyHashingSHA256, KeyHashingXXHash:
+	default:
+		return fmt.Errorf("the algorithm must be %s or %s, %s given", KeyHashingSHA256, KeyHashingXXHash, o.Algorithm)
+	}
+
+	// The hashed mapping keys must fit in the limit too.
+	if minLength := len(MappingKeyPrefix) + len(o.digest("")); o.MaxLength < minLength {
+		return fmt.Errorf("the max_length must be at least %d with the %s algorithm, %d given", minLength, o.Algorithm, o.MaxLength)
+	}
+
+	return nil
+}
+
+// HashedKeyStorer decorates any Storer to hash the keys longer than
+// MaxLength, e.g. the URLs and Vary values too long for memcached. The
+// mapping keys keep the MappingKeyPrefix first: the mapping of a long base
+// key is stored under MappingKeyPrefix + the hash of the base key.
+//
+// The original key of each hashed one written with Set or SetMultiLevel is
+// kept under KeyHashIndexPrefix + the hash with the same ttl, so MapKeys,
+// DeleteMany and GetMetadata still work with the original keys. The real
+// keys listed by ListKeys are stored in the mappings and never hashed.
+type HashedKeyStorer struct {
+	Storer
+
+	options KeyHashingOptions
+	stale   time.Duration
+}
+
+// NewHashedKeyStorer wraps the storer to hash its long keys.
+func NewHashedKeyStorer(storer Storer, options KeyHashingOptions, stale time.Duration) (*HashedKeyStorer, error) {
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid key_hashing configuration: %w", err)
+	}
+
+	return &HashedKeyStorer{Storer: storer, options: options, stale: stale}, nil
+}
+
+// HashedKeyStorerFromConfiguration wraps the storer when the key_hashing key
+// is set in the provider configuration, it returns the storer untouched
+// otherwise.
+func HashedKeyStorerFromConfiguration(storer Storer, provider CacheProvider, stale time.Duration) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	hashingCfg, ok := cfg[KeyHashingConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	options := defaultKeyHashingOptions()
+	if err := DecodeConfiguration(hashingCfg, &options); err != nil {
+		return nil, fmt.Errorf("invalid key_hashing configuration: %w", err)
+	}
+
+	return NewHashedKeyStorer(storer, options, stale)
+}
+
+// Unwrap returns the decorated storer.
+func (s *HashedKeyStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// hashed returns the key stored in the decorated storer.
+func (s *HashedKeyStorer) hashed(key string) string {
+	if len(key) <= s.options.MaxLength {
+		return key
+	}
+
+	if rest, found := strings.CutPrefix(key, MappingKeyPrefix); found {
+		return MappingKeyPrefix + s.options.digest(rest)
+	}
+
+	return s.options.digest(key)
+}
+
+// hashedBase returns the base key given to the decorated storer, its
+// mapping key must fit in the limit.
+func (s *HashedKeyStorer) hashedBase(key string) string {
+	return strings.TrimPrefix(s.hashed(MappingKeyPrefix+key), MappingKeyPrefix)
+}
+
+// remember stores the original key of the hashed one.
+func (s *HashedKeyStorer) remember(key, hashed string, duration time.Duration) {
+	key = strings.TrimPrefix(key, MappingKeyPrefix)
+	hashed = strings.TrimPrefix(hashed, MappingKeyPrefix)
+
+	if key != hashed {
+		_ = s.Storer.Set(KeyHashIndexPrefix+hashed, []byte(key), duration)
+	}
+}
+
+// index returns the original keys by hash.
+func (s *HashedKeyStorer) index() map[string]string {
+	return s.Storer.MapKeys(KeyHashIndexPrefix)
+}
+
+// original returns the original key of the stored one, using the given index
+// or reading it when nil. The key is returned as is when unknown.
+func (s *HashedKeyStorer) original(stored string, index map[string]string) string {
+	rest, mapping := strings.CutPrefix(stored, MappingKeyPrefix)
+	if !strings.HasPrefix(rest, HashedKeyPrefix) {
+		return stored
+	}
+
+	original, found := index[rest]
+	if index == nil {
+		value := s.Storer.Get(KeyHashIndexPrefix + rest)
+		original, found = string(value), len(value) > 0
+	}
+
+	if !found {
+		return stored
+	}
+
+	if mapping {
+		return MappingKeyPrefix + original
+	}
+
+	return original
+}
+
+// MapKeys method returns the keys starting with the prefix, the hashed keys
+// are returned with their original name.
+func (s *HashedKeyStorer) MapKeys(prefix string) map[string]string {
+	hashedPrefix := s.hashed(prefix)
+	index := s.index()
+	keys := map[string]string{}
+
+	for name, value := range s.Storer.MapKeys(hashedPrefix) {
+		stored := hashedPrefix + name
+		if strings.HasPrefix(stored, KeyHashIndexPrefix) {
+			continue
+		}
+
+		if original := s.original(stored, index); strings.HasPrefix(original, prefix) {
+			keys[strings.TrimPrefix(original, prefix)] = value
+		}
+	}
+
+	// The hashed keys whose original starts with the prefix while their hash
+	// doesn't.
+	for hash, original := range index {
+		for stored, name := range map[string]string{hash: original, MappingKeyPrefix + hash: MappingKeyPrefix + original} {
+			if strings.HasPrefix(stored, hashedPrefix) || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+
+			if value := s.Storer.Get(stored); len(value) > 0 {
+				keys[strings.TrimPrefix(name, prefix)] = string(value)
+			}
+		}
+	}
+
+	return keys
+}
+
+// Get method returns the value stored under the hashed key.
+func (s *HashedKeyStorer) Get(key string) []byte {
+	return s.Storer.Get(s.hashed(key))
+}
+
+// Lookup method returns the value stored under the hashed key.
+func (s *HashedKeyStorer) Lookup(key string) ([]byte, error) {
+	return Lookup(s.Storer, s.hashed(key))
+}
+
+// GetMetadata returns the metadata of the hashed base key, with the original
+// varied keys.
+func (s *HashedKeyStorer) GetMetadata(key string) ([]KeyMetadata, error) {
+	metadata, err := GetMetadata(s.Storer, s.hashedBase(key))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range metadata {
+		metadata[i].Key = s.original(metadata[i].Key, nil)
+	}
+
+	return metadata, nil
+}
+
+// GetMultiLevel returns the fresh and stale candidates of the hashed key.
+func (s *HashedKeyStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	return s.Storer.GetMultiLevel(s.hashedBase(key), req, validator)
+}
+
+// Set method stores the value under the hashed key.
+func (s *HashedKeyStorer) Set(key string, value []byte, duration time.Duration) error {
+	hashed := s.hashed(key)
+	if err := s.Storer.Set(hashed, value, duration); err != nil {
+		return err
+	}
+
+	s.remember(key, hashed, duration)
+
+	return nil
+}
+
+// SetMultiLevel stores the value and its mapping under the hashed keys, the
+// real key is kept as is.
+func (s *HashedKeyStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	hashedBase, hashedVaried := s.hashedBase(baseKey), s.hashed(variedKey)
+
+	if err := s.Storer.SetMultiLevel(hashedBase, hashedVaried, value, variedHeaders, etag, duration, realKey); err != nil {
+		return err
+	}
+
+	s.remember(baseKey, hashedBase, duration+s.stale)
+	s.remember(variedKey, hashedVaried, duration+s.stale)
+
+	return nil
+}
+
+// Delete method deletes the hashed key and its original one.
+func (s *HashedKeyStorer) Delete(key string) {
+	hashed := s.hashed(key)
+	s.Storer.Delete(hashed)
+
+	if hashed != key && !strings.HasPrefix(key, MappingKeyPrefix) {
+		s.Storer.Delete(KeyHashIndexPrefix + hashed)
+	}
+}
+
+// DeleteMany method deletes the keys matching the regular expression, the
+// hashed keys are matched by their original name.
+func (s *HashedKeyStorer) DeleteMany(key string) {
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	for hash, original := range s.index() {
+		matched := false
+
+		if rgKey.MatchString(original) {
+			s.Storer.Delete(hash)
+
+			matched = true
+		}
+
+		if rgKey.MatchString(MappingKeyPrefix + original) {
+			s.Storer.Delete(MappingKeyPrefix + hash)
+
+			matched = true
+		}
+
+		if matched {
+			s.Storer.Delete(KeyHashIndexPrefix + hash)
+		}
+	}
+
+	s.Storer.DeleteMany(key)
+}
+
+// TryLock acquires the lock of the hashed key.
+func (s *HashedKeyStorer) TryLock(key string, ttl time.Duration) (bool, error) {
+	return LockerFor(s.Storer).TryLock(s.hashed(key), ttl)
+}
+
+// Unlock releases the lock of the hashed key.
+func (s *HashedKeyStorer) Unlock(key string) error {
+	return LockerFor(s.Storer).Unlock(s.hashed(key))
+}
+
+// SetNX stores the value of the hashed key only if it doesn't exist.
+func (s *HashedKeyStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	return SetNX(s.Storer, s.hashed(key), value, ttl)
+}
+
+// CompareAndSwap replaces the value of the hashed key only if the stored
+// one equals old.
+func (s *HashedKeyStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return CompareAndSwap(s.Storer, s.hashed(key), old, value, ttl)
+}
+
+// RefreshTTL extends the expiration of the hashed key.
+func (s *HashedKeyStorer) RefreshTTL(key string, duration time.Duration) error {
+	return RefreshTTL(s.Storer, s.hashed(key), duration)
+}
+
+// GetTTL returns the remaining lifetime of the hashed key.
+func (s *HashedKeyStorer) GetTTL(key string) (time.Duration, error) {
+	return GetTTL(s.Storer, s.hashed(key))
+}
+
+// Increment adds delta to the counter of the hashed key.
+func (s *HashedKeyStorer) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	return Increment(s.Storer, s.hashed(key), delta, ttl)
+}
+
+// Transact runs fn in a transaction of the decorated storer, on the hashed
+// keys.
+func (s *HashedKeyStorer) Transact(fn func(tx Tx) error) error {
+	return Transact(s.Storer, func(tx Tx) error {
+		return fn(hashedTx{Tx: tx, storer: s})
+	})
+}
+
+// hashedTx hashes the long keys of the transaction.
+type hashedTx struct {
+	Tx
+	storer *HashedKeyStorer
+}
+
+func (t hashedTx) Get(key string) ([]byte, error) {
+	return t.Tx.Get(t.storer.hashed(key))
+}
+
+func (t hashedTx) Set(key string, value []byte, ttl time.Duration) error {
+	return t.Tx.Set(t.storer.hashed(key), value, ttl)
+}
+
+func (t hashedTx) Delete(key string) error {
+	return t.Tx.Delete(t.storer.hashed(key))
+}
@@ -0,0 +1,111 @@
+package core_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func hashingOptions() core.KeyHashingOptions {
+	return core.KeyHashingOptions{MaxLength: 100, Algorithm: core.KeyHashingSHA256}
+}
+
+func TestHashedKeyStorer(t *testing.T) {
+	memory := newMemoryStorer()
+	storer, _ := core.NewHashedKeyStorer(memory, hashingOptions(), 0)
+	long := "GET-domain.com-/" + strings.Repeat("a", 200)
+
+	_ = storer.Set("short", []byte("short"), time.Minute)
+	_ = storer.Set(long, []byte("long"), time.Minute)
+
+	if string(memory.Get("short")) != "short" || string(storer.Get(long)) != "long" {
+		t.Error("The short keys should be stored as is and the long ones read back")
+	}
+
+	for key := range memory.MapKeys("") {
+		if len(key) > 100 {
+			t.Errorf("The stored keys should be bounded, %s given", key)
+		}
+	}
+
+	keys := storer.MapKeys("")
+	if keys[long] != "long" || keys["short"] != "short" || len(keys) != 2 {
+		t.Errorf("The keys should be mapped with their original name, %v given", keys)
+	}
+
+	if keys = storer.MapKeys("GET-domain.com-/"); keys[strings.Repeat("a", 200)] != "long" {
+		t.Errorf("The hashed keys should be matched by their original prefix, %v given", keys)
+	}
+
+	storer.Delete(long)
+
+	if storer.Get(long) != nil || len(memory.MapKeys(core.KeyHashIndexPrefix)) != 0 {
+		t.Error("The hashed key and its original one should be deleted")
+	}
+
+	if _, err := core.NewHashedKeyStorer(memory, core.KeyHashingOptions{MaxLength: 50, Algorithm: core.KeyHashingSHA256}, 0); err == nil {
+		t.Error("A limit shorter than the hashed keys should be rejected")
+	}
+
+	if _, err := core.NewHashedKeyStorer(memory, core.KeyHashingOptions{MaxLength: 100, Algorithm: "md5"}, 0); err == nil {
+		t.Error("An unknown algorithm should be rejected")
+	}
+}
+
+func TestHashedKeyStorer_MultiLevel(t *testing.T) {
+	memory := newMemoryStorer()
+	storer, _ := core.NewHashedKeyStorer(memory, core.KeyHashingOptions{MaxLength: 100, Algorithm: core.KeyHashingXXHash}, time.Minute)
+	base := "GET-domain.com-/" + strings.Repeat("b", 200)
+	varied := base + "-gzip"
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+
+	if err := storer.SetMultiLevel(base, varied, value, http.Header{}, "", time.Minute, base); err != nil {
+		t.Fatalf("The multi level write shouldn't fail, %v given", err)
+	}
+
+	if fresh, _ := storer.GetMultiLevel(base, req, &core.Revalidator{}); fresh == nil {
+		t.Error("The response should be read with the long base key")
+	}
+
+	metadata, err := core.GetMetadata(storer, base)
+	if err != nil || len(metadata) != 1 || metadata[0].Key != varied || metadata[0].RealKey != base {
+		t.Errorf("The metadata should hold the original keys, %+v and %v given", metadata, err)
+	}
+
+	if _, found := storer.MapKeys(core.MappingKeyPrefix)[base]; !found {
+		t.Error("The mapping should be mapped with the original base key")
+	}
+
+	storer.DeleteMany("^" + core.MappingKeyPrefix + "GET-domain.com-/b+$")
+	storer.DeleteMany("gzip$")
+
+	if len(memory.MapKeys("")) != 0 {
+		t.Errorf("The hashed keys should be deleted by their original name, %v left", memory.MapKeys(""))
+	}
+}
+
+func TestHashedKeyStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	if wrapped, _ := core.HashedKeyStorerFromConfiguration(memory, core.CacheProvider{}, 0); wrapped != memory {
+		t.Error("The storer shouldn't be wrapped without key_hashing")
+	}
+
+	wrapped, err := core.HashedKeyStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		core.KeyHashingConfigurationKey: map[string]interface{}{"max_length": "200", "algorithm": "xxhash"},
+	}}, 0)
+	if _, ok := wrapped.(*core.HashedKeyStorer); !ok || err != nil {
+		t.Errorf("The storer should be wrapped, %T and %v given", wrapped, err)
+	}
+
+	if _, err = core.HashedKeyStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		core.KeyHashingConfigurationKey: map[string]interface{}{"length": 200},
+	}}, 0); err == nil {
+		t.Error("An unknown key should be rejected")
+	}
+}
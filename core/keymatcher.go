@@ -0,0 +1,248 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MatchMode is the way a KeyMatcher pattern matches the keys.
+type MatchMode uint8
+
+const (
+	// MatchExact matches the key equal to the pattern.
+	MatchExact MatchMode = iota
+	// MatchPrefix matches the keys starting with the pattern.
+	MatchPrefix
+	// MatchGlob matches the keys with the shell glob pattern: * matches any
+	// sequence, ? any character, [abc] or [a-z] a class and [!abc] its
+	// negation. A backslash escapes the next character.
+	MatchGlob
+	// MatchRegex matches the keys with the Go regular expression, unanchored
+	// unless the pattern says otherwise.
+	MatchRegex
+)
+
+var errUnclosedGlobClass = errors.New("unclosed character class")
+
+// String returns the mode name.
+func (m MatchMode) String() string {
+	switch m {
+	case MatchExact:
+		return "exact"
+	case MatchPrefix:
+		return "prefix"
+	case MatchGlob:
+		return "glob"
+	case MatchRegex:
+		return "regex"
+	default:
+		return fmt.Sprintf("MatchMode(%d)", m)
+	}
+}
+
+// KeyMatcher selects the keys to purge with the same semantics on every
+// storage, whatever the pattern syntax of the backend. Every mode is turned
+// into the Go regular expression given to DeleteManyCount.
+type KeyMatcher struct {
+	mode    MatchMode
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// NewKeyMatcher creates the KeyMatcher of the pattern in the given mode, it
+// fails when the glob or the regular expression is invalid.
+func NewKeyMatcher(mode MatchMode, pattern string) (KeyMatcher, error) {
+	var expression string
+
+	switch mode {
+	case MatchExact:
+		expression = "^" + regexp.QuoteMeta(pattern) + "$"
+	case MatchPrefix:
+		expression = "^" + regexp.QuoteMeta(pattern)
+	case MatchGlob:
+		translated, err := globToRegexp(pattern)
+		if err != nil {
+			return KeyMatcher{}, fmt.Errorf("invalid glob %s: %w", pattern, err)
+		}
+
+		expression = translated
+	case MatchRegex:
+		expression = pattern
+	default:
+		return KeyMatcher{}, fmt.Errorf("unknown match mode %s", mode)
+	}
+
+	regex, err := regexp.Compile(expression)
+	if err != nil {
+		return KeyMatcher{}, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	return KeyMatcher{mode: mode, pattern: pattern, regex: regex}, nil
+}
+
+// ExactKey matches the given key only.
+func ExactKey(key string) KeyMatcher {
+	matcher, _ := NewKeyMatcher(MatchExact, key)
+
+	return matcher
+}
+
+// KeysWithPrefix matches the keys starting with the given prefix.
+func KeysWithPrefix(prefix string) KeyMatcher {
+	matcher, _ := NewKeyMatcher(MatchPrefix, prefix)
+
+	return matcher
+}
+
+// KeysMatchingGlob matches the keys with the shell glob pattern.
+func KeysMatchingGlob(pattern string) (KeyMatcher, error) {
+	return NewKeyMatcher(MatchGlob, pattern)
+}
+
+// KeysMatchingRegex matches the keys with the Go regular expression.
+func KeysMatchingRegex(pattern string) (KeyMatcher, error) {
+	return NewKeyMatcher(MatchRegex, pattern)
+}
+
+// Mode returns the matcher mode.
+func (m KeyMatcher) Mode() MatchMode {
+	return m.mode
+}
+
+// Pattern returns the pattern as given.
+func (m KeyMatcher) Pattern() string {
+	return m.pattern
+}
+
+// String returns the mode and the pattern.
+func (m KeyMatcher) String() string {
+	return m.mode.String() + ":" + m.pattern
+}
+
+// Match reports whether the key is matched.
+func (m KeyMatcher) Match(key string) bool {
+	return m.regex != nil && m.regex.MatchString(key)
+}
+
+// Regexp returns the Go regular expression matching the same keys.
+func (m KeyMatcher) Regexp() string {
+	if m.regex == nil {
+		return ""
+	}
+
+	return m.regex.String()
+}
+
+// Prefix returns the literal prefix of every matched key, empty when the keys
+// can start with anything.
+func (m KeyMatcher) Prefix() string {
+	switch m.mode {
+	case MatchExact, MatchPrefix:
+		return m.pattern
+	default:
+		return PatternPrefix(m.Regexp())
+	}
+}
+
+// DeleteMatching deletes the keys selected by the matcher, it reports the
+// deleted keys like DeleteManyCount. The exact key is deleted directly, the
+// other modes go through DeleteManyCount with the matcher regular expression.
+func DeleteMatching(storer Storer, matcher KeyMatcher, dryRun bool) (DeleteManyResult, error) {
+	if matcher.regex == nil {
+		return DeleteManyResult{}, errors.New("the key matcher is not initialized")
+	}
+
+	if matcher.mode != MatchExact {
+		return DeleteManyCount(storer, matcher.Regexp(), dryRun)
+	}
+
+	result := DeleteManyResult{}
+
+	if _, err := Lookup(storer, matcher.pattern); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return result, nil
+		}
+
+		return result, err
+	}
+
+	if !dryRun {
+		storer.Delete(matcher.pattern)
+	}
+
+	result.Add(matcher.pattern, dryRun)
+
+	return result, nil
+}
+
+// globToRegexp translates the glob pattern into an anchored Go regular
+// expression.
+func globToRegexp(pattern string) (string, error) {
+	var builder strings.Builder
+
+	builder.WriteString("^")
+
+	runes := []rune(pattern)
+
+	for index := 0; index < len(runes); index++ {
+		switch current := runes[index]; current {
+		case '*':
+			builder.WriteString("(?s:.*)")
+		case '?':
+			builder.WriteString("(?s:.)")
+		case '\\':
+			if index+1 < len(runes) {
+				index++
+			}
+
+			builder.WriteString(regexp.QuoteMeta(string(runes[index])))
+		case '[':
+			end := index + 1
+			if end < len(runes) && (runes[end] == '!' || runes[end] == '^') {
+				end++
+			}
+
+			// A closing bracket right after the opening one is a member.
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+
+			if end >= len(runes) {
+				return "", errUnclosedGlobClass
+			}
+
+			class := runes[index+1 : end]
+			builder.WriteString("[")
+
+			if len(class) > 0 && class[0] == '!' {
+				builder.WriteString("^")
+
+				class = class[1:]
+			}
+
+			for _, member := range class {
+				if member == '\\' || member == '[' || member == ']' {
+					builder.WriteString("\\")
+				}
+
+				builder.WriteRune(member)
+			}
+
+			builder.WriteString("]")
+
+			index = end
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(current)))
+		}
+	}
+
+	builder.WriteString("$")
+
+	return builder.String(), nil
+}
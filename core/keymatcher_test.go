@@ -0,0 +1,102 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestKeyMatcher_Match(t *testing.T) {
+	for _, tc := range []struct {
+		mode    core.MatchMode
+		pattern string
+		key     string
+		matches bool
+	}{
+		{mode: core.MatchExact, pattern: "GET-a.b", key: "GET-a.b", matches: true},
+		{mode: core.MatchExact, pattern: "GET-a.b", key: "GET-axb"},
+		{mode: core.MatchExact, pattern: "GET-a", key: "GET-ab"},
+		{mode: core.MatchPrefix, pattern: "GET-a.", key: "GET-a.b", matches: true},
+		{mode: core.MatchPrefix, pattern: "GET-a.", key: "GET-ab"},
+		{mode: core.MatchPrefix, pattern: "GET", key: "IDX_GET"},
+		{mode: core.MatchGlob, pattern: "GET-*/products", key: "GET-https-domain.com-/products", matches: true},
+		{mode: core.MatchGlob, pattern: "GET-*/products", key: "GET-https-domain.com-/products/1"},
+		{mode: core.MatchGlob, pattern: "GET-?", key: "GET-a", matches: true},
+		{mode: core.MatchGlob, pattern: "GET-[a-c]", key: "GET-b", matches: true},
+		{mode: core.MatchGlob, pattern: "GET-[!a-c]", key: "GET-b"},
+		{mode: core.MatchGlob, pattern: "GET-\\*", key: "GET-*", matches: true},
+		{mode: core.MatchGlob, pattern: "GET-\\*", key: "GET-a"},
+		{mode: core.MatchGlob, pattern: "a.b", key: "axb"},
+		{mode: core.MatchRegex, pattern: "products", key: "GET-/products/1", matches: true},
+		{mode: core.MatchRegex, pattern: "^products", key: "GET-/products/1"},
+	} {
+		matcher, err := core.NewKeyMatcher(tc.mode, tc.pattern)
+		if err != nil {
+			t.Fatalf("unexpected error for %s:%s, %v", tc.mode, tc.pattern, err)
+		}
+
+		if matcher.Match(tc.key) != tc.matches {
+			t.Errorf("%s should match %s: %v", matcher, tc.key, tc.matches)
+		}
+	}
+}
+
+func TestKeyMatcher_Invalid(t *testing.T) {
+	if _, err := core.KeysMatchingGlob("GET-[a"); err == nil {
+		t.Error("an unclosed glob class should be rejected")
+	}
+
+	if _, err := core.KeysMatchingRegex("("); err == nil {
+		t.Error("an invalid regex should be rejected")
+	}
+
+	if _, err := core.NewKeyMatcher(core.MatchMode(42), "a"); err == nil {
+		t.Error("an unknown mode should be rejected")
+	}
+}
+
+func TestKeyMatcher_Prefix(t *testing.T) {
+	glob, _ := core.KeysMatchingGlob("GET-a.b*c")
+	regex, _ := core.KeysMatchingRegex("GET")
+
+	for matcher, expected := range map[core.KeyMatcher]string{
+		core.ExactKey("GET-a"):        "GET-a",
+		core.KeysWithPrefix("GET-a."): "GET-a.",
+		glob:                          "GET-a.b",
+		regex:                         "",
+	} {
+		if prefix := matcher.Prefix(); prefix != expected {
+			t.Errorf("the %s prefix should be %q, %q given", matcher, expected, prefix)
+		}
+	}
+}
+
+func TestDeleteMatching(t *testing.T) {
+	memory := newMemoryStorer()
+	_ = memory.Set("GET-a.b", []byte(baseValue), time.Minute)
+	_ = memory.Set("GET-axb", []byte(baseValue), time.Minute)
+	_ = memory.Set("IDX_GET-a.b", []byte(baseValue), time.Minute)
+
+	result, err := core.DeleteMatching(memory, core.KeysWithPrefix("GET-a."), true)
+	if err != nil || result.Count != 1 || result.Keys[0] != "GET-a.b" {
+		t.Fatalf("the dry-run should list the key starting with the prefix only, %+v given, %v", result, err)
+	}
+
+	if result, _ = core.DeleteMatching(memory, core.ExactKey("GET-axb"), false); result.Count != 1 || len(memory.Get("GET-axb")) != 0 {
+		t.Errorf("the exact key should be deleted, %+v given", result)
+	}
+
+	if result, _ = core.DeleteMatching(memory, core.ExactKey("missing"), false); result.Count != 0 {
+		t.Errorf("a missing key shouldn't be counted, %+v given", result)
+	}
+
+	glob, _ := core.KeysMatchingGlob("*GET-a.b")
+	if result, _ = core.DeleteMatching(memory, glob, false); result.Count != 2 {
+		t.Errorf("the keys matching the glob should be deleted, %+v given", result)
+	}
+
+	if _, err = core.DeleteMatching(memory, core.KeyMatcher{}, false); err == nil {
+		t.Error("the zero matcher should be rejected")
+	}
+}
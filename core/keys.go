@@ -0,0 +1,85 @@
+package core
+
+import (
+	"slices"
+	"time"
+)
+
+// KeyWalker is an optional interface a Storer can implement to stream the
+// keys returned by ListKeys instead of materializing them all in memory. The
+// walk stops early when fn returns false.
+type KeyWalker interface {
+	WalkKeys(fn func(key string) bool) error
+}
+
+// KeyPaginator is an optional interface a Storer can implement to list the
+// keys returned by ListKeys page by page. The empty cursor starts the
+// listing, the returned next cursor is empty once every key is listed. The
+// limit is a hint: the storages scanning their mappings never split one
+// across two pages, a page may also be empty while next isn't.
+type KeyPaginator interface {
+	ListKeysPaginated(cursor string, limit int) (keys []string, next string)
+}
+
+// WalkKeys streams the storer keys with its WalkKeys method when it
+// implements KeyWalker, it walks the ListKeys result otherwise.
+func WalkKeys(storer Storer, fn func(key string) bool) error {
+	if walker, ok := storer.(KeyWalker); ok {
+		return walker.WalkKeys(fn)
+	}
+
+	for _, key := range storer.ListKeys() {
+		if !fn(key) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// ListKeysPaginated returns a page of the storer keys with its
+// ListKeysPaginated method when it implements KeyPaginator. Otherwise the
+// ListKeys result is sorted and the cursor is the last key of the previous
+// page.
+func ListKeysPaginated(storer Storer, cursor string, limit int) (keys []string, next string) {
+	if paginator, ok := storer.(KeyPaginator); ok {
+		return paginator.ListKeysPaginated(cursor, limit)
+	}
+
+	all := storer.ListKeys()
+	slices.Sort(all)
+
+	start, _ := slices.BinarySearch(all, cursor)
+	if start < len(all) && all[start] == cursor {
+		start++
+	}
+
+	if limit <= 0 || start+limit >= len(all) {
+		return all[start:], ""
+	}
+
+	keys = all[start : start+limit]
+
+	return keys, keys[len(keys)-1]
+}
+
+// MappingRealKeys returns the real keys of the mapping entries which are
+// not fully expired at the given time.
+func MappingRealKeys(value []byte, now time.Time) []string {
+	mapping, err := DecodeMapping(value)
+	if err != nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(mapping.GetMapping()))
+
+	for _, v := range mapping.GetMapping() {
+		if v.GetFreshTime().AsTime().Before(now) && v.GetStaleTime().AsTime().Before(now) {
+			continue
+		}
+
+		keys = append(keys, v.GetRealKey())
+	}
+
+	return keys
+}
@@ -0,0 +1,77 @@
+package core_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestListKeysPaginated(t *testing.T) {
+	memory := newMemoryStorer()
+
+	for i := range 5 {
+		_ = memory.Set(fmt.Sprintf("key-%d", i), []byte(baseValue), time.Minute)
+	}
+
+	listed := []string{}
+	cursor := ""
+
+	for page := 0; ; page++ {
+		var keys []string
+
+		keys, cursor = core.ListKeysPaginated(memory, cursor, 2)
+		if len(keys) > 2 {
+			t.Errorf("The page %d shouldn't exceed the limit, %v given", page, keys)
+		}
+
+		listed = append(listed, keys...)
+
+		if cursor == "" {
+			break
+		}
+	}
+
+	if !slices.Equal(listed, []string{"key-0", "key-1", "key-2", "key-3", "key-4"}) {
+		t.Errorf("Every key should be listed once in order, %v given", listed)
+	}
+}
+
+func TestWalkKeys(t *testing.T) {
+	memory := newMemoryStorer()
+
+	for i := range 5 {
+		_ = memory.Set(fmt.Sprintf("key-%d", i), []byte(baseValue), time.Minute)
+	}
+
+	count := 0
+	_ = core.WalkKeys(memory, func(string) bool {
+		count++
+
+		return count < 3
+	})
+
+	if count != 3 {
+		t.Errorf("The walk should stop once fn returns false, %d keys walked", count)
+	}
+}
+
+func TestMappingRealKeys(t *testing.T) {
+	now := time.Now()
+
+	mapping, err := core.MappingUpdater("fresh", nil, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", "fresh-real")
+	if err != nil {
+		t.Fatalf("Impossible to build the mapping, %v", err)
+	}
+
+	mapping, err = core.MappingUpdater("expired", mapping, nopLogger{}, now, now.Add(-time.Hour), now.Add(-time.Minute), nil, "", "expired-real")
+	if err != nil {
+		t.Fatalf("Impossible to update the mapping, %v", err)
+	}
+
+	if keys := core.MappingRealKeys(mapping, now); !slices.Equal(keys, []string{"fresh-real"}) {
+		t.Errorf("Only the fresh key should be returned, %v given", keys)
+	}
+}
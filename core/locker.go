@@ -0,0 +1,107 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// LockKeyPrefix prefixes the lock keys so they never collide with the
+// stored values.
+const LockKeyPrefix = "LOCK_"
+
+// maxMemoryLocks is the number of held locks above which the expired ones
+// are swept on acquisition.
+const maxMemoryLocks = 1024
+
+// Locker is an optional interface a Storer can implement to hold short-lived
+// locks shared by every instance using the same storage, so only one of them
+// revalidates a given stale entry at a time. TryLock never waits: it returns
+// false when the lock is already held. The lock is released after ttl if it
+// is never unlocked.
+type Locker interface {
+	TryLock(key string, ttl time.Duration) (bool, error)
+	Unlock(key string) error
+}
+
+// MemoryLocker is the in-process Locker, used when the storer can't share
+// its locks with the other instances.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]time.Time
+}
+
+// NewMemoryLocker creates an empty MemoryLocker.
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: map[string]time.Time{}}
+}
+
+// TryLock acquires the lock unless it's held and not expired yet. A zero
+// ttl holds the lock until Unlock.
+func (l *MemoryLocker) TryLock(key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.locks) > maxMemoryLocks {
+		for k, expiresAt := range l.locks {
+			if !expiresAt.IsZero() && expiresAt.Before(now) {
+				delete(l.locks, k)
+			}
+		}
+	}
+
+	if expiresAt, held := l.locks[key]; held && (expiresAt.IsZero() || expiresAt.After(now)) {
+		return false, nil
+	}
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+
+	l.locks[key] = expiresAt
+
+	return true, nil
+}
+
+// Unlock releases the lock.
+func (l *MemoryLocker) Unlock(key string) error {
+	l.mu.Lock()
+	delete(l.locks, key)
+	l.mu.Unlock()
+
+	return nil
+}
+
+var memoryLocker = NewMemoryLocker()
+
+// LockerFor returns the Locker implemented by the storer or one of the
+// storers it decorates, the process wide MemoryLocker otherwise.
+func LockerFor(storer Storer) Locker {
+	for storer != nil {
+		if locker, ok := storer.(Locker); ok {
+			return locker
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return memoryLocker
+}
+
+// LockToken returns a random token identifying the lock owner, the remote
+// lockers only release the locks holding their own token.
+func LockToken() string {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+
+	return hex.EncodeToString(token)
+}
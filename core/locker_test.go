@@ -0,0 +1,72 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+type lockingStorer struct {
+	*memoryStorer
+	*core.MemoryLocker
+}
+
+func TestMemoryLocker_TryLock(t *testing.T) {
+	locker := core.NewMemoryLocker()
+
+	if acquired, _ := locker.TryLock(byteKey, time.Minute); !acquired {
+		t.Fatal("The lock should be acquired")
+	}
+
+	if acquired, _ := locker.TryLock(byteKey, time.Minute); acquired {
+		t.Error("The lock should already be held")
+	}
+
+	_ = locker.Unlock(byteKey)
+
+	if acquired, _ := locker.TryLock(byteKey, time.Minute); !acquired {
+		t.Error("The lock should be acquired once released")
+	}
+}
+
+func TestMemoryLocker_TryLock_Expired(t *testing.T) {
+	locker := core.NewMemoryLocker()
+
+	_, _ = locker.TryLock(byteKey, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if acquired, _ := locker.TryLock(byteKey, time.Minute); !acquired {
+		t.Error("The expired lock should be acquired")
+	}
+
+	_, _ = locker.TryLock("forever", 0)
+	time.Sleep(20 * time.Millisecond)
+
+	if acquired, _ := locker.TryLock("forever", 0); acquired {
+		t.Error("The lock without ttl should be held until unlocked")
+	}
+}
+
+func TestLockerFor(t *testing.T) {
+	native := &lockingStorer{memoryStorer: newMemoryStorer(), MemoryLocker: core.NewMemoryLocker()}
+	instrumented := core.NewInstrumentedStorer(native, &recordedMetrics{operations: map[string]int{}})
+
+	if core.LockerFor(instrumented) != core.Locker(native) {
+		t.Error("The decorated storer locker should be returned")
+	}
+
+	chained := core.NewChainedStorer(newMemoryStorer(), native, 0)
+	if acquired, _ := chained.TryLock(byteKey, time.Minute); !acquired {
+		t.Fatal("The lock should be acquired through the remote storer")
+	}
+
+	if acquired, _ := native.TryLock(byteKey, time.Minute); acquired {
+		t.Error("The chained storer should use the remote storer locker")
+	}
+
+	fallback := core.LockerFor(newMemoryStorer())
+	if fallback != core.LockerFor(newMemoryStorer()) {
+		t.Error("The storers without locker should share the memory locker")
+	}
+}
@@ -1,5 +1,15 @@
 package core
 
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Logger is the logging interface given to the storages factories. The
+// *zap.SugaredLogger implements it as is, NewSlogLogger adapts a
+// *slog.Logger and so any slog.Handler.
 type Logger interface {
 	Debug(args ...interface{})
 	Info(args ...interface{})
@@ -16,3 +26,88 @@ type Logger interface {
 	Panicf(template string, args ...interface{})
 	Fatalf(template string, args ...interface{})
 }
+
+// slogLogger adapts a *slog.Logger to the Logger interface. The DPanic
+// messages are logged as errors, the Panic ones panic and the Fatal ones exit
+// once logged.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to the given slog.Logger, the
+// slog.Default one when nil.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &slogLogger{logger: logger}
+}
+
+func (s *slogLogger) log(level slog.Level, message string) {
+	s.logger.Log(context.Background(), level, message)
+}
+
+func (s *slogLogger) Debug(args ...interface{}) {
+	s.log(slog.LevelDebug, fmt.Sprint(args...))
+}
+
+func (s *slogLogger) Info(args ...interface{}) {
+	s.log(slog.LevelInfo, fmt.Sprint(args...))
+}
+
+func (s *slogLogger) Warn(args ...interface{}) {
+	s.log(slog.LevelWarn, fmt.Sprint(args...))
+}
+
+func (s *slogLogger) Error(args ...interface{}) {
+	s.log(slog.LevelError, fmt.Sprint(args...))
+}
+
+func (s *slogLogger) DPanic(args ...interface{}) {
+	s.log(slog.LevelError, fmt.Sprint(args...))
+}
+
+func (s *slogLogger) Panic(args ...interface{}) {
+	message := fmt.Sprint(args...)
+	s.log(slog.LevelError, message)
+
+	panic(message)
+}
+
+func (s *slogLogger) Fatal(args ...interface{}) {
+	s.log(slog.LevelError, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+func (s *slogLogger) Debugf(template string, args ...interface{}) {
+	s.log(slog.LevelDebug, fmt.Sprintf(template, args...))
+}
+
+func (s *slogLogger) Infof(template string, args ...interface{}) {
+	s.log(slog.LevelInfo, fmt.Sprintf(template, args...))
+}
+
+func (s *slogLogger) Warnf(template string, args ...interface{}) {
+	s.log(slog.LevelWarn, fmt.Sprintf(template, args...))
+}
+
+func (s *slogLogger) Errorf(template string, args ...interface{}) {
+	s.log(slog.LevelError, fmt.Sprintf(template, args...))
+}
+
+func (s *slogLogger) DPanicf(template string, args ...interface{}) {
+	s.log(slog.LevelError, fmt.Sprintf(template, args...))
+}
+
+func (s *slogLogger) Panicf(template string, args ...interface{}) {
+	message := fmt.Sprintf(template, args...)
+	s.log(slog.LevelError, message)
+
+	panic(message)
+}
+
+func (s *slogLogger) Fatalf(template string, args ...interface{}) {
+	s.log(slog.LevelError, fmt.Sprintf(template, args...))
+	os.Exit(1)
+}
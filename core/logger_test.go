@@ -0,0 +1,51 @@
+package core_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	var buffer bytes.Buffer
+
+	logger := core.NewSlogLogger(slog.New(slog.NewTextHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debugf("Hidden %s", "message")
+	logger.Infof("Stored the key %s", "foo")
+	logger.Warn("Slow ", "backend")
+	logger.Errorf("Impossible to store the key %s, %v", "bar", "timeout")
+
+	output := buffer.String()
+
+	if strings.Contains(output, "Hidden") {
+		t.Errorf("the debug message shouldn't be logged, got %s", output)
+	}
+
+	for _, expected := range []string{
+		`level=INFO msg="Stored the key foo"`,
+		`level=WARN msg="Slow backend"`,
+		`level=ERROR msg="Impossible to store the key bar, timeout"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("the output should contain %s, got %s", expected, output)
+		}
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != "Impossible to continue" {
+			t.Errorf("the Panicf call should panic with its message, got %v", recovered)
+		}
+	}()
+
+	logger.Panicf("Impossible to %s", "continue")
+}
+
+func TestNewSlogLogger_Default(t *testing.T) {
+	if core.NewSlogLogger(nil) == nil {
+		t.Error("the logger shouldn't be nil")
+	}
+}
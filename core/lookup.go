@@ -0,0 +1,33 @@
+package core
+
+import "errors"
+
+// ErrKeyNotFound is returned by Lookup when the key doesn't exist or is
+// expired.
+var ErrKeyNotFound = errors.New("key not found")
+
+// StorerV2 is the Storer able to tell a miss from a backend failure. Lookup
+// returns ErrKeyNotFound on a miss, the backend error when the storage can't
+// be read and the value otherwise, even when it's empty. Get keeps returning
+// nil or an empty value in both the first cases.
+type StorerV2 interface {
+	Storer
+	Lookup(key string) ([]byte, error)
+}
+
+// Lookup reads the key with the storer Lookup method when it implements
+// StorerV2. Otherwise an empty value returned by Get is reported as
+// ErrKeyNotFound since a miss can't be told from a failure nor from an empty
+// value.
+func Lookup(storer Storer, key string) ([]byte, error) {
+	if v2, ok := storer.(StorerV2); ok {
+		return v2.Lookup(key)
+	}
+
+	value := storer.Get(key)
+	if len(value) == 0 {
+		return nil, ErrKeyNotFound
+	}
+
+	return value, nil
+}
@@ -0,0 +1,87 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// lookupStorer implements StorerV2 on top of the memoryStorer.
+type lookupStorer struct {
+	*memoryStorer
+	err error
+}
+
+func (l *lookupStorer) Lookup(key string) ([]byte, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	value, found := l.values[key]
+	if !found {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return value, nil
+}
+
+func TestLookup(t *testing.T) {
+	memory := newMemoryStorer()
+
+	if _, err := core.Lookup(memory, byteKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return ErrKeyNotFound, %v given", err)
+	}
+
+	storer := &lookupStorer{memoryStorer: newMemoryStorer()}
+	_ = storer.Set(byteKey, []byte{}, time.Minute)
+
+	if value, err := core.Lookup(storer, byteKey); err != nil || value == nil {
+		t.Errorf("An empty value should be told from a miss, %v given", err)
+	}
+
+	storer.err = errUnavailable
+
+	instrumented := core.NewInstrumentedStorer(storer, &recordedMetrics{operations: map[string]int{}})
+	if _, err := core.Lookup(instrumented, byteKey); !errors.Is(err, errUnavailable) {
+		t.Errorf("The backend failure should go through the decorators, %v given", err)
+	}
+}
+
+func TestChainedStorer_Lookup(t *testing.T) {
+	local := &lookupStorer{memoryStorer: newMemoryStorer(), err: errUnavailable}
+	remote := &lookupStorer{memoryStorer: newMemoryStorer()}
+	_ = remote.Set(byteKey, []byte(baseValue), time.Minute)
+
+	chained := core.NewChainedStorer(local, remote, 0)
+
+	if value, err := core.Lookup(chained, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The remote value should be returned on a local failure, %v given", err)
+	}
+
+	if _, err := core.Lookup(chained, "missing"); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("The remote miss should be returned, %v given", err)
+	}
+}
+
+func TestCircuitBreakerStorer_Lookup(t *testing.T) {
+	storer := &lookupStorer{memoryStorer: newMemoryStorer(), err: errUnavailable}
+
+	breaker, _ := core.NewCircuitBreakerStorer(storer, core.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		ProbeTimeout:     time.Second,
+	}, nopLogger{})
+
+	if _, err := breaker.Lookup(byteKey); !errors.Is(err, errUnavailable) || breaker.State() != core.CircuitOpen {
+		t.Fatalf("A failed lookup should open the circuit, %v given", err)
+	}
+
+	if _, err := breaker.Lookup(byteKey); !errors.Is(err, core.ErrCircuitOpen) {
+		t.Errorf("The lookups should be rejected while open, %v given", err)
+	}
+}
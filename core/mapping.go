@@ -0,0 +1,130 @@
+package core
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// maxMappingUpdateAttempts bounds the retries of a conflicting mapping
+// update.
+const maxMappingUpdateAttempts = 10
+
+// ErrMappingConflict is returned by an atomic mapping update when the
+// mapping was written by another instance between its read and its write.
+var ErrMappingConflict = errors.New("the mapping was updated concurrently")
+
+// MappingVersion returns the version of the encoded mapping, incremented by
+// MappingUpdater on each update. It returns 0 for an empty or invalid
+// mapping.
+func MappingVersion(item []byte) uint64 {
+	if len(item) == 0 {
+		return 0
+	}
+
+	mapping, err := DecodeMapping(item)
+	if err != nil {
+		return 0
+	}
+
+	return mapping.GetVersion()
+}
+
+// UpdateMapping runs the read-modify-write update of a mapping until it
+// doesn't return ErrMappingConflict, waiting a short random delay between
+// the attempts. The update must read the mapping again on each call. It
+// returns ErrMappingConflict once the attempts are exhausted.
+func UpdateMapping(update func() error) error {
+	return retryOnConflict(ErrMappingConflict, update)
+}
+
+// MappingKeyResolver is an optional interface a Storer can implement when its
+// mappings aren't stored under the MappingKeyPrefix + base key name, e.g.
+// behind a Redis hash tag. MappingKey returns the key the mapping of the base
+// key is stored under.
+type MappingKeyResolver interface {
+	MappingKey(baseKey string) string
+}
+
+// MappingKeyFor returns the key the mapping of the base key is stored under,
+// using the first storer implementing MappingKeyResolver under the
+// decorators. It's MappingKeyPrefix + the base key otherwise.
+func MappingKeyFor(storer Storer, baseKey string) string {
+	for storer != nil {
+		if resolver, ok := storer.(MappingKeyResolver); ok {
+			return resolver.MappingKey(baseKey)
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return MappingKeyPrefix + baseKey
+}
+
+// swapMapping stores the updated mapping in place of current, the encoded
+// mapping read before the update, with the ConditionalSetter of the storer.
+// It returns ErrMappingConflict when the mapping was written meanwhile. The
+// storers without ConditionalSetter compare the version of the stored
+// mapping before the Set instead, which narrows the race without closing it.
+func swapMapping(storer Storer, mappingKey string, current, value []byte, ttl time.Duration) error {
+	var (
+		swapped bool
+		err     error
+	)
+
+	if len(current) == 0 {
+		swapped, err = SetNX(storer, mappingKey, value, ttl)
+	} else {
+		swapped, err = CompareAndSwap(storer, mappingKey, current, value, ttl)
+	}
+
+	if errors.Is(err, ErrConditionalSetNotSupported) {
+		if stored, lookupErr := Lookup(storer, mappingKey); (lookupErr != nil && !errors.Is(lookupErr, ErrKeyNotFound)) || MappingVersion(stored) != MappingVersion(current) {
+			return ErrMappingConflict
+		}
+
+		return storer.Set(mappingKey, value, ttl)
+	}
+
+	if err == nil && !swapped {
+		err = ErrMappingConflict
+	}
+
+	return err
+}
+
+// mappingStaleUntil returns the latest stale time of the mapping entries, now
+// when there is none.
+func mappingStaleUntil(mapping *StorageMapper, now time.Time) time.Time {
+	staleUntil := now
+
+	for _, index := range mapping.GetMapping() {
+		if staleTime := index.GetStaleTime().AsTime(); staleTime.After(staleUntil) {
+			staleUntil = staleTime
+		}
+	}
+
+	return staleUntil
+}
+
+// retryOnConflict runs the function until it doesn't return the conflict
+// error or the attempts are exhausted.
+func retryOnConflict(conflict error, run func() error) error {
+	var err error
+
+	for attempt := range maxMappingUpdateAttempts {
+		if err = run(); !errors.Is(err, conflict) {
+			return err
+		}
+
+		//nolint:gosec // The jitter doesn't need a cryptographic source.
+		time.Sleep(time.Duration(attempt+1)*time.Millisecond + time.Duration(rand.Int64N(int64(time.Millisecond))))
+	}
+
+	return err
+}
@@ -0,0 +1,56 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestMappingVersion(t *testing.T) {
+	now := time.Now()
+
+	if version := core.MappingVersion(nil); version != 0 {
+		t.Errorf("An empty mapping should have the version 0, %d given", version)
+	}
+
+	mapping, _ := core.MappingUpdater("first", nil, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", "first")
+	mapping, _ = core.MappingUpdater("second", mapping, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", "second")
+
+	if version := core.MappingVersion(mapping); version != 2 {
+		t.Errorf("Each update should increment the version, %d given", version)
+	}
+}
+
+func TestUpdateMapping(t *testing.T) {
+	attempts := 0
+
+	err := core.UpdateMapping(func() error {
+		attempts++
+		if attempts < 3 {
+			return core.ErrMappingConflict
+		}
+
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("The conflicting updates should be retried, %d attempts and %v given", attempts, err)
+	}
+
+	attempts = 0
+
+	if err = core.UpdateMapping(func() error {
+		attempts++
+
+		return errUnavailable
+	}); !errors.Is(err, errUnavailable) || attempts != 1 {
+		t.Errorf("The other errors shouldn't be retried, %d attempts and %v given", attempts, err)
+	}
+
+	if err = core.UpdateMapping(func() error {
+		return core.ErrMappingConflict
+	}); !errors.Is(err, core.ErrMappingConflict) {
+		t.Errorf("The conflict should be returned once the attempts are exhausted, %v given", err)
+	}
+}
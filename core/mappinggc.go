@@ -0,0 +1,351 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MappingGCIntervalConfigurationKey is the key read from the provider
+// configuration to prune the expired mapping entries periodically.
+const MappingGCIntervalConfigurationKey = "mapping_gc_interval"
+
+// pruneMapping removes the entries whose stale time passed from the mapping,
+// it returns the number of removed entries and the latest stale time of the
+// remaining ones.
+func pruneMapping(mapping *StorageMapper, now time.Time) (int, time.Time) {
+	removed := 0
+	staleUntil := now
+
+	for key, index := range mapping.GetMapping() {
+		staleTime := index.GetStaleTime().AsTime()
+		if !staleTime.After(now) {
+			delete(mapping.Mapping, key)
+
+			removed++
+
+			continue
+		}
+
+		if staleTime.After(staleUntil) {
+			staleUntil = staleTime
+		}
+	}
+
+	return removed, staleUntil
+}
+
+// PruneMapping removes the entries whose stale time passed from the encoded
+// mapping, it returns the pruned mapping and the number of removed entries.
+// The mapping is returned untouched when nothing is removed, nil once every
+// entry is removed.
+func PruneMapping(item []byte, now time.Time) ([]byte, int, error) {
+	if len(item) == 0 {
+		return item, 0, nil
+	}
+
+	mapping, err := DecodeMapping(item)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	removed, _ := pruneMapping(mapping, now)
+	if removed == 0 {
+		return item, 0, nil
+	}
+
+	if len(mapping.GetMapping()) == 0 {
+		return nil, removed, nil
+	}
+
+	mapping.Version++
+
+	pruned, err := EncodeMapping(mapping)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return pruned, removed, nil
+}
+
+// CollectMapping prunes the expired entries of the mapping stored under
+// mappingKey, it returns the number of removed entries. The pruned mapping
+// expires with its latest entry and is deleted once empty. The mapping is
+// read again and the pruning retried when it's updated meanwhile.
+func CollectMapping(storer Storer, mappingKey string) (int, error) {
+	var removed int
+
+	err := UpdateMapping(func() error {
+		removed = 0
+
+		item, err := Lookup(storer, mappingKey)
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		mapping, err := DecodeMapping(item)
+		if err != nil {
+			return fmt.Errorf("impossible to decode the mapping %s: %w", mappingKey, err)
+		}
+
+		pruned, staleUntil := pruneMapping(mapping, time.Now())
+		if pruned == 0 {
+			return nil
+		}
+
+		if len(mapping.GetMapping()) == 0 {
+			// A mapping can't be deleted conditionally, the version check
+			// narrows the race with a concurrent update.
+			if current, lookupErr := Lookup(storer, mappingKey); lookupErr != nil || MappingVersion(current) != mapping.GetVersion() {
+				return ErrMappingConflict
+			}
+
+			storer.Delete(mappingKey)
+
+			removed = pruned
+
+			return nil
+		}
+
+		mapping.Version++
+
+		value, err := EncodeMapping(mapping)
+		if err != nil {
+			return err
+		}
+
+		if err = swapMapping(storer, mappingKey, item, value, time.Until(staleUntil)); err != nil {
+			if errors.Is(err, ErrMappingConflict) {
+				return err
+			}
+
+			return fmt.Errorf("impossible to store the pruned mapping %s: %w", mappingKey, err)
+		}
+
+		removed = pruned
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// CollectMappings prunes the expired entries of every mapping, it returns the
+// number of removed entries.
+func CollectMappings(storer Storer) (int, error) {
+	return collectMappings(storer, nil, nil)
+}
+
+// collectMappings is CollectMappings pruning each mapping once the limiter
+// allows it, it stops when stop is closed.
+func collectMappings(storer Storer, limiter *BackgroundLimiter, stop <-chan struct{}) (int, error) {
+	keys := []string{}
+
+	walkErr := walkMappings(storer, func(key string, _ []byte) bool {
+		keys = append(keys, MappingKeyFor(storer, key))
+
+		return true
+	})
+
+	total := 0
+	errs := []error{walkErr}
+
+	for _, key := range keys {
+		if !limiter.Wait(stop, "mapping_gc") {
+			break
+		}
+
+		removed, err := CollectMapping(storer, key)
+		total += removed
+		errs = append(errs, err)
+	}
+
+	return total, errors.Join(errs...)
+}
+
+// collectingMappings holds the mappings pruned in background by
+// MappingElectionFor, a mapping is never pruned twice at once.
+var collectingMappings sync.Map
+
+// MappingElectionFor is MappingElection for the mapping stored under
+// mappingKey, the expired entries it holds are pruned in background.
+func MappingElectionFor(provider Storer, mappingKey string, item []byte, req *http.Request, validator *Revalidator, logger Logger) (fresh *http.Response, stale *http.Response, err error) {
+	fresh, stale, err = MappingElection(provider, item, req, validator, logger)
+	if err == nil {
+		recordElection(provider, mappingKey, item, fresh, stale, validator)
+	}
+
+	if err != nil || !hasExpiredEntries(item, time.Now()) {
+		return fresh, stale, err
+	}
+
+	id := provider.Name() + "-" + provider.Uuid() + "-" + mappingKey
+	if _, running := collectingMappings.LoadOrStore(id, struct{}{}); running {
+		return fresh, stale, err
+	}
+
+	go func() {
+		defer collectingMappings.Delete(id)
+
+		if removed, collectErr := CollectMapping(provider, mappingKey); collectErr != nil {
+			logger.Errorf("Impossible to prune the mapping %s, %v", mappingKey, collectErr)
+		} else if removed > 0 {
+			logger.Debugf("Pruned %d expired entries from the mapping %s", removed, mappingKey)
+		}
+	}()
+
+	return fresh, stale, err
+}
+
+func hasExpiredEntries(item []byte, now time.Time) bool {
+	if len(item) == 0 {
+		return false
+	}
+
+	mapping, err := DecodeMapping(item)
+	if err != nil {
+		return false
+	}
+
+	for _, index := range mapping.GetMapping() {
+		if !index.GetStaleTime().AsTime().After(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MappingGCStorer decorates any Storer to prune the expired mapping entries
+// periodically, the MappingUpdater only adds or replaces them.
+type MappingGCStorer struct {
+	Storer
+
+	interval time.Duration
+	logger   Logger
+	limiter  *BackgroundLimiter
+	mu       sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewMappingGCStorer wraps the storer, the pruning starts on Init.
+func NewMappingGCStorer(storer Storer, interval time.Duration, logger Logger) (*MappingGCStorer, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid mapping_gc_interval configuration: the interval must be positive, %s given", interval)
+	}
+
+	return &MappingGCStorer{Storer: storer, interval: interval, logger: logger}, nil
+}
+
+// mappingGCConfiguration is the typed interval read from the provider
+// configuration.
+type mappingGCConfiguration struct {
+	MappingGCInterval time.Duration  `json:"mapping_gc_interval"`
+	Others            map[string]any `json:",remain"`
+}
+
+// MappingGCStorerFromConfiguration wraps the storer when the
+// mapping_gc_interval key is set in the provider configuration, it returns
+// the storer untouched otherwise.
+func MappingGCStorerFromConfiguration(storer Storer, provider CacheProvider, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	if _, ok = cfg[MappingGCIntervalConfigurationKey]; !ok {
+		return storer, nil
+	}
+
+	var gc mappingGCConfiguration
+	if err := DecodeConfiguration(cfg, &gc); err != nil {
+		return nil, fmt.Errorf("invalid mapping_gc_interval configuration: %w", err)
+	}
+
+	limiter, err := BackgroundLimiterFromConfiguration(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	gcStorer, err := NewMappingGCStorer(storer, gc.MappingGCInterval, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	gcStorer.limiter = limiter
+
+	return gcStorer, nil
+}
+
+// Unwrap returns the decorated storer.
+func (s *MappingGCStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+func (s *MappingGCStorer) collect(stop <-chan struct{}) {
+	removed, err := collectMappings(s.Storer, s.limiter, stop)
+	if err != nil {
+		s.logger.Errorf("Impossible to prune the mappings, %v", err)
+	}
+
+	if removed > 0 {
+		s.logger.Debugf("Pruned %d expired mapping entries", removed)
+	}
+}
+
+// Init method initializes the decorated storer then starts the pruning.
+func (s *MappingGCStorer) Init() error {
+	if err := s.Storer.Init(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		return nil
+	}
+
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.collect(stop)
+			}
+		}
+	}(s.stop, s.done)
+
+	return nil
+}
+
+// Reset method stops the pruning then resets the decorated storer.
+func (s *MappingGCStorer) Reset() error {
+	s.mu.Lock()
+
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+
+		s.stop, s.done = nil, nil
+	}
+
+	s.mu.Unlock()
+
+	return s.Storer.Reset()
+}
@@ -0,0 +1,192 @@
+package core_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func expiredMapping(t *testing.T, now time.Time) []byte {
+	t.Helper()
+
+	mapping, err := core.MappingUpdater("expired", nil, nopLogger{}, now.Add(-2*time.Hour), now.Add(-2*time.Hour), now.Add(-time.Hour), nil, "", "expired")
+	if err != nil {
+		t.Fatalf("impossible to build the mapping, %v", err)
+	}
+
+	// The updater drops the expired entries given the update time, this one
+	// stays since it's added as if it were an hour ago.
+	mapping, err = core.MappingUpdater("fresh", mapping, nopLogger{}, now.Add(-2*time.Hour), now.Add(time.Hour), now.Add(2*time.Hour), nil, "", "fresh")
+	if err != nil {
+		t.Fatalf("impossible to build the mapping, %v", err)
+	}
+
+	return mapping
+}
+
+func TestPruneMapping(t *testing.T) {
+	now := time.Now()
+	mapping := expiredMapping(t, now)
+
+	pruned, removed, err := core.PruneMapping(mapping, now)
+	if err != nil || removed != 1 {
+		t.Fatalf("one entry should be removed, got %d, %v", removed, err)
+	}
+
+	decoded, _ := core.DecodeMapping(pruned)
+	if _, found := decoded.GetMapping()["fresh"]; !found || len(decoded.GetMapping()) != 1 {
+		t.Errorf("only the fresh entry should remain, got %v", decoded.GetMapping())
+	}
+
+	if core.MappingVersion(pruned) <= core.MappingVersion(mapping) {
+		t.Error("the pruned mapping version should be incremented")
+	}
+
+	if untouched, removed, _ := core.PruneMapping(pruned, now); removed != 0 || string(untouched) != string(pruned) {
+		t.Error("the mapping without expired entry should be returned untouched")
+	}
+
+	if empty, removed, _ := core.PruneMapping(mapping, now.Add(3*time.Hour)); removed != 2 || empty != nil {
+		t.Errorf("every entry should be removed, got %d", removed)
+	}
+}
+
+func TestMappingUpdater_Prune(t *testing.T) {
+	now := time.Now()
+
+	mapping, _ := core.MappingUpdater("old", nil, nopLogger{}, now.Add(-time.Hour), now.Add(-time.Hour), now.Add(-time.Minute), nil, "", "old")
+	mapping, _ = core.MappingUpdater("new", mapping, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", "new")
+
+	decoded, _ := core.DecodeMapping(mapping)
+	if _, found := decoded.GetMapping()["old"]; found {
+		t.Error("the expired entry should be dropped on update")
+	}
+}
+
+func TestCollectMappings(t *testing.T) {
+	memory := newMemoryStorer()
+	now := time.Now()
+
+	_ = memory.Set(core.MappingKeyPrefix+"partial", expiredMapping(t, now), time.Hour)
+
+	gone, _ := core.MappingUpdater("gone", nil, nopLogger{}, now.Add(-time.Hour), now.Add(-time.Hour), now.Add(-time.Minute), nil, "", "gone")
+	_ = memory.Set(core.MappingKeyPrefix+"gone", gone, time.Hour)
+
+	removed, err := core.CollectMappings(memory)
+	if err != nil || removed != 2 {
+		t.Fatalf("two entries should be removed, got %d, %v", removed, err)
+	}
+
+	if len(memory.Get(core.MappingKeyPrefix+"gone")) != 0 {
+		t.Error("the empty mapping should be deleted")
+	}
+
+	decoded, _ := core.DecodeMapping(memory.Get(core.MappingKeyPrefix + "partial"))
+	if len(decoded.GetMapping()) != 1 {
+		t.Errorf("only the fresh entry should remain, got %v", decoded.GetMapping())
+	}
+
+	if removed, _ = core.CollectMapping(memory, core.MappingKeyPrefix+"missing"); removed != 0 {
+		t.Error("a missing mapping has nothing to prune")
+	}
+}
+
+// racingStorer runs the race once before its first CompareAndSwap, like a
+// concurrent writer updating the key between its read and its swap.
+type racingStorer struct {
+	conditionalStorer
+	race func()
+}
+
+func (r *racingStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	if race := r.race; race != nil {
+		r.race = nil
+		race()
+	}
+
+	return r.conditionalStorer.CompareAndSwap(key, old, value, ttl)
+}
+
+func TestCollectMapping_Conflict(t *testing.T) {
+	now := time.Now()
+	mappingKey := core.MappingKeyPrefix + "partial"
+	storer := &racingStorer{conditionalStorer: conditionalStorer{newMemoryStorer()}}
+
+	_ = storer.Set(mappingKey, expiredMapping(t, now), time.Hour)
+
+	storer.race = func() {
+		mapping, _ := core.MappingUpdater("concurrent", storer.Get(mappingKey), nopLogger{}, now.Add(-2*time.Hour), now.Add(time.Minute), now.Add(time.Hour), nil, "", "concurrent")
+		_ = storer.Set(mappingKey, mapping, time.Hour)
+	}
+
+	if removed, err := core.CollectMapping(storer, mappingKey); err != nil || removed != 1 {
+		t.Fatalf("the expired entry should be removed, got %d, %v", removed, err)
+	}
+
+	decoded, _ := core.DecodeMapping(storer.Get(mappingKey))
+	if _, found := decoded.GetMapping()["concurrent"]; !found || len(decoded.GetMapping()) != 2 {
+		t.Errorf("the entry added during the pruning should be kept, got %v", decoded.GetMapping())
+	}
+}
+
+func TestMappingElectionFor(t *testing.T) {
+	memory := newMemoryStorer()
+	mappingKey := core.MappingKeyPrefix + byteKey
+	mapping := expiredMapping(t, time.Now())
+
+	_ = memory.Set(mappingKey, mapping, time.Hour)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	_, _, _ = core.MappingElectionFor(memory, mappingKey, mapping, req, &core.Revalidator{}, nopLogger{})
+
+	for range 100 {
+		if decoded, _ := core.DecodeMapping(memory.Get(mappingKey)); len(decoded.GetMapping()) == 1 {
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Error("the expired entry should be pruned in background")
+}
+
+func TestMappingGCStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.MappingGCStorerFromConfiguration(memory, core.CacheProvider{}, nopLogger{})
+	if err != nil || storer != core.Storer(memory) {
+		t.Error("the storer should be returned untouched without mapping_gc_interval")
+	}
+
+	if _, err = core.MappingGCStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"mapping_gc_interval": "-1s",
+	}}, nopLogger{}); err == nil {
+		t.Error("a negative interval should be rejected")
+	}
+
+	storer, err = core.MappingGCStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"mapping_gc_interval": "10ms",
+	}}, nopLogger{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_ = memory.Set(core.MappingKeyPrefix+byteKey, expiredMapping(t, time.Now()), time.Hour)
+	_ = storer.Init()
+
+	defer func() {
+		_ = storer.Reset()
+	}()
+
+	for range 100 {
+		if decoded, _ := core.DecodeMapping(memory.Get(core.MappingKeyPrefix + byteKey)); len(decoded.GetMapping()) == 1 {
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Error("the expired entry should be pruned periodically")
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// KeyMetadata describes a varied key referenced by the mapping of a base
+// key: when it was stored, until when it's served fresh then stale, and the
+// request headers it varies on.
+type KeyMetadata struct {
+	Key           string
+	RealKey       string
+	StoredAt      time.Time
+	FreshUntil    time.Time
+	StaleUntil    time.Time
+	Etag          string
+	VariedHeaders http.Header
+	// Hits is the number of times the key was elected, LastAccess the time
+	// of the last one. They're counted when the access tracking is enabled.
+	Hits       uint64
+	LastAccess time.Time
+}
+
+// Fresh returns true when the key is served fresh at now.
+func (m KeyMetadata) Fresh(now time.Time) bool {
+	return now.Before(m.FreshUntil)
+}
+
+// Stale returns true when the key is no longer fresh at now but can still
+// be served stale.
+func (m KeyMetadata) Stale(now time.Time) bool {
+	return !m.Fresh(now) && now.Before(m.StaleUntil)
+}
+
+// MetadataReader is an optional interface a Storer can implement when its
+// mappings aren't stored under the MappingKeyPrefix + key name returned by
+// Get, e.g. behind a Redis hash tag. GetMetadata returns the metadata of the
+// varied keys of the base key, none when the mapping doesn't exist.
+type MetadataReader interface {
+	GetMetadata(key string) ([]KeyMetadata, error)
+}
+
+// DecodeMetadata decodes the mapping into the metadata of its varied keys,
+// sorted by key.
+func DecodeMetadata(item []byte) ([]KeyMetadata, error) {
+	if len(item) == 0 {
+		return []KeyMetadata{}, nil
+	}
+
+	mapping, err := DecodeMapping(item)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make([]KeyMetadata, 0, len(mapping.GetMapping()))
+
+	for key, index := range mapping.GetMapping() {
+		var variedHeaders http.Header
+		if len(index.GetVariedHeaders()) > 0 {
+			variedHeaders = make(http.Header, len(index.GetVariedHeaders()))
+			for name, values := range index.GetVariedHeaders() {
+				variedHeaders[name] = values.GetHeaderValue()
+			}
+		}
+
+		var lastAccess time.Time
+		if index.GetLastAccess() != nil {
+			lastAccess = index.GetLastAccess().AsTime()
+		}
+
+		metadata = append(metadata, KeyMetadata{
+			Key:           key,
+			RealKey:       index.GetRealKey(),
+			StoredAt:      index.GetStoredAt().AsTime(),
+			FreshUntil:    index.GetFreshTime().AsTime(),
+			StaleUntil:    index.GetStaleTime().AsTime(),
+			Etag:          index.GetEtag(),
+			VariedHeaders: variedHeaders,
+			Hits:          index.GetHits(),
+			LastAccess:    lastAccess,
+		})
+	}
+
+	sort.Slice(metadata, func(i, j int) bool {
+		return metadata[i].Key < metadata[j].Key
+	})
+
+	return metadata, nil
+}
+
+// GetMetadata returns the metadata of the varied keys stored for the base
+// key, using the first storer implementing MetadataReader under the
+// decorators. It decodes the mapping returned by Get otherwise.
+func GetMetadata(storer Storer, key string) ([]KeyMetadata, error) {
+	for current := storer; current != nil; {
+		if reader, ok := current.(MetadataReader); ok {
+			return reader.GetMetadata(key)
+		}
+
+		unwrapper, ok := current.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		current = unwrapper.Unwrap()
+	}
+
+	return DecodeMetadata(storer.Get(MappingKeyPrefix + key))
+}
@@ -0,0 +1,68 @@
+package core_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestGetMetadata(t *testing.T) {
+	memory := newMemoryStorer()
+
+	metadata, err := core.GetMetadata(memory, "base")
+	if err != nil || len(metadata) != 0 {
+		t.Errorf("A missing mapping shouldn't have metadata, %v and %v given", metadata, err)
+	}
+
+	_ = memory.SetMultiLevel("base", "varied-gzip", []byte(baseValue), http.Header{"Accept-Encoding": []string{"gzip"}}, "\"etag\"", time.Minute, "real-gzip")
+	_ = memory.SetMultiLevel("base", "varied", []byte(baseValue), nil, "", time.Minute, "real")
+
+	metadata, err = core.GetMetadata(core.NewInstrumentedStorer(memory, &recordedMetrics{operations: map[string]int{}}), "base")
+	if err != nil || len(metadata) != 2 {
+		t.Fatalf("Both varied keys should have metadata, %v and %v given", metadata, err)
+	}
+
+	gzip := metadata[1]
+	if gzip.Key != "varied-gzip" || gzip.RealKey != "real-gzip" || gzip.Etag != "\"etag\"" || gzip.VariedHeaders.Get("Accept-Encoding") != "gzip" {
+		t.Errorf("The metadata should describe the varied key, %+v given", gzip)
+	}
+
+	if !gzip.Fresh(time.Now()) || gzip.Stale(time.Now()) {
+		t.Error("The key should be fresh")
+	}
+
+	if gzip.Fresh(gzip.FreshUntil) {
+		t.Error("The key shouldn't be fresh anymore once its fresh time is reached")
+	}
+
+	_ = memory.Set(core.MappingKeyPrefix+"invalid", []byte("invalid"), time.Minute)
+
+	if _, err = core.GetMetadata(memory, "invalid"); err == nil {
+		t.Error("An invalid mapping should return an error")
+	}
+}
+
+func TestEncryptedStorer_GetMetadata(t *testing.T) {
+	storer, _ := core.EncryptedStorerFromConfiguration(newMemoryStorer(), core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"encryption": map[string]interface{}{
+				"key": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+			},
+		},
+	}, time.Hour, nopLogger{})
+
+	_ = storer.SetMultiLevel("base", "varied", []byte(baseValue), http.Header{}, "", time.Minute, "real")
+
+	metadata, err := core.GetMetadata(core.NewInstrumentedStorer(storer, &recordedMetrics{operations: map[string]int{}}), "base")
+	if err != nil || len(metadata) != 1 {
+		t.Fatalf("The decrypted mapping should be decoded, %v and %v given", metadata, err)
+	}
+
+	if now := time.Now().Add(time.Minute); !metadata[0].Stale(now) {
+		t.Errorf("The key should be stale once fresh, %+v given", metadata[0])
+	}
+}
@@ -0,0 +1,178 @@
+// Package metrics exposes the storers activity as Prometheus collectors.
+package metrics
+
+import (
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "storages"
+
+// Collector is a core.Metrics implementation backed by Prometheus
+// counters and histograms.
+type Collector struct {
+	hits              *prometheus.CounterVec
+	misses            *prometheus.CounterVec
+	setErrors         *prometheus.CounterVec
+	latency           *prometheus.HistogramVec
+	compressionRatio  *prometheus.HistogramVec
+	replicationLag    *prometheus.HistogramVec
+	replicationErrors *prometheus.CounterVec
+	throttled         *prometheus.CounterVec
+	throttleWait      *prometheus.HistogramVec
+}
+
+var (
+	_ core.Metrics            = (*Collector)(nil)
+	_ core.ReplicationMetrics = (*Collector)(nil)
+)
+
+// NewCollector creates a new Collector.
+func NewCollector() *Collector {
+	return &Collector{
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "hits_total",
+			Help:      "Number of lookups that returned a value, per provider.",
+		}, []string{"provider"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "misses_total",
+			Help:      "Number of lookups that returned nothing, per provider.",
+		}, []string{"provider"}),
+		setErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "set_errors_total",
+			Help:      "Number of failed writes, per provider.",
+		}, []string{"provider"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of the storer operations, per provider.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"provider", "operation"}),
+		compressionRatio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "compression_ratio",
+			Help:      "Ratio between the compressed and the raw value sizes, per codec.",
+			Buckets:   prometheus.LinearBuckets(0.1, 0.1, 10),
+		}, []string{"codec"}),
+		replicationLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "replication_lag_seconds",
+			Help:      "Delay between the primary and the secondary writes, per secondary.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"secondary"}),
+		replicationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "replication_errors_total",
+			Help:      "Number of failed or dropped replications, per secondary.",
+		}, []string{"secondary"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "background_throttled_total",
+			Help:      "Number of background operations delayed by the rate limit, per operation.",
+		}, []string{"operation"}),
+		throttleWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "background_throttle_wait_seconds",
+			Help:      "Delay of the background operations throttled by the rate limit, per operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"operation"}),
+	}
+}
+
+// ObserveHit increments the hits counter.
+func (c *Collector) ObserveHit(provider string) {
+	c.hits.WithLabelValues(provider).Inc()
+}
+
+// ObserveMiss increments the misses counter.
+func (c *Collector) ObserveMiss(provider string) {
+	c.misses.WithLabelValues(provider).Inc()
+}
+
+// ObserveSetError increments the set errors counter.
+func (c *Collector) ObserveSetError(provider string) {
+	c.setErrors.WithLabelValues(provider).Inc()
+}
+
+// ObserveLatency records the operation duration.
+func (c *Collector) ObserveLatency(provider, operation string, duration time.Duration) {
+	c.latency.WithLabelValues(provider, operation).Observe(duration.Seconds())
+}
+
+// ObserveCompression records the compression ratio, it matches the
+// core.ObserveCompression signature.
+func (c *Collector) ObserveCompression(codec string, raw, compressed int) {
+	if raw == 0 {
+		return
+	}
+
+	c.compressionRatio.WithLabelValues(codec).Observe(float64(compressed) / float64(raw))
+}
+
+// ObserveReplication records the replication lag, or counts the failure.
+func (c *Collector) ObserveReplication(secondary string, lag time.Duration, err error) {
+	if err != nil {
+		c.replicationErrors.WithLabelValues(secondary).Inc()
+
+		return
+	}
+
+	c.replicationLag.WithLabelValues(secondary).Observe(lag.Seconds())
+}
+
+// ObserveThrottling counts the throttled background operation and records
+// its delay, it matches the core.ObserveThrottling signature.
+func (c *Collector) ObserveThrottling(operation string, wait time.Duration) {
+	c.throttled.WithLabelValues(operation).Inc()
+	c.throttleWait.WithLabelValues(operation).Observe(wait.Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.hits.Describe(ch)
+	c.misses.Describe(ch)
+	c.setErrors.Describe(ch)
+	c.latency.Describe(ch)
+	c.compressionRatio.Describe(ch)
+	c.replicationLag.Describe(ch)
+	c.replicationErrors.Describe(ch)
+	c.throttled.Describe(ch)
+	c.throttleWait.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.hits.Collect(ch)
+	c.misses.Collect(ch)
+	c.setErrors.Collect(ch)
+	c.latency.Collect(ch)
+	c.compressionRatio.Collect(ch)
+	c.replicationLag.Collect(ch)
+	c.replicationErrors.Collect(ch)
+	c.throttled.Collect(ch)
+	c.throttleWait.Collect(ch)
+}
+
+// Register creates a Collector, registers it into the given registerer and
+// hooks it to the core compressors and background limiters.
+func Register(registerer prometheus.Registerer) (*Collector, error) {
+	collector := NewCollector()
+	if err := registerer.Register(collector); err != nil {
+		return nil, err
+	}
+
+	core.ObserveCompression(collector.ObserveCompression)
+	core.ObserveThrottling(collector.ObserveThrottling)
+
+	return collector, nil
+}
+
+// Instrument wraps the storer to report its activity to the collector.
+func (c *Collector) Instrument(storer core.Storer) core.Storer {
+	return core.NewInstrumentedStorer(storer, c)
+}
@@ -0,0 +1,72 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	collector, err := metrics.Register(registry)
+	if err != nil {
+		t.Fatalf("The collector should be registered: %v", err)
+	}
+
+	defer core.ObserveCompression(nil)
+	defer core.ObserveThrottling(nil)
+
+	collector.ObserveHit("OTTER")
+	collector.ObserveHit("OTTER")
+	collector.ObserveMiss("OTTER")
+	collector.ObserveSetError("REDIS")
+	collector.ObserveLatency("OTTER", "get", time.Millisecond)
+	collector.ObserveReplication("REDIS-eu", time.Millisecond, nil)
+	collector.ObserveReplication("REDIS-us", 0, core.ErrReplicationQueueFull)
+
+	compressor, _ := core.NewCompressor(core.GzipCompression)
+	_, _ = compressor.Compress([]byte(strings.Repeat("a", 1024)))
+
+	limiter, _ := core.NewBackgroundLimiter(1000, 1)
+	limiter.Wait(nil, "sweep")
+	limiter.Wait(nil, "sweep")
+
+	expected := `
+# HELP storages_hits_total Number of lookups that returned a value, per provider.
+# TYPE storages_hits_total counter
+storages_hits_total{provider="OTTER"} 2
+# HELP storages_misses_total Number of lookups that returned nothing, per provider.
+# TYPE storages_misses_total counter
+storages_misses_total{provider="OTTER"} 1
+# HELP storages_set_errors_total Number of failed writes, per provider.
+# TYPE storages_set_errors_total counter
+storages_set_errors_total{provider="REDIS"} 1
+# HELP storages_replication_errors_total Number of failed or dropped replications, per secondary.
+# TYPE storages_replication_errors_total counter
+storages_replication_errors_total{secondary="REDIS-us"} 1
+# HELP storages_background_throttled_total Number of background operations delayed by the rate limit, per operation.
+# TYPE storages_background_throttled_total counter
+storages_background_throttled_total{operation="sweep"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "storages_hits_total", "storages_misses_total", "storages_set_errors_total", "storages_replication_errors_total", "storages_background_throttled_total"); err != nil {
+		t.Error(err)
+	}
+
+	if count := testutil.CollectAndCount(collector, "storages_compression_ratio"); count != 1 {
+		t.Errorf("The gzip compression ratio should be observed, %d series given", count)
+	}
+
+	if count := testutil.CollectAndCount(collector, "storages_operation_duration_seconds"); count != 1 {
+		t.Errorf("The get latency should be observed, %d series given", count)
+	}
+
+	if count := testutil.CollectAndCount(collector, "storages_replication_lag_seconds"); count != 1 {
+		t.Errorf("The replication lag should be observed, %d series given", count)
+	}
+}
@@ -0,0 +1,311 @@
+// Package mock provides a deterministic in-memory storer running on an
+// injectable clock, so the tests advance the time to go through the fresh,
+// stale and expired states of a response without sleeping.
+package mock
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+type entry struct {
+	value []byte
+	// expiresAt is zero for the entries stored without duration.
+	expiresAt time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// Storer is an in-memory core.Storer whose entries expire according to its
+// clock, time.Now unless replaced with SetNow. The values are stored as
+// given, without compression.
+type Storer struct {
+	mu      sync.RWMutex
+	entries map[string]entry
+	now     func() time.Time
+	stale   time.Duration
+	logger  core.Logger
+}
+
+// New creates an empty storer keeping the responses stored with
+// SetMultiLevel for their duration then stale for the stale duration.
+func New(stale time.Duration, logger core.Logger) *Storer {
+	return &Storer{entries: map[string]entry{}, now: time.Now, stale: stale, logger: logger}
+}
+
+// SetNow replaces the clock of the storer.
+func (s *Storer) SetNow(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// Now returns the current time of the storer clock.
+func (s *Storer) Now() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.now()
+}
+
+// Name returns the storer name.
+func (s *Storer) Name() string {
+	return "MOCK"
+}
+
+// Uuid returns the storer identifier, the same for every instance.
+func (s *Storer) Uuid() string {
+	return "mock"
+}
+
+// MapKeys method returns the unexpired values of the keys starting with the
+// prefix, keyed by the key without the prefix.
+func (s *Storer) MapKeys(prefix string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	keys := map[string]string{}
+
+	for key, e := range s.entries {
+		if k, found := strings.CutPrefix(key, prefix); found && !e.expired(now) {
+			keys[k] = string(e.value)
+		}
+	}
+
+	return keys
+}
+
+// ListKeys method returns the sorted real keys referenced by the mappings.
+func (s *Storer) ListKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	keys := []string{}
+
+	for key, e := range s.entries {
+		if strings.HasPrefix(key, core.MappingKeyPrefix) && !e.expired(now) {
+			keys = append(keys, core.MappingRealKeys(e.value, now)...)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// WalkEntries method streams the unexpired entries, sorted by key, with
+// their remaining TTL at the storer time.
+func (s *Storer) WalkEntries(fn func(key string, value []byte, ttl time.Duration) bool) error {
+	s.mu.RLock()
+	now := s.now()
+	keys := make([]string, 0, len(s.entries))
+	entries := make(map[string]entry, len(s.entries))
+
+	for key, e := range s.entries {
+		if !e.expired(now) {
+			keys = append(keys, key)
+			entries[key] = e
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var ttl time.Duration
+		if !entries[key].expiresAt.IsZero() {
+			ttl = entries[key].expiresAt.Sub(now)
+		}
+
+		if !fn(key, entries[key].value, ttl) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Get method returns the value of the key, nil once it expired.
+func (s *Storer) Get(key string) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, found := s.entries[key]
+	if !found || e.expired(s.now()) {
+		return nil
+	}
+
+	return e.value
+}
+
+// Set method stores the value for the duration, it never expires when the
+// duration isn't positive.
+func (s *Storer) Set(key string, value []byte, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(key, value, duration)
+
+	return nil
+}
+
+func (s *Storer) set(key string, value []byte, duration time.Duration) {
+	e := entry{value: value}
+	if duration > 0 {
+		e.expiresAt = s.now().Add(duration)
+	}
+
+	s.entries[key] = e
+}
+
+// RefreshTTL method makes the key expire after duration from the storer
+// time, core.ErrKeyNotFound when it doesn't exist or expired.
+func (s *Storer) RefreshTTL(key string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, found := s.entries[key]
+	if !found || e.expired(s.now()) {
+		return core.ErrKeyNotFound
+	}
+
+	s.set(key, e.value, duration)
+
+	return nil
+}
+
+// GetTTL method returns the remaining lifetime of the key at the storer
+// time, zero when it never expires.
+func (s *Storer) GetTTL(key string) (time.Duration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, found := s.entries[key]
+	if !found {
+		return 0, core.ErrKeyNotFound
+	}
+
+	return core.RemainingTTL(e.expiresAt, s.now())
+}
+
+// Delete method removes the key.
+func (s *Storer) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// DeleteMany method removes the keys matching the regular expression.
+func (s *Storer) DeleteMany(key string) {
+	re, err := regexp.Compile(key)
+	if err != nil {
+		s.logger.Errorf("Impossible to compile the DeleteMany regexp %s, %v", key, err)
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.entries {
+		if re.MatchString(k) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+// GetMultiLevel elects the fresh and the stale responses at the storer time.
+func (s *Storer) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	item := s.Get(core.MappingKeyPrefix + key)
+	if item == nil {
+		return nil, nil
+	}
+
+	fresh, stale, _ = core.MappingElectionAt(s, item, req, validator, s.logger, s.Now())
+
+	return fresh, stale
+}
+
+// SetMultiLevel stores the value for the duration plus the stale one and
+// references it in the mapping of the base key, at the storer time.
+func (s *Storer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	s.mu.Lock()
+
+	now := s.now()
+	s.set(variedKey, value, duration+s.stale)
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	var item []byte
+	if e, found := s.entries[mappingKey]; found && !e.expired(now) {
+		item = e.value
+	}
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, item, s.logger, now, now.Add(duration), now.Add(duration+s.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		s.mu.Unlock()
+
+		return err
+	}
+
+	s.set(mappingKey, val, 0)
+	s.mu.Unlock()
+
+	core.DeleteEvictedVariants(s, evicted)
+
+	return nil
+}
+
+// Init method does nothing.
+func (s *Storer) Init() error {
+	return nil
+}
+
+// Reset method removes every entry.
+func (s *Storer) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = map[string]entry{}
+
+	return nil
+}
+
+// Clock is a manual clock to give to SetNow.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock creates a clock stopped at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
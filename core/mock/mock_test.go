@@ -0,0 +1,129 @@
+package mock_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/mock"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
+)
+
+type nopLogger struct {
+	core.Logger
+}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+func TestStorer_Transitions(t *testing.T) {
+	clock := mock.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	storer := mock.New(time.Minute, nopLogger{})
+	storer.SetNow(clock.Now)
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	if err := storer.SetMultiLevel("base", "base-gzip", value, http.Header{}, "", 10*time.Second, "base"); err != nil {
+		t.Fatalf("The response should be stored, %v given", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+
+	if fresh, stale := storer.GetMultiLevel("base", req, &core.Revalidator{}); fresh == nil || stale != nil {
+		t.Fatal("The response should be fresh")
+	}
+
+	clock.Advance(30 * time.Second)
+
+	fresh, stale := storer.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh != nil || stale == nil {
+		t.Fatal("The response should be stale")
+	}
+
+	if age := stale.Header.Get(core.AgeHeader); age != "30" {
+		t.Errorf("The age should follow the clock, %s given", age)
+	}
+
+	clock.Advance(time.Minute)
+
+	if fresh, stale = storer.GetMultiLevel("base", req, &core.Revalidator{}); fresh != nil || stale != nil {
+		t.Error("The response should be expired")
+	}
+
+	if storer.Get("base-gzip") != nil {
+		t.Error("The value should be expired")
+	}
+}
+
+func TestStorer_RefreshMultiLevel(t *testing.T) {
+	clock := mock.NewClock(time.Now())
+	storer := mock.New(time.Minute, nopLogger{})
+	storer.SetNow(clock.Now)
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	_ = storer.SetMultiLevel("base", "base-gzip", value, http.Header{}, "", 10*time.Second, "base")
+
+	clock.Advance(30 * time.Second)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	validator := &core.Revalidator{}
+
+	if _, stale := storer.GetMultiLevel("base", req, validator); stale == nil || validator.StaleKey != "base-gzip" {
+		t.Fatalf("The stale response should be elected with its key, %s given", validator.StaleKey)
+	}
+
+	if err := core.RefreshMultiLevelAt(storer, "base", validator.StaleKey, time.Minute, storer.Now()); err != nil {
+		t.Fatalf("The response should be refreshed, %v given", err)
+	}
+
+	clock.Advance(50 * time.Second)
+
+	if fresh, _ := storer.GetMultiLevel("base", req, &core.Revalidator{}); fresh == nil {
+		t.Error("The refreshed response should be fresh after its former expiration")
+	}
+
+	if err := storer.RefreshTTL("missing", time.Second); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	if ttl, err := storer.GetTTL("base-gzip"); err != nil || ttl != 70*time.Second {
+		t.Errorf("The remaining lifetime should follow the clock, %s and %v given", ttl, err)
+	}
+}
+
+func TestStorer_Set(t *testing.T) {
+	clock := mock.NewClock(time.Now())
+	storer := mock.New(0, nopLogger{})
+	storer.SetNow(clock.Now)
+
+	_ = storer.Set("short", []byte("short"), time.Second)
+	_ = storer.Set("forever", []byte("forever"), 0)
+
+	if len(storer.MapKeys("")) != 2 {
+		t.Errorf("Both keys should be listed, %v given", storer.MapKeys(""))
+	}
+
+	clock.Advance(time.Hour)
+
+	if storer.Get("short") != nil || string(storer.Get("forever")) != "forever" {
+		t.Error("Only the key stored with a duration should expire")
+	}
+
+	storer.DeleteMany("^for")
+
+	if len(storer.MapKeys("")) != 0 {
+		t.Errorf("The matching keys should be deleted, %v left", storer.MapKeys(""))
+	}
+}
+
+func TestStorer_Conformance(t *testing.T) {
+	clock := mock.NewClock(time.Now())
+
+	storertestsuite.RunStorerConformanceTests(t, func(_ *testing.T, stale time.Duration) core.Storer {
+		storer := mock.New(stale, nopLogger{})
+		storer.SetNow(clock.Now)
+
+		return storer
+	}, storertestsuite.Options{Wait: clock.Advance})
+}
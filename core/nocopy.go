@@ -0,0 +1,25 @@
+package core
+
+// NoCopyGetter is an optional interface the in-memory storers implement to
+// read a value without copying it. GetNoCopy returns the stored slice
+// itself, shared with the storer and every other reader, and false when the
+// key doesn't exist or is expired. The caller only reads it: modifying or
+// appending to it corrupts the stored value. The storer never modifies a
+// stored slice, a write replaces it, so the slice stays valid as long as
+// the caller holds it.
+type NoCopyGetter interface {
+	GetNoCopy(key string) ([]byte, bool)
+}
+
+// GetNoCopy reads the value with the storer GetNoCopy method when it
+// implements NoCopyGetter, with Get otherwise. The decorators aren't
+// unwrapped since they may transform the keys or the values.
+func GetNoCopy(storer Storer, key string) ([]byte, bool) {
+	if getter, ok := storer.(NoCopyGetter); ok {
+		return getter.GetNoCopy(key)
+	}
+
+	value := storer.Get(key)
+
+	return value, len(value) > 0
+}
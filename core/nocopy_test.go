@@ -0,0 +1,42 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+// noCopyStorer is a memoryStorer implementing the NoCopyGetter, it counts
+// the values read without copy.
+type noCopyStorer struct {
+	*memoryStorer
+	reads int
+}
+
+func (n *noCopyStorer) GetNoCopy(key string) ([]byte, bool) {
+	n.reads++
+
+	value := n.memoryStorer.Get(key)
+
+	return value, value != nil
+}
+
+func TestGetNoCopy(t *testing.T) {
+	storer := &noCopyStorer{memoryStorer: newMemoryStorer()}
+	_ = storer.Set("key", []byte("value"), 0)
+
+	if value, found := core.GetNoCopy(storer, "key"); !found || string(value) != "value" || storer.reads != 1 {
+		t.Errorf("The value should be read without copy, %s given", value)
+	}
+
+	if _, found := core.GetNoCopy(storer, "missing"); found {
+		t.Error("A missing key shouldn't be found")
+	}
+
+	plain := newMemoryStorer()
+	_ = plain.Set("key", []byte("value"), 0)
+
+	if value, found := core.GetNoCopy(plain, "key"); !found || string(value) != "value" {
+		t.Errorf("The storers without NoCopyGetter should be read with Get, %s given", value)
+	}
+}
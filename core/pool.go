@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// PoolConfigurationKey is the key read from the provider configuration to
+// tune the connections to the remote backend.
+const PoolConfigurationKey = "pool"
+
+// Pool tunes the connections of the network backed storages, the zero
+// values keep the client library defaults. Each provider maps the settings
+// to its client, the ones it has no equivalent for are ignored.
+type Pool struct {
+	// Size is the number of connections at most to each node.
+	Size int `json:"size"`
+	// MinIdle is the number of idle connections kept open.
+	MinIdle int `json:"min_idle"`
+	// MaxRetries is the number of retries of a failed command, -1 disables
+	// them.
+	MaxRetries int `json:"max_retries"`
+	// DialBackoff is the delay before retrying a failed dial or command,
+	// doubled after each failure up to MaxDialBackoff.
+	DialBackoff    time.Duration `json:"dial_backoff"`
+	MaxDialBackoff time.Duration `json:"max_dial_backoff"`
+}
+
+// PoolFromConfiguration returns the pool settings declared under the pool
+// key of the provider configuration, the zero Pool when absent.
+func PoolFromConfiguration(configuration any) (Pool, error) {
+	var pool Pool
+
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok || cfg[PoolConfigurationKey] == nil {
+		return pool, nil
+	}
+
+	if err := DecodeConfiguration(cfg[PoolConfigurationKey], &pool); err != nil {
+		return pool, fmt.Errorf("invalid pool configuration: %w", err)
+	}
+
+	if pool.Size < 0 || pool.MinIdle < 0 {
+		return pool, fmt.Errorf("invalid pool configuration: the size and min_idle can't be negative, %d and %d given", pool.Size, pool.MinIdle)
+	}
+
+	if pool.Size > 0 && pool.MinIdle > pool.Size {
+		return pool, fmt.Errorf("invalid pool configuration: the min_idle can't exceed the size, %d and %d given", pool.MinIdle, pool.Size)
+	}
+
+	if pool.MaxRetries < -1 {
+		return pool, fmt.Errorf("invalid pool configuration: the max_retries must be -1 or more, %d given", pool.MaxRetries)
+	}
+
+	if pool.DialBackoff < 0 || pool.MaxDialBackoff < 0 {
+		return pool, fmt.Errorf("invalid pool configuration: the dial_backoff and max_dial_backoff can't be negative, %s and %s given", pool.DialBackoff, pool.MaxDialBackoff)
+	}
+
+	if pool.MaxDialBackoff > 0 && pool.DialBackoff > pool.MaxDialBackoff {
+		return pool, fmt.Errorf("invalid pool configuration: the dial_backoff can't exceed the max_dial_backoff, %s and %s given", pool.DialBackoff, pool.MaxDialBackoff)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,46 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestPoolFromConfiguration(t *testing.T) {
+	pool, err := core.PoolFromConfiguration(map[string]interface{}{
+		"pool": map[string]interface{}{
+			"size":             "64",
+			"min_idle":         8,
+			"max_retries":      -1,
+			"dial_backoff":     "10ms",
+			"max_dial_backoff": "1s",
+		},
+		"compression": "lz4",
+	})
+	if err != nil {
+		t.Fatalf("The pool should be valid, %v given", err)
+	}
+
+	expected := core.Pool{Size: 64, MinIdle: 8, MaxRetries: -1, DialBackoff: 10 * time.Millisecond, MaxDialBackoff: time.Second}
+	if pool != expected {
+		t.Errorf("The pool should be %+v, %+v given", expected, pool)
+	}
+
+	if pool, _ = core.PoolFromConfiguration(nil); pool != (core.Pool{}) {
+		t.Errorf("The pool should keep the client defaults, %+v given", pool)
+	}
+
+	for name, invalid := range map[string]map[string]interface{}{
+		"negative size":       {"size": -1},
+		"min idle above size": {"size": 2, "min_idle": 4},
+		"max retries":         {"max_retries": -2},
+		"negative backoff":    {"dial_backoff": "-1s"},
+		"backoff above max":   {"dial_backoff": "2s", "max_dial_backoff": "1s"},
+		"unknown key":         {"sizes": 2},
+	} {
+		if _, err = core.PoolFromConfiguration(map[string]interface{}{"pool": invalid}); err == nil {
+			t.Errorf("The %s pool should be invalid", name)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// KeyPrefixConfigurationKey is the key read from the provider configuration
+// to isolate the keys of an instance or a tenant sharing the storage.
+const KeyPrefixConfigurationKey = "key_prefix"
+
+// PrefixedStorer decorates any Storer to prefix every key it reads, writes,
+// lists or deletes, so several instances or tenants can share one storage.
+// The mapping keys keep the MappingKeyPrefix first: the mapping of key is
+// stored under MappingKeyPrefix + prefix + key.
+type PrefixedStorer struct {
+	Storer
+
+	prefix string
+}
+
+// NewPrefixedStorer wraps the storer with the prefix.
+func NewPrefixedStorer(storer Storer, prefix string) (*PrefixedStorer, error) {
+	if prefix == "" {
+		return nil, errors.New("invalid key_prefix configuration: the prefix can't be empty")
+	}
+
+	return &PrefixedStorer{Storer: storer, prefix: prefix}, nil
+}
+
+// PrefixedStorerFromConfiguration wraps the storer when the key_prefix key
+// is set in the provider configuration, it returns the storer untouched
+// otherwise.
+func PrefixedStorerFromConfiguration(storer Storer, provider CacheProvider) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	prefixCfg, ok := cfg[KeyPrefixConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	prefix, ok := prefixCfg.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid key_prefix configuration: a string is expected, %T given", prefixCfg)
+	}
+
+	return NewPrefixedStorer(storer, prefix)
+}
+
+// Unwrap returns the decorated storer.
+func (s *PrefixedStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// Prefix returns the prefix of the keys.
+func (s *PrefixedStorer) Prefix() string {
+	return s.prefix
+}
+
+// prefixed returns the key stored in the decorated storer.
+func (s *PrefixedStorer) prefixed(key string) string {
+	if rest, found := strings.CutPrefix(key, MappingKeyPrefix); found {
+		return MappingKeyPrefix + s.prefix + rest
+	}
+
+	return s.prefix + key
+}
+
+// Uuid returns the decorated storer identifier suffixed with the prefix, so
+// the instances sharing the storage are registered apart.
+func (s *PrefixedStorer) Uuid() string {
+	return s.Storer.Uuid() + "-" + s.prefix
+}
+
+// MapKeys method returns the keys starting with the prefix of this
+// instance, stripped of both prefixes.
+func (s *PrefixedStorer) MapKeys(prefix string) map[string]string {
+	return s.Storer.MapKeys(s.prefixed(prefix))
+}
+
+// ListKeys method returns the real keys stored by this instance.
+func (s *PrefixedStorer) ListKeys() []string {
+	keys := []string{}
+
+	for _, key := range s.Storer.ListKeys() {
+		if rest, found := strings.CutPrefix(key, s.prefix); found {
+			keys = append(keys, rest)
+		}
+	}
+
+	return keys
+}
+
+// Get method returns the value stored under the prefixed key.
+func (s *PrefixedStorer) Get(key string) []byte {
+	return s.Storer.Get(s.prefixed(key))
+}
+
+// Lookup method returns the value stored under the prefixed key.
+func (s *PrefixedStorer) Lookup(key string) ([]byte, error) {
+	return Lookup(s.Storer, s.prefixed(key))
+}
+
+// GetMetadata returns the metadata of the prefixed key, the keys are
+// stripped of the prefix.
+func (s *PrefixedStorer) GetMetadata(key string) ([]KeyMetadata, error) {
+	metadata, err := GetMetadata(s.Storer, s.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range metadata {
+		metadata[i].Key = strings.TrimPrefix(metadata[i].Key, s.prefix)
+		metadata[i].RealKey = strings.TrimPrefix(metadata[i].RealKey, s.prefix)
+	}
+
+	return metadata, nil
+}
+
+// GetMultiLevel returns the fresh and stale candidates of the prefixed key.
+func (s *PrefixedStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	return s.Storer.GetMultiLevel(s.prefix+key, req, validator)
+}
+
+// Set method stores the value under the prefixed key.
+func (s *PrefixedStorer) Set(key string, value []byte, duration time.Duration) error {
+	return s.Storer.Set(s.prefixed(key), value, duration)
+}
+
+// SetMultiLevel stores the value and its mapping under the prefixed keys,
+// the real key is prefixed too so ListKeys can tell the keys of this
+// instance.
+func (s *PrefixedStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	return s.Storer.SetMultiLevel(s.prefix+baseKey, s.prefix+variedKey, value, variedHeaders, etag, duration, s.prefix+realKey)
+}
+
+// Delete method deletes the prefixed key.
+func (s *PrefixedStorer) Delete(key string) {
+	s.Storer.Delete(s.prefixed(key))
+}
+
+// DeleteMany method deletes the keys of this instance matching the regular
+// expression, their mappings included. The expression is matched against the
+// keys stripped of the prefix, a leading ^ anchors it right after the prefix.
+func (s *PrefixedStorer) DeleteMany(key string) {
+	if _, err := regexp.Compile(key); err != nil {
+		return
+	}
+
+	rest, anchored := strings.CutPrefix(key, "^")
+	if !anchored {
+		rest = ".*(?:" + rest + ")"
+	}
+
+	s.Storer.DeleteMany("^(?:" + regexp.QuoteMeta(MappingKeyPrefix) + ")?" + regexp.QuoteMeta(s.prefix) + "(?:" + rest + ")")
+}
+
+// TryLock acquires the lock of the prefixed key.
+func (s *PrefixedStorer) TryLock(key string, ttl time.Duration) (bool, error) {
+	return LockerFor(s.Storer).TryLock(s.prefix+key, ttl)
+}
+
+// Unlock releases the lock of the prefixed key.
+func (s *PrefixedStorer) Unlock(key string) error {
+	return LockerFor(s.Storer).Unlock(s.prefix + key)
+}
+
+// SetNX stores the value of the prefixed key only if it doesn't exist.
+func (s *PrefixedStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	return SetNX(s.Storer, s.prefixed(key), value, ttl)
+}
+
+// CompareAndSwap replaces the value of the prefixed key only if the stored
+// one equals old.
+func (s *PrefixedStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return CompareAndSwap(s.Storer, s.prefixed(key), old, value, ttl)
+}
+
+// RefreshTTL extends the expiration of the prefixed key.
+func (s *PrefixedStorer) RefreshTTL(key string, duration time.Duration) error {
+	return RefreshTTL(s.Storer, s.prefixed(key), duration)
+}
+
+// GetTTL returns the remaining lifetime of the prefixed key.
+func (s *PrefixedStorer) GetTTL(key string) (time.Duration, error) {
+	return GetTTL(s.Storer, s.prefixed(key))
+}
+
+// Increment adds delta to the counter of the prefixed key.
+func (s *PrefixedStorer) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	return Increment(s.Storer, s.prefixed(key), delta, ttl)
+}
+
+// Transact runs fn in a transaction of the decorated storer, on the
+// prefixed keys.
+func (s *PrefixedStorer) Transact(fn func(tx Tx) error) error {
+	return Transact(s.Storer, func(tx Tx) error {
+		return fn(prefixedTx{Tx: tx, storer: s})
+	})
+}
+
+// prefixedTx prefixes the keys of the transaction.
+type prefixedTx struct {
+	Tx
+	storer *PrefixedStorer
+}
+
+func (t prefixedTx) Get(key string) ([]byte, error) {
+	return t.Tx.Get(t.storer.prefixed(key))
+}
+
+func (t prefixedTx) Set(key string, value []byte, ttl time.Duration) error {
+	return t.Tx.Set(t.storer.prefixed(key), value, ttl)
+}
+
+func (t prefixedTx) Delete(key string) error {
+	return t.Tx.Delete(t.storer.prefixed(key))
+}
@@ -0,0 +1,122 @@
+package core_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestPrefixedStorer(t *testing.T) {
+	shared := newMemoryStorer()
+	first, _ := core.NewPrefixedStorer(shared, "first:")
+	second, _ := core.NewPrefixedStorer(shared, "second:")
+
+	_ = first.Set("key", []byte("first"), time.Minute)
+	_ = second.Set("key", []byte("second"), time.Minute)
+
+	if string(first.Get("key")) != "first" || string(second.Get("key")) != "second" {
+		t.Errorf("Each tenant should read its own value, %s and %s given", first.Get("key"), second.Get("key"))
+	}
+
+	if string(shared.Get("first:key")) != "first" {
+		t.Errorf("The key should be stored with the prefix, %s given", shared.Get("first:key"))
+	}
+
+	if keys := first.ListKeys(); !slices.Equal(keys, []string{"key"}) {
+		t.Errorf("Only the tenant keys should be listed, %v given", keys)
+	}
+
+	first.Delete("key")
+
+	if first.Get("key") != nil || string(second.Get("key")) != "second" {
+		t.Error("Only the tenant key should be deleted")
+	}
+
+	if first.Uuid() == second.Uuid() {
+		t.Error("The tenants should be registered apart")
+	}
+}
+
+func TestPrefixedStorer_MultiLevel(t *testing.T) {
+	shared := newMemoryStorer()
+	first, _ := core.NewPrefixedStorer(shared, "first:")
+	second, _ := core.NewPrefixedStorer(shared, "second:")
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nfirst")
+
+	if err := first.SetMultiLevel("base", "base-varied", value, http.Header{}, "", time.Minute, "real"); err != nil {
+		t.Fatalf("The multi level write shouldn't fail, %v given", err)
+	}
+
+	if shared.Get(core.MappingKeyPrefix+"first:base") == nil {
+		t.Error("The mapping should be stored under the mapping and the tenant prefixes")
+	}
+
+	if fresh, _ := first.GetMultiLevel("base", req, &core.Revalidator{}); fresh == nil {
+		t.Error("The tenant should read its response")
+	}
+
+	if fresh, _ := second.GetMultiLevel("base", req, &core.Revalidator{}); fresh != nil {
+		t.Error("The other tenant shouldn't read the response")
+	}
+
+	metadata, err := core.GetMetadata(first, "base")
+	if err != nil || len(metadata) != 1 || metadata[0].Key != "base-varied" || metadata[0].RealKey != "real" {
+		t.Errorf("The metadata keys should be stripped of the prefix, %+v and %v given", metadata, err)
+	}
+
+	if _, found := first.MapKeys(core.MappingKeyPrefix)["base"]; !found || len(second.MapKeys(core.MappingKeyPrefix)) != 0 {
+		t.Error("The mappings should be mapped per tenant")
+	}
+}
+
+func TestPrefixedStorer_DeleteMany(t *testing.T) {
+	shared := newMemoryStorer()
+	first, _ := core.NewPrefixedStorer(shared, "first:")
+	second, _ := core.NewPrefixedStorer(shared, "second:")
+
+	for _, storer := range []core.Storer{first, second} {
+		_ = storer.Set("GET-domain.com-/a", []byte("a"), time.Minute)
+		_ = storer.Set("GET-domain.com-/b", []byte("b"), time.Minute)
+		_ = storer.Set(core.MappingKeyPrefix+"GET-domain.com-/a", []byte("mapping"), time.Minute)
+	}
+
+	first.DeleteMany("^GET-domain.com-/a")
+
+	if first.Get("GET-domain.com-/a") != nil || first.Get(core.MappingKeyPrefix+"GET-domain.com-/a") != nil {
+		t.Error("The anchored expression should delete the key and its mapping")
+	}
+
+	if first.Get("GET-domain.com-/b") == nil || second.Get("GET-domain.com-/a") == nil {
+		t.Error("The other keys and tenants should be kept")
+	}
+
+	first.DeleteMany("/b$")
+
+	if first.Get("GET-domain.com-/b") != nil || second.Get("GET-domain.com-/b") == nil {
+		t.Error("The unanchored expression should only delete the tenant keys")
+	}
+}
+
+func TestPrefixedStorerFromConfiguration(t *testing.T) {
+	storer := newMemoryStorer()
+
+	if wrapped, _ := core.PrefixedStorerFromConfiguration(storer, core.CacheProvider{}); wrapped != storer {
+		t.Error("The storer shouldn't be wrapped without key_prefix")
+	}
+
+	wrapped, err := core.PrefixedStorerFromConfiguration(storer, core.CacheProvider{Configuration: map[string]interface{}{"key_prefix": "tenant:"}})
+	if prefixed, ok := wrapped.(*core.PrefixedStorer); err != nil || !ok || prefixed.Prefix() != "tenant:" {
+		t.Errorf("The storer should be prefixed, %v given", err)
+	}
+
+	for _, prefix := range []any{"", 1} {
+		if _, err = core.PrefixedStorerFromConfiguration(storer, core.CacheProvider{Configuration: map[string]interface{}{"key_prefix": prefix}}); err == nil {
+			t.Errorf("The key_prefix %v should be rejected", prefix)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package core
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaConfigurationKey is the key read from the provider configuration to
+// bound the entries stored by an instance or a tenant.
+const QuotaConfigurationKey = "quota"
+
+// QuotaOptions tunes the QuotaStorer, a zero limit is unbounded.
+type QuotaOptions struct {
+	// MaxEntries is the number of responses and values stored at most.
+	MaxEntries int `json:"max_entries"`
+	// MaxBytes is the size of the stored values at most.
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+func (o QuotaOptions) validate() error {
+	if o.MaxEntries < 0 {
+		return fmt.Errorf("the max_entries can't be negative, %d given", o.MaxEntries)
+	}
+
+	if o.MaxBytes < 0 {
+		return fmt.Errorf("the max_bytes can't be negative, %d given", o.MaxBytes)
+	}
+
+	if o.MaxEntries == 0 && o.MaxBytes == 0 {
+		return errors.New("the max_entries or the max_bytes must be set")
+	}
+
+	return nil
+}
+
+// quotaEntry is a tracked key, a base key holds the size of its varied keys.
+type quotaEntry struct {
+	key        string
+	size       int64
+	variedKeys map[string]int64
+}
+
+// QuotaStorer decorates any Storer, usually a PrefixedStorer, to bound the
+// entries or the bytes it writes. Once over quota, the least recently used
+// entries of this storer are deleted: the other tenants sharing the storage
+// are never evicted. A response stored with SetMultiLevel counts as one
+// entry per base key, evicted with its mapping and its varied keys. The
+// mappings and the surrogate keys aren't counted.
+//
+// The usage index is kept in memory: it only knows the keys written through
+// this instance since its creation.
+type QuotaStorer struct {
+	Storer
+
+	options QuotaOptions
+	logger  Logger
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	bytes   int64
+}
+
+// NewQuotaStorer wraps the storer with an empty usage index.
+func NewQuotaStorer(storer Storer, options QuotaOptions, logger Logger) (*QuotaStorer, error) {
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid quota configuration: %w", err)
+	}
+
+	return &QuotaStorer{
+		Storer:  storer,
+		options: options,
+		logger:  logger,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}, nil
+}
+
+// QuotaStorerFromConfiguration wraps the storer when the quota key is set in
+// the provider configuration, it returns the storer untouched otherwise.
+func QuotaStorerFromConfiguration(storer Storer, provider CacheProvider, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	quotaCfg, ok := cfg[QuotaConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	var options QuotaOptions
+	if err := DecodeConfiguration(quotaCfg, &options); err != nil {
+		return nil, fmt.Errorf("invalid quota configuration: %w", err)
+	}
+
+	return NewQuotaStorer(storer, options, logger)
+}
+
+// Unwrap returns the decorated storer.
+func (s *QuotaStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// Usage returns the tracked entries count and bytes.
+func (s *QuotaStorer) Usage() (entries int, bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len(), s.bytes
+}
+
+func untracked(key string) bool {
+	return strings.HasPrefix(key, MappingKeyPrefix) || strings.HasPrefix(key, SurrogateKeyPrefix) || strings.HasPrefix(key, LockKeyPrefix)
+}
+
+// touch marks the entry as recently used.
+func (s *QuotaStorer) touch(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, found := s.entries[key]; found {
+		s.lru.MoveToFront(element)
+	}
+}
+
+// forget drops the entry from the index without deleting it.
+func (s *QuotaStorer) forget(key string) *quotaEntry {
+	element, found := s.entries[key]
+	if !found {
+		return nil
+	}
+
+	entry, _ := s.lru.Remove(element).(*quotaEntry)
+	delete(s.entries, key)
+	s.bytes -= entry.size
+
+	return entry
+}
+
+// track records the write and returns the entries to evict to respect the
+// quota, the written one is never evicted.
+func (s *QuotaStorer) track(key, variedKey string, size int64) []*quotaEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, found := s.entries[key]
+	if !found {
+		element = s.lru.PushFront(&quotaEntry{key: key})
+		s.entries[key] = element
+	}
+
+	s.lru.MoveToFront(element)
+
+	entry, _ := element.Value.(*quotaEntry)
+	s.bytes -= entry.size
+
+	if variedKey == "" {
+		entry.size = size
+	} else {
+		if entry.variedKeys == nil {
+			entry.variedKeys = map[string]int64{}
+		}
+
+		entry.size += size - entry.variedKeys[variedKey]
+		entry.variedKeys[variedKey] = size
+	}
+
+	s.bytes += entry.size
+
+	evicted := []*quotaEntry{}
+
+	for s.lru.Len() > 1 && s.exceeded() {
+		oldest, _ := s.lru.Back().Value.(*quotaEntry)
+		evicted = append(evicted, s.forget(oldest.key))
+	}
+
+	return evicted
+}
+
+func (s *QuotaStorer) exceeded() bool {
+	return (s.options.MaxEntries > 0 && s.lru.Len() > s.options.MaxEntries) ||
+		(s.options.MaxBytes > 0 && s.bytes > s.options.MaxBytes)
+}
+
+// evict deletes the entries, with their mapping when they're base keys.
+func (s *QuotaStorer) evict(entries []*quotaEntry) {
+	for _, entry := range entries {
+		s.logger.Debugf("Evict the key %s from %s to respect the quota", entry.key, s.Storer.Name())
+
+		if entry.variedKeys == nil {
+			s.Storer.Delete(entry.key)
+
+			continue
+		}
+
+		for variedKey := range entry.variedKeys {
+			s.Storer.Delete(variedKey)
+		}
+
+		s.Storer.Delete(MappingKeyPrefix + entry.key)
+	}
+}
+
+// Get method returns the stored value and marks it as recently used, a
+// tracked key found missing is dropped from the index.
+func (s *QuotaStorer) Get(key string) []byte {
+	value := s.Storer.Get(key)
+	if value == nil {
+		s.mu.Lock()
+		s.forget(key)
+		s.mu.Unlock()
+
+		return value
+	}
+
+	s.touch(key)
+
+	return value
+}
+
+// Lookup method returns the stored value and marks it as recently used.
+func (s *QuotaStorer) Lookup(key string) ([]byte, error) {
+	value, err := Lookup(s.Storer, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		s.mu.Lock()
+		s.forget(key)
+		s.mu.Unlock()
+	} else if err == nil {
+		s.touch(key)
+	}
+
+	return value, err
+}
+
+// GetMultiLevel returns the fresh and stale candidates and marks the base key
+// as recently used.
+func (s *QuotaStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale = s.Storer.GetMultiLevel(key, req, validator)
+	if fresh != nil || stale != nil {
+		s.touch(key)
+	}
+
+	return fresh, stale
+}
+
+// Set method stores the value then evicts the least recently used entries
+// over quota.
+func (s *QuotaStorer) Set(key string, value []byte, duration time.Duration) error {
+	if err := s.Storer.Set(key, value, duration); err != nil || untracked(key) {
+		return err
+	}
+
+	s.evict(s.track(key, "", int64(len(key)+len(value))))
+
+	return nil
+}
+
+// SetMultiLevel stores the response then evicts the least recently used
+// entries over quota.
+func (s *QuotaStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if err := s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey); err != nil {
+		return err
+	}
+
+	s.evict(s.track(baseKey, variedKey, int64(len(variedKey)+len(value))))
+
+	return nil
+}
+
+// SetNX stores the value only if the key doesn't exist, using the
+// ConditionalSetter of the decorated storer, then evicts the least recently
+// used entries over quota.
+func (s *QuotaStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	stored, err := SetNX(s.Storer, key, value, ttl)
+	if err == nil && stored && !untracked(key) {
+		s.evict(s.track(key, "", int64(len(key)+len(value))))
+	}
+
+	return stored, err
+}
+
+// CompareAndSwap replaces the value only if the stored one equals old,
+// using the ConditionalSetter of the decorated storer, then evicts the least
+// recently used entries over quota.
+func (s *QuotaStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	swapped, err := CompareAndSwap(s.Storer, key, old, value, ttl)
+	if err == nil && swapped && !untracked(key) {
+		s.evict(s.track(key, "", int64(len(key)+len(value))))
+	}
+
+	return swapped, err
+}
+
+// Transact runs fn in a transaction of the decorated storer, then counts
+// its writes and evicts the least recently used entries over quota.
+func (s *QuotaStorer) Transact(fn func(tx Tx) error) error {
+	writes, err := transactRecorded(s.Storer, fn)
+	if err != nil {
+		return err
+	}
+
+	for _, write := range writes {
+		switch {
+		case write.deleted:
+			s.mu.Lock()
+			s.forget(strings.TrimPrefix(write.key, MappingKeyPrefix))
+			s.mu.Unlock()
+		case !untracked(write.key):
+			s.evict(s.track(write.key, "", int64(len(write.key)+len(write.value))))
+		}
+	}
+
+	return nil
+}
+
+// Delete method deletes the key and drops it from the index.
+func (s *QuotaStorer) Delete(key string) {
+	s.Storer.Delete(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.forget(strings.TrimPrefix(key, MappingKeyPrefix))
+}
+
+// DeleteMany method deletes the matching keys and drops them from the index.
+func (s *QuotaStorer) DeleteMany(key string) {
+	s.Storer.DeleteMany(key)
+
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tracked := range s.entries {
+		if rgKey.MatchString(tracked) {
+			s.forget(tracked)
+		}
+	}
+}
+
+// Reset method resets the decorated storer and empties the index.
+func (s *QuotaStorer) Reset() error {
+	s.mu.Lock()
+	s.entries = map[string]*list.Element{}
+	s.lru.Init()
+	s.bytes = 0
+	s.mu.Unlock()
+
+	return s.Storer.Reset()
+}
@@ -0,0 +1,107 @@
+package core_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestQuotaStorer_MaxEntries(t *testing.T) {
+	shared := newMemoryStorer()
+	tenant, _ := core.NewPrefixedStorer(shared, "tenant:")
+	other, _ := core.NewPrefixedStorer(shared, "other:")
+	storer, _ := core.NewQuotaStorer(tenant, core.QuotaOptions{MaxEntries: 2}, nopLogger{})
+
+	_ = other.Set("kept", []byte("value"), time.Minute)
+	_ = storer.Set("first", []byte("value"), time.Minute)
+	_ = storer.Set("second", []byte("value"), time.Minute)
+
+	// Reading the first key makes the second one the least recently used.
+	_ = storer.Get("first")
+	_ = storer.Set("third", []byte("value"), time.Minute)
+
+	if storer.Get("second") != nil || storer.Get("first") == nil || storer.Get("third") == nil {
+		t.Error("The least recently used key should be evicted")
+	}
+
+	if other.Get("kept") == nil {
+		t.Error("The other tenants keys shouldn't be evicted")
+	}
+
+	if entries, _ := storer.Usage(); entries != 2 {
+		t.Errorf("The index should hold 2 entries, %d given", entries)
+	}
+}
+
+func TestQuotaStorer_MaxBytes(t *testing.T) {
+	storer, _ := core.NewQuotaStorer(newMemoryStorer(), core.QuotaOptions{MaxBytes: 100}, nopLogger{})
+
+	for i := range 5 {
+		_ = storer.Set("key-"+strconv.Itoa(i), make([]byte, 30), time.Minute)
+	}
+
+	entries, bytes := storer.Usage()
+	if bytes > 100 || entries != 2 {
+		t.Errorf("The quota should be respected, %d entries and %d bytes given", entries, bytes)
+	}
+
+	if storer.Get("key-0") != nil || storer.Get("key-4") == nil {
+		t.Error("The oldest keys should be evicted")
+	}
+
+	// A single value larger than the quota is kept until the next write.
+	_ = storer.Set("large", make([]byte, 200), time.Minute)
+
+	if storer.Get("large") == nil {
+		t.Error("The written key should never be evicted")
+	}
+}
+
+func TestQuotaStorer_MultiLevel(t *testing.T) {
+	backend := newMemoryStorer()
+	storer, _ := core.NewQuotaStorer(backend, core.QuotaOptions{MaxEntries: 1}, nopLogger{})
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+
+	_ = storer.SetMultiLevel("first", "first-a", value, http.Header{}, "", time.Minute, "first-a")
+	_ = storer.SetMultiLevel("first", "first-b", value, http.Header{}, "", time.Minute, "first-b")
+
+	if entries, _ := storer.Usage(); entries != 1 {
+		t.Fatalf("The varied keys should count as one entry, %d given", entries)
+	}
+
+	_ = storer.SetMultiLevel("second", "second-a", value, http.Header{}, "", time.Minute, "second-a")
+
+	if backend.Get("first-a") != nil || backend.Get("first-b") != nil || backend.Get(core.MappingKeyPrefix+"first") != nil {
+		t.Error("The evicted base key should be deleted with its mapping and varied keys")
+	}
+
+	storer.Delete(core.MappingKeyPrefix + "second")
+
+	if entries, _ := storer.Usage(); entries != 0 {
+		t.Errorf("The deleted mapping should drop its base key, %d given", entries)
+	}
+}
+
+func TestQuotaStorerFromConfiguration(t *testing.T) {
+	storer := newMemoryStorer()
+
+	if wrapped, _ := core.QuotaStorerFromConfiguration(storer, core.CacheProvider{}, nopLogger{}); wrapped != storer {
+		t.Error("The storer shouldn't be wrapped without quota")
+	}
+
+	wrapped, err := core.QuotaStorerFromConfiguration(storer, core.CacheProvider{Configuration: map[string]interface{}{
+		"quota": map[string]interface{}{"max_entries": "10", "max_bytes": 1024},
+	}}, nopLogger{})
+	if _, ok := wrapped.(*core.QuotaStorer); err != nil || !ok {
+		t.Errorf("The storer should be wrapped, %v given", err)
+	}
+
+	for _, quota := range []map[string]interface{}{{}, {"max_entries": -1}, {"max_bytes": -1}, {"unknown": 1}} {
+		if _, err = core.QuotaStorerFromConfiguration(storer, core.CacheProvider{Configuration: map[string]interface{}{"quota": quota}}, nopLogger{}); err == nil {
+			t.Errorf("The quota %v should be rejected", quota)
+		}
+	}
+}
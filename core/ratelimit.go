@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BackgroundRateLimitConfigurationKey is the key read from the provider
+// configuration to rate limit the background operations of the storage.
+const BackgroundRateLimitConfigurationKey = "background_rate_limit"
+
+// backgroundRateLimitConfiguration is the token bucket read from the
+// provider configuration.
+type backgroundRateLimitConfiguration struct {
+	// Rate is the number of background operations per second.
+	Rate float64 `json:"rate"`
+	// Burst is the number of operations run at once after an idle period,
+	// the rate rounded up by default.
+	Burst int `json:"burst"`
+}
+
+// BackgroundLimiter is a token bucket spacing the background operations of a
+// storage, e.g. the sweeps, the garbage collections, the reconnections and
+// the scans, so they don't saturate a backend shared with the requests. A
+// nil BackgroundLimiter never waits.
+type BackgroundLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBackgroundLimiter returns a limiter allowing rate operations per second
+// and bursts of burst operations.
+func NewBackgroundLimiter(rate float64, burst int) (*BackgroundLimiter, error) {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return nil, fmt.Errorf("invalid background_rate_limit configuration: the rate must be positive, %v given", rate)
+	}
+
+	if burst <= 0 {
+		return nil, fmt.Errorf("invalid background_rate_limit configuration: the burst must be positive, %d given", burst)
+	}
+
+	return &BackgroundLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}, nil
+}
+
+// BackgroundLimiterFromConfiguration returns the limiter declared under the
+// background_rate_limit key of the provider configuration, nil when absent.
+func BackgroundLimiterFromConfiguration(configuration any) (*BackgroundLimiter, error) {
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok || cfg[BackgroundRateLimitConfigurationKey] == nil {
+		return nil, nil
+	}
+
+	var limit backgroundRateLimitConfiguration
+	if err := DecodeConfiguration(cfg[BackgroundRateLimitConfigurationKey], &limit); err != nil {
+		return nil, fmt.Errorf("invalid background_rate_limit configuration: %w", err)
+	}
+
+	if limit.Burst == 0 && limit.Rate > 0 && limit.Rate < math.MaxInt32 {
+		limit.Burst = int(math.Ceil(limit.Rate))
+	}
+
+	return NewBackgroundLimiter(limit.Rate, limit.Burst)
+}
+
+// reserve takes a token and returns the delay before it's available.
+func (l *BackgroundLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = min(l.burst, l.tokens+max(now.Sub(l.last), 0).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Wait blocks until the operation may run, the throttled operations are
+// reported to the throttling observer. It returns false when stop is closed
+// meanwhile, the caller then gives up the operation.
+func (l *BackgroundLimiter) Wait(stop <-chan struct{}, operation string) bool {
+	if l == nil {
+		return true
+	}
+
+	delay := l.reserve(time.Now())
+	if delay <= 0 {
+		return true
+	}
+
+	if observer := throttlingObserver.Load(); observer != nil {
+		(*observer)(operation, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+var throttlingObserver atomic.Pointer[func(operation string, wait time.Duration)]
+
+// ObserveThrottling registers the function notified with the delay of each
+// background operation throttled by a BackgroundLimiter. Passing nil removes
+// the observer.
+func ObserveThrottling(observer func(operation string, wait time.Duration)) {
+	if observer == nil {
+		throttlingObserver.Store(nil)
+
+		return
+	}
+
+	throttlingObserver.Store(&observer)
+}
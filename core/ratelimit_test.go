@@ -0,0 +1,77 @@
+package core_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestBackgroundLimiter(t *testing.T) {
+	var throttled atomic.Int32
+
+	core.ObserveThrottling(func(operation string, wait time.Duration) {
+		if operation == "sweep" && wait > 0 {
+			throttled.Add(1)
+		}
+	})
+	defer core.ObserveThrottling(nil)
+
+	limiter, err := core.NewBackgroundLimiter(20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	for range 3 {
+		if !limiter.Wait(nil, "sweep") {
+			t.Fatal("The operation should run")
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("The operation over the burst should wait for a token, %s elapsed", elapsed)
+	}
+
+	if throttled.Load() != 1 {
+		t.Errorf("The throttled operation should be observed once, %d given", throttled.Load())
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+
+	if limiter.Wait(stop, "sweep") {
+		t.Error("The stopped operation shouldn't run")
+	}
+
+	var unlimited *core.BackgroundLimiter
+	if !unlimited.Wait(nil, "sweep") {
+		t.Error("A nil limiter should never wait")
+	}
+}
+
+func TestBackgroundLimiterFromConfiguration(t *testing.T) {
+	if limiter, err := core.BackgroundLimiterFromConfiguration(map[string]interface{}{}); limiter != nil || err != nil {
+		t.Errorf("No limiter should be created without configuration, %v and %v given", limiter, err)
+	}
+
+	if limiter, err := core.BackgroundLimiterFromConfiguration(map[string]interface{}{
+		"background_rate_limit": map[string]interface{}{"rate": "2.5"},
+	}); limiter == nil || err != nil {
+		t.Errorf("The limiter should be created with the default burst, %v given", err)
+	}
+
+	if _, err := core.BackgroundLimiterFromConfiguration(map[string]interface{}{
+		"background_rate_limit": map[string]interface{}{"rate": 0},
+	}); err == nil {
+		t.Error("A zero rate should be rejected")
+	}
+
+	if _, err := core.BackgroundLimiterFromConfiguration(map[string]interface{}{
+		"background_rate_limit": map[string]interface{}{"rate": 10, "burst": -1},
+	}); err == nil {
+		t.Error("A negative burst should be rejected")
+	}
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ReadOnlyConfigurationKey is the key read from the provider configuration
+// to be able to freeze the storage, its value is the initial state.
+const ReadOnlyConfigurationKey = "read_only"
+
+var (
+	// ErrReadOnly is returned by the ReadOnlyStorer writes while the storer
+	// is read-only.
+	ErrReadOnly = errors.New("the storer is read-only")
+	// ErrReadOnlyNotSupported is returned by SetReadOnly when no decorator
+	// can freeze the storer.
+	ErrReadOnlyNotSupported = errors.New("the storer can't be set read-only")
+)
+
+// ReadOnlyStorer decorates any Storer to freeze it during a migration, an
+// audit or an incident without stopping the traffic. While read-only, Set
+// and SetMultiLevel fail with ErrReadOnly, Delete and DeleteMany are dropped
+// and the reads are still served.
+type ReadOnlyStorer struct {
+	Storer
+
+	logger   Logger
+	readOnly atomic.Bool
+}
+
+// NewReadOnlyStorer wraps the storer in the given state.
+func NewReadOnlyStorer(storer Storer, readOnly bool, logger Logger) *ReadOnlyStorer {
+	s := &ReadOnlyStorer{Storer: storer, logger: logger}
+	s.readOnly.Store(readOnly)
+
+	return s
+}
+
+// readOnlyConfiguration is the typed state read from the provider
+// configuration.
+type readOnlyConfiguration struct {
+	ReadOnly bool           `json:"read_only"`
+	Others   map[string]any `json:",remain"`
+}
+
+// ReadOnlyStorerFromConfiguration wraps the storer when the read_only key is
+// set in the provider configuration, it returns the storer untouched
+// otherwise. Set it to false to start writable and freeze the storer later
+// with SetReadOnly.
+func ReadOnlyStorerFromConfiguration(storer Storer, provider CacheProvider, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	if _, ok = cfg[ReadOnlyConfigurationKey]; !ok {
+		return storer, nil
+	}
+
+	var readOnly readOnlyConfiguration
+	if err := DecodeConfiguration(cfg, &readOnly); err != nil {
+		return nil, fmt.Errorf("invalid read_only configuration: %w", err)
+	}
+
+	return NewReadOnlyStorer(storer, readOnly.ReadOnly, logger), nil
+}
+
+// SetReadOnly sets the state of the first ReadOnlyStorer, walking down the
+// decorators. It returns ErrReadOnlyNotSupported when there is none.
+func SetReadOnly(storer Storer, readOnly bool) error {
+	for storer != nil {
+		if frozen, ok := storer.(*ReadOnlyStorer); ok {
+			frozen.SetReadOnly(readOnly)
+
+			return nil
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return ErrReadOnlyNotSupported
+}
+
+// Unwrap returns the decorated storer.
+func (s *ReadOnlyStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// SetReadOnly freezes the storer or accepts the writes again.
+func (s *ReadOnlyStorer) SetReadOnly(readOnly bool) {
+	if s.readOnly.Swap(readOnly) == readOnly {
+		return
+	}
+
+	if readOnly {
+		s.logger.Warnf("The %s storer is read-only, the writes are rejected", s.Storer.Name())
+	} else {
+		s.logger.Infof("The %s storer accepts the writes again", s.Storer.Name())
+	}
+}
+
+// ReadOnly reports whether the writes are rejected.
+func (s *ReadOnlyStorer) ReadOnly() bool {
+	return s.readOnly.Load()
+}
+
+// Set method stores the value unless the storer is read-only.
+func (s *ReadOnlyStorer) Set(key string, value []byte, duration time.Duration) error {
+	if s.readOnly.Load() {
+		return ErrReadOnly
+	}
+
+	return s.Storer.Set(key, value, duration)
+}
+
+// SetMultiLevel method stores the response unless the storer is read-only.
+func (s *ReadOnlyStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if s.readOnly.Load() {
+		return ErrReadOnly
+	}
+
+	return s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+}
+
+// Delete method deletes the key unless the storer is read-only.
+func (s *ReadOnlyStorer) Delete(key string) {
+	if s.readOnly.Load() {
+		s.logger.Debugf("Impossible to delete the key %s, %v", key, ErrReadOnly)
+
+		return
+	}
+
+	s.Storer.Delete(key)
+}
+
+// DeleteMany method deletes the matching keys unless the storer is
+// read-only.
+func (s *ReadOnlyStorer) DeleteMany(key string) {
+	if s.readOnly.Load() {
+		s.logger.Debugf("Impossible to delete the keys matching %s, %v", key, ErrReadOnly)
+
+		return
+	}
+
+	s.Storer.DeleteMany(key)
+}
+
+// DeleteManyCount deletes the matching keys with the decorated storer, the
+// dry-runs are still served while the storer is read-only.
+func (s *ReadOnlyStorer) DeleteManyCount(pattern string, dryRun bool) (DeleteManyResult, error) {
+	if !dryRun && s.readOnly.Load() {
+		return DeleteManyResult{}, ErrReadOnly
+	}
+
+	return DeleteManyCount(s.Storer, pattern, dryRun)
+}
+
+// SetNX stores the value only if the key doesn't exist, unless the storer is
+// read-only.
+func (s *ReadOnlyStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	if s.readOnly.Load() {
+		return false, ErrReadOnly
+	}
+
+	return SetNX(s.Storer, key, value, ttl)
+}
+
+// CompareAndSwap replaces the value only if the stored one equals old,
+// unless the storer is read-only.
+func (s *ReadOnlyStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	if s.readOnly.Load() {
+		return false, ErrReadOnly
+	}
+
+	return CompareAndSwap(s.Storer, key, old, value, ttl)
+}
+
+// Transact runs fn in a transaction of the decorated storer, unless the
+// storer is read-only.
+func (s *ReadOnlyStorer) Transact(fn func(tx Tx) error) error {
+	if s.readOnly.Load() {
+		return ErrReadOnly
+	}
+
+	return Transact(s.Storer, fn)
+}
+
+// RefreshTTL extends the expiration of the key, unless the storer is
+// read-only.
+func (s *ReadOnlyStorer) RefreshTTL(key string, duration time.Duration) error {
+	if s.readOnly.Load() {
+		return ErrReadOnly
+	}
+
+	return RefreshTTL(s.Storer, key, duration)
+}
+
+// Increment adds delta to the counter, unless the storer is read-only.
+func (s *ReadOnlyStorer) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	if s.readOnly.Load() {
+		return 0, ErrReadOnly
+	}
+
+	return Increment(s.Storer, key, delta, ttl)
+}
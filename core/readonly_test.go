@@ -0,0 +1,95 @@
+package core_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestReadOnlyStorer(t *testing.T) {
+	memory := newMemoryStorer()
+	storer := core.NewReadOnlyStorer(memory, false, nopLogger{})
+
+	if err := storer.Set(byteKey, []byte(baseValue), time.Minute); err != nil {
+		t.Fatalf("The writable storer should accept the writes, %v given", err)
+	}
+
+	storer.SetReadOnly(true)
+
+	if err := storer.Set(byteKey, []byte("other"), time.Minute); !errors.Is(err, core.ErrReadOnly) {
+		t.Errorf("The writes should be rejected while read-only, %v given", err)
+	}
+
+	if err := storer.SetMultiLevel(byteKey, byteKey, []byte("other"), http.Header{}, "", time.Minute, byteKey); !errors.Is(err, core.ErrReadOnly) {
+		t.Errorf("The multi level writes should be rejected while read-only, %v given", err)
+	}
+
+	storer.Delete(byteKey)
+	storer.DeleteMany(".*")
+
+	if string(storer.Get(byteKey)) != baseValue {
+		t.Error("The reads should be served and the deletions dropped while read-only")
+	}
+
+	if _, err := core.DeleteManyCount(storer, ".*", false); !errors.Is(err, core.ErrReadOnly) {
+		t.Errorf("The counted deletions should be rejected while read-only, %v given", err)
+	}
+
+	if result, err := core.DeleteManyCount(storer, ".*", true); err != nil || result.Count != 1 {
+		t.Errorf("The dry-runs should be served while read-only, %+v given, %v", result, err)
+	}
+
+	storer.SetReadOnly(false)
+	storer.Delete(byteKey)
+
+	if storer.ReadOnly() || len(storer.Get(byteKey)) != 0 {
+		t.Error("The deletions should reach the storer once writable")
+	}
+}
+
+func TestReadOnlyStorerFromConfiguration(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.ReadOnlyStorerFromConfiguration(memory, core.CacheProvider{}, nopLogger{})
+	if err != nil || storer != core.Storer(memory) {
+		t.Error("The storer should be returned untouched without read_only")
+	}
+
+	if err = core.SetReadOnly(memory, true); !errors.Is(err, core.ErrReadOnlyNotSupported) {
+		t.Errorf("The undecorated storer can't be set read-only, %v given", err)
+	}
+
+	if _, err = core.ReadOnlyStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"read_only": "maybe",
+	}}, nopLogger{}); err == nil {
+		t.Error("An invalid read_only value should be rejected")
+	}
+
+	storer, err = core.ReadOnlyStorerFromConfiguration(memory, core.CacheProvider{Configuration: map[string]interface{}{
+		"read_only": true,
+	}}, nopLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	wrapped, _ := core.NewCircuitBreakerStorer(storer, core.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Second,
+		ProbeTimeout:     time.Second,
+	}, nopLogger{})
+
+	if err = wrapped.Set(byteKey, []byte(baseValue), time.Minute); !errors.Is(err, core.ErrReadOnly) {
+		t.Errorf("The storer should start read-only, %v given", err)
+	}
+
+	if err = core.SetReadOnly(wrapped, false); err != nil {
+		t.Fatalf("The read-only decorator should be found, %v given", err)
+	}
+
+	if err = storer.Set(byteKey, []byte(baseValue), time.Minute); err != nil {
+		t.Errorf("The storer should accept the writes again, %v given", err)
+	}
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ReconnectorConfigurationKey is the key read from the provider
+// configuration to tune the reconnection backoff.
+const ReconnectorConfigurationKey = "reconnect"
+
+// ErrReconnecting is returned by the providers calls while their Reconnector
+// restores the connection, the RetryStorer retries them.
+var ErrReconnecting = errors.New("reconnecting error")
+
+// Reconnector states.
+const (
+	StateConnected int32 = iota
+	StateReconnecting
+	StateStopped
+)
+
+const (
+	defaultReconnectInitialInterval = time.Second
+	defaultReconnectMaxInterval     = 30 * time.Second
+	defaultReconnectJitter          = 0.2
+)
+
+// Reconnector runs the provider connect function in the background with an
+// exponential backoff until it succeeds, the max attempts are reached or it
+// is stopped. Only one reconnection loop runs at a time.
+type Reconnector struct {
+	// InitialInterval is the delay before the second attempt, doubled after
+	// each failure up to MaxInterval.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// MaxAttempts stops the loop after that many failed attempts, zero
+	// means unlimited.
+	MaxAttempts int
+	// Jitter randomizes each delay by up to this fraction.
+	Jitter float64
+	// Limiter spaces the attempts, it's read from the background_rate_limit
+	// key of the provider configuration.
+	Limiter *BackgroundLimiter
+
+	state   atomic.Int32
+	connect func(ctx context.Context) error
+	logger  Logger
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewReconnector creates a Reconnector calling connect on each attempt. The
+// backoff is read from the reconnect key of the provider configuration
+// (initial_interval, max_interval, max_attempts and jitter).
+func NewReconnector(configuration any, logger Logger, connect func(ctx context.Context) error) *Reconnector {
+	ctx, cancel := context.WithCancel(context.Background())
+	reconnector := &Reconnector{
+		InitialInterval: defaultReconnectInitialInterval,
+		MaxInterval:     defaultReconnectMaxInterval,
+		Jitter:          defaultReconnectJitter,
+		connect:         connect,
+		logger:          logger,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok {
+		return reconnector
+	}
+
+	// NewStorer reports an invalid limit.
+	reconnector.Limiter, _ = BackgroundLimiterFromConfiguration(cfg)
+
+	reconnectCfg, ok := cfg[ReconnectorConfigurationKey].(map[string]interface{})
+	if !ok {
+		return reconnector
+	}
+
+	for key, target := range map[string]*time.Duration{
+		"initial_interval": &reconnector.InitialInterval,
+		"max_interval":     &reconnector.MaxInterval,
+	} {
+		if v, ok := reconnectCfg[key].(string); ok {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				*target = d
+			}
+		}
+	}
+
+	switch v := reconnectCfg["max_attempts"].(type) {
+	case int:
+		reconnector.MaxAttempts = v
+	case float64:
+		reconnector.MaxAttempts = int(v)
+	case string:
+		reconnector.MaxAttempts, _ = strconv.Atoi(v)
+	}
+
+	switch v := reconnectCfg["jitter"].(type) {
+	case float64:
+		reconnector.Jitter = v
+	case string:
+		reconnector.Jitter, _ = strconv.ParseFloat(v, 64)
+	}
+
+	return reconnector
+}
+
+// State returns the current state.
+func (r *Reconnector) State() int32 {
+	return r.state.Load()
+}
+
+// Reconnecting returns true while the reconnection loop runs.
+func (r *Reconnector) Reconnecting() bool {
+	return r.state.Load() == StateReconnecting
+}
+
+// Trigger starts the reconnection loop in the background unless it is
+// already running or the reconnector is stopped.
+func (r *Reconnector) Trigger() {
+	if !r.state.CompareAndSwap(StateConnected, StateReconnecting) {
+		return
+	}
+
+	go r.run()
+}
+
+// Stop cancels the running reconnection loop and prevents the next ones.
+func (r *Reconnector) Stop() {
+	r.state.Store(StateStopped)
+	r.cancel()
+}
+
+func (r *Reconnector) delay(attempt int) time.Duration {
+	delay := r.InitialInterval << min(attempt-1, 30)
+	if delay <= 0 || delay > r.MaxInterval {
+		delay = r.MaxInterval
+	}
+
+	if r.Jitter > 0 {
+		//nolint:gosec
+		delay += time.Duration((rand.Float64()*2 - 1) * r.Jitter * float64(delay))
+	}
+
+	return delay
+}
+
+func (r *Reconnector) run() {
+	for attempt := 1; ; attempt++ {
+		if !r.Limiter.Wait(r.ctx.Done(), "reconnect") {
+			return
+		}
+
+		err := r.connect(r.ctx)
+		if err == nil {
+			r.state.CompareAndSwap(StateReconnecting, StateConnected)
+			r.logger.Infof("Reconnected after %d attempt(s)", attempt)
+
+			return
+		}
+
+		if r.MaxAttempts > 0 && attempt >= r.MaxAttempts {
+			r.state.CompareAndSwap(StateReconnecting, StateConnected)
+			r.logger.Errorf("Impossible to reconnect after %d attempts, %v", attempt, err)
+
+			return
+		}
+
+		r.logger.Warnf("Impossible to reconnect (attempt %d), %v", attempt, err)
+
+		timer := time.NewTimer(r.delay(attempt))
+
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+	}
+}
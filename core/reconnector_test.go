@@ -0,0 +1,83 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestReconnector_Backoff(t *testing.T) {
+	var attempts atomic.Int32
+
+	reconnector := core.NewReconnector(map[string]interface{}{
+		"reconnect": map[string]interface{}{
+			"initial_interval": "5ms",
+			"max_interval":     "20ms",
+			"jitter":           "0",
+		},
+	}, nopLogger{}, func(context.Context) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("unreachable")
+		}
+
+		return nil
+	})
+
+	reconnector.Trigger()
+	reconnector.Trigger()
+
+	if !reconnector.Reconnecting() {
+		t.Error("The reconnector should be reconnecting")
+	}
+
+	waitFor(t, func() bool { return reconnector.State() == core.StateConnected })
+
+	if attempts.Load() != 3 {
+		t.Errorf("The connect function should be called 3 times, %d given", attempts.Load())
+	}
+}
+
+func TestReconnector_MaxAttempts(t *testing.T) {
+	var attempts atomic.Int32
+
+	reconnector := core.NewReconnector(map[string]interface{}{
+		"reconnect": map[string]interface{}{
+			"initial_interval": "1ms",
+			"max_attempts":     2,
+		},
+	}, nopLogger{}, func(context.Context) error {
+		attempts.Add(1)
+
+		return errors.New("unreachable")
+	})
+
+	reconnector.Trigger()
+	waitFor(t, func() bool { return !reconnector.Reconnecting() })
+
+	if attempts.Load() != 2 {
+		t.Errorf("The connect function should be called 2 times, %d given", attempts.Load())
+	}
+}
+
+func TestReconnector_Stop(t *testing.T) {
+	var attempts atomic.Int32
+
+	reconnector := core.NewReconnector(nil, nopLogger{}, func(context.Context) error {
+		attempts.Add(1)
+
+		return errors.New("unreachable")
+	})
+
+	reconnector.Trigger()
+	reconnector.Stop()
+	time.Sleep(20 * time.Millisecond)
+	reconnector.Trigger()
+
+	if reconnector.State() != core.StateStopped || attempts.Load() > 1 {
+		t.Errorf("The reconnector should be stopped after %d attempt(s)", attempts.Load())
+	}
+}
@@ -0,0 +1,307 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRefreshAheadHitThreshold = 5
+	defaultRefreshAheadWindow       = time.Minute
+	defaultRefreshAheadRatio        = 0.2
+	defaultRefreshAheadMaxKeys      = 10000
+)
+
+// RefreshFunc refreshes the key before it expires, usually by fetching it
+// from the origin and storing it again. It receives the key given to Set or
+// the base key given to SetMultiLevel.
+type RefreshFunc func(key string) error
+
+// RefreshAheadOptions tunes the RefreshAhead.
+type RefreshAheadOptions struct {
+	// HitThreshold is the number of hits in a Window making a key hot, 5 by
+	// default.
+	HitThreshold int
+	// Window is the period the hits are counted over, 1m by default.
+	Window time.Duration
+	// Ratio is the part of the TTL left when a hot key is refreshed, 0.2 by
+	// default: a key stored for 10m is refreshed during its last 2m.
+	Ratio float64
+	// MaxKeys bounds the tracked keys, 10000 by default. The keys written
+	// while it's reached aren't refreshed.
+	MaxKeys int
+}
+
+func (o *RefreshAheadOptions) setDefaults() {
+	if o.HitThreshold == 0 {
+		o.HitThreshold = defaultRefreshAheadHitThreshold
+	}
+
+	if o.Window == 0 {
+		o.Window = defaultRefreshAheadWindow
+	}
+
+	if o.Ratio == 0 {
+		o.Ratio = defaultRefreshAheadRatio
+	}
+
+	if o.MaxKeys == 0 {
+		o.MaxKeys = defaultRefreshAheadMaxKeys
+	}
+}
+
+func (o RefreshAheadOptions) validate() error {
+	if o.HitThreshold < 0 {
+		return fmt.Errorf("the hit threshold can't be negative, %d given", o.HitThreshold)
+	}
+
+	if o.Window < 0 {
+		return fmt.Errorf("the window can't be negative, %s given", o.Window)
+	}
+
+	if o.Ratio < 0 || o.Ratio >= 1 {
+		return fmt.Errorf("the ratio must be in [0, 1), %v given", o.Ratio)
+	}
+
+	if o.MaxKeys < 0 {
+		return fmt.Errorf("the max keys can't be negative, %d given", o.MaxKeys)
+	}
+
+	return nil
+}
+
+// refreshEntry is a tracked key, its hits are counted since windowStart.
+type refreshEntry struct {
+	ttl         time.Duration
+	expiresAt   time.Time
+	hits        int
+	windowStart time.Time
+	refreshing  bool
+}
+
+// RefreshAhead decorates any Storer to keep its popular entries warm. Like
+// the InstrumentedStorer, it observes the hits of Get, Lookup and
+// GetMultiLevel, counted per key. When a key hit at least HitThreshold times
+// in the current Window is hit during the last Ratio of its TTL, the refresh
+// callback is called in the background so the entry is stored again before
+// it expires. A key is refreshed once at a time.
+//
+// Only the keys written through this instance with a positive duration are
+// tracked, their expiration is reset by the next write.
+type RefreshAhead struct {
+	Storer
+
+	refresh RefreshFunc
+	options RefreshAheadOptions
+	logger  Logger
+
+	mu      sync.Mutex
+	entries map[string]*refreshEntry
+	wg      sync.WaitGroup
+}
+
+// NewRefreshAhead wraps the storer to call refresh on its hot keys nearing
+// their expiration.
+func NewRefreshAhead(storer Storer, refresh RefreshFunc, options RefreshAheadOptions, logger Logger) (*RefreshAhead, error) {
+	if refresh == nil {
+		return nil, errors.New("invalid refresh ahead configuration: the refresh callback is required")
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid refresh ahead configuration: %w", err)
+	}
+
+	options.setDefaults()
+
+	return &RefreshAhead{
+		Storer:  storer,
+		refresh: refresh,
+		options: options,
+		logger:  logger,
+		entries: map[string]*refreshEntry{},
+	}, nil
+}
+
+// Unwrap returns the decorated storer.
+func (s *RefreshAhead) Unwrap() Storer {
+	return s.Storer
+}
+
+// Tracked returns the number of tracked keys.
+func (s *RefreshAhead) Tracked() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// Wait blocks until the running refreshes are done.
+func (s *RefreshAhead) Wait() {
+	s.wg.Wait()
+}
+
+// track records the expiration of the written key.
+func (s *RefreshAhead) track(key string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if duration <= 0 {
+		delete(s.entries, key)
+
+		return
+	}
+
+	now := time.Now()
+
+	entry, found := s.entries[key]
+	if !found {
+		if len(s.entries) >= s.options.MaxKeys {
+			s.pruneLocked(now)
+		}
+
+		if len(s.entries) >= s.options.MaxKeys {
+			return
+		}
+
+		entry = &refreshEntry{windowStart: now}
+		s.entries[key] = entry
+	}
+
+	entry.ttl = duration
+	entry.expiresAt = now.Add(duration)
+}
+
+// pruneLocked forgets the expired keys.
+func (s *RefreshAhead) pruneLocked(now time.Time) {
+	for key, entry := range s.entries {
+		if !entry.refreshing && !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// hit counts the hit and starts the refresh of the hot key nearing its
+// expiration.
+func (s *RefreshAhead) hit(key string) {
+	s.mu.Lock()
+
+	entry, found := s.entries[key]
+	if !found {
+		s.mu.Unlock()
+
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(entry.windowStart) >= s.options.Window {
+		entry.windowStart = now
+		entry.hits = 0
+	}
+
+	entry.hits++
+
+	remaining := entry.expiresAt.Sub(now)
+	if entry.refreshing || entry.hits < s.options.HitThreshold || remaining <= 0 ||
+		remaining > time.Duration(s.options.Ratio*float64(entry.ttl)) {
+		s.mu.Unlock()
+
+		return
+	}
+
+	entry.refreshing = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		if err := s.refresh(key); err != nil {
+			s.logger.Warnf("Impossible to refresh the key %s ahead of its expiration, %v", key, err)
+		}
+
+		s.mu.Lock()
+		if entry, found := s.entries[key]; found {
+			entry.refreshing = false
+		}
+		s.mu.Unlock()
+	}()
+}
+
+// forget stops tracking the key.
+func (s *RefreshAhead) forget(key string) {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+}
+
+// Get counts the hit when a value is returned.
+func (s *RefreshAhead) Get(key string) []byte {
+	value := s.Storer.Get(key)
+	if len(value) > 0 {
+		s.hit(key)
+	}
+
+	return value
+}
+
+// Lookup method returns the stored value and counts the hit.
+func (s *RefreshAhead) Lookup(key string) ([]byte, error) {
+	value, err := Lookup(s.Storer, key)
+	if err == nil {
+		s.hit(key)
+	}
+
+	return value, err
+}
+
+// GetMultiLevel counts the hit when a fresh response is elected.
+func (s *RefreshAhead) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale = s.Storer.GetMultiLevel(key, req, validator)
+	if fresh != nil {
+		s.hit(key)
+	}
+
+	return fresh, stale
+}
+
+// Set method stores the value and tracks its expiration.
+func (s *RefreshAhead) Set(key string, value []byte, duration time.Duration) error {
+	if err := s.Storer.Set(key, value, duration); err != nil {
+		return err
+	}
+
+	s.track(key, duration)
+
+	return nil
+}
+
+// SetMultiLevel method stores the response and tracks the expiration of
+// its base key.
+func (s *RefreshAhead) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if err := s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey); err != nil {
+		return err
+	}
+
+	s.track(baseKey, duration)
+
+	return nil
+}
+
+// Delete method deletes the key and stops tracking it.
+func (s *RefreshAhead) Delete(key string) {
+	s.Storer.Delete(key)
+	s.forget(key)
+}
+
+// Reset method resets the storer and forgets the tracked keys.
+func (s *RefreshAhead) Reset() error {
+	s.mu.Lock()
+	s.entries = map[string]*refreshEntry{}
+	s.mu.Unlock()
+
+	return s.Storer.Reset()
+}
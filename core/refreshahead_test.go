@@ -0,0 +1,108 @@
+package core_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestRefreshAhead(t *testing.T) {
+	var (
+		storer    *core.RefreshAhead
+		refreshed atomic.Int32
+	)
+
+	storer, err := core.NewRefreshAhead(newMemoryStorer(), func(key string) error {
+		refreshed.Add(1)
+
+		return storer.Set(key, []byte("refreshed"), 200*time.Millisecond)
+	}, core.RefreshAheadOptions{HitThreshold: 3, Ratio: 0.5}, nopLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	_ = storer.Set(byteKey, []byte(baseValue), 200*time.Millisecond)
+	_ = storer.Set("cold", []byte(baseValue), 200*time.Millisecond)
+
+	for range 5 {
+		storer.Get(byteKey)
+	}
+
+	storer.Wait()
+
+	if refreshed.Load() != 0 {
+		t.Error("The hot key shouldn't be refreshed while far from its expiration")
+	}
+
+	time.Sleep(120 * time.Millisecond)
+
+	storer.Get("cold")
+
+	for range 5 {
+		_, _ = storer.Lookup(byteKey)
+	}
+
+	storer.Wait()
+
+	if refreshed.Load() != 1 {
+		t.Errorf("The hot key nearing its expiration should be refreshed once, %d refreshes given", refreshed.Load())
+	}
+
+	if string(storer.Get(byteKey)) != "refreshed" {
+		t.Error("The refreshed value should be stored")
+	}
+
+	storer.Delete(byteKey)
+
+	if storer.Tracked() != 1 {
+		t.Errorf("The deleted key should be forgotten, %d tracked keys given", storer.Tracked())
+	}
+}
+
+func TestRefreshAheadFailure(t *testing.T) {
+	var calls atomic.Int32
+
+	storer, err := core.NewRefreshAhead(newMemoryStorer(), func(string) error {
+		calls.Add(1)
+
+		return errors.New("unavailable origin")
+	}, core.RefreshAheadOptions{HitThreshold: 1, Ratio: 0.9}, nopLogger{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	_ = storer.Set(byteKey, []byte(baseValue), time.Second)
+	time.Sleep(150 * time.Millisecond)
+
+	storer.Get(byteKey)
+	storer.Wait()
+	storer.Get(byteKey)
+	storer.Wait()
+
+	if calls.Load() != 2 {
+		t.Errorf("The failed refresh should be retried on the next hit, %d calls given", calls.Load())
+	}
+}
+
+func TestRefreshAheadOptions(t *testing.T) {
+	if _, err := core.NewRefreshAhead(newMemoryStorer(), nil, core.RefreshAheadOptions{}, nopLogger{}); err == nil {
+		t.Error("The refresh callback should be required")
+	}
+
+	if _, err := core.NewRefreshAhead(newMemoryStorer(), func(string) error { return nil }, core.RefreshAheadOptions{Ratio: 1}, nopLogger{}); err == nil {
+		t.Error("A ratio of 1 should be rejected")
+	}
+
+	storer, _ := core.NewRefreshAhead(newMemoryStorer(), func(string) error { return nil }, core.RefreshAheadOptions{MaxKeys: 1}, nopLogger{})
+
+	_ = storer.Set("first", []byte(baseValue), time.Minute)
+	_ = storer.Set("second", []byte(baseValue), time.Minute)
+	_ = storer.Set("eternal", []byte(baseValue), 0)
+
+	if storer.Tracked() != 1 {
+		t.Errorf("The tracked keys should be bounded, %d given", storer.Tracked())
+	}
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrTTLRefreshNotSupported is returned when no storer under the decorators
+// implements TTLRefresher.
+var ErrTTLRefreshNotSupported = errors.New("the storer doesn't support the TTL refreshes")
+
+// TTLRefresher is an optional interface a Storer can implement to extend the
+// expiration of a stored key in place, without writing its value again.
+// RefreshTTL makes the key expire after duration, it returns ErrKeyNotFound
+// when the key doesn't exist. Like the conditional sets, the duration isn't
+// extended by the stale duration.
+type TTLRefresher interface {
+	RefreshTTL(key string, duration time.Duration) error
+}
+
+// touchRefresher refreshes the TTLs with the Touch of a Toucher.
+type touchRefresher struct {
+	Toucher
+}
+
+func (t touchRefresher) RefreshTTL(key string, duration time.Duration) error {
+	return t.Touch(key, duration)
+}
+
+// TTLRefresherFor returns the TTLRefresher implemented by the storer or one
+// of the storers it decorates, a Toucher refreshes the TTLs too.
+func TTLRefresherFor(storer Storer) (TTLRefresher, bool) {
+	for storer != nil {
+		if refresher, ok := storer.(TTLRefresher); ok {
+			return refresher, true
+		}
+
+		if toucher, ok := storer.(Toucher); ok {
+			return touchRefresher{Toucher: toucher}, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// RefreshTTL makes the key expire after duration, using the TTLRefresher of
+// the storer. It returns ErrTTLRefreshNotSupported when there is none.
+func RefreshTTL(storer Storer, key string, duration time.Duration) error {
+	refresher, ok := TTLRefresherFor(storer)
+	if !ok {
+		return ErrTTLRefreshNotSupported
+	}
+
+	return refresher.RefreshTTL(key, duration)
+}
+
+// refreshMapping makes the entry of the varied key fresh for duration from
+// now, then stale for the stale duration it was stored with. It returns the
+// stale duration of the entry and the latest stale time of the entries.
+func refreshMapping(mapping *StorageMapper, variedKey string, now time.Time, duration time.Duration) (time.Duration, time.Time, error) {
+	index, found := mapping.GetMapping()[variedKey]
+	if !found {
+		return 0, time.Time{}, ErrKeyNotFound
+	}
+
+	stale := max(index.GetStaleTime().AsTime().Sub(index.GetFreshTime().AsTime()), 0)
+
+	index.StoredAt = timestamppb.New(now)
+	index.FreshTime = timestamppb.New(now.Add(duration))
+	index.StaleTime = timestamppb.New(now.Add(duration + stale))
+
+	staleUntil := now
+
+	for _, entry := range mapping.GetMapping() {
+		if staleTime := entry.GetStaleTime().AsTime(); staleTime.After(staleUntil) {
+			staleUntil = staleTime
+		}
+	}
+
+	return stale, staleUntil, nil
+}
+
+// RefreshMultiLevel makes the response stored under the varied key of the
+// base key fresh again for duration, once the origin revalidated it, e.g.
+// answered 304 to the conditional request sent for the stale response whose
+// key is Revalidator.StaleKey. The response is then stale for the stale
+// duration it was stored with. The expiration of the varied key is extended
+// in place with RefreshTTL and only the mapping is stored again, the body
+// isn't rewritten. It returns ErrKeyNotFound when the response expired
+// meanwhile, the caller stores it again with SetMultiLevel.
+func RefreshMultiLevel(storer Storer, baseKey, variedKey string, duration time.Duration) error {
+	return RefreshMultiLevelAt(storer, baseKey, variedKey, duration, time.Now())
+}
+
+// RefreshMultiLevelAt is RefreshMultiLevel at the given time, for the
+// storers running on their own clock.
+func RefreshMultiLevelAt(storer Storer, baseKey, variedKey string, duration time.Duration, now time.Time) error {
+	refresher, ok := TTLRefresherFor(storer)
+	if !ok {
+		return ErrTTLRefreshNotSupported
+	}
+
+	mappingKey := MappingKeyFor(storer, baseKey)
+
+	return UpdateMapping(func() error {
+		item, err := Lookup(storer, mappingKey)
+		if err != nil {
+			return err
+		}
+
+		mapping, err := DecodeMapping(item)
+		if err != nil {
+			return fmt.Errorf("impossible to decode the mapping %s: %w", mappingKey, err)
+		}
+
+		stale, staleUntil, err := refreshMapping(mapping, variedKey, now, duration)
+		if err != nil {
+			return err
+		}
+
+		if err = refresher.RefreshTTL(variedKey, duration+stale); err != nil {
+			return err
+		}
+
+		mapping.Version++
+
+		value, err := EncodeMapping(mapping)
+		if err != nil {
+			return err
+		}
+
+		if err = swapMapping(storer, mappingKey, item, value, staleUntil.Sub(now)); err != nil && !errors.Is(err, ErrMappingConflict) {
+			return fmt.Errorf("impossible to store the refreshed mapping %s: %w", mappingKey, err)
+		}
+
+		return err
+	})
+}
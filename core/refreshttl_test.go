@@ -0,0 +1,140 @@
+package core_test
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// refreshingStorer is a memoryStorer implementing the TTLRefresher, it
+// records the refreshed durations.
+type refreshingStorer struct {
+	*memoryStorer
+	mu        sync.Mutex
+	refreshed map[string]time.Duration
+}
+
+func (r *refreshingStorer) RefreshTTL(key string, duration time.Duration) error {
+	if r.Get(key) == nil {
+		return core.ErrKeyNotFound
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refreshed[key] = duration
+
+	return nil
+}
+
+func TestRefreshMultiLevel(t *testing.T) {
+	storer := &refreshingStorer{memoryStorer: newMemoryStorer(), refreshed: map[string]time.Duration{}}
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+
+	if err := storer.SetMultiLevel("base", "base-varied", value, http.Header{}, "", time.Minute, "base"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	mapping, err := core.MappingUpdater("base-varied", nil, nopLogger{}, now.Add(-time.Minute), now.Add(-time.Second), now.Add(time.Hour), http.Header{}, "", "base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = storer.Set(core.MappingKeyPrefix+"base", mapping, 0)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	validator := &core.Revalidator{}
+
+	if fresh, stale := storer.GetMultiLevel("base", req, validator); fresh != nil || stale == nil {
+		t.Fatal("The response should be elected stale")
+	}
+
+	if validator.StaleKey != "base-varied" {
+		t.Fatalf("The stale key should be given by the validator, %s given", validator.StaleKey)
+	}
+
+	if err = core.RefreshMultiLevel(storer, "base", validator.StaleKey, time.Minute); err != nil {
+		t.Fatalf("The response should be refreshed, %v given", err)
+	}
+
+	if ttl := storer.refreshed["base-varied"]; ttl < time.Minute+time.Hour || ttl > time.Minute+time.Hour+2*time.Second {
+		t.Errorf("The varied key should expire after the duration and the stale duration, %s given", ttl)
+	}
+
+	if fresh, _ := storer.GetMultiLevel("base", req, &core.Revalidator{}); fresh == nil {
+		t.Error("The refreshed response should be elected fresh")
+	}
+
+	if err = core.RefreshMultiLevel(storer, "base", "base-missing", time.Minute); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing varied key should be reported, %v given", err)
+	}
+
+	if err = core.RefreshMultiLevel(newMemoryStorer(), "base", "base-varied", time.Minute); !errors.Is(err, core.ErrTTLRefreshNotSupported) {
+		t.Errorf("The storer without TTL refresh should be reported, %v given", err)
+	}
+}
+
+// taggedMappingStorer stores the mappings behind the hash tag of their base
+// key, like Redis in cluster mode.
+type taggedMappingStorer struct {
+	*refreshingStorer
+}
+
+func (taggedMappingStorer) MappingKey(baseKey string) string {
+	return "{" + baseKey + "}" + core.MappingKeyPrefix + baseKey
+}
+
+func TestRefreshMultiLevel_MappingKey(t *testing.T) {
+	storer := taggedMappingStorer{&refreshingStorer{memoryStorer: newMemoryStorer(), refreshed: map[string]time.Duration{}}}
+	now := time.Now()
+
+	mapping, err := core.MappingUpdater("{base}base-varied", nil, nopLogger{}, now.Add(-time.Minute), now.Add(-time.Second), now.Add(time.Hour), http.Header{}, "", "base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = storer.Set("{base}base-varied", []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello"), time.Hour)
+	_ = storer.Set(storer.MappingKey("base"), mapping, time.Hour)
+
+	if err = core.RefreshMultiLevel(storer, "base", "{base}base-varied", time.Minute); err != nil {
+		t.Fatalf("The response behind the hash tag should be refreshed, %v given", err)
+	}
+
+	metadata, _ := core.DecodeMetadata(storer.Get(storer.MappingKey("base")))
+	if len(metadata) != 1 || !metadata[0].Fresh(time.Now()) {
+		t.Errorf("The mapping behind the hash tag should be refreshed, %+v given", metadata)
+	}
+
+	if storer.Get(core.MappingKeyPrefix+"base") != nil {
+		t.Error("The mapping shouldn't be stored without its hash tag")
+	}
+}
+
+func TestRefreshTTL(t *testing.T) {
+	storer, err := core.NewPrefixedStorer(&refreshingStorer{memoryStorer: newMemoryStorer(), refreshed: map[string]time.Duration{}}, "tenant-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = storer.Set("key", []byte("value"), time.Second)
+
+	if err = core.RefreshTTL(storer, "key", time.Minute); err != nil {
+		t.Errorf("The prefixed key should be refreshed, %v given", err)
+	}
+
+	_ = storer.Set(core.MappingKeyPrefix+"base", []byte("mapping"), time.Second)
+
+	if err = core.RefreshTTL(storer, core.MappingKeyPrefix+"base", time.Minute); err != nil {
+		t.Errorf("The prefixed mapping key should be refreshed, %v given", err)
+	}
+
+	if err = core.RefreshTTL(storer, "missing", time.Minute); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should be reported, %v given", err)
+	}
+}
@@ -2,7 +2,10 @@ package core
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pierrec/lz4/v4"
 )
@@ -12,7 +15,18 @@ import (
 // return it to the pool after Close. Readers must never be pooled this way:
 // a pooled reader escapes through http.Response.Body and would be recycled
 // while another goroutine still reads from it.
-var Lz4WriterPool = sync.Pool{New: func() any { return lz4.NewWriter(nil) }}
+//
+// The lz4 default block size is 4 MB, which makes every compression and
+// later decompression of the value churn 4 MB pooled blocks even for tiny
+// payloads. Cached bodies are usually far smaller, so the pooled writers use
+// the smallest block size. Readers pick the block size up from the frame
+// header.
+var Lz4WriterPool = sync.Pool{New: func() any {
+	writer := lz4.NewWriter(nil)
+	_ = writer.Apply(lz4.BlockSizeOption(lz4.Block64Kb))
+
+	return writer
+}}
 
 // MappingWalker is an optional interface a Storer can implement to stream
 // mapping entries in bounded batches instead of materializing the whole
@@ -63,3 +77,155 @@ func GetRegisteredStorers() []Storer {
 
 	return storers
 }
+
+// FactoryFunc creates a Storer from its provider configuration, each storage
+// module exposes one as Factory.
+type FactoryFunc func(provider CacheProvider, logger Logger, stale time.Duration) (Storer, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]FactoryFunc{}
+)
+
+// RegisterFactory makes a storage available by name to NewStorer. The storage
+// modules register themselves when imported, like the database/sql drivers.
+// It panics if the name is already registered or the factory is nil.
+func RegisterFactory(name string, factory FactoryFunc) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	name = strings.ToLower(name)
+
+	if factory == nil {
+		panic("storages: RegisterFactory factory is nil for " + name)
+	}
+
+	if _, exists := factories[name]; exists {
+		panic("storages: RegisterFactory called twice for " + name)
+	}
+
+	factories[name] = factory
+}
+
+// RegisteredFactories returns the sorted names of the registered storages.
+func RegisteredFactories() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// NewStorer creates the storage registered under the given name and wraps it
+// with the key hashing, the value size limit, the key prefix, the quota, the
+// encryption, the body deduplication, the mapping pruning, the cross-instance
+// invalidation, the TTL jitter, the circuit breaker, the async writes and the
+// read-only mode when they're configured. The bodies are deduplicated before
+// they're encrypted.
+func NewStorer(name string, provider CacheProvider, logger Logger, stale time.Duration) (Storer, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[strings.ToLower(name)]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown storage %s, the registered ones are %v", name, RegisteredFactories())
+	}
+
+	storer, err := factory(provider, logger, stale)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = BackgroundLimiterFromConfiguration(provider.Configuration); err != nil {
+		return nil, err
+	}
+
+	if err = SetVaryNormalizationFromConfiguration(provider); err != nil {
+		return nil, err
+	}
+
+	if err = SetMaxVariantsFromConfiguration(provider); err != nil {
+		return nil, err
+	}
+
+	if err = SetMappingCodecFromConfiguration(provider); err != nil {
+		return nil, err
+	}
+
+	if err = SetAccessTrackingFromConfiguration(storer, provider); err != nil {
+		return nil, err
+	}
+
+	storer, err = HashedKeyStorerFromConfiguration(storer, provider, stale)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = ValueLimitStorerFromConfiguration(storer, provider, stale, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = PrefixedStorerFromConfiguration(storer, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = QuotaStorerFromConfiguration(storer, provider, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = EncryptedStorerFromConfiguration(storer, provider, stale, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = DedupStorerFromConfiguration(storer, provider, stale, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = MappingGCStorerFromConfiguration(storer, provider, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = InvalidatedStorerFromConfiguration(storer, provider, stale, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = JitterStorerFromConfiguration(storer, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = RetryStorerFromConfiguration(storer, provider, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = CircuitBreakerStorerFromConfiguration(storer, provider, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = AsyncStorerFromConfiguration(storer, provider, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	storer, err = AnalyticsStorerFromConfiguration(storer, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadOnlyStorerFromConfiguration(storer, provider, logger)
+}
@@ -0,0 +1,93 @@
+package core_test
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("Memory", func(core.CacheProvider, core.Logger, time.Duration) (core.Storer, error) {
+		return newMemoryStorer(), nil
+	})
+}
+
+func TestRegisterFactory(t *testing.T) {
+	if !slices.Contains(core.RegisteredFactories(), "memory") {
+		t.Error("The memory storage should be registered")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Registering the same storage twice should panic")
+		}
+	}()
+
+	core.RegisterFactory("memory", func(core.CacheProvider, core.Logger, time.Duration) (core.Storer, error) {
+		return newMemoryStorer(), nil
+	})
+}
+
+func TestNewStorer(t *testing.T) {
+	storer, err := core.NewStorer("MEMORY", core.CacheProvider{}, nopLogger{}, 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the memory storage: %v", err)
+	}
+
+	if storer.Name() != "MEMORY" {
+		t.Errorf("The memory storage should be returned, %s given", storer.Name())
+	}
+
+	if _, err = core.NewStorer("unknown", core.CacheProvider{}, nopLogger{}, 0); err == nil {
+		t.Error("An unknown storage should return an error")
+	}
+
+	storer, err = core.NewStorer("memory", core.CacheProvider{
+		Configuration: map[string]interface{}{
+			core.EncryptionConfigurationKey: map[string]interface{}{
+				"key": base64.StdEncoding.EncodeToString(make([]byte, 32)),
+			},
+		},
+	}, nopLogger{}, 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the encrypted memory storage: %v", err)
+	}
+
+	if _, ok := storer.(*core.EncryptedStorer); !ok {
+		t.Error("The configured encryption should wrap the storage")
+	}
+}
+
+func TestNewStorer_Dedup(t *testing.T) {
+	storer, err := core.NewStorer("memory", core.CacheProvider{
+		Configuration: map[string]interface{}{
+			core.DedupConfigurationKey: map[string]interface{}{},
+			core.EncryptionConfigurationKey: map[string]interface{}{
+				"key": base64.StdEncoding.EncodeToString(make([]byte, 32)),
+			},
+		},
+	}, nopLogger{}, 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the deduplicated memory storage: %v", err)
+	}
+
+	dedup, ok := storer.(*core.DedupStorer)
+	if !ok {
+		t.Fatal("The configured deduplication should wrap the storage")
+	}
+
+	if _, ok = dedup.Unwrap().(*core.EncryptedStorer); !ok {
+		t.Error("The bodies should be deduplicated before they're encrypted")
+	}
+
+	_ = storer.SetMultiLevel("base", "varied", dedupResponse(strings.Repeat("shared ", 200)), nil, "", time.Minute, "base")
+
+	if len(storer.MapKeys(core.DedupBodyKeyPrefix)) != 1 {
+		t.Error("The body should be stored under its digest")
+	}
+}
@@ -0,0 +1,36 @@
+package core
+
+import "errors"
+
+// ErrReloadNotSupported is returned by Reload when the storer can't apply a
+// new configuration in place.
+var ErrReloadNotSupported = errors.New("the storer doesn't support reloading its configuration")
+
+// Reloadable is an optional interface a Storer can implement to apply a new
+// provider configuration without being recreated, so a server reloading its
+// configuration keeps the stored entries. The new clients are built before
+// the old ones are closed, the storer keeps its previous configuration when
+// Reload returns an error.
+type Reloadable interface {
+	Reload(cfg CacheProvider) error
+}
+
+// Reload applies the configuration to the first storer implementing
+// Reloadable, walking down the decorators. The decorators themselves keep
+// their configuration (encryption, async writes...).
+func Reload(storer Storer, cfg CacheProvider) error {
+	for storer != nil {
+		if reloadable, ok := storer.(Reloadable); ok {
+			return reloadable.Reload(cfg)
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return ErrReloadNotSupported
+}
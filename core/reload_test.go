@@ -0,0 +1,35 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+type reloadableStorer struct {
+	*memoryStorer
+	url string
+}
+
+func (r *reloadableStorer) Reload(cfg core.CacheProvider) error {
+	r.url = cfg.URL
+
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	storer := &reloadableStorer{memoryStorer: newMemoryStorer()}
+
+	if err := core.Reload(core.NewInstrumentedStorer(storer, nil), core.CacheProvider{URL: "127.0.0.1:6379"}); err != nil {
+		t.Fatalf("The decorated storer should be reloaded, %v given", err)
+	}
+
+	if storer.url != "127.0.0.1:6379" {
+		t.Errorf("The new configuration should be applied, %s given", storer.url)
+	}
+
+	if err := core.Reload(newMemoryStorer(), core.CacheProvider{}); !errors.Is(err, core.ErrReloadNotSupported) {
+		t.Errorf("The reload should be unsupported, %v given", err)
+	}
+}
@@ -0,0 +1,392 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultReplicationQueueLength = 1024
+
+// ErrReplicationQueueFull is reported to the ReplicationMetrics when an
+// asynchronous replication is dropped because the secondary queue is full.
+var ErrReplicationQueueFull = errors.New("the replication queue is full")
+
+// ReplicationMetrics receives the replications made by a ReplicatedStorer.
+// The lag is the delay between the primary write and the secondary one, the
+// error is nil when the secondary write succeeded.
+type ReplicationMetrics interface {
+	ObserveReplication(secondary string, lag time.Duration, err error)
+}
+
+// ReplicationOptions tunes the ReplicatedStorer.
+type ReplicationOptions struct {
+	// Async replicates in background, each secondary has its own queue and
+	// worker. The writes are replicated before returning otherwise.
+	Async bool `json:"async"`
+	// QueueLength is the number of replications waiting for each secondary
+	// when Async is set, the next ones are dropped.
+	QueueLength int `json:"queue_length"`
+	// Metrics receives the replications lag and failures, they're only
+	// logged when nil.
+	Metrics ReplicationMetrics `json:"-"`
+}
+
+// ReplicaStatus describes the replication to a secondary.
+type ReplicaStatus struct {
+	Name string
+	// Pending is the number of queued replications.
+	Pending int
+	// Failures is the number of failed or dropped replications.
+	Failures int64
+	// Lag is the lag of the last replication.
+	Lag time.Duration
+}
+
+type replication struct {
+	apply    func(Storer) error
+	queuedAt time.Time
+}
+
+// replica is a secondary with its own queue, a slow or failing secondary
+// never delays the others.
+type replica struct {
+	storer   Storer
+	name     string
+	queue    chan replication
+	failures atomic.Int64
+	lag      atomic.Int64
+}
+
+// ReplicatedStorer decorates the primary Storer to mirror its writes,
+// counters, TTL refreshes and deletions to the secondary storers, usually in
+// other regions, for an active-passive replication. The reads only reach the primary. A secondary
+// failure is logged and reported to the metrics, it never fails the write.
+type ReplicatedStorer struct {
+	Storer
+
+	replicas []*replica
+	options  ReplicationOptions
+	logger   Logger
+	workers  sync.WaitGroup
+	mu       sync.RWMutex
+	closed   bool
+}
+
+// NewReplicatedStorer wraps the primary storer and starts a worker per
+// secondary when the replication is asynchronous.
+func NewReplicatedStorer(primary Storer, secondaries []Storer, options ReplicationOptions, logger Logger) (*ReplicatedStorer, error) {
+	if len(secondaries) == 0 {
+		return nil, errors.New("invalid replication configuration: at least one secondary is required")
+	}
+
+	if options.QueueLength < 0 {
+		return nil, fmt.Errorf("invalid replication configuration: the queue_length can't be negative, %d given", options.QueueLength)
+	}
+
+	if options.QueueLength == 0 {
+		options.QueueLength = defaultReplicationQueueLength
+	}
+
+	s := &ReplicatedStorer{Storer: primary, options: options, logger: logger}
+
+	for _, secondary := range secondaries {
+		r := &replica{storer: secondary, name: fmt.Sprintf("%s-%s", secondary.Name(), secondary.Uuid())}
+
+		if options.Async {
+			r.queue = make(chan replication, options.QueueLength)
+
+			s.workers.Add(1)
+
+			go s.work(r)
+		}
+
+		s.replicas = append(s.replicas, r)
+	}
+
+	return s, nil
+}
+
+// Unwrap returns the primary storer.
+func (s *ReplicatedStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// Secondaries returns the secondary storers.
+func (s *ReplicatedStorer) Secondaries() []Storer {
+	secondaries := make([]Storer, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		secondaries = append(secondaries, r.storer)
+	}
+
+	return secondaries
+}
+
+// Status returns the replication status of each secondary.
+func (s *ReplicatedStorer) Status() []ReplicaStatus {
+	status := make([]ReplicaStatus, 0, len(s.replicas))
+
+	for _, r := range s.replicas {
+		status = append(status, ReplicaStatus{
+			Name:     r.name,
+			Pending:  len(r.queue),
+			Failures: r.failures.Load(),
+			Lag:      time.Duration(r.lag.Load()),
+		})
+	}
+
+	return status
+}
+
+func (s *ReplicatedStorer) work(r *replica) {
+	defer s.workers.Done()
+
+	for operation := range r.queue {
+		s.apply(r, operation)
+	}
+}
+
+func (s *ReplicatedStorer) apply(r *replica, operation replication) {
+	err := operation.apply(r.storer)
+	s.observe(r, time.Since(operation.queuedAt), err)
+}
+
+func (s *ReplicatedStorer) observe(r *replica, lag time.Duration, err error) {
+	r.lag.Store(int64(lag))
+
+	if err != nil {
+		r.failures.Add(1)
+		s.logger.Errorf("Impossible to replicate into %s, %v", r.name, err)
+	}
+
+	if s.options.Metrics != nil {
+		s.options.Metrics.ObserveReplication(r.name, lag, err)
+	}
+}
+
+// replicate applies the operation to every secondary, in parallel when
+// synchronous, queued when asynchronous.
+func (s *ReplicatedStorer) replicate(apply func(Storer) error) {
+	operation := replication{apply: apply, queuedAt: time.Now()}
+
+	if !s.options.Async {
+		var wg sync.WaitGroup
+
+		for _, r := range s.replicas {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				s.apply(r, operation)
+			}()
+		}
+
+		wg.Wait()
+
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	for _, r := range s.replicas {
+		select {
+		case r.queue <- operation:
+		default:
+			s.observe(r, 0, ErrReplicationQueueFull)
+		}
+	}
+}
+
+// Set method stores the value in the primary then replicates it.
+func (s *ReplicatedStorer) Set(key string, value []byte, duration time.Duration) error {
+	if err := s.Storer.Set(key, value, duration); err != nil {
+		return err
+	}
+
+	s.replicate(func(secondary Storer) error {
+		return secondary.Set(key, value, duration)
+	})
+
+	return nil
+}
+
+// SetMultiLevel stores the response in the primary then replicates it.
+func (s *ReplicatedStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if err := s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey); err != nil {
+		return err
+	}
+
+	s.replicate(func(secondary Storer) error {
+		return secondary.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+	})
+
+	return nil
+}
+
+// SetNX stores the value in the primary only if the key doesn't exist there,
+// then replicates the stored value. The secondaries mirror the primary, they
+// aren't compared.
+func (s *ReplicatedStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	stored, err := SetNX(s.Storer, key, value, ttl)
+	if err == nil && stored {
+		s.replicate(func(secondary Storer) error {
+			return secondary.Set(key, value, ttl)
+		})
+	}
+
+	return stored, err
+}
+
+// CompareAndSwap replaces the value in the primary only if the stored one
+// equals old, then replicates the swapped value.
+func (s *ReplicatedStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	swapped, err := CompareAndSwap(s.Storer, key, old, value, ttl)
+	if err == nil && swapped {
+		s.replicate(func(secondary Storer) error {
+			return secondary.Set(key, value, ttl)
+		})
+	}
+
+	return swapped, err
+}
+
+// Transact runs fn in a transaction of the primary, then replicates its
+// writes in order.
+func (s *ReplicatedStorer) Transact(fn func(tx Tx) error) error {
+	writes, err := transactRecorded(s.Storer, fn)
+	if err != nil || len(writes) == 0 {
+		return err
+	}
+
+	s.replicate(func(secondary Storer) error {
+		errs := []error{}
+
+		for _, write := range writes {
+			if write.deleted {
+				secondary.Delete(write.key)
+
+				continue
+			}
+
+			errs = append(errs, secondary.Set(write.key, write.value, write.ttl))
+		}
+
+		return errors.Join(errs...)
+	})
+
+	return nil
+}
+
+// Increment adds delta to the counter of the primary, then replicates its
+// new value rather than the delta so the secondaries converge.
+func (s *ReplicatedStorer) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	counter, err := Increment(s.Storer, key, delta, ttl)
+	if err != nil {
+		return counter, err
+	}
+
+	value := []byte(strconv.FormatInt(counter, 10))
+
+	s.replicate(func(secondary Storer) error {
+		return secondary.Set(key, value, ttl)
+	})
+
+	return counter, nil
+}
+
+// Touch makes the key of the primary expire after ttl, then replicates the
+// touch.
+func (s *ReplicatedStorer) Touch(key string, ttl time.Duration) error {
+	if err := Touch(s.Storer, key, ttl); err != nil {
+		return err
+	}
+
+	s.replicate(func(secondary Storer) error {
+		return Touch(secondary, key, ttl)
+	})
+
+	return nil
+}
+
+// RefreshTTL makes the key of the primary expire after duration, then
+// replicates the refresh.
+func (s *ReplicatedStorer) RefreshTTL(key string, duration time.Duration) error {
+	if err := RefreshTTL(s.Storer, key, duration); err != nil {
+		return err
+	}
+
+	s.replicate(func(secondary Storer) error {
+		return RefreshTTL(secondary, key, duration)
+	})
+
+	return nil
+}
+
+// Delete method deletes the key from the primary and the secondaries.
+func (s *ReplicatedStorer) Delete(key string) {
+	s.Storer.Delete(key)
+
+	s.replicate(func(secondary Storer) error {
+		secondary.Delete(key)
+
+		return nil
+	})
+}
+
+// DeleteMany method deletes the matching keys from the primary and the
+// secondaries.
+func (s *ReplicatedStorer) DeleteMany(key string) {
+	s.Storer.DeleteMany(key)
+
+	s.replicate(func(secondary Storer) error {
+		secondary.DeleteMany(key)
+
+		return nil
+	})
+}
+
+// Init method initializes the primary and the secondaries.
+func (s *ReplicatedStorer) Init() error {
+	errs := []error{s.Storer.Init()}
+	for _, r := range s.replicas {
+		errs = append(errs, r.storer.Init())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Reset method flushes the queued replications then resets the primary and
+// the secondaries.
+func (s *ReplicatedStorer) Reset() error {
+	s.mu.Lock()
+
+	if !s.closed {
+		s.closed = true
+
+		for _, r := range s.replicas {
+			if r.queue != nil {
+				close(r.queue)
+			}
+		}
+	}
+
+	s.mu.Unlock()
+	s.workers.Wait()
+
+	errs := []error{s.Storer.Reset()}
+	for _, r := range s.replicas {
+		errs = append(errs, r.storer.Reset())
+	}
+
+	return errors.Join(errs...)
+}
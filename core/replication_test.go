@@ -0,0 +1,140 @@
+package core_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+type recordedReplications struct {
+	mu       sync.Mutex
+	observed int
+	failed   int
+}
+
+func (r *recordedReplications) ObserveReplication(_ string, _ time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.observed++
+
+	if err != nil {
+		r.failed++
+	}
+}
+
+func TestReplicatedStorer_Sync(t *testing.T) {
+	primary, secondary := newMemoryStorer(), newMemoryStorer()
+	failing := &flakyStorer{memoryStorer: newMemoryStorer()}
+	failing.failures.Store(1)
+	metrics := &recordedReplications{}
+
+	storer, err := core.NewReplicatedStorer(primary, []core.Storer{secondary, failing}, core.ReplicationOptions{Metrics: metrics}, nopLogger{})
+	if err != nil {
+		t.Fatalf("Impossible to create the replicated storer, %v", err)
+	}
+
+	if err = storer.Set("key", []byte(baseValue), time.Minute); err != nil {
+		t.Errorf("A secondary failure shouldn't fail the write, %v given", err)
+	}
+
+	if string(primary.Get("key")) != baseValue || string(secondary.Get("key")) != baseValue {
+		t.Error("The value should be written in the primary and replicated")
+	}
+
+	if failing.Get("key") != nil || metrics.observed != 2 || metrics.failed != 1 {
+		t.Errorf("The failed replication should be reported, %+v given", metrics)
+	}
+
+	if status := storer.Status(); status[1].Failures != 1 || status[0].Failures != 0 {
+		t.Errorf("The failures should be counted per secondary, %+v given", status)
+	}
+
+	storer.Delete("key")
+
+	if primary.Get("key") != nil || secondary.Get("key") != nil {
+		t.Error("The deletion should be replicated")
+	}
+}
+
+func TestReplicatedStorer_Expirations(t *testing.T) {
+	primary := conditionalStorer{newMemoryStorer()}
+	secondary := newMemoryStorer()
+
+	storer, _ := core.NewReplicatedStorer(primary, []core.Storer{secondary}, core.ReplicationOptions{}, nopLogger{})
+
+	for range 3 {
+		_, _ = core.Increment(storer, "counter", 1, time.Minute)
+	}
+
+	if string(primary.Get("counter")) != "3" || string(secondary.Get("counter")) != "3" {
+		t.Errorf("The counter should be replicated, %s given", secondary.Get("counter"))
+	}
+
+	refreshingPrimary := &refreshingStorer{memoryStorer: newMemoryStorer(), refreshed: map[string]time.Duration{}}
+	refreshingSecondary := &refreshingStorer{memoryStorer: newMemoryStorer(), refreshed: map[string]time.Duration{}}
+
+	storer, _ = core.NewReplicatedStorer(refreshingPrimary, []core.Storer{refreshingSecondary}, core.ReplicationOptions{}, nopLogger{})
+	_ = storer.Set("key", []byte(baseValue), time.Minute)
+
+	if err := core.Touch(storer, "key", time.Hour); err != nil || refreshingSecondary.refreshed["key"] != time.Hour {
+		t.Errorf("The touch should be replicated, %v given", err)
+	}
+
+	if err := core.RefreshTTL(storer, "key", 2*time.Hour); err != nil || refreshingSecondary.refreshed["key"] != 2*time.Hour {
+		t.Errorf("The TTL refresh should be replicated, %v given", err)
+	}
+}
+
+func TestReplicatedStorer_Async(t *testing.T) {
+	primary, secondary := newMemoryStorer(), newMemoryStorer()
+	slow := &gatedStorer{memoryStorer: newMemoryStorer(), started: make(chan struct{}, 10), gate: make(chan struct{})}
+
+	storer, err := core.NewReplicatedStorer(primary, []core.Storer{secondary, slow}, core.ReplicationOptions{Async: true, QueueLength: 1}, nopLogger{})
+	if err != nil {
+		t.Fatalf("Impossible to create the replicated storer, %v", err)
+	}
+
+	// Each write waits for the fast secondary so only the slow one is behind.
+	for _, key := range []string{"first", "second", "third"} {
+		_ = storer.Set(key, []byte(baseValue), time.Minute)
+
+		for deadline := time.Now().Add(time.Second); secondary.Get(key) == nil && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+
+		if secondary.Get(key) == nil {
+			t.Fatalf("The slow secondary shouldn't delay the others, %s is missing", key)
+		}
+
+		if key == "first" {
+			<-slow.started
+		}
+	}
+
+	if status := storer.Status(); status[1].Failures != 1 || status[1].Pending != 1 || status[0].Failures != 0 {
+		t.Errorf("The slow secondary should drop the replications over its queue length, %+v given", status)
+	}
+
+	close(slow.gate)
+
+	if err = storer.Reset(); err != nil {
+		t.Errorf("The reset shouldn't fail, %v given", err)
+	}
+
+	if slow.Get("first") == nil || slow.Get("second") == nil || slow.Get("third") != nil {
+		t.Error("The queued replications should be flushed on reset")
+	}
+}
+
+func TestNewReplicatedStorer(t *testing.T) {
+	if _, err := core.NewReplicatedStorer(newMemoryStorer(), nil, core.ReplicationOptions{}, nopLogger{}); err == nil {
+		t.Error("A replicated storer without secondary should be rejected")
+	}
+
+	if _, err := core.NewReplicatedStorer(newMemoryStorer(), []core.Storer{newMemoryStorer()}, core.ReplicationOptions{QueueLength: -1}, nopLogger{}); err == nil {
+		t.Error("A negative queue length should be rejected")
+	}
+}
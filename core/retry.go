@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	// RetryConfigurationKey is the key read from the provider configuration
+	// to retry the calls failing with a transient error.
+	RetryConfigurationKey = "retry"
+
+	defaultRetryAttempts       = 3
+	defaultRetryInitialBackoff = 50 * time.Millisecond
+	defaultRetryMaxBackoff     = time.Second
+)
+
+// The operations retried by the RetryStorer, used as keys of the per
+// operation attempts.
+const (
+	RetryGet           = "get"
+	RetrySet           = "set"
+	RetrySetMultiLevel = "set_multi_level"
+	RetryInit          = "init"
+)
+
+// RetryOptions tunes the RetryStorer.
+type RetryOptions struct {
+	// Attempts is the number of calls made before giving up, the first one
+	// included.
+	Attempts int `json:"attempts"`
+	// Operations overrides the attempts per operation, e.g. {"set": 5}.
+	Operations map[string]int `json:"operations"`
+	// InitialBackoff is the delay before the second attempt, doubled after
+	// each failure up to MaxBackoff.
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+	// Retryable classifies the errors, IsRetryable when nil.
+	Retryable func(err error) bool `json:"-"`
+}
+
+func defaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		Attempts:       defaultRetryAttempts,
+		InitialBackoff: defaultRetryInitialBackoff,
+		MaxBackoff:     defaultRetryMaxBackoff,
+	}
+}
+
+func (o RetryOptions) validate() error {
+	if o.Attempts <= 0 {
+		return fmt.Errorf("the attempts must be positive, %d given", o.Attempts)
+	}
+
+	for operation, attempts := range o.Operations {
+		switch operation {
+		case RetryGet, RetrySet, RetrySetMultiLevel, RetryInit:
+		default:
+			return fmt.Errorf("unknown operation %s, expected get, set, set_multi_level or init", operation)
+		}
+
+		if attempts <= 0 {
+			return fmt.Errorf("the %s attempts must be positive, %d given", operation, attempts)
+		}
+	}
+
+	if o.InitialBackoff <= 0 || o.MaxBackoff < o.InitialBackoff {
+		return fmt.Errorf("the initial_backoff must be positive and lower than the max_backoff, %s and %s given", o.InitialBackoff, o.MaxBackoff)
+	}
+
+	return nil
+}
+
+// IsRetryable reports whether the error is transient: the timeouts, the
+// broken connections and ErrReconnecting are, the misses, the cancellations
+// and the other errors aren't.
+func IsRetryable(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, ErrKeyNotFound), errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, ErrReconnecting),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, os.ErrDeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryStorer decorates any Storer to retry its reads and writes failing
+// with a retryable error, waiting an exponential backoff between the
+// attempts. The last error is returned once the attempts are exhausted.
+// The deletions return no error and aren't retried.
+type RetryStorer struct {
+	Storer
+
+	options RetryOptions
+	logger  Logger
+}
+
+// NewRetryStorer wraps the storer to retry its failed calls.
+func NewRetryStorer(storer Storer, options RetryOptions, logger Logger) (*RetryStorer, error) {
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid retry configuration: %w", err)
+	}
+
+	if options.Retryable == nil {
+		options.Retryable = IsRetryable
+	}
+
+	return &RetryStorer{Storer: storer, options: options, logger: logger}, nil
+}
+
+// RetryStorerFromConfiguration wraps the storer when the retry key is set in
+// the provider configuration, it returns the storer untouched otherwise.
+func RetryStorerFromConfiguration(storer Storer, provider CacheProvider, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	retryCfg, ok := cfg[RetryConfigurationKey]
+	if !ok {
+		return storer, nil
+	}
+
+	options := defaultRetryOptions()
+	if err := DecodeConfiguration(retryCfg, &options); err != nil {
+		return nil, fmt.Errorf("invalid retry configuration: %w", err)
+	}
+
+	return NewRetryStorer(storer, options, logger)
+}
+
+// Unwrap returns the decorated storer.
+func (s *RetryStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+func (s *RetryStorer) attempts(operation string) int {
+	if attempts, ok := s.options.Operations[operation]; ok {
+		return attempts
+	}
+
+	return s.options.Attempts
+}
+
+// do runs the call until it succeeds, fails with a non retryable error or
+// the attempts of the operation are exhausted.
+func (s *RetryStorer) do(operation string, call func() error) error {
+	backoff := s.options.InitialBackoff
+	attempts := s.attempts(operation)
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = call(); !s.options.Retryable(err) || attempt >= attempts {
+			return err
+		}
+
+		s.logger.Debugf("Retry the %s call on %s in %s, %v", operation, s.Storer.Name(), backoff, err)
+
+		//nolint:gosec // The jitter doesn't need a cryptographic source.
+		time.Sleep(backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1)))
+
+		backoff = min(backoff*2, s.options.MaxBackoff)
+	}
+}
+
+// Get retries the read on a retryable Lookup error.
+func (s *RetryStorer) Get(key string) []byte {
+	value, _ := s.Lookup(key)
+
+	return value
+}
+
+// Lookup retries the read on a retryable error, ErrKeyNotFound isn't.
+func (s *RetryStorer) Lookup(key string) ([]byte, error) {
+	var value []byte
+
+	err := s.do(RetryGet, func() error {
+		var err error
+		value, err = Lookup(s.Storer, key)
+
+		return err
+	})
+
+	return value, err
+}
+
+// Set retries the write on a retryable error.
+func (s *RetryStorer) Set(key string, value []byte, duration time.Duration) error {
+	return s.do(RetrySet, func() error {
+		return s.Storer.Set(key, value, duration)
+	})
+}
+
+// SetMultiLevel retries the write of the value and its mapping on a
+// retryable error.
+func (s *RetryStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	return s.do(RetrySetMultiLevel, func() error {
+		return s.Storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+	})
+}
+
+// Init retries the initialization on a retryable error.
+func (s *RetryStorer) Init() error {
+	return s.do(RetryInit, s.Storer.Init)
+}
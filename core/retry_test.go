@@ -0,0 +1,116 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// transientStorer is a memoryStorer whose writes fail with err until failures
+// reaches zero.
+type transientStorer struct {
+	*memoryStorer
+
+	err      error
+	failures int
+	calls    int
+}
+
+func (f *transientStorer) Set(key string, value []byte, duration time.Duration) error {
+	f.calls++
+
+	if f.failures > 0 {
+		f.failures--
+
+		return f.err
+	}
+
+	return f.memoryStorer.Set(key, value, duration)
+}
+
+func (f *transientStorer) SetMultiLevel(_, variedKey string, value []byte, _ http.Header, _ string, duration time.Duration, _ string) error {
+	return f.Set(variedKey, value, duration)
+}
+
+func retryOptions() core.RetryOptions {
+	return core.RetryOptions{Attempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+}
+
+func TestRetryStorer(t *testing.T) {
+	flaky := &transientStorer{memoryStorer: newMemoryStorer(), err: core.ErrReconnecting, failures: 2}
+
+	storer, err := core.NewRetryStorer(flaky, retryOptions(), nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = storer.Set("key", []byte("value"), time.Minute); err != nil || flaky.calls != 3 {
+		t.Errorf("the transient errors should be retried, %d calls, %v", flaky.calls, err)
+	}
+
+	flaky.calls, flaky.failures = 0, 5
+	if err = storer.Set("key", []byte("value"), time.Minute); !errors.Is(err, core.ErrReconnecting) || flaky.calls != 3 {
+		t.Errorf("the last error should be returned once the attempts are exhausted, %d calls, %v", flaky.calls, err)
+	}
+
+	flaky.calls, flaky.failures, flaky.err = 0, 1, errors.New("invalid value")
+	if err = storer.Set("key", []byte("value"), time.Minute); err == nil || flaky.calls != 1 {
+		t.Errorf("the permanent errors shouldn't be retried, %d calls, %v", flaky.calls, err)
+	}
+
+	if _, err = storer.Lookup("missing"); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("the miss should be returned as is, got %v", err)
+	}
+}
+
+func TestRetryStorer_Operations(t *testing.T) {
+	flaky := &transientStorer{memoryStorer: newMemoryStorer(), err: context.DeadlineExceeded, failures: 5}
+	options := retryOptions()
+	options.Operations = map[string]int{core.RetrySetMultiLevel: 6}
+
+	storer, _ := core.NewRetryStorer(flaky, options, nopLogger{})
+
+	if err := storer.SetMultiLevel("base", "varied", []byte("value"), nil, "", time.Minute, "varied"); err != nil || flaky.calls != 6 {
+		t.Errorf("the operation attempts should override the default ones, %d calls, %v", flaky.calls, err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	for err, expected := range map[error]bool{
+		nil:                      false,
+		core.ErrKeyNotFound:      false,
+		context.Canceled:         false,
+		errors.New("invalid"):    false,
+		core.ErrReconnecting:     true,
+		context.DeadlineExceeded: true,
+		fmt.Errorf("read: %w", core.ErrReconnecting): true,
+	} {
+		if core.IsRetryable(err) != expected {
+			t.Errorf("IsRetryable(%v) should be %v", err, expected)
+		}
+	}
+}
+
+func TestRetryStorerFromConfiguration(t *testing.T) {
+	storer, err := core.RetryStorerFromConfiguration(newMemoryStorer(), core.CacheProvider{Configuration: map[string]interface{}{
+		core.RetryConfigurationKey: map[string]interface{}{"attempts": "5", "initial_backoff": "10ms", "operations": map[string]interface{}{"get": 1}},
+	}}, nopLogger{})
+	if _, ok := storer.(*core.RetryStorer); !ok || err != nil {
+		t.Errorf("the storer should be wrapped, got %T, %v", storer, err)
+	}
+
+	if _, err = core.RetryStorerFromConfiguration(newMemoryStorer(), core.CacheProvider{Configuration: map[string]interface{}{
+		core.RetryConfigurationKey: map[string]interface{}{"operations": map[string]interface{}{"delete": 2}},
+	}}, nopLogger{}); err == nil {
+		t.Error("an unknown operation should be rejected")
+	}
+
+	if storer, _ = core.RetryStorerFromConfiguration(newMemoryStorer(), core.CacheProvider{}, nopLogger{}); storer == nil {
+		t.Error("the storer should be returned untouched without retry")
+	}
+}
@@ -1,10 +1,17 @@
 package core
 
 import (
+	"net/http"
+	"strings"
 	"time"
 )
 
 type Revalidator struct {
+	// AllowNotModified lets MappingElection answer a conditional request
+	// matching a fresh key without loading its body: NotModified is set with
+	// NotModifiedKey, ResponseETag and LastModified, and no response is
+	// returned so the caller synthesizes the 304.
+	AllowNotModified            bool
 	Matched                     bool
 	IfNoneMatchPresent          bool
 	IfMatchPresent              bool
@@ -19,6 +26,50 @@ type Revalidator struct {
 	IfMatch                     []string
 	RequestETags                []string
 	ResponseETag                string
+	NotModifiedKey              string
+	LastModified                time.Time
+	// StaleKey is the varied key of the stale response elected by
+	// MappingElection, to give to RefreshMultiLevel once the origin
+	// revalidated it.
+	StaleKey string
+	// FreshKey is the varied key of the fresh response elected by
+	// MappingElection.
+	FreshKey string
+}
+
+// RequestNotModified reports whether the conditional headers of the GET or
+// HEAD request match the stored response, so a 304 can be sent instead. The
+// If-None-Match header is compared to the etag with the weak comparison and
+// takes precedence over If-Modified-Since, compared to the time the response
+// was stored: its Last-Modified date can't be later.
+func RequestNotModified(req *http.Request, etag string, storedAt time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := req.Header.Values("If-None-Match"); len(ifNoneMatch) > 0 {
+		if etag == "" {
+			return false
+		}
+
+		for _, header := range ifNoneMatch {
+			for _, candidate := range strings.Split(header, ",") {
+				candidate = strings.TrimSpace(candidate)
+				if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || storedAt.IsZero() {
+		return false
+	}
+
+	return !storedAt.Truncate(time.Second).After(ifModifiedSince)
 }
 
 func ValidateETagFromHeader(etag string, validator *Revalidator) {
@@ -0,0 +1,85 @@
+package core_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestRequestNotModified(t *testing.T) {
+	storedAt := time.Date(2024, 1, 1, 12, 0, 0, 500, time.UTC)
+
+	for name, tc := range map[string]struct {
+		method      string
+		headers     map[string]string
+		etag        string
+		notModified bool
+	}{
+		"unconditional":           {headers: map[string]string{}, etag: `"v1"`},
+		"etag match":              {headers: map[string]string{"If-None-Match": `"v0", "v1"`}, etag: `"v1"`, notModified: true},
+		"weak etag match":         {headers: map[string]string{"If-None-Match": `W/"v1"`}, etag: `"v1"`, notModified: true},
+		"any etag":                {headers: map[string]string{"If-None-Match": "*"}, etag: `"v1"`, notModified: true},
+		"etag mismatch":           {headers: map[string]string{"If-None-Match": `"v0"`}, etag: `"v1"`},
+		"no stored etag":          {headers: map[string]string{"If-None-Match": `"v1"`}},
+		"modified since":          {headers: map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 11:59:59 GMT"}},
+		"not modified since":      {headers: map[string]string{"If-Modified-Since": "Mon, 01 Jan 2024 12:00:00 GMT"}, notModified: true},
+		"if-none-match precedes":  {headers: map[string]string{"If-None-Match": `"v0"`, "If-Modified-Since": "Mon, 01 Jan 2024 13:00:00 GMT"}, etag: `"v1"`},
+		"malformed date":          {headers: map[string]string{"If-Modified-Since": "yesterday"}},
+		"unsafe method":           {method: http.MethodPost, headers: map[string]string{"If-None-Match": "*"}, etag: `"v1"`},
+		"head with matching etag": {method: http.MethodHead, headers: map[string]string{"If-None-Match": `"v1"`}, etag: `"v1"`, notModified: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, "/", nil)
+			for header, value := range tc.headers {
+				req.Header.Set(header, value)
+			}
+
+			if core.RequestNotModified(req, tc.etag, storedAt) != tc.notModified {
+				t.Errorf("The request should be not modified: %v", tc.notModified)
+			}
+		})
+	}
+}
+
+func TestMappingElection_NotModified(t *testing.T) {
+	memory := newMemoryStorer()
+	response := []byte("HTTP/1.1 200 OK\r\nEtag: \"v1\"\r\nContent-Length: 5\r\n\r\nHello")
+
+	_ = memory.SetMultiLevel("base", "varied", response, http.Header{}, `"v1"`, time.Minute, "real")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+
+	fresh, _ := memory.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The fresh response should be loaded unless allowed otherwise")
+	}
+
+	memory.Delete("varied")
+
+	validator := &core.Revalidator{AllowNotModified: true}
+
+	fresh, stale := memory.GetMultiLevel("base", req, validator)
+	if fresh != nil || stale != nil || !validator.NotModified {
+		t.Fatal("The election should signal the not modified response without loading it")
+	}
+
+	if validator.NotModifiedKey != "varied" || validator.ResponseETag != `"v1"` || validator.LastModified.IsZero() {
+		t.Errorf("The elected key metadata should be set, %+v given", validator)
+	}
+
+	req.Header.Set("If-None-Match", `"v0"`)
+
+	validator = &core.Revalidator{AllowNotModified: true}
+	if _, _ = memory.GetMultiLevel("base", req, validator); validator.NotModified {
+		t.Error("The mismatching conditional request shouldn't be not modified")
+	}
+}
@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is the set of the values matched by a cron field, one bit per value.
+type field uint64
+
+func (f field) has(value int) bool {
+	return f&(1<<uint(value)) != 0
+}
+
+// bounds are the values allowed in a cron field, with their names.
+type bounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondBounds = bounds{name: "second", min: 0, max: 59}
+	minuteBounds = bounds{name: "minute", min: 0, max: 59}
+	hourBounds   = bounds{name: "hour", min: 0, max: 23}
+	dayBounds    = bounds{name: "day of month", min: 1, max: 31}
+	monthBounds  = bounds{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// The day of week 7 is Sunday too.
+	weekdayBounds = bounds{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros are the shortcuts of the usual schedules.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxSearchYears bounds the search of the next activation, a schedule never
+// matching a date, e.g. on February 30th, has none.
+const maxSearchYears = 5
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	seconds  field
+	minutes  field
+	hours    field
+	days     field
+	months   field
+	weekdays field
+	// anyDay and anyWeekday tell whether the day fields are wildcards, the
+	// days match either field when both are restricted.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// ParseSchedule parses the cron expression: minute, hour, day of month,
+// month and day of week, preceded by the second when six fields are given.
+// The fields accept *, the lists (1,15), the ranges (1-5), the steps (*/10
+// or 8-18/2) and the month and day names (jan, mon). The @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight and @hourly macros are
+// accepted too.
+func ParseSchedule(expression string) (Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, found := macros[strings.ToLower(expression)]; found {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return Schedule{}, fmt.Errorf("invalid schedule %q: 5 or 6 fields expected, %d given", expression, len(fields))
+	}
+
+	var (
+		schedule Schedule
+		err      error
+	)
+
+	for index, target := range []struct {
+		field  *field
+		bounds bounds
+	}{
+		{field: &schedule.seconds, bounds: secondBounds},
+		{field: &schedule.minutes, bounds: minuteBounds},
+		{field: &schedule.hours, bounds: hourBounds},
+		{field: &schedule.days, bounds: dayBounds},
+		{field: &schedule.months, bounds: monthBounds},
+		{field: &schedule.weekdays, bounds: weekdayBounds},
+	} {
+		if *target.field, err = parseField(fields[index], target.bounds); err != nil {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: %w", expression, err)
+		}
+	}
+
+	if schedule.weekdays.has(7) {
+		schedule.weekdays |= 1
+	}
+
+	schedule.anyDay = strings.HasPrefix(fields[3], "*")
+	schedule.anyWeekday = strings.HasPrefix(fields[5], "*")
+
+	return schedule, nil
+}
+
+// parseField parses the comma separated list of a cron field.
+func parseField(expression string, b bounds) (field, error) {
+	var result field
+
+	for _, part := range strings.Split(expression, ",") {
+		values, step, found := strings.Cut(part, "/")
+
+		increment := 1
+
+		if found {
+			parsed, err := strconv.Atoi(step)
+			if err != nil || parsed <= 0 {
+				return 0, fmt.Errorf("the %s step %q must be a positive number", b.name, step)
+			}
+
+			increment = parsed
+		}
+
+		start, end := b.min, b.max
+
+		if values != "*" {
+			low, high, isRange := strings.Cut(values, "-")
+
+			var err error
+			if start, err = parseValue(low, b); err != nil {
+				return 0, err
+			}
+
+			end = start
+
+			if isRange {
+				if end, err = parseValue(high, b); err != nil {
+					return 0, err
+				}
+			} else if found {
+				end = b.max
+			}
+
+			if start > end {
+				return 0, fmt.Errorf("the %s range %q is reversed", b.name, values)
+			}
+		}
+
+		for value := start; value <= end; value += increment {
+			result |= 1 << uint(value)
+		}
+	}
+
+	return result, nil
+}
+
+// parseValue parses a number or a name of a cron field.
+func parseValue(value string, b bounds) (int, error) {
+	if named, found := b.names[strings.ToLower(value)]; found {
+		return named, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < b.min || parsed > b.max {
+		return 0, fmt.Errorf("the %s %q must be between %d and %d", b.name, value, b.min, b.max)
+	}
+
+	return parsed, nil
+}
+
+// matchesDay tells whether the schedule runs on the day of the date.
+func (s Schedule) matchesDay(date time.Time) bool {
+	day := s.days.has(date.Day())
+	weekday := s.weekdays.has(int(date.Weekday()))
+
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Next returns the first activation strictly after the given time, in its
+// location. It returns the zero time when the schedule never matches.
+func (s Schedule) Next(after time.Time) time.Time {
+	location := after.Location()
+	next := after.Truncate(time.Second).Add(time.Second)
+	limit := next.AddDate(maxSearchYears, 0, 0)
+
+	for next.Before(limit) {
+		switch {
+		case !s.months.has(int(next.Month())):
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, location)
+		case !s.matchesDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, location)
+		case !s.hours.has(next.Hour()):
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, location)
+		case !s.minutes.has(next.Minute()):
+			next = next.Truncate(time.Minute).Add(time.Minute)
+		case !s.seconds.has(next.Second()):
+			next = next.Add(time.Second)
+		default:
+			return next
+		}
+	}
+
+	return time.Time{}
+}
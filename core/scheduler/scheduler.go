@@ -0,0 +1,269 @@
+// Package scheduler runs the purge rules declared by the operators against a
+// storage, e.g. every night at 03:00 purge the keys matching
+// ^/api/reports/.*. Each rule has a cron schedule and selects the keys by
+// prefix, glob, regular expression or surrogate key tag, the vocabulary of
+// the admin API.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// Rule is a purge rule declared in the configuration.
+type Rule struct {
+	// Name identifies the rule in the logs and in Purge, the index of the
+	// rule by default.
+	Name string `json:"name"`
+	// Schedule is the cron expression of the rule runs.
+	Schedule string `json:"schedule"`
+	// Timezone is the IANA location the schedule is evaluated in, the local
+	// time by default.
+	Timezone string `json:"timezone"`
+	// Exactly one of Prefix, Glob, Regex and Tag selects the purged keys.
+	Prefix string `json:"prefix"`
+	Glob   string `json:"glob"`
+	Regex  string `json:"regex"`
+	Tag    string `json:"tag"`
+}
+
+// rule is a validated Rule with its next run.
+type rule struct {
+	name     string
+	schedule Schedule
+	location *time.Location
+	matcher  core.KeyMatcher
+	tag      string
+	next     time.Time
+}
+
+// Scheduler runs the purge rules against a storage on their schedule.
+type Scheduler struct {
+	storer core.Storer
+	logger core.Logger
+	rules  []*rule
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+type purgeScheduleConfiguration struct {
+	PurgeSchedule []Rule         `json:"purge_schedule"`
+	Others        map[string]any `json:",remain"`
+}
+
+// New validates the rules and returns their scheduler, Start runs them.
+func New(storer core.Storer, rules []Rule, logger core.Logger) (*Scheduler, error) {
+	scheduler := &Scheduler{storer: storer, logger: logger}
+	names := make(map[string]bool, len(rules))
+
+	for index, declared := range rules {
+		if declared.Name == "" {
+			declared.Name = fmt.Sprint(index)
+		}
+
+		if names[declared.Name] {
+			return nil, fmt.Errorf("invalid purge_schedule configuration: the rule %s is declared twice", declared.Name)
+		}
+
+		names[declared.Name] = true
+
+		parsed, err := newRule(declared)
+		if err != nil {
+			return nil, fmt.Errorf("invalid purge_schedule configuration: the rule %s: %w", declared.Name, err)
+		}
+
+		scheduler.rules = append(scheduler.rules, parsed)
+	}
+
+	return scheduler, nil
+}
+
+// FromConfiguration returns the scheduler of the rules declared under the
+// purge_schedule key of the provider configuration, nil when absent.
+func FromConfiguration(storer core.Storer, provider core.CacheProvider, logger core.Logger) (*Scheduler, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok || cfg[core.PurgeScheduleConfigurationKey] == nil {
+		return nil, nil
+	}
+
+	var configuration purgeScheduleConfiguration
+	if err := core.DecodeConfiguration(cfg, &configuration); err != nil {
+		return nil, fmt.Errorf("invalid purge_schedule configuration: %w", err)
+	}
+
+	return New(storer, configuration.PurgeSchedule, logger)
+}
+
+func newRule(declared Rule) (*rule, error) {
+	schedule, err := ParseSchedule(declared.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	location := time.Local
+
+	if declared.Timezone != "" {
+		if location, err = time.LoadLocation(declared.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %s: %w", declared.Timezone, err)
+		}
+	}
+
+	parsed := &rule{name: declared.Name, schedule: schedule, location: location}
+	selectors := 0
+
+	for _, selector := range []struct {
+		mode    core.MatchMode
+		pattern string
+	}{
+		{mode: core.MatchPrefix, pattern: declared.Prefix},
+		{mode: core.MatchGlob, pattern: declared.Glob},
+		{mode: core.MatchRegex, pattern: declared.Regex},
+	} {
+		if selector.pattern == "" {
+			continue
+		}
+
+		selectors++
+
+		if parsed.matcher, err = core.NewKeyMatcher(selector.mode, selector.pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	if declared.Tag != "" {
+		selectors++
+		parsed.tag = declared.Tag
+	}
+
+	if selectors != 1 {
+		return nil, errors.New("exactly one of prefix, glob, regex and tag must be set")
+	}
+
+	return parsed, nil
+}
+
+// Start runs the rules on their schedule until Stop is called.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+
+	now := time.Now()
+	for _, r := range s.rules {
+		r.next = r.schedule.Next(now.In(r.location))
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.running = true
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops the runs and waits for the running purges to finish.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.running = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+}
+
+func (s *Scheduler) run(stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		earliest := time.Time{}
+
+		for _, r := range s.rules {
+			if !r.next.IsZero() && (earliest.IsZero() || r.next.Before(earliest)) {
+				earliest = r.next
+			}
+		}
+
+		if earliest.IsZero() {
+			<-stop
+
+			return
+		}
+
+		timer := time.NewTimer(time.Until(earliest))
+
+		select {
+		case <-stop:
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+
+		for _, r := range s.rules {
+			if r.next.IsZero() || r.next.After(now) {
+				continue
+			}
+
+			s.execute(r)
+			r.next = r.schedule.Next(now.In(r.location))
+		}
+	}
+}
+
+// execute runs the rule and logs its outcome.
+func (s *Scheduler) execute(r *rule) {
+	result, err := s.purge(r)
+	if err != nil {
+		s.logger.Errorf("Impossible to run the purge rule %s, %v", r.name, err)
+
+		return
+	}
+
+	s.logger.Infof("The purge rule %s deleted %d keys", r.name, result.Count)
+}
+
+// Purge runs the named rule immediately, outside of its schedule.
+func (s *Scheduler) Purge(name string) (core.DeleteManyResult, error) {
+	for _, r := range s.rules {
+		if r.name == name {
+			return s.purge(r)
+		}
+	}
+
+	return core.DeleteManyResult{}, fmt.Errorf("unknown purge rule %s", name)
+}
+
+func (s *Scheduler) purge(r *rule) (core.DeleteManyResult, error) {
+	if r.tag == "" {
+		return core.DeleteMatching(s.storer, r.matcher, false)
+	}
+
+	result := core.DeleteManyResult{}
+
+	for _, key := range core.SurrogateKeys(s.storer, r.tag) {
+		s.storer.Delete(key)
+		result.Add(key, false)
+	}
+
+	s.storer.Delete(core.SurrogateKeyPrefix + r.tag)
+
+	return result, nil
+}
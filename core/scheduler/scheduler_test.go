@@ -0,0 +1,143 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/mock"
+	"github.com/darkweak/storages/core/scheduler"
+)
+
+type nopLogger struct {
+	core.Logger
+}
+
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+func (nopLogger) Infof(string, ...interface{}) {}
+
+func TestSchedule_Next(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("The time zone database is not available")
+	}
+
+	// Thursday.
+	from := time.Date(2026, time.January, 15, 10, 30, 15, 0, time.UTC)
+
+	for expression, expected := range map[string]time.Time{
+		"0 3 * * *":         time.Date(2026, time.January, 16, 3, 0, 0, 0, time.UTC),
+		"*/20 * * * *":      time.Date(2026, time.January, 15, 10, 40, 0, 0, time.UTC),
+		"30 8-18/4 * * *":   time.Date(2026, time.January, 15, 12, 30, 0, 0, time.UTC),
+		"0 0 * * mon":       time.Date(2026, time.January, 19, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":         time.Date(2026, time.January, 18, 0, 0, 0, 0, time.UTC),
+		"0 0 1 * mon":       time.Date(2026, time.January, 19, 0, 0, 0, 0, time.UTC),
+		"0 0 29 feb *":      time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"@monthly":          time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"*/10 * * * * *":    time.Date(2026, time.January, 15, 10, 30, 20, 0, time.UTC),
+		"0 0 30 feb *":      {},
+		"15,45 10 15 1 *":   time.Date(2026, time.January, 15, 10, 45, 0, 0, time.UTC),
+		"0 0 1 jan-mar/2 *": time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		schedule, err := scheduler.ParseSchedule(expression)
+		if err != nil {
+			t.Fatalf("The schedule %q should be valid, %v given", expression, err)
+		}
+
+		if next := schedule.Next(from); !next.Equal(expected) {
+			t.Errorf("The schedule %q should run at %s after %s, %s given", expression, expected, from, next)
+		}
+	}
+
+	schedule, _ := scheduler.ParseSchedule("0 3 * * *")
+	if next := schedule.Next(from.In(paris)); !next.Equal(time.Date(2026, time.January, 16, 3, 0, 0, 0, paris)) {
+		t.Errorf("The schedule should run at 03:00 in the given location, %s given", next)
+	}
+
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * foo *"} {
+		if _, err := scheduler.ParseSchedule(expression); err == nil {
+			t.Errorf("The schedule %q should be rejected", expression)
+		}
+	}
+}
+
+func TestScheduler_Purge(t *testing.T) {
+	storer := mock.New(0, nopLogger{})
+	for _, key := range []string{"/api/reports/1", "/api/reports/2", "/api/users/1", "tagged"} {
+		_ = storer.Set(key, []byte("value"), time.Hour)
+	}
+
+	_ = storer.Set(core.SurrogateKeyPrefix+"users", []byte("%2Fapi%2Fusers%2F1,tagged"), time.Hour)
+
+	s, err := scheduler.New(storer, []scheduler.Rule{
+		{Name: "reports", Schedule: "0 3 * * *", Regex: "^/api/reports/.*"},
+		{Name: "users", Schedule: "@weekly", Tag: "users"},
+	}, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := s.Purge("reports"); err != nil || result.Count != 2 {
+		t.Errorf("The reports rule should purge 2 keys, %v and %v given", result, err)
+	}
+
+	if result, err := s.Purge("users"); err != nil || result.Count != 2 {
+		t.Errorf("The users rule should purge 2 keys, %v and %v given", result, err)
+	}
+
+	if keys := storer.MapKeys(""); len(keys) != 0 {
+		t.Errorf("Every key should be purged, %v given", keys)
+	}
+
+	if _, err := s.Purge("unknown"); err == nil {
+		t.Error("An unknown rule should be rejected")
+	}
+
+	for _, rules := range [][]scheduler.Rule{
+		{{Schedule: "0 3 * * *"}},
+		{{Schedule: "0 3 * * *", Prefix: "/api", Tag: "users"}},
+		{{Schedule: "0 3 * * *", Regex: "("}},
+		{{Schedule: "0 3 * * *", Prefix: "/api", Timezone: "Nowhere/Unknown"}},
+		{{Name: "twice", Schedule: "@daily", Prefix: "/a"}, {Name: "twice", Schedule: "@daily", Prefix: "/b"}},
+	} {
+		if _, err := scheduler.New(storer, rules, nopLogger{}); err == nil {
+			t.Errorf("The rules %v should be rejected", rules)
+		}
+	}
+}
+
+func TestScheduler_Start(t *testing.T) {
+	storer := mock.New(0, nopLogger{})
+	_ = storer.Set("/api/reports/1", []byte("value"), time.Hour)
+	_ = storer.Set("/api/users/1", []byte("value"), time.Hour)
+
+	s, err := scheduler.FromConfiguration(storer, core.CacheProvider{Configuration: map[string]interface{}{
+		"purge_schedule": []interface{}{
+			map[string]interface{}{"schedule": "* * * * * *", "glob": "/api/reports/*"},
+		},
+	}}, nopLogger{})
+	if err != nil || s == nil {
+		t.Fatalf("The scheduler should be created, %v given", err)
+	}
+
+	s.Start()
+	defer s.Stop()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(storer.Get("/api/reports/1")) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("The rule should run every second")
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if len(storer.Get("/api/users/1")) == 0 {
+		t.Error("The keys not matched by the rule should be kept")
+	}
+
+	if s, err = scheduler.FromConfiguration(storer, core.CacheProvider{}, nopLogger{}); s != nil || err != nil {
+		t.Errorf("No scheduler should be created without configuration, %v given", err)
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// sharedSetting guards a setting shared by every storage of the process,
+// such as the vary normalization, against the provider configurations
+// declaring different values. The first configured value is kept, the
+// storages configuring another one are rejected instead of silently
+// overriding it.
+type sharedSetting struct {
+	mu         sync.Mutex
+	key        string
+	configured any
+}
+
+// configure applies the value declared by a provider configuration, it
+// fails when another storage configured a different one.
+func (s *sharedSetting) configure(value any, apply func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.configured != nil && !reflect.DeepEqual(s.configured, value) {
+		return fmt.Errorf(
+			"invalid %s configuration: the setting is shared by the storages of the process, %+v conflicts with the %+v configured by another storage",
+			s.key, value, s.configured,
+		)
+	}
+
+	s.configured = value
+
+	apply()
+
+	return nil
+}
+
+// reset forgets the configured value, when the setting is changed without
+// configuration.
+func (s *sharedSetting) reset() {
+	s.mu.Lock()
+	s.configured = nil
+	s.mu.Unlock()
+}
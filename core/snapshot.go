@@ -0,0 +1,56 @@
+package core
+
+import "io"
+
+// Snapshotter is an optional interface an embedded Storer can implement to
+// survive the process restarts or to be copied between hosts: Snapshot
+// writes every entry to w and Restore stores the entries of a snapshot,
+// keeping the current ones.
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+	Restore(r io.Reader) error
+}
+
+// snapshotterFor returns the Snapshotter implemented by the storer or one
+// of the storers it decorates.
+func snapshotterFor(storer Storer) (Snapshotter, bool) {
+	for storer != nil {
+		if snapshotter, ok := storer.(Snapshotter); ok {
+			return snapshotter, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// Snapshot writes the storer entries to w with its Snapshot method when it
+// implements Snapshotter, as a Dump export otherwise.
+func Snapshot(storer Storer, w io.Writer) error {
+	if snapshotter, ok := snapshotterFor(storer); ok {
+		return snapshotter.Snapshot(w)
+	}
+
+	_, err := Dump(storer, w)
+
+	return err
+}
+
+// Restore stores the entries of a Snapshot with the storer Restore method
+// when it implements Snapshotter, as a Dump export otherwise. The snapshot
+// must come from the same storage.
+func Restore(storer Storer, r io.Reader) error {
+	if snapshotter, ok := snapshotterFor(storer); ok {
+		return snapshotter.Restore(r)
+	}
+
+	_, err := Warmup(storer, r)
+
+	return err
+}
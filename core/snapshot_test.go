@@ -0,0 +1,65 @@
+package core_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+// snapshottingStorer records its snapshots and restorations.
+type snapshottingStorer struct {
+	*memoryStorer
+
+	snapshots, restorations int
+}
+
+func (s *snapshottingStorer) Snapshot(w io.Writer) error {
+	s.snapshots++
+
+	_, err := w.Write([]byte("native"))
+
+	return err
+}
+
+func (s *snapshottingStorer) Restore(r io.Reader) error {
+	s.restorations++
+
+	_, err := io.ReadAll(r)
+
+	return err
+}
+
+func TestSnapshot(t *testing.T) {
+	native := &snapshottingStorer{memoryStorer: newMemoryStorer()}
+	storer, _ := core.NewJitterStorer(native, 0.1)
+
+	var snapshot bytes.Buffer
+
+	if err := core.Snapshot(storer, &snapshot); err != nil || snapshot.String() != "native" || native.snapshots != 1 {
+		t.Errorf("The decorated Snapshotter should write the snapshot, %q given, %v", snapshot.String(), err)
+	}
+
+	if err := core.Restore(storer, &snapshot); err != nil || native.restorations != 1 {
+		t.Errorf("The decorated Snapshotter should restore the snapshot, %v", err)
+	}
+}
+
+func TestSnapshotFallback(t *testing.T) {
+	memory := newMemoryStorer()
+
+	var snapshot bytes.Buffer
+
+	if err := core.Snapshot(memory, &snapshot); err != nil || snapshot.Len() != 0 {
+		t.Fatalf("The storer without Snapshotter should be dumped, %q given, %v", snapshot.String(), err)
+	}
+
+	if err := core.Restore(memory, bytes.NewBufferString(`{"key":"restored","ttl":60000000000,"value":"TXkgZmlyc3QgZGF0YQ=="}`)); err != nil {
+		t.Fatalf("The dump should be restored, %v", err)
+	}
+
+	if string(memory.Get("restored")) != baseValue {
+		t.Errorf("The dumped entry should be stored, %s given", memory.Get("restored"))
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// softPurgeMapping ends the fresh window of the entries selected by match,
+// they're only served as stale until their stale time. It returns the
+// purged keys and the latest stale time of the entries.
+func softPurgeMapping(mapping *StorageMapper, now time.Time, match func(key string, index *KeyIndex) bool) ([]string, time.Time) {
+	purged := []string{}
+	staleUntil := now
+
+	for key, index := range mapping.GetMapping() {
+		staleTime := index.GetStaleTime().AsTime()
+		if staleTime.After(staleUntil) {
+			staleUntil = staleTime
+		}
+
+		if !index.GetFreshTime().AsTime().After(now) || !staleTime.After(now) || !match(key, index) {
+			continue
+		}
+
+		index.FreshTime = timestamppb.New(now)
+		purged = append(purged, key)
+	}
+
+	return purged, staleUntil
+}
+
+// SoftPurgeMapping marks stale the entries of the mapping stored under
+// mappingKey selected by match, it returns their keys. The mapping is read
+// again and the purge retried when it's updated meanwhile.
+func SoftPurgeMapping(storer Storer, mappingKey string, match func(key string, index *KeyIndex) bool) ([]string, error) {
+	var purged []string
+
+	err := UpdateMapping(func() error {
+		purged = nil
+
+		item, err := Lookup(storer, mappingKey)
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		mapping, err := DecodeMapping(item)
+		if err != nil {
+			return fmt.Errorf("impossible to decode the mapping %s: %w", mappingKey, err)
+		}
+
+		keys, staleUntil := softPurgeMapping(mapping, time.Now(), match)
+		if len(keys) == 0 {
+			return nil
+		}
+
+		mapping.Version++
+
+		value, err := EncodeMapping(mapping)
+		if err != nil {
+			return err
+		}
+
+		if err = swapMapping(storer, mappingKey, item, value, time.Until(staleUntil)); err != nil {
+			if errors.Is(err, ErrMappingConflict) {
+				return err
+			}
+
+			return fmt.Errorf("impossible to store the soft purged mapping %s: %w", mappingKey, err)
+		}
+
+		purged = keys
+
+		return nil
+	})
+
+	return purged, err
+}
+
+// SoftDelete marks stale the responses stored under the key, instead of
+// deleting them. The key is a base key, a varied key or a real key. The
+// responses are then served as stale until their stale time, so the next
+// requests revalidate them with the origin rather than missing at once.
+func SoftDelete(storer Storer, key string) (DeleteManyResult, error) {
+	return SoftDeleteMany(storer, ExactKey(key))
+}
+
+// SoftDeleteMany marks stale the responses whose base key, varied key or
+// real key is selected by the matcher, it reports how many varied keys
+// were marked stale. The responses already stale are left untouched.
+func SoftDeleteMany(storer Storer, matcher KeyMatcher) (DeleteManyResult, error) {
+	result := DeleteManyResult{}
+
+	if matcher.regex == nil {
+		return result, errors.New("the key matcher is not initialized")
+	}
+
+	baseKeys := []string{}
+
+	walkErr := walkMappings(storer, func(key string, _ []byte) bool {
+		baseKeys = append(baseKeys, key)
+
+		return true
+	})
+
+	slices.Sort(baseKeys)
+
+	errs := []error{walkErr}
+
+	for _, baseKey := range baseKeys {
+		wholeMapping := matcher.Match(baseKey)
+
+		purged, err := SoftPurgeMapping(storer, MappingKeyFor(storer, baseKey), func(key string, index *KeyIndex) bool {
+			return wholeMapping || matcher.Match(key) || (index.GetRealKey() != "" && matcher.Match(index.GetRealKey()))
+		})
+		errs = append(errs, err)
+		result.Count += len(purged)
+	}
+
+	return result, errors.Join(errs...)
+}
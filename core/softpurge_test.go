@@ -0,0 +1,133 @@
+package core_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func storeSoftPurgeMapping(t *testing.T, storer core.Storer, baseKey string, variedKeys ...string) {
+	t.Helper()
+
+	now := time.Now()
+
+	var (
+		mapping []byte
+		err     error
+	)
+
+	for _, variedKey := range variedKeys {
+		mapping, err = core.MappingUpdater(variedKey, mapping, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", variedKey+"-real")
+		if err != nil {
+			t.Fatalf("impossible to build the mapping, %v", err)
+		}
+	}
+
+	_ = storer.Set(core.MappingKeyPrefix+baseKey, mapping, time.Hour)
+}
+
+func freshKeys(t *testing.T, storer core.Storer, baseKey string) []string {
+	t.Helper()
+
+	mapping, err := core.DecodeMapping(storer.Get(core.MappingKeyPrefix + baseKey))
+	if err != nil {
+		t.Fatalf("impossible to decode the mapping, %v", err)
+	}
+
+	keys := []string{}
+
+	for key, index := range mapping.GetMapping() {
+		if index.GetFreshTime().AsTime().After(time.Now()) {
+			keys = append(keys, key)
+		}
+
+		if !index.GetStaleTime().AsTime().After(time.Now()) {
+			t.Errorf("the stale window of %s should be kept", key)
+		}
+	}
+
+	return keys
+}
+
+func TestSoftDelete(t *testing.T) {
+	storer := newMemoryStorer()
+	storeSoftPurgeMapping(t, storer, "base", "base-gzip", "base-br")
+
+	result, err := core.SoftDelete(storer, "base-gzip")
+	if err != nil || result.Count != 1 {
+		t.Fatalf("the varied key should be marked stale, got %+v, %v", result, err)
+	}
+
+	if fresh := freshKeys(t, storer, "base"); len(fresh) != 1 || fresh[0] != "base-br" {
+		t.Errorf("only the other varied key should stay fresh, got %v", fresh)
+	}
+
+	if result, _ = core.SoftDelete(storer, "base-gzip"); result.Count != 0 {
+		t.Errorf("a stale entry shouldn't be purged again, got %d", result.Count)
+	}
+
+	if result, _ = core.SoftDelete(storer, "base"); result.Count != 1 || len(freshKeys(t, storer, "base")) != 0 {
+		t.Errorf("the base key should mark stale the whole mapping, got %d", result.Count)
+	}
+
+	if core.MappingVersion(storer.Get(core.MappingKeyPrefix+"base")) == 0 {
+		t.Error("the mapping version should be incremented")
+	}
+}
+
+func TestSoftDeleteMany(t *testing.T) {
+	storer := newMemoryStorer()
+	storeSoftPurgeMapping(t, storer, "first", "first-a", "first-b")
+	storeSoftPurgeMapping(t, storer, "second", "second-a")
+
+	result, err := core.SoftDeleteMany(storer, core.KeysWithPrefix("first"))
+	if err != nil || result.Count != 2 {
+		t.Fatalf("the mapping matching the prefix should be marked stale, got %+v, %v", result, err)
+	}
+
+	if len(freshKeys(t, storer, "first")) != 0 || len(freshKeys(t, storer, "second")) != 1 {
+		t.Error("only the matching mapping should be marked stale")
+	}
+
+	if result, _ = core.SoftDeleteMany(storer, core.ExactKey("second-a-real")); result.Count != 1 || len(freshKeys(t, storer, "second")) != 0 {
+		t.Errorf("the real key should select its entry, got %d", result.Count)
+	}
+
+	if _, err = core.SoftDeleteMany(storer, core.KeyMatcher{}); err == nil {
+		t.Error("an uninitialized matcher should be rejected")
+	}
+}
+
+// taggedWalkingStorer walks the mappings stored behind their hash tag.
+type taggedWalkingStorer struct {
+	taggedMappingStorer
+}
+
+func (s taggedWalkingStorer) WalkMappings(prefix string, fn func(key string, value []byte) bool) error {
+	for key, value := range s.MapKeys("{") {
+		if _, baseKey, found := strings.Cut(key, "}"+prefix); found && !fn(baseKey, []byte(value)) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func TestSoftDelete_MappingKey(t *testing.T) {
+	storer := taggedWalkingStorer{taggedMappingStorer{&refreshingStorer{memoryStorer: newMemoryStorer()}}}
+	storeSoftPurgeMapping(t, storer.memoryStorer, "base", "{base}base-gzip")
+
+	mapping := storer.Get(core.MappingKeyPrefix + "base")
+	storer.Delete(core.MappingKeyPrefix + "base")
+	_ = storer.Set(storer.MappingKey("base"), mapping, time.Hour)
+
+	if result, err := core.SoftDelete(storer, "base"); err != nil || result.Count != 1 {
+		t.Fatalf("the mapping behind the hash tag should be marked stale, got %+v, %v", result, err)
+	}
+
+	if metadata, _ := core.DecodeMetadata(storer.Get(storer.MappingKey("base"))); len(metadata) != 1 || metadata[0].Fresh(time.Now()) {
+		t.Errorf("the entry behind the hash tag should be stale, %+v given", metadata)
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import "sync/atomic"
+
+// StorerStats describes how big a storage is and how well it serves the
+// reads. The fields a backend can't report are left to zero.
+type StorerStats struct {
+	// Entries is the number of stored keys, mappings included.
+	Entries int64 `json:"entries"`
+	// Bytes is the memory or the disk space used by the storage.
+	Bytes int64 `json:"bytes"`
+	// Evictions is the number of keys removed to make room for others.
+	Evictions int64   `json:"evictions"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	HitRatio  float64 `json:"hit_ratio"`
+	// Analytics are filled by the AnalyticsStorer.
+	Analytics *KeyAnalytics `json:"analytics,omitempty"`
+}
+
+// StatsReporter is an optional interface a Storer can implement to report
+// its size and its hit ratio.
+type StatsReporter interface {
+	Stats() StorerStats
+}
+
+// Stats returns the stats of the first storer implementing StatsReporter,
+// walking down the decorators. The boolean is false when none does.
+func Stats(storer Storer) (StorerStats, bool) {
+	for storer != nil {
+		if reporter, ok := storer.(StatsReporter); ok {
+			return reporter.Stats(), true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return StorerStats{}, false
+}
+
+// HitCounter counts the hits and the misses of the storages whose backend
+// doesn't, the zero value is ready to use.
+type HitCounter struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Record counts a hit when found, a miss otherwise.
+func (c *HitCounter) Record(found bool) {
+	if found {
+		c.hits.Add(1)
+
+		return
+	}
+
+	c.misses.Add(1)
+}
+
+// Stats returns the stats filled with the counted hits, misses and ratio.
+func (c *HitCounter) Stats() StorerStats {
+	return NewStorerStats(c.hits.Load(), c.misses.Load())
+}
+
+// NewStorerStats returns the stats filled with the hits, the misses and the
+// hit ratio they give.
+func NewStorerStats(hits, misses int64) StorerStats {
+	stats := StorerStats{Hits: hits, Misses: misses}
+
+	if total := hits + misses; total > 0 {
+		stats.HitRatio = float64(hits) / float64(total)
+	}
+
+	return stats
+}
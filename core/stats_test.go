@@ -0,0 +1,46 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+type reportingStorer struct {
+	*memoryStorer
+	core.HitCounter
+}
+
+func (r *reportingStorer) Stats() core.StorerStats {
+	stats := r.HitCounter.Stats()
+	stats.Entries = 2
+
+	return stats
+}
+
+func TestStats(t *testing.T) {
+	if _, found := core.Stats(newMemoryStorer()); found {
+		t.Error("A storer without stats shouldn't report any")
+	}
+
+	reporting := &reportingStorer{memoryStorer: newMemoryStorer()}
+	reporting.Record(true)
+	reporting.Record(true)
+	reporting.Record(true)
+	reporting.Record(false)
+
+	stats, found := core.Stats(core.NewInstrumentedStorer(reporting, &recordedMetrics{operations: map[string]int{}}))
+	if !found {
+		t.Fatal("The decorated storer stats should be reported")
+	}
+
+	if stats.Entries != 2 || stats.Hits != 3 || stats.Misses != 1 || stats.HitRatio != 0.75 {
+		t.Errorf("Unexpected stats, %+v given", stats)
+	}
+}
+
+func TestNewStorerStats(t *testing.T) {
+	if stats := core.NewStorerStats(0, 0); stats.HitRatio != 0 {
+		t.Errorf("The hit ratio should be 0 without lookups, %v given", stats.HitRatio)
+	}
+}
@@ -0,0 +1,154 @@
+package core_test
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const (
+	byteKey   = "MyByteKey"
+	baseValue = "My first data"
+)
+
+type nopLogger struct {
+	core.Logger
+}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// memoryStorer is a minimal map based Storer used to exercise the core
+// decorators.
+type memoryStorer struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+func newMemoryStorer() *memoryStorer {
+	return &memoryStorer{values: map[string][]byte{}}
+}
+
+func (m *memoryStorer) Name() string {
+	return "MEMORY"
+}
+
+func (m *memoryStorer) Uuid() string {
+	return ""
+}
+
+func (m *memoryStorer) Init() error {
+	return nil
+}
+
+func (m *memoryStorer) Reset() error {
+	return nil
+}
+
+func (m *memoryStorer) MapKeys(prefix string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := map[string]string{}
+
+	for key, value := range m.values {
+		if k, found := strings.CutPrefix(key, prefix); found {
+			keys[k] = string(value)
+		}
+	}
+
+	return keys
+}
+
+func (m *memoryStorer) ListKeys() []string {
+	keys := []string{}
+
+	for key := range m.MapKeys("") {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (m *memoryStorer) Get(key string) []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.values[key]
+}
+
+func (m *memoryStorer) Set(key string, value []byte, _ time.Duration) error {
+	if key == "" {
+		return errors.New("empty key")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = value
+
+	return nil
+}
+
+func (m *memoryStorer) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+}
+
+func (m *memoryStorer) DeleteMany(key string) {
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.values {
+		if rgKey.MatchString(k) {
+			delete(m.values, k)
+		}
+	}
+}
+
+func (m *memoryStorer) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale, _ = core.MappingElection(m, m.Get(core.MappingKeyPrefix+key), req, validator, nopLogger{})
+
+	return fresh, stale
+}
+
+func (m *memoryStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressor, err := core.NewCompressor(core.NoCompression)
+	if err != nil {
+		return err
+	}
+
+	stored, err := compressor.Compress(value)
+	if err != nil {
+		return err
+	}
+
+	if err = m.Set(variedKey, stored, duration); err != nil {
+		return err
+	}
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	val, err := core.MappingUpdater(variedKey, m.Get(mappingKey), nopLogger{}, now, now.Add(duration), now.Add(duration), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	return m.Set(mappingKey, val, 0)
+}
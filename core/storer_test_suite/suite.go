@@ -0,0 +1,334 @@
+// Package storertestsuite runs the conformance tests every storer must pass,
+// so the providers, including the third-party ones, prove they respect the
+// core.Storer contract.
+//
+//	func TestConformance(t *testing.T) {
+//		storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+//			storer, err := Factory(core.CacheProvider{}, logger, stale)
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//
+//			return storer
+//		}, storertestsuite.Options{})
+//	}
+package storertestsuite
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const (
+	defaultTTL         = time.Second
+	defaultConcurrency = 10
+	staleDuration      = time.Hour
+)
+
+// The conformance tests names, to give to Options.Skip.
+const (
+	SetGet                  = "SetGet"
+	Delete                  = "Delete"
+	TTLExpiry               = "TTLExpiry"
+	PrefixScan              = "PrefixScan"
+	MultiLevel              = "MultiLevel"
+	StaleMultiLevel         = "StaleMultiLevel"
+	ConcurrentSetMultiLevel = "ConcurrentSetMultiLevel"
+	Purge                   = "Purge"
+)
+
+// Factory creates the storer under test, keeping the responses stored with
+// SetMultiLevel stale for the given duration. It's called once per test,
+// the keys are prefixed by the test so a shared backend needn't be flushed.
+type Factory func(t *testing.T, stale time.Duration) core.Storer
+
+// Options tunes the conformance tests.
+type Options struct {
+	// TTL is the duration given to the expiring writes, 1s by default. It
+	// must be above the storer expiration granularity.
+	TTL time.Duration
+	// Wait moves the time forward, time.Sleep by default. The storers
+	// running on a manual clock give its Advance method.
+	Wait func(d time.Duration)
+	// Concurrency is the number of concurrent SetMultiLevel calls, 10 by
+	// default.
+	Concurrency int
+	// Skip lists the tests the storer doesn't support.
+	Skip []string
+}
+
+type suite struct {
+	factory Factory
+	options Options
+	prefix  string
+}
+
+// RunStorerConformanceTests runs the conformance tests against the storers
+// created by the factory, each one in its own subtest.
+func RunStorerConformanceTests(t *testing.T, factory Factory, options Options) {
+	t.Helper()
+
+	if options.TTL <= 0 {
+		options.TTL = defaultTTL
+	}
+
+	if options.Wait == nil {
+		options.Wait = time.Sleep
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrency
+	}
+
+	s := suite{factory: factory, options: options, prefix: "SUITE_" + strconv.FormatInt(time.Now().UnixNano(), 36) + "_"}
+
+	for _, test := range []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{name: SetGet, run: s.testSetGet},
+		{name: Delete, run: s.testDelete},
+		{name: TTLExpiry, run: s.testTTLExpiry},
+		{name: PrefixScan, run: s.testPrefixScan},
+		{name: MultiLevel, run: s.testMultiLevel},
+		{name: StaleMultiLevel, run: s.testStaleMultiLevel},
+		{name: ConcurrentSetMultiLevel, run: s.testConcurrentSetMultiLevel},
+		{name: Purge, run: s.testPurge},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if slices.Contains(options.Skip, test.name) {
+				t.Skipf("%s is skipped by the storer", test.name)
+			}
+
+			test.run(t)
+		})
+	}
+}
+
+// key returns a key unique to the test.
+func (s suite) key(t *testing.T, name string) string {
+	return s.prefix + strings.ReplaceAll(t.Name(), "/", "_") + "_" + name
+}
+
+func response(body string) []byte {
+	return []byte(fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(body), body))
+}
+
+func request(t *testing.T, language string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if language != "" {
+		req.Header.Set("Accept-Language", language)
+	}
+
+	return req
+}
+
+func body(t *testing.T, res *http.Response) string {
+	t.Helper()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Impossible to read the response body, %v", err)
+	}
+
+	return string(content)
+}
+
+// setVariant stores the response varying on the Accept-Language header.
+func setVariant(t *testing.T, storer core.Storer, baseKey, language string, duration time.Duration) {
+	t.Helper()
+
+	variedKey := baseKey + "-" + language
+	if err := storer.SetMultiLevel(baseKey, variedKey, response(language), http.Header{"Accept-Language": []string{language}}, "", duration, variedKey); err != nil {
+		t.Fatalf("Impossible to store the %s variant of %s, %v", language, baseKey, err)
+	}
+}
+
+func (s suite) testSetGet(t *testing.T) {
+	storer := s.factory(t, 0)
+	key := s.key(t, "key")
+
+	if err := storer.Set(key, []byte("value"), time.Minute); err != nil {
+		t.Fatalf("The key should be stored, %v given", err)
+	}
+
+	if value := storer.Get(key); string(value) != "value" {
+		t.Errorf("The stored value should be read back, %q given", value)
+	}
+
+	if value := storer.Get(s.key(t, "missing")); len(value) != 0 {
+		t.Errorf("A missing key should be a miss, %q given", value)
+	}
+
+	_ = storer.Set(key, []byte("overwritten"), time.Minute)
+
+	if value := storer.Get(key); string(value) != "overwritten" {
+		t.Errorf("The value should be overwritten, %q given", value)
+	}
+}
+
+func (s suite) testDelete(t *testing.T) {
+	storer := s.factory(t, 0)
+	key := s.key(t, "key")
+
+	_ = storer.Set(key, []byte("value"), time.Minute)
+	storer.Delete(key)
+
+	if value := storer.Get(key); len(value) != 0 {
+		t.Errorf("The deleted key should be a miss, %q given", value)
+	}
+
+	storer.Delete(s.key(t, "missing"))
+}
+
+func (s suite) testTTLExpiry(t *testing.T) {
+	storer := s.factory(t, 0)
+	expiring, kept := s.key(t, "expiring"), s.key(t, "kept")
+
+	_ = storer.Set(expiring, []byte("value"), s.options.TTL)
+	_ = storer.Set(kept, []byte("value"), time.Hour)
+
+	if len(storer.Get(expiring)) == 0 {
+		t.Fatal("The key should be found before its expiration")
+	}
+
+	s.options.Wait(2 * s.options.TTL)
+
+	if value := storer.Get(expiring); len(value) != 0 {
+		t.Errorf("The key should expire after its TTL, %q given", value)
+	}
+
+	if len(storer.Get(kept)) == 0 {
+		t.Error("The key with a longer TTL should be kept")
+	}
+}
+
+func (s suite) testPrefixScan(t *testing.T) {
+	storer := s.factory(t, 0)
+	prefix := s.key(t, "prefix_")
+
+	_ = storer.Set(prefix+"first", []byte("first"), time.Minute)
+	_ = storer.Set(prefix+"second", []byte("second"), time.Minute)
+	_ = storer.Set(s.key(t, "other"), []byte("other"), time.Minute)
+
+	keys := storer.MapKeys(prefix)
+	if len(keys) != 2 || keys["first"] != "first" || keys["second"] != "second" {
+		t.Errorf("The keys starting with the prefix should be returned without it, %v given", keys)
+	}
+}
+
+func (s suite) testMultiLevel(t *testing.T) {
+	storer := s.factory(t, 0)
+	baseKey := s.key(t, "base")
+
+	setVariant(t, storer, baseKey, "en", time.Minute)
+	setVariant(t, storer, baseKey, "fr", time.Minute)
+
+	for _, language := range []string{"en", "fr"} {
+		fresh, _ := storer.GetMultiLevel(baseKey, request(t, language), &core.Revalidator{})
+		if fresh == nil {
+			t.Fatalf("The %s variant should be elected fresh", language)
+		}
+
+		if content := body(t, fresh); content != language {
+			t.Errorf("The %s variant should be elected, %s given", language, content)
+		}
+	}
+
+	if fresh, stale := storer.GetMultiLevel(baseKey, request(t, "de"), &core.Revalidator{}); fresh != nil || stale != nil {
+		t.Error("No variant should match another Accept-Language")
+	}
+
+	if fresh, stale := storer.GetMultiLevel(s.key(t, "missing"), request(t, "en"), &core.Revalidator{}); fresh != nil || stale != nil {
+		t.Error("A missing base key should be a miss")
+	}
+}
+
+func (s suite) testStaleMultiLevel(t *testing.T) {
+	storer := s.factory(t, staleDuration)
+	baseKey := s.key(t, "base")
+
+	setVariant(t, storer, baseKey, "en", s.options.TTL)
+	s.options.Wait(2 * s.options.TTL)
+
+	fresh, stale := storer.GetMultiLevel(baseKey, request(t, "en"), &core.Revalidator{})
+	if fresh != nil {
+		t.Error("The response shouldn't be fresh after its TTL")
+	}
+
+	if stale == nil {
+		t.Fatal("The response should be served stale during the stale duration")
+	}
+
+	if content := body(t, stale); content != "en" {
+		t.Errorf("The stale response should be elected, %s given", content)
+	}
+}
+
+func (s suite) testConcurrentSetMultiLevel(t *testing.T) {
+	storer := s.factory(t, 0)
+	baseKey := s.key(t, "base")
+
+	var wg sync.WaitGroup
+
+	for i := range s.options.Concurrency {
+		wg.Add(1)
+
+		go func(language string) {
+			defer wg.Done()
+
+			variedKey := baseKey + "-" + language
+			if err := storer.SetMultiLevel(baseKey, variedKey, response(language), http.Header{"Accept-Language": []string{language}}, "", time.Minute, variedKey); err != nil {
+				t.Errorf("Impossible to store the %s variant of %s concurrently, %v", language, baseKey, err)
+			}
+		}("l" + strconv.Itoa(i))
+	}
+
+	wg.Wait()
+
+	for i := range s.options.Concurrency {
+		language := "l" + strconv.Itoa(i)
+
+		if fresh, _ := storer.GetMultiLevel(baseKey, request(t, language), &core.Revalidator{}); fresh == nil {
+			t.Errorf("The %s variant stored concurrently should be kept in the mapping", language)
+		}
+	}
+}
+
+func (s suite) testPurge(t *testing.T) {
+	storer := s.factory(t, 0)
+	baseKey, kept := s.key(t, "purged"), s.key(t, "kept")
+
+	setVariant(t, storer, baseKey, "en", time.Minute)
+	setVariant(t, storer, kept, "en", time.Minute)
+
+	storer.DeleteMany("^" + core.MappingKeyPrefix + baseKey + "$")
+	storer.DeleteMany("^" + baseKey + "-")
+
+	if fresh, stale := storer.GetMultiLevel(baseKey, request(t, "en"), &core.Revalidator{}); fresh != nil || stale != nil {
+		t.Error("The purged key should be a miss")
+	}
+
+	if value := storer.Get(baseKey + "-en"); len(value) != 0 {
+		t.Error("The purged variant should be deleted")
+	}
+
+	if fresh, _ := storer.GetMultiLevel(kept, request(t, "en"), &core.Revalidator{}); fresh == nil {
+		t.Error("The keys not matching the pattern should be kept")
+	}
+}
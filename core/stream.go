@@ -0,0 +1,266 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// StreamConfigurationKey is the key read from the provider configuration
+	// to tune the chunked storage of the streamed values.
+	StreamConfigurationKey = "stream"
+	// ChunkKeyPrefix prefixes the keys holding the chunks of a streamed value.
+	ChunkKeyPrefix = "CHUNK_"
+
+	// DefaultChunkSize stays under the default value size limit of the remote
+	// providers (1MB for NATS and Olric tables).
+	DefaultChunkSize = 512 * 1024
+)
+
+// chunkedManifestMagic marks the value stored under the streamed key, it
+// describes the chunks holding the content.
+var chunkedManifestMagic = []byte("STORAGES_CHUNKED\x00")
+
+// ErrStreamNotFound is returned by GetReader when the key doesn't exist.
+var ErrStreamNotFound = errors.New("stream not found")
+
+// StreamStorer is an optional interface a Storer can implement to store and
+// load large values without buffering them fully in memory.
+type StreamStorer interface {
+	SetReader(key string, r io.Reader, ttl time.Duration) error
+	GetReader(key string) (io.ReadCloser, error)
+}
+
+type chunkedManifest struct {
+	// Generation identifies the chunks of this write, a concurrent or later
+	// write of the same key never mixes its chunks with these ones.
+	Generation string `json:"generation"`
+	Chunks     int    `json:"chunks"`
+	Size       int64  `json:"size"`
+}
+
+// ChunkedStreamer implements StreamStorer on top of the Get and Set methods
+// of any storer. The content is split in chunks stored under their own key,
+// the streamed key holds the manifest listing them and is written last so a
+// reader never sees a partial value.
+type ChunkedStreamer struct {
+	storer    Storer
+	chunkSize int
+}
+
+// NewChunkedStreamer creates a ChunkedStreamer, a non positive chunkSize
+// means DefaultChunkSize.
+func NewChunkedStreamer(storer Storer, chunkSize int) *ChunkedStreamer {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return &ChunkedStreamer{storer: storer, chunkSize: chunkSize}
+}
+
+// ChunkSizeFromConfiguration returns the chunk_size declared under the
+// stream key of the provider configuration, DefaultChunkSize when unset.
+func ChunkSizeFromConfiguration(configuration any) int {
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok {
+		return DefaultChunkSize
+	}
+
+	streamCfg, ok := cfg[StreamConfigurationKey].(map[string]interface{})
+	if !ok {
+		return DefaultChunkSize
+	}
+
+	var chunkSize int
+
+	switch v := streamCfg["chunk_size"].(type) {
+	case int:
+		chunkSize = v
+	case float64:
+		chunkSize = int(v)
+	case string:
+		chunkSize, _ = strconv.Atoi(v)
+	}
+
+	if chunkSize <= 0 {
+		return DefaultChunkSize
+	}
+
+	return chunkSize
+}
+
+// StreamStorerFor returns the StreamStorer implemented by the storer, a
+// ChunkedStreamer using the default chunk size otherwise.
+func StreamStorerFor(storer Storer) StreamStorer {
+	if streamer, ok := storer.(StreamStorer); ok {
+		return streamer
+	}
+
+	return NewChunkedStreamer(storer, DefaultChunkSize)
+}
+
+func chunkKey(key, generation string, index int) string {
+	return fmt.Sprintf("%s%s_%s_%d", ChunkKeyPrefix, key, generation, index)
+}
+
+// IsChunkedManifest reports whether the value is the manifest stored by a
+// ChunkedStreamer under the streamed key.
+func IsChunkedManifest(value []byte) bool {
+	return bytes.HasPrefix(value, chunkedManifestMagic)
+}
+
+func decodeManifest(value []byte) *chunkedManifest {
+	if !IsChunkedManifest(value) {
+		return nil
+	}
+
+	var manifest chunkedManifest
+	if err := json.Unmarshal(value[len(chunkedManifestMagic):], &manifest); err != nil {
+		return nil
+	}
+
+	return &manifest
+}
+
+func (s *ChunkedStreamer) manifest(key string) (*chunkedManifest, []byte) {
+	value := s.storer.Get(key)
+
+	manifest := decodeManifest(value)
+	if manifest == nil {
+		return nil, value
+	}
+
+	return manifest, nil
+}
+
+func (s *ChunkedStreamer) deleteChunks(key string, manifest *chunkedManifest) {
+	for index := range manifest.Chunks {
+		s.storer.Delete(chunkKey(key, manifest.Generation, index))
+	}
+}
+
+// SetReader stores the content read from r chunk by chunk, only one chunk
+// is held in memory at a time.
+func (s *ChunkedStreamer) SetReader(key string, r io.Reader, ttl time.Duration) error {
+	manifest := &chunkedManifest{Generation: LockToken()}
+	buf := make([]byte, s.chunkSize)
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if setErr := s.storer.Set(chunkKey(key, manifest.Generation, manifest.Chunks), bytes.Clone(buf[:n]), ttl); setErr != nil {
+				s.deleteChunks(key, manifest)
+
+				return setErr
+			}
+
+			manifest.Chunks++
+			manifest.Size += int64(n)
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			s.deleteChunks(key, manifest)
+
+			return err
+		}
+	}
+
+	encoded, err := json.Marshal(manifest)
+	if err != nil {
+		s.deleteChunks(key, manifest)
+
+		return err
+	}
+
+	previous, _ := s.manifest(key)
+
+	if err = s.storer.Set(key, append(bytes.Clone(chunkedManifestMagic), encoded...), ttl); err != nil {
+		s.deleteChunks(key, manifest)
+
+		return err
+	}
+
+	if previous != nil {
+		s.deleteChunks(key, previous)
+	}
+
+	return nil
+}
+
+// GetReader returns a reader loading the chunks on demand. A value stored
+// without SetReader is returned as is.
+func (s *ChunkedStreamer) GetReader(key string) (io.ReadCloser, error) {
+	manifest, value := s.manifest(key)
+	if manifest == nil {
+		if len(value) == 0 {
+			return nil, ErrStreamNotFound
+		}
+
+		return io.NopCloser(bytes.NewReader(value)), nil
+	}
+
+	return &chunkedReader{streamer: s, key: key, manifest: manifest}, nil
+}
+
+// DeleteChunks removes the chunks listed by the manifest once stored under
+// the key, e.g. returned by its deletion. Any other value is ignored.
+func (s *ChunkedStreamer) DeleteChunks(key string, manifest []byte) {
+	if decoded := decodeManifest(manifest); decoded != nil {
+		s.deleteChunks(key, decoded)
+	}
+}
+
+// Delete removes the streamed value and its chunks.
+func (s *ChunkedStreamer) Delete(key string) {
+	if manifest, _ := s.manifest(key); manifest != nil {
+		s.deleteChunks(key, manifest)
+	}
+
+	s.storer.Delete(key)
+}
+
+type chunkedReader struct {
+	streamer *ChunkedStreamer
+	key      string
+	manifest *chunkedManifest
+	index    int
+	current  []byte
+}
+
+// Read loads the next chunk once the current one is consumed. A missing
+// chunk, expired or evicted, ends the read with io.ErrUnexpectedEOF.
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for len(r.current) == 0 {
+		if r.index >= r.manifest.Chunks {
+			return 0, io.EOF
+		}
+
+		r.current = r.streamer.storer.Get(chunkKey(r.key, r.manifest.Generation, r.index))
+		if len(r.current) == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		r.index++
+	}
+
+	n := copy(p, r.current)
+	r.current = r.current[n:]
+
+	return n, nil
+}
+
+func (r *chunkedReader) Close() error {
+	r.current = nil
+	r.index = r.manifest.Chunks
+
+	return nil
+}
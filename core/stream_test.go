@@ -0,0 +1,127 @@
+package core_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestChunkedStreamer_SetReader(t *testing.T) {
+	storer := newMemoryStorer()
+	streamer := core.NewChunkedStreamer(storer, 4)
+	value := strings.Repeat(baseValue, 3)
+
+	if err := streamer.SetReader(byteKey, strings.NewReader(value), time.Minute); err != nil {
+		t.Fatalf("Impossible to stream the value: %v", err)
+	}
+
+	if chunks := len(storer.MapKeys(core.ChunkKeyPrefix)); chunks != (len(value)+3)/4 {
+		t.Errorf("The value should be stored in %d chunks, %d found", (len(value)+3)/4, chunks)
+	}
+
+	reader, err := streamer.GetReader(byteKey)
+	if err != nil {
+		t.Fatalf("Impossible to get the reader: %v", err)
+	}
+
+	defer reader.Close()
+
+	res, _ := io.ReadAll(reader)
+	if string(res) != value {
+		t.Errorf("%s not corresponding to %s", res, value)
+	}
+}
+
+func TestChunkedStreamer_Overwrite(t *testing.T) {
+	storer := newMemoryStorer()
+	streamer := core.NewChunkedStreamer(storer, 4)
+
+	_ = streamer.SetReader(byteKey, strings.NewReader(strings.Repeat("a", 16)), time.Minute)
+	_ = streamer.SetReader(byteKey, strings.NewReader("bb"), time.Minute)
+
+	if chunks := len(storer.MapKeys(core.ChunkKeyPrefix)); chunks != 1 {
+		t.Errorf("The previous chunks should be deleted, %d chunks found", chunks)
+	}
+
+	reader, _ := streamer.GetReader(byteKey)
+	if res, _ := io.ReadAll(reader); string(res) != "bb" {
+		t.Errorf("%s not corresponding to bb", res)
+	}
+
+	streamer.Delete(byteKey)
+
+	if len(storer.MapKeys(core.ChunkKeyPrefix)) != 0 || len(storer.Get(byteKey)) != 0 {
+		t.Error("The streamed value and its chunks should be deleted")
+	}
+}
+
+func TestChunkedStreamer_GetReader(t *testing.T) {
+	storer := newMemoryStorer()
+	streamer := core.NewChunkedStreamer(storer, 4)
+
+	if _, err := streamer.GetReader(byteKey); !errors.Is(err, core.ErrStreamNotFound) {
+		t.Errorf("The missing key should return ErrStreamNotFound, %v given", err)
+	}
+
+	_ = storer.Set(byteKey, []byte(baseValue), time.Minute)
+
+	reader, _ := streamer.GetReader(byteKey)
+	if res, _ := io.ReadAll(reader); string(res) != baseValue {
+		t.Errorf("The value stored without SetReader should be returned as is, %s given", res)
+	}
+
+	_ = streamer.SetReader(byteKey, bytes.NewReader([]byte(baseValue)), time.Minute)
+	for key := range storer.MapKeys(core.ChunkKeyPrefix) {
+		storer.Delete(core.ChunkKeyPrefix + key)
+
+		break
+	}
+
+	reader, _ = streamer.GetReader(byteKey)
+	if _, err := io.ReadAll(reader); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("A missing chunk should return io.ErrUnexpectedEOF, %v given", err)
+	}
+}
+
+func TestChunkedStreamer_DeleteChunks(t *testing.T) {
+	storer := newMemoryStorer()
+	streamer := core.NewChunkedStreamer(storer, 4)
+
+	_ = streamer.SetReader(byteKey, strings.NewReader(baseValue), time.Minute)
+
+	manifest := storer.Get(byteKey)
+	if !core.IsChunkedManifest(manifest) || core.IsChunkedManifest([]byte(baseValue)) {
+		t.Fatal("Only the streamed key should hold a manifest")
+	}
+
+	storer.Delete(byteKey)
+	streamer.DeleteChunks(byteKey, []byte(baseValue))
+
+	if len(storer.MapKeys(core.ChunkKeyPrefix)) == 0 {
+		t.Fatal("A value which isn't a manifest shouldn't delete any chunk")
+	}
+
+	streamer.DeleteChunks(byteKey, manifest)
+
+	if len(storer.MapKeys(core.ChunkKeyPrefix)) != 0 {
+		t.Error("The chunks listed by the manifest should be deleted")
+	}
+}
+
+func TestChunkSizeFromConfiguration(t *testing.T) {
+	if size := core.ChunkSizeFromConfiguration(nil); size != core.DefaultChunkSize {
+		t.Errorf("The default chunk size should be used, %d given", size)
+	}
+
+	size := core.ChunkSizeFromConfiguration(map[string]interface{}{
+		core.StreamConfigurationKey: map[string]interface{}{"chunk_size": "1024"},
+	})
+	if size != 1024 {
+		t.Errorf("The configured chunk size should be used, %d given", size)
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// OperationTimeoutConfigurationKey is the key read from the provider
+	// configuration to bound every backend call.
+	OperationTimeoutConfigurationKey = "operation_timeout"
+	// ReadTimeoutConfigurationKey is the key read from the provider
+	// configuration to bound the backend reads, it overrides the
+	// operation_timeout.
+	ReadTimeoutConfigurationKey = "read_timeout"
+	// WriteTimeoutConfigurationKey is the key read from the provider
+	// configuration to bound the backend writes and deletions, it overrides
+	// the operation_timeout.
+	WriteTimeoutConfigurationKey = "write_timeout"
+)
+
+// Timeouts bounds the calls made by the network backed storages, a zero
+// timeout leaves the call bounded by the client library only.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// timeoutsConfiguration is the typed timeouts read from the provider
+// configuration.
+type timeoutsConfiguration struct {
+	OperationTimeout time.Duration `json:"operation_timeout"`
+	ReadTimeout      time.Duration `json:"read_timeout"`
+	WriteTimeout     time.Duration `json:"write_timeout"`
+}
+
+// TimeoutsFromConfiguration returns the timeouts set in the provider
+// configuration, the read_timeout and write_timeout default to the
+// operation_timeout.
+func TimeoutsFromConfiguration(configuration any) (Timeouts, error) {
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok {
+		return Timeouts{}, nil
+	}
+
+	timeouts := map[string]interface{}{}
+
+	for _, key := range []string{OperationTimeoutConfigurationKey, ReadTimeoutConfigurationKey, WriteTimeoutConfigurationKey} {
+		if value, found := cfg[key]; found {
+			timeouts[key] = value
+		}
+	}
+
+	var parsed timeoutsConfiguration
+	if err := DecodeConfiguration(timeouts, &parsed); err != nil {
+		return Timeouts{}, fmt.Errorf("invalid timeouts configuration: %w", err)
+	}
+
+	for key, value := range map[string]time.Duration{
+		OperationTimeoutConfigurationKey: parsed.OperationTimeout,
+		ReadTimeoutConfigurationKey:      parsed.ReadTimeout,
+		WriteTimeoutConfigurationKey:     parsed.WriteTimeout,
+	} {
+		if value < 0 {
+			return Timeouts{}, fmt.Errorf("invalid timeouts configuration: the %s can't be negative, %s given", key, value)
+		}
+	}
+
+	result := Timeouts{Read: parsed.ReadTimeout, Write: parsed.WriteTimeout}
+
+	if result.Read == 0 {
+		result.Read = parsed.OperationTimeout
+	}
+
+	if result.Write == 0 {
+		result.Write = parsed.OperationTimeout
+	}
+
+	return result, nil
+}
+
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+
+	return context.WithTimeout(parent, timeout)
+}
+
+// ReadContext returns the parent context bounded by the read timeout.
+func (t Timeouts) ReadContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(parent, t.Read)
+}
+
+// WriteContext returns the parent context bounded by the write timeout.
+func (t Timeouts) WriteContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(parent, t.Write)
+}
@@ -0,0 +1,56 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestTimeoutsFromConfiguration(t *testing.T) {
+	timeouts, err := core.TimeoutsFromConfiguration(map[string]interface{}{
+		"operation_timeout": "2s",
+		"read_timeout":      "500ms",
+		"compression":       "lz4",
+	})
+	if err != nil {
+		t.Fatalf("The timeouts should be valid, %v given", err)
+	}
+
+	if timeouts.Read != 500*time.Millisecond || timeouts.Write != 2*time.Second {
+		t.Errorf("The read timeout should override the operation one, %+v given", timeouts)
+	}
+
+	if timeouts, _ = core.TimeoutsFromConfiguration(nil); timeouts != (core.Timeouts{}) {
+		t.Errorf("The timeouts should be unbounded by default, %+v given", timeouts)
+	}
+
+	if _, err = core.TimeoutsFromConfiguration(map[string]interface{}{"write_timeout": "-1s"}); err == nil {
+		t.Error("A negative timeout should be invalid")
+	}
+
+	if _, err = core.TimeoutsFromConfiguration(map[string]interface{}{"read_timeout": "soon"}); err == nil {
+		t.Error("A malformed timeout should be invalid")
+	}
+}
+
+func TestTimeouts_Context(t *testing.T) {
+	timeouts := core.Timeouts{Read: 10 * time.Millisecond}
+
+	ctx, cancel := timeouts.ReadContext(context.Background())
+	defer cancel()
+
+	if _, found := ctx.Deadline(); !found {
+		t.Error("The read context should have a deadline")
+	}
+
+	<-ctx.Done()
+
+	ctx, cancel = timeouts.WriteContext(context.Background())
+	defer cancel()
+
+	if _, found := ctx.Deadline(); found {
+		t.Error("The write context shouldn't have a deadline")
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// TLSConfigurationKey is the key read from the provider configuration to
+// connect to the remote backend over TLS.
+const TLSConfigurationKey = "tls"
+
+// TLSConfig describes the TLS and mTLS settings shared by the remote
+// providers.
+type TLSConfig struct {
+	// CAFile is the PEM encoded CA bundle used to verify the server.
+	CAFile string `json:"ca_file" yaml:"ca_file"`
+	// CertFile and KeyFile are the PEM encoded client certificate and key
+	// presented to the server for mTLS.
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+	// InsecureSkipVerify disables the server certificate verification.
+	InsecureSkipVerify bool `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+	// ServerName overrides the name used to verify the server certificate.
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// Build returns the crypto/tls configuration.
+func (c TLSConfig) Build() (*tls.Config, error) {
+	//nolint:gosec
+	config := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ServerName:         c.ServerName,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if c.CAFile != "" {
+		ca, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("impossible to read the CA file %s: %w", c.CAFile, err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificate found in the CA file %s", c.CAFile)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("both cert_file and key_file must be set to use a client certificate")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("impossible to load the client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// TLSConfigFromConfiguration returns the TLS configuration declared under
+// the tls key of the provider configuration, nil when TLS is not configured.
+func TLSConfigFromConfiguration(configuration any) (*tls.Config, error) {
+	cfg, ok := configuration.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	tlsCfg, ok := cfg[TLSConfigurationKey].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var config TLSConfig
+
+	for key, target := range map[string]*string{
+		"ca_file":     &config.CAFile,
+		"cert_file":   &config.CertFile,
+		"key_file":    &config.KeyFile,
+		"server_name": &config.ServerName,
+	} {
+		if v, ok := tlsCfg[key].(string); ok {
+			*target = v
+		}
+	}
+
+	switch v := tlsCfg["insecure_skip_verify"].(type) {
+	case bool:
+		config.InsecureSkipVerify = v
+	case string:
+		config.InsecureSkipVerify, _ = strconv.ParseBool(v)
+	}
+
+	return config.Build()
+}
@@ -0,0 +1,94 @@
+package core_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func writeCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "storages"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	_ = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	_ = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+
+	return certFile, keyFile
+}
+
+func TestTLSConfigFromConfiguration(t *testing.T) {
+	if config, err := core.TLSConfigFromConfiguration(map[string]interface{}{}); config != nil || err != nil {
+		t.Error("No TLS configuration should be returned without the tls key")
+	}
+
+	certFile, keyFile := writeCertificate(t)
+
+	config, err := core.TLSConfigFromConfiguration(map[string]interface{}{
+		"tls": map[string]interface{}{
+			"ca_file":              certFile,
+			"cert_file":            certFile,
+			"key_file":             keyFile,
+			"insecure_skip_verify": "true",
+			"server_name":          "storages.local",
+		},
+	})
+	if err != nil {
+		t.Fatalf("The TLS configuration should be valid: %v", err)
+	}
+
+	if config.RootCAs == nil || len(config.Certificates) != 1 {
+		t.Error("The CA and the client certificate should be loaded")
+	}
+
+	if !config.InsecureSkipVerify || config.ServerName != "storages.local" {
+		t.Errorf("The verification settings should be applied, %+v given", config)
+	}
+
+	if _, err = core.TLSConfigFromConfiguration(map[string]interface{}{
+		"tls": map[string]interface{}{"cert_file": certFile},
+	}); err == nil {
+		t.Error("A client certificate without key should be rejected")
+	}
+
+	if _, err = core.TLSConfigFromConfiguration(map[string]interface{}{
+		"tls": map[string]interface{}{"ca_file": keyFile},
+	}); err == nil {
+		t.Error("A CA file without certificate should be rejected")
+	}
+}
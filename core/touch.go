@@ -0,0 +1,45 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTouchNotSupported is returned when no storer under the decorators
+// implements Toucher or TTLRefresher.
+var ErrTouchNotSupported = errors.New("the storer doesn't support the touches")
+
+// Toucher is an optional interface a Storer can implement to extend the
+// expiration of a key with the native expire command of its backend, to
+// build the sliding expiration policies: each read touches the key so it
+// only expires once it's no longer read. Touch makes the key expire after
+// ttl, it returns ErrKeyNotFound when the key doesn't exist. Like the
+// conditional sets, the ttl isn't extended by the stale duration.
+type Toucher interface {
+	Touch(key string, ttl time.Duration) error
+}
+
+// Touch makes the key expire after ttl, using the Toucher of the storer or
+// one of the storers it decorates, or their TTLRefresher extending the
+// expiration the same way. It returns ErrTouchNotSupported when there is
+// none.
+func Touch(storer Storer, key string, ttl time.Duration) error {
+	for storer != nil {
+		if toucher, ok := storer.(Toucher); ok {
+			return toucher.Touch(key, ttl)
+		}
+
+		if refresher, ok := storer.(TTLRefresher); ok {
+			return refresher.RefreshTTL(key, ttl)
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return ErrTouchNotSupported
+}
@@ -0,0 +1,60 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// touchingStorer is a memoryStorer implementing the Toucher, it records the
+// touched durations.
+type touchingStorer struct {
+	*memoryStorer
+	touched map[string]time.Duration
+}
+
+func (t touchingStorer) Touch(key string, ttl time.Duration) error {
+	if t.Get(key) == nil {
+		return core.ErrKeyNotFound
+	}
+
+	t.touched[key] = ttl
+
+	return nil
+}
+
+func TestTouch(t *testing.T) {
+	toucher := touchingStorer{memoryStorer: newMemoryStorer(), touched: map[string]time.Duration{}}
+
+	storer, err := core.NewPrefixedStorer(toucher, "tenant-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = storer.Set("session", []byte("value"), time.Minute)
+
+	if err = core.Touch(storer, "session", time.Hour); err != nil || toucher.touched["tenant-session"] != time.Hour {
+		t.Errorf("The prefixed key should be touched, %v given", err)
+	}
+
+	if err = core.RefreshTTL(storer, "session", 2*time.Hour); err != nil || toucher.touched["tenant-session"] != 2*time.Hour {
+		t.Errorf("The TTL refreshes should touch the key, %v given", err)
+	}
+
+	if err = core.Touch(storer, "missing", time.Hour); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should be reported, %v given", err)
+	}
+
+	refresher := &refreshingStorer{memoryStorer: newMemoryStorer(), refreshed: map[string]time.Duration{}}
+	_ = refresher.Set("session", []byte("value"), time.Minute)
+
+	if err = core.Touch(refresher, "session", time.Hour); err != nil || refresher.refreshed["session"] != time.Hour {
+		t.Errorf("The key should be touched with the TTL refresh, %v given", err)
+	}
+
+	if err = core.Touch(newMemoryStorer(), "session", time.Hour); !errors.Is(err, core.ErrTouchNotSupported) {
+		t.Errorf("The storer without touch should be reported, %v given", err)
+	}
+}
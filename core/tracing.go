@@ -0,0 +1,239 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The attributes set by a TracedStorer on its spans. The keys are hashed so
+// the spans never leak the cached URLs.
+const (
+	TraceOperationAttribute = "storages.operation"
+	TraceBackendAttribute   = "storages.backend"
+	TraceKeyHashAttribute   = "storages.key_hash"
+	TraceValueSizeAttribute = "storages.value_size"
+	TraceHitAttribute       = "storages.hit"
+)
+
+// Tracer starts the spans of a TracedStorer, as children of the span held
+// by the context. The core/tracing module implements it on OpenTelemetry.
+type Tracer interface {
+	Start(ctx context.Context, operation string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// ContextBinder is an optional interface a Storer can implement to run its
+// calls with the given context, e.g. to propagate the span of the caller to
+// the client of its backend.
+type ContextBinder interface {
+	WithContext(ctx context.Context) Storer
+}
+
+// BindContext returns the storer running its calls with the context when it
+// implements ContextBinder, the storer untouched otherwise.
+func BindContext(storer Storer, ctx context.Context) Storer {
+	if binder, ok := storer.(ContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+
+	return storer
+}
+
+// TracedStorer decorates any Storer to wrap each of its calls in a span
+// holding the operation, the backend, the key hash, the value size and the
+// error. The span context is bound to the decorated storer, wrap the
+// provider directly to propagate it to its client.
+type TracedStorer struct {
+	Storer
+
+	tracer Tracer
+	// ctx is the context bound with WithContext, nil until then.
+	ctx context.Context
+}
+
+// NewTracedStorer wraps the storer to trace its calls with the tracer.
+func NewTracedStorer(storer Storer, tracer Tracer) *TracedStorer {
+	return &TracedStorer{Storer: storer, tracer: tracer}
+}
+
+// Unwrap returns the decorated storer.
+func (s *TracedStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// WithContext returns the storer whose spans are children of the span held
+// by ctx, e.g. the one of the origin request.
+func (s *TracedStorer) WithContext(ctx context.Context) Storer {
+	bound := *s
+	bound.ctx = ctx
+
+	return &bound
+}
+
+// HashKey returns the FNV-1a hash of the key set on the spans.
+func HashKey(key string) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+func (s *TracedStorer) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+
+	return s.ctx
+}
+
+// start opens the span of the operation and returns the decorated storer
+// bound to its context.
+func (s *TracedStorer) start(parent context.Context, operation, key string) (Storer, Span) {
+	ctx, span := s.tracer.Start(parent, operation)
+	span.SetAttribute(TraceOperationAttribute, operation)
+	span.SetAttribute(TraceBackendAttribute, s.Storer.Name())
+
+	if key != "" {
+		span.SetAttribute(TraceKeyHashAttribute, HashKey(key))
+	}
+
+	return BindContext(s.Storer, ctx), span
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	span.End()
+}
+
+// MapKeys traces the listing of the keys starting with the prefix.
+func (s *TracedStorer) MapKeys(prefix string) map[string]string {
+	storer, span := s.start(s.context(), "map_keys", prefix)
+	defer span.End()
+
+	return storer.MapKeys(prefix)
+}
+
+// ListKeys traces the listing of the keys.
+func (s *TracedStorer) ListKeys() []string {
+	storer, span := s.start(s.context(), "list_keys", "")
+	defer span.End()
+
+	return storer.ListKeys()
+}
+
+// Get traces the read and sets the size of the returned value.
+func (s *TracedStorer) Get(key string) []byte {
+	storer, span := s.start(s.context(), "get", key)
+	defer span.End()
+
+	value := storer.Get(key)
+	span.SetAttribute(TraceHitAttribute, len(value) != 0)
+	span.SetAttribute(TraceValueSizeAttribute, len(value))
+
+	return value
+}
+
+// Lookup traces the read, ErrKeyNotFound isn't recorded as an error.
+func (s *TracedStorer) Lookup(key string) ([]byte, error) {
+	storer, span := s.start(s.context(), "get", key)
+
+	value, err := Lookup(storer, key)
+	span.SetAttribute(TraceHitAttribute, err == nil)
+	span.SetAttribute(TraceValueSizeAttribute, len(value))
+
+	if errors.Is(err, ErrKeyNotFound) {
+		endSpan(span, nil)
+	} else {
+		endSpan(span, err)
+	}
+
+	return value, err
+}
+
+// Set traces the write and sets the size of the stored value.
+func (s *TracedStorer) Set(key string, value []byte, duration time.Duration) error {
+	storer, span := s.start(s.context(), "set", key)
+	span.SetAttribute(TraceValueSizeAttribute, len(value))
+
+	err := storer.Set(key, value, duration)
+	endSpan(span, err)
+
+	return err
+}
+
+// Delete traces the deletion.
+func (s *TracedStorer) Delete(key string) {
+	storer, span := s.start(s.context(), "delete", key)
+	defer span.End()
+
+	storer.Delete(key)
+}
+
+// DeleteMany traces the deletion of the keys matching the pattern.
+func (s *TracedStorer) DeleteMany(key string) {
+	storer, span := s.start(s.context(), "delete_many", key)
+	defer span.End()
+
+	storer.DeleteMany(key)
+}
+
+// Init traces the initialization.
+func (s *TracedStorer) Init() error {
+	storer, span := s.start(s.context(), "init", "")
+
+	err := storer.Init()
+	endSpan(span, err)
+
+	return err
+}
+
+// Reset traces the reset.
+func (s *TracedStorer) Reset() error {
+	storer, span := s.start(s.context(), "reset", "")
+
+	err := storer.Reset()
+	endSpan(span, err)
+
+	return err
+}
+
+// GetMultiLevel traces the read as a child of the span of the request when
+// the storer isn't bound to another context.
+func (s *TracedStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	parent := s.context()
+	if s.ctx == nil && req != nil {
+		parent = req.Context()
+	}
+
+	storer, span := s.start(parent, "get_multi_level", key)
+	defer span.End()
+
+	fresh, stale = storer.GetMultiLevel(key, req, validator)
+	span.SetAttribute(TraceHitAttribute, fresh != nil || stale != nil)
+
+	return fresh, stale
+}
+
+// SetMultiLevel traces the write of the varied value and its mapping.
+func (s *TracedStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	storer, span := s.start(s.context(), "set_multi_level", variedKey)
+	span.SetAttribute(TraceValueSizeAttribute, len(value))
+
+	err := storer.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+	endSpan(span, err)
+
+	return err
+}
@@ -0,0 +1,75 @@
+// Package tracing exposes the storers calls as OpenTelemetry spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkweak/storages/core"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/darkweak/storages/core/tracing"
+
+// Tracer is a core.Tracer implementation starting OpenTelemetry client
+// spans named after the operation, e.g. "storages.get".
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ core.Tracer = (*Tracer)(nil)
+
+// NewTracer creates a Tracer from the given provider, the global one when
+// nil.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// Wrap returns the storer tracing its calls with a Tracer of the provider.
+func Wrap(storer core.Storer, provider trace.TracerProvider) *core.TracedStorer {
+	return core.NewTracedStorer(storer, NewTracer(provider))
+}
+
+// Start starts the span of the operation as a child of the span of ctx.
+func (t *Tracer) Start(ctx context.Context, operation string) (context.Context, core.Span) {
+	ctx, span := t.tracer.Start(ctx, "storages."+operation, trace.WithSpanKind(trace.SpanKindClient))
+
+	return ctx, otelSpan{span}
+}
+
+// otelSpan converts the attributes of the core.Span to the OpenTelemetry
+// ones.
+type otelSpan struct {
+	span trace.Span
+}
+
+func (o otelSpan) SetAttribute(key string, value any) {
+	switch v := value.(type) {
+	case string:
+		o.span.SetAttributes(attribute.String(key, v))
+	case int:
+		o.span.SetAttributes(attribute.Int(key, v))
+	case int64:
+		o.span.SetAttributes(attribute.Int64(key, v))
+	case bool:
+		o.span.SetAttributes(attribute.Bool(key, v))
+	default:
+		o.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (o otelSpan) RecordError(err error) {
+	o.span.RecordError(err)
+	o.span.SetStatus(codes.Error, err.Error())
+}
+
+func (o otelSpan) End() {
+	o.span.End()
+}
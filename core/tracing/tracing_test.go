@@ -0,0 +1,86 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setStorer only implements the Set of a core.Storer.
+type setStorer struct {
+	core.Storer
+}
+
+func (setStorer) Name() string {
+	return "SET"
+}
+
+func (setStorer) Set(string, []byte, time.Duration) error {
+	return nil
+}
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := tracing.NewTracer(provider)
+
+	parentCtx, parent := provider.Tracer("test").Start(context.Background(), "origin")
+
+	_, span := tracer.Start(parentCtx, "get")
+	span.SetAttribute(core.TraceKeyHashAttribute, core.HashKey("key"))
+	span.SetAttribute(core.TraceValueSizeAttribute, 42)
+	span.SetAttribute(core.TraceHitAttribute, true)
+	span.RecordError(core.ErrKeyNotFound)
+	span.End()
+	parent.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("Both spans should be ended, %d given", len(ended))
+	}
+
+	get := ended[0]
+	if get.Name() != "storages.get" || get.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("The span should be a child of the origin one, got %s", get.Name())
+	}
+
+	expected := map[attribute.Key]attribute.Value{
+		core.TraceKeyHashAttribute:   attribute.StringValue(core.HashKey("key")),
+		core.TraceValueSizeAttribute: attribute.IntValue(42),
+		core.TraceHitAttribute:       attribute.BoolValue(true),
+	}
+
+	for _, kv := range get.Attributes() {
+		if value, ok := expected[kv.Key]; ok && value != kv.Value {
+			t.Errorf("The attribute %s should be %v, %v given", kv.Key, value.Emit(), kv.Value.Emit())
+		}
+
+		delete(expected, kv.Key)
+	}
+
+	if len(expected) != 0 {
+		t.Errorf("The attributes %v should be set", expected)
+	}
+
+	if get.Status().Code != codes.Error || len(get.Events()) != 1 {
+		t.Errorf("The error should be recorded, got %v", get.Status())
+	}
+}
+
+func TestWrap(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	storer := tracing.Wrap(setStorer{}, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_ = storer.Set("key", []byte("value"), time.Minute)
+
+	if ended := recorder.Ended(); len(ended) != 1 || ended[0].Name() != "storages.set" || ended[0].Status().Code == codes.Error {
+		t.Errorf("The set should be traced, %v given", ended)
+	}
+}
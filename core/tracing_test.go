@@ -0,0 +1,131 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+type spanKey struct{}
+
+type recordedSpan struct {
+	operation  string
+	parent     any
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (r *recordedSpan) SetAttribute(key string, value any) {
+	r.attributes[key] = value
+}
+
+func (r *recordedSpan) RecordError(err error) {
+	r.err = err
+}
+
+func (r *recordedSpan) End() {
+	r.ended = true
+}
+
+// recordingTracer keeps the started spans, the context holds the current
+// span under spanKey.
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, operation string) (context.Context, core.Span) {
+	span := &recordedSpan{operation: operation, parent: ctx.Value(spanKey{}), attributes: map[string]any{}}
+	r.spans = append(r.spans, span)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// bindingStorer is a memoryStorer recording the context it's bound to.
+type bindingStorer struct {
+	*memoryStorer
+
+	bound *context.Context
+}
+
+func (b bindingStorer) WithContext(ctx context.Context) core.Storer {
+	*b.bound = ctx
+
+	return b
+}
+
+// failingSetStorer is a memoryStorer whose writes fail.
+type failingSetStorer struct {
+	*memoryStorer
+}
+
+func (failingSetStorer) Set(string, []byte, time.Duration) error {
+	return errors.New("unavailable")
+}
+
+func TestTracedStorer(t *testing.T) {
+	tracer := &recordingTracer{}
+	storer := core.NewTracedStorer(newMemoryStorer(), tracer)
+
+	_ = storer.Set("key", []byte("value"), time.Minute)
+	_ = storer.Get("key")
+
+	if _, err := storer.Lookup("missing"); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Fatalf("the miss should be returned, got %v", err)
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("each call should be traced, %d spans", len(tracer.spans))
+	}
+
+	set, get, lookup := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	if set.operation != "set" || set.attributes[core.TraceValueSizeAttribute] != 5 || set.attributes[core.TraceBackendAttribute] != "MEMORY" || !set.ended {
+		t.Errorf("the set span should hold its attributes, got %v", set.attributes)
+	}
+
+	if hash := get.attributes[core.TraceKeyHashAttribute]; hash != core.HashKey("key") || hash == "key" {
+		t.Errorf("the key should be hashed, got %v", hash)
+	}
+
+	if get.attributes[core.TraceHitAttribute] != true || lookup.attributes[core.TraceHitAttribute] != false || lookup.err != nil {
+		t.Errorf("the hit and the miss should be reported without error, got %v and %v, %v", get.attributes, lookup.attributes, lookup.err)
+	}
+
+	failing := core.NewTracedStorer(failingSetStorer{newMemoryStorer()}, tracer)
+	if err := failing.Set("key", []byte("value"), time.Minute); err == nil || tracer.spans[3].err == nil {
+		t.Error("the error should be recorded on the span")
+	}
+}
+
+func TestTracedStorer_Context(t *testing.T) {
+	tracer := &recordingTracer{}
+
+	var bound context.Context
+
+	storer := core.NewTracedStorer(bindingStorer{memoryStorer: newMemoryStorer(), bound: &bound}, tracer)
+	origin := &recordedSpan{operation: "origin", attributes: map[string]any{}}
+	ctx := context.WithValue(context.Background(), spanKey{}, origin)
+
+	_ = storer.WithContext(ctx).Set("key", []byte("value"), time.Minute)
+
+	span := tracer.spans[0]
+	if span.parent != origin {
+		t.Error("the span should be a child of the bound context one")
+	}
+
+	if bound == nil || bound.Value(spanKey{}) != span {
+		t.Error("the span context should be propagated to the decorated storer")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	_, _ = storer.GetMultiLevel("key", req, &core.Revalidator{})
+
+	if tracer.spans[1].parent != origin {
+		t.Error("the multi level read should be a child of the request span")
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTransactionNotSupported is returned when no storer under the
+	// decorators implements Transactor.
+	ErrTransactionNotSupported = errors.New("the storer doesn't support the transactions")
+	// ErrTransactionConflict is returned by a transaction when the keys it
+	// read were written by another instance before it committed.
+	ErrTransactionConflict = errors.New("the transaction conflicted with a concurrent write")
+)
+
+// Tx reads and writes the keys of a transaction. Get returns
+// ErrKeyNotFound when the key doesn't exist and sees the writes of the
+// transaction. Like ConditionalSetter, the ttl of Set isn't extended by the
+// stale duration and a zero ttl keeps the value until it's deleted.
+type Tx interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// Transactor is an optional interface a Storer can implement to apply the
+// writes of several keys atomically, e.g. a value and its mapping. Transact
+// commits the writes of fn when it returns nil and discards them otherwise.
+// fn runs again when the transaction conflicts with a concurrent write, so
+// it must not have other side effects. ErrTransactionConflict is returned
+// once the attempts are exhausted.
+type Transactor interface {
+	Transact(fn func(tx Tx) error) error
+}
+
+// TransactorFor returns the Transactor implemented by the storer or one of
+// the storers it decorates. The values are read and stored as given, the
+// decorators encoding them such as the EncryptedStorer are bypassed. The
+// decorators guarding the writes, the ReadOnlyStorer, the QuotaStorer, the
+// ValueLimitStorer, the CircuitBreakerStorer and the ReplicatedStorer,
+// implement Transactor to apply their policy to the transactions.
+func TransactorFor(storer Storer) (Transactor, bool) {
+	for storer != nil {
+		if transactor, ok := storer.(Transactor); ok {
+			return transactor, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// Transact runs fn in a transaction of the storer. It returns
+// ErrTransactionNotSupported when the storer has no Transactor.
+func Transact(storer Storer, fn func(tx Tx) error) error {
+	transactor, ok := TransactorFor(storer)
+	if !ok {
+		return ErrTransactionNotSupported
+	}
+
+	return transactor.Transact(fn)
+}
+
+// RetryTransaction runs the transaction until it doesn't return
+// ErrTransactionConflict, waiting a short random delay between the
+// attempts, for the storages detecting the conflicts on commit.
+func RetryTransaction(run func() error) error {
+	return retryOnConflict(ErrTransactionConflict, run)
+}
+
+// txWrite is a write of a committed transaction.
+type txWrite struct {
+	key     string
+	value   []byte
+	ttl     time.Duration
+	deleted bool
+}
+
+// recordingTx records the writes of the transaction, for the decorators
+// applying them once it's committed.
+type recordingTx struct {
+	Tx
+	writes []txWrite
+}
+
+func (t *recordingTx) Set(key string, value []byte, ttl time.Duration) error {
+	if err := t.Tx.Set(key, value, ttl); err != nil {
+		return err
+	}
+
+	t.writes = append(t.writes, txWrite{key: key, value: value, ttl: ttl})
+
+	return nil
+}
+
+func (t *recordingTx) Delete(key string) error {
+	if err := t.Tx.Delete(key); err != nil {
+		return err
+	}
+
+	t.writes = append(t.writes, txWrite{key: key, deleted: true})
+
+	return nil
+}
+
+// transactRecorded runs fn in a transaction of the storer and returns the
+// writes of the committed attempt.
+func transactRecorded(storer Storer, fn func(tx Tx) error) ([]txWrite, error) {
+	var tx *recordingTx
+
+	err := Transact(storer, func(inner Tx) error {
+		tx = &recordingTx{Tx: inner}
+
+		return fn(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.writes, nil
+}
@@ -0,0 +1,151 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// transactionalStorer is a memoryStorer implementing the Transactor, the
+// writes are applied once the function returns nil.
+type transactionalStorer struct {
+	*memoryStorer
+}
+
+type memoryTx struct {
+	storer  *memoryStorer
+	written map[string][]byte
+}
+
+func (m *memoryTx) Get(key string) ([]byte, error) {
+	value, ok := m.written[key]
+	if !ok {
+		value = m.storer.Get(key)
+	}
+
+	if value == nil {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return value, nil
+}
+
+func (m *memoryTx) Set(key string, value []byte, _ time.Duration) error {
+	m.written[key] = value
+
+	return nil
+}
+
+func (m *memoryTx) Delete(key string) error {
+	m.written[key] = nil
+
+	return nil
+}
+
+func (t transactionalStorer) Transact(fn func(tx core.Tx) error) error {
+	tx := &memoryTx{storer: t.memoryStorer, written: map[string][]byte{}}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	for key, value := range tx.written {
+		if value == nil {
+			t.Delete(key)
+		} else {
+			_ = t.Set(key, value, 0)
+		}
+	}
+
+	return nil
+}
+
+func TestTransact(t *testing.T) {
+	memory := newMemoryStorer()
+
+	storer, err := core.NewPrefixedStorer(transactionalStorer{memory}, "tenant-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = core.Transact(storer, func(tx core.Tx) error {
+		if _, err := tx.Get("value"); !errors.Is(err, core.ErrKeyNotFound) {
+			t.Errorf("the absent key should be reported, got %v", err)
+		}
+
+		_ = tx.Set("value", []byte("body"), time.Minute)
+		_ = tx.Set(core.MappingKeyPrefix+"base", []byte("index"), 0)
+
+		if value, _ := tx.Get("value"); string(value) != "body" {
+			t.Errorf("the transaction should see its writes, got %s", value)
+		}
+
+		return nil
+	})
+	if err != nil || string(memory.Get("tenant-value")) != "body" || string(memory.Get(core.MappingKeyPrefix+"tenant-base")) != "index" {
+		t.Errorf("the prefixed keys should be written, %v", err)
+	}
+
+	failure := errors.New("rollback")
+
+	err = core.Transact(storer, func(tx core.Tx) error {
+		_ = tx.Delete("value")
+
+		return failure
+	})
+	if !errors.Is(err, failure) || memory.Get("tenant-value") == nil {
+		t.Errorf("the writes should be discarded on error, %v", err)
+	}
+
+	if err = core.Transact(newMemoryStorer(), func(core.Tx) error { return nil }); !errors.Is(err, core.ErrTransactionNotSupported) {
+		t.Errorf("the storer without transactions should be reported, got %v", err)
+	}
+}
+
+func TestTransact_Decorators(t *testing.T) {
+	write := func(tx core.Tx) error {
+		return tx.Set("value", []byte("body"), time.Minute)
+	}
+
+	readOnly := core.NewReadOnlyStorer(transactionalStorer{newMemoryStorer()}, true, nopLogger{})
+	if err := core.Transact(readOnly, write); !errors.Is(err, core.ErrReadOnly) || readOnly.Get("value") != nil {
+		t.Errorf("the read-only storer should reject the transaction, got %v", err)
+	}
+
+	quota, _ := core.NewQuotaStorer(transactionalStorer{newMemoryStorer()}, core.QuotaOptions{MaxEntries: 1}, nopLogger{})
+	_ = quota.Set("first", []byte("body"), time.Minute)
+
+	if err := core.Transact(quota, write); err != nil || quota.Get("first") != nil {
+		t.Errorf("the transaction writes should be counted against the quota, got %v", err)
+	}
+
+	secondary := newMemoryStorer()
+
+	replicated, _ := core.NewReplicatedStorer(transactionalStorer{newMemoryStorer()}, []core.Storer{secondary}, core.ReplicationOptions{}, nopLogger{})
+	if err := core.Transact(replicated, write); err != nil || string(secondary.Get("value")) != "body" {
+		t.Errorf("the transaction writes should reach the secondaries, got %v", err)
+	}
+
+	breaker, _ := core.NewCircuitBreakerStorer(transactionalStorer{newMemoryStorer()}, core.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute, ProbeTimeout: time.Second}, nopLogger{})
+	_ = core.Transact(breaker, func(core.Tx) error { return errors.New("rollback") })
+
+	if err := core.Transact(breaker, write); err != nil || breaker.State() != core.CircuitClosed {
+		t.Errorf("the aborted transaction shouldn't open the circuit, got %v", err)
+	}
+}
+
+func TestRetryTransaction(t *testing.T) {
+	attempts := 0
+
+	err := core.RetryTransaction(func() error {
+		if attempts++; attempts < 3 {
+			return core.ErrTransactionConflict
+		}
+
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("the conflicting transaction should be retried, %d attempts, %v", attempts, err)
+	}
+}
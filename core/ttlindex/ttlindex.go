@@ -0,0 +1,324 @@
+// Package ttlindex tracks the expiration of the keys of the storages without
+// a native or a precise expiry. The index answers whether a key expired at
+// a given time and a sweeper removes the expired keys from the storage. It
+// can be persisted alongside the data to survive the restarts.
+package ttlindex
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	magic = []byte("TTL1")
+
+	errMalformedIndex = errors.New("malformed TTL index")
+)
+
+type entry struct {
+	key       string
+	expiresAt time.Time
+	// position is the index of the entry in the heap.
+	position int
+}
+
+// expirations is a min-heap of the entries ordered by expiration.
+type expirations []*entry
+
+func (e expirations) Len() int {
+	return len(e)
+}
+
+func (e expirations) Less(i, j int) bool {
+	return e[i].expiresAt.Before(e[j].expiresAt)
+}
+
+func (e expirations) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+	e[i].position = i
+	e[j].position = j
+}
+
+func (e *expirations) Push(x any) {
+	item, _ := x.(*entry)
+	item.position = len(*e)
+	*e = append(*e, item)
+}
+
+func (e *expirations) Pop() any {
+	old := *e
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*e = old[:len(old)-1]
+
+	return item
+}
+
+// Index is a min-heap of the keys expirations, safe for a concurrent use.
+// The keys without expiration aren't tracked.
+type Index struct {
+	mu      sync.Mutex
+	heap    expirations
+	entries map[string]*entry
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{entries: map[string]*entry{}}
+}
+
+// Set tracks the expiration of the key, a zero expiration stops tracking it.
+func (i *Index) Set(key string, expiresAt time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.set(key, expiresAt)
+}
+
+func (i *Index) set(key string, expiresAt time.Time) {
+	current, found := i.entries[key]
+
+	switch {
+	case expiresAt.IsZero() && found:
+		heap.Remove(&i.heap, current.position)
+		delete(i.entries, key)
+	case expiresAt.IsZero():
+	case found:
+		current.expiresAt = expiresAt
+		heap.Fix(&i.heap, current.position)
+	default:
+		current = &entry{key: key, expiresAt: expiresAt}
+		heap.Push(&i.heap, current)
+		i.entries[key] = current
+	}
+}
+
+// Delete stops tracking the key.
+func (i *Index) Delete(key string) {
+	i.Set(key, time.Time{})
+}
+
+// ExpiresAt returns the expiration of the key, false when it isn't tracked.
+func (i *Index) ExpiresAt(key string) (time.Time, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	current, found := i.entries[key]
+	if !found {
+		return time.Time{}, false
+	}
+
+	return current.expiresAt, true
+}
+
+// Expired returns true when the key is tracked and expired at now.
+func (i *Index) Expired(key string, now time.Time) bool {
+	expiresAt, found := i.ExpiresAt(key)
+
+	return found && !now.Before(expiresAt)
+}
+
+// Len returns the number of tracked keys.
+func (i *Index) Len() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return len(i.heap)
+}
+
+// Sweep stops tracking the keys expired at now and returns them, the
+// earliest expired first.
+func (i *Index) Sweep(now time.Time) []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	keys := []string{}
+
+	for len(i.heap) > 0 && !now.Before(i.heap[0].expiresAt) {
+		expired, _ := heap.Pop(&i.heap).(*entry)
+		delete(i.entries, expired.key)
+		keys = append(keys, expired.key)
+	}
+
+	return keys
+}
+
+// Start runs a sweeper every interval until Stop, onExpire removes the swept
+// keys from the storage. The keys set again since their sweep are given
+// too, the callback must check them with ExpiresAt.
+func (i *Index) Start(interval time.Duration, onExpire func(keys []string)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.stop != nil {
+		return
+	}
+
+	i.stop, i.done = make(chan struct{}), make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				if keys := i.Sweep(now); len(keys) > 0 {
+					onExpire(keys)
+				}
+			}
+		}
+	}(i.stop, i.done)
+}
+
+// Stop stops the sweeper and waits for its last sweep.
+func (i *Index) Stop() {
+	i.mu.Lock()
+	stop, done := i.stop, i.done
+	i.stop, i.done = nil, nil
+	i.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// WriteTo writes the tracked keys and their expiration to w.
+func (i *Index) WriteTo(w io.Writer) (int64, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	buffered := bufio.NewWriter(w)
+	header := make([]byte, len(magic)+4)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], uint32(len(i.heap)))
+
+	written, err := buffered.Write(header)
+	if err != nil {
+		return int64(written), err
+	}
+
+	record := make([]byte, 12)
+
+	for _, current := range i.heap {
+		binary.BigEndian.PutUint64(record[0:8], uint64(current.expiresAt.UnixNano()))
+		binary.BigEndian.PutUint32(record[8:12], uint32(len(current.key)))
+
+		n, err := buffered.Write(record)
+		written += n
+
+		if err != nil {
+			return int64(written), err
+		}
+
+		n, err = buffered.WriteString(current.key)
+		written += n
+
+		if err != nil {
+			return int64(written), err
+		}
+	}
+
+	return int64(written), buffered.Flush()
+}
+
+// ReadFrom tracks the keys written by WriteTo, the tracked keys are kept
+// unless the read index holds them too.
+func (i *Index) ReadFrom(r io.Reader) (int64, error) {
+	buffered := bufio.NewReader(r)
+	header := make([]byte, len(magic)+4)
+
+	read, err := io.ReadFull(buffered, header)
+	if err != nil {
+		return int64(read), fmt.Errorf("%w: %w", errMalformedIndex, err)
+	}
+
+	if string(header[:len(magic)]) != string(magic) {
+		return int64(read), errMalformedIndex
+	}
+
+	count := binary.BigEndian.Uint32(header[len(magic):])
+	record := make([]byte, 12)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for range count {
+		n, err := io.ReadFull(buffered, record)
+		read += n
+
+		if err != nil {
+			return int64(read), fmt.Errorf("%w: %w", errMalformedIndex, err)
+		}
+
+		key := make([]byte, binary.BigEndian.Uint32(record[8:12]))
+
+		n, err = io.ReadFull(buffered, key)
+		read += n
+
+		if err != nil {
+			return int64(read), fmt.Errorf("%w: %w", errMalformedIndex, err)
+		}
+
+		i.set(string(key), time.Unix(0, int64(binary.BigEndian.Uint64(record[0:8]))))
+	}
+
+	return int64(read), nil
+}
+
+// Save writes the index to the file at path, in a temporary file renamed in
+// place so an interrupted save never leaves a partial index.
+func (i *Index) Save(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err = i.WriteTo(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Load reads the index saved at path, a missing file is an empty index.
+func (i *Index) Load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = i.ReadFrom(file)
+
+	return err
+}
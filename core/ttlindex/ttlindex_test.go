@@ -0,0 +1,97 @@
+package ttlindex_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core/ttlindex"
+)
+
+func TestIndex_Sweep(t *testing.T) {
+	now := time.Now()
+	index := ttlindex.New()
+
+	index.Set("third", now.Add(3*time.Second))
+	index.Set("first", now.Add(time.Second))
+	index.Set("second", now.Add(2*time.Second))
+	index.Set("forever", time.Time{})
+
+	if index.Len() != 3 {
+		t.Errorf("The keys without expiration shouldn't be tracked, %d keys given", index.Len())
+	}
+
+	if !index.Expired("first", now.Add(time.Second)) || index.Expired("third", now.Add(time.Second)) || index.Expired("forever", now.Add(time.Hour)) {
+		t.Error("The expiration should be checked against the given time")
+	}
+
+	index.Set("third", now.Add(time.Hour))
+	index.Delete("second")
+
+	if keys := index.Sweep(now.Add(5 * time.Second)); !slices.Equal(keys, []string{"first"}) {
+		t.Errorf("The expired keys should be swept, %v given", keys)
+	}
+
+	if _, found := index.ExpiresAt("first"); found || index.Len() != 1 {
+		t.Error("The swept keys shouldn't be tracked anymore")
+	}
+}
+
+func TestIndex_Start(t *testing.T) {
+	index := ttlindex.New()
+	index.Set("key", time.Now().Add(10*time.Millisecond))
+
+	var (
+		mu    sync.Mutex
+		swept []string
+	)
+
+	index.Start(5*time.Millisecond, func(keys []string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		swept = append(swept, keys...)
+	})
+	defer index.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !slices.Equal(swept, []string{"key"}) {
+		t.Errorf("The sweeper should remove the expired key, %v given", swept)
+	}
+}
+
+func TestIndex_Persistence(t *testing.T) {
+	expiresAt := time.Unix(0, time.Now().Add(time.Minute).UnixNano())
+	index := ttlindex.New()
+	index.Set("first", expiresAt)
+	index.Set("second", expiresAt.Add(time.Minute))
+
+	path := filepath.Join(t.TempDir(), "ttl.index")
+	if err := index.Save(path); err != nil {
+		t.Fatalf("The index should be saved, %v given", err)
+	}
+
+	loaded := ttlindex.New()
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("The index should be loaded, %v given", err)
+	}
+
+	if at, found := loaded.ExpiresAt("first"); !found || !at.Equal(expiresAt) || loaded.Len() != 2 {
+		t.Errorf("The expirations should be restored, %s given", at)
+	}
+
+	if err := ttlindex.New().Load(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("A missing index should be empty, %v given", err)
+	}
+
+	if _, err := ttlindex.New().ReadFrom(bytes.NewReader([]byte("garbage"))); err == nil {
+		t.Error("A malformed index should be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTTLReadNotSupported is returned when no storer under the decorators
+// implements TTLReader.
+var ErrTTLReadNotSupported = errors.New("the storer doesn't support reading the TTLs")
+
+// TTLReader is an optional interface a Storer can implement to tell how long
+// a key has left, for the admin tools and the refresh-ahead logic. GetTTL
+// returns the remaining lifetime of the key, zero when it never expires, and
+// ErrKeyNotFound when it doesn't exist. The precision is the backend one,
+// e.g. the second for Badger.
+type TTLReader interface {
+	GetTTL(key string) (time.Duration, error)
+}
+
+// TTLReaderFor returns the TTLReader implemented by the storer or one of the
+// storers it decorates.
+func TTLReaderFor(storer Storer) (TTLReader, bool) {
+	for storer != nil {
+		if reader, ok := storer.(TTLReader); ok {
+			return reader, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// GetTTL returns the remaining lifetime of the key, using the TTLReader of
+// the storer. It returns ErrTTLReadNotSupported when there is none.
+func GetTTL(storer Storer, key string) (time.Duration, error) {
+	reader, ok := TTLReaderFor(storer)
+	if !ok {
+		return 0, ErrTTLReadNotSupported
+	}
+
+	return reader.GetTTL(key)
+}
+
+// RemainingTTL returns the lifetime left at now until expiresAt, zero for
+// the keys without expiration and ErrKeyNotFound for the expired ones.
+func RemainingTTL(expiresAt, now time.Time) (time.Duration, error) {
+	if expiresAt.IsZero() {
+		return 0, nil
+	}
+
+	remaining := expiresAt.Sub(now)
+	if remaining <= 0 {
+		return 0, ErrKeyNotFound
+	}
+
+	return remaining, nil
+}
@@ -0,0 +1,66 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// ttlStorer is a memoryStorer implementing the TTLReader with fixed TTLs.
+type ttlStorer struct {
+	*memoryStorer
+	ttls map[string]time.Duration
+}
+
+func (s ttlStorer) GetTTL(key string) (time.Duration, error) {
+	if s.Get(key) == nil {
+		return 0, core.ErrKeyNotFound
+	}
+
+	return s.ttls[key], nil
+}
+
+func TestGetTTL(t *testing.T) {
+	reader := ttlStorer{memoryStorer: newMemoryStorer(), ttls: map[string]time.Duration{"tenant-key": time.Minute, core.MappingKeyPrefix + "tenant-base": time.Hour}}
+	_ = reader.Set("tenant-key", []byte("value"), time.Minute)
+	_ = reader.Set(core.MappingKeyPrefix+"tenant-base", []byte("mapping"), time.Hour)
+
+	storer, err := core.NewPrefixedStorer(reader, "tenant-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl, err := core.GetTTL(storer, "key"); err != nil || ttl != time.Minute {
+		t.Errorf("The TTL of the prefixed key should be returned, %s and %v given", ttl, err)
+	}
+
+	if ttl, err := core.GetTTL(storer, core.MappingKeyPrefix+"base"); err != nil || ttl != time.Hour {
+		t.Errorf("The TTL of the prefixed mapping key should be returned, %s and %v given", ttl, err)
+	}
+
+	if _, err := core.GetTTL(storer, "missing"); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should be reported, %v given", err)
+	}
+
+	if _, err := core.GetTTL(newMemoryStorer(), "key"); !errors.Is(err, core.ErrTTLReadNotSupported) {
+		t.Errorf("The storer without TTL read should be reported, %v given", err)
+	}
+}
+
+func TestRemainingTTL(t *testing.T) {
+	now := time.Now()
+
+	if ttl, err := core.RemainingTTL(time.Time{}, now); ttl != 0 || err != nil {
+		t.Errorf("A key without expiration should have no TTL, %s and %v given", ttl, err)
+	}
+
+	if ttl, err := core.RemainingTTL(now.Add(time.Minute), now); ttl != time.Minute || err != nil {
+		t.Errorf("The lifetime left should be returned, %s and %v given", ttl, err)
+	}
+
+	if _, err := core.RemainingTTL(now, now); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("An expired key should be reported missing, %v given", err)
+	}
+}
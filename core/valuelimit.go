@@ -0,0 +1,493 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// MaxValueBytesConfigurationKey is the key read from the provider
+	// configuration to bound the size of the stored values.
+	MaxValueBytesConfigurationKey = "max_value_bytes"
+	// ValueOverflowConfigurationKey is the key read from the provider
+	// configuration to choose what happens to the values over the limit.
+	ValueOverflowConfigurationKey = "value_overflow"
+	// SpillStorageConfigurationKey is the key read from the provider
+	// configuration to declare the storage receiving the spilled values.
+	SpillStorageConfigurationKey = "spill_storage"
+
+	// ValueOverflowReject fails the writes of the values over the limit.
+	ValueOverflowReject = "reject"
+	// ValueOverflowTruncate stores the headers of the responses over the
+	// limit without their body.
+	ValueOverflowTruncate = "truncate"
+	// ValueOverflowSpill stores the values over the limit in the spill
+	// storer.
+	ValueOverflowSpill = "spill"
+
+	// TruncatedHeader is added to the responses stored without their body,
+	// it holds the size of the original response.
+	TruncatedHeader = "Storages-Truncated"
+)
+
+// ErrValueTooLarge is returned by the ValueLimitStorer writes of the values
+// over the limit which can't be truncated nor spilled.
+var ErrValueTooLarge = errors.New("the value is too large")
+
+// ValueLimitOptions tunes the ValueLimitStorer.
+type ValueLimitOptions struct {
+	// MaxBytes is the size of the stored values at most.
+	MaxBytes int
+	// Overflow is ValueOverflowReject, ValueOverflowTruncate or
+	// ValueOverflowSpill.
+	Overflow string
+	// Spill receives the values over the limit with ValueOverflowSpill.
+	Spill Storer
+}
+
+func (o ValueLimitOptions) validate() error {
+	if o.MaxBytes <= 0 {
+		return fmt.Errorf("the max_value_bytes must be positive, %d given", o.MaxBytes)
+	}
+
+	switch o.Overflow {
+	case ValueOverflowReject, ValueOverflowTruncate:
+		return nil
+	case ValueOverflowSpill:
+		if o.Spill == nil {
+			return errors.New("the spill overflow requires a spill_storage")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("the value_overflow must be %s, %s or %s, %s given", ValueOverflowReject, ValueOverflowTruncate, ValueOverflowSpill, o.Overflow)
+	}
+}
+
+// ValueLimitStorer decorates any Storer to apply the same policy to the
+// values over MaxBytes whatever the backend:
+//   - ValueOverflowReject fails the write with ErrValueTooLarge.
+//   - ValueOverflowTruncate stores the responses headers only, with the
+//     TruncatedHeader. They're only served to the HEAD requests, the other
+//     values are rejected.
+//   - ValueOverflowSpill stores the value in the spill storer, read when the
+//     decorated storer misses. The deletions reach both storers.
+//
+// The size is the one of the value given to the storer, before its
+// compression.
+type ValueLimitStorer struct {
+	Storer
+
+	options ValueLimitOptions
+	logger  Logger
+}
+
+// NewValueLimitStorer wraps the storer with the limit.
+func NewValueLimitStorer(storer Storer, options ValueLimitOptions, logger Logger) (*ValueLimitStorer, error) {
+	if options.Overflow == "" {
+		options.Overflow = ValueOverflowReject
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid max_value_bytes configuration: %w", err)
+	}
+
+	return &ValueLimitStorer{Storer: storer, options: options, logger: logger}, nil
+}
+
+// valueLimitConfiguration is the typed limit read from the provider
+// configuration.
+type valueLimitConfiguration struct {
+	MaxValueBytes int    `json:"max_value_bytes"`
+	ValueOverflow string `json:"value_overflow"`
+	SpillStorage  *struct {
+		Name          string `json:"name"`
+		URL           string `json:"url"`
+		Path          string `json:"path"`
+		Configuration any    `json:"configuration"`
+	} `json:"spill_storage"`
+	Others map[string]any `json:",remain"`
+}
+
+// ValueLimitStorerFromConfiguration wraps the storer when the
+// max_value_bytes key is set in the provider configuration, it returns the
+// storer untouched otherwise. The spill storage is created with NewStorer.
+func ValueLimitStorerFromConfiguration(storer Storer, provider CacheProvider, stale time.Duration, logger Logger) (Storer, error) {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return storer, nil
+	}
+
+	if _, ok = cfg[MaxValueBytesConfigurationKey]; !ok {
+		return storer, nil
+	}
+
+	var limit valueLimitConfiguration
+	if err := DecodeConfiguration(cfg, &limit); err != nil {
+		return nil, fmt.Errorf("invalid max_value_bytes configuration: %w", err)
+	}
+
+	options := ValueLimitOptions{MaxBytes: limit.MaxValueBytes, Overflow: limit.ValueOverflow}
+
+	if limit.SpillStorage != nil {
+		spill, err := NewStorer(limit.SpillStorage.Name, CacheProvider{
+			URL:           limit.SpillStorage.URL,
+			Path:          limit.SpillStorage.Path,
+			Configuration: limit.SpillStorage.Configuration,
+		}, logger, stale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spill_storage configuration: %w", err)
+		}
+
+		options.Spill = spill
+	}
+
+	return NewValueLimitStorer(storer, options, logger)
+}
+
+// Unwrap returns the decorated storer.
+func (s *ValueLimitStorer) Unwrap() Storer {
+	return s.Storer
+}
+
+// Spill returns the spill storer, nil unless the overflow is
+// ValueOverflowSpill.
+func (s *ValueLimitStorer) Spill() Storer {
+	if s.options.Overflow != ValueOverflowSpill {
+		return nil
+	}
+
+	return s.options.Spill
+}
+
+// truncateResponse returns the response headers with an empty body, false
+// when the value isn't a response.
+func truncateResponse(value []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(value, []byte("HTTP/")) {
+		return nil, false
+	}
+
+	head, _, found := bytes.Cut(value, []byte("\r\n\r\n"))
+	if !found {
+		return nil, false
+	}
+
+	var truncated bytes.Buffer
+
+	for _, line := range bytes.Split(head, []byte("\r\n")) {
+		name, _, _ := bytes.Cut(line, []byte(":"))
+		if header := http.CanonicalHeaderKey(string(bytes.TrimSpace(name))); header == "Content-Length" || header == "Transfer-Encoding" {
+			continue
+		}
+
+		truncated.Write(line)
+		truncated.WriteString("\r\n")
+	}
+
+	truncated.WriteString("Content-Length: 0\r\n" + TruncatedHeader + ": " + strconv.Itoa(len(value)) + "\r\n\r\n")
+
+	return truncated.Bytes(), true
+}
+
+// overflow returns the value to store in the decorated storer, nil when it
+// must be spilled, or the error rejecting it.
+func (s *ValueLimitStorer) overflow(key string, value []byte) ([]byte, error) {
+	if len(value) <= s.options.MaxBytes {
+		return value, nil
+	}
+
+	switch s.options.Overflow {
+	case ValueOverflowTruncate:
+		if truncated, ok := truncateResponse(value); ok {
+			s.logger.Debugf("Store the key %s without its body, its %d bytes are over the %d limit", key, len(value), s.options.MaxBytes)
+
+			return truncated, nil
+		}
+	case ValueOverflowSpill:
+		return nil, nil
+	}
+
+	s.logger.Errorf("Impossible to store the key %s, its %d bytes are over the %d limit", key, len(value), s.options.MaxBytes)
+
+	return nil, fmt.Errorf("%w: %d bytes are over the %d limit", ErrValueTooLarge, len(value), s.options.MaxBytes)
+}
+
+// spill stores the value over the limit in the spill storer with the write,
+// then deletes the previous value the decorated storer may still hold when
+// the write stored it.
+func (s *ValueLimitStorer) spill(key string, write func(spill Storer) (bool, error)) (bool, error) {
+	stored, err := write(s.options.Spill)
+	if err != nil {
+		s.logger.Errorf("Impossible to spill the key %s into %s, %v", key, s.options.Spill.Name(), err)
+
+		return false, err
+	}
+
+	if stored {
+		s.Storer.Delete(key)
+	}
+
+	return stored, nil
+}
+
+// Get method returns the stored value, the spilled one on a miss.
+func (s *ValueLimitStorer) Get(key string) []byte {
+	value := s.Storer.Get(key)
+	if len(value) == 0 && s.Spill() != nil {
+		return s.options.Spill.Get(key)
+	}
+
+	return value
+}
+
+// Lookup method returns the stored value, the spilled one on a miss.
+func (s *ValueLimitStorer) Lookup(key string) ([]byte, error) {
+	value, err := Lookup(s.Storer, key)
+	if errors.Is(err, ErrKeyNotFound) && s.Spill() != nil {
+		return Lookup(s.options.Spill, key)
+	}
+
+	return value, err
+}
+
+// GetMultiLevel returns the fresh and stale candidates, the spilled ones on
+// a miss. The truncated responses are only returned to the HEAD requests.
+func (s *ValueLimitStorer) GetMultiLevel(key string, req *http.Request, validator *Revalidator) (fresh *http.Response, stale *http.Response) {
+	fresh, stale = s.Storer.GetMultiLevel(key, req, validator)
+	if fresh == nil && stale == nil && s.Spill() != nil {
+		fresh, stale = s.options.Spill.GetMultiLevel(key, req, validator)
+	}
+
+	if req.Method != http.MethodHead {
+		fresh, stale = dropTruncated(fresh), dropTruncated(stale)
+	}
+
+	return fresh, stale
+}
+
+func dropTruncated(response *http.Response) *http.Response {
+	if response == nil || response.Header.Get(TruncatedHeader) == "" {
+		return response
+	}
+
+	_ = response.Body.Close()
+
+	return nil
+}
+
+// Set method stores the value, applying the overflow policy over the limit.
+func (s *ValueLimitStorer) Set(key string, value []byte, duration time.Duration) error {
+	limited, err := s.overflow(key, value)
+	if err != nil {
+		return err
+	}
+
+	if limited == nil {
+		_, err = s.spill(key, func(spill Storer) (bool, error) {
+			return true, spill.Set(key, value, duration)
+		})
+
+		return err
+	}
+
+	return s.Storer.Set(key, limited, duration)
+}
+
+// SetMultiLevel stores the response, applying the overflow policy over the
+// limit.
+func (s *ValueLimitStorer) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	limited, err := s.overflow(variedKey, value)
+	if err != nil {
+		return err
+	}
+
+	if limited == nil {
+		_, err = s.spill(variedKey, func(spill Storer) (bool, error) {
+			return true, spill.SetMultiLevel(baseKey, variedKey, value, variedHeaders, etag, duration, realKey)
+		})
+
+		return err
+	}
+
+	return s.Storer.SetMultiLevel(baseKey, variedKey, limited, variedHeaders, etag, duration, realKey)
+}
+
+// SetNX stores the value only if the key doesn't exist, applying the
+// overflow policy over the limit. A spilled value is stored only if neither
+// storer holds the key.
+func (s *ValueLimitStorer) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	limited, err := s.overflow(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	if limited == nil {
+		return s.spill(key, func(spill Storer) (bool, error) {
+			if _, lookupErr := Lookup(s.Storer, key); !errors.Is(lookupErr, ErrKeyNotFound) {
+				return false, lookupErr
+			}
+
+			return SetNX(spill, key, value, ttl)
+		})
+	}
+
+	return SetNX(s.Storer, key, limited, ttl)
+}
+
+// CompareAndSwap replaces the value only if the stored one equals old,
+// applying the overflow policy over the limit. A spilled value replaces old
+// in the spill storer, or in the decorated storer when it holds old: that
+// check isn't atomic with the spill.
+func (s *ValueLimitStorer) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	limited, err := s.overflow(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	if limited == nil {
+		return s.spill(key, func(spill Storer) (bool, error) {
+			swapped, swapErr := CompareAndSwap(spill, key, old, value, ttl)
+			if swapErr != nil || swapped {
+				return swapped, swapErr
+			}
+
+			if current, lookupErr := Lookup(s.Storer, key); lookupErr != nil || !bytes.Equal(current, old) {
+				return false, nil
+			}
+
+			return SetNX(spill, key, value, ttl)
+		})
+	}
+
+	return CompareAndSwap(s.Storer, key, old, limited, ttl)
+}
+
+// limitedTx applies the overflow policy to the writes of a transaction, the
+// values to spill are stored once it's committed.
+type limitedTx struct {
+	Tx
+	storer  *ValueLimitStorer
+	spilled []txWrite
+}
+
+func (t *limitedTx) Set(key string, value []byte, ttl time.Duration) error {
+	limited, err := t.storer.overflow(key, value)
+	if err != nil {
+		return err
+	}
+
+	if limited == nil {
+		t.spilled = append(t.spilled, txWrite{key: key, value: value, ttl: ttl})
+
+		// The previous value may still be held by the decorated storer.
+		return t.Tx.Delete(key)
+	}
+
+	return t.Tx.Set(key, limited, ttl)
+}
+
+// Transact runs fn in a transaction of the decorated storer, applying the
+// overflow policy to its writes. The spilled values are stored in the spill
+// storer once the transaction is committed, outside of it.
+func (s *ValueLimitStorer) Transact(fn func(tx Tx) error) error {
+	var tx *limitedTx
+
+	err := Transact(s.Storer, func(inner Tx) error {
+		tx = &limitedTx{Tx: inner, storer: s}
+
+		return fn(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+
+	for _, write := range tx.spilled {
+		if err = s.options.Spill.Set(write.key, write.value, write.ttl); err != nil {
+			s.logger.Errorf("Impossible to spill the key %s into %s, %v", write.key, s.options.Spill.Name(), err)
+		}
+
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Delete method deletes the key from the decorated and the spill storers.
+func (s *ValueLimitStorer) Delete(key string) {
+	s.Storer.Delete(key)
+
+	if s.Spill() != nil {
+		s.options.Spill.Delete(key)
+	}
+}
+
+// DeleteMany method deletes the matching keys from the decorated and the
+// spill storers.
+func (s *ValueLimitStorer) DeleteMany(key string) {
+	s.Storer.DeleteMany(key)
+
+	if s.Spill() != nil {
+		s.options.Spill.DeleteMany(key)
+	}
+}
+
+// MapKeys method returns the keys of both storers, the decorated storer
+// values win.
+func (s *ValueLimitStorer) MapKeys(prefix string) map[string]string {
+	keys := s.Storer.MapKeys(prefix)
+
+	if s.Spill() != nil {
+		for key, value := range s.options.Spill.MapKeys(prefix) {
+			if _, found := keys[key]; !found {
+				keys[key] = value
+			}
+		}
+	}
+
+	return keys
+}
+
+// ListKeys method returns the keys of both storers.
+func (s *ValueLimitStorer) ListKeys() []string {
+	keys := s.Storer.ListKeys()
+	if s.Spill() == nil {
+		return keys
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		seen[key] = struct{}{}
+	}
+
+	for _, key := range s.options.Spill.ListKeys() {
+		if _, found := seen[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Init method initializes the decorated and the spill storers.
+func (s *ValueLimitStorer) Init() error {
+	if s.Spill() != nil {
+		return errors.Join(s.Storer.Init(), s.options.Spill.Init())
+	}
+
+	return s.Storer.Init()
+}
+
+// Reset method resets the decorated and the spill storers.
+func (s *ValueLimitStorer) Reset() error {
+	if s.Spill() != nil {
+		return errors.Join(s.Storer.Reset(), s.options.Spill.Reset())
+	}
+
+	return s.Storer.Reset()
+}
@@ -0,0 +1,180 @@
+package core_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func largeResponse(size int) []byte {
+	return []byte("HTTP/1.1 200 OK\r\nContent-Length: " + strconv.Itoa(size) + "\r\nEtag: \"large\"\r\n\r\n" + strings.Repeat("a", size))
+}
+
+func TestValueLimitStorer_Reject(t *testing.T) {
+	backend := newMemoryStorer()
+	storer, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 16}, nopLogger{})
+
+	if err := storer.Set("small", []byte("value"), time.Minute); err != nil {
+		t.Errorf("A value under the limit should be stored, %v given", err)
+	}
+
+	if err := storer.Set("large", make([]byte, 32), time.Minute); !errors.Is(err, core.ErrValueTooLarge) {
+		t.Errorf("A value over the limit should be rejected, %v given", err)
+	}
+
+	if backend.Get("large") != nil {
+		t.Error("The rejected value shouldn't be stored")
+	}
+}
+
+func TestValueLimitStorer_Truncate(t *testing.T) {
+	backend := newMemoryStorer()
+	storer, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 100, Overflow: core.ValueOverflowTruncate}, nopLogger{})
+
+	if err := storer.SetMultiLevel("base", "base-varied", largeResponse(200), http.Header{}, "", time.Minute, "base-varied"); err != nil {
+		t.Fatalf("The response should be truncated, %v given", err)
+	}
+
+	head, _ := http.NewRequest(http.MethodHead, "http://domain.com/", nil)
+
+	fresh, _ := storer.GetMultiLevel("base", head, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The truncated response should be served to the HEAD requests")
+	}
+
+	body, _ := io.ReadAll(fresh.Body)
+	if len(body) != 0 || fresh.Header.Get("Etag") != "\"large\"" || fresh.Header.Get(core.TruncatedHeader) == "" {
+		t.Errorf("The headers should be kept without the body, %v and %q given", fresh.Header, body)
+	}
+
+	get, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+
+	if fresh, stale := storer.GetMultiLevel("base", get, &core.Revalidator{}); fresh != nil || stale != nil {
+		t.Error("The truncated response shouldn't be served to the GET requests")
+	}
+
+	if err := storer.Set("raw", make([]byte, 200), time.Minute); !errors.Is(err, core.ErrValueTooLarge) {
+		t.Errorf("A value which isn't a response should be rejected, %v given", err)
+	}
+}
+
+func TestValueLimitStorer_Spill(t *testing.T) {
+	backend, spill := newMemoryStorer(), newMemoryStorer()
+	storer, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 100, Overflow: core.ValueOverflowSpill, Spill: spill}, nopLogger{})
+
+	_ = storer.Set("key", []byte("small"), time.Minute)
+	_ = storer.Set("key", make([]byte, 200), time.Minute)
+
+	if backend.Get("key") != nil || len(spill.Get("key")) != 200 || len(storer.Get("key")) != 200 {
+		t.Error("The large value should be spilled and read from the spill storer")
+	}
+
+	if err := storer.SetMultiLevel("base", "base-varied", largeResponse(200), http.Header{}, "", time.Minute, "base-varied"); err != nil {
+		t.Fatalf("The response should be spilled, %v given", err)
+	}
+
+	get, _ := http.NewRequest(http.MethodGet, "http://domain.com/", nil)
+
+	if fresh, _ := storer.GetMultiLevel("base", get, &core.Revalidator{}); fresh == nil {
+		t.Error("The spilled response should be served")
+	}
+
+	storer.Delete("key")
+	storer.DeleteMany("^base")
+
+	if spill.Get("key") != nil || spill.Get("base-varied") != nil {
+		t.Error("The deletions should reach the spill storer")
+	}
+}
+
+func TestValueLimitStorer_ConditionalSet(t *testing.T) {
+	backend := conditionalStorer{newMemoryStorer()}
+	storer, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 100}, nopLogger{})
+
+	if _, err := core.SetNX(storer, "key", make([]byte, 200), time.Minute); !errors.Is(err, core.ErrValueTooLarge) || backend.Get("key") != nil {
+		t.Errorf("The SetNX over the limit should be rejected, %v given", err)
+	}
+
+	_ = storer.Set("key", []byte("small"), time.Minute)
+
+	if _, err := core.CompareAndSwap(storer, "key", []byte("small"), make([]byte, 200), time.Minute); !errors.Is(err, core.ErrValueTooLarge) || string(backend.Get("key")) != "small" {
+		t.Errorf("The CompareAndSwap over the limit should be rejected, %v given", err)
+	}
+
+	truncating, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 100, Overflow: core.ValueOverflowTruncate}, nopLogger{})
+
+	if stored, err := core.SetNX(truncating, "large", largeResponse(200), time.Minute); err != nil || !stored || !strings.Contains(string(backend.Get("large")), core.TruncatedHeader) {
+		t.Errorf("The SetNX over the limit should store the truncated response, %v given", err)
+	}
+
+	spill := conditionalStorer{newMemoryStorer()}
+	spilling, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 100, Overflow: core.ValueOverflowSpill, Spill: spill}, nopLogger{})
+
+	if stored, _ := core.SetNX(spilling, "key", make([]byte, 200), time.Minute); stored || spill.Get("key") != nil {
+		t.Error("The spilled SetNX shouldn't store the key held by the decorated storer")
+	}
+
+	if swapped, err := core.CompareAndSwap(spilling, "key", []byte("small"), make([]byte, 200), time.Minute); err != nil || !swapped || backend.Get("key") != nil || len(spill.Get("key")) != 200 {
+		t.Errorf("The CompareAndSwap over the limit should spill the value, %v given", err)
+	}
+
+	if swapped, _ := core.CompareAndSwap(spilling, "key", []byte("small"), make([]byte, 300), time.Minute); swapped {
+		t.Error("The spilled value shouldn't be swapped when it differs from old")
+	}
+}
+
+func TestValueLimitStorer_Transact(t *testing.T) {
+	backend := transactionalStorer{newMemoryStorer()}
+	storer, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 100}, nopLogger{})
+
+	if err := core.Transact(storer, func(tx core.Tx) error {
+		return tx.Set("key", make([]byte, 200), time.Minute)
+	}); !errors.Is(err, core.ErrValueTooLarge) || backend.Get("key") != nil {
+		t.Errorf("The transaction writing over the limit should be rejected, %v given", err)
+	}
+
+	spill := newMemoryStorer()
+	spilling, _ := core.NewValueLimitStorer(backend, core.ValueLimitOptions{MaxBytes: 100, Overflow: core.ValueOverflowSpill, Spill: spill}, nopLogger{})
+
+	_ = backend.Set("key", []byte("small"), time.Minute)
+
+	if err := core.Transact(spilling, func(tx core.Tx) error {
+		return tx.Set("key", make([]byte, 200), time.Minute)
+	}); err != nil || backend.Get("key") != nil || len(spill.Get("key")) != 200 {
+		t.Errorf("The transaction writing over the limit should spill the value, %v given", err)
+	}
+}
+
+func TestValueLimitStorerFromConfiguration(t *testing.T) {
+	storer := newMemoryStorer()
+
+	if wrapped, _ := core.ValueLimitStorerFromConfiguration(storer, core.CacheProvider{}, 0, nopLogger{}); wrapped != storer {
+		t.Error("The storer shouldn't be wrapped without max_value_bytes")
+	}
+
+	wrapped, err := core.ValueLimitStorerFromConfiguration(storer, core.CacheProvider{Configuration: map[string]interface{}{
+		"max_value_bytes": "1024",
+		"value_overflow":  "spill",
+		"spill_storage":   map[string]interface{}{"name": "memory"},
+	}}, 0, nopLogger{})
+	if limited, ok := wrapped.(*core.ValueLimitStorer); err != nil || !ok || limited.Spill() == nil {
+		t.Errorf("The storer should be wrapped with a spill storer, %v given", err)
+	}
+
+	for _, cfg := range []map[string]interface{}{
+		{"max_value_bytes": 0},
+		{"max_value_bytes": 10, "value_overflow": "drop"},
+		{"max_value_bytes": 10, "value_overflow": "spill"},
+		{"max_value_bytes": 10, "value_overflow": "spill", "spill_storage": map[string]interface{}{"name": "unknown"}},
+	} {
+		if _, err = core.ValueLimitStorerFromConfiguration(storer, core.CacheProvider{Configuration: cfg}, 0, nopLogger{}); err == nil {
+			t.Errorf("The configuration %v should be rejected", cfg)
+		}
+	}
+}
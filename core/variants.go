@@ -0,0 +1,98 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// MaxVariantsConfigurationKey is the key read from the provider
+// configuration to cap the varied entries of every mapping.
+const MaxVariantsConfigurationKey = "max_variants"
+
+var (
+	maxVariants           atomic.Int64
+	configuredMaxVariants = sharedSetting{key: MaxVariantsConfigurationKey}
+)
+
+// SetMaxVariants caps the varied entries of every mapping of the process,
+// MappingUpdater evicts the least recently stored ones beyond. Zero removes
+// the cap. It overrides the cap configured by the storages.
+func SetMaxVariants(limit int) {
+	configuredMaxVariants.reset()
+	maxVariants.Store(int64(max(limit, 0)))
+}
+
+// MaxVariants returns the cap of the varied entries per mapping, zero when
+// unbounded.
+func MaxVariants() int {
+	return int(maxVariants.Load())
+}
+
+// maxVariantsConfiguration is the typed max_variants key.
+type maxVariantsConfiguration struct {
+	MaxVariants int `json:"max_variants"`
+}
+
+// SetMaxVariantsFromConfiguration applies the max_variants key of the
+// provider configuration, if any. The cap is shared by the whole process,
+// the storages declaring another cap than the configured one are rejected.
+func SetMaxVariantsFromConfiguration(provider CacheProvider) error {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if _, ok = cfg[MaxVariantsConfigurationKey]; !ok {
+		return nil
+	}
+
+	var variants maxVariantsConfiguration
+	if err := DecodeConfiguration(map[string]interface{}{MaxVariantsConfigurationKey: cfg[MaxVariantsConfigurationKey]}, &variants); err != nil {
+		return fmt.Errorf("invalid max_variants configuration: %w", err)
+	}
+
+	if variants.MaxVariants < 0 {
+		return fmt.Errorf("invalid max_variants configuration: the cap can't be negative, %d given", variants.MaxVariants)
+	}
+
+	return configuredMaxVariants.configure(variants.MaxVariants, func() {
+		maxVariants.Store(int64(variants.MaxVariants))
+	})
+}
+
+// evictVariants removes the least recently stored entries exceeding the cap
+// from the mapping and returns their keys. The written key is never evicted,
+// the entries stored at the same time are evicted by key order.
+func evictVariants(mapping *StorageMapper, limit int, written string) []string {
+	if limit <= 0 || len(mapping.GetMapping()) <= limit {
+		return nil
+	}
+
+	keys := make([]string, 0, len(mapping.GetMapping()))
+	for key := range mapping.GetMapping() {
+		if key != written {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+	slices.SortStableFunc(keys, func(a, b string) int {
+		return mapping.Mapping[a].GetStoredAt().AsTime().Compare(mapping.Mapping[b].GetStoredAt().AsTime())
+	})
+
+	evicted := keys[:len(mapping.GetMapping())-limit]
+	for _, key := range evicted {
+		delete(mapping.Mapping, key)
+	}
+
+	return evicted
+}
+
+// DeleteEvictedVariants deletes the values of the variants evicted by
+// MappingUpdaterWithEvictions.
+func DeleteEvictedVariants(storer Storer, evicted []string) {
+	for _, key := range evicted {
+		storer.Delete(key)
+	}
+}
@@ -0,0 +1,95 @@
+package core_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestMappingUpdaterWithEvictions(t *testing.T) {
+	core.SetMaxVariants(2)
+	defer core.SetMaxVariants(0)
+
+	var (
+		mapping []byte
+		evicted []string
+		err     error
+	)
+
+	now := time.Now()
+
+	for i, key := range []string{"oldest", "middle", "newest"} {
+		storedAt := now.Add(time.Duration(i) * time.Second)
+
+		mapping, evicted, err = core.MappingUpdaterWithEvictions(key, mapping, nopLogger{}, storedAt, storedAt.Add(time.Minute), storedAt.Add(time.Hour), http.Header{}, "", key)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if len(evicted) != 1 || evicted[0] != "oldest" {
+		t.Errorf("The least recently stored variant should be evicted, %v given", evicted)
+	}
+
+	decoded, _ := core.DecodeMapping(mapping)
+	if len(decoded.GetMapping()) != 2 || decoded.GetMapping()["oldest"] != nil {
+		t.Errorf("The mapping should hold the 2 newest variants, %v given", decoded.GetMapping())
+	}
+
+	core.SetMaxVariants(0)
+
+	if _, evicted, _ = core.MappingUpdaterWithEvictions("another", mapping, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), nil, "", ""); len(evicted) != 0 {
+		t.Error("The variants shouldn't be evicted without cap")
+	}
+}
+
+func TestMappingUpdaterWithEvictions_SameStoredAt(t *testing.T) {
+	core.SetMaxVariants(2)
+	defer core.SetMaxVariants(0)
+
+	var (
+		mapping []byte
+		evicted []string
+		err     error
+	)
+
+	now := time.Now()
+
+	for _, key := range []string{"b", "c", "a"} {
+		mapping, evicted, err = core.MappingUpdaterWithEvictions(key, mapping, nopLogger{}, now, now.Add(time.Minute), now.Add(time.Hour), http.Header{}, "", key)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("The written variant should be kept and the others evicted by key order, %v given", evicted)
+	}
+
+	decoded, _ := core.DecodeMapping(mapping)
+	if decoded.GetMapping()["a"] == nil || decoded.GetMapping()["c"] == nil {
+		t.Errorf("The mapping should hold the written variant, %v given", decoded.GetMapping())
+	}
+}
+
+func TestSetMaxVariantsFromConfiguration(t *testing.T) {
+	defer core.SetMaxVariants(0)
+
+	if err := core.SetMaxVariantsFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{"max_variants": "8"}}); err != nil || core.MaxVariants() != 8 {
+		t.Errorf("The cap should be 8, %d given, %v", core.MaxVariants(), err)
+	}
+
+	if err := core.SetMaxVariantsFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{"max_variants": -1}}); err == nil {
+		t.Error("The negative cap should be rejected")
+	}
+
+	if err := core.SetMaxVariantsFromConfiguration(core.CacheProvider{}); err != nil || core.MaxVariants() != 8 {
+		t.Error("The cap should be kept without the key")
+	}
+
+	if err := core.SetMaxVariantsFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{"max_variants": 4}}); err == nil || core.MaxVariants() != 8 {
+		t.Error("Another cap declared by another storage should be rejected")
+	}
+}
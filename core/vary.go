@@ -0,0 +1,193 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// VaryNormalizationConfigurationKey is the key read from the provider
+// configuration to declare the VaryNormalization rules.
+const VaryNormalizationConfigurationKey = "vary_normalization"
+
+// VaryNormalization are the rules applied to the varied header values before
+// they're stored in the mappings by MappingUpdater and compared by
+// MappingElection, so equivalent requests share the same variant.
+type VaryNormalization struct {
+	// CaseInsensitive lowercases the values.
+	CaseInsensitive bool `json:"case_insensitive"`
+	// SortTokens lists the headers whose comma separated tokens are sorted,
+	// e.g. Accept-Encoding.
+	SortTokens []string `json:"sort_tokens"`
+	// StripQValues lists the headers whose tokens lose their parameters, e.g.
+	// Accept-Language. The tokens with a zero q-value are dropped.
+	StripQValues []string `json:"strip_q_values"`
+	// Ignore lists the headers never compared.
+	Ignore []string `json:"ignore"`
+}
+
+var (
+	varyNormalization           atomic.Pointer[VaryNormalization]
+	configuredVaryNormalization = sharedSetting{key: VaryNormalizationConfigurationKey}
+)
+
+// SetVaryNormalization applies the rules to every mapping of the process,
+// the mappings stored before are normalized when they're compared. Passing
+// nil restores the exact matching. It overrides the rules configured by the
+// storages.
+func SetVaryNormalization(rules *VaryNormalization) {
+	configuredVaryNormalization.reset()
+
+	if rules == nil {
+		varyNormalization.Store(nil)
+
+		return
+	}
+
+	normalized := canonicalVaryNormalization(*rules)
+	varyNormalization.Store(&normalized)
+}
+
+// canonicalVaryNormalization returns the rules with the canonical header
+// names.
+func canonicalVaryNormalization(rules VaryNormalization) VaryNormalization {
+	normalized := VaryNormalization{CaseInsensitive: rules.CaseInsensitive}
+	for _, header := range rules.SortTokens {
+		normalized.SortTokens = append(normalized.SortTokens, http.CanonicalHeaderKey(header))
+	}
+
+	for _, header := range rules.StripQValues {
+		normalized.StripQValues = append(normalized.StripQValues, http.CanonicalHeaderKey(header))
+	}
+
+	for _, header := range rules.Ignore {
+		normalized.Ignore = append(normalized.Ignore, http.CanonicalHeaderKey(header))
+	}
+
+	return normalized
+}
+
+// SetVaryNormalizationFromConfiguration applies the rules declared under the
+// vary_normalization key of the provider configuration, if any. The rules
+// are shared by the whole process, the storages declaring other rules than
+// the configured ones are rejected.
+func SetVaryNormalizationFromConfiguration(provider CacheProvider) error {
+	cfg, ok := provider.Configuration.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	rulesCfg, ok := cfg[VaryNormalizationConfigurationKey]
+	if !ok {
+		return nil
+	}
+
+	var rules VaryNormalization
+	if err := DecodeConfiguration(rulesCfg, &rules); err != nil {
+		return fmt.Errorf("invalid vary_normalization configuration: %w", err)
+	}
+
+	normalized := canonicalVaryNormalization(rules)
+
+	return configuredVaryNormalization.configure(normalized, func() {
+		varyNormalization.Store(&normalized)
+	})
+}
+
+// VaryIgnored reports whether the header is never compared.
+func VaryIgnored(name string) bool {
+	rules := varyNormalization.Load()
+
+	return rules != nil && slices.Contains(rules.Ignore, http.CanonicalHeaderKey(name))
+}
+
+// NormalizeVaryValue returns the value of the varied header with the rules
+// applied, the value itself without rules.
+func NormalizeVaryValue(name, value string) string {
+	rules := varyNormalization.Load()
+	if rules == nil {
+		return value
+	}
+
+	name = http.CanonicalHeaderKey(name)
+
+	if rules.CaseInsensitive {
+		value = strings.ToLower(value)
+	}
+
+	stripQValues := slices.Contains(rules.StripQValues, name)
+	sortTokens := slices.Contains(rules.SortTokens, name)
+
+	if !stripQValues && !sortTokens {
+		return value
+	}
+
+	tokens := []string{}
+
+	for _, token := range strings.Split(value, ",") {
+		token = strings.TrimSpace(token)
+		if stripQValues {
+			var params string
+
+			token, params, _ = strings.Cut(token, ";")
+			token = strings.TrimSpace(token)
+
+			if zeroQValue(params) {
+				continue
+			}
+		}
+
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	if sortTokens {
+		slices.Sort(tokens)
+	}
+
+	return strings.Join(tokens, ", ")
+}
+
+// zeroQValue reports whether the token parameters hold q=0.
+func zeroQValue(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if value = strings.TrimSpace(value); strings.EqualFold(key, "q") && value != "" {
+			return strings.Trim(value, "0.") == ""
+		}
+	}
+
+	return false
+}
+
+// varyMatches reports whether the request header matches the stored varied
+// values.
+func varyMatches(req *http.Request, name string, stored []string) bool {
+	if VaryIgnored(name) {
+		return true
+	}
+
+	return NormalizeVaryValue(name, req.Header.Get(name)) == NormalizeVaryValue(name, strings.Join(stored, ", "))
+}
+
+// normalizeVariedHeaders returns the varied headers to store in the mapping.
+func normalizeVariedHeaders(variedHeaders http.Header) http.Header {
+	if varyNormalization.Load() == nil || variedHeaders == nil {
+		return variedHeaders
+	}
+
+	normalized := make(http.Header, len(variedHeaders))
+
+	for name, values := range variedHeaders {
+		if VaryIgnored(name) {
+			continue
+		}
+
+		normalized[name] = []string{NormalizeVaryValue(name, strings.Join(values, ", "))}
+	}
+
+	return normalized
+}
@@ -0,0 +1,108 @@
+package core_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+func TestNormalizeVaryValue(t *testing.T) {
+	if core.NormalizeVaryValue("Accept-Encoding", "GZIP, br") != "GZIP, br" {
+		t.Error("The values should be untouched without rules")
+	}
+
+	core.SetVaryNormalization(&core.VaryNormalization{
+		CaseInsensitive: true,
+		SortTokens:      []string{"accept-encoding", "Accept-Language"},
+		StripQValues:    []string{"accept-language"},
+		Ignore:          []string{"user-agent"},
+	})
+	defer core.SetVaryNormalization(nil)
+
+	for _, tc := range []struct{ name, value, expected string }{
+		{"Accept-Encoding", "GZIP, br,deflate", "br, deflate, gzip"},
+		{"Accept-Language", "fr-FR;q=0.9, en;q=0.8, de;q=0, it;q=0.0", "en, fr-fr"},
+		{"Accept-Language", "fr;q=", "fr"},
+		{"Cookie", "Session=ABC, b", "session=abc, b"},
+	} {
+		if normalized := core.NormalizeVaryValue(tc.name, tc.value); normalized != tc.expected {
+			t.Errorf("The %s value %q should be normalized as %q, %q given", tc.name, tc.value, tc.expected, normalized)
+		}
+	}
+
+	if !core.VaryIgnored("User-Agent") || core.VaryIgnored("Accept-Encoding") {
+		t.Error("Only the ignore-listed headers should be ignored")
+	}
+}
+
+func TestMappingElection_VaryNormalization(t *testing.T) {
+	err := core.SetVaryNormalizationFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{
+		"vary_normalization": map[string]interface{}{
+			"sort_tokens":    "Accept-Encoding",
+			"strip_q_values": []string{"Accept-Encoding"},
+			"ignore":         []string{"User-Agent"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	defer core.SetVaryNormalization(nil)
+
+	memory := newMemoryStorer()
+	response := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+
+	_ = memory.SetMultiLevel("base", "varied", response, http.Header{
+		"Accept-Encoding": []string{"gzip;q=1.0, br"},
+		"User-Agent":      []string{"curl"},
+	}, "", time.Minute, "real")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	req.Header.Set("User-Agent", "Mozilla")
+
+	if fresh, _ := memory.GetMultiLevel("base", req, &core.Revalidator{}); fresh == nil {
+		t.Error("The equivalent request should match the stored variant")
+	}
+
+	req.Header.Set("Accept-Encoding", "br")
+
+	if fresh, _ := memory.GetMultiLevel("base", req, &core.Revalidator{}); fresh != nil {
+		t.Error("The different request shouldn't match the stored variant")
+	}
+
+	if err = core.SetVaryNormalizationFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{
+		"vary_normalization": map[string]interface{}{"unknown": true},
+	}}); err == nil {
+		t.Error("The unknown rules should be rejected")
+	}
+}
+
+func TestSetVaryNormalizationFromConfiguration_Conflict(t *testing.T) {
+	defer core.SetVaryNormalization(nil)
+
+	configure := func(rules map[string]interface{}) error {
+		return core.SetVaryNormalizationFromConfiguration(core.CacheProvider{Configuration: map[string]interface{}{
+			"vary_normalization": rules,
+		}})
+	}
+
+	if err := configure(map[string]interface{}{"sort_tokens": []string{"accept-encoding"}}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if err := configure(map[string]interface{}{"sort_tokens": []string{"Accept-Encoding"}}); err != nil {
+		t.Errorf("The same rules declared by another storage should be accepted, %v given", err)
+	}
+
+	if err := configure(map[string]interface{}{"case_insensitive": true}); err == nil {
+		t.Error("The other rules declared by another storage should be rejected")
+	}
+
+	if core.NormalizeVaryValue("Accept-Encoding", "GZIP") != "GZIP" {
+		t.Error("The rejected rules shouldn't be applied")
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DumpEntry is a line of the ndjson export written by Dump and read by
+// Warmup. The value is base64 encoded, the TTL is the remaining lifetime in
+// nanoseconds and zero means the entry never expires.
+type DumpEntry struct {
+	Key   string        `json:"key"`
+	TTL   time.Duration `json:"ttl"`
+	Value []byte        `json:"value"`
+}
+
+// EntryWalker is an optional interface a Storer can implement to stream
+// every raw entry, mappings included, with its remaining TTL. The expired
+// entries are skipped, a zero TTL means the entry never expires. The walk
+// stops early when fn returns false.
+type EntryWalker interface {
+	WalkEntries(fn func(key string, value []byte, ttl time.Duration) bool) error
+}
+
+// walkMappings streams the mappings with WalkMappings when the storer
+// implements MappingWalker, it walks the MapKeys result otherwise.
+func walkMappings(storer Storer, fn func(key string, value []byte) bool) error {
+	if walker, ok := storer.(MappingWalker); ok {
+		return walker.WalkMappings(MappingKeyPrefix, fn)
+	}
+
+	for key, value := range storer.MapKeys(MappingKeyPrefix) {
+		if !fn(key, []byte(value)) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// WalkEntries streams the storer entries with its WalkEntries method when it
+// implements EntryWalker. Otherwise it walks the mappings and returns the
+// varied keys they reference then the mapping itself, their TTL is the
+// remaining stale time: the keys stored with Set only are skipped.
+func WalkEntries(storer Storer, fn func(key string, value []byte, ttl time.Duration) bool) error {
+	if walker, ok := storer.(EntryWalker); ok {
+		return walker.WalkEntries(fn)
+	}
+
+	var err error
+
+	walkErr := walkMappings(storer, func(key string, mapping []byte) bool {
+		metadata, decodeErr := DecodeMetadata(mapping)
+		if decodeErr != nil {
+			err = errors.Join(err, fmt.Errorf("impossible to decode the mapping of %s: %w", key, decodeErr))
+
+			return true
+		}
+
+		now := time.Now()
+		staleUntil := now
+
+		for _, m := range metadata {
+			if !m.StaleUntil.After(now) {
+				continue
+			}
+
+			value := storer.Get(m.Key)
+			if len(value) == 0 {
+				continue
+			}
+
+			if !fn(m.Key, value, m.StaleUntil.Sub(now)) {
+				return false
+			}
+
+			if m.StaleUntil.After(staleUntil) {
+				staleUntil = m.StaleUntil
+			}
+		}
+
+		if !staleUntil.After(now) {
+			return true
+		}
+
+		return fn(MappingKeyPrefix+key, mapping, staleUntil.Sub(now))
+	})
+
+	return errors.Join(walkErr, err)
+}
+
+// Dump writes the storer entries to w as ndjson DumpEntry lines, it returns
+// the number of written entries. The values are exported as stored, a node
+// warmed up from the dump must share the compression and the encryption
+// configuration.
+func Dump(storer Storer, w io.Writer) (int, error) {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	count := 0
+
+	var err error
+
+	walkErr := WalkEntries(storer, func(key string, value []byte, ttl time.Duration) bool {
+		if err = encoder.Encode(DumpEntry{Key: key, TTL: ttl, Value: value}); err != nil {
+			return false
+		}
+
+		count++
+
+		return true
+	})
+
+	if err = errors.Join(walkErr, err); err != nil {
+		return count, err
+	}
+
+	return count, buffered.Flush()
+}
+
+// Warmup stores the entries read from a Dump export, it returns the number of
+// stored entries. The values are stored before their mapping when the dump
+// comes from the mappings walk, so a warming node never references a
+// missing value.
+func Warmup(storer Storer, source io.Reader) (int, error) {
+	decoder := json.NewDecoder(bufio.NewReader(source))
+	count := 0
+
+	for {
+		var entry DumpEntry
+
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+
+		if err != nil {
+			return count, fmt.Errorf("invalid dump entry: %w", err)
+		}
+
+		if strings.TrimSpace(entry.Key) == "" || entry.TTL < 0 {
+			continue
+		}
+
+		if err = storer.Set(entry.Key, entry.Value, entry.TTL); err != nil {
+			return count, fmt.Errorf("impossible to store the key %s: %w", entry.Key, err)
+		}
+
+		count++
+	}
+}
@@ -0,0 +1,100 @@
+package core_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// walkingStorer exposes its raw entries through core.EntryWalker.
+type walkingStorer struct {
+	*memoryStorer
+}
+
+func (w walkingStorer) WalkEntries(fn func(key string, value []byte, ttl time.Duration) bool) error {
+	for key, value := range w.MapKeys("") {
+		if !fn(key, []byte(value), time.Minute) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func TestDumpWarmup_Mappings(t *testing.T) {
+	source := newMemoryStorer()
+
+	for _, variant := range []string{"gzip", "br"} {
+		variedKey := "warmup-" + variant
+
+		err := source.SetMultiLevel("warmup", variedKey, []byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"), http.Header{"Accept-Encoding": []string{variant}}, "", time.Minute, variedKey)
+		if err != nil {
+			t.Fatalf("Impossible to store the response, %v", err)
+		}
+	}
+
+	_ = source.Set("plain", []byte("value"), time.Minute)
+
+	var dump bytes.Buffer
+
+	count, err := core.Dump(source, &dump)
+	if err != nil || count != 3 {
+		t.Fatalf("The variants and their mapping should be dumped, %d entries and %v given", count, err)
+	}
+
+	if lines := strings.Count(dump.String(), "\n"); lines != 3 {
+		t.Errorf("Each entry should be a line, %d lines given", lines)
+	}
+
+	target := newMemoryStorer()
+
+	if count, err = core.Warmup(target, &dump); err != nil || count != 3 {
+		t.Fatalf("The dumped entries should be stored, %d entries and %v given", count, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com/warmup", nil)
+	req.Header.Set("Accept-Encoding", "br")
+
+	fresh, _ := target.GetMultiLevel("warmup", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The warmed up storer should serve the response")
+	}
+
+	_ = fresh.Body.Close()
+
+	if target.Get("plain") != nil {
+		t.Error("The keys stored without mapping can't be walked")
+	}
+}
+
+func TestDumpWarmup_EntryWalker(t *testing.T) {
+	source := walkingStorer{memoryStorer: newMemoryStorer()}
+	_ = source.Set("plain", []byte("value"), time.Minute)
+
+	var dump bytes.Buffer
+
+	if count, err := core.Dump(source, &dump); err != nil || count != 1 {
+		t.Fatalf("The walked entries should be dumped, %d entries and %v given", count, err)
+	}
+
+	target := newMemoryStorer()
+
+	if _, err := core.Warmup(target, &dump); err != nil {
+		t.Fatalf("Impossible to warm up the storer, %v", err)
+	}
+
+	if string(target.Get("plain")) != "value" {
+		t.Errorf("The plain key should be warmed up, %s given", target.Get("plain"))
+	}
+}
+
+func TestWarmup_Invalid(t *testing.T) {
+	count, err := core.Warmup(newMemoryStorer(), strings.NewReader(`{"key":"first","ttl":60000000000,"value":"dmFsdWU="}`+"\n{not json"))
+	if err == nil || count != 1 {
+		t.Errorf("The malformed line should fail after the first entry, %d entries and %v given", count, err)
+	}
+}
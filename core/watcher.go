@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// DefaultEventsChannel is the channel the storages without native change
+	// notifications publish their events on.
+	DefaultEventsChannel = "storages-events"
+	// EventsBufferSize is the capacity of the channels returned by Watch.
+	EventsBufferSize = 64
+)
+
+// EventKind is the change carried by an Event.
+type EventKind string
+
+const (
+	// EventSet is a stored key.
+	EventSet EventKind = "set"
+	// EventDelete is a deleted key.
+	EventDelete EventKind = "delete"
+	// EventExpire is a key expired or evicted by the storage.
+	EventExpire EventKind = "expire"
+)
+
+// Event is a change of a key, the Value is the stored one when the storage
+// carries it with a set.
+type Event struct {
+	Kind  EventKind `json:"kind"`
+	Key   string    `json:"key"`
+	Value []byte    `json:"value,omitempty"`
+}
+
+// Encode returns the JSON payload of the event.
+func (e Event) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// DecodeEvent decodes the JSON payload of an event.
+func DecodeEvent(payload []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return event, fmt.Errorf("invalid event: %w", err)
+	}
+
+	switch event.Kind {
+	case EventSet, EventDelete, EventExpire:
+		return event, nil
+	default:
+		return event, fmt.Errorf("invalid event: unknown kind %s", event.Kind)
+	}
+}
+
+// Watcher is an optional interface a Storer can implement to stream the
+// changes of its keys, e.g. to update the edge caches or the metrics: Badger
+// with its Subscribe, Etcd with a watch, Redis with the keyspace
+// notifications and Olric with its PubSub. The events of the keys starting
+// with the prefix are sent until the context is done, then the channel is
+// closed. A slow reader holds the next events back.
+type Watcher interface {
+	Watch(ctx context.Context, prefix string) (<-chan Event, error)
+}
+
+// WatcherFor returns the Watcher implemented by the storer or one of the
+// storers it decorates. The decorated storer sees the keys as they're
+// stored, e.g. with their key_prefix.
+func WatcherFor(storer Storer) (Watcher, bool) {
+	for storer != nil {
+		if watcher, ok := storer.(Watcher); ok {
+			return watcher, true
+		}
+
+		unwrapper, ok := storer.(interface{ Unwrap() Storer })
+		if !ok {
+			break
+		}
+
+		storer = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// SendEvent sends the event to the watcher channel, it returns false once the
+// context is done.
+func SendEvent(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case events <- event:
+		return true
+	}
+}
@@ -0,0 +1,78 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/darkweak/storages/core"
+)
+
+// watchedStorer is a storage streaming a single event.
+type watchedStorer struct {
+	*memoryStorer
+}
+
+func (watchedStorer) Watch(ctx context.Context, prefix string) (<-chan core.Event, error) {
+	events := make(chan core.Event, 1)
+	events <- core.Event{Kind: core.EventSet, Key: prefix + byteKey}
+
+	close(events)
+
+	return events, nil
+}
+
+func TestEvent(t *testing.T) {
+	event := core.Event{Kind: core.EventSet, Key: byteKey, Value: []byte(baseValue)}
+
+	payload, err := event.Encode()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	decoded, err := core.DecodeEvent(payload)
+	if err != nil || decoded.Kind != event.Kind || decoded.Key != event.Key || string(decoded.Value) != baseValue {
+		t.Errorf("The event should be decoded as encoded, %+v given, %v", decoded, err)
+	}
+
+	for _, payload := range []string{"", "{", `{"kind":"tag","key":"k"}`} {
+		if _, err = core.DecodeEvent([]byte(payload)); err == nil {
+			t.Errorf("The payload %q should be rejected", payload)
+		}
+	}
+}
+
+func TestWatcherFor(t *testing.T) {
+	if _, ok := core.WatcherFor(newMemoryStorer()); ok {
+		t.Error("The memory storer doesn't implement the Watcher")
+	}
+
+	prefixed, err := core.NewPrefixedStorer(watchedStorer{newMemoryStorer()}, "tenant")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	watcher, ok := core.WatcherFor(prefixed)
+	if !ok {
+		t.Fatal("The decorated storer Watcher should be returned")
+	}
+
+	events, _ := watcher.Watch(context.Background(), "tenant")
+	if event := <-events; event.Key != "tenant"+byteKey {
+		t.Errorf("The event of the decorated storer should be received, %+v given", event)
+	}
+}
+
+func TestSendEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan core.Event, 1)
+
+	if !core.SendEvent(ctx, events, core.Event{Kind: core.EventDelete, Key: byteKey}) {
+		t.Error("The event should be sent to the buffered channel")
+	}
+
+	cancel()
+
+	if core.SendEvent(ctx, events, core.Event{Kind: core.EventDelete, Key: byteKey}) {
+		t.Error("The event shouldn't be sent once the context is done")
+	}
+}
@@ -6,15 +6,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/darkweak/storages/core"
-	"github.com/pierrec/lz4/v4"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
-	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/connectivity"
 )
 
@@ -23,36 +27,230 @@ type Etcd struct {
 	*clientv3.Client
 
 	stale         time.Duration
+	uuid          string
 	ctx           context.Context
 	logger        core.Logger
-	reconnecting  bool
+	compressor    core.Compressor
+	reconnector   *core.Reconnector
 	configuration clientv3.Config
+	// locks keeps the lease of each lock held by this instance.
+	locks sync.Map
+	// leases are shared by the values expiring in the same window,
+	// mappingLeases by the mappings which are kept alive on update.
+	leases        *leaseBuckets
+	mappingLeases *leaseBuckets
+	options       options
+	timeouts      core.Timeouts
+	// streamer stores the values over the max_value_size in chunks.
+	streamer       *core.ChunkedStreamer
+	mu             sync.Mutex
+	stopCompactor  context.CancelFunc
+	stopQuotaCheck context.CancelFunc
 }
 
-// Factory function create new Etcd instance.
-func Factory(etcdCfg core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+// options are the provider settings read from the configuration next to the
+// client ones.
+type options struct {
+	// LeaseGranularity is the window of the expirations sharing a lease,
+	// 10s by default.
+	LeaseGranularity time.Duration `json:"lease_granularity"`
+	// CompactionInterval enables the periodic compaction of the revisions
+	// history, disabled when 0.
+	CompactionInterval time.Duration `json:"compaction_interval"`
+	// CompactionRetention is the number of revisions kept by the compaction,
+	// 1000 by default.
+	CompactionRetention int64 `json:"compaction_retention"`
+	// MaxValueSize is the size of the values stored under a single key,
+	// 1MiB by default. The larger ones are split in chunks.
+	MaxValueSize int `json:"max_value_size"`
+	// QuotaBytes is the etcd --quota-backend-bytes, 2GiB by default. A
+	// warning is logged every QuotaCheckInterval, 1m by default and disabled
+	// when 0, once the DB size reaches QuotaWarningRatio of it, 0.8 by
+	// default.
+	QuotaBytes         int64         `json:"quota_bytes"`
+	QuotaWarningRatio  float64       `json:"quota_warning_ratio"`
+	QuotaCheckInterval time.Duration `json:"quota_check_interval"`
+	// EvictOnQuota evicts the oldest entries then compacts the history when
+	// the warning is logged.
+	EvictOnQuota bool `json:"evict_on_quota"`
+	// timeouts bounds the calls made to the cluster.
+	timeouts core.Timeouts
+	// limiter spaces the compactions and the quota checks.
+	limiter *core.BackgroundLimiter
+
+	Client map[string]interface{} `json:",remain"`
+}
+
+const defaultCompactionRetention = 1000
+
+func parseOptions(etcdConfiguration any) (options, error) {
+	opts := options{
+		LeaseGranularity:    defaultLeaseGranularity,
+		CompactionRetention: defaultCompactionRetention,
+		MaxValueSize:        defaultMaxValueSize,
+		QuotaBytes:          defaultQuotaBytes,
+		QuotaWarningRatio:   defaultQuotaWarningRatio,
+		QuotaCheckInterval:  defaultQuotaCheckInterval,
+	}
+
+	if err := core.DecodeConfiguration(etcdConfiguration, &opts); err != nil {
+		return opts, fmt.Errorf("invalid etcd configuration: %w", err)
+	}
+
+	if opts.LeaseGranularity < time.Second {
+		return opts, fmt.Errorf("invalid etcd configuration: the lease_granularity must be at least 1s, %s given", opts.LeaseGranularity)
+	}
+
+	if opts.CompactionInterval < 0 || opts.CompactionRetention <= 0 {
+		return opts, errors.New("invalid etcd configuration: the compaction_interval can't be negative and the compaction_retention must be positive")
+	}
+
+	if opts.MaxValueSize <= 0 {
+		return opts, fmt.Errorf("invalid etcd configuration: the max_value_size must be positive, %d given", opts.MaxValueSize)
+	}
+
+	if opts.QuotaBytes <= 0 || opts.QuotaWarningRatio <= 0 || opts.QuotaWarningRatio > 1 || opts.QuotaCheckInterval < 0 {
+		return opts, errors.New("invalid etcd configuration: the quota_bytes must be positive, the quota_warning_ratio between 0 and 1 and the quota_check_interval can't be negative")
+	}
+
+	timeouts, err := core.TimeoutsFromConfiguration(etcdConfiguration)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.timeouts = timeouts
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(etcdConfiguration)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.limiter = limiter
+
+	return opts, nil
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("etcd", Factory)
+}
+
+func parseConfiguration(etcdConfiguration any, target *clientv3.Config) error {
+	bc, err := json.Marshal(etcdConfiguration)
+	if err == nil {
+		err = json.Unmarshal(bc, target)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid etcd configuration: %w", err)
+	}
+
+	return nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(etcdConfiguration any) error {
+	if err := parseConfiguration(etcdConfiguration, &clientv3.Config{}); err != nil {
+		return err
+	}
+
+	if _, err := parseOptions(etcdConfiguration); err != nil {
+		return err
+	}
+
+	_, err := core.PoolFromConfiguration(etcdConfiguration)
+
+	return err
+}
+
+// newConfiguration builds the client configuration, the compressor and the
+// options declared in the provider configuration.
+func newConfiguration(etcdCfg core.CacheProvider, logger core.Logger) (clientv3.Config, core.Compressor, options, error) {
 	etcdConfiguration := clientv3.Config{
 		DialTimeout:      5 * time.Second,
 		AutoSyncInterval: 1 * time.Second,
 	}
 
-	zapLogger, ok := logger.(*zap.SugaredLogger)
-	if ok {
-		etcdConfiguration.Logger = zapLogger.Desugar()
-	}
+	etcdConfiguration.Logger = newZapLogger(logger)
 
 	if etcdCfg.URL != "" {
 		etcdConfiguration.Endpoints = strings.Split(etcdCfg.URL, ",")
 	} else {
-		bc, err := json.Marshal(etcdCfg.Configuration)
-		if err != nil {
-			return nil, err
+		if err := parseConfiguration(etcdCfg.Configuration, &etcdConfiguration); err != nil {
+			return etcdConfiguration, nil, options{}, err
 		}
+	}
 
-		err = json.Unmarshal(bc, &etcdConfiguration)
-		if err != nil {
-			return nil, err
+	opts, err := parseOptions(etcdCfg.Configuration)
+	if err != nil {
+		return etcdConfiguration, nil, opts, err
+	}
+
+	compressor, err := core.CompressorFromConfiguration(etcdCfg.Configuration)
+	if err != nil {
+		return etcdConfiguration, nil, opts, err
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(etcdCfg.Configuration)
+	if err != nil {
+		return etcdConfiguration, nil, opts, err
+	}
+
+	if tlsConfig != nil {
+		etcdConfiguration.TLS = tlsConfig
+	}
+
+	pool, err := core.PoolFromConfiguration(etcdCfg.Configuration)
+	if err != nil {
+		return etcdConfiguration, nil, opts, err
+	}
+
+	applyPool(&etcdConfiguration, pool)
+
+	return etcdConfiguration, compressor, opts, nil
+}
+
+// minConnectTimeout is the gRPC default, kept when the dial backoff is tuned.
+const minConnectTimeout = 20 * time.Second
+
+// applyPool applies the shared pool settings to the client configuration.
+// The client multiplexes its calls over one gRPC connection, the size and
+// min_idle don't apply.
+func applyPool(etcdConfiguration *clientv3.Config, pool core.Pool) {
+	switch {
+	case pool.MaxRetries < 0:
+		// The client makes MaxUnaryRetries attempts, one disables the retries.
+		etcdConfiguration.MaxUnaryRetries = 1
+	case pool.MaxRetries > 0:
+		etcdConfiguration.MaxUnaryRetries = uint(pool.MaxRetries) + 1
+	}
+
+	if pool.DialBackoff > 0 {
+		etcdConfiguration.BackoffWaitBetween = pool.DialBackoff
+	}
+
+	if pool.DialBackoff > 0 || pool.MaxDialBackoff > 0 {
+		dialBackoff := backoff.DefaultConfig
+		if pool.DialBackoff > 0 {
+			dialBackoff.BaseDelay = pool.DialBackoff
 		}
+
+		if pool.MaxDialBackoff > 0 {
+			dialBackoff.MaxDelay = pool.MaxDialBackoff
+		}
+
+		etcdConfiguration.DialOptions = append(etcdConfiguration.DialOptions, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           dialBackoff,
+			MinConnectTimeout: minConnectTimeout,
+		}))
+	}
+}
+
+// Factory function create new Etcd instance.
+func Factory(etcdCfg core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	etcdConfiguration, compressor, opts, err := newConfiguration(etcdCfg, logger)
+	if err != nil {
+		return nil, err
 	}
 
 	cli, err := clientv3.New(etcdConfiguration)
@@ -66,13 +264,76 @@ func Factory(etcdCfg core.CacheProvider, logger core.Logger, stale time.Duration
 
 	}
 
-	return &Etcd{
+	instance := &Etcd{
 		Client:        cli,
 		ctx:           context.Background(),
 		stale:         stale,
+		uuid:          core.InstanceID("etcd", etcdCfg, stale),
 		logger:        logger,
+		compressor:    compressor,
 		configuration: etcdConfiguration,
-	}, nil
+		options:       opts,
+		timeouts:      opts.timeouts,
+		leases:        newLeaseBuckets(opts.LeaseGranularity),
+		mappingLeases: newLeaseBuckets(opts.LeaseGranularity),
+	}
+	instance.reconnector = core.NewReconnector(etcdCfg.Configuration, logger, instance.connect)
+	instance.streamer = core.NewChunkedStreamer(rawStorer{instance}, opts.MaxValueSize)
+
+	return instance, nil
+}
+
+// Reload connects a new client with the given configuration then closes the
+// previous one, the stored keys live in Etcd and are kept.
+func (provider *Etcd) Reload(etcdCfg core.CacheProvider) error {
+	etcdConfiguration, compressor, opts, err := newConfiguration(etcdCfg, provider.logger)
+	if err != nil {
+		return err
+	}
+
+	cli, err := clientv3.New(etcdConfiguration)
+	if err != nil {
+		provider.logger.Errorf("Impossible to reload the Etcd DB, %v", err)
+
+		return err
+	}
+
+	previous := provider.Client
+	provider.Client = cli
+	provider.configuration = etcdConfiguration
+	provider.compressor = compressor
+	provider.timeouts = opts.timeouts
+	provider.leases = newLeaseBuckets(opts.LeaseGranularity)
+	provider.mappingLeases = newLeaseBuckets(opts.LeaseGranularity)
+	provider.streamer = core.NewChunkedStreamer(rawStorer{provider}, opts.MaxValueSize)
+
+	provider.mu.Lock()
+	provider.options = opts
+	restart := provider.stopCompactor != nil
+	restartQuota := provider.stopQuotaCheck != nil
+	provider.mu.Unlock()
+
+	if restart {
+		provider.stopCompaction()
+		provider.startCompaction()
+	}
+
+	if restartQuota {
+		provider.stopQuotaWatcher()
+		provider.startQuotaWatcher()
+	}
+
+	return previous.Close()
+}
+
+// readContext returns the context bounding a read.
+func (provider *Etcd) readContext() (context.Context, context.CancelFunc) {
+	return provider.timeouts.ReadContext(provider.ctx)
+}
+
+// writeContext returns the context bounding a write or a deletion.
+func (provider *Etcd) writeContext() (context.Context, context.CancelFunc) {
+	return provider.timeouts.WriteContext(provider.ctx)
 }
 
 // Name returns the storer name.
@@ -82,18 +343,12 @@ func (provider *Etcd) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Etcd) Uuid() string {
-	return fmt.Sprintf(
-		"%s-%s-%s-%s",
-		strings.Join(provider.Endpoints(), ","),
-		provider.Username,
-		provider.Password,
-		provider.stale,
-	)
+	return provider.uuid
 }
 
 // ListKeys method returns the list of existing keys.
 func (provider *Etcd) ListKeys() []string {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to list the etcd keys while reconnecting.")
 
 		return []string{}
@@ -101,11 +356,12 @@ func (provider *Etcd) ListKeys() []string {
 
 	keys := []string{}
 
-	result, e := provider.Client.Get(provider.ctx, core.MappingKeyPrefix, clientv3.WithPrefix())
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	result, e := provider.Client.Get(ctx, core.MappingKeyPrefix, clientv3.WithPrefix())
 	if e != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		return []string{}
 	}
@@ -124,7 +380,7 @@ func (provider *Etcd) ListKeys() []string {
 
 // MapKeys method returns the map of existing keys.
 func (provider *Etcd) MapKeys(prefix string) map[string]string {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to list the etcd keys while reconnecting.")
 
 		return map[string]string{}
@@ -132,11 +388,12 @@ func (provider *Etcd) MapKeys(prefix string) map[string]string {
 
 	keys := map[string]string{}
 
-	result, err := provider.Client.Get(provider.ctx, "\x00", clientv3.WithFromKey())
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	result, err := provider.Client.Get(ctx, "\x00", clientv3.WithFromKey())
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		return map[string]string{}
 	}
@@ -154,43 +411,123 @@ func (provider *Etcd) MapKeys(prefix string) map[string]string {
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Etcd) Get(key string) (item []byte) {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to get the etcd key while reconnecting.")
 
 		return []byte{}
 	}
 
-	result, err := provider.Client.Get(provider.ctx, key)
-	if err != nil && !provider.reconnecting {
-		go provider.Reconnect()
+	item, _ = provider.Lookup(key)
 
-		return
+	return
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist. The chunked values are reassembled.
+func (provider *Etcd) Lookup(key string) ([]byte, error) {
+	value, err := provider.lookup(key)
+	if err == nil && core.IsChunkedManifest(value) {
+		return provider.readChunks(key)
 	}
 
-	if err == nil && result != nil && len(result.Kvs) > 0 {
-		item = result.Kvs[0].Value
+	return value, err
+}
+
+// lookup returns the value stored under the key as is.
+func (provider *Etcd) lookup(key string) ([]byte, error) {
+	if provider.reconnector.Reconnecting() {
+		return nil, core.ErrReconnecting
 	}
 
-	return
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	result, err := provider.Client.Get(ctx, key)
+	if err != nil {
+		provider.Reconnect()
+
+		return nil, err
+	}
+
+	if len(result.Kvs) == 0 {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return result.Kvs[0].Value, nil
+}
+
+// maxTxnOps is the default etcd --max-txn-ops, the number of operations
+// allowed in a transaction.
+const maxTxnOps = 128
+
+// GetMany method returns the values of the found keys, read in one
+// transaction per maxTxnOps keys. The chunked values are reassembled.
+func (provider *Etcd) GetMany(keys []string) map[string][]byte {
+	values := make(map[string][]byte, len(keys))
+
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to get the etcd keys while reconnecting.")
+
+		return values
+	}
+
+	for batch := range slices.Chunk(keys, maxTxnOps) {
+		ops := make([]clientv3.Op, len(batch))
+		for i, key := range batch {
+			ops[i] = clientv3.OpGet(key)
+		}
+
+		ctx, cancel := provider.readContext()
+		res, err := provider.Txn(ctx).Then(ops...).Commit()
+
+		cancel()
+
+		if err != nil {
+			provider.Reconnect()
+
+			return values
+		}
+
+		for i, response := range res.Responses {
+			kvs := response.GetResponseRange().GetKvs()
+			if len(kvs) == 0 {
+				continue
+			}
+
+			value := kvs[0].Value
+			if core.IsChunkedManifest(value) {
+				if value, err = provider.readChunks(batch[i]); err != nil {
+					continue
+				}
+			}
+
+			values[batch[i]] = value
+		}
+	}
+
+	return values
 }
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Etcd) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to get the etcd key while reconnecting.")
 
 		return
 	}
 
-	result, err := provider.Client.Get(provider.ctx, core.MappingKeyPrefix+key)
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	result, err := provider.Client.Get(ctx, core.MappingKeyPrefix+key)
 	if err != nil {
-		go provider.Reconnect()
+		provider.Reconnect()
 
 		return fresh, stale
 	}
 
 	if len(result.Kvs) > 0 {
-		fresh, stale, _ = core.MappingElection(provider, result.Kvs[0].Value, req, validator, provider.logger)
+		fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, result.Kvs[0].Value, req, validator, provider.logger)
 	}
 
 	return fresh, stale
@@ -198,88 +535,161 @@ func (provider *Etcd) GetMultiLevel(key string, req *http.Request, validator *co
 
 // SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
 func (provider *Etcd) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to set the etcd value while reconnecting.")
 
-		return errors.New("reconnecting error")
+		return core.ErrReconnecting
 	}
 
 	now := time.Now()
 
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to set the etcd value while reconnecting.")
 
-		return errors.New("reconnecting error")
+		return core.ErrReconnecting
 	}
 
 	if provider.Client.ActiveConnection().GetState() != connectivity.Ready && provider.Client.ActiveConnection().GetState() != connectivity.Idle {
 		return fmt.Errorf("the connection is not ready: %v", provider.Client.ActiveConnection().GetState())
 	}
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
 		provider.logger.Errorf("Impossible to compress the key %s into Etcd, %v", variedKey, err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Etcd, %v", variedKey, err)
+	// The values over the max_value_size are stored in chunks first, the
+	// transaction then only writes the mapping.
+	chunked := provider.chunked(compressed)
+	if chunked {
+		if err = provider.streamer.SetReader(variedKey, bytes.NewReader(compressed), duration); err != nil {
+			provider.logger.Errorf("Impossible to set the chunks of the key %s into Etcd, %v", variedKey, err)
 
-		return err
+			return err
+		}
 	}
 
-	rs, err := provider.Grant(context.TODO(), int64(duration.Seconds()))
-	if err == nil {
-		_, err = provider.Put(provider.ctx, variedKey, compressed.String(), clientv3.WithLease(rs.ID))
-	}
+	leaseCtx, leaseCancel := provider.writeContext()
+	valueLease, err := provider.leases.get(leaseCtx, provider.Client, duration)
+
+	leaseCancel()
 
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
-		provider.logger.Errorf("Impossible to set value into Etcd, %v", err)
+		provider.logger.Errorf("Impossible to grant the key %s lease in Etcd, %v", variedKey, err)
 
 		return err
 	}
 
 	mappingKey := core.MappingKeyPrefix + baseKey
-	result := provider.Get(mappingKey)
 
-	val, e := core.MappingUpdater(variedKey, result, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
-	if e != nil {
-		return e
+	// The value and the mapping are written in the same transaction, only if
+	// the mapping revision didn't change since it was read, the revision is 0
+	// when the mapping doesn't exist.
+	var evicted []string
+
+	err = core.UpdateMapping(func() error {
+		ctx, cancel := provider.writeContext()
+		defer cancel()
+
+		res, err := provider.Client.Get(ctx, mappingKey)
+		if err != nil {
+			return err
+		}
+
+		var (
+			result   []byte
+			revision int64
+			current  clientv3.LeaseID
+		)
+
+		if len(res.Kvs) > 0 {
+			result = res.Kvs[0].Value
+			revision = res.Kvs[0].ModRevision
+			current = clientv3.LeaseID(res.Kvs[0].Lease)
+		}
+
+		var val []byte
+
+		val, evicted, err = core.MappingUpdaterWithEvictions(variedKey, result, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+		if err != nil {
+			return err
+		}
+
+		leaseOption, err := provider.mappingLease(current, duration+provider.stale)
+		if err != nil {
+			provider.logger.Errorf("Impossible to grant the mapping %s lease in Etcd, %v", mappingKey, err)
+
+			return err
+		}
+
+		ops := []clientv3.Op{clientv3.OpPut(mappingKey, string(val), leaseOption)}
+		if !chunked {
+			ops = append(ops, clientv3.OpPut(variedKey, string(compressed), clientv3.WithLease(valueLease)))
+		}
+
+		txn, err := provider.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(mappingKey), "=", revision)).
+			Then(ops...).
+			Commit()
+		if err != nil {
+			provider.leases.forget(valueLease)
+			provider.logger.Errorf("Impossible to set value into Etcd, %v", err)
+
+			return err
+		}
+
+		if !txn.Succeeded {
+			return core.ErrMappingConflict
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return provider.Set(mappingKey, val, duration+provider.stale)
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
 }
 
 // Set method will store the response in Etcd provider.
 func (provider *Etcd) Set(key string, value []byte, duration time.Duration) error {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to set the etcd value while reconnecting.")
 
-		return errors.New("reconnecting error")
+		return core.ErrReconnecting
 	}
 
 	if provider.Client.ActiveConnection().GetState() != connectivity.Ready && provider.Client.ActiveConnection().GetState() != connectivity.Idle {
 		return fmt.Errorf("the connection is not ready: %v", provider.Client.ActiveConnection().GetState())
 	}
 
-	rs, err := provider.Grant(context.TODO(), int64(duration.Seconds()))
+	if provider.chunked(value) {
+		return provider.streamer.SetReader(key, bytes.NewReader(value), duration)
+	}
+
+	return provider.put(key, value, duration)
+}
+
+// put stores the value attached to the lease of its expiration window.
+func (provider *Etcd) put(key string, value []byte, duration time.Duration) error {
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	leaseID, err := provider.leases.get(ctx, provider.Client, duration)
 	if err == nil {
-		_, err = provider.Put(provider.ctx, key, string(value), clientv3.WithLease(rs.ID))
+		if _, err = provider.Put(ctx, key, string(value), clientv3.WithLease(leaseID)); err != nil {
+			provider.leases.forget(leaseID)
+		}
 	}
 
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Errorf("Impossible to set value into Etcd, %v", err)
 	}
@@ -287,20 +697,62 @@ func (provider *Etcd) Set(key string, value []byte, duration time.Duration) erro
 	return err
 }
 
+// mappingLease returns the put option keeping the mapping alive at least
+// ttl. The current lease is kept, and renewed with a keep-alive when it
+// expires sooner, if it was granted for long enough. The mapping is moved
+// to the lease of its expiration window otherwise.
+func (provider *Etcd) mappingLease(current clientv3.LeaseID, ttl time.Duration) (clientv3.OpOption, error) {
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	if current != clientv3.NoLease {
+		lease, err := provider.TimeToLive(ctx, current)
+		if err == nil && lease.TTL > 0 && time.Duration(lease.GrantedTTL)*time.Second >= ttl {
+			if time.Duration(lease.TTL)*time.Second >= ttl {
+				return clientv3.WithIgnoreLease(), nil
+			}
+
+			if _, err = provider.KeepAliveOnce(ctx, current); err == nil {
+				return clientv3.WithIgnoreLease(), nil
+			}
+		}
+	}
+
+	leaseID, err := provider.mappingLeases.get(ctx, provider.Client, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return clientv3.WithLease(leaseID), nil
+}
+
 // Delete method will delete the response in Etcd provider if exists corresponding to key param.
 func (provider *Etcd) Delete(key string) {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to delete the etcd key while reconnecting.")
 
 		return
 	}
 
-	_, _ = provider.Client.Delete(provider.ctx, key)
+	provider.streamer.DeleteChunks(key, provider.delete(key))
+}
+
+// delete removes the key and returns its previous value.
+func (provider *Etcd) delete(key string) []byte {
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	res, err := provider.Client.Delete(ctx, key, clientv3.WithPrevKV())
+	if err != nil || len(res.PrevKvs) == 0 {
+		return nil
+	}
+
+	return res.PrevKvs[0].Value
 }
 
 // DeleteMany method will delete the responses in Etcd provider if exists corresponding to the regex key param.
 func (provider *Etcd) DeleteMany(key string) {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to delete the etcd keys while reconnecting.")
 
 		return
@@ -311,7 +763,11 @@ func (provider *Etcd) DeleteMany(key string) {
 		return
 	}
 
-	if r, e := provider.Client.Get(provider.ctx, "\x00", clientv3.WithFromKey()); e == nil {
+	ctx, cancel := provider.readContext()
+	r, e := provider.Client.Get(ctx, "\x00", clientv3.WithFromKey())
+	cancel()
+
+	if e == nil {
 		for _, k := range r.Kvs {
 			key := string(k.Key)
 			if rgKey.MatchString(key) {
@@ -321,24 +777,271 @@ func (provider *Etcd) DeleteMany(key string) {
 	}
 }
 
-// Init method will.
+// SetNX stores the value in a transaction only if the key doesn't exist.
+func (provider *Etcd) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	return provider.putIf(key, value, ttl, clientv3.Compare(clientv3.CreateRevision(key), "=", 0))
+}
+
+// CompareAndSwap replaces the value in a transaction only if the stored one
+// equals old.
+func (provider *Etcd) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return provider.putIf(key, value, ttl, clientv3.Compare(clientv3.Value(key), "=", string(old)))
+}
+
+// putIf stores the value attached to a lease of the given ttl only if the
+// comparison succeeds, the lease is revoked otherwise.
+func (provider *Etcd) putIf(key string, value []byte, ttl time.Duration, cmp clientv3.Cmp) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to set the etcd value while reconnecting.")
+
+		return false, core.ErrReconnecting
+	}
+
+	if provider.chunked(value) {
+		return false, core.ErrValueTooLarge
+	}
+
+	put := clientv3.OpPut(key, string(value))
+
+	var leaseID clientv3.LeaseID
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	if ttl > 0 {
+		lease, err := provider.Grant(ctx, int64(math.Ceil(ttl.Seconds())))
+		if err != nil {
+			provider.Reconnect()
+
+			provider.logger.Errorf("Impossible to grant the key %s lease in Etcd, %v", key, err)
+
+			return false, err
+		}
+
+		leaseID = lease.ID
+		put = clientv3.OpPut(key, string(value), clientv3.WithLease(leaseID))
+	}
+
+	resp, err := provider.Txn(ctx).If(cmp).Then(put).Commit()
+	if err != nil || !resp.Succeeded {
+		if leaseID != 0 {
+			_, _ = provider.Revoke(ctx, leaseID)
+		}
+
+		if err != nil {
+			provider.logger.Errorf("Impossible to conditionally set the key %s into Etcd, %v", key, err)
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// TryLock creates the lock key attached to a lease of the given ttl, only if
+// the key doesn't exist yet. The lease expiration releases the lock.
+func (provider *Etcd) TryLock(key string, ttl time.Duration) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to acquire the etcd lock while reconnecting.")
+
+		return false, core.ErrReconnecting
+	}
+
+	lockKey := core.LockKeyPrefix + key
+	token := core.LockToken()
+	put := clientv3.OpPut(lockKey, token)
+
+	var leaseID clientv3.LeaseID
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	if ttl > 0 {
+		lease, err := provider.Grant(ctx, int64(math.Ceil(ttl.Seconds())))
+		if err != nil {
+			provider.Reconnect()
+
+			provider.logger.Errorf("Impossible to grant the lock %s lease in Etcd, %v", key, err)
+
+			return false, err
+		}
+
+		leaseID = lease.ID
+		put = clientv3.OpPut(lockKey, token, clientv3.WithLease(leaseID))
+	}
+
+	resp, err := provider.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(lockKey), "=", 0)).
+		Then(put).
+		Commit()
+	if err != nil || !resp.Succeeded {
+		if leaseID != 0 {
+			_, _ = provider.Revoke(ctx, leaseID)
+		}
+
+		if err != nil {
+			provider.logger.Errorf("Impossible to acquire the lock %s in Etcd, %v", key, err)
+		}
+
+		return false, err
+	}
+
+	provider.locks.Store(key, leaseID)
+
+	return true, nil
+}
+
+// Unlock releases the lock if this instance still holds it, revoking the
+// lease deletes the lock key.
+func (provider *Etcd) Unlock(key string) error {
+	leaseID, held := provider.locks.LoadAndDelete(key)
+	if !held {
+		return nil
+	}
+
+	var err error
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	if id := leaseID.(clientv3.LeaseID); id != 0 {
+		_, err = provider.Revoke(ctx, id)
+	} else {
+		_, err = provider.Client.Delete(ctx, core.LockKeyPrefix+key)
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to release the lock %s in Etcd, %v", key, err)
+	}
+
+	return err
+}
+
+// Healthy requests the status of each endpoint, the storage is healthy once
+// one of them answers without reporting an error such as the NOSPACE alarm.
+func (provider *Etcd) Healthy(ctx context.Context) error {
+	err := errors.New("no Etcd endpoint given")
+
+	for _, endpoint := range provider.Endpoints() {
+		status, statusErr := provider.Status(ctx, endpoint)
+		if statusErr != nil {
+			err = statusErr
+
+			continue
+		}
+
+		if len(status.Errors) > 0 {
+			err = fmt.Errorf("the Etcd endpoint %s reports %s", endpoint, strings.Join(status.Errors, ", "))
+
+			continue
+		}
+
+		return nil
+	}
+
+	return err
+}
+
+// Init method starts the periodic compaction when configured and the quota
+// watcher.
 func (provider *Etcd) Init() error {
+	provider.startCompaction()
+	provider.startQuotaWatcher()
+
 	return nil
 }
 
 // Reset method will reset or close provider.
 func (provider *Etcd) Reset() error {
+	provider.stopCompaction()
+	provider.stopQuotaWatcher()
+	provider.reconnector.Stop()
+
 	return provider.Close()
 }
 
+func (provider *Etcd) startCompaction() {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.options.CompactionInterval <= 0 || provider.stopCompactor != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	provider.stopCompactor = cancel
+
+	go func(interval time.Duration, retention int64, limiter *core.BackgroundLimiter) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !limiter.Wait(ctx.Done(), "compaction") {
+					return
+				}
+
+				provider.compact(ctx, retention)
+			}
+		}
+	}(provider.options.CompactionInterval, provider.options.CompactionRetention, provider.options.limiter)
+}
+
+func (provider *Etcd) stopCompaction() {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.stopCompactor != nil {
+		provider.stopCompactor()
+		provider.stopCompactor = nil
+	}
+}
+
+// compact drops the revisions history older than the retention, the
+// overwritten and expired keys keep using space until they're compacted.
+func (provider *Etcd) compact(ctx context.Context, retention int64) {
+	if provider.reconnector.Reconnecting() {
+		return
+	}
+
+	res, err := provider.Client.Get(ctx, core.MappingKeyPrefix, clientv3.WithCountOnly())
+	if err != nil {
+		provider.logger.Errorf("Impossible to read the Etcd revision, %v", err)
+
+		return
+	}
+
+	revision := res.Header.GetRevision() - retention
+	if revision <= 0 {
+		return
+	}
+
+	if _, err = provider.Compact(ctx, revision); err != nil && !errors.Is(err, rpctypes.ErrCompacted) {
+		provider.logger.Errorf("Impossible to compact the Etcd revisions, %v", err)
+
+		return
+	}
+
+	provider.logger.Debugf("Compacted the Etcd revisions up to %d", revision)
+}
+
+// Reconnect starts the background reconnection unless it already runs.
 func (provider *Etcd) Reconnect() {
-	provider.reconnecting = true
+	provider.reconnector.Trigger()
+}
 
-	if c, err := clientv3.New(provider.configuration); err == nil && c != nil {
-		provider.Client = c
-		provider.reconnecting = false
-	} else {
-		time.Sleep(10 * time.Second)
-		provider.Reconnect()
+func (provider *Etcd) connect(_ context.Context) error {
+	c, err := clientv3.New(provider.configuration)
+	if err != nil {
+		return err
 	}
+
+	provider.Client = c
+	provider.leases.reset()
+	provider.mappingLeases.reset()
+
+	return nil
 }
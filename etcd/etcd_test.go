@@ -1,6 +1,9 @@
 package etcd_test
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"testing"
 	"time"
 
@@ -120,3 +123,271 @@ func TestEtcd_Init(t *testing.T) {
 		t.Error("Impossible to init Etcd provider")
 	}
 }
+
+func TestEtcd_TryLock(t *testing.T) {
+	client, _ := getEtcdInstance()
+	locker, ok := client.(core.Locker)
+	if !ok {
+		t.Fatal("Etcd should implement core.Locker")
+	}
+
+	acquired, err := locker.TryLock("revalidation", 5*time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("The lock should be acquired, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); acquired {
+		t.Error("The lock should already be held")
+	}
+
+	if err = locker.Unlock("revalidation"); err != nil {
+		t.Errorf("Impossible to release the lock, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); !acquired {
+		t.Error("The lock should be acquired once released")
+	}
+
+	_ = locker.Unlock("revalidation")
+}
+
+func TestEtcd_ConditionalSet(t *testing.T) {
+	client, _ := getEtcdInstance()
+	setter, ok := client.(core.ConditionalSetter)
+	if !ok {
+		t.Fatal("Etcd should implement core.ConditionalSetter")
+	}
+
+	client.Delete("leader")
+
+	if stored, err := setter.SetNX("leader", []byte("first"), 5*time.Second); err != nil || !stored {
+		t.Fatalf("The absent key should be stored, %v", err)
+	}
+
+	if stored, _ := setter.SetNX("leader", []byte("second"), 5*time.Second); stored {
+		t.Error("The existing key shouldn't be replaced")
+	}
+
+	if swapped, _ := setter.CompareAndSwap("leader", []byte("second"), []byte("third"), 5*time.Second); swapped {
+		t.Error("The value shouldn't be swapped when the old one differs")
+	}
+
+	if swapped, err := setter.CompareAndSwap("leader", []byte("first"), []byte("third"), 5*time.Second); err != nil || !swapped {
+		t.Errorf("The value should be swapped, %v", err)
+	}
+
+	if value := client.Get("leader"); string(value) != "third" {
+		t.Errorf("The swapped value should be stored, %s given", value)
+	}
+
+	client.Delete("leader")
+}
+
+func TestEtcd_Transact(t *testing.T) {
+	client, _ := getEtcdInstance()
+	client.Delete("tx-value")
+	client.Delete("tx-mapping")
+
+	err := core.Transact(client, func(tx core.Tx) error {
+		if _, err := tx.Get("tx-value"); !errors.Is(err, core.ErrKeyNotFound) {
+			t.Errorf("The absent key should be reported, %v given", err)
+		}
+
+		_ = tx.Set("tx-value", []byte("body"), time.Minute)
+		_ = tx.Set("tx-mapping", []byte("index"), 0)
+
+		if value, _ := tx.Get("tx-mapping"); string(value) != "index" {
+			t.Errorf("The transaction should see its writes, %s given", value)
+		}
+
+		return nil
+	})
+	if err != nil || string(client.Get("tx-value")) != "body" || string(client.Get("tx-mapping")) != "index" {
+		t.Fatalf("Both keys should be written, %v", err)
+	}
+
+	failure := errors.New("rollback")
+
+	err = core.Transact(client, func(tx core.Tx) error {
+		_ = tx.Delete("tx-value")
+
+		return failure
+	})
+	if !errors.Is(err, failure) || client.Get("tx-value") == nil {
+		t.Errorf("The writes should be discarded on error, %v", err)
+	}
+
+	client.Delete("tx-value")
+	client.Delete("tx-mapping")
+}
+
+func TestEtcd_Reload(t *testing.T) {
+	client, _ := getEtcdInstance()
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	err := core.Reload(client, core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"Endpoints": []string{"http://etcd:2379"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Impossible to reload the Etcd provider, %v", err)
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should be kept after the reload", byteKey)
+	}
+}
+
+func TestEtcd_Healthy(t *testing.T) {
+	client, _ := getEtcdInstance()
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The reachable endpoints should be healthy, %v given", err)
+	}
+}
+
+func TestEtcd_SetMultiLevel(t *testing.T) {
+	client, _ := getEtcdInstance()
+
+	for _, variedKey := range []string{"etcd-varied-short", "etcd-varied-long"} {
+		if err := client.SetMultiLevel("etcd-base", variedKey, []byte(baseValue), http.Header{}, "", time.Minute, variedKey); err != nil {
+			t.Fatalf("Impossible to set the key %s, %v", variedKey, err)
+		}
+	}
+
+	metadata, err := core.GetMetadata(client, "etcd-base")
+	if err != nil || len(metadata) != 2 {
+		t.Errorf("The mapping should reference both varied keys, %v and %v given", metadata, err)
+	}
+}
+
+func TestEtcd_Chunking(t *testing.T) {
+	client, _ := etcd.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"Endpoints":      []string{"http://etcd:2379"},
+			"max_value_size": 4,
+		},
+	}, zap.NewNop().Sugar(), 0)
+
+	if err := client.Set("etcd-chunked", []byte(baseValue), time.Minute); err != nil {
+		t.Fatalf("Impossible to set the chunked value, %v", err)
+	}
+
+	if res := client.Get("etcd-chunked"); string(res) != baseValue {
+		t.Errorf("The chunks should be reassembled, %s given", res)
+	}
+
+	if len(client.MapKeys(core.ChunkKeyPrefix+"etcd-chunked")) != (len(baseValue)+3)/4 {
+		t.Error("The value should be stored in chunks")
+	}
+
+	client.Delete("etcd-chunked")
+
+	if len(client.MapKeys(core.ChunkKeyPrefix+"etcd-chunked")) != 0 {
+		t.Error("The chunks should be deleted with the value")
+	}
+
+	if _, err := client.(core.ConditionalSetter).SetNX("etcd-chunked", []byte(baseValue), time.Minute); !errors.Is(err, core.ErrValueTooLarge) {
+		t.Errorf("The conditional set of a value over the max_value_size should be rejected, %v given", err)
+	}
+}
+
+func TestEtcd_Validate(t *testing.T) {
+	if err := etcd.Validate(map[string]interface{}{
+		"Endpoints":            []string{"http://etcd:2379"},
+		"lease_granularity":    "30s",
+		"compaction_interval":  "5m",
+		"compaction_retention": "10000",
+		"max_value_size":       "524288",
+		"quota_bytes":          8589934592,
+		"quota_warning_ratio":  0.9,
+		"evict_on_quota":       true,
+		"pool":                 map[string]interface{}{"max_retries": 3, "dial_backoff": "50ms", "max_dial_backoff": "5s"},
+	}); err != nil {
+		t.Errorf("The configuration should be valid, %v", err)
+	}
+
+	if err := etcd.Validate(map[string]interface{}{"lease_granularity": "100ms"}); err == nil {
+		t.Error("A lease_granularity under a second should be invalid")
+	}
+
+	if err := etcd.Validate(map[string]interface{}{"compaction_retention": 0}); err == nil {
+		t.Error("A zero compaction_retention should be invalid")
+	}
+
+	if err := etcd.Validate(map[string]interface{}{"max_value_size": 0}); err == nil {
+		t.Error("A zero max_value_size should be invalid")
+	}
+
+	if err := etcd.Validate(map[string]interface{}{"quota_warning_ratio": 1.5}); err == nil {
+		t.Error("A quota_warning_ratio over 1 should be invalid")
+	}
+
+	if err := etcd.Validate(map[string]interface{}{"pool": map[string]interface{}{"dial_backoff": "-1s"}}); err == nil {
+		t.Error("A negative dial_backoff should be invalid")
+	}
+}
+
+func TestEtcd_Invalidation(t *testing.T) {
+	client, _ := getEtcdInstance()
+	bus, ok := client.(core.InvalidationBus)
+	if !ok {
+		t.Fatal("Etcd should implement core.InvalidationBus")
+	}
+
+	received := make(chan core.InvalidationEvent, 1)
+
+	unsubscribe, err := bus.SubscribeInvalidations("etcd-test", func(event core.InvalidationEvent) {
+		received <- event
+	})
+	if err != nil {
+		t.Fatalf("Impossible to subscribe to the invalidations, %v", err)
+	}
+
+	defer unsubscribe()
+
+	time.Sleep(100 * time.Millisecond)
+
+	event := core.InvalidationEvent{Kind: core.InvalidateKey, Key: byteKey, Origin: "other"}
+	if err = bus.PublishInvalidation("etcd-test", event); err != nil {
+		t.Fatalf("Impossible to publish the invalidation, %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got != event {
+			t.Errorf("The published event should be received, %+v given", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("The published event should be received")
+	}
+}
+
+func TestEtcd_Watch(t *testing.T) {
+	client, _ := getEtcdInstance()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.(core.Watcher).Watch(ctx, "WATCHED_")
+	if err != nil {
+		t.Fatalf("Impossible to watch the keys, %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	_ = client.Set("WATCHED_"+byteKey, []byte(baseValue), time.Minute)
+	client.Delete("WATCHED_" + byteKey)
+
+	for _, expected := range []core.EventKind{core.EventSet, core.EventDelete} {
+		select {
+		case event := <-events:
+			if event.Kind != expected || event.Key != "WATCHED_"+byteKey {
+				t.Errorf("The %s event of the watched key should be received, %+v given", expected, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The %s event wasn't received", expected)
+		}
+	}
+}
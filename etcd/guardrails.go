@@ -0,0 +1,181 @@
+package etcd
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// defaultMaxValueSize stays under the default etcd --max-request-bytes
+	// (1.5MiB) once the mapping is added to the request.
+	defaultMaxValueSize = 1024 * 1024
+	// defaultQuotaBytes is the default etcd --quota-backend-bytes.
+	defaultQuotaBytes         = 2 * 1024 * 1024 * 1024
+	defaultQuotaWarningRatio  = 0.8
+	defaultQuotaCheckInterval = time.Minute
+	// quotaEvictionBatch is the number of mappings evicted per check.
+	quotaEvictionBatch = 100
+)
+
+// rawStorer is the provider without the chunking, the storer of its
+// core.ChunkedStreamer: the chunks and the manifests are plain values.
+type rawStorer struct {
+	*Etcd
+}
+
+func (r rawStorer) Get(key string) []byte {
+	value, _ := r.lookup(key)
+
+	return value
+}
+
+func (r rawStorer) Set(key string, value []byte, duration time.Duration) error {
+	return r.put(key, value, duration)
+}
+
+func (r rawStorer) Delete(key string) {
+	r.delete(key)
+}
+
+// chunked reports whether the value is over the max_value_size and must be
+// stored in chunks.
+func (provider *Etcd) chunked(value []byte) bool {
+	return len(value) > provider.options.MaxValueSize
+}
+
+// readChunks loads the chunks of the value whose manifest is stored under
+// the key, an expired chunk is reported as a miss.
+func (provider *Etcd) readChunks(key string) ([]byte, error) {
+	reader, err := provider.streamer.GetReader(key)
+	if err != nil {
+		return nil, core.ErrKeyNotFound
+	}
+
+	defer reader.Close()
+
+	value, err := io.ReadAll(reader)
+	if err != nil {
+		provider.logger.Debugf("Impossible to read the chunks of the key %s from Etcd, %v", key, err)
+
+		return nil, core.ErrKeyNotFound
+	}
+
+	return value, nil
+}
+
+func (provider *Etcd) startQuotaWatcher() {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.options.QuotaCheckInterval <= 0 || provider.stopQuotaCheck != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	provider.stopQuotaCheck = cancel
+
+	go func(opts options) {
+		ticker := time.NewTicker(opts.QuotaCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !opts.limiter.Wait(ctx.Done(), "quota_check") {
+					return
+				}
+
+				provider.checkQuota(ctx, opts)
+			}
+		}
+	}(provider.options)
+}
+
+func (provider *Etcd) stopQuotaWatcher() {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.stopQuotaCheck != nil {
+		provider.stopQuotaCheck()
+		provider.stopQuotaCheck = nil
+	}
+}
+
+// dbSize returns the largest DB size reported by the endpoints, the space
+// in use when the server reports it, the allocated one otherwise.
+func (provider *Etcd) dbSize(ctx context.Context) int64 {
+	var size int64
+
+	for _, endpoint := range provider.Endpoints() {
+		status, err := provider.Status(ctx, endpoint)
+		if err != nil {
+			provider.logger.Debugf("Impossible to read the Etcd endpoint %s status, %v", endpoint, err)
+
+			continue
+		}
+
+		inUse := status.DbSizeInUse
+		if inUse == 0 {
+			inUse = status.DbSize
+		}
+
+		size = max(size, inUse)
+	}
+
+	return size
+}
+
+// checkQuota warns when the DB size reaches the quota_warning_ratio of the
+// quota_bytes, then evicts the oldest entries when evict_on_quota is set.
+func (provider *Etcd) checkQuota(ctx context.Context, opts options) {
+	if provider.reconnector.Reconnecting() {
+		return
+	}
+
+	size := provider.dbSize(ctx)
+	if float64(size) < float64(opts.QuotaBytes)*opts.QuotaWarningRatio {
+		return
+	}
+
+	provider.logger.Warnf("The Etcd DB size %d bytes is approaching the %d bytes quota, the writes fail with NOSPACE once it's reached", size, opts.QuotaBytes)
+
+	if opts.EvictOnQuota {
+		provider.evictOldest(ctx)
+		provider.compact(ctx, opts.CompactionRetention)
+	}
+}
+
+// evictOldest deletes the least recently written mappings with their
+// varied keys. The space is released by the compaction that follows.
+func (provider *Etcd) evictOldest(ctx context.Context) {
+	res, err := provider.Client.Get(
+		ctx,
+		core.MappingKeyPrefix,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortAscend),
+		clientv3.WithLimit(quotaEvictionBatch),
+	)
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the oldest Etcd mappings, %v", err)
+
+		return
+	}
+
+	for _, kv := range res.Kvs {
+		if mapping, err := core.DecodeMapping(kv.Value); err == nil {
+			for variedKey := range mapping.GetMapping() {
+				provider.Delete(variedKey)
+			}
+		}
+
+		provider.Delete(string(kv.Key))
+	}
+
+	provider.logger.Warnf("Evicted the %d oldest Etcd entries to stay under the quota", len(res.Kvs))
+}
@@ -0,0 +1,86 @@
+package etcd
+
+import (
+	"context"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// invalidationTTL is how long a published invalidation is kept, the
+// watchers receive it as soon as it's put.
+const invalidationTTL = 10 * time.Second
+
+func invalidationPrefix(channel string) string {
+	return core.InvalidationKeyPrefix + channel + "/"
+}
+
+// PublishInvalidation puts the event under the channel prefix, each
+// publisher overwrites its own key attached to a short lease.
+func (provider *Etcd) PublishInvalidation(channel string, event core.InvalidationEvent) error {
+	payload, err := event.Encode()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	leaseID, err := provider.leases.get(ctx, provider.Client, invalidationTTL)
+	if err != nil {
+		provider.Reconnect()
+
+		return err
+	}
+
+	_, err = provider.Client.Put(ctx, invalidationPrefix(channel)+event.Origin, string(payload), clientv3.WithLease(leaseID))
+	if err != nil {
+		provider.leases.forget(leaseID)
+	}
+
+	return err
+}
+
+// SubscribeInvalidations watches the channel prefix and calls fn with every
+// event put under it. The watch is started again on the current client when
+// it's closed, e.g. by a reload.
+func (provider *Etcd) SubscribeInvalidations(channel string, fn func(core.InvalidationEvent)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		for ctx.Err() == nil {
+			watch := provider.Client.Watch(clientv3.WithRequireLeader(ctx), invalidationPrefix(channel), clientv3.WithPrefix())
+
+			for response := range watch {
+				if err := response.Err(); err != nil {
+					provider.logger.Errorf("Impossible to watch the Etcd invalidations, %v", err)
+
+					continue
+				}
+
+				for _, ev := range response.Events {
+					if ev.Type != clientv3.EventTypePut {
+						continue
+					}
+
+					event, err := core.DecodeInvalidationEvent(ev.Kv.Value)
+					if err != nil {
+						provider.logger.Errorf("Impossible to decode the Etcd invalidation, %v", err)
+
+						continue
+					}
+
+					fn(event)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	return cancel, nil
+}
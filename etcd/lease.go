@@ -0,0 +1,82 @@
+package etcd
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const defaultLeaseGranularity = 10 * time.Second
+
+// leaseBuckets shares one lease between the keys expiring in the same
+// granularity window instead of granting a lease per key. The expiration is
+// rounded up to the end of its window, the keys live at most one granularity
+// longer than asked.
+type leaseBuckets struct {
+	mu          sync.Mutex
+	granularity time.Duration
+	leases      map[int64]clientv3.LeaseID
+}
+
+func newLeaseBuckets(granularity time.Duration) *leaseBuckets {
+	if granularity <= 0 {
+		granularity = defaultLeaseGranularity
+	}
+
+	return &leaseBuckets{granularity: granularity, leases: map[int64]clientv3.LeaseID{}}
+}
+
+// get returns the lease of the window covering now + ttl, it's granted on
+// the first use of the window.
+func (b *leaseBuckets) get(ctx context.Context, lessor clientv3.Lease, ttl time.Duration) (clientv3.LeaseID, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl).Truncate(b.granularity).Add(b.granularity)
+	window := expiresAt.UnixNano()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if id, found := b.leases[window]; found {
+		return id, nil
+	}
+
+	for w := range b.leases {
+		if w <= now.UnixNano() {
+			delete(b.leases, w)
+		}
+	}
+
+	lease, err := lessor.Grant(ctx, int64(math.Ceil(expiresAt.Sub(now).Seconds())))
+	if err != nil {
+		return 0, err
+	}
+
+	b.leases[window] = lease.ID
+
+	return lease.ID, nil
+}
+
+// forget drops the lease, e.g. revoked by another client, so the next write
+// of its window grants a new one.
+func (b *leaseBuckets) forget(id clientv3.LeaseID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for w, leaseID := range b.leases {
+		if leaseID == id {
+			delete(b.leases, w)
+		}
+	}
+}
+
+// reset drops every lease, they may belong to another cluster once the
+// client is replaced.
+func (b *leaseBuckets) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.leases = map[int64]clientv3.LeaseID{}
+}
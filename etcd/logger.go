@@ -0,0 +1,84 @@
+package etcd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/darkweak/storages/core"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// loggerCore forwards the Etcd client logs, which require a *zap.Logger, to
+// any core.Logger. The fields are appended to the message.
+type loggerCore struct {
+	zapcore.LevelEnabler
+
+	logger core.Logger
+	fields []zapcore.Field
+}
+
+// newZapLogger returns the *zap.Logger given to the Etcd client, the
+// *zap.SugaredLogger is used as is.
+func newZapLogger(logger core.Logger) *zap.Logger {
+	if sugared, ok := logger.(*zap.SugaredLogger); ok {
+		return sugared.Desugar()
+	}
+
+	return zap.New(&loggerCore{LevelEnabler: zapcore.InfoLevel, logger: logger})
+}
+
+func (c *loggerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &loggerCore{
+		LevelEnabler: c.LevelEnabler,
+		logger:       c.logger,
+		fields:       append(append([]zapcore.Field{}, c.fields...), fields...),
+	}
+}
+
+func (c *loggerCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *loggerCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+
+	for _, field := range append(append([]zapcore.Field{}, c.fields...), fields...) {
+		field.AddTo(encoder)
+	}
+
+	message := entry.Message
+
+	if len(encoder.Fields) > 0 {
+		parts := make([]string, 0, len(encoder.Fields))
+		for key, value := range encoder.Fields {
+			parts = append(parts, fmt.Sprintf("%s=%v", key, value))
+		}
+
+		slices.Sort(parts)
+
+		message += " " + strings.Join(parts, " ")
+	}
+
+	switch {
+	case entry.Level >= zapcore.ErrorLevel:
+		c.logger.Error(message)
+	case entry.Level == zapcore.WarnLevel:
+		c.logger.Warn(message)
+	case entry.Level == zapcore.InfoLevel:
+		c.logger.Info(message)
+	default:
+		c.logger.Debug(message)
+	}
+
+	return nil
+}
+
+func (c *loggerCore) Sync() error {
+	return nil
+}
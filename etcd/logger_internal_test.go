@@ -0,0 +1,34 @@
+package etcd
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/darkweak/storages/core"
+	"go.uber.org/zap"
+)
+
+func TestNewZapLogger(t *testing.T) {
+	if _, wrapped := newZapLogger(zap.NewNop().Sugar()).Core().(*loggerCore); wrapped {
+		t.Error("the zap logger should be used as is")
+	}
+
+	var buffer bytes.Buffer
+
+	logger := newZapLogger(core.NewSlogLogger(slog.New(slog.NewTextHandler(&buffer, nil))))
+
+	logger.Debug("hidden")
+	logger.With(zap.String("endpoint", "localhost:2379")).Warn("retrying of unary invoker failed", zap.Int("attempt", 2))
+
+	output := buffer.String()
+
+	if strings.Contains(output, "hidden") {
+		t.Errorf("the debug message shouldn't be forwarded, got %s", output)
+	}
+
+	if !strings.Contains(output, `level=WARN msg="retrying of unary invoker failed attempt=2 endpoint=localhost:2379"`) {
+		t.Errorf("the warning should be forwarded with its fields, got %s", output)
+	}
+}
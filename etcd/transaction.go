@@ -0,0 +1,88 @@
+package etcd
+
+import (
+	"time"
+
+	"github.com/darkweak/storages/core"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// Transact runs fn in a serializable etcd STM, committed only if the keys
+// it read weren't written since. The STM runs fn again on conflict until
+// the write timeout. The values written with a ttl share the leases of
+// their expiration window. The values over the max_value_size can't be
+// chunked in a transaction and fail with core.ErrValueTooLarge.
+func (provider *Etcd) Transact(fn func(tx core.Tx) error) error {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to run the etcd transaction while reconnecting.")
+
+		return core.ErrReconnecting
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	_, err := concurrency.NewSTM(provider.Client, func(stm concurrency.STM) error {
+		return fn(&etcdTx{provider: provider, stm: stm, written: map[string]bool{}})
+	}, concurrency.WithAbortContext(ctx))
+
+	return err
+}
+
+// etcdTx is the core.Tx of an etcd STM. written tracks the keys set, true,
+// or deleted, false, by the transaction since the STM returns an empty
+// value for both the missing and the deleted keys.
+type etcdTx struct {
+	provider *Etcd
+	stm      concurrency.STM
+	written  map[string]bool
+}
+
+func (e *etcdTx) Get(key string) ([]byte, error) {
+	set, found := e.written[key]
+	if found && !set {
+		return nil, core.ErrKeyNotFound
+	}
+
+	value := e.stm.Get(key)
+	if !found && e.stm.Rev(key) == 0 {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return []byte(value), nil
+}
+
+func (e *etcdTx) Set(key string, value []byte, ttl time.Duration) error {
+	if e.provider.chunked(value) {
+		return core.ErrValueTooLarge
+	}
+
+	var opts []clientv3.OpOption
+
+	if ttl > 0 {
+		ctx, cancel := e.provider.writeContext()
+		defer cancel()
+
+		leaseID, err := e.provider.leases.get(ctx, e.provider.Client, ttl)
+		if err != nil {
+			e.provider.logger.Errorf("Impossible to grant the key %s lease in Etcd, %v", key, err)
+
+			return err
+		}
+
+		opts = append(opts, clientv3.WithLease(leaseID))
+	}
+
+	e.stm.Put(key, string(value), opts...)
+	e.written[key] = true
+
+	return nil
+}
+
+func (e *etcdTx) Delete(key string) error {
+	e.stm.Del(key)
+	e.written[key] = false
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package etcd
+
+import (
+	"context"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Watch streams the changes of the keys starting with the prefix with an
+// Etcd watch, the set values are sent as stored. The keys expired with their
+// lease are sent as deletions. The watch is started again on the current
+// client when it's closed, e.g. by a reload, the changes made meanwhile are
+// missed.
+func (provider *Etcd) Watch(ctx context.Context, prefix string) (<-chan core.Event, error) {
+	events := make(chan core.Event, core.EventsBufferSize)
+
+	go func() {
+		defer close(events)
+
+		for ctx.Err() == nil {
+			watch := provider.Client.Watch(clientv3.WithRequireLeader(ctx), prefix, clientv3.WithPrefix())
+
+			for response := range watch {
+				if err := response.Err(); err != nil {
+					provider.logger.Errorf("Impossible to watch the Etcd keys %s, %v", prefix, err)
+
+					continue
+				}
+
+				for _, ev := range response.Events {
+					event := core.Event{Kind: core.EventSet, Key: string(ev.Kv.Key), Value: ev.Kv.Value}
+					if ev.Type == clientv3.EventTypeDelete {
+						event = core.Event{Kind: core.EventDelete, Key: string(ev.Kv.Key)}
+					}
+
+					if !core.SendEvent(ctx, events, event) {
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,696 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const (
+	defaultPath          = "souin-fs"
+	defaultSweepInterval = time.Minute
+
+	// FsyncNone lets the kernel flush the written files.
+	FsyncNone = "none"
+	// FsyncFile flushes each file before it is renamed in place.
+	FsyncFile = "file"
+	// FsyncAlways flushes each file then its directory, the write survives a
+	// power loss once Set returns.
+	FsyncAlways = "always"
+
+	// tempFilePrefix marks the files being written, they're renamed in place
+	// once complete.
+	tempFilePrefix = ".tmp-"
+	// staleTempFileAge is the age of the temporary files left by an
+	// interrupted write the sweeper removes.
+	staleTempFileAge = time.Hour
+
+	// headerSize is the size of the magic, the expiration and the key length
+	// written before the key and the value.
+	headerSize = 16
+)
+
+var (
+	entryMagic = []byte("SFS1")
+
+	errMalformedEntry = errors.New("malformed entry")
+)
+
+// FS provider type, each entry is a file under a two levels sharded
+// directory tree so no directory holds too many files.
+type FS struct {
+	root          string
+	fsync         string
+	sweepInterval time.Duration
+	limiter       *core.BackgroundLimiter
+	stop          chan struct{}
+	stale         time.Duration
+	uuid          string
+	logger        core.Logger
+	compressor    core.Compressor
+	// locks serialize the mapping updates, sharded by key hash.
+	locks [256]sync.Mutex
+}
+
+// configuration is the typed FS provider configuration.
+type configuration struct {
+	// Path is the root directory, the provider path is used when empty.
+	Path string `json:"path"`
+	// Fsync is FsyncNone, FsyncFile or FsyncAlways.
+	Fsync string `json:"fsync"`
+	// SweepInterval is the delay between two removals of the expired files.
+	SweepInterval time.Duration `json:"sweep_interval"`
+}
+
+func parseConfiguration(fsConfiguration core.CacheProvider) (configuration, error) {
+	cfg := configuration{
+		Path:          fsConfiguration.Path,
+		Fsync:         FsyncNone,
+		SweepInterval: defaultSweepInterval,
+	}
+
+	if err := core.DecodeConfiguration(fsConfiguration.Configuration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid fs configuration: %w", err)
+	}
+
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+
+	switch cfg.Fsync {
+	case FsyncNone, FsyncFile, FsyncAlways:
+	default:
+		return cfg, fmt.Errorf("invalid fs configuration: the fsync must be %s, %s or %s, %s given", FsyncNone, FsyncFile, FsyncAlways, cfg.Fsync)
+	}
+
+	if cfg.SweepInterval <= 0 {
+		return cfg, fmt.Errorf("invalid fs configuration: the sweep_interval must be positive, %s given", cfg.SweepInterval)
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(fsConfiguration any) error {
+	_, err := parseConfiguration(core.CacheProvider{Configuration: fsConfiguration})
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("fs", Factory)
+}
+
+// Factory function create new FS instance.
+func Factory(fsConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(fsConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, err := core.CompressorFromConfiguration(fsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(fsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(root, 0o750); err != nil {
+		logger.Errorf("Impossible to create the FS directory %s, %v", root, err)
+
+		return nil, err
+	}
+
+	return &FS{
+		root:          root,
+		fsync:         cfg.Fsync,
+		sweepInterval: cfg.SweepInterval,
+		stale:         stale,
+		uuid:          core.InstanceID("fs", fsConfiguration, stale),
+		logger:        logger,
+		compressor:    compressor,
+		limiter:       limiter,
+	}, nil
+}
+
+// hash returns the hexadecimal sha256 of the key, it names the entry file.
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file holding the key: root/ab/cd/abcd...
+func (provider *FS) path(key string) string {
+	h := hash(key)
+
+	return filepath.Join(provider.root, h[0:2], h[2:4], h)
+}
+
+func (provider *FS) lock(key string) *sync.Mutex {
+	sum := sha256.Sum256([]byte(key))
+
+	return &provider.locks[sum[0]]
+}
+
+// expiresAt returns the expiration of an entry stored for the given duration
+// as an unix timestamp in nanoseconds, zero means it never expires.
+func expiresAt(duration time.Duration) int64 {
+	if duration <= 0 {
+		return 0
+	}
+
+	return time.Now().Add(duration).UnixNano()
+}
+
+func expired(expiration int64, now time.Time) bool {
+	return expiration != 0 && expiration <= now.UnixNano()
+}
+
+func encodeHeader(key string, expiration int64) []byte {
+	header := make([]byte, headerSize, headerSize+len(key))
+	copy(header, entryMagic)
+	binary.BigEndian.PutUint64(header[4:12], uint64(expiration))
+	binary.BigEndian.PutUint32(header[12:16], uint32(len(key)))
+
+	return append(header, key...)
+}
+
+// decodeHeader returns the key, the expiration and the offset of the value.
+func decodeHeader(data []byte) (string, int64, int, error) {
+	if len(data) < headerSize || !bytes.Equal(data[:4], entryMagic) {
+		return "", 0, 0, errMalformedEntry
+	}
+
+	expiration := int64(binary.BigEndian.Uint64(data[4:12]))
+	end := headerSize + int(binary.BigEndian.Uint32(data[12:16]))
+
+	if end > len(data) {
+		return "", 0, 0, errMalformedEntry
+	}
+
+	return string(data[headerSize:end]), expiration, end, nil
+}
+
+// readHeader reads the key and the expiration of the entry file without
+// loading its value.
+func readHeader(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer file.Close()
+
+	header := make([]byte, headerSize)
+	if _, err = io.ReadFull(file, header); err != nil || !bytes.Equal(header[:4], entryMagic) {
+		return "", 0, errMalformedEntry
+	}
+
+	data := make([]byte, headerSize+int(binary.BigEndian.Uint32(header[12:16])))
+	copy(data, header)
+
+	if _, err = io.ReadFull(file, data[headerSize:]); err != nil {
+		return "", 0, errMalformedEntry
+	}
+
+	key, expiration, _, err := decodeHeader(data)
+
+	return key, expiration, err
+}
+
+// load maps the entry file of the key and returns its value, the release
+// function must be called once the value isn't used anymore.
+func (provider *FS) load(key string) ([]byte, func(), error) {
+	path := provider.path(key)
+
+	data, release, err := mmapFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored, expiration, offset, err := decodeHeader(data)
+	if err != nil || stored != key {
+		release()
+
+		return nil, nil, core.ErrKeyNotFound
+	}
+
+	if expired(expiration, time.Now()) {
+		release()
+
+		_ = os.Remove(path)
+
+		return nil, nil, core.ErrKeyNotFound
+	}
+
+	return data[offset:], release, nil
+}
+
+// write stores the content read from r under the key, in a temporary file
+// renamed in place so the readers never see a partial entry.
+func (provider *FS) write(key string, r io.Reader, duration time.Duration) error {
+	path := provider.path(key)
+	dir := filepath.Dir(path)
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, tempFilePrefix)
+	if err != nil {
+		return err
+	}
+
+	tmp := file.Name()
+
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if _, err = file.Write(encodeHeader(key, expiresAt(duration))); err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(file, r); err != nil {
+		return err
+	}
+
+	if provider.fsync != FsyncNone {
+		if err = file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	if provider.fsync == FsyncAlways {
+		return syncDir(dir)
+	}
+
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// walk calls walkFn with the path, the key and the expiration of each
+// entry, the temporary files are skipped.
+func (provider *FS) walk(walkFn func(path, key string, expiration int64) error) error {
+	return filepath.WalkDir(provider.root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		key, expiration, err := readHeader(path)
+		if err != nil {
+			return nil
+		}
+
+		return walkFn(path, key, expiration)
+	})
+}
+
+// Name returns the storer name.
+func (provider *FS) Name() string {
+	return "FS"
+}
+
+// Uuid returns an unique identifier.
+func (provider *FS) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *FS) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+	now := time.Now()
+
+	err := provider.walk(func(_, key string, expiration int64) error {
+		if !strings.HasPrefix(key, prefix) || expired(expiration, now) {
+			return nil
+		}
+
+		if value, err := provider.Lookup(key); err == nil {
+			keys[strings.TrimPrefix(key, prefix)] = string(value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in FS, %v", err)
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *FS) ListKeys() []string {
+	keys := []string{}
+	now := time.Now()
+
+	err := provider.walk(func(_, key string, expiration int64) error {
+		if !strings.HasPrefix(key, core.MappingKeyPrefix) || expired(expiration, now) {
+			return nil
+		}
+
+		if value, err := provider.Lookup(key); err == nil {
+			keys = append(keys, core.MappingRealKeys(value, now)...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in FS, %v", err)
+	}
+
+	return keys
+}
+
+// WalkEntries streams every entry with its remaining TTL, zero when the
+// entry never expires.
+func (provider *FS) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	stop := errors.New("stop")
+
+	err := provider.walk(func(_, key string, expiration int64) error {
+		var ttl time.Duration
+		if expiration != 0 {
+			if ttl = time.Until(time.Unix(0, expiration)); ttl <= 0 {
+				return nil
+			}
+		}
+
+		value, err := provider.Lookup(key)
+		if err != nil {
+			return nil
+		}
+
+		if !walkFn(key, value, ttl) {
+			return stop
+		}
+
+		return nil
+	})
+	if errors.Is(err, stop) {
+		return nil
+	}
+
+	return err
+}
+
+// Snapshot writes every entry to w as a core.Dump export, it can be restored
+// on another host whatever its path.
+func (provider *FS) Snapshot(w io.Writer) error {
+	_, err := core.Dump(provider, w)
+
+	return err
+}
+
+// Restore stores the entries of a Snapshot with their remaining TTL, the
+// current entries are kept.
+func (provider *FS) Restore(r io.Reader) error {
+	_, err := core.Warmup(provider, r)
+
+	return err
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *FS) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *FS) Lookup(key string) ([]byte, error) {
+	value, release, err := provider.load(key)
+	if err != nil {
+		if !errors.Is(err, core.ErrKeyNotFound) {
+			provider.logger.Errorf("Impossible to get the key %s in FS, %v", key, err)
+		}
+
+		return nil, err
+	}
+
+	defer release()
+
+	return bytes.Clone(value), nil
+}
+
+// mappedReader reads a mapped value and releases it once closed.
+type mappedReader struct {
+	*bytes.Reader
+
+	release func()
+	once    sync.Once
+}
+
+func (r *mappedReader) Close() error {
+	r.once.Do(r.release)
+
+	return nil
+}
+
+// GetReader returns a reader on the mapped value, the value is never copied
+// in memory.
+func (provider *FS) GetReader(key string) (io.ReadCloser, error) {
+	value, release, err := provider.load(key)
+	if errors.Is(err, core.ErrKeyNotFound) {
+		return nil, core.ErrStreamNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &mappedReader{Reader: bytes.NewReader(value), release: release}, nil
+}
+
+// SetReader stores the content read from r without buffering it in memory.
+func (provider *FS) SetReader(key string, r io.Reader, ttl time.Duration) error {
+	if err := provider.write(key, r, ttl); err != nil {
+		provider.logger.Errorf("Impossible to set value into FS, %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *FS) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	result := provider.Get(core.MappingKeyPrefix + key)
+	if len(result) == 0 {
+		return fresh, stale
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, result, req, validator, provider.logger)
+
+	return fresh, stale
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *FS) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into FS, %v", variedKey, err)
+
+		return err
+	}
+
+	if err = provider.Set(variedKey, compressed, duration+provider.stale); err != nil {
+		return err
+	}
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	mu := provider.lock(mappingKey)
+	mu.Lock()
+	defer mu.Unlock()
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, provider.Get(mappingKey), provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	if err = provider.Set(mappingKey, val, duration+provider.stale); err != nil {
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in FS provider.
+func (provider *FS) Set(key string, value []byte, duration time.Duration) error {
+	if err := provider.write(key, bytes.NewReader(value), duration); err != nil {
+		provider.logger.Errorf("Impossible to set value into FS, %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Delete method will delete the response in FS provider if exists corresponding to key param.
+func (provider *FS) Delete(key string) {
+	if err := os.Remove(provider.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		provider.logger.Errorf("Impossible to delete the key %s in FS, %v", key, err)
+	}
+}
+
+// DeleteMany method will delete the responses in FS provider if exists corresponding to the regex key param.
+func (provider *FS) DeleteMany(key string) {
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	err = provider.walk(func(path, k string, _ int64) error {
+		if rgKey.MatchString(k) {
+			_ = os.Remove(path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to delete the keys in FS, %v", err)
+	}
+}
+
+// sweep removes the expired entries, reading their header only, and the
+// temporary files left by the interrupted writes.
+func (provider *FS) sweep() error {
+	now := time.Now()
+
+	return filepath.WalkDir(provider.root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+
+		if strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			if info, err := entry.Info(); err == nil && now.Sub(info.ModTime()) > staleTempFileAge {
+				_ = os.Remove(path)
+			}
+
+			return nil
+		}
+
+		if _, expiration, err := readHeader(path); err == nil && expired(expiration, now) {
+			_ = os.Remove(path)
+		}
+
+		return nil
+	})
+}
+
+// Healthy checks the root directory is still reachable.
+func (provider *FS) Healthy(_ context.Context) error {
+	info, err := os.Stat(provider.root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", provider.root)
+	}
+
+	return nil
+}
+
+// Init method will start the background sweep of the expired files.
+func (provider *FS) Init() error {
+	if provider.stop != nil {
+		return nil
+	}
+
+	provider.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(provider.sweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(stop, "sweep") {
+					return
+				}
+
+				if err := provider.sweep(); err != nil {
+					provider.logger.Errorf("Impossible to sweep the expired keys in FS, %v", err)
+				}
+			}
+		}
+	}(provider.stop)
+
+	return nil
+}
+
+// Reset method will stop the background sweep, the files are kept.
+func (provider *FS) Reset() error {
+	if provider.stop != nil {
+		close(provider.stop)
+		provider.stop = nil
+	}
+
+	return nil
+}
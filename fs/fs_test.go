@@ -0,0 +1,301 @@
+package fs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
+	"github.com/darkweak/storages/fs"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getFSInstance(t *testing.T, configuration map[string]interface{}) (core.Storer, string) {
+	t.Helper()
+
+	path := t.TempDir()
+
+	client, err := fs.Factory(core.CacheProvider{Path: path, Configuration: configuration}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the FS storage, %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Reset()
+	})
+
+	if err = client.Init(); err != nil {
+		t.Fatalf("Impossible to init the FS storage, %v", err)
+	}
+
+	return client, path
+}
+
+func TestIShouldBeAbleToReadAndWriteDataInFS(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+
+	_ = client.Set("Test", []byte(baseValue), 20*time.Second)
+
+	if res := client.Get("Test"); string(res) != baseValue {
+		t.Errorf("%s not corresponding to %s", string(res), baseValue)
+	}
+
+	if res := client.Get(nonExistentKey); res != nil {
+		t.Errorf("Key %s should not exist", nonExistentKey)
+	}
+}
+
+func TestFS_ShardedLayout(t *testing.T) {
+	client, root := getFSInstance(t, map[string]interface{}{"fsync": fs.FsyncAlways})
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	files := []string{}
+	_ = filepath.WalkDir(root, func(path string, entry os.DirEntry, _ error) error {
+		if !entry.IsDir() {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	if len(files) != 1 {
+		t.Fatalf("One file should be written, %v given", files)
+	}
+
+	rel, _ := filepath.Rel(root, files[0])
+	parts := strings.Split(rel, string(filepath.Separator))
+
+	if len(parts) != 3 || len(parts[0]) != 2 || len(parts[1]) != 2 || !strings.HasPrefix(parts[2], parts[0]+parts[1]) {
+		t.Errorf("The file should be stored under two levels of shards, %s given", rel)
+	}
+}
+
+func TestFS_SetRequestInCache_TTL(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+	_ = client.Set(byteKey, []byte(baseValue), 100*time.Millisecond)
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should exist", byteKey)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if client.Get(byteKey) != nil {
+		t.Errorf("Key %s should be expired", byteKey)
+	}
+}
+
+func TestFS_Sweep(t *testing.T) {
+	client, root := getFSInstance(t, map[string]interface{}{"sweep_interval": "50ms"})
+
+	_ = client.Set("expiring", []byte(baseValue), 10*time.Millisecond)
+	_ = client.Set("kept", []byte(baseValue), time.Minute)
+
+	time.Sleep(300 * time.Millisecond)
+
+	count := 0
+	_ = filepath.WalkDir(root, func(_ string, entry os.DirEntry, _ error) error {
+		if !entry.IsDir() {
+			count++
+		}
+
+		return nil
+	})
+
+	if count != 1 {
+		t.Errorf("The sweeper should remove the expired file only, %d files left", count)
+	}
+}
+
+func TestFS_DeleteMany(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+
+	_ = client.Set("DELETE_MANY_1", []byte(baseValue), time.Minute)
+	_ = client.Set("DELETE_MANY_2", []byte(baseValue), time.Minute)
+	_ = client.Set("KEEP_MANY_3", []byte(baseValue), time.Minute)
+
+	client.DeleteMany("^DELETE_MANY_.+")
+
+	if client.Get("DELETE_MANY_1") != nil || client.Get("DELETE_MANY_2") != nil {
+		t.Error("The matching keys should be deleted")
+	}
+
+	if client.Get("KEEP_MANY_3") == nil {
+		t.Error("The other keys should be kept")
+	}
+
+	client.Delete("KEEP_MANY_3")
+
+	if client.Get("KEEP_MANY_3") != nil {
+		t.Error("The deleted key shouldn't exist")
+	}
+}
+
+func TestFS_SetMultiLevel(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+
+	for _, variant := range []string{"gzip", "br"} {
+		variedKey := "multi-level-" + variant
+
+		err := client.SetMultiLevel("multi-level", variedKey, []byte(baseValue), http.Header{"Accept-Encoding": []string{variant}}, "", time.Minute, variedKey)
+		if err != nil {
+			t.Fatalf("Failed to set the value: %v", err)
+		}
+	}
+
+	mapping, err := core.DecodeMapping(client.Get(core.MappingKeyPrefix + "multi-level"))
+	if err != nil {
+		t.Fatalf("Impossible to decode the mapping, %v", err)
+	}
+
+	if len(mapping.GetMapping()) != 2 {
+		t.Errorf("The mapping should hold both variants, %d given", len(mapping.GetMapping()))
+	}
+
+	if keys := client.ListKeys(); len(keys) != 2 {
+		t.Errorf("The variants should be listed, %v given", keys)
+	}
+
+	if keys := client.MapKeys(core.MappingKeyPrefix); len(keys) != 1 {
+		t.Errorf("The mapping should be mapped, %v given", keys)
+	}
+}
+
+func TestFS_Stream(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+	streamer, _ := client.(core.StreamStorer)
+
+	if err := streamer.SetReader(byteKey, strings.NewReader(baseValue), time.Minute); err != nil {
+		t.Fatalf("Impossible to stream the value, %v", err)
+	}
+
+	reader, err := streamer.GetReader(byteKey)
+	if err != nil {
+		t.Fatalf("Impossible to read the streamed value, %v", err)
+	}
+
+	value, _ := io.ReadAll(reader)
+	_ = reader.Close()
+
+	if string(value) != baseValue {
+		t.Errorf("%s not corresponding to %s", value, baseValue)
+	}
+
+	if _, err = streamer.GetReader(nonExistentKey); !errors.Is(err, core.ErrStreamNotFound) {
+		t.Errorf("A missing key should return core.ErrStreamNotFound, %v given", err)
+	}
+}
+
+func TestFS_Persistence(t *testing.T) {
+	path := t.TempDir()
+
+	client, _ := fs.Factory(core.CacheProvider{Path: path}, zap.NewNop().Sugar(), 0)
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Reset()
+
+	client, err := fs.Factory(core.CacheProvider{Path: path}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to reopen the storage, %v", err)
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should be persisted", byteKey)
+	}
+}
+
+func TestFS_Lookup(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The storage should be healthy, %v given", err)
+	}
+}
+
+func TestFS_Validate(t *testing.T) {
+	if err := fs.Validate(map[string]interface{}{"fsync": "file", "sweep_interval": "5m"}); err != nil {
+		t.Errorf("The configuration should be valid, %v given", err)
+	}
+
+	if err := fs.Validate(map[string]interface{}{"fsync": "sometimes"}); err == nil {
+		t.Error("An unknown fsync policy should be invalid")
+	}
+}
+
+func TestFS_DumpWarmup(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Set("persistent", []byte(baseValue), 0)
+
+	var dump bytes.Buffer
+
+	if count, err := core.Dump(client, &dump); err != nil || count != 2 {
+		t.Fatalf("Every entry should be dumped, %d entries and %v given", count, err)
+	}
+
+	target, _ := getFSInstance(t, nil)
+
+	if _, err := core.Warmup(target, &dump); err != nil {
+		t.Fatalf("Impossible to warm up the entries, %v", err)
+	}
+
+	if string(target.Get(byteKey)) != baseValue || string(target.Get("persistent")) != baseValue {
+		t.Error("The dumped entries should be restored")
+	}
+}
+
+func TestFS_SnapshotRestore(t *testing.T) {
+	client, _ := getFSInstance(t, nil)
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	var snapshot bytes.Buffer
+
+	if err := core.Snapshot(client, &snapshot); err != nil {
+		t.Fatalf("Impossible to snapshot the storage, %v", err)
+	}
+
+	target, _ := getFSInstance(t, nil)
+
+	if err := core.Restore(target, &snapshot); err != nil {
+		t.Fatalf("Impossible to restore the storage, %v", err)
+	}
+
+	if string(target.Get(byteKey)) != baseValue {
+		t.Error("The snapshot should be restored in another storage")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := fs.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
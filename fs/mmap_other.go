@@ -0,0 +1,15 @@
+//go:build !unix
+
+package fs
+
+import "os"
+
+// mmapFile reads the whole file where mmap isn't available.
+func mmapFile(path string) ([]byte, func(), error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() {}, nil
+}
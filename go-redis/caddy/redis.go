@@ -43,6 +43,11 @@ func (b *Redis) Provision(ctx caddy.Context) error {
 		return err
 	}
 
+	storer, err = core.EncryptedStorerFromConfiguration(storer, b.Configuration.Provider, b.Configuration.Stale, logger.Sugar())
+	if err != nil {
+		return err
+	}
+
 	core.RegisterStorage(storer)
 
 	return nil
@@ -1,7 +1,6 @@
 package redis
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -9,11 +8,12 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/darkweak/storages/core"
-	"github.com/pierrec/lz4/v4"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -21,16 +21,66 @@ import (
 type Redis struct {
 	inClient      redis.UniversalClient
 	stale         time.Duration
+	uuid          string
 	ctx           context.Context
 	logger        core.Logger
+	compressor    core.Compressor
 	configuration redis.UniversalOptions
 	close         func() error
-	reconnecting  bool
+	reconnector   *core.Reconnector
 	hashtags      string
+	timeouts      core.Timeouts
+	// locks keeps the token of each lock held by this instance.
+	locks sync.Map
 }
 
-// Factory function create new Redis instance.
-func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+// unlockScript deletes the lock only if it still holds the owner token, a
+// lock expired then acquired by another instance is never released.
+var unlockScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+
+// incrementScript increments the counter with INCRBY then sets its PX
+// expiration when ARGV[2] is positive, or removes it.
+var incrementScript = redis.NewScript(`local counter = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 then redis.call("PEXPIRE", KEYS[1], ARGV[2]) else redis.call("PERSIST", KEYS[1]) end
+return counter`)
+
+// compareAndSwapScript replaces the value only if it still equals the old
+// one, with the PX expiration when ARGV[3] is positive.
+var compareAndSwapScript = redis.NewScript(`if redis.call("GET", KEYS[1]) ~= ARGV[1] then return 0 end
+if tonumber(ARGV[3]) > 0 then redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3]) else redis.call("SET", KEYS[1], ARGV[2]) end
+return 1`)
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("go-redis", Factory)
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(redisConfiguration any) error {
+	bc, err := json.Marshal(redisConfiguration)
+	if err == nil {
+		err = json.Unmarshal(bc, &redis.UniversalOptions{})
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid go-redis configuration: %w", err)
+	}
+
+	_, err = core.PoolFromConfiguration(redisConfiguration)
+
+	return err
+}
+
+// settings are the client options and the provider settings read from the
+// configuration.
+type settings struct {
+	options    redis.UniversalOptions
+	hashtags   string
+	compressor core.Compressor
+	timeouts   core.Timeouts
+}
+
+func parseSettings(redisConfiguration core.CacheProvider, logger core.Logger) (settings, error) {
 	var options redis.UniversalOptions
 
 	var hashtags string
@@ -38,11 +88,13 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 	if redisConfiguration.Configuration != nil {
 		bc, err := json.Marshal(redisConfiguration.Configuration)
 		if err != nil {
-			return nil, err
+			return settings{}, err
 		}
 
 		if err := json.Unmarshal(bc, &options); err != nil {
-			logger.Infof("Cannot parse your redis configuration: %+v", err)
+			logger.Errorf("Cannot parse your redis configuration: %+v", err)
+
+			return settings{}, fmt.Errorf("invalid go-redis configuration: %w", err)
 		}
 
 		if redisConfig, ok := redisConfiguration.Configuration.(map[string]interface{}); ok && redisConfig != nil {
@@ -55,12 +107,12 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 			if value, ok := redisConfig["TLSConfig"]; ok {
 				tlsConfigBytes, err := json.Marshal(value)
 				if err != nil {
-					return nil, err
+					return settings{}, err
 				}
 
 				var tlsConfig tls.Config
 				if err = json.Unmarshal(tlsConfigBytes, &tlsConfig); err != nil {
-					return nil, err
+					return settings{}, err
 				}
 
 				options.TLSConfig = &tlsConfig
@@ -74,24 +126,121 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 	}
 
 	if len(options.Addrs) == 0 {
-		return nil, errors.New("no redis addresses given")
+		return settings{}, errors.New("no redis addresses given")
 	}
 
 	if options.ClientName == "" {
 		options.ClientName = "souin-redis"
 	}
 
-	cli := redis.NewUniversalClient(&options)
+	compressor, err := core.CompressorFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
 
-	return &Redis{
+	tlsConfig, err := core.TLSConfigFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
+
+	if tlsConfig != nil {
+		options.TLSConfig = tlsConfig
+	}
+
+	timeouts, err := core.TimeoutsFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
+
+	pool, err := core.PoolFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
+
+	applyPool(&options, pool)
+
+	return settings{options: options, hashtags: hashtags, compressor: compressor, timeouts: timeouts}, nil
+}
+
+// applyPool overrides the client options with the pool settings set.
+func applyPool(options *redis.UniversalOptions, pool core.Pool) {
+	if pool.Size > 0 {
+		options.PoolSize = pool.Size
+	}
+
+	if pool.MinIdle > 0 {
+		options.MinIdleConns = pool.MinIdle
+	}
+
+	if pool.MaxRetries != 0 {
+		options.MaxRetries = pool.MaxRetries
+	}
+
+	if pool.DialBackoff > 0 {
+		options.MinRetryBackoff = pool.DialBackoff
+	}
+
+	if pool.MaxDialBackoff > 0 {
+		options.MaxRetryBackoff = pool.MaxDialBackoff
+	}
+}
+
+// Factory function create new Redis instance.
+func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	parsed, err := parseSettings(redisConfiguration, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	cli := redis.NewUniversalClient(&parsed.options)
+
+	instance := &Redis{
 		inClient:      cli,
 		ctx:           context.Background(),
 		stale:         stale,
-		configuration: options,
+		uuid:          core.InstanceID("go-redis", redisConfiguration, stale),
+		configuration: parsed.options,
 		logger:        logger,
+		compressor:    parsed.compressor,
 		close:         cli.Close,
-		hashtags:      hashtags,
-	}, nil
+		hashtags:      parsed.hashtags,
+		timeouts:      parsed.timeouts,
+	}
+	instance.reconnector = core.NewReconnector(redisConfiguration.Configuration, logger, instance.connect)
+
+	return instance, nil
+}
+
+// Reload connects a new client with the given configuration then closes the
+// previous one, the stored keys live in Redis and are kept.
+func (provider *Redis) Reload(redisConfiguration core.CacheProvider) error {
+	parsed, err := parseSettings(redisConfiguration, provider.logger)
+	if err != nil {
+		return err
+	}
+
+	cli := redis.NewUniversalClient(&parsed.options)
+	if err = cli.Ping(provider.ctx).Err(); err != nil {
+		provider.logger.Errorf("Impossible to reload the Redis client, %v", err)
+
+		_ = cli.Close()
+
+		return err
+	}
+
+	previous := provider.close
+	provider.inClient = cli
+	provider.close = cli.Close
+	provider.configuration = parsed.options
+	provider.compressor = parsed.compressor
+	provider.hashtags = parsed.hashtags
+	provider.timeouts = parsed.timeouts
+
+	if previous != nil {
+		return previous()
+	}
+
+	return nil
 }
 
 // Name returns the storer name.
@@ -101,55 +250,127 @@ func (provider *Redis) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Redis) Uuid() string {
-	return fmt.Sprintf(
-		"%s-%s-%d-%s-%s",
-		strings.Join(provider.configuration.Addrs, ","),
-		provider.configuration.Username,
-		provider.configuration.DB,
-		provider.configuration.ClientName,
-		provider.stale,
-	)
+	return provider.uuid
 }
 
 // ListKeys method returns the list of existing keys.
 func (provider *Redis) ListKeys() []string {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to list the redis keys while reconnecting.")
+	keys := []string{}
 
+	if err := provider.WalkKeys(func(key string) bool {
+		keys = append(keys, key)
+
+		return true
+	}); err != nil {
 		return []string{}
 	}
 
-	keys := []string{}
+	return keys
+}
 
-	iter := provider.inClient.Scan(provider.ctx, 0, provider.hashtags+core.MappingKeyPrefix+"*", 100).Iterator()
-	for iter.Next(provider.ctx) {
-		value := provider.Get(iter.Val())
+// readContext returns the context bounding a read.
+func (provider *Redis) readContext() (context.Context, context.CancelFunc) {
+	return provider.timeouts.ReadContext(provider.ctx)
+}
 
-		mapping, err := core.DecodeMapping(value)
-		if err != nil {
-			continue
-		}
+// writeContext returns the context bounding a write or a deletion.
+func (provider *Redis) writeContext() (context.Context, context.CancelFunc) {
+	return provider.timeouts.WriteContext(provider.ctx)
+}
 
-		for _, v := range mapping.GetMapping() {
-			if v.GetFreshTime().AsTime().Before(time.Now()) && v.GetStaleTime().AsTime().Before(time.Now()) {
-				continue
-			}
+// scan starts a SCAN iteration, each SCAN call is bounded by the read
+// timeout.
+func (provider *Redis) scan(match string, count int64) *redis.ScanIterator {
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	return provider.inClient.Scan(ctx, 0, match, count).Iterator()
+}
+
+// next fetches the next key of the SCAN iteration.
+func (provider *Redis) next(iter *redis.ScanIterator) bool {
+	ctx, cancel := provider.readContext()
+	defer cancel()
 
-			keys = append(keys, v.GetRealKey())
+	return iter.Next(ctx)
+}
+
+// WalkKeys streams the keys returned by ListKeys, scanning the mappings in
+// bounded batches.
+func (provider *Redis) WalkKeys(walkFn func(key string) bool) error {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to list the redis keys while reconnecting.")
+
+		return core.ErrReconnecting
+	}
+
+	iter := provider.scan(provider.hashtags+core.MappingKeyPrefix+"*", mappingBatchSize)
+	for provider.next(iter) {
+		for _, key := range core.MappingRealKeys(provider.Get(iter.Val()), time.Now()) {
+			if !walkFn(key) {
+				return nil
+			}
 		}
 	}
 
 	if err := iter.Err(); err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
+		provider.Reconnect()
+
+		provider.logger.Error(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// ListKeysPaginated returns a page of the keys returned by ListKeys, the
+// cursor is the redis SCAN one.
+func (provider *Redis) ListKeysPaginated(cursor string, limit int) ([]string, string) {
+	keys := []string{}
+
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to list the redis keys while reconnecting.")
+
+		return keys, cursor
+	}
+
+	var position uint64
+
+	if cursor != "" {
+		var err error
+		if position, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			provider.logger.Errorf("Invalid redis keys cursor %s, %v", cursor, err)
+
+			return keys, ""
 		}
+	}
+
+	if limit <= 0 {
+		limit = mappingBatchSize
+	}
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	mappings, next, err := provider.inClient.Scan(ctx, position, provider.hashtags+core.MappingKeyPrefix+"*", int64(limit)).Result()
+	if err != nil {
+		provider.Reconnect()
 
 		provider.logger.Error(err)
 
-		return []string{}
+		return keys, cursor
 	}
 
-	return keys
+	for _, mapping := range mappings {
+		keys = append(keys, core.MappingRealKeys(provider.Get(mapping), time.Now())...)
+	}
+
+	if next == 0 {
+		return keys, ""
+	}
+
+	return keys, strconv.FormatUint(next, 10)
 }
 
 // MapKeys method returns the list of existing keys.
@@ -171,10 +392,10 @@ const mappingBatchSize = 100
 // bounded batches so the whole mapping index is never loaded in memory at
 // once. The walk stops early when walkFn returns false.
 func (provider *Redis) WalkMappings(prefix string, walkFn func(key string, value []byte) bool) error {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to walk the redis mappings while reconnecting.")
 
-		return errors.New("reconnecting error")
+		return core.ErrReconnecting
 	}
 
 	batch := make([]string, 0, mappingBatchSize)
@@ -184,7 +405,10 @@ func (provider *Redis) WalkMappings(prefix string, walkFn func(key string, value
 			return true, nil
 		}
 
-		vals, err := provider.inClient.MGet(provider.ctx, batch...).Result()
+		ctx, cancel := provider.readContext()
+		vals, err := provider.inClient.MGet(ctx, batch...).Result()
+		cancel()
+
 		if err != nil {
 			return false, err
 		}
@@ -210,8 +434,8 @@ func (provider *Redis) WalkMappings(prefix string, walkFn func(key string, value
 		return true, nil
 	}
 
-	iter := provider.inClient.Scan(provider.ctx, 0, prefix+"*", mappingBatchSize).Iterator()
-	for iter.Next(provider.ctx) {
+	iter := provider.scan(prefix+"*", mappingBatchSize)
+	for provider.next(iter) {
 		batch = append(batch, iter.Val())
 
 		if len(batch) >= mappingBatchSize {
@@ -232,102 +456,173 @@ func (provider *Redis) WalkMappings(prefix string, walkFn func(key string, value
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Redis) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	b, e := provider.inClient.Get(provider.ctx, provider.hashtags+core.MappingKeyPrefix+key).Bytes()
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	b, e := provider.inClient.Get(ctx, provider.hashtags+core.MappingKeyPrefix+key).Bytes()
 	if e != nil {
 		return fresh, stale
 	}
 
-	fresh, stale, _ = core.MappingElection(provider, b, req, validator, provider.logger)
+	fresh, stale, _ = core.MappingElectionFor(provider, provider.hashtags+core.MappingKeyPrefix+key, b, req, validator, provider.logger)
 
 	return fresh, stale
 }
 
+// GetMetadata returns the metadata of the varied keys stored for the base
+// key, its mapping is stored behind the hash tags.
+func (provider *Redis) GetMetadata(key string) ([]core.KeyMetadata, error) {
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	b, err := provider.inClient.Get(ctx, provider.hashtags+core.MappingKeyPrefix+key).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	return core.DecodeMetadata(b)
+}
+
 // SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
 func (provider *Redis) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	// The lz4 default block size is 4 MB, which makes every compression and
-	// later decompression of the value churn 4 MB pooled blocks even for tiny
-	// payloads. Cached bodies are usually far smaller, so use the smallest
-	// block size. Readers pick the block size up from the frame header.
-	if err := writer.Apply(lz4.BlockSizeOption(lz4.Block64Kb)); err != nil {
-		provider.logger.Errorf("Impossible to configure the compressor for key %s into Redis, %v", variedKey, err)
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into Redis, %v", variedKey, err)
 
 		return err
 	}
 
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
-		provider.logger.Errorf("Impossible to compress the key %s into Redis, %v", variedKey, err)
+	if err := provider.Set(provider.hashtags+variedKey, compressed, duration); err != nil {
+		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Redis, %v", variedKey, err)
+	mappingKey := provider.hashtags + core.MappingKeyPrefix + baseKey
 
-		return err
-	}
+	var evicted []string
 
-	if err := provider.Set(provider.hashtags+variedKey, compressed.Bytes(), duration); err != nil {
-		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
+	// WATCH the mapping key so the transaction fails when another instance
+	// updates it between the read and the write.
+	err = core.UpdateMapping(func() error {
+		ctx, cancel := provider.writeContext()
+		defer cancel()
 
-		return err
-	}
+		err := provider.inClient.Watch(ctx, func(tx *redis.Tx) error {
+			result, err := tx.Get(ctx, mappingKey).Bytes()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
 
-	mappingKey := provider.hashtags + core.MappingKeyPrefix + baseKey
-	result, err := provider.inClient.Get(provider.ctx, mappingKey).Bytes()
+			var val []byte
 
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return err
-	}
+			val, evicted, err = core.MappingUpdaterWithEvictions(provider.hashtags+variedKey, result, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+			if err != nil {
+				return err
+			}
 
-	val, err := core.MappingUpdater(provider.hashtags+variedKey, result, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
-	if err != nil {
-		return err
-	}
+			// Bound the mapping key lifetime instead of storing it forever: it only
+			// needs to outlive the longest-lived entry it references. Never shorten
+			// an expiration owned by a longer-lived entry; TTL returns a negative
+			// value for missing keys or keys without expiration, so legacy unbounded
+			// mapping keys become bounded on their next update.
+			mappingTTL := duration + provider.stale
+			if remaining := tx.TTL(ctx, mappingKey).Val(); remaining > mappingTTL {
+				mappingTTL = remaining
+			}
 
-	// Bound the mapping key lifetime instead of storing it forever: it only
-	// needs to outlive the longest-lived entry it references. Never shorten
-	// an expiration owned by a longer-lived entry; TTL returns a negative
-	// value for missing keys or keys without expiration, so legacy unbounded
-	// mapping keys become bounded on their next update.
-	mappingTTL := duration + provider.stale
-	if remaining := provider.inClient.TTL(provider.ctx, mappingKey).Val(); remaining > mappingTTL {
-		mappingTTL = remaining
-	}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				return pipe.Set(ctx, mappingKey, val, mappingTTL).Err()
+			})
 
-	if err = provider.inClient.Set(provider.ctx, mappingKey, val, mappingTTL).Err(); err != nil {
+			return err
+		}, mappingKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			return core.ErrMappingConflict
+		}
+
+		return err
+	})
+	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
+
+		return err
 	}
 
-	return err
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
 }
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Redis) Get(key string) (item []byte) {
-	if provider.reconnecting {
+	item, _ = provider.Lookup(key)
+
+	return
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist.
+func (provider *Redis) Lookup(key string) ([]byte, error) {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to get the redis key while reconnecting.")
 
-		return
+		return nil, core.ErrReconnecting
+	}
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	result, err := provider.inClient.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, core.ErrKeyNotFound
 	}
 
-	result, err := provider.inClient.Get(provider.ctx, key).Result()
 	if err != nil {
-		if !errors.Is(err, redis.Nil) && !provider.reconnecting {
-			go provider.Reconnect()
+		provider.Reconnect()
+
+		return nil, err
+	}
+
+	return []byte(result), nil
+}
+
+// GetMany method returns the values of the found keys, read in one
+// pipeline.
+func (provider *Redis) GetMany(keys []string) map[string][]byte {
+	values := make(map[string][]byte, len(keys))
+
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to get the redis keys while reconnecting.")
+
+		return values
+	}
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	cmds, err := provider.inClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Get(ctx, key)
 		}
 
-		return
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		provider.Reconnect()
+
+		return values
 	}
 
-	item = []byte(result)
+	for i, cmd := range cmds {
+		if value, err := cmd.(*redis.StringCmd).Bytes(); err == nil {
+			values[keys[i]] = value
+		}
+	}
 
-	return
+	return values
 }
 
 // Prefix method returns the keys that match the prefix key.
@@ -338,10 +633,10 @@ func (provider *Redis) Prefix(key string) []string {
 
 // Set method will store the response in Etcd provider.
 func (provider *Redis) Set(key string, value []byte, duration time.Duration) error {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to set the redis value while reconnecting.")
 
-		return errors.New("reconnecting error")
+		return core.ErrReconnecting
 	}
 
 	if duration == -1 {
@@ -350,11 +645,12 @@ func (provider *Redis) Set(key string, value []byte, duration time.Duration) err
 		duration += provider.stale
 	}
 
-	err := provider.inClient.Set(provider.ctx, key, value, duration).Err()
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	err := provider.inClient.Set(ctx, key, value, duration).Err()
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
 	}
@@ -364,52 +660,258 @@ func (provider *Redis) Set(key string, value []byte, duration time.Duration) err
 
 // Delete method will delete the response in Etcd provider if exists corresponding to key param.
 func (provider *Redis) Delete(key string) {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to delete the redis key while reconnecting.")
 
 		return
 	}
 
-	_ = provider.inClient.Del(provider.ctx, key)
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	_ = provider.inClient.Del(ctx, key)
 }
 
 // DeleteMany method will delete the responses in Redis provider if exists corresponding to the regex key param.
 func (provider *Redis) DeleteMany(key string) {
-	if provider.reconnecting {
+	_, _ = provider.DeleteManyCount(key, false)
+}
+
+// DeleteManyCount deletes the keys matching the regular expression, the SCAN
+// only matches the keys starting with its literal prefix. In dry-run the
+// matching keys are listed without being deleted.
+func (provider *Redis) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result := core.DeleteManyResult{}
+
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to delete the redis keys while reconnecting.")
 
-		return
+		return result, core.ErrReconnecting
 	}
 
-	rgKey, err := regexp.Compile(key)
+	rgKey, err := regexp.Compile(pattern)
 	if err != nil {
-		return
+		return result, err
 	}
 
 	keys := []string{}
-	iter := provider.inClient.Scan(provider.ctx, 0, "*", 100).Iterator()
+	iter := provider.scan(escapeGlob(core.PatternPrefix(pattern))+"*", 100)
 
-	for iter.Next(provider.ctx) {
+	for provider.next(iter) {
 		if rgKey.MatchString(iter.Val()) {
 			keys = append(keys, iter.Val())
+			result.Add(iter.Val(), dryRun)
 		}
 
 		if len(keys) >= 100 {
-			provider.inClient.Unlink(provider.ctx, keys...)
+			if !dryRun {
+				provider.unlink(keys)
+			}
+
 			keys = keys[:0]
 		}
 	}
 
-	if iter.Err() != nil && !provider.reconnecting {
-		go provider.Reconnect()
+	if err = iter.Err(); err != nil {
+		provider.Reconnect()
 
-		return
+		return result, err
 	}
 
 	// unlink the rest
-	if len(keys) > 0 {
-		provider.inClient.Unlink(provider.ctx, keys...)
+	if !dryRun && len(keys) > 0 {
+		provider.unlink(keys)
+	}
+
+	return result, nil
+}
+
+// escapeGlob escapes the SCAN MATCH special characters.
+func escapeGlob(value string) string {
+	var escaped strings.Builder
+
+	for _, r := range value {
+		if strings.ContainsRune(`*?[]\`, r) {
+			escaped.WriteRune('\\')
+		}
+
+		escaped.WriteRune(r)
 	}
+
+	return escaped.String()
+}
+
+func (provider *Redis) unlink(keys []string) {
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	provider.inClient.Unlink(ctx, keys...)
+}
+
+// TryLock acquires the lock with SET NX PX, it fails without waiting if
+// another instance holds it.
+func (provider *Redis) TryLock(key string, ttl time.Duration) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to acquire the redis lock while reconnecting.")
+
+		return false, core.ErrReconnecting
+	}
+
+	token := core.LockToken()
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	acquired, err := provider.inClient.SetNX(ctx, core.LockKeyPrefix+key, token, ttl).Result()
+	if err != nil {
+		provider.logger.Errorf("Impossible to acquire the lock %s in Redis, %v", key, err)
+
+		return false, err
+	}
+
+	if acquired {
+		provider.locks.Store(key, token)
+	}
+
+	return acquired, nil
+}
+
+// Unlock releases the lock if this instance still holds it.
+func (provider *Redis) Unlock(key string) error {
+	token, held := provider.locks.LoadAndDelete(key)
+	if !held {
+		return nil
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	err := unlockScript.Run(ctx, provider.inClient, []string{core.LockKeyPrefix + key}, token).Err()
+	if err != nil {
+		provider.logger.Errorf("Impossible to release the lock %s in Redis, %v", key, err)
+	}
+
+	return err
+}
+
+// SetNX stores the value with SET NX only if the key doesn't exist.
+func (provider *Redis) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to set the redis value while reconnecting.")
+
+		return false, core.ErrReconnecting
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	stored, err := provider.inClient.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s if absent into Redis, %v", key, err)
+	}
+
+	return stored, err
+}
+
+// CompareAndSwap replaces the value with a Lua script only if the stored one
+// equals old.
+func (provider *Redis) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to set the redis value while reconnecting.")
+
+		return false, core.ErrReconnecting
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	swapped, err := compareAndSwapScript.Run(ctx, provider.inClient, []string{key}, old, value, ttl.Milliseconds()).Int()
+	if err != nil {
+		provider.logger.Errorf("Impossible to compare and swap the key %s into Redis, %v", key, err)
+
+		return false, err
+	}
+
+	return swapped == 1, nil
+}
+
+// RefreshTTL extends the expiration of the key in place with PEXPIRE.
+func (provider *Redis) RefreshTTL(key string, duration time.Duration) error {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to refresh the redis TTL while reconnecting.")
+
+		return core.ErrReconnecting
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	refreshed, err := provider.inClient.PExpire(ctx, key, duration).Result()
+	if err != nil {
+		provider.logger.Errorf("Impossible to refresh the TTL of the key %s into Redis, %v", key, err)
+
+		return err
+	}
+
+	if !refreshed {
+		return core.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// GetTTL returns the remaining lifetime of the key with PTTL.
+func (provider *Redis) GetTTL(key string) (time.Duration, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to get the redis TTL while reconnecting.")
+
+		return 0, core.ErrReconnecting
+	}
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	ttl, err := provider.inClient.PTTL(ctx, key).Result()
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the TTL of the key %s in Redis, %v", key, err)
+
+		return 0, err
+	}
+
+	// PTTL answers -2 for the missing keys and -1 for the persistent ones.
+	switch ttl {
+	case -2:
+		return 0, core.ErrKeyNotFound
+	case -1:
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Increment adds delta to the counter with INCRBY in a Lua script setting
+// its expiration.
+func (provider *Redis) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to increment the redis counter while reconnecting.")
+
+		return 0, core.ErrReconnecting
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	counter, err := incrementScript.Run(ctx, provider.inClient, []string{key}, delta, ttl.Milliseconds()).Int64()
+	if err != nil {
+		provider.logger.Errorf("Impossible to increment the counter %s in Redis, %v", key, err)
+	}
+
+	return counter, err
+}
+
+// Healthy sends a PING to Redis.
+func (provider *Redis) Healthy(ctx context.Context) error {
+	return provider.inClient.Ping(ctx).Err()
 }
 
 // Init method will.
@@ -419,22 +921,35 @@ func (provider *Redis) Init() error {
 
 // Reset method will reset or close provider.
 func (provider *Redis) Reset() error {
-	if provider.reconnecting {
+	if provider.reconnector.Reconnecting() {
 		provider.logger.Error("Impossible to reset the redis instance while reconnecting.")
 
 		return nil
 	}
 
+	provider.reconnector.Stop()
+
 	return provider.inClient.Close()
 }
 
+// Reconnect starts the background reconnection unless it already runs.
 func (provider *Redis) Reconnect() {
-	provider.reconnecting = true
+	provider.reconnector.Trigger()
+}
 
-	if provider.inClient = redis.NewUniversalClient(&provider.configuration); provider.inClient != nil {
-		provider.reconnecting = false
-	} else {
-		time.Sleep(10 * time.Second)
-		provider.Reconnect()
+func (provider *Redis) connect(ctx context.Context) error {
+	cli := redis.NewUniversalClient(&provider.configuration)
+	if err := cli.Ping(ctx).Err(); err != nil {
+		_ = cli.Close()
+
+		return err
 	}
+
+	previous := provider.inClient
+	provider.inClient = cli
+	provider.close = cli.Close
+
+	_ = previous.Close()
+
+	return nil
 }
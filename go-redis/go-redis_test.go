@@ -52,6 +52,24 @@ func TestRedisConnectionFactoryConfiguration(t *testing.T) {
 	}
 }
 
+func TestRedisConnectionFactoryPool(t *testing.T) {
+	instance, err := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"Addrs": []string{redisAddr},
+		"pool":  map[string]interface{}{"size": 32, "min_idle": 4, "max_retries": 2, "dial_backoff": "10ms"},
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil || instance == nil {
+		t.Errorf("The pool should be accepted, %v given", err)
+	}
+
+	_, err = redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"Addrs": []string{redisAddr},
+		"pool":  map[string]interface{}{"size": 2, "min_idle": 4},
+	}}, zap.NewNop().Sugar(), 0)
+	if err == nil {
+		t.Error("The min_idle above the pool size should be refused")
+	}
+}
+
 func TestIShouldBeAbleToReadAndWriteDataInRedis(t *testing.T) {
 	client, _ := getRedisInstance()
 
@@ -285,3 +303,157 @@ func TestRedis_SetMultiLevel_MappingTTL(t *testing.T) {
 
 	client.DeleteMany(".*")
 }
+
+func TestRedis_TryLock(t *testing.T) {
+	client, _ := getRedisInstance()
+	locker, ok := client.(core.Locker)
+	if !ok {
+		t.Fatal("Redis should implement core.Locker")
+	}
+
+	acquired, err := locker.TryLock("revalidation", 5*time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("The lock should be acquired, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); acquired {
+		t.Error("The lock should already be held")
+	}
+
+	if err = locker.Unlock("revalidation"); err != nil {
+		t.Errorf("Impossible to release the lock, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); !acquired {
+		t.Error("The lock should be acquired once released")
+	}
+
+	_ = locker.Unlock("revalidation")
+}
+
+func TestRedis_ConditionalSet(t *testing.T) {
+	client, _ := getRedisInstance()
+	setter, ok := client.(core.ConditionalSetter)
+	if !ok {
+		t.Fatal("Redis should implement core.ConditionalSetter")
+	}
+
+	client.Delete("leader")
+
+	if stored, err := setter.SetNX("leader", []byte("first"), 5*time.Second); err != nil || !stored {
+		t.Fatalf("The absent key should be stored, %v", err)
+	}
+
+	if stored, _ := setter.SetNX("leader", []byte("second"), 5*time.Second); stored {
+		t.Error("The existing key shouldn't be replaced")
+	}
+
+	if swapped, _ := setter.CompareAndSwap("leader", []byte("second"), []byte("third"), 5*time.Second); swapped {
+		t.Error("The value shouldn't be swapped when the old one differs")
+	}
+
+	if swapped, err := setter.CompareAndSwap("leader", []byte("first"), []byte("third"), 5*time.Second); err != nil || !swapped {
+		t.Errorf("The value should be swapped, %v", err)
+	}
+
+	if value := client.Get("leader"); string(value) != "third" {
+		t.Errorf("The swapped value should be stored, %s given", value)
+	}
+
+	client.Delete("leader")
+}
+
+func TestRedis_Increment(t *testing.T) {
+	client, _ := getRedisInstance()
+	counter, ok := client.(core.Counter)
+	if !ok {
+		t.Fatal("Redis should implement core.Counter")
+	}
+
+	client.Delete("hits")
+
+	if value, err := counter.Increment("hits", 2, 5*time.Second); err != nil || value != 2 {
+		t.Fatalf("The missing counter should start at zero, %d given, %v", value, err)
+	}
+
+	if value, err := counter.Increment("hits", -3, 5*time.Second); err != nil || value != -1 {
+		t.Errorf("The counter should be decremented, %d given, %v", value, err)
+	}
+
+	if value := client.Get("hits"); string(value) != "-1" {
+		t.Errorf("The counter should be stored as a decimal string, %s given", value)
+	}
+
+	client.Delete("hits")
+}
+
+func TestRedis_ListKeysPaginated(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	paginator, ok := client.(core.KeyPaginator)
+	if !ok {
+		t.Fatal("Redis should implement core.KeyPaginator")
+	}
+
+	expected := []string{"page-1", "page-2", "page-3", "page-4", "page-5"}
+	for _, key := range expected {
+		if err := client.SetMultiLevel(key, key, []byte(baseValue), http.Header{}, "", time.Minute, key); err != nil {
+			t.Fatalf("Impossible to set the key %s, %v", key, err)
+		}
+	}
+
+	listed := map[string]bool{}
+	cursor := ""
+
+	for range 1000 {
+		var keys []string
+
+		keys, cursor = paginator.ListKeysPaginated(cursor, 2)
+		for _, key := range keys {
+			listed[key] = true
+		}
+
+		if cursor == "" {
+			break
+		}
+	}
+
+	if cursor != "" {
+		t.Fatal("The pagination should end")
+	}
+
+	for _, key := range expected {
+		if !listed[key] {
+			t.Errorf("The key %s should be listed, %v given", key, listed)
+		}
+	}
+}
+
+func TestRedis_Reload(t *testing.T) {
+	client, _ := getRedisInstance()
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if err := core.Reload(client, core.CacheProvider{URL: redisAddr}); err != nil {
+		t.Fatalf("Impossible to reload the Redis provider, %v", err)
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should be kept after the reload", byteKey)
+	}
+
+	if err := core.Reload(client, core.CacheProvider{URL: "127.0.0.1:1"}); err == nil {
+		t.Error("An unreachable address shouldn't be applied")
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("The previous client should be kept when the reload fails")
+	}
+}
+
+func TestRedis_Healthy(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The reachable server should be healthy, %v given", err)
+	}
+}
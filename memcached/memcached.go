@@ -0,0 +1,484 @@
+package memcached
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/darkweak/storages/core"
+)
+
+const (
+	defaultServer   = "127.0.0.1:11211"
+	defaultIndexKey = "STORAGES_INDEX"
+
+	// maxKeyLength is the memcached key length limit.
+	maxKeyLength = 250
+	// maxRelativeExpiration is the longest expiration memcached accepts as
+	// a number of seconds, it's read as an unix timestamp above.
+	maxRelativeExpiration = 60 * 60 * 24 * 30
+	// indexMaxAttempts bounds the compare-and-swap retries of the index.
+	indexMaxAttempts = 10
+)
+
+var errIndexContention = errors.New("too many concurrent updates of the index")
+
+// Memcached provider type.
+type Memcached struct {
+	*memcache.Client
+
+	servers    []string
+	indexKey   string
+	stale      time.Duration
+	uuid       string
+	logger     core.Logger
+	compressor core.Compressor
+}
+
+// configuration is the typed Memcached provider configuration.
+type configuration struct {
+	Servers      []string      `json:"servers"`
+	IndexKey     string        `json:"index_key"`
+	Timeout      time.Duration `json:"timeout"`
+	MaxIdleConns int           `json:"max_idle_conns"`
+}
+
+func parseConfiguration(memcachedConfiguration core.CacheProvider) (configuration, error) {
+	cfg := configuration{IndexKey: defaultIndexKey}
+
+	if memcachedConfiguration.URL != "" {
+		cfg.Servers = strings.Split(memcachedConfiguration.URL, ",")
+	}
+
+	if err := core.DecodeConfiguration(memcachedConfiguration.Configuration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid memcached configuration: %w", err)
+	}
+
+	if cfg.IndexKey == "" {
+		return cfg, errors.New("invalid memcached configuration: the index_key can't be empty")
+	}
+
+	if cfg.Timeout < 0 || cfg.MaxIdleConns < 0 {
+		return cfg, errors.New("invalid memcached configuration: the timeout and max_idle_conns can't be negative")
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(memcachedConfiguration any) error {
+	_, err := parseConfiguration(core.CacheProvider{Configuration: memcachedConfiguration})
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("memcached", Factory)
+}
+
+// Factory function create new Memcached instance.
+func Factory(memcachedConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(memcachedConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := cfg.Servers
+	indexKey := cfg.IndexKey
+
+	if len(servers) == 0 {
+		servers = []string{defaultServer}
+	}
+
+	compressor, err := core.CompressorFromConfiguration(memcachedConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(memcachedConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := newRing(servers...)
+	if err != nil {
+		logger.Errorf("Impossible to resolve the Memcached servers %v, %v", servers, err)
+
+		return nil, err
+	}
+
+	client := memcache.NewFromSelector(selector)
+	client.Timeout = cfg.Timeout
+	client.MaxIdleConns = cfg.MaxIdleConns
+
+	if tlsConfig != nil {
+		client.DialContext = (&tls.Dialer{Config: tlsConfig}).DialContext
+	}
+
+	return &Memcached{
+		Client:     client,
+		servers:    servers,
+		indexKey:   indexKey,
+		stale:      stale,
+		uuid:       core.InstanceID("memcached", memcachedConfiguration, stale),
+		logger:     logger,
+		compressor: compressor,
+	}, nil
+}
+
+// storageKey returns the key as stored in memcached, the keys too long or
+// containing whitespaces or control characters are hashed.
+func storageKey(key string) string {
+	if len(key) <= maxKeyLength && !strings.ContainsFunc(key, func(r rune) bool {
+		return r <= ' ' || r == 0x7f
+	}) {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return "sha256-" + hex.EncodeToString(sum[:])
+}
+
+// expiration converts the duration to the memcached expiration, zero means
+// no expiration.
+func expiration(duration time.Duration) int32 {
+	if duration <= 0 {
+		return 0
+	}
+
+	seconds := int64(math.Ceil(duration.Seconds()))
+	if seconds > maxRelativeExpiration {
+		return int32(time.Now().Add(duration).Unix())
+	}
+
+	return int32(seconds)
+}
+
+// updateIndex applies the update to the sidecar index key holding the
+// stored keys and their expiration, memcached can't list its keys. The
+// write relies on compare-and-swap so concurrent instances don't override
+// each other, the expired keys are pruned on each write.
+func (provider *Memcached) updateIndex(update func(index map[string]int64)) error {
+	for range indexMaxAttempts {
+		index := map[string]int64{}
+
+		item, err := provider.Client.Get(provider.indexKey)
+		if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+			return err
+		}
+
+		if item != nil {
+			_ = json.Unmarshal(item.Value, &index)
+		}
+
+		update(index)
+
+		now := time.Now().Unix()
+		for key, invalidAt := range index {
+			if invalidAt > 0 && invalidAt <= now {
+				delete(index, key)
+			}
+		}
+
+		value, err := json.Marshal(index)
+		if err != nil {
+			return err
+		}
+
+		if item == nil {
+			err = provider.Client.Add(&memcache.Item{Key: provider.indexKey, Value: value})
+		} else {
+			item.Value = value
+			err = provider.Client.CompareAndSwap(item)
+		}
+
+		if errors.Is(err, memcache.ErrNotStored) || errors.Is(err, memcache.ErrCASConflict) || errors.Is(err, memcache.ErrCacheMiss) {
+			continue
+		}
+
+		return err
+	}
+
+	return errIndexContention
+}
+
+// indexedKeys returns the keys stored in the sidecar index.
+func (provider *Memcached) indexedKeys() []string {
+	index := map[string]int64{}
+
+	item, err := provider.Client.Get(provider.indexKey)
+	if err != nil {
+		if !errors.Is(err, memcache.ErrCacheMiss) {
+			provider.logger.Errorf("Impossible to get the Memcached index, %v", err)
+		}
+
+		return []string{}
+	}
+
+	_ = json.Unmarshal(item.Value, &index)
+
+	now := time.Now().Unix()
+	keys := make([]string, 0, len(index))
+
+	for key, invalidAt := range index {
+		if invalidAt == 0 || invalidAt > now {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Name returns the storer name.
+func (provider *Memcached) Name() string {
+	return "MEMCACHED"
+}
+
+// Uuid returns an unique identifier.
+func (provider *Memcached) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *Memcached) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+	storageKeys := map[string]string{}
+
+	for _, key := range provider.indexedKeys() {
+		if strings.HasPrefix(key, prefix) {
+			storageKeys[storageKey(key)] = key
+		}
+	}
+
+	if len(storageKeys) == 0 {
+		return keys
+	}
+
+	toFetch := make([]string, 0, len(storageKeys))
+	for key := range storageKeys {
+		toFetch = append(toFetch, key)
+	}
+
+	items, err := provider.Client.GetMulti(toFetch)
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the Memcached keys, %v", err)
+
+		return keys
+	}
+
+	for key, item := range items {
+		nk, _ := strings.CutPrefix(storageKeys[key], prefix)
+		keys[nk] = string(item.Value)
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Memcached) ListKeys() []string {
+	keys := []string{}
+
+	for _, value := range provider.MapKeys(core.MappingKeyPrefix) {
+		mapping, err := core.DecodeMapping([]byte(value))
+		if err != nil {
+			continue
+		}
+
+		for _, v := range mapping.GetMapping() {
+			if v.GetFreshTime().AsTime().Before(time.Now()) && v.GetStaleTime().AsTime().Before(time.Now()) {
+				continue
+			}
+
+			keys = append(keys, v.GetRealKey())
+		}
+	}
+
+	return keys
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Memcached) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist.
+func (provider *Memcached) Lookup(key string) ([]byte, error) {
+	item, err := provider.Client.Get(storageKey(key))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s in Memcached, %v", key, err)
+
+		return nil, err
+	}
+
+	return item.Value, nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Memcached) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	result := provider.Get(core.MappingKeyPrefix + key)
+	if len(result) == 0 {
+		return fresh, stale
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, result, req, validator, provider.logger)
+
+	return fresh, stale
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *Memcached) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into Memcached, %v", variedKey, err)
+
+		return err
+	}
+
+	if err = provider.Set(variedKey, compressed, duration+provider.stale); err != nil {
+		return err
+	}
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, provider.Get(mappingKey), provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	if err = provider.Set(mappingKey, val, duration+provider.stale); err != nil {
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in Memcached provider.
+func (provider *Memcached) Set(key string, value []byte, duration time.Duration) error {
+	err := provider.Client.Set(&memcache.Item{
+		Key:        storageKey(key),
+		Value:      value,
+		Expiration: expiration(duration),
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to set value into Memcached, %v", err)
+
+		return err
+	}
+
+	var invalidAt int64
+	if duration > 0 {
+		invalidAt = time.Now().Add(duration).Unix()
+	}
+
+	if err = provider.updateIndex(func(index map[string]int64) {
+		index[key] = invalidAt
+	}); err != nil {
+		provider.logger.Errorf("Impossible to index the key %s into Memcached, %v", key, err)
+	}
+
+	return nil
+}
+
+// Touch method makes the key expire after ttl with the memcached touch
+// command, its expiration is updated in the index too.
+func (provider *Memcached) Touch(key string, ttl time.Duration) error {
+	err := provider.Client.Touch(storageKey(key), expiration(ttl))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to touch the key %s in Memcached, %v", key, err)
+
+		return err
+	}
+
+	var invalidAt int64
+	if ttl > 0 {
+		invalidAt = time.Now().Add(ttl).Unix()
+	}
+
+	if err = provider.updateIndex(func(index map[string]int64) {
+		index[key] = invalidAt
+	}); err != nil {
+		provider.logger.Errorf("Impossible to index the key %s into Memcached, %v", key, err)
+	}
+
+	return nil
+}
+
+// Delete method will delete the response in Memcached provider if exists corresponding to key param.
+func (provider *Memcached) Delete(key string) {
+	if err := provider.Client.Delete(storageKey(key)); err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		provider.logger.Errorf("Impossible to delete the key %s in Memcached, %v", key, err)
+	}
+
+	_ = provider.updateIndex(func(index map[string]int64) {
+		delete(index, key)
+	})
+}
+
+// DeleteMany method will delete the responses in Memcached provider if exists corresponding to the regex key param.
+func (provider *Memcached) DeleteMany(key string) {
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	deleted := []string{}
+
+	for _, k := range provider.indexedKeys() {
+		if rgKey.MatchString(k) {
+			_ = provider.Client.Delete(storageKey(k))
+			deleted = append(deleted, k)
+		}
+	}
+
+	if len(deleted) == 0 {
+		return
+	}
+
+	_ = provider.updateIndex(func(index map[string]int64) {
+		for _, k := range deleted {
+			delete(index, k)
+		}
+	})
+}
+
+// Healthy checks the servers are reachable.
+func (provider *Memcached) Healthy(_ context.Context) error {
+	return provider.Client.Ping()
+}
+
+// Init method will check the servers are reachable.
+func (provider *Memcached) Init() error {
+	return provider.Client.Ping()
+}
+
+// Reset method will reset or close provider.
+func (provider *Memcached) Reset() error {
+	return provider.Client.Close()
+}
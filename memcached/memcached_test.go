@@ -0,0 +1,139 @@
+package memcached_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/memcached"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getMemcachedInstance() (core.Storer, error) {
+	return memcached.Factory(core.CacheProvider{URL: "127.0.0.1:11211"}, zap.NewNop().Sugar(), 0)
+}
+
+func TestMemcachedConnectionFactory(t *testing.T) {
+	instance, err := getMemcachedInstance()
+
+	if nil != err {
+		t.Error("Shouldn't have panic", err)
+	}
+
+	if nil == instance {
+		t.Error("Memcached should be instanciated")
+	}
+}
+
+func TestIShouldBeAbleToReadAndWriteDataInMemcached(t *testing.T) {
+	client, _ := getMemcachedInstance()
+
+	_ = client.Set("Test", []byte(baseValue), time.Duration(20)*time.Second)
+
+	res := client.Get("Test")
+	if len(res) == 0 {
+		t.Errorf("Key %s should exist", baseValue)
+	}
+
+	if baseValue != string(res) {
+		t.Errorf("%s not corresponding to %s", string(res), baseValue)
+	}
+}
+
+func TestMemcached_GetRequestInCache(t *testing.T) {
+	client, _ := getMemcachedInstance()
+	res := client.Get(nonExistentKey)
+
+	if 0 < len(res) {
+		t.Errorf("Key %s should not exist", nonExistentKey)
+	}
+}
+
+func TestMemcached_GetSetRequestInCache_OneByte(t *testing.T) {
+	client, _ := getMemcachedInstance()
+	_ = client.Set(byteKey, []byte("A"), time.Duration(20)*time.Second)
+
+	res := client.Get(byteKey)
+	if len(res) == 0 {
+		t.Errorf("Key %s should exist", byteKey)
+	}
+
+	if string(res) != "A" {
+		t.Errorf("%s not corresponding to %v", res, 65)
+	}
+}
+
+func TestMemcached_SetRequestInCache_LongKey(t *testing.T) {
+	key := "GET-http-example.com-/" + strings.Repeat("a", 300) + " with spaces"
+	client, _ := getMemcachedInstance()
+	_ = client.Set(key, []byte(baseValue), time.Duration(20)*time.Second)
+
+	if string(client.Get(key)) != baseValue {
+		t.Errorf("Key %s should be equals to %s", key, baseValue)
+	}
+
+	if _, ok := client.MapKeys("GET-http-example.com-/")[strings.TrimPrefix(key, "GET-http-example.com-/")]; !ok {
+		t.Errorf("Key %s should be indexed", key)
+	}
+}
+
+func TestMemcached_DeleteRequestInCache(t *testing.T) {
+	client, _ := getMemcachedInstance()
+	client.Delete(byteKey)
+
+	if 0 < len(client.Get(byteKey)) {
+		t.Errorf("Key %s should not exist", byteKey)
+	}
+}
+
+func TestMemcached_SetMultiLevel(t *testing.T) {
+	client, _ := getMemcachedInstance()
+	baseKey := "MultiLevelBaseKey"
+	variedKey := "MultiLevelBaseKey-varied"
+
+	err := client.SetMultiLevel(baseKey, variedKey, []byte(baseValue), http.Header{}, "", 20*time.Second, baseKey)
+	if err != nil {
+		t.Fatalf("Impossible to set the multi level key: %v", err)
+	}
+
+	if len(client.Get(variedKey)) == 0 {
+		t.Errorf("Key %s should exist", variedKey)
+	}
+
+	if !slices.Contains(client.ListKeys(), baseKey) {
+		t.Errorf("Key %s should be listed", baseKey)
+	}
+
+	client.DeleteMany("MultiLevel.*")
+
+	if 0 < len(client.Get(variedKey)) || slices.Contains(client.ListKeys(), baseKey) {
+		t.Errorf("Key %s should have been deleted", baseKey)
+	}
+}
+
+func TestMemcached_Init(t *testing.T) {
+	client, _ := getMemcachedInstance()
+	err := client.Init()
+
+	if nil != err {
+		t.Error("Impossible to init Memcached provider")
+	}
+}
+
+func TestMemcached_Healthy(t *testing.T) {
+	client, _ := getMemcachedInstance()
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The reachable servers should be healthy, %v given", err)
+	}
+}
@@ -0,0 +1,84 @@
+package memcached
+
+import (
+	"hash/crc32"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// virtualNodes is the number of points each server owns on the ring, it
+// smooths the keys distribution between the servers.
+const virtualNodes = 160
+
+// ring is a consistent hash memcache.ServerSelector: adding or removing a
+// server only moves the keys it owns instead of reshuffling the whole
+// keyspace like the default modulo based selector.
+type ring struct {
+	points  []uint32
+	owners  map[uint32]net.Addr
+	servers []net.Addr
+}
+
+func newRing(servers ...string) (*ring, error) {
+	r := &ring{owners: map[uint32]net.Addr{}}
+
+	for _, server := range servers {
+		var (
+			addr net.Addr
+			err  error
+		)
+
+		if strings.Contains(server, "/") {
+			addr, err = net.ResolveUnixAddr("unix", server)
+		} else {
+			addr, err = net.ResolveTCPAddr("tcp", server)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		r.servers = append(r.servers, addr)
+
+		for i := range virtualNodes {
+			point := crc32.ChecksumIEEE([]byte(server + "-" + strconv.Itoa(i)))
+			if _, exists := r.owners[point]; !exists {
+				r.owners[point] = addr
+				r.points = append(r.points, point)
+			}
+		}
+	}
+
+	slices.Sort(r.points)
+
+	return r, nil
+}
+
+// PickServer returns the server owning the first point after the key hash.
+func (r *ring) PickServer(key string) (net.Addr, error) {
+	if len(r.points) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+
+	idx, _ := slices.BinarySearch(r.points, crc32.ChecksumIEEE([]byte(key)))
+	if idx == len(r.points) {
+		idx = 0
+	}
+
+	return r.owners[r.points[idx]], nil
+}
+
+// Each iterates over each server.
+func (r *ring) Each(f func(net.Addr) error) error {
+	for _, server := range r.servers {
+		if err := f(server); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
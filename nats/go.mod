@@ -1,6 +1,6 @@
 module github.com/darkweak/storages/nats
 
-go 1.22.1
+go 1.23
 
 replace github.com/darkweak/storages/core => ../core
 
@@ -8,14 +8,18 @@ require (
 	dario.cat/mergo v1.0.0
 	github.com/darkweak/storages/core v0.0.19
 	github.com/nats-io/nats.go v1.39.1
-	github.com/pierrec/lz4/v4 v4.1.23
 	go.uber.org/zap v1.27.0
 )
 
 require (
-	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-viper/mapstructure/v2 v2.2.1 // indirect
+	github.com/klauspost/compress v1.18.4 // indirect
 	github.com/nats-io/nkeys v0.4.9 // indirect
 	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/pierrec/lz4/v4 v4.1.23 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
 	golang.org/x/crypto v0.31.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,43 @@
+package nats
+
+import (
+	"github.com/darkweak/storages/core"
+	nats "github.com/nats-io/nats.go"
+)
+
+func invalidationSubject(channel string) string {
+	return core.InvalidationKeyPrefix + channel
+}
+
+// PublishInvalidation publishes the event on the channel subject.
+func (provider *Nats) PublishInvalidation(channel string, event core.InvalidationEvent) error {
+	payload, err := event.Encode()
+	if err != nil {
+		return err
+	}
+
+	return provider.conn.Publish(invalidationSubject(channel), payload)
+}
+
+// SubscribeInvalidations subscribes to the channel subject and calls fn with
+// every event published on it. The Nats client subscribes again by itself
+// once reconnected to the server.
+func (provider *Nats) SubscribeInvalidations(channel string, fn func(core.InvalidationEvent)) (func(), error) {
+	subscription, err := provider.conn.Subscribe(invalidationSubject(channel), func(message *nats.Msg) {
+		event, err := core.DecodeInvalidationEvent(message.Data)
+		if err != nil {
+			provider.logger.Errorf("Impossible to decode the Nats invalidation, %v", err)
+
+			return
+		}
+
+		fn(event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = subscription.Unsubscribe()
+	}, nil
+}
@@ -2,34 +2,85 @@ package nats
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"dario.cat/mergo"
 	"github.com/darkweak/storages/core"
 	nats "github.com/nats-io/nats.go"
-	lz4 "github.com/pierrec/lz4/v4"
 )
 
 // Nats provider type.
 type Nats struct {
 	// keyvalue     jetstream.KeyValue
-	jsCtx  nats.JetStreamContext
-	bucket string
-	stale  time.Duration
-	logger core.Logger
+	conn        *nats.Conn
+	jsCtx       nats.JetStreamContext
+	bucket      string
+	maxAge      time.Duration
+	stale       time.Duration
+	uuid        string
+	logger      core.Logger
+	compressor  core.Compressor
+	options     nats.Options
+	reconnector *core.Reconnector
+	streamer    *core.ChunkedStreamer
+	timeouts    core.Timeouts
+	// reads and writes are the JetStream contexts waiting at most the read
+	// and write timeouts for the server responses.
+	reads  nats.JetStreamContext
+	writes nats.JetStreamContext
+
+	// keys is the local index of the bucket keys, kept up to date by the
+	// watcher so the keys deleted or purged by the other instances are
+	// invalidated without listing the whole bucket.
+	keys      sync.Map
+	watcherMu sync.Mutex
+	watcher   nats.KeyWatcher
 }
 
+// item is the envelope stored in the bucket. The bucket MaxAge is shared by
+// all the keys, InvalidAt carries the per-key expiration.
 type item struct {
-	invalidAt time.Time
-	value     []byte
+	InvalidAt time.Time
+	Value     []byte
+}
+
+func encodeItem(value []byte, duration time.Duration) ([]byte, error) {
+	property := item{Value: value}
+	if duration > 0 {
+		property.InvalidAt = time.Now().Add(duration)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(property); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeItem(value []byte) (item, bool) {
+	var res item
+
+	if err := gob.NewDecoder(bytes.NewBuffer(value)).Decode(&res); err != nil {
+		return item{Value: value}, false
+	}
+
+	return res, true
+}
+
+func (res item) expired() bool {
+	return !res.InvalidAt.IsZero() && !res.InvalidAt.After(time.Now())
 }
 
 func sanitizeProperties(configMap map[string]interface{}) map[string]interface{} {
@@ -107,27 +158,114 @@ func sanitizeProperties(configMap map[string]interface{}) map[string]interface{}
 	return configMap
 }
 
+// configuration is the typed Nats provider configuration, the other keys
+// are the nats.Options fields.
+type configuration struct {
+	KeyValue string                 `json:"keyvalue"`
+	MaxAge   time.Duration          `json:"max_age"`
+	Options  map[string]interface{} `json:",remain"`
+}
+
+func parseConfiguration(natsConfiguration any) (configuration, nats.Options, error) {
+	cfg := configuration{KeyValue: "souin-bucket"}
+
+	var parsedNats nats.Options
+
+	if err := core.DecodeConfiguration(natsConfiguration, &cfg); err != nil {
+		return cfg, parsedNats, fmt.Errorf("invalid nats configuration: %w", err)
+	}
+
+	if cfg.KeyValue == "" {
+		return cfg, parsedNats, errors.New("invalid nats configuration: the keyvalue bucket name can't be empty")
+	}
+
+	if cfg.MaxAge < 0 {
+		return cfg, parsedNats, fmt.Errorf("invalid nats configuration: the max_age can't be negative, %s given", cfg.MaxAge)
+	}
+
+	b, err := json.Marshal(sanitizeProperties(cfg.Options))
+	if err == nil {
+		err = json.Unmarshal(b, &parsedNats)
+	}
+
+	if err != nil {
+		return cfg, parsedNats, fmt.Errorf("invalid nats configuration: %w", err)
+	}
+
+	return cfg, parsedNats, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(natsConfiguration any) error {
+	if _, _, err := parseConfiguration(natsConfiguration); err != nil {
+		return err
+	}
+
+	_, err := core.PoolFromConfiguration(natsConfiguration)
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("nats", Factory)
+}
+
+// applyPool applies the shared pool settings to the connection options, the
+// retries are the reconnections. The client holds a single connection, the
+// size and min_idle don't apply.
+func applyPool(natsOptions *nats.Options, pool core.Pool) {
+	switch {
+	case pool.MaxRetries < 0:
+		natsOptions.AllowReconnect = false
+	case pool.MaxRetries > 0:
+		natsOptions.MaxReconnect = pool.MaxRetries
+	}
+
+	if pool.DialBackoff > 0 {
+		natsOptions.ReconnectWait = pool.DialBackoff
+	}
+
+	if pool.MaxDialBackoff > 0 {
+		wait, maxWait := natsOptions.ReconnectWait, pool.MaxDialBackoff
+		natsOptions.CustomReconnectDelayCB = func(attempts int) time.Duration {
+			delay := wait << min(max(attempts-1, 0), 30)
+			if delay <= 0 || delay > maxWait {
+				return maxWait
+			}
+
+			return delay
+		}
+	}
+}
+
 // Factory function create new Nats instance.
 func Factory(natsConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
 	natsOptions := nats.GetDefaultOptions()
-	bucketName := "souin-bucket"
 
-	if natsConfiguration.Configuration != nil {
-		var parsedNats nats.Options
+	cfg, parsedNats, err := parseConfiguration(natsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
 
-		if bucket, ok := natsConfiguration.Configuration.(map[string]interface{})["keyvalue"]; ok {
-			bucketName, _ = bucket.(string)
-		}
+	compressor, err := core.CompressorFromConfiguration(natsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
 
-		natsConfiguration.Configuration = sanitizeProperties(natsConfiguration.Configuration.(map[string]interface{}))
-		if b, e := json.Marshal(natsConfiguration.Configuration); e == nil {
-			if e = json.Unmarshal(b, &parsedNats); e != nil {
-				logger.Error("Impossible to parse the configuration for the Nuts provider", e)
-			}
-		}
+	tlsConfig, err := core.TLSConfigFromConfiguration(natsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
 
+	timeouts, err := core.TimeoutsFromConfiguration(natsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	if natsConfiguration.Configuration != nil {
 		if err := mergo.Merge(&natsOptions, parsedNats, mergo.WithOverride); err != nil {
-			logger.Error("An error occurred during the nutsOptions merge from the default options with your configuration.")
+			logger.Error("An error occurred during the natsOptions merge from the default options with your configuration.")
 		}
 	} else {
 		natsOptions.Servers = strings.Split(natsConfiguration.URL, ",")
@@ -137,30 +275,196 @@ func Factory(natsConfiguration core.CacheProvider, logger core.Logger, stale tim
 		natsOptions.Servers = []string{nats.DefaultURL}
 	}
 
-	natsConn, err := natsOptions.Connect()
+	if tlsConfig != nil {
+		natsOptions.Secure = true
+		natsOptions.TLSConfig = tlsConfig
+	}
+
+	pool, err := core.PoolFromConfiguration(natsConfiguration.Configuration)
 	if err != nil {
-		logger.Error("Impossible to connect to the Nats DB.", err)
+		return nil, err
+	}
 
+	applyPool(&natsOptions, pool)
+
+	instance := &Nats{bucket: cfg.KeyValue, maxAge: cfg.MaxAge, logger: logger, stale: stale, uuid: core.InstanceID("nats", natsConfiguration, stale), compressor: compressor, options: natsOptions, timeouts: timeouts}
+	if err = instance.connect(context.Background()); err != nil {
 		return nil, err
 	}
 
+	instance.reconnector = core.NewReconnector(natsConfiguration.Configuration, logger, instance.connect)
+	instance.streamer = core.NewChunkedStreamer(instance, core.ChunkSizeFromConfiguration(natsConfiguration.Configuration))
+
+	return instance, nil
+}
+
+func (provider *Nats) connect(_ context.Context) error {
+	natsConn, err := provider.options.Connect()
+	if err != nil {
+		provider.logger.Error("Impossible to connect to the Nats DB.", err)
+
+		return err
+	}
+
 	stream, err := natsConn.JetStream()
 	if err != nil {
-		logger.Error("Impossible to instantiate the Nats DB.", err)
+		provider.logger.Error("Impossible to instantiate the Nats DB.", err)
 
-		return nil, err
+		return err
 	}
 
 	_, err = stream.CreateKeyValue(&nats.KeyValueConfig{
-		Bucket: bucketName,
+		Bucket: provider.bucket,
+		TTL:    provider.maxAge,
 	})
+	if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		provider.logger.Warnf("The Nats bucket %s already exists with another configuration, keep it as is.", provider.bucket)
+
+		_, err = stream.KeyValue(provider.bucket)
+	}
+
 	if err != nil {
-		logger.Error("Impossible to create the Nats bucket %s.", err, bucketName)
+		provider.logger.Errorf("Impossible to create the Nats bucket %s, %v", provider.bucket, err)
 
-		return nil, err
+		return err
+	}
+
+	provider.conn = natsConn
+	provider.jsCtx = stream
+	provider.reads, provider.writes = stream, stream
+
+	if provider.timeouts.Read > 0 {
+		if provider.reads, err = natsConn.JetStream(nats.MaxWait(provider.timeouts.Read)); err != nil {
+			return err
+		}
+	}
+
+	if provider.timeouts.Write > 0 {
+		if provider.writes, err = natsConn.JetStream(nats.MaxWait(provider.timeouts.Write)); err != nil {
+			return err
+		}
+	}
+
+	provider.watcherMu.Lock()
+	restartWatcher := provider.watcher != nil
+	provider.watcherMu.Unlock()
+
+	if restartWatcher {
+		return provider.watch()
 	}
 
-	return &Nats{jsCtx: stream, bucket: bucketName, logger: logger, stale: stale}, nil
+	return nil
+}
+
+// watch starts watching the bucket to maintain the local keys index. The
+// previous watcher is stopped, that's required after a reconnection.
+func (provider *Nats) watch() error {
+	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := keyvalue.WatchAll(nats.MetaOnly())
+	if err != nil {
+		provider.logger.Errorf("Impossible to watch the Nats bucket %s, %v", provider.bucket, err)
+
+		return err
+	}
+
+	provider.watcherMu.Lock()
+	if provider.watcher != nil {
+		_ = provider.watcher.Stop()
+	}
+
+	provider.watcher = watcher
+	provider.watcherMu.Unlock()
+
+	go func() {
+		for entry := range watcher.Updates() {
+			// A nil entry marks the end of the initial values.
+			if entry == nil {
+				continue
+			}
+
+			switch entry.Operation() {
+			case nats.KeyValuePut:
+				provider.keys.Store(entry.Key(), struct{}{})
+			case nats.KeyValueDelete, nats.KeyValuePurge:
+				provider.keys.Delete(entry.Key())
+			}
+		}
+	}()
+
+	return nil
+}
+
+// listKeys returns the indexed keys while the bucket is watched, it lists
+// the whole bucket otherwise.
+func (provider *Nats) listKeys(keyvalue nats.KeyValue) []string {
+	provider.watcherMu.Lock()
+	watching := provider.watcher != nil
+	provider.watcherMu.Unlock()
+
+	if !watching {
+		keys, _ := keyvalue.Keys()
+
+		return keys
+	}
+
+	keys := []string{}
+
+	provider.keys.Range(func(key, _ any) bool {
+		keys = append(keys, key.(string))
+
+		return true
+	})
+
+	return keys
+}
+
+// load returns the stored envelope, the expired entries are deleted. It
+// returns core.ErrKeyNotFound when the key doesn't exist or is expired.
+func (provider *Nats) load(keyvalue nats.KeyValue, key string) (item, error) {
+	value, err := keyvalue.Get(key)
+	if err != nil {
+		if !errors.Is(err, nats.ErrKeyNotFound) {
+			provider.logger.Errorf("Impossible to get the key %s in Nats: %v", key, err)
+
+			return item{}, err
+		}
+
+		// The bucket MaxAge drops the values without delete marker.
+		provider.keys.Delete(key)
+
+		return item{}, core.ErrKeyNotFound
+	}
+
+	res, _ := decodeItem(value.Value())
+	if res.expired() {
+		_ = keyvalue.Delete(key)
+
+		return item{}, core.ErrKeyNotFound
+	}
+
+	return res, nil
+}
+
+// keyValue returns the bucket bound to the given JetStream context, the
+// reconnection starts once the client gave up reconnecting by itself and
+// closed the connection.
+func (provider *Nats) keyValue(js nats.JetStreamContext) (nats.KeyValue, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to reach the nats bucket while reconnecting.")
+
+		return nil, core.ErrReconnecting
+	}
+
+	keyvalue, err := js.KeyValue(provider.bucket)
+	if errors.Is(err, nats.ErrConnectionClosed) {
+		provider.Reconnect()
+	}
+
+	return keyvalue, err
 }
 
 // Name returns the storer name.
@@ -170,27 +474,23 @@ func (provider *Nats) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Nats) Uuid() string {
-	return fmt.Sprintf("%s-%s", provider.bucket, provider.stale)
+	return provider.uuid
 }
 
 // MapKeys method returns a map with the key and value.
 func (provider *Nats) MapKeys(prefix string) map[string]string {
 	keys := map[string]string{}
 
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
-	if err != nil {
-		return keys
-	}
-
-	keysList, err := keyvalue.Keys()
+	keyvalue, err := provider.keyValue(provider.reads)
 	if err != nil {
 		return keys
 	}
 
-	for _, key := range keysList {
+	for _, key := range provider.listKeys(keyvalue) {
 		if strings.HasPrefix(key, prefix) {
-			val, _ := keyvalue.Get(key)
-			keys[strings.TrimPrefix(key, prefix)] = string(val.Value())
+			if res, err := provider.load(keyvalue, key); err == nil {
+				keys[strings.TrimPrefix(key, prefix)] = string(res.Value)
+			}
 		}
 	}
 
@@ -199,63 +499,52 @@ func (provider *Nats) MapKeys(prefix string) map[string]string {
 
 // ListKeys method returns the list of existing keys.
 func (provider *Nats) ListKeys() []string {
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
+	keyvalue, err := provider.keyValue(provider.reads)
 	if err != nil {
 		return []string{}
 	}
 
-	keys, _ := keyvalue.Keys()
-
-	return keys
+	return provider.listKeys(keyvalue)
 }
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Nats) Get(key string) []byte {
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
-	if err != nil {
-		return nil
-	}
+	value, _ := provider.Lookup(key)
 
-	value, err := keyvalue.Get(key)
-	if err != nil && !errors.Is(err, nats.ErrKeyNotFound) {
-		provider.logger.Errorf("Impossible to get the key %s in Nats: %v", key, err)
-
-		return nil
-	} else if err != nil {
-		return nil
-	}
-
-	var res item
+	return value
+}
 
-	err = gob.NewDecoder(bytes.NewBuffer(value.Value())).Decode(&res)
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Nats) Lookup(key string) ([]byte, error) {
+	keyvalue, err := provider.keyValue(provider.reads)
 	if err != nil {
-		return value.Value()
+		return nil, err
 	}
 
-	if res.invalidAt.After(time.Now()) {
-		return res.value
+	res, err := provider.load(keyvalue, key)
+	if err != nil {
+		return nil, err
 	}
 
-	_ = keyvalue.Delete(key)
-
-	return value.Value()
+	return res.Value, nil
 }
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Nats) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
+	keyvalue, err := provider.keyValue(provider.reads)
 	if err != nil {
 		return
 	}
 
-	value, err := keyvalue.Get(core.MappingKeyPrefix + key)
+	mapping, err := provider.load(keyvalue, core.MappingKeyPrefix+key)
 	if err != nil {
 		provider.logger.Debugf("Impossible to get the mapping key %s in Nats", core.MappingKeyPrefix+key)
 
 		return
 	}
 
-	fresh, stale, _ = core.MappingElection(provider, value.Value(), req, validator, provider.logger)
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, mapping.Value, req, validator, provider.logger)
 
 	return
 }
@@ -264,70 +553,64 @@ func (provider *Nats) GetMultiLevel(key string, req *http.Request, validator *co
 func (provider *Nats) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
 		provider.logger.Errorf("Impossible to compress the key %s into Nats: %v", variedKey, err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Nats, %v", variedKey, err)
-
+	if err = provider.Set(variedKey, compressed, duration+provider.stale); err != nil {
 		return err
 	}
 
-	property := item{
-		invalidAt: now.Add(duration + provider.stale),
-		value:     compressed.Bytes(),
+	keyvalue, err := provider.keyValue(provider.reads)
+	if err != nil {
+		return err
 	}
 
-	buf := new(bytes.Buffer)
+	mappingKey := core.MappingKeyPrefix + baseKey
+	mapping, _ := provider.load(keyvalue, mappingKey)
 
-	err := gob.NewEncoder(buf).Encode(property)
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, mapping.Value, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
 	if err != nil {
-		provider.logger.Errorf("Impossible to encode the key %s in Nats: %v", variedKey, err)
-
-		return nil
-	}
+		provider.logger.Errorf("Impossible to update the mapping key %s in Nats: %v", mappingKey, err)
 
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
-	if err != nil {
 		return err
 	}
 
-	_, err = keyvalue.Put(variedKey, buf.Bytes())
-	if err != nil {
-		provider.logger.Errorf("Impossible to set value into Nats for the key %s, %v", variedKey, err)
+	// The mapping key is rewritten on each update so it refreshes the bucket
+	// MaxAge, its own expiration never shortens the one of a longer-lived
+	// varied key it references.
+	mappingTTL := duration + provider.stale
+	if remaining := time.Until(mapping.InvalidAt); remaining > mappingTTL {
+		mappingTTL = remaining
+	}
 
+	if err = provider.Set(mappingKey, val, mappingTTL); err != nil {
 		return err
 	}
 
-	mappingKey := core.MappingKeyPrefix + baseKey
-	r := provider.Get(mappingKey)
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
 
-	val, err := core.MappingUpdater(variedKey, r, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+// Set method will store the response in Nats provider.
+func (provider *Nats) Set(key string, value []byte, duration time.Duration) error {
+	encoded, err := encodeItem(value, duration)
 	if err != nil {
-		provider.logger.Errorf("Impossible to update the mapping key %s in Nats: %v", mappingKey, err)
+		provider.logger.Errorf("Impossible to encode the key %s in Nats: %v", key, err)
 
 		return err
 	}
 
-	return provider.Set(mappingKey, val, duration+provider.stale)
-}
-
-// Set method will store the response in Nats provider.
-func (provider *Nats) Set(key string, value []byte, _ time.Duration) error {
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
+	keyvalue, err := provider.keyValue(provider.writes)
 	if err != nil {
 		return err
 	}
 
-	_, err = keyvalue.Put(key, value)
+	_, err = keyvalue.Put(key, encoded)
 	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Nats, %v", err)
 	}
@@ -335,11 +618,21 @@ func (provider *Nats) Set(key string, value []byte, _ time.Duration) error {
 	return err
 }
 
+// SetReader method will store the value read from r in chunks.
+func (provider *Nats) SetReader(key string, r io.Reader, ttl time.Duration) error {
+	return provider.streamer.SetReader(key, r, ttl)
+}
+
+// GetReader method returns a reader loading the chunks on demand.
+func (provider *Nats) GetReader(key string) (io.ReadCloser, error) {
+	return provider.streamer.GetReader(key)
+}
+
 // Delete method will delete the response in Nats provider if exists corresponding to key param.
 func (provider *Nats) Delete(key string) {
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
+	keyvalue, err := provider.keyValue(provider.writes)
 	if err != nil {
-		provider.logger.Errorf("Impossible to delete the key %s in Nats %s, %v", key, err)
+		provider.logger.Errorf("Impossible to delete the key %s in Nats, %v", key, err)
 
 		return
 	}
@@ -354,29 +647,47 @@ func (provider *Nats) DeleteMany(key string) {
 		return
 	}
 
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
-	if err != nil {
-		return
-	}
-
-	keys, err := keyvalue.Keys()
+	keyvalue, err := provider.keyValue(provider.writes)
 	if err != nil {
 		return
 	}
 
-	for _, key := range keys {
+	for _, key := range provider.listKeys(keyvalue) {
 		if rgKey.MatchString(key) {
 			_ = keyvalue.Purge(key)
 		}
 	}
 }
 
-// Init method will.
+// Init method will start watching the bucket.
 func (provider *Nats) Init() error {
-	return nil
+	provider.watcherMu.Lock()
+	watching := provider.watcher != nil
+	provider.watcherMu.Unlock()
+
+	if watching {
+		return nil
+	}
+
+	return provider.watch()
 }
 
 // Reset method will reset or close provider.
 func (provider *Nats) Reset() error {
+	provider.reconnector.Stop()
+
+	provider.watcherMu.Lock()
+	defer provider.watcherMu.Unlock()
+
+	if provider.watcher != nil {
+		_ = provider.watcher.Stop()
+		provider.watcher = nil
+	}
+
 	return nil
 }
+
+// Reconnect starts the background reconnection unless it already runs.
+func (provider *Nats) Reconnect() {
+	provider.reconnector.Trigger()
+}
@@ -1,6 +1,10 @@
 package nats_test
 
 import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
 	"testing"
 	"time"
 
@@ -33,6 +37,18 @@ func TestNatsConnectionFactory(t *testing.T) {
 	}
 }
 
+func TestNats_ValidatePool(t *testing.T) {
+	if err := nats.Validate(map[string]interface{}{
+		"pool": map[string]interface{}{"max_retries": 10, "dial_backoff": "100ms", "max_dial_backoff": "2s"},
+	}); err != nil {
+		t.Errorf("The pool configuration should be valid, %v given", err)
+	}
+
+	if err := nats.Validate(map[string]interface{}{"pool": map[string]interface{}{"dial_backoff": "3s", "max_dial_backoff": "2s"}}); err == nil {
+		t.Error("A dial_backoff above the max_dial_backoff should be invalid")
+	}
+}
+
 func TestIShouldBeAbleToReadAndWriteDataInNats(t *testing.T) {
 	client, _ := getNatsInstance()
 
@@ -105,3 +121,134 @@ func TestNats_Init(t *testing.T) {
 		t.Error("Impossible to init Nats provider")
 	}
 }
+
+func TestNats_SetRequestInCache_Expired(t *testing.T) {
+	key := "MyExpiredKey"
+	client, _ := getNatsInstance()
+	_ = client.Set(key, []byte(baseValue), time.Second)
+	time.Sleep(2 * time.Second)
+
+	if 0 < len(client.Get(key)) {
+		t.Errorf("Key %s should have expired", key)
+	}
+}
+
+func TestNats_SetMultiLevel(t *testing.T) {
+	client, _ := getNatsInstance()
+	baseKey := "MultiLevelBaseKey"
+	variedKey := "MultiLevelBaseKey-varied"
+
+	err := client.SetMultiLevel(baseKey, variedKey, []byte(baseValue), http.Header{}, "", 20*time.Second, baseKey)
+	if err != nil {
+		t.Fatalf("Impossible to set the multi level key: %v", err)
+	}
+
+	if len(client.Get(variedKey)) == 0 {
+		t.Errorf("Key %s should exist", variedKey)
+	}
+
+	if _, ok := client.MapKeys(core.MappingKeyPrefix)[baseKey]; !ok {
+		t.Errorf("The mapping key of %s should exist", baseKey)
+	}
+}
+
+func TestNats_WatchInvalidation(t *testing.T) {
+	z, _ := zap.NewDevelopment()
+	configuration := core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"keyvalue": "souin-watched-bucket",
+			"max_age":  "1m",
+		},
+	}
+
+	watched, err := nats.Factory(configuration, z.Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the watched instance: %v", err)
+	}
+
+	defer func() { _ = watched.Reset() }()
+
+	if err = watched.Init(); err != nil {
+		t.Fatalf("Impossible to init the watched instance: %v", err)
+	}
+
+	other, err := nats.Factory(configuration, z.Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the other instance: %v", err)
+	}
+
+	_ = other.Set(byteKey, []byte(baseValue), time.Minute)
+	time.Sleep(500 * time.Millisecond)
+
+	if !slices.Contains(watched.ListKeys(), byteKey) {
+		t.Errorf("Key %s should be indexed", byteKey)
+	}
+
+	other.Delete(byteKey)
+	time.Sleep(500 * time.Millisecond)
+
+	if slices.Contains(watched.ListKeys(), byteKey) {
+		t.Errorf("Key %s should have been invalidated", byteKey)
+	}
+}
+
+func TestNats_SetReader(t *testing.T) {
+	client, _ := getNatsInstance()
+
+	streamer, ok := client.(core.StreamStorer)
+	if !ok {
+		t.Fatal("Nats should implement core.StreamStorer")
+	}
+
+	// Larger than the value size limit, it must be split in chunks.
+	value := bytes.Repeat([]byte(baseValue), 200*1024)
+
+	if err := streamer.SetReader("large_value_test", bytes.NewReader(value), 20*time.Second); err != nil {
+		t.Fatalf("Impossible to stream the value: %v", err)
+	}
+
+	reader, err := streamer.GetReader("large_value_test")
+	if err != nil {
+		t.Fatalf("Impossible to get the reader: %v", err)
+	}
+
+	defer reader.Close()
+
+	res, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(res, value) {
+		t.Errorf("Expected %d bytes, got %d bytes, %v", len(value), len(res), err)
+	}
+}
+
+func TestNats_Invalidation(t *testing.T) {
+	client, _ := getNatsInstance()
+	bus, ok := client.(core.InvalidationBus)
+	if !ok {
+		t.Fatal("Nats should implement core.InvalidationBus")
+	}
+
+	received := make(chan core.InvalidationEvent, 1)
+
+	unsubscribe, err := bus.SubscribeInvalidations("nats-test", func(event core.InvalidationEvent) {
+		received <- event
+	})
+	if err != nil {
+		t.Fatalf("Impossible to subscribe to the invalidations, %v", err)
+	}
+
+	defer unsubscribe()
+
+	event := core.InvalidationEvent{Kind: core.InvalidateKey, Key: byteKey, Origin: "other"}
+	if err = bus.PublishInvalidation("nats-test", event); err != nil {
+		t.Fatalf("Impossible to publish the invalidation, %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got != event {
+			t.Errorf("The published event should be received, %+v given", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("The published event should be received")
+	}
+}
@@ -1,12 +1,13 @@
 module github.com/darkweak/storages/nuts
 
-go 1.22.1
+go 1.23
 
 replace github.com/darkweak/storages/core => ../core
 
 require (
 	dario.cat/mergo v1.0.1
 	github.com/darkweak/storages/core v0.0.19
+	github.com/dustin/go-humanize v1.0.1
 	github.com/nutsdb/nutsdb v1.0.4
 	github.com/pierrec/lz4/v4 v4.1.23
 	go.uber.org/zap v1.27.0
@@ -16,12 +17,18 @@ require (
 	github.com/antlabs/stl v0.0.1 // indirect
 	github.com/antlabs/timer v0.0.11 // indirect
 	github.com/bwmarrin/snowflake v0.3.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-viper/mapstructure/v2 v2.2.1 // indirect
 	github.com/gofrs/flock v0.8.1 // indirect
+	github.com/klauspost/compress v1.18.4 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/tidwall/btree v1.6.0 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	github.com/xujiajun/mmap-go v1.0.1 // indirect
 	github.com/xujiajun/utils v0.0.0-20220904132955-5f7c5b914235 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 	google.golang.org/protobuf v1.36.5 // indirect
 )
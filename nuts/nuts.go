@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,27 +18,128 @@ import (
 
 	"dario.cat/mergo"
 	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/ttlindex"
+	"github.com/dustin/go-humanize"
 	"github.com/nutsdb/nutsdb"
-	lz4 "github.com/pierrec/lz4/v4"
 )
 
-var nutsInstanceMap = sync.Map{}
+var (
+	nutsInstanceMap = sync.Map{}
+	// nutsTTLIndexes holds the TTL index of each database directory, shared
+	// by the instances sharing the database.
+	nutsTTLIndexes = sync.Map{}
+)
 
 // Nuts provider type.
 type Nuts struct {
 	*nutsdb.DB
 
-	stale       time.Duration
-	logger      core.Logger
-	uuid        string
-	instanceKey string
+	stale         time.Duration
+	logger        core.Logger
+	compressor    core.Compressor
+	uuid          string
+	instanceKey   string
+	dir           string
+	ttl           *ttlindex.Index
+	hits          core.HitCounter
+	mappings      string
+	mergeInterval time.Duration
+	limiter       *core.BackgroundLimiter
+	mergeStop     chan struct{}
+	mergeDone     sync.WaitGroup
 }
 
 const (
-	bucket    = "souin-bucket"
-	nutsLimit = 1 << 16
+	// valuesBucket holds the values, the mappings are kept apart in
+	// mappingsBucket so the scans of the ones never walk the others.
+	valuesBucket   = "souin-bucket"
+	mappingsBucket = "souin-mappings"
+	nutsLimit      = 1 << 16
+
+	defaultMergeInterval = 2 * time.Hour
+
+	// ttlIndexFile is the TTL index saved in the database directory. nutsdb
+	// expires the keys with a second granularity, the index tells the
+	// expired keys apart to the nanosecond.
+	ttlIndexFile     = "souin-ttl.index"
+	ttlSweepInterval = time.Second
 )
 
+// ttlIndexFor returns the TTL index of the database directory, loaded from
+// its saved file the first time.
+func ttlIndexFor(dir string, logger core.Logger) *ttlindex.Index {
+	if index, ok := nutsTTLIndexes.Load(dir); ok {
+		return index.(*ttlindex.Index)
+	}
+
+	index := ttlindex.New()
+	if err := index.Load(filepath.Join(dir, ttlIndexFile)); err != nil {
+		logger.Errorf("Impossible to load the Nuts TTL index, %v", err)
+	}
+
+	actual, _ := nutsTTLIndexes.LoadOrStore(dir, index)
+
+	return actual.(*ttlindex.Index)
+}
+
+// expiration returns the nutsdb TTL of the duration, rounded up to the
+// second so the keys never expire earlier than asked.
+func expiration(duration time.Duration) uint32 {
+	if duration <= 0 {
+		return nutsdb.Persistent
+	}
+
+	return uint32(math.Ceil(duration.Seconds()))
+}
+
+// track records the expiration of the key stored for the duration.
+func (provider *Nuts) track(key string, duration time.Duration) {
+	var expiresAt time.Time
+	if duration > 0 {
+		expiresAt = time.Now().Add(duration)
+	}
+
+	provider.ttl.Set(key, expiresAt)
+}
+
+// bucketFor returns the bucket holding the key.
+func (provider *Nuts) bucketFor(key string) string {
+	if strings.HasPrefix(key, core.MappingKeyPrefix) {
+		return provider.mappings
+	}
+
+	return valuesBucket
+}
+
+// bucketsFor returns the buckets which may hold keys starting with prefix.
+func (provider *Nuts) bucketsFor(prefix string) []string {
+	switch {
+	case strings.HasPrefix(prefix, core.MappingKeyPrefix):
+		return []string{provider.mappings}
+	case strings.HasPrefix(core.MappingKeyPrefix, prefix):
+		return provider.buckets()
+	default:
+		return []string{valuesBucket}
+	}
+}
+
+// buckets returns the distinct buckets of the database.
+func (provider *Nuts) buckets() []string {
+	if provider.mappings == valuesBucket {
+		return []string{valuesBucket}
+	}
+
+	return []string{valuesBucket, mappingsBucket}
+}
+
+// isEmptyBucket reports whether the error only tells the bucket is missing
+// or empty.
+func isEmptyBucket(err error) bool {
+	return errors.Is(err, nutsdb.ErrBucketNotExist) || errors.Is(err, nutsdb.ErrorBucketNotExist) ||
+		errors.Is(err, nutsdb.ErrBucketNotFound) || errors.Is(err, nutsdb.ErrBucketEmpty) ||
+		errors.Is(err, nutsdb.ErrKeyNotFound)
+}
+
 func sanitizeProperties(configMap map[string]interface{}) map[string]interface{} {
 	for _, iteration := range []string{"RWMode", "StartFileLoadingMode"} {
 		if v := configMap[iteration]; v != nil {
@@ -125,24 +229,111 @@ func sanitizeProperties(configMap map[string]interface{}) map[string]interface{}
 	return configMap
 }
 
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("nuts", Factory)
+}
+
+// configuration is the typed Nuts provider configuration, the other keys
+// are forwarded to the nutsdb.Options.
+type configuration struct {
+	// SegmentSize is the size of the data files ("256MB"), the existing
+	// database must be reopened with the size it was created with.
+	SegmentSize string `json:"segment_size"`
+	// MergeInterval is the delay between two merges of the data files,
+	// zero disables them.
+	MergeInterval time.Duration          `json:"merge_interval"`
+	Options       map[string]interface{} `json:",remain"`
+}
+
+func parseConfiguration(nutsConfiguration any) (configuration, nutsdb.Options, error) {
+	cfg := configuration{MergeInterval: defaultMergeInterval}
+
+	var parsedNuts nutsdb.Options
+
+	configMap, ok := nutsConfiguration.(map[string]interface{})
+	if !ok {
+		return cfg, parsedNuts, fmt.Errorf("invalid nuts configuration: expected a map, %T given", nutsConfiguration)
+	}
+
+	if err := core.DecodeConfiguration(configMap, &cfg); err != nil {
+		return cfg, parsedNuts, fmt.Errorf("invalid nuts configuration: %w", err)
+	}
+
+	if cfg.Options == nil {
+		cfg.Options = map[string]interface{}{}
+	}
+
+	b, err := json.Marshal(sanitizeProperties(cfg.Options))
+	if err == nil {
+		err = json.Unmarshal(b, &parsedNuts)
+	}
+
+	if err != nil {
+		return cfg, parsedNuts, fmt.Errorf("invalid nuts configuration: %w", err)
+	}
+
+	if _, ok := configMap["merge_interval"]; !ok && parsedNuts.MergeInterval != 0 {
+		cfg.MergeInterval = parsedNuts.MergeInterval
+	}
+
+	if cfg.MergeInterval < 0 {
+		return cfg, parsedNuts, fmt.Errorf("invalid nuts configuration: the merge_interval must be positive, %s given", cfg.MergeInterval)
+	}
+
+	if cfg.SegmentSize != "" {
+		size, err := humanize.ParseBytes(cfg.SegmentSize)
+		if err != nil {
+			return cfg, parsedNuts, fmt.Errorf("invalid nuts configuration: segment_size: %w", err)
+		}
+
+		if size == 0 || size > math.MaxInt64 {
+			return cfg, parsedNuts, fmt.Errorf("invalid nuts configuration: the segment_size must be positive, %s given", cfg.SegmentSize)
+		}
+
+		parsedNuts.SegmentSize = int64(size)
+	}
+
+	return cfg, parsedNuts, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(nutsConfiguration any) error {
+	_, _, err := parseConfiguration(nutsConfiguration)
+
+	return err
+}
+
 // Factory function create new Nuts instance.
 func Factory(nutsConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
 	nutsOptions := nutsdb.DefaultOptions
 	nutsOptions.Dir = "/tmp/souin-nuts"
 
+	mergeInterval := defaultMergeInterval
+
+	compressor, err := core.CompressorFromConfiguration(nutsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(nutsConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
 	if nutsConfiguration.Configuration != nil {
-		var parsedNuts nutsdb.Options
+		cfg, parsedNuts, err := parseConfiguration(nutsConfiguration.Configuration)
+		if err != nil {
+			logger.Error("Impossible to parse the configuration for the Nuts provider", err)
 
-		nutsConfiguration.Configuration = sanitizeProperties(nutsConfiguration.Configuration.(map[string]interface{}))
-		if b, e := json.Marshal(nutsConfiguration.Configuration); e == nil {
-			if e = json.Unmarshal(b, &parsedNuts); e != nil {
-				logger.Error("Impossible to parse the configuration for the Nuts provider", e)
-			}
+			return nil, err
 		}
 
 		if err := mergo.Merge(&nutsOptions, parsedNuts, mergo.WithOverride); err != nil {
 			logger.Error("An error occurred during the nutsOptions merge from the default options with your configuration.")
 		}
+
+		mergeInterval = cfg.MergeInterval
 	} else {
 		nutsOptions.RWMode = nutsdb.MMap
 		if nutsConfiguration.Path != "" {
@@ -150,11 +341,21 @@ func Factory(nutsConfiguration core.CacheProvider, logger core.Logger, stale tim
 		}
 	}
 
+	// The merges are scheduled by the provider, see Init.
+	nutsOptions.MergeInterval = 0
+
 	if instance, ok := nutsInstanceMap.Load(nutsOptions.Dir); ok && instance != nil {
 		return &Nuts{
-			DB:     instance.(*nutsdb.DB),
-			stale:  stale,
-			logger: logger,
+			DB:            instance.(*nutsdb.DB),
+			stale:         stale,
+			uuid:          core.InstanceID("nuts", nutsConfiguration, stale),
+			logger:        logger,
+			compressor:    compressor,
+			dir:           nutsOptions.Dir,
+			ttl:           ttlIndexFor(nutsOptions.Dir, logger),
+			mergeInterval: mergeInterval,
+			limiter:       limiter,
+			mappings:      mappingsBucketOf(instance.(*nutsdb.DB)),
 		}, nil
 	}
 
@@ -174,9 +375,16 @@ func Factory(nutsConfiguration core.CacheProvider, logger core.Logger, stale tim
 
 			if instance, ok := nutsInstanceMap.Load(nutsOptions.Dir); ok && instance != nil {
 				return &Nuts{
-					DB:     instance.(*nutsdb.DB),
-					stale:  stale,
-					logger: logger,
+					DB:            instance.(*nutsdb.DB),
+					stale:         stale,
+					uuid:          core.InstanceID("nuts", nutsConfiguration, stale),
+					logger:        logger,
+					compressor:    compressor,
+					dir:           nutsOptions.Dir,
+					ttl:           ttlIndexFor(nutsOptions.Dir, logger),
+					mergeInterval: mergeInterval,
+					limiter:       limiter,
+					mappings:      mappingsBucketOf(instance.(*nutsdb.DB)),
 				}, nil
 			} else {
 				return nil, err
@@ -186,18 +394,83 @@ func Factory(nutsConfiguration core.CacheProvider, logger core.Logger, stale tim
 		return nil, err
 	}
 
+	mappings, err := prepareBuckets(database)
+	if err != nil {
+		logger.Errorf("Impossible to prepare the Nuts buckets, %v", err)
+
+		_ = database.Close()
+
+		return nil, err
+	}
+
+	if mappings == valuesBucket {
+		logger.Warnf("The Nuts database %s uses the single bucket layout, remove it to store the mappings apart", nutsOptions.Dir)
+	}
+
 	instance := &Nuts{
-		DB:          database,
-		stale:       stale,
-		logger:      logger,
-		uuid:        fmt.Sprintf("%s-%s", nutsOptions.Dir, stale),
-		instanceKey: nutsOptions.Dir,
+		DB:            database,
+		stale:         stale,
+		uuid:          core.InstanceID("nuts", nutsConfiguration, stale),
+		logger:        logger,
+		compressor:    compressor,
+		instanceKey:   nutsOptions.Dir,
+		dir:           nutsOptions.Dir,
+		ttl:           ttlIndexFor(nutsOptions.Dir, logger),
+		mergeInterval: mergeInterval,
+		limiter:       limiter,
+		mappings:      mappings,
 	}
 	nutsInstanceMap.Store(nutsOptions.Dir, instance.DB)
 
 	return instance, nil
 }
 
+// prepareBuckets creates the values and mappings buckets of a new database,
+// then returns the bucket holding the mappings. nutsdb restarts the bucket
+// ids when it reopens a database, a bucket created then would share its id
+// with an existing one: the databases created with the previous single
+// bucket layout keep their mappings in the values bucket.
+func prepareBuckets(database *nutsdb.DB) (string, error) {
+	mappings := mappingsBucket
+
+	err := database.Update(func(tx *nutsdb.Tx) error {
+		switch {
+		case tx.ExistBucket(nutsdb.DataStructureBTree, mappingsBucket):
+			return nil
+		case tx.ExistBucket(nutsdb.DataStructureBTree, valuesBucket):
+			mappings = valuesBucket
+
+			return nil
+		}
+
+		for _, name := range []string{valuesBucket, mappingsBucket} {
+			if err := tx.NewBucket(nutsdb.DataStructureBTree, name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return mappings, err
+}
+
+// mappingsBucketOf returns the bucket holding the mappings of an opened
+// database.
+func mappingsBucketOf(database *nutsdb.DB) string {
+	mappings := valuesBucket
+
+	_ = database.View(func(tx *nutsdb.Tx) error {
+		if tx.ExistBucket(nutsdb.DataStructureBTree, mappingsBucket) {
+			mappings = mappingsBucket
+		}
+
+		return nil
+	})
+
+	return mappings
+}
+
 // Name returns the storer name.
 func (provider *Nuts) Name() string {
 	return "NUTS"
@@ -213,7 +486,15 @@ func (provider *Nuts) ListKeys() []string {
 	keys := []string{}
 
 	err := provider.View(func(tx *nutsdb.Tx) error {
-		values, _ := tx.PrefixScan(bucket, []byte(core.MappingKeyPrefix), 0, 100)
+		values, err := tx.GetValues(provider.mappings)
+		if isEmptyBucket(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
 		for _, v := range values {
 			mapping, err := core.DecodeMapping(v)
 			if err == nil {
@@ -238,12 +519,14 @@ func (provider *Nuts) MapKeys(prefix string) map[string]string {
 	bytePrefix := []byte(prefix)
 
 	err := provider.View(func(tx *nutsdb.Tx) error {
-		nKeys, values, _ := tx.GetAll(bucket)
-		for iteration, v := range values {
-			k := nKeys[iteration]
-			if bytes.HasPrefix(k, bytePrefix) {
-				nk, _ := strings.CutPrefix(string(k), prefix)
-				keys[nk] = string(v)
+		for _, bucket := range provider.bucketsFor(prefix) {
+			nKeys, values, _ := tx.GetAll(bucket)
+			for iteration, v := range values {
+				k := nKeys[iteration]
+				if bytes.HasPrefix(k, bytePrefix) {
+					nk, _ := strings.CutPrefix(string(k), prefix)
+					keys[nk] = string(v)
+				}
 			}
 		}
 
@@ -256,26 +539,105 @@ func (provider *Nuts) MapKeys(prefix string) map[string]string {
 	return keys
 }
 
+// WalkEntries streams every entry with its remaining TTL, zero when the
+// entry never expires.
+func (provider *Nuts) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	return provider.View(func(tx *nutsdb.Tx) error {
+		for _, bucket := range provider.buckets() {
+			keys, values, err := tx.GetAll(bucket)
+			if isEmptyBucket(err) {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			for iteration, key := range keys {
+				remaining, err := tx.GetTTL(bucket, key)
+				if err != nil || remaining == 0 {
+					continue
+				}
+
+				var ttl time.Duration
+				if remaining > 0 {
+					ttl = time.Duration(remaining) * time.Second
+				}
+
+				if expiresAt, tracked := provider.ttl.ExpiresAt(string(key)); tracked {
+					if ttl = time.Until(expiresAt); ttl <= 0 {
+						continue
+					}
+				}
+
+				if !walkFn(string(key), values[iteration], ttl) {
+					return nil
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Snapshot writes every entry to w as a core.Dump export.
+func (provider *Nuts) Snapshot(w io.Writer) error {
+	_, err := core.Dump(provider, w)
+
+	return err
+}
+
+// Restore stores the entries of a Snapshot with their remaining TTL, the
+// current entries are kept.
+func (provider *Nuts) Restore(r io.Reader) error {
+	_, err := core.Warmup(provider, r)
+
+	return err
+}
+
 // Get method returns the populated response if exists, empty response then.
 func (provider *Nuts) Get(key string) []byte {
+	item, _ := provider.Lookup(key)
+
+	return item
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Nuts) Lookup(key string) ([]byte, error) {
+	if provider.ttl.Expired(key, time.Now()) {
+		provider.hits.Record(false)
+
+		return nil, core.ErrKeyNotFound
+	}
+
 	var item []byte
 
-	_ = provider.View(func(tx *nutsdb.Tx) error {
-		v, e := tx.Get(bucket, []byte(key))
-		if v != nil {
-			item = v
-		}
+	err := provider.View(func(tx *nutsdb.Tx) error {
+		v, e := tx.Get(provider.bucketFor(key), []byte(key))
+		item = v
 
 		return e
 	})
+	if errors.Is(err, nutsdb.ErrKeyNotFound) {
+		provider.hits.Record(false)
 
-	return item
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider.hits.Record(true)
+
+	return item, nil
 }
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Nuts) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
 	_ = provider.View(func(tx *nutsdb.Tx) error {
-		value, err := tx.Get(bucket, []byte(core.MappingKeyPrefix+key))
+		value, err := tx.Get(provider.mappings, []byte(core.MappingKeyPrefix+key))
 		if err != nil && !errors.Is(err, nutsdb.ErrKeyNotFound) {
 			return err
 		}
@@ -283,9 +645,11 @@ func (provider *Nuts) GetMultiLevel(key string, req *http.Request, validator *co
 		var val []byte
 		if value != nil {
 			val = value
+		} else {
+			provider.hits.Record(false)
 		}
 
-		fresh, stale, err = core.MappingElection(provider, val, req, validator, provider.logger)
+		fresh, stale, err = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
 
 		return err
 	})
@@ -297,42 +661,26 @@ func (provider *Nuts) GetMultiLevel(key string, req *http.Request, validator *co
 func (provider *Nuts) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
 		provider.logger.Errorf("Impossible to compress the key %s into Nuts, %v", variedKey, err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Nuts, %v", variedKey, err)
+	var evicted []string
 
-		return err
-	}
-
-	_ = provider.Update(func(tx *nutsdb.Tx) error {
-		return tx.NewBucket(nutsdb.DataStructureBTree, bucket)
-	})
+	// The value and its mapping are written in the same transaction.
+	err = provider.Update(func(ntx *nutsdb.Tx) error {
+		err := ntx.Put(provider.bucketFor(variedKey), []byte(variedKey), compressed, expiration(duration+provider.stale))
+		if err != nil {
+			provider.logger.Errorf("Impossible to set the key %s into Nuts, %v", variedKey, err)
 
-	err := provider.Update(func(tx *nutsdb.Tx) error {
-		e := tx.Put(bucket, []byte(variedKey), compressed.Bytes(), uint32((duration + provider.stale).Seconds()))
-		if e != nil {
-			provider.logger.Errorf("Impossible to set the key %s into Nuts, %v", variedKey, e)
+			return err
 		}
 
-		return e
-	})
-	if err != nil {
-		return err
-	}
-
-	err = provider.Update(func(ntx *nutsdb.Tx) error {
 		mappingKey := core.MappingKeyPrefix + baseKey
-		item, err := ntx.Get(bucket, []byte(mappingKey))
+		item, err := ntx.Get(provider.mappings, []byte(mappingKey))
 
 		if err != nil && !errors.Is(err, nutsdb.ErrKeyNotFound) {
 			provider.logger.Errorf("Impossible to get the base key %s in Nuts, %v", baseKey, err)
@@ -345,76 +693,256 @@ func (provider *Nuts) SetMultiLevel(baseKey, variedKey string, value []byte, var
 			val = item
 		}
 
-		val, err = core.MappingUpdater(variedKey, val, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+		val, evicted, err = core.MappingUpdaterWithEvictions(variedKey, val, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
 		if err != nil {
 			return err
 		}
 
 		provider.logger.Debugf("Store the new mapping for the key %s in Nuts", variedKey)
 
-		return ntx.Put(bucket, []byte(mappingKey), val, nutsdb.Persistent)
+		return ntx.Put(provider.mappings, []byte(mappingKey), val, nutsdb.Persistent)
 	})
 	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Nuts, %v", err)
+
+		return err
 	}
 
-	return err
+	provider.track(variedKey, duration+provider.stale)
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
 }
 
 // Set method will store the response in Nuts provider.
 func (provider *Nuts) Set(key string, value []byte, duration time.Duration) error {
-	_ = provider.Update(func(tx *nutsdb.Tx) error {
-		return tx.NewBucket(nutsdb.DataStructureBTree, bucket)
-	})
-
 	err := provider.Update(func(tx *nutsdb.Tx) error {
-		return tx.Put(bucket, []byte(key), value, uint32(duration.Seconds()))
+		return tx.Put(provider.bucketFor(key), []byte(key), value, expiration(duration))
 	})
 	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Nuts, %v", err)
+
+		return err
 	}
 
-	return err
+	provider.track(key, duration)
+
+	return nil
 }
 
 // Delete method will delete the response in Nuts provider if exists corresponding to key param.
 func (provider *Nuts) Delete(key string) {
 	_ = provider.Update(func(tx *nutsdb.Tx) error {
-		return tx.Delete(bucket, []byte(key))
+		return tx.Delete(provider.bucketFor(key), []byte(key))
+	})
+
+	provider.ttl.Delete(key)
+}
+
+// Transact runs fn in a Nuts transaction, the writes are serialized by the
+// database so they never conflict.
+func (provider *Nuts) Transact(fn func(tx core.Tx) error) error {
+	return provider.Update(func(ntx *nutsdb.Tx) error {
+		return fn(nutsTx{provider: provider, tx: ntx})
 	})
 }
 
+// nutsTx is the core.Tx of a Nuts transaction.
+type nutsTx struct {
+	provider *Nuts
+	tx       *nutsdb.Tx
+}
+
+func (n nutsTx) Get(key string) ([]byte, error) {
+	value, err := n.tx.Get(n.provider.bucketFor(key), []byte(key))
+	if isEmptyBucket(err) || errors.Is(err, nutsdb.ErrNotFoundKey) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return value, err
+}
+
+func (n nutsTx) Set(key string, value []byte, ttl time.Duration) error {
+	if err := n.tx.Put(n.provider.bucketFor(key), []byte(key), value, expiration(ttl)); err != nil {
+		return err
+	}
+
+	n.provider.track(key, ttl)
+
+	return nil
+}
+
+func (n nutsTx) Delete(key string) error {
+	n.provider.ttl.Delete(key)
+
+	err := n.tx.Delete(n.provider.bucketFor(key), []byte(key))
+	if isEmptyBucket(err) || errors.Is(err, nutsdb.ErrNotFoundKey) {
+		return nil
+	}
+
+	return err
+}
+
 // DeleteMany method will delete the responses in Nuts provider if exists corresponding to the regex key param.
 func (provider *Nuts) DeleteMany(key string) {
-	rgKey, err := regexp.Compile(key)
+	_, _ = provider.DeleteManyCount(key, false)
+}
+
+// DeleteManyCount deletes the keys matching the regular expression, the keys
+// not starting with its literal prefix aren't matched. In dry-run the
+// matching keys are listed without being deleted.
+func (provider *Nuts) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result := core.DeleteManyResult{}
+
+	rgKey, err := regexp.Compile(pattern)
 	if err != nil {
-		provider.logger.Errorf("The key %s is not a valid regexp: %v", key, err)
+		provider.logger.Errorf("The key %s is not a valid regexp: %v", pattern, err)
 
-		return
+		return result, err
 	}
 
-	_ = provider.Update(func(ntx *nutsdb.Tx) error {
-		if entries, err := ntx.GetKeys(bucket); err != nil {
-			return err
-		} else {
+	prefix := core.PatternPrefix(pattern)
+
+	err = provider.Update(func(ntx *nutsdb.Tx) error {
+		for _, bucket := range provider.bucketsFor(prefix) {
+			entries, err := ntx.GetKeys(bucket)
+			if isEmptyBucket(err) {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
 			for _, entry := range entries {
-				if rgKey.Match(entry) {
-					_ = ntx.Delete(bucket, entry)
+				if !bytes.HasPrefix(entry, []byte(prefix)) || !rgKey.Match(entry) {
+					continue
+				}
+
+				if dryRun || ntx.Delete(bucket, entry) == nil {
+					result.Add(string(entry), dryRun)
 				}
 			}
 		}
 
 		return nil
 	})
+
+	return result, err
 }
 
-// Init method will.
+// Stats returns the count and the size of the live keys and values, the
+// records not merged yet aren't counted.
+func (provider *Nuts) Stats() core.StorerStats {
+	stats := provider.hits.Stats()
+
+	_ = provider.View(func(tx *nutsdb.Tx) error {
+		for _, bucket := range provider.buckets() {
+			keys, values, err := tx.GetAll(bucket)
+			if err != nil {
+				continue
+			}
+
+			stats.Entries += int64(len(keys))
+
+			for iteration, key := range keys {
+				stats.Bytes += int64(len(key) + len(values[iteration]))
+			}
+		}
+
+		return nil
+	})
+
+	return stats
+}
+
+// Init method starts the sweep of the keys expired according to the TTL
+// index and merges the data files every merge interval, the merges reclaim
+// the space of the deleted and expired records.
 func (provider *Nuts) Init() error {
+	if provider.DB != nil {
+		provider.ttl.Start(ttlSweepInterval, provider.expire)
+	}
+
+	if provider.mergeStop != nil || provider.mergeInterval == 0 || provider.DB == nil {
+		return nil
+	}
+
+	provider.mergeStop = make(chan struct{})
+	provider.mergeDone.Add(1)
+
+	go func(stop chan struct{}) {
+		defer provider.mergeDone.Done()
+
+		ticker := time.NewTicker(provider.mergeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(stop, "merge") {
+					return
+				}
+
+				provider.merge()
+			}
+		}
+	}(provider.mergeStop)
+
 	return nil
 }
 
+// expire deletes the keys swept from the TTL index, the ones stored again
+// since are kept. The lookups already miss the swept keys, so their deletion
+// waits for the background limiter.
+func (provider *Nuts) expire(keys []string) {
+	provider.limiter.Wait(nil, "ttl_sweep")
+
+	err := provider.Update(func(tx *nutsdb.Tx) error {
+		for _, key := range keys {
+			if _, tracked := provider.ttl.ExpiresAt(key); tracked {
+				continue
+			}
+
+			if err := tx.Delete(provider.bucketFor(key), []byte(key)); err != nil && !isEmptyBucket(err) && !errors.Is(err, nutsdb.ErrNotFoundKey) {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to delete the expired keys in Nuts, %v", err)
+	}
+}
+
+// merge merges the data files, nothing is done while there is less than
+// two files to merge.
+func (provider *Nuts) merge() {
+	err := provider.Merge()
+	if err != nil && !errors.Is(err, nutsdb.ErrDontNeedMerge) && !errors.Is(err, nutsdb.ErrIsMerging) {
+		provider.logger.Errorf("Impossible to merge the Nuts data files, %v", err)
+	}
+}
+
 // Reset method will reset or close provider.
 func (provider *Nuts) Reset() error {
+	if provider.mergeStop != nil {
+		close(provider.mergeStop)
+		provider.mergeStop = nil
+		// Never close the DB while a merge is running.
+		provider.mergeDone.Wait()
+	}
+
+	provider.ttl.Stop()
+
+	if err := provider.ttl.Save(filepath.Join(provider.dir, ttlIndexFile)); err != nil {
+		provider.logger.Errorf("Impossible to save the Nuts TTL index, %v", err)
+	}
+
 	var err error
 	// Close the DB connection
 	if provider.DB != nil {
@@ -422,6 +950,7 @@ func (provider *Nuts) Reset() error {
 	}
 	// Only delete this instance from the cache
 	nutsInstanceMap.Delete(provider.instanceKey)
+	nutsTTLIndexes.Delete(provider.dir)
 
 	return err
 }
@@ -2,12 +2,15 @@ package nuts_test
 
 import (
 	"bytes"
+	"errors"
 	"net/http"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
 	"github.com/darkweak/storages/nuts"
+	"github.com/nutsdb/nutsdb"
 	"github.com/pierrec/lz4/v4"
 	"go.uber.org/zap"
 )
@@ -92,6 +95,22 @@ func TestNuts_SetRequestInCache_TTL(t *testing.T) {
 	}
 }
 
+func TestNuts_SetRequestInCache_SubSecondTTL(t *testing.T) {
+	key := "MySubSecondKey"
+	client, _ := getNutsInstance()
+	_ = client.Set(key, []byte("Hello world"), 100*time.Millisecond)
+
+	if len(client.Get(key)) == 0 {
+		t.Errorf("Key %s should be found before its expiration", key)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if value := client.Get(key); len(value) != 0 {
+		t.Errorf("Key %s should expire after its TTL, %s provided", key, value)
+	}
+}
+
 func TestNuts_DeleteRequestInCache(t *testing.T) {
 	client, _ := getNutsInstance()
 	client.Delete(byteKey)
@@ -157,3 +176,223 @@ func TestNuts_SetMultiLevel_LargeValue(t *testing.T) {
 			len(largeValue), len(retrieved), float64(len(retrieved))/float64(len(largeValue))*100)
 	}
 }
+
+func TestNuts_Lookup(t *testing.T) {
+	client, _ := getNutsInstance()
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestNuts_Stats(t *testing.T) {
+	client, _ := getNutsInstance()
+	before, _ := core.Stats(client)
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Get(byteKey)
+	_ = client.Get(nonExistentKey)
+	_ = client.Get(nonExistentKey)
+
+	stats, found := core.Stats(client)
+	if !found {
+		t.Fatal("The nuts storer should report its stats")
+	}
+
+	if stats.Entries < 1 || stats.Bytes < int64(len(byteKey)+len(baseValue)) || stats.Hits-before.Hits != 1 || stats.Misses-before.Misses != 2 {
+		t.Errorf("Unexpected stats, %+v given", stats)
+	}
+}
+
+func TestNuts_DeleteManyCount(t *testing.T) {
+	client, _ := getNutsInstance()
+	_ = client.Set("PURGE_first", []byte(baseValue), time.Minute)
+	_ = client.Set("PURGE_second", []byte(baseValue), time.Minute)
+	_ = client.Set("KEEP_PURGE_third", []byte(baseValue), time.Minute)
+
+	result, err := core.DeleteManyCount(client, "^PURGE_", true)
+	if err != nil || result.Count != 2 || len(result.Keys) != 2 {
+		t.Fatalf("The dry-run should list the two matching keys, %+v given, %v", result, err)
+	}
+
+	if len(client.Get("PURGE_first")) == 0 {
+		t.Error("The dry-run shouldn't delete the matching keys")
+	}
+
+	if result, _ = core.DeleteManyCount(client, "^PURGE_", false); result.Count != 2 || len(result.Keys) != 0 {
+		t.Errorf("The two matching keys should be deleted, %+v given", result)
+	}
+
+	if len(client.Get("PURGE_first")) != 0 || len(client.Get("KEEP_PURGE_third")) == 0 {
+		t.Error("Only the matching keys should be deleted")
+	}
+}
+
+func TestNuts_SnapshotRestore(t *testing.T) {
+	client, _ := getNutsInstance()
+	_ = client.Set("SNAPSHOT_KEY", []byte(baseValue), time.Minute)
+	_ = client.Set("SNAPSHOT_PERSISTENT", []byte(baseValue), 0)
+
+	var snapshot bytes.Buffer
+
+	if err := core.Snapshot(client, &snapshot); err != nil {
+		t.Fatalf("Impossible to snapshot the database, %v", err)
+	}
+
+	if !bytes.Contains(snapshot.Bytes(), []byte(`"key":"SNAPSHOT_KEY"`)) {
+		t.Fatalf("The key should be in the snapshot, %s given", snapshot.String())
+	}
+
+	client.Delete("SNAPSHOT_KEY")
+	client.Delete("SNAPSHOT_PERSISTENT")
+
+	if err := core.Restore(client, &snapshot); err != nil {
+		t.Fatalf("Impossible to restore the database, %v", err)
+	}
+
+	if string(client.Get("SNAPSHOT_KEY")) != baseValue || string(client.Get("SNAPSHOT_PERSISTENT")) != baseValue {
+		t.Error("The snapshot keys should be restored")
+	}
+}
+
+func TestNuts_MappingsBucket(t *testing.T) {
+	client, err := nuts.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to open the database, %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	_ = client.Set("value", []byte(baseValue), time.Minute)
+	_ = client.SetMultiLevel("base", "varied", []byte(baseValue), http.Header{}, "", time.Minute, "real")
+
+	_ = client.(*nuts.Nuts).View(func(tx *nutsdb.Tx) error {
+		if keys, _ := tx.GetKeys("souin-mappings"); len(keys) != 1 {
+			t.Errorf("The mapping should be stored in its own bucket, %d keys given", len(keys))
+		}
+
+		return nil
+	})
+
+	if keys := client.MapKeys(core.MappingKeyPrefix); len(keys) != 1 {
+		t.Errorf("Only the mappings should be scanned, %v given", keys)
+	}
+
+	if keys := client.MapKeys(""); len(keys) != 3 {
+		t.Errorf("Both buckets should be scanned, %v given", keys)
+	}
+
+	if keys := client.ListKeys(); len(keys) != 1 || keys[0] != "real" {
+		t.Errorf("The real key should be listed, %v given", keys)
+	}
+}
+
+func TestNuts_Transact(t *testing.T) {
+	client, err := nuts.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to open the database, %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	mappingKey := core.MappingKeyPrefix + "base"
+
+	err = core.Transact(client, func(tx core.Tx) error {
+		if _, err := tx.Get(mappingKey); !errors.Is(err, core.ErrKeyNotFound) {
+			t.Errorf("The absent key should be reported, %v given", err)
+		}
+
+		if err := tx.Set("varied", []byte(baseValue), time.Minute); err != nil {
+			return err
+		}
+
+		if err := tx.Set(mappingKey, []byte("index"), 0); err != nil {
+			return err
+		}
+
+		if value, _ := tx.Get(mappingKey); string(value) != "index" {
+			t.Errorf("The transaction should see its writes, %s given", value)
+		}
+
+		return nil
+	})
+	if err != nil || string(client.Get("varied")) != baseValue || string(client.Get(mappingKey)) != "index" {
+		t.Fatalf("Both keys should be written, %v", err)
+	}
+
+	failure := errors.New("rollback")
+
+	err = core.Transact(client, func(tx core.Tx) error {
+		_ = tx.Delete("varied")
+
+		return failure
+	})
+	if !errors.Is(err, failure) || client.Get("varied") == nil {
+		t.Errorf("The writes should be discarded on error, %v", err)
+	}
+}
+
+func TestNuts_SingleBucketLayout(t *testing.T) {
+	dir := t.TempDir()
+
+	legacy, err := nutsdb.Open(nutsdb.DefaultOptions, nutsdb.WithDir(dir))
+	if err != nil {
+		t.Fatalf("Impossible to open the legacy database, %v", err)
+	}
+
+	_ = legacy.Update(func(tx *nutsdb.Tx) error {
+		return tx.NewBucket(nutsdb.DataStructureBTree, "souin-bucket")
+	})
+	_ = legacy.Update(func(tx *nutsdb.Tx) error {
+		return tx.Put("souin-bucket", []byte(core.MappingKeyPrefix+"legacy"), []byte(baseValue), nutsdb.Persistent)
+	})
+	_ = legacy.Close()
+
+	client, err := nuts.Factory(core.CacheProvider{Path: dir}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to open the database, %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	_ = client.Set("value", []byte(baseValue), time.Minute)
+
+	if string(client.Get(core.MappingKeyPrefix+"legacy")) != baseValue || string(client.Get("value")) != baseValue {
+		t.Error("The single bucket database should stay readable")
+	}
+
+	if keys := client.MapKeys(""); len(keys) != 2 {
+		t.Errorf("The keys should be listed once, %v given", keys)
+	}
+}
+
+func TestNuts_Validate(t *testing.T) {
+	if err := nuts.Validate(map[string]interface{}{"segment_size": "64MB", "merge_interval": "30m"}); err != nil {
+		t.Errorf("The configuration should be valid, %v given", err)
+	}
+
+	if err := nuts.Validate(map[string]interface{}{"segment_size": "big"}); err == nil {
+		t.Error("An invalid segment_size should be rejected")
+	}
+
+	if err := nuts.Validate(map[string]interface{}{"merge_interval": "-1m"}); err == nil {
+		t.Error("A negative merge_interval should be rejected")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := nuts.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
@@ -0,0 +1,140 @@
+package olric
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/buraksezer/olric"
+)
+
+// OnEvicted registers fn for the keys expired or evicted by Olric, they're
+// detected by a scan of the DMaps every eviction_scan_interval started with
+// the first callback. A key stored then gone between two scans is notified,
+// the values are already gone and passed as nil. The keys deleted by the
+// other instances are notified too.
+func (provider *Olric) OnEvicted(fn func(key string, value []byte)) {
+	provider.evictions.Add(fn)
+
+	provider.evictionMu.Lock()
+	defer provider.evictionMu.Unlock()
+
+	if provider.stopEvictions != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	provider.stopEvictions = cancel
+	provider.scanning.Store(true)
+
+	go func(interval time.Duration) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		provider.scanEvictions()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(ctx.Done(), "eviction_scan") {
+					return
+				}
+
+				provider.scanEvictions()
+			}
+		}
+	}(provider.evictionScan)
+}
+
+// stopEvictionScans stops the eviction scans if they're started.
+func (provider *Olric) stopEvictionScans() {
+	provider.evictionMu.Lock()
+	defer provider.evictionMu.Unlock()
+
+	if provider.stopEvictions != nil {
+		provider.stopEvictions()
+		provider.stopEvictions = nil
+		provider.scanning.Store(false)
+	}
+}
+
+// forgetEvicted records the explicit deletion of the key, the next scan
+// doesn't notify it.
+func (provider *Olric) forgetEvicted(key string) {
+	if provider.scanning.Load() {
+		provider.deleted.Store(key, struct{}{})
+	}
+}
+
+// scanEvictions notifies the keys seen by the previous scan and gone or
+// expired since without being deleted by this instance. Olric collects the
+// expired keys lazily, their expiry is read once they're first scanned.
+func (provider *Olric) scanEvictions() {
+	if provider.reconnector.Reconnecting() {
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	current := map[string]int64{}
+
+	for _, name := range provider.dmaps.distinct() {
+		dmap, release := provider.dmap(name)
+		ctx, cancel := provider.readContext()
+
+		records, err := dmap.Scan(ctx)
+		if err != nil {
+			cancel()
+			release()
+
+			provider.logger.Errorf("Impossible to scan the Olric evictions, %v", err)
+
+			return
+		}
+
+		for records.Next() {
+			key := records.Key()
+
+			expiry, found := provider.known[key]
+			if !found || (expiry != 0 && expiry <= now) {
+				response, err := dmap.Get(ctx, key)
+
+				switch {
+				case errors.Is(err, olric.ErrKeyNotFound):
+					// Expired but not collected yet, notified below if known.
+					continue
+				case err == nil:
+					expiry = response.TTL()
+				}
+			}
+
+			current[key] = expiry
+		}
+
+		records.Close()
+		cancel()
+		release()
+	}
+
+	for key := range provider.known {
+		if _, found := current[key]; found {
+			continue
+		}
+
+		if _, deleted := provider.deleted.LoadAndDelete(key); !deleted {
+			provider.evictions.Notify(key, nil)
+		}
+	}
+
+	// The keys deleted after the scan are kept for the next one.
+	provider.deleted.Range(func(key, _ any) bool {
+		if _, found := current[key.(string)]; !found {
+			provider.deleted.Delete(key)
+		}
+
+		return true
+	})
+
+	provider.known = current
+}
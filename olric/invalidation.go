@@ -0,0 +1,119 @@
+package olric
+
+import (
+	"context"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// PublishInvalidation publishes the event on the channel with the Olric
+// PubSub, the successor of the DTopics.
+func (provider *Olric) PublishInvalidation(channel string, event core.InvalidationEvent) error {
+	payload, err := event.Encode()
+	if err != nil {
+		return err
+	}
+
+	pubsub, err := provider.NewPubSub()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	_, err = pubsub.Publish(ctx, channel, string(payload))
+
+	return err
+}
+
+// SubscribeInvalidations subscribes to the channel and calls fn with every
+// event published on it. The subscription is made again on the current
+// client when it's lost, e.g. by a reconnection.
+func (provider *Olric) SubscribeInvalidations(channel string, fn func(core.InvalidationEvent)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := provider.subscribe(ctx, channel, func(payload []byte) {
+		event, err := core.DecodeInvalidationEvent(payload)
+		if err != nil {
+			provider.logger.Errorf("Impossible to decode the Olric invalidation, %v", err)
+
+			return
+		}
+
+		fn(event)
+	}, nil)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	return cancel, nil
+}
+
+// subscribe subscribes to the channel and calls fn with the payload of every
+// message published on it until the context is done, then closed is called
+// if set. The subscription is made again on the current client when it's
+// lost.
+func (provider *Olric) subscribe(ctx context.Context, channel string, fn func(payload []byte), closed func()) error {
+	pubsub, err := provider.NewPubSub()
+	if err != nil {
+		return err
+	}
+
+	subscription := pubsub.Subscribe(ctx, channel)
+
+	if _, err = subscription.Receive(ctx); err != nil {
+		_ = subscription.Close()
+
+		return err
+	}
+
+	go func() {
+		if closed != nil {
+			defer closed()
+		}
+
+		messages := subscription.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				_ = subscription.Close()
+
+				return
+			case message, ok := <-messages:
+				if ok {
+					fn([]byte(message.Payload))
+
+					continue
+				}
+
+				_ = subscription.Close()
+
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(time.Second):
+					}
+
+					pubsub, err := provider.NewPubSub()
+					if err == nil {
+						subscription = pubsub.Subscribe(ctx, channel)
+
+						break
+					}
+
+					provider.logger.Errorf("Impossible to subscribe to the Olric channel %s, %v", channel, err)
+				}
+
+				messages = subscription.Channel()
+			}
+		}
+	}()
+
+	return nil
+}
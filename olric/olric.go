@@ -5,17 +5,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/buraksezer/olric"
 	"github.com/buraksezer/olric/config"
 	"github.com/darkweak/storages/core"
 	"github.com/google/uuid"
-	"github.com/pierrec/lz4/v4"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,413 +29,1552 @@ import (
 type Olric struct {
 	olric.Client
 
-	dm            *sync.Pool
-	stale         time.Duration
-	logger        core.Logger
-	addresses     []string
-	reconnecting  bool
-	configuration config.Client
+	// member is the Olric node started in the process when the embedded mode
+	// is enabled, nil when connected to an external cluster.
+	member *olric.Olric
+	uid    string
+	dmaps  dmapNames
+	// dm holds a pool of DMap clients per DMap name, filled by Init.
+	dm          map[string]*sync.Pool
+	stale       time.Duration
+	uuid        string
+	logger      core.Logger
+	compressor  core.Compressor
+	addresses   []string
+	reconnector *core.Reconnector
+	// clientOptions create the cluster client, on reconnection too.
+	clientOptions []olric.ClusterClientOption
+	streamer      *core.ChunkedStreamer
+	timeouts      core.Timeouts
+	// locks keeps the lock context of each lock held by this instance.
+	locks sync.Map
+	// events publishes the changes on core.DefaultEventsChannel for the
+	// watchers.
+	events bool
+	// evictionScan is the delay between two scans detecting the evictions.
+	evictionScan time.Duration
+	// deleteWorkers bounds the DMaps scanned and the batches deleted at once
+	// by DeleteMany.
+	deleteWorkers int
+	// limiter spaces the eviction scans.
+	limiter       *core.BackgroundLimiter
+	evictions     core.EvictionCallbacks
+	evictionMu    sync.Mutex
+	stopEvictions func()
+	// known are the keys seen by the last eviction scan with their expiry,
+	// deleted the ones explicitly deleted since.
+	known    map[string]int64
+	deleted  sync.Map
+	scanning atomic.Bool
 }
 
-func tryToLoadConfiguration(olricInstance *config.Config, olricConfiguration core.CacheProvider, logger core.Logger) (*config.Config, bool) {
-	var err error
+// lockAcquisitionDeadline is how long TryLock waits for a held lock, the
+// Olric locks always wait until a deadline.
+const lockAcquisitionDeadline = time.Millisecond
 
-	isAlreadyLoaded := false
+// The mapping lock is held while a mapping is read, updated and written
+// back, it expires after mappingLockTimeout if the instance dies meanwhile.
+const (
+	mappingLockTimeout  = 5 * time.Second
+	mappingLockDeadline = 100 * time.Millisecond
+)
+
+// compareAndSwapLockPrefix prefixes the lock held by CompareAndSwap while
+// the value is read, compared and written back, with the mapping lock
+// timeout and deadline.
+const compareAndSwapLockPrefix = core.LockKeyPrefix + "CAS_"
+
+const (
+	embeddedConfigurationKey = "embedded"
+	dmapConfigurationKey     = "dmap"
+	dmapsConfigurationKey    = "dmaps"
+	clientConfigurationKey   = "client"
+	eventsConfigurationKey   = "events"
+	evictionScanKey          = "eviction_scan_interval"
+	defaultDMapName          = "souin-map"
+)
+
+// dmapNames are the DMaps storing each kind of key, they may all be the same.
+type dmapNames struct {
+	Values     string `json:"values"`
+	Mappings   string `json:"mappings"`
+	Surrogates string `json:"surrogates"`
+}
+
+// dmapConfiguration is the part of the provider configuration naming the
+// DMaps, the other keys are read by loadConfiguration.
+type dmapConfiguration struct {
+	// DMap is the name of the DMap storing every key.
+	DMap string `json:"dmap"`
+	// DMaps overrides the DMap name for each kind of key.
+	DMaps  dmapNames              `json:"dmaps"`
+	Remain map[string]interface{} `json:",remain"`
+}
+
+// parseDMapNames returns the DMap names, the ones not declared in dmaps
+// default to the dmap name, souin-map otherwise.
+func parseDMapNames(olricConfiguration core.CacheProvider) (dmapNames, error) {
+	cfg := dmapConfiguration{DMap: defaultDMapName}
 
-	if olricConfiguration.Configuration == nil && olricConfiguration.Path != "" {
-		if olricInstance, err = config.Load(olricConfiguration.Path); err == nil {
-			isAlreadyLoaded = true
+	if olricCfg, ok := olricConfiguration.Configuration.(map[string]interface{}); ok {
+		if err := core.DecodeConfiguration(olricCfg, &cfg); err != nil {
+			return dmapNames{}, fmt.Errorf("invalid olric configuration: %w", err)
+		}
+	}
+
+	if cfg.DMap == "" {
+		return dmapNames{}, errors.New("invalid olric configuration: the dmap name can't be empty")
+	}
+
+	names := cfg.DMaps
+
+	for _, name := range []*string{&names.Values, &names.Mappings, &names.Surrogates} {
+		if *name == "" {
+			*name = cfg.DMap
+		}
+	}
+
+	return names, nil
+}
+
+// forKey returns the name of the DMap storing the key.
+func (names dmapNames) forKey(key string) string {
+	switch {
+	case strings.HasPrefix(key, core.MappingKeyPrefix):
+		return names.Mappings
+	case strings.HasPrefix(key, core.SurrogateKeyPrefix):
+		return names.Surrogates
+	default:
+		return names.Values
+	}
+}
+
+// distinct returns each DMap name once.
+func (names dmapNames) distinct() []string {
+	distinct := []string{names.Values}
+
+	for _, name := range []string{names.Mappings, names.Surrogates} {
+		if !slices.Contains(distinct, name) {
+			distinct = append(distinct, name)
+		}
+	}
+
+	return distinct
+}
+
+var (
+	enabledEmbeddedInstances = sync.Map{}
+	// storagesConfigurationKeys are consumed by the provider and must not be
+	// forwarded to the embedded Olric configuration.
+	storagesConfigurationKeys = append([]string{"mode", embeddedConfigurationKey, dmapConfigurationKey, dmapsConfigurationKey, clientConfigurationKey, eventsConfigurationKey, evictionScanKey}, core.SharedConfigurationKeys...)
+)
+
+// clientConfiguration tunes the cluster client of the remote mode, the zero
+// values keep the Olric defaults. The Olric servers don't authenticate their
+// clients, use the tls block to present a client certificate.
+type clientConfiguration struct {
+	DialTimeout  time.Duration `json:"dial_timeout"`
+	ReadTimeout  time.Duration `json:"read_timeout"`
+	WriteTimeout time.Duration `json:"write_timeout"`
+	// MaxRetries is the number of retries of a failed command, -1 disables
+	// them.
+	MaxRetries      int           `json:"max_retries"`
+	MinRetryBackoff time.Duration `json:"min_retry_backoff"`
+	MaxRetryBackoff time.Duration `json:"max_retry_backoff"`
+	// PoolSize is the number of connections at most to each member.
+	PoolSize           int           `json:"pool_size"`
+	PoolFIFO           bool          `json:"pool_fifo"`
+	PoolTimeout        time.Duration `json:"pool_timeout"`
+	MinIdleConns       int           `json:"min_idle_conns"`
+	MaxConnAge         time.Duration `json:"max_conn_age"`
+	IdleTimeout        time.Duration `json:"idle_timeout"`
+	IdleCheckFrequency time.Duration `json:"idle_check_frequency"`
+	// RoutingTableFetchInterval is the delay between two refreshes of the
+	// partitions owners.
+	RoutingTableFetchInterval time.Duration `json:"routing_table_fetch_interval"`
+}
+
+// applyPool overrides the client block with the shared pool settings set.
+func applyPool(cfg *clientConfiguration, pool core.Pool) {
+	if pool.Size > 0 {
+		cfg.PoolSize = pool.Size
+	}
+
+	if pool.MinIdle > 0 {
+		cfg.MinIdleConns = pool.MinIdle
+	}
+
+	if pool.MaxRetries != 0 {
+		cfg.MaxRetries = pool.MaxRetries
+	}
+
+	if pool.DialBackoff > 0 {
+		cfg.MinRetryBackoff = pool.DialBackoff
+	}
+
+	if pool.MaxDialBackoff > 0 {
+		cfg.MaxRetryBackoff = pool.MaxDialBackoff
+	}
+}
+
+// parseClientConfiguration returns the cluster client options read from the
+// client, pool and tls blocks.
+func parseClientConfiguration(olricConfiguration core.CacheProvider) ([]olric.ClusterClientOption, error) {
+	cfg := clientConfiguration{}
+
+	if olricCfg, ok := olricConfiguration.Configuration.(map[string]interface{}); ok && olricCfg[clientConfigurationKey] != nil {
+		if err := core.DecodeConfiguration(olricCfg[clientConfigurationKey], &cfg); err != nil {
+			return nil, fmt.Errorf("invalid olric configuration: %w", err)
+		}
+	}
+
+	pool, err := core.PoolFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	applyPool(&cfg, pool)
+
+	if cfg.PoolSize < 0 || cfg.MinIdleConns < 0 {
+		return nil, fmt.Errorf("invalid olric configuration: the pool_size and min_idle_conns can't be negative, %d and %d given", cfg.PoolSize, cfg.MinIdleConns)
+	}
+
+	if cfg.RoutingTableFetchInterval < 0 {
+		return nil, fmt.Errorf("invalid olric configuration: the routing_table_fetch_interval can't be negative, %s given", cfg.RoutingTableFetchInterval)
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig := &config.Client{
+		DialTimeout:        cfg.DialTimeout,
+		ReadTimeout:        cfg.ReadTimeout,
+		WriteTimeout:       cfg.WriteTimeout,
+		MaxRetries:         cfg.MaxRetries,
+		MinRetryBackoff:    cfg.MinRetryBackoff,
+		MaxRetryBackoff:    cfg.MaxRetryBackoff,
+		PoolFIFO:           cfg.PoolFIFO,
+		PoolSize:           cfg.PoolSize,
+		MinIdleConns:       cfg.MinIdleConns,
+		MaxConnAge:         cfg.MaxConnAge,
+		PoolTimeout:        cfg.PoolTimeout,
+		IdleTimeout:        cfg.IdleTimeout,
+		IdleCheckFrequency: cfg.IdleCheckFrequency,
+		TLSConfig:          tlsConfig,
+	}
+
+	if err = clientConfig.Sanitize(); err != nil {
+		return nil, fmt.Errorf("invalid olric configuration: %w", err)
+	}
+
+	options := []olric.ClusterClientOption{olric.WithConfig(clientConfig)}
+	if cfg.RoutingTableFetchInterval > 0 {
+		options = append(options, olric.WithRoutingTableFetchInterval(cfg.RoutingTableFetchInterval))
+	}
+
+	return options, nil
+}
+
+// isEmbedded returns true when the embedded flag is set or, for backward
+// compatibility, when the mode is local and no URL is given.
+func isEmbedded(olricConfiguration core.CacheProvider) bool {
+	olricCfg, ok := olricConfiguration.Configuration.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if v, found := olricCfg[embeddedConfigurationKey]; found && v != nil {
+		switch val := v.(type) {
+		case bool:
+			return val
+		case string:
+			embedded, _ := strconv.ParseBool(val)
+
+			return embedded
+		}
+	}
+
+	mode, _ := olricCfg["mode"].(string)
+
+	return mode == "local" && olricConfiguration.URL == ""
+}
+
+// eventsEnabled reports whether the changes are published for the watchers.
+func eventsEnabled(olricConfiguration core.CacheProvider) bool {
+	olricCfg, ok := olricConfiguration.Configuration.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	switch val := olricCfg[eventsConfigurationKey].(type) {
+	case bool:
+		return val
+	case string:
+		enabled, _ := strconv.ParseBool(val)
+
+		return enabled
+	}
+
+	return false
+}
+
+// parseEvictionScanInterval returns the delay between two eviction scans.
+func parseEvictionScanInterval(olricConfiguration core.CacheProvider) (time.Duration, error) {
+	olricCfg, ok := olricConfiguration.Configuration.(map[string]interface{})
+	if !ok || olricCfg[evictionScanKey] == nil {
+		return core.DefaultEvictionScanInterval, nil
+	}
+
+	var cfg struct {
+		EvictionScanInterval time.Duration `json:"eviction_scan_interval"`
+	}
+
+	if err := core.DecodeConfiguration(map[string]interface{}{evictionScanKey: olricCfg[evictionScanKey]}, &cfg); err != nil {
+		return 0, fmt.Errorf("invalid olric configuration: %w", err)
+	}
+
+	if cfg.EvictionScanInterval <= 0 {
+		return 0, fmt.Errorf("invalid olric configuration: the eviction_scan_interval must be positive, %s given", cfg.EvictionScanInterval)
+	}
+
+	return cfg.EvictionScanInterval, nil
+}
+
+// loadConfiguration loads the embedded Olric configuration from the inline
+// keys or the Path, a nil configuration means none is given.
+func loadConfiguration(olricConfiguration core.CacheProvider) (*config.Config, error) {
+	olricCfg := map[string]interface{}{}
+
+	if cfg, ok := olricConfiguration.Configuration.(map[string]interface{}); ok {
+		for key, value := range cfg {
+			if !slices.Contains(storagesConfigurationKeys, key) {
+				olricCfg[key] = value
+			}
+		}
+	}
+
+	if len(olricCfg) == 0 {
+		if olricConfiguration.Path == "" {
+			return nil, nil
+		}
+
+		olricInstance, err := config.Load(olricConfiguration.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid olric configuration: impossible to load %s, %w", olricConfiguration.Path, err)
+		}
+
+		return olricInstance, nil
+	}
+
+	yamlConfig, err := yaml.Marshal(olricCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid olric configuration: %w", err)
+	}
+
+	tmpFile := filepath.Join(os.TempDir(), uuid.NewString()+".yml")
+
+	defer func() {
+		_ = os.RemoveAll(tmpFile)
+	}()
+
+	if err := os.WriteFile(tmpFile, yamlConfig, 0o600); err != nil {
+		return nil, fmt.Errorf("impossible to create the embedded Olric config from the given one, %w", err)
+	}
+
+	olricInstance, err := config.Load(tmpFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid olric configuration: %w", err)
+	}
+
+	return olricInstance, nil
+}
+
+// Validate returns an error describing the malformed DMap names, embedded
+// configuration keys or client configuration keys.
+func Validate(olricConfiguration any) error {
+	provider := core.CacheProvider{Configuration: olricConfiguration}
+	if _, err := parseDMapNames(provider); err != nil {
+		return err
+	}
+
+	if _, err := parseEvictionScanInterval(provider); err != nil {
+		return err
+	}
+
+	if !isEmbedded(provider) {
+		_, err := parseClientConfiguration(provider)
+
+		return err
+	}
+
+	_, err := loadConfiguration(provider)
+
+	return err
+}
+
+func newEmbeddedOlric(olricInstance *config.Config, logger core.Logger) (*olric.Olric, error) {
+	ready := make(chan struct{})
+	olricInstance.Started = func() {
+		close(ready)
+	}
+
+	olricDB, err := olric.New(olricInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+
+	go func(cdb *olric.Olric) {
+		if err := cdb.Start(); err != nil {
+			errCh <- err
+		}
+	}(olricDB)
+
+	select {
+	case err = <-errCh:
+		logger.Errorf("Impossible to start the embedded Olric member, %v", err)
+
+		return nil, err
+	case <-ready:
+	}
+
+	logger.Info("Embedded Olric is ready for this node.")
+
+	return olricDB, nil
+}
+
+func embeddedFactory(olricConfiguration core.CacheProvider, logger core.Logger, stale time.Duration, compressor core.Compressor, dmaps dmapNames, timeouts core.Timeouts, evictionScan time.Duration, deleteWorkers int, limiter *core.BackgroundLimiter) (core.Storer, error) {
+	olricInstance, err := loadConfiguration(olricConfiguration)
+	if err != nil {
+		logger.Errorf("Impossible to load the embedded Olric configuration, %v", err)
+
+		return nil, err
+	}
+
+	if olricInstance == nil {
+		olricInstance = config.New("local")
+		olricInstance.DMaps.MaxInuse = 512 << 20
+	}
+
+	address := net.JoinHostPort(olricInstance.BindAddr, strconv.Itoa(olricInstance.BindPort))
+	uid := address + stale.String()
+
+	if instance, ok := enabledEmbeddedInstances.Load(uid); ok {
+		existing := instance.(*Olric)
+		if existing.dmaps == dmaps && existing.events == eventsEnabled(olricConfiguration) && existing.evictionScan == evictionScan && existing.deleteWorkers == deleteWorkers {
+			return existing, nil
+		}
+
+		// Another application of the process shares the member with its own
+		// DMaps, events, eviction scans or DeleteMany workers.
+		shared := &Olric{
+			Client:        existing.Client,
+			member:        existing.member,
+			uid:           uid,
+			dmaps:         dmaps,
+			stale:         stale,
+			uuid:          core.InstanceID("olric", olricConfiguration, stale),
+			logger:        logger,
+			compressor:    compressor,
+			addresses:     existing.addresses,
+			timeouts:      timeouts,
+			events:        eventsEnabled(olricConfiguration),
+			evictionScan:  evictionScan,
+			deleteWorkers: deleteWorkers,
+			limiter:       limiter,
+		}
+		shared.reconnector = core.NewReconnector(olricConfiguration.Configuration, logger, shared.connect)
+		shared.streamer = core.NewChunkedStreamer(shared, core.ChunkSizeFromConfiguration(olricConfiguration.Configuration))
+
+		return shared, nil
+	}
+
+	member, err := newEmbeddedOlric(olricInstance, logger)
+	if err != nil {
+		logger.Error("Impossible to setup Embedded Olric instance")
+
+		return nil, err
+	}
+
+	instance := &Olric{
+		Client:        member.NewEmbeddedClient(),
+		member:        member,
+		uid:           uid,
+		dmaps:         dmaps,
+		dm:            nil,
+		stale:         stale,
+		uuid:          core.InstanceID("olric", olricConfiguration, stale),
+		logger:        logger,
+		compressor:    compressor,
+		addresses:     []string{address},
+		timeouts:      timeouts,
+		events:        eventsEnabled(olricConfiguration),
+		evictionScan:  evictionScan,
+		deleteWorkers: deleteWorkers,
+		limiter:       limiter,
+	}
+	instance.reconnector = core.NewReconnector(olricConfiguration.Configuration, logger, instance.connect)
+	instance.streamer = core.NewChunkedStreamer(instance, core.ChunkSizeFromConfiguration(olricConfiguration.Configuration))
+	enabledEmbeddedInstances.Store(uid, instance)
+
+	return instance, nil
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("olric", Factory)
+}
+
+// Factory function create new Olric instance.
+func Factory(olricConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	compressor, err := core.CompressorFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	dmaps, err := parseDMapNames(olricConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts, err := core.TimeoutsFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	evictionScan, err := parseEvictionScanInterval(olricConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteWorkers, err := core.DeleteManyWorkersFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	if isEmbedded(olricConfiguration) {
+		logger.Debug("Olric embedded mode enabled, starting an Olric member in the process")
+
+		return embeddedFactory(olricConfiguration, logger, stale, compressor, dmaps, timeouts, evictionScan, deleteWorkers, limiter)
+	}
+
+	clientOptions, err := parseClientConfiguration(olricConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := olric.NewClusterClient(strings.Split(olricConfiguration.URL, ","), clientOptions...)
+	if err != nil {
+		logger.Errorf("Impossible to connect to Olric, %v", err)
+	}
+
+	instance := &Olric{
+		Client:        client,
+		dmaps:         dmaps,
+		dm:            nil,
+		stale:         stale,
+		uuid:          core.InstanceID("olric", olricConfiguration, stale),
+		logger:        logger,
+		compressor:    compressor,
+		clientOptions: clientOptions,
+		addresses:     strings.Split(olricConfiguration.URL, ","),
+		timeouts:      timeouts,
+		events:        eventsEnabled(olricConfiguration),
+		evictionScan:  evictionScan,
+		deleteWorkers: deleteWorkers,
+		limiter:       limiter,
+	}
+	instance.reconnector = core.NewReconnector(olricConfiguration.Configuration, logger, instance.connect)
+	instance.streamer = core.NewChunkedStreamer(instance, core.ChunkSizeFromConfiguration(olricConfiguration.Configuration))
+
+	return instance, nil
+}
+
+// Reload applies the new compressor, DMap names, events and stream settings,
+// the eviction scan interval applies to the next started scan. The
+// entries stored in the previous DMaps are not moved. In remote mode a
+// new cluster client is connected to the given addresses then the previous
+// one is closed. The embedded member keeps running with its configuration to
+// retain the stored entries, moving it to another address requires a restart.
+func (provider *Olric) Reload(olricConfiguration core.CacheProvider) error {
+	compressor, err := core.CompressorFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return err
+	}
+
+	dmaps, err := parseDMapNames(olricConfiguration)
+	if err != nil {
+		return err
+	}
+
+	timeouts, err := core.TimeoutsFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return err
+	}
+
+	evictionScan, err := parseEvictionScanInterval(olricConfiguration)
+	if err != nil {
+		return err
+	}
+
+	deleteWorkers, err := core.DeleteManyWorkersFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(olricConfiguration.Configuration)
+	if err != nil {
+		return err
+	}
+
+	if provider.member != nil {
+		if !isEmbedded(olricConfiguration) {
+			return errors.New("impossible to reload the embedded Olric member in remote mode, a restart is required")
+		}
+
+		olricInstance, loadErr := loadConfiguration(olricConfiguration)
+		if loadErr != nil {
+			return loadErr
+		}
+
+		if olricInstance != nil && net.JoinHostPort(olricInstance.BindAddr, strconv.Itoa(olricInstance.BindPort)) != provider.addresses[0] {
+			return errors.New("impossible to move the embedded Olric member to another address, a restart is required")
+		}
+
+		provider.compressor = compressor
+		provider.timeouts = timeouts
+		provider.events = eventsEnabled(olricConfiguration)
+		provider.evictionScan = evictionScan
+		provider.deleteWorkers = deleteWorkers
+		provider.limiter = limiter
+		provider.streamer = core.NewChunkedStreamer(provider, core.ChunkSizeFromConfiguration(olricConfiguration.Configuration))
+
+		if provider.dmaps != dmaps {
+			provider.dmaps = dmaps
+
+			if provider.dm != nil {
+				_ = provider.Init()
+			}
+		}
+
+		return nil
+	}
+
+	if isEmbedded(olricConfiguration) {
+		return errors.New("impossible to reload the remote Olric client in embedded mode, a restart is required")
+	}
+
+	clientOptions, err := parseClientConfiguration(olricConfiguration)
+	if err != nil {
+		return err
+	}
+
+	addresses := strings.Split(olricConfiguration.URL, ",")
+
+	client, err := olric.NewClusterClient(addresses, clientOptions...)
+	if err != nil {
+		provider.logger.Errorf("Impossible to reload the Olric client, %v", err)
+
+		return err
+	}
+
+	previous := provider.Client
+	provider.Client = client
+	provider.clientOptions = clientOptions
+	provider.addresses = addresses
+	provider.compressor = compressor
+	provider.timeouts = timeouts
+	provider.events = eventsEnabled(olricConfiguration)
+	provider.evictionScan = evictionScan
+	provider.deleteWorkers = deleteWorkers
+	provider.limiter = limiter
+	provider.dmaps = dmaps
+	provider.streamer = core.NewChunkedStreamer(provider, core.ChunkSizeFromConfiguration(olricConfiguration.Configuration))
+
+	// The pooled DMaps are bound to the previous client.
+	if provider.dm != nil {
+		_ = provider.Init()
+	}
+
+	return previous.Close(context.Background())
+}
+
+// readContext returns the context bounding a read.
+func (provider *Olric) readContext() (context.Context, context.CancelFunc) {
+	return provider.timeouts.ReadContext(context.Background())
+}
+
+// writeContext returns the context bounding a write or a deletion.
+func (provider *Olric) writeContext() (context.Context, context.CancelFunc) {
+	return provider.timeouts.WriteContext(context.Background())
+}
+
+// Name returns the storer name.
+func (provider *Olric) Name() string {
+	return "OLRIC"
+}
+
+// Uuid returns an unique identifier.
+func (provider *Olric) Uuid() string {
+	return provider.uuid
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Olric) ListKeys() []string {
+	keys := []string{}
+
+	if err := provider.WalkKeys(func(key string) bool {
+		keys = append(keys, key)
+
+		return true
+	}); err != nil {
+		return []string{}
+	}
+
+	return keys
+}
+
+// WalkKeys streams the keys returned by ListKeys, the mappings are scanned
+// on the cluster with an iterator.
+func (provider *Olric) WalkKeys(walkFn func(key string) bool) error {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to list the olric keys while reconnecting.")
+
+		return core.ErrReconnecting
+	}
+
+	dm, release := provider.dmap(provider.dmaps.Mappings)
+	defer release()
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	records, err := dm.Scan(ctx, olric.Match("^"+core.MappingKeyPrefix))
+	if err != nil {
+		provider.Reconnect()
+
+		provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
+
+		return err
+	}
+
+	defer records.Close()
+
+	for records.Next() {
+		for _, key := range core.MappingRealKeys(provider.Get(records.Key()), time.Now()) {
+			if !walkFn(key) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// MapKeys method returns the map of existing keys.
+func (provider *Olric) MapKeys(prefix string) map[string]string {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to list the olric keys while reconnecting.")
+
+		return map[string]string{}
+	}
+
+	keys := map[string]string{}
+
+	for _, name := range provider.dmaps.distinct() {
+		dm, release := provider.dmap(name)
+		ctx, cancel := provider.readContext()
+
+		records, err := dm.Scan(ctx)
+		if err != nil {
+			cancel()
+			release()
+			provider.Reconnect()
+
+			provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
+
+			return map[string]string{}
+		}
+
+		for records.Next() {
+			if strings.HasPrefix(records.Key(), prefix) {
+				k, _ := strings.CutPrefix(records.Key(), prefix)
+				keys[k] = string(provider.Get(records.Key()))
+			}
+		}
+
+		records.Close()
+		cancel()
+		release()
+	}
+
+	return keys
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Olric) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	res, e := dm.Get(ctx, key)
+	if e != nil {
+		return fresh, stale
+	}
+
+	val, _ := res.Byte()
+	fresh, stale, _ = core.MappingElectionFor(provider, key, val, req, validator, provider.logger)
+
+	return fresh, stale
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *Olric) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	dmap, release := provider.dmap(provider.dmaps.forKey(variedKey))
+	defer release()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into Olric, %v", variedKey, err)
+
+		return err
+	}
+
+	ctx, cancel := provider.writeContext()
+	err = dmap.Put(ctx, variedKey, compressed, olric.EX(duration))
+	cancel()
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to set value into Olric, %v", err)
+
+		return err
+	}
+
+	provider.publishEvent(core.EventSet, variedKey)
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	mappings, releaseMappings := provider.dmap(provider.dmaps.Mappings)
+	defer releaseMappings()
+
+	var evicted []string
+
+	err = core.UpdateMapping(func() error {
+		ctx, cancel := provider.writeContext()
+		defer cancel()
+
+		lock, err := mappings.LockWithTimeout(ctx, core.LockKeyPrefix+mappingKey, mappingLockTimeout, mappingLockDeadline)
+		if errors.Is(err, olric.ErrLockNotAcquired) {
+			return core.ErrMappingConflict
+		}
+
+		if err != nil {
+			provider.logger.Errorf("Impossible to lock the mapping %s in Olric, %v", mappingKey, err)
+
+			return err
 		}
-	} else if olricConfiguration.Configuration != nil {
-		tmpFile := "/tmp/" + uuid.NewString() + ".yml"
-		yamlConfig, _ := yaml.Marshal(olricConfiguration.Configuration)
 
 		defer func() {
-			if err = os.RemoveAll(tmpFile); err != nil {
-				logger.Error("Impossible to remove the temporary file")
+			if err := lock.Unlock(ctx); err != nil {
+				provider.logger.Errorf("Impossible to unlock the mapping %s in Olric, %v", mappingKey, err)
 			}
 		}()
 
-		if err = os.WriteFile(tmpFile, yamlConfig, 0o600); err != nil {
-			logger.Error("Impossible to create the embedded Olric config from the given one")
-		}
+		var val []byte
+
+		res, err := mappings.Get(ctx, mappingKey)
+		if err != nil && !errors.Is(err, olric.ErrKeyNotFound) {
+			provider.logger.Errorf("Impossible to get the key %s Olric, %v", baseKey, err)
+
+			return nil
+		}
+
+		// The mapping doesn't exist yet when the key isn't found.
+		if err == nil {
+			if val, err = res.Byte(); err != nil {
+				provider.logger.Errorf("Impossible to parse the key %s value as byte, %v", baseKey, err)
+
+				return err
+			}
+		}
+
+		val, evicted, err = core.MappingUpdaterWithEvictions(variedKey, val, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+		if err != nil {
+			return err
+		}
+
+		return provider.Set(mappingKey, val, time.Hour)
+	})
+	if err != nil {
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Olric) Get(key string) []byte {
+	val, err := provider.Lookup(key)
+	if err != nil {
+		return []byte{}
+	}
+
+	return val
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist.
+func (provider *Olric) Lookup(key string) ([]byte, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to get the olric key while reconnecting.")
+
+		return nil, core.ErrReconnecting
+	}
+
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	res, err := dm.Get(ctx, key)
+	if errors.Is(err, olric.ErrKeyNotFound) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		if !errors.Is(err, olric.ErrKeyTooLarge) {
+			provider.Reconnect()
+		}
+
+		return nil, err
+	}
+
+	return res.Byte()
+}
+
+// GetMany method returns the values of the found keys. The cluster client
+// reads them in one pipeline per DMap, a round-trip per partition owning
+// some of them, the embedded member reads them locally.
+func (provider *Olric) GetMany(keys []string) map[string][]byte {
+	values := make(map[string][]byte, len(keys))
+
+	if provider.member != nil {
+		for _, key := range keys {
+			if value, err := provider.Lookup(key); err == nil {
+				values[key] = value
+			}
+		}
+
+		return values
+	}
+
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to get the olric keys while reconnecting.")
+
+		return values
+	}
 
-		if olricInstance, err = config.Load(tmpFile); err == nil {
-			isAlreadyLoaded = true
-		} else {
-			logger.Error("Impossible to create the embedded Olric config from the given one")
-		}
+	byDMap := map[string][]string{}
+	for _, key := range keys {
+		name := provider.dmaps.forKey(key)
+		byDMap[name] = append(byDMap[name], key)
+	}
+
+	for name, dmapKeys := range byDMap {
+		provider.pipelineGet(name, dmapKeys, values)
 	}
 
-	return olricInstance, isAlreadyLoaded
+	return values
 }
 
-func newEmbeddedOlric(olricConfiguration core.CacheProvider, logger core.Logger) (*olric.EmbeddedClient, error) {
-	var olricInstance *config.Config
+// pipelineGet reads the keys of the DMap in one pipeline into values.
+func (provider *Olric) pipelineGet(name string, keys []string, values map[string][]byte) {
+	dm, release := provider.dmap(name)
+	defer release()
 
-	var loaded bool
+	pipeline, err := dm.Pipeline()
+	if err != nil {
+		provider.logger.Errorf("Impossible to create the Olric pipeline, %v", err)
 
-	if olricInstance, loaded = tryToLoadConfiguration(olricInstance, olricConfiguration, logger); !loaded {
-		olricInstance = config.New("local")
-		olricInstance.DMaps.MaxInuse = 512 << 20
+		return
 	}
 
-	started, cancel := context.WithCancel(context.Background())
-	olricInstance.Started = func() {
-		logger.Error("Embedded Olric is ready")
+	defer pipeline.Close()
 
-		defer cancel()
-	}
+	ctx, cancel := provider.readContext()
+	defer cancel()
 
-	olricDB, err := olric.New(olricInstance)
-	if err != nil {
-		return nil, err
+	futures := make([]*olric.FutureGet, len(keys))
+	for i, key := range keys {
+		futures[i] = pipeline.Get(ctx, key)
 	}
 
-	errCh := make(chan error, 1)
+	if err = pipeline.Exec(ctx); err != nil {
+		provider.Reconnect()
 
-	defer func() {
-		close(errCh)
-	}()
+		provider.logger.Errorf("Impossible to get the keys from Olric, %v", err)
 
-	go func(cdb *olric.Olric) {
-		if err = cdb.Start(); err != nil {
-			errCh <- err
+		return
+	}
+
+	for i, future := range futures {
+		res, err := future.Result()
+		if err != nil {
+			continue
 		}
-	}(olricDB)
 
-	select {
-	case err = <-errCh:
-	case <-started.Done():
+		if value, err := res.Byte(); err == nil {
+			values[keys[i]] = value
+		}
 	}
+}
 
-	dbClient := olricDB.NewEmbeddedClient()
+// Set method will store the response in Olric provider.
+func (provider *Olric) Set(key string, value []byte, duration time.Duration) error {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to set the olric value while reconnecting.")
 
-	logger.Info("Embedded Olric is ready for this node.")
+		return core.ErrReconnecting
+	}
 
-	return dbClient, nil
-}
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
 
-// Factory function create new Olric instance.
-func Factory(olricConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
-	if olricConfiguration.URL == "" && olricConfiguration.Configuration != nil {
-		if olricCfg, ok := olricConfiguration.Configuration.(map[string]interface{}); ok {
-			if mode, found := olricCfg["mode"]; found && mode.(string) == "local" {
-				logger.Debug("Olric configuration URL is empty, trying to load olric in embedded mode")
+	ctx, cancel := provider.writeContext()
+	defer cancel()
 
-				client, err := newEmbeddedOlric(olricConfiguration, logger)
-				if err != nil {
-					logger.Error("Impossible to setup Embedded Olric instance")
+	err := dm.Put(ctx, key, value, olric.EX(duration))
+	if err != nil {
+		provider.Reconnect()
 
-					return nil, err
-				}
+		provider.logger.Errorf("Impossible to set value into Olric, %v", err)
 
-				return &Olric{
-					Client:        client,
-					dm:            nil,
-					stale:         stale,
-					logger:        logger,
-					configuration: config.Client{},
-					addresses:     strings.Split(olricConfiguration.URL, ","),
-				}, nil
-			}
-		}
+		return err
 	}
 
-	client, err := olric.NewClusterClient(strings.Split(olricConfiguration.URL, ","))
-	if err != nil {
-		logger.Errorf("Impossible to connect to Olric, %v", err)
-	}
+	provider.publishEvent(core.EventSet, key)
 
-	return &Olric{
-		Client:        client,
-		dm:            nil,
-		stale:         stale,
-		logger:        logger,
-		configuration: config.Client{},
-		addresses:     strings.Split(olricConfiguration.URL, ","),
-	}, nil
+	return err
 }
 
-// Name returns the storer name.
-func (provider *Olric) Name() string {
-	return "OLRIC"
+// SetReader method will store the value read from r in chunks.
+func (provider *Olric) SetReader(key string, r io.Reader, ttl time.Duration) error {
+	return provider.streamer.SetReader(key, r, ttl)
 }
 
-// Uuid returns an unique identifier.
-func (provider *Olric) Uuid() string {
-	return fmt.Sprintf("%s-%s", provider.addresses, provider.stale)
+// GetReader method returns a reader loading the chunks on demand.
+func (provider *Olric) GetReader(key string) (io.ReadCloser, error) {
+	return provider.streamer.GetReader(key)
 }
 
-// ListKeys method returns the list of existing keys.
-func (provider *Olric) ListKeys() []string {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to list the olric keys while reconnecting.")
+// Delete method will delete the response in Olric provider if exists corresponding to key param.
+func (provider *Olric) Delete(key string) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to delete the olric key while reconnecting.")
 
-		return []string{}
+		return
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
 
-	records, err := dm.Scan(context.Background(), olric.Match("^"+core.MappingKeyPrefix))
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	_, err := dm.Delete(ctx, key)
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.logger.Errorf("Impossible to delete value into Olric, %v", err)
 
-		provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
+		return
+	}
 
-		return []string{}
+	provider.forgetEvicted(key)
+	provider.publishEvent(core.EventDelete, key)
+}
+
+// DeleteMany method will delete the responses in Olric provider if exists corresponding to the regex key param.
+func (provider *Olric) DeleteMany(key string) {
+	_, _ = provider.DeleteManyCount(key, false)
+}
+
+// deleteManyBatchSize is the number of keys removed by each Delete of
+// DeleteMany.
+const deleteManyBatchSize = 100
+
+// DeleteManyCount deletes the keys matching the regular expression, scanned
+// on the cluster with the Olric Match. The scanned keys are matched again with
+// the Go regexp, so the semantics don't depend on the cluster version. The
+// DMaps are scanned in parallel, then the matching keys are deleted in
+// batches by delete_many_workers workers, the errors of the scans and the
+// batches are joined. In dry-run the matching keys are listed without being
+// deleted.
+func (provider *Olric) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result := core.DeleteManyResult{}
+
+	rgKey, err := regexp.Compile(pattern)
+	if err != nil {
+		provider.logger.Errorf("The key %s is not a valid regexp: %v", pattern, err)
+
+		return result, err
 	}
 
-	keys := []string{}
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to delete the olric keys while reconnecting.")
 
-	for records.Next() {
-		mapping, err := core.DecodeMapping(provider.Get(records.Key()))
-		if err == nil {
-			for _, v := range mapping.GetMapping() {
-				keys = append(keys, v.GetRealKey())
-			}
-		}
+		return result, core.ErrReconnecting
 	}
 
-	records.Close()
+	var mu sync.Mutex
 
-	return keys
-}
+	matched := map[string][]string{}
+	scans := []func() error{}
 
-// MapKeys method returns the map of existing keys.
-func (provider *Olric) MapKeys(prefix string) map[string]string {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to list the olric keys while reconnecting.")
+	for _, name := range provider.dmaps.distinct() {
+		scans = append(scans, func() error {
+			keys, scanErr := provider.matchKeys(name, pattern, rgKey)
 
-		return map[string]string{}
+			mu.Lock()
+			matched[name] = keys
+			mu.Unlock()
+
+			return scanErr
+		})
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	scanErr := core.RunBounded(provider.deleteWorkers, scans)
+	if scanErr != nil {
+		provider.Reconnect()
+	}
 
-	records, err := dm.Scan(context.Background())
-	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
+	if dryRun {
+		for _, keys := range matched {
+			for _, key := range keys {
+				result.Add(key, true)
+			}
 		}
 
-		provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
+		slices.Sort(result.Keys)
 
-		return map[string]string{}
+		return result, scanErr
 	}
 
-	keys := map[string]string{}
+	deleted := []string{}
+	deletions := []func() error{}
 
-	for records.Next() {
-		if strings.HasPrefix(records.Key(), prefix) {
-			k, _ := strings.CutPrefix(records.Key(), prefix)
-			keys[k] = string(provider.Get(records.Key()))
+	for name, keys := range matched {
+		for batch := range slices.Chunk(keys, deleteManyBatchSize) {
+			deletions = append(deletions, func() error {
+				if deleteErr := provider.deleteBatch(name, batch); deleteErr != nil {
+					provider.logger.Errorf("Impossible to delete the keys matching %s in Olric, %v", pattern, deleteErr)
+
+					return deleteErr
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				deleted = append(deleted, batch...)
+
+				return nil
+			})
 		}
 	}
 
-	records.Close()
+	err = errors.Join(scanErr, core.RunBounded(provider.deleteWorkers, deletions))
 
-	return keys
+	for _, key := range deleted {
+		result.Add(key, false)
+		provider.forgetEvicted(key)
+		provider.publishEvent(core.EventDelete, key)
+	}
+
+	return result, err
 }
 
-// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
-func (provider *Olric) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+// matchKeys scans the DMap for the keys matching the pattern.
+func (provider *Olric) matchKeys(name, pattern string, rgKey *regexp.Regexp) ([]string, error) {
+	dmap, release := provider.dmap(name)
+	defer release()
 
-	res, e := dm.Get(context.Background(), key)
-	if e != nil {
-		return fresh, stale
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	records, err := dmap.Scan(ctx, olric.Match(pattern))
+	if err != nil {
+		provider.logger.Errorf("An error occurred while trying to list keys in Olric: %v", err)
+
+		return nil, err
 	}
 
-	val, _ := res.Byte()
-	fresh, stale, _ = core.MappingElection(provider, val, req, validator, provider.logger)
+	defer records.Close()
 
-	return fresh, stale
+	keys := []string{}
+
+	for records.Next() {
+		if rgKey.MatchString(records.Key()) {
+			keys = append(keys, records.Key())
+		}
+	}
+
+	return keys, nil
 }
 
-// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
-func (provider *Olric) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
-	now := time.Now()
+// deleteBatch removes the keys from the DMap.
+func (provider *Olric) deleteBatch(name string, keys []string) error {
+	dmap, release := provider.dmap(name)
+	defer release()
 
-	dmap := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dmap)
+	ctx, cancel := provider.writeContext()
+	defer cancel()
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
+	_, err := dmap.Delete(ctx, keys...)
 
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
+	return err
+}
 
-		provider.logger.Errorf("Impossible to compress the key %s into Olric, %v", variedKey, err)
+// putOptions returns the Put options expiring the value after ttl, none
+// when the ttl is zero.
+func putOptions(ttl time.Duration, options ...olric.PutOption) []olric.PutOption {
+	if ttl > 0 {
+		options = append(options, olric.PX(ttl))
+	}
 
-		return err
+	return options
+}
+
+// SetNX stores the value with the NX option only if the key doesn't exist.
+func (provider *Olric) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to set the olric value while reconnecting.")
+
+		return false, core.ErrReconnecting
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Olric, %v", variedKey, err)
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
 
-		return err
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	err := dm.Put(ctx, key, value, putOptions(ttl, olric.NX())...)
+	if errors.Is(err, olric.ErrKeyFound) {
+		return false, nil
 	}
 
-	if err := dmap.Put(context.Background(), variedKey, compressed.Bytes(), olric.EX(duration)); err != nil {
-		provider.logger.Errorf("Impossible to set value into Olric, %v", err)
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s if absent into Olric, %v", key, err)
 
-		return err
+		return false, err
 	}
 
-	mappingKey := core.MappingKeyPrefix + baseKey
+	provider.publishEvent(core.EventSet, key)
 
-	res, err := dmap.Get(context.Background(), mappingKey)
-	if err != nil && !errors.Is(err, olric.ErrKeyNotFound) {
-		provider.logger.Errorf("Impossible to get the key %s Olric, %v", baseKey, err)
+	return true, nil
+}
 
-		return nil
+// CompareAndSwap replaces the value only if the stored one equals old, the
+// key is locked meanwhile since Olric has no native compare and swap.
+func (provider *Olric) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to set the olric value while reconnecting.")
+
+		return false, core.ErrReconnecting
 	}
 
-	val, err := res.Byte()
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	lock, err := dm.LockWithTimeout(ctx, compareAndSwapLockPrefix+key, mappingLockTimeout, mappingLockDeadline)
 	if err != nil {
-		provider.logger.Errorf("Impossible to parse the key %s value as byte, %v", baseKey, err)
+		provider.logger.Errorf("Impossible to lock the key %s in Olric, %v", key, err)
 
-		return err
+		return false, err
+	}
+
+	defer func() {
+		if err := lock.Unlock(ctx); err != nil {
+			provider.logger.Errorf("Impossible to unlock the key %s in Olric, %v", key, err)
+		}
+	}()
+
+	res, err := dm.Get(ctx, key)
+	if errors.Is(err, olric.ErrKeyNotFound) {
+		return false, nil
 	}
 
-	val, err = core.MappingUpdater(variedKey, val, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
 	if err != nil {
-		return err
+		provider.logger.Errorf("Impossible to get the key %s from Olric, %v", key, err)
+
+		return false, err
+	}
+
+	current, err := res.Byte()
+	if err != nil || !bytes.Equal(current, old) {
+		return false, err
+	}
+
+	if err = dm.Put(ctx, key, value, putOptions(ttl)...); err != nil {
+		provider.logger.Errorf("Impossible to compare and swap the key %s into Olric, %v", key, err)
+
+		return false, err
 	}
 
-	return provider.Set(mappingKey, val, time.Hour)
+	provider.publishEvent(core.EventSet, key)
+
+	return true, nil
 }
 
-// Get method returns the populated response if exists, empty response then.
-func (provider *Olric) Get(key string) []byte {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to get the olric key while reconnecting.")
+// RefreshTTL extends the expiration of the key in place with the Olric
+// Expire.
+func (provider *Olric) RefreshTTL(key string, duration time.Duration) error {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to refresh the olric TTL while reconnecting.")
 
-		return []byte{}
+		return core.ErrReconnecting
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
 
-	res, err := dm.Get(context.Background(), key)
-	if err != nil {
-		if !errors.Is(err, olric.ErrKeyNotFound) && !errors.Is(err, olric.ErrKeyTooLarge) && !provider.reconnecting {
-			go provider.Reconnect()
-		}
+	ctx, cancel := provider.writeContext()
+	defer cancel()
 
-		return []byte{}
+	err := dm.Expire(ctx, key, duration)
+	if errors.Is(err, olric.ErrKeyNotFound) {
+		return core.ErrKeyNotFound
 	}
 
-	val, _ := res.Byte()
+	if err != nil {
+		provider.logger.Errorf("Impossible to refresh the TTL of the key %s into Olric, %v", key, err)
+	}
 
-	return val
+	return err
 }
 
-// Set method will store the response in Olric provider.
-func (provider *Olric) Set(key string, value []byte, duration time.Duration) error {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to set the olric value while reconnecting.")
+// GetTTL returns the remaining lifetime of the key from its Olric expiry.
+func (provider *Olric) GetTTL(key string) (time.Duration, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to get the olric TTL while reconnecting.")
 
-		return errors.New("reconnecting error")
+		return 0, core.ErrReconnecting
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
+
+	ctx, cancel := provider.readContext()
+	defer cancel()
+
+	res, err := dm.Get(ctx, key)
+	if errors.Is(err, olric.ErrKeyNotFound) {
+		return 0, core.ErrKeyNotFound
+	}
 
-	err := dm.Put(context.Background(), key, value, olric.EX(duration))
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.logger.Errorf("Impossible to get the TTL of the key %s in Olric, %v", key, err)
 
-		provider.logger.Errorf("Impossible to set value into Olric, %v", err)
+		return 0, err
+	}
 
-		return err
+	// The expiry is a unix time in milliseconds, zero without expiration.
+	var expiresAt time.Time
+	if res.TTL() > 0 {
+		expiresAt = time.UnixMilli(res.TTL())
 	}
 
-	return err
+	return core.RemainingTTL(expiresAt, time.Now())
 }
 
-// Delete method will delete the response in Olric provider if exists corresponding to key param.
-func (provider *Olric) Delete(key string) {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to delete the olric key while reconnecting.")
+// Increment adds delta to the counter with Incr then sets its expiration,
+// a zero ttl keeps the one of an existing counter.
+func (provider *Olric) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to increment the olric counter while reconnecting.")
 
-		return
+		return 0, core.ErrReconnecting
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, release := provider.dmap(provider.dmaps.forKey(key))
+	defer release()
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	counter, err := dm.Incr(ctx, key, int(delta))
+	if err == nil && ttl > 0 {
+		err = dm.Expire(ctx, key, ttl)
+	}
 
-	_, err := dm.Delete(context.Background(), key)
 	if err != nil {
-		provider.logger.Errorf("Impossible to delete value into Olric, %v", err)
+		provider.logger.Errorf("Impossible to increment the counter %s in Olric, %v", key, err)
+
+		return 0, err
 	}
+
+	provider.publishEvent(core.EventSet, key)
+
+	return int64(counter), nil
 }
 
-// DeleteMany method will delete the responses in Olric provider if exists corresponding to the regex key param.
-func (provider *Olric) DeleteMany(key string) {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to delete the olric keys while reconnecting.")
+// TryLock acquires the Olric distributed lock, it fails almost without
+// waiting if another instance holds it.
+func (provider *Olric) TryLock(key string, ttl time.Duration) (bool, error) {
+	if provider.reconnector.Reconnecting() {
+		provider.logger.Error("Impossible to acquire the olric lock while reconnecting.")
 
-		return
+		return false, core.ErrReconnecting
+	}
+
+	dm, release := provider.dmap(provider.dmaps.Values)
+	defer release()
+
+	var (
+		lock olric.LockContext
+		err  error
+	)
+
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	if ttl > 0 {
+		lock, err = dm.LockWithTimeout(ctx, core.LockKeyPrefix+key, ttl, lockAcquisitionDeadline)
+	} else {
+		lock, err = dm.Lock(ctx, core.LockKeyPrefix+key, lockAcquisitionDeadline)
 	}
 
-	dmap := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dmap)
+	if errors.Is(err, olric.ErrLockNotAcquired) {
+		return false, nil
+	}
 
-	records, err := dmap.Scan(context.Background(), olric.Match(key))
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.logger.Errorf("Impossible to acquire the lock %s in Olric, %v", key, err)
 
-		provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
+		return false, err
+	}
 
-		return
+	provider.locks.Store(key, lock)
+
+	return true, nil
+}
+
+// Unlock releases the lock if this instance still holds it.
+func (provider *Olric) Unlock(key string) error {
+	lock, held := provider.locks.LoadAndDelete(key)
+	if !held {
+		return nil
 	}
 
-	keys := []string{}
-	for records.Next() {
-		keys = append(keys, records.Key())
+	ctx, cancel := provider.writeContext()
+	defer cancel()
+
+	err := lock.(olric.LockContext).Unlock(ctx)
+	if err != nil && !errors.Is(err, olric.ErrNoSuchLock) {
+		provider.logger.Errorf("Impossible to release the lock %s in Olric, %v", key, err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Healthy requests the stats of the cluster members, the storage is healthy
+// once one of them answers.
+func (provider *Olric) Healthy(ctx context.Context) error {
+	members, err := provider.Members(ctx)
+	if err != nil {
+		return err
 	}
 
-	records.Close()
+	err = errors.New("no Olric member found")
+
+	for _, member := range members {
+		if _, err = provider.Stats(ctx, member.Name); err == nil {
+			return nil
+		}
+	}
 
-	_, _ = dmap.Delete(context.Background(), keys...)
+	return err
 }
 
 // Init method will initialize Olric provider if needed.
 func (provider *Olric) Init() error {
-	provider.dm = &sync.Pool{
-		New: func() interface{} {
-			dmap, _ := provider.NewDMap("souin-map")
+	pools := map[string]*sync.Pool{}
 
-			return dmap
-		},
+	for _, name := range provider.dmaps.distinct() {
+		pools[name] = &sync.Pool{
+			New: func() interface{} {
+				dmap, _ := provider.NewDMap(name)
+
+				return dmap
+			},
+		}
 	}
 
+	provider.dm = pools
+
 	return nil
 }
 
+// dmap returns a client of the named DMap and the function putting it back
+// in the pool.
+func (provider *Olric) dmap(name string) (olric.DMap, func()) {
+	pool := provider.dm[name]
+	dm := pool.Get().(olric.DMap)
+
+	return dm, func() {
+		pool.Put(dm)
+	}
+}
+
 // Reset method will reset or close provider.
 func (provider *Olric) Reset() error {
+	provider.reconnector.Stop()
+	provider.stopEvictionScans()
+
+	if provider.member != nil {
+		enabledEmbeddedInstances.Delete(provider.uid)
+
+		return provider.member.Shutdown(context.Background())
+	}
+
 	return provider.Close(context.Background())
 }
 
+// Reconnect starts the background reconnection unless it already runs.
 func (provider *Olric) Reconnect() {
-	provider.reconnecting = true
+	// The embedded member lives in the process, there is nothing to reconnect to.
+	if provider.member != nil {
+		return
+	}
 
-	if c, err := olric.NewClusterClient(provider.addresses, olric.WithConfig(&provider.configuration)); err == nil && c != nil {
-		provider.Client = c
-		provider.reconnecting = false
-	} else {
-		time.Sleep(10 * time.Second)
-		provider.Reconnect()
+	provider.reconnector.Trigger()
+}
+
+func (provider *Olric) connect(_ context.Context) error {
+	c, err := olric.NewClusterClient(provider.addresses, provider.clientOptions...)
+	if err != nil {
+		return err
 	}
+
+	provider.Client = c
+
+	return nil
 }
@@ -1,6 +1,13 @@
 package olric_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,13 +38,7 @@ func getOlricInstance() (core.Storer, error) {
 	return instance, nil
 }
 
-var embeddedInstance core.Storer
-
 func getEmbeddedOlricInstance() (core.Storer, error) {
-	if embeddedInstance != nil {
-		return embeddedInstance, nil
-	}
-
 	instance, err := olric.Factory(core.CacheProvider{
 		Configuration: map[string]interface{}{
 			"mode": "local",
@@ -52,8 +53,6 @@ func getEmbeddedOlricInstance() (core.Storer, error) {
 		return nil, err
 	}
 
-	embeddedInstance = instance
-
 	return instance, nil
 }
 
@@ -272,3 +271,468 @@ func TestEmbeddedOlric_Init(t *testing.T) {
 		t.Error("Impossible to init Olric provider")
 	}
 }
+
+func TestEmbeddedOlric_Flag(t *testing.T) {
+	provider := core.CacheProvider{
+		URL: "ignored:3320",
+		Configuration: map[string]interface{}{
+			"embedded":   true,
+			"compressor": "none",
+			"olricd": map[string]interface{}{
+				"bindAddr": "localhost",
+				"bindPort": 3330,
+			},
+			"memberlist": map[string]interface{}{
+				"environment": "local",
+				"bindAddr":    "localhost",
+				"bindPort":    3332,
+			},
+		},
+	}
+
+	client, err := olric.Factory(provider, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("The embedded member should start: %v", err)
+	}
+
+	defer func() {
+		_ = client.Reset()
+	}()
+
+	if client.Uuid() != core.InstanceID("olric", provider, 0) {
+		t.Errorf("The uuid should identify the embedded member configuration, %s given", client.Uuid())
+	}
+
+	if same, _ := olric.Factory(provider, zap.NewNop().Sugar(), 0); same != client {
+		t.Error("The running embedded member should be reused")
+	}
+
+	_ = client.Init()
+	_ = client.Set("Test", []byte(baseValue), 10*time.Second)
+
+	if res := client.Get("Test"); baseValue != string(res) {
+		t.Errorf("%s not corresponding to %s", res, baseValue)
+	}
+}
+
+func TestEmbeddedOlric_TryLock(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+	locker, ok := client.(core.Locker)
+	if !ok {
+		t.Fatal("Olric should implement core.Locker")
+	}
+
+	acquired, err := locker.TryLock("revalidation", 5*time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("The lock should be acquired, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); acquired {
+		t.Error("The lock should already be held")
+	}
+
+	if err = locker.Unlock("revalidation"); err != nil {
+		t.Errorf("Impossible to release the lock, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); !acquired {
+		t.Error("The lock should be acquired once released")
+	}
+
+	_ = locker.Unlock("revalidation")
+}
+
+func TestEmbeddedOlric_ConditionalSet(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+	setter, ok := client.(core.ConditionalSetter)
+	if !ok {
+		t.Fatal("Olric should implement core.ConditionalSetter")
+	}
+
+	client.Delete("leader")
+
+	if stored, err := setter.SetNX("leader", []byte("first"), 5*time.Second); err != nil || !stored {
+		t.Fatalf("The absent key should be stored, %v", err)
+	}
+
+	if stored, _ := setter.SetNX("leader", []byte("second"), 5*time.Second); stored {
+		t.Error("The existing key shouldn't be replaced")
+	}
+
+	if swapped, _ := setter.CompareAndSwap("leader", []byte("second"), []byte("third"), 5*time.Second); swapped {
+		t.Error("The value shouldn't be swapped when the old one differs")
+	}
+
+	if swapped, err := setter.CompareAndSwap("leader", []byte("first"), []byte("third"), 5*time.Second); err != nil || !swapped {
+		t.Errorf("The value should be swapped, %v", err)
+	}
+
+	if value := client.Get("leader"); string(value) != "third" {
+		t.Errorf("The swapped value should be stored, %s given", value)
+	}
+
+	client.Delete("leader")
+}
+
+func TestEmbeddedOlric_Increment(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+	counter, ok := client.(core.Counter)
+	if !ok {
+		t.Fatal("Olric should implement core.Counter")
+	}
+
+	client.Delete("hits")
+
+	if value, err := counter.Increment("hits", 2, 5*time.Second); err != nil || value != 2 {
+		t.Fatalf("The missing counter should start at zero, %d given, %v", value, err)
+	}
+
+	if value, err := counter.Increment("hits", -3, 5*time.Second); err != nil || value != -1 {
+		t.Errorf("The counter should be decremented, %d given, %v", value, err)
+	}
+
+	if value := client.Get("hits"); string(value) != "-1" {
+		t.Errorf("The counter should be stored as a decimal string, %s given", value)
+	}
+
+	client.Delete("hits")
+}
+
+func TestEmbeddedOlric_SetReader(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+
+	streamer, ok := client.(core.StreamStorer)
+	if !ok {
+		t.Fatal("Olric should implement core.StreamStorer")
+	}
+
+	// Larger than the value size limit, it must be split in chunks.
+	value := bytes.Repeat([]byte(baseValue), 200*1024)
+
+	if err := streamer.SetReader("large_value_test", bytes.NewReader(value), 20*time.Second); err != nil {
+		t.Fatalf("Impossible to stream the value: %v", err)
+	}
+
+	reader, err := streamer.GetReader("large_value_test")
+	if err != nil {
+		t.Fatalf("Impossible to get the reader: %v", err)
+	}
+
+	defer reader.Close()
+
+	res, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(res, value) {
+		t.Errorf("Expected %d bytes, got %d bytes, %v", len(value), len(res), err)
+	}
+}
+
+func TestEmbeddedOlric_WalkKeys(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+
+	walker, ok := client.(core.KeyWalker)
+	if !ok {
+		t.Fatal("Olric should implement core.KeyWalker")
+	}
+
+	for _, key := range []string{"walk-1", "walk-2", "walk-3"} {
+		if err := client.SetMultiLevel(key, key, []byte(baseValue), http.Header{}, "", time.Minute, key); err != nil {
+			t.Fatalf("Impossible to set the key %s, %v", key, err)
+		}
+	}
+
+	walked := map[string]bool{}
+
+	if err := walker.WalkKeys(func(key string) bool {
+		walked[key] = true
+
+		return true
+	}); err != nil {
+		t.Fatalf("Impossible to walk the keys, %v", err)
+	}
+
+	for _, key := range []string{"walk-1", "walk-2", "walk-3"} {
+		if !walked[key] {
+			t.Errorf("The key %s should be walked, %v given", key, walked)
+		}
+	}
+
+	count := 0
+	_ = walker.WalkKeys(func(string) bool {
+		count++
+
+		return false
+	})
+
+	if count != 1 {
+		t.Errorf("The walk should stop after the first key, %d walked", count)
+	}
+}
+
+func TestEmbeddedOlric_Reload(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+	_ = client.Set("reload", []byte(baseValue), time.Minute)
+
+	err := core.Reload(client, core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"mode":       "local",
+			"compressor": "zstd",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Impossible to reload the embedded Olric provider, %v", err)
+	}
+
+	if string(client.Get("reload")) != baseValue {
+		t.Error("The embedded member should keep its entries after the reload")
+	}
+
+	if err = core.Reload(client, core.CacheProvider{URL: "localhost:3320"}); err == nil {
+		t.Error("The embedded member shouldn't be switched to the remote mode")
+	}
+}
+
+func TestEmbeddedOlric_Healthy(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The embedded member should be healthy, %v given", err)
+	}
+}
+
+func TestEmbeddedOlric_DMaps(t *testing.T) {
+	client, err := olric.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"mode": "local",
+			"dmaps": map[string]interface{}{
+				"values":   "app-values",
+				"mappings": "app-mappings",
+			},
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the Olric provider, %v", err)
+	}
+
+	_ = client.Init()
+
+	if err = client.SetMultiLevel("dmap-base", "dmap-varied", []byte(baseValue), http.Header{}, "", time.Minute, "dmap-varied"); err != nil {
+		t.Fatalf("Impossible to set the key, %v", err)
+	}
+
+	if len(client.Get("dmap-varied")) == 0 {
+		t.Error("The value should be read from its DMap")
+	}
+
+	mappings, _ := olric.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"mode": "local",
+			"dmap": "app-mappings",
+		},
+	}, zap.NewNop().Sugar(), 0)
+	_ = mappings.Init()
+
+	if len(mappings.Get(core.MappingKeyPrefix+"dmap-base")) == 0 {
+		t.Error("The mapping should be stored in the mappings DMap")
+	}
+
+	if len(mappings.Get("dmap-varied")) != 0 {
+		t.Error("The value shouldn't be stored in the mappings DMap")
+	}
+
+	if err = olric.Validate(map[string]interface{}{"dmaps": map[string]interface{}{"value": "app"}}); err == nil {
+		t.Error("An unknown DMap kind should be invalid")
+	}
+}
+
+func TestEmbeddedOlric_SetMultiLevelConcurrently(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+
+	var wg sync.WaitGroup
+
+	for i := range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			variedKey := fmt.Sprintf("concurrent-varied-%d", i)
+			if err := client.SetMultiLevel("concurrent-base", variedKey, []byte(baseValue), http.Header{}, "", time.Minute, variedKey); err != nil {
+				t.Errorf("Impossible to set the key %s, %v", variedKey, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	mapping, err := core.DecodeMapping(client.Get(core.MappingKeyPrefix + "concurrent-base"))
+	if err != nil {
+		t.Fatalf("Impossible to decode the mapping, %v", err)
+	}
+
+	if len(mapping.GetMapping()) != 10 || mapping.GetVersion() != 10 {
+		t.Errorf("Every concurrent update should be kept, %d keys at version %d given", len(mapping.GetMapping()), mapping.GetVersion())
+	}
+}
+
+func TestEmbeddedOlric_Lookup(t *testing.T) {
+	client, _ := getEmbeddedOlricInstance()
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestOlric_ValidateClient(t *testing.T) {
+	if err := olric.Validate(map[string]interface{}{
+		"client": map[string]interface{}{
+			"dial_timeout":                 "1s",
+			"read_timeout":                 "500ms",
+			"max_retries":                  -1,
+			"pool_size":                    32,
+			"min_idle_conns":               4,
+			"routing_table_fetch_interval": "30s",
+		},
+	}); err != nil {
+		t.Errorf("The client configuration should be valid, %v given", err)
+	}
+
+	if err := olric.Validate(map[string]interface{}{"client": map[string]interface{}{"pool_size": -1}}); err == nil {
+		t.Error("A negative pool size should be invalid")
+	}
+
+	if err := olric.Validate(map[string]interface{}{"client": map[string]interface{}{"dial_timeout": "soon"}}); err == nil {
+		t.Error("A malformed duration should be invalid")
+	}
+
+	if err := olric.Validate(map[string]interface{}{"pool": map[string]interface{}{"size": 16, "min_idle": 2, "dial_backoff": "10ms"}}); err != nil {
+		t.Errorf("The pool configuration should be valid, %v given", err)
+	}
+
+	if err := olric.Validate(map[string]interface{}{"pool": map[string]interface{}{"max_retries": -2}}); err == nil {
+		t.Error("A max_retries below -1 should be invalid")
+	}
+}
+
+func TestOlric_Invalidation(t *testing.T) {
+	client, err := getEmbeddedOlricInstance()
+	if err != nil {
+		t.Fatalf("Impossible to start the embedded Olric, %v", err)
+	}
+
+	bus, ok := client.(core.InvalidationBus)
+	if !ok {
+		t.Fatal("Olric should implement core.InvalidationBus")
+	}
+
+	received := make(chan core.InvalidationEvent, 1)
+
+	unsubscribe, err := bus.SubscribeInvalidations("olric-test", func(event core.InvalidationEvent) {
+		received <- event
+	})
+	if err != nil {
+		t.Fatalf("Impossible to subscribe to the invalidations, %v", err)
+	}
+
+	defer unsubscribe()
+
+	event := core.InvalidationEvent{Kind: core.InvalidateKey, Key: byteKey, Origin: "other"}
+	if err = bus.PublishInvalidation("olric-test", event); err != nil {
+		t.Fatalf("Impossible to publish the invalidation, %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got != event {
+			t.Errorf("The published event should be received, %+v given", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("The published event should be received")
+	}
+}
+
+func TestOlric_Watch(t *testing.T) {
+	client, err := olric.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"mode":   "local",
+			"events": true,
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to start the embedded Olric, %v", err)
+	}
+
+	if err = client.Init(); err != nil {
+		t.Fatalf("Impossible to init the embedded Olric, %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.(core.Watcher).Watch(ctx, "WATCHED_")
+	if err != nil {
+		t.Fatalf("Impossible to watch the keys, %v", err)
+	}
+
+	_ = client.Set("UNWATCHED_"+byteKey, []byte(baseValue), time.Minute)
+	_ = client.Set("WATCHED_"+byteKey, []byte(baseValue), time.Minute)
+	client.Delete("WATCHED_" + byteKey)
+
+	for _, expected := range []core.EventKind{core.EventSet, core.EventDelete} {
+		select {
+		case event := <-events:
+			if event.Kind != expected || event.Key != "WATCHED_"+byteKey {
+				t.Errorf("The %s event of the watched key should be received, %+v given", expected, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The %s event wasn't received", expected)
+		}
+	}
+
+	cancel()
+
+	for range events {
+	}
+}
+
+func TestOlric_OnEvicted(t *testing.T) {
+	client, err := olric.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"mode":                   "local",
+			"eviction_scan_interval": "200ms",
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to start the embedded Olric, %v", err)
+	}
+
+	if err = client.Init(); err != nil {
+		t.Fatalf("Impossible to init the embedded Olric, %v", err)
+	}
+
+	evicted := make(chan string, 16)
+
+	client.(core.EvictionNotifier).OnEvicted(func(key string, _ []byte) {
+		evicted <- key
+	})
+
+	_ = client.Set("EXPIRED_"+byteKey, []byte(baseValue), time.Second)
+	_ = client.Set("DELETED_"+byteKey, []byte(baseValue), time.Minute)
+
+	time.Sleep(500 * time.Millisecond)
+	client.Delete("DELETED_" + byteKey)
+
+	select {
+	case key := <-evicted:
+		if key != "EXPIRED_"+byteKey {
+			t.Errorf("Only the expired key should be notified, %s given", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("The expired key should be notified")
+	}
+}
@@ -0,0 +1,61 @@
+package olric
+
+import (
+	"context"
+	"strings"
+
+	"github.com/darkweak/storages/core"
+)
+
+// publishEvent publishes the change on core.DefaultEventsChannel when the
+// events are enabled.
+func (provider *Olric) publishEvent(kind core.EventKind, key string) {
+	if !provider.events {
+		return
+	}
+
+	payload, err := core.Event{Kind: kind, Key: key}.Encode()
+	if err != nil {
+		return
+	}
+
+	pubsub, err := provider.NewPubSub()
+	if err == nil {
+		ctx, cancel := provider.writeContext()
+		_, err = pubsub.Publish(ctx, core.DefaultEventsChannel, string(payload))
+
+		cancel()
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to publish the %s event of the key %s on Olric, %v", kind, key, err)
+	}
+}
+
+// Watch streams the changes of the keys starting with the prefix, published
+// with the Olric PubSub, the successor of the DTopics, by the instances
+// whose events key is enabled. The events don't carry the values and Olric
+// doesn't notify the expirations.
+func (provider *Olric) Watch(ctx context.Context, prefix string) (<-chan core.Event, error) {
+	events := make(chan core.Event, core.EventsBufferSize)
+
+	err := provider.subscribe(ctx, core.DefaultEventsChannel, func(payload []byte) {
+		event, err := core.DecodeEvent(payload)
+		if err != nil {
+			provider.logger.Errorf("Impossible to decode the Olric event, %v", err)
+
+			return
+		}
+
+		if strings.HasPrefix(event.Key, prefix) {
+			core.SendEvent(ctx, events, event)
+		}
+	}, func() {
+		close(events)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -3,75 +3,330 @@ package otter
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/darkweak/storages/core"
+	"github.com/dustin/go-humanize"
 	"github.com/maypok86/otter"
-	"github.com/pierrec/lz4/v4"
 )
 
 // Otter provider type.
 type Otter struct {
 	cache       *otter.CacheWithVariableTTL[string, []byte]
 	stale       time.Duration
+	uuid        string
 	logger      core.Logger
-	instanceKey int
+	compressor  core.Compressor
+	instanceKey string
+	priorities  *priorityRules
+	counters    sync.Mutex
+
+	persistPath     string
+	persistInterval time.Duration
+	limiter         *core.BackgroundLimiter
+	stop            chan struct{}
+	stopped         chan struct{}
 }
 
-var instanceMap = sync.Map{}
+// defaultPersistInterval is the delay between two flushes of the cache to
+// the persist_path file.
+const defaultPersistInterval = time.Minute
+
+// counterRetention is the ttl of the counters stored without expiration,
+// Otter always expires its entries.
+const counterRetention = 100 * 365 * 24 * time.Hour
+
+// entryOverhead approximates the memory used by an entry besides its key and
+// value when the cache is sized in bytes.
+const entryOverhead = 64
+
+var (
+	instanceMap = sync.Map{}
+	// evictionCallbacks holds the core.EvictionCallbacks of each shared cache
+	// by instance key.
+	evictionCallbacks = sync.Map{}
+)
+
+// evictionCallbacksFor returns the callbacks of the cache.
+func evictionCallbacksFor(instanceKey string) *core.EvictionCallbacks {
+	callbacks, _ := evictionCallbacks.LoadOrStore(instanceKey, &core.EvictionCallbacks{})
+
+	return callbacks.(*core.EvictionCallbacks)
+}
+
+// evictionListener notifies the callbacks of the cache with the expired and
+// the evicted entries.
+func evictionListener(instanceKey string) func(key string, value []byte, cause otter.DeletionCause) {
+	return func(key string, value []byte, cause otter.DeletionCause) {
+		if cause != otter.Replaced {
+			forgetPriority(instanceKey, key)
+		}
+
+		if cause != otter.Expired && cause != otter.Size {
+			return
+		}
+
+		if callbacks, ok := evictionCallbacks.Load(instanceKey); ok {
+			callbacks.(*core.EvictionCallbacks).Notify(key, value)
+		}
+	}
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("otter", Factory)
+}
+
+// configuration is the typed Otter provider configuration.
+type configuration struct {
+	// Size is the maximum number of entries.
+	Size int `json:"size"`
+	// MaxBytes is the maximum size of the stored keys and values ("256MB"),
+	// it replaces Size when set.
+	MaxBytes string `json:"max_bytes"`
+	// PersistPath is the file the entries are flushed to periodically and
+	// restored from at Init, the cache isn't persisted when empty.
+	PersistPath string `json:"persist_path"`
+	// PersistInterval is the delay between two flushes.
+	PersistInterval time.Duration `json:"persist_interval"`
+	// Priorities weigh the cost of the entries so the low priority ones are
+	// evicted first under memory pressure.
+	Priorities priorityConfiguration `json:"priorities"`
+}
+
+func parseConfiguration(otterConfiguration any) (configuration, int, error) {
+	cfg := configuration{Size: 10_000, PersistInterval: defaultPersistInterval}
+
+	if err := core.DecodeConfiguration(otterConfiguration, &cfg); err != nil {
+		return cfg, 0, fmt.Errorf("invalid otter configuration: %w", err)
+	}
+
+	if cfg.Size <= 0 {
+		return cfg, 0, fmt.Errorf("invalid otter configuration: the size must be positive, %d given", cfg.Size)
+	}
+
+	if cfg.PersistInterval <= 0 {
+		return cfg, 0, fmt.Errorf("invalid otter configuration: the persist_interval must be positive, %s given", cfg.PersistInterval)
+	}
+
+	if _, err := newPriorityRules(cfg.Priorities); err != nil {
+		return cfg, 0, err
+	}
+
+	if cfg.MaxBytes == "" {
+		return cfg, 0, nil
+	}
+
+	maxBytes, err := humanize.ParseBytes(cfg.MaxBytes)
+	if err != nil {
+		return cfg, 0, fmt.Errorf("invalid otter configuration: max_bytes: %w", err)
+	}
+
+	if maxBytes == 0 || maxBytes > math.MaxInt {
+		return cfg, 0, fmt.Errorf("invalid otter configuration: the max_bytes must be positive, %s given", cfg.MaxBytes)
+	}
+
+	return cfg, int(maxBytes), nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(otterConfiguration any) error {
+	_, _, err := parseConfiguration(otterConfiguration)
+
+	return err
+}
+
+// entryCost is the cost of an entry when the cache is sized in bytes.
+func entryCost(key string, value []byte) uint32 {
+	cost := len(key) + len(value) + entryOverhead
+	if cost > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	return uint32(cost)
+}
+
+// cacheSettings returns the capacity, the instance key and the cost function
+// matching the configuration. The capacity of a weighed cache is scaled by
+// the weight of the normal priority, so it holds as many normal entries.
+func cacheSettings(cfg configuration, maxBytes int) (int, string, func(string, []byte) uint32) {
+	capacity, instanceKey, cost := maxBytes, fmt.Sprintf("bytes-%d", maxBytes), entryCost
+	if maxBytes <= 0 {
+		capacity, instanceKey, cost = cfg.Size, fmt.Sprintf("size-%d", cfg.Size), func(string, []byte) uint32 {
+			return 1
+		}
+	}
+
+	if !cfg.Priorities.enabled() {
+		return capacity, instanceKey, cost
+	}
+
+	instanceKey += "-weighed"
+
+	return capacity * int(PriorityNormal.weight()), instanceKey, weighedCost(instanceKey, cost)
+}
 
 // Factory function create new Otter instance.
 func Factory(otterCfg core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
-	defaultStorageSize := 10_000
 	otterConfiguration := otterCfg.Configuration
 
-	if otterConfiguration != nil {
-		if oc, ok := otterConfiguration.(map[string]interface{}); ok {
-			if v, found := oc["size"]; found && v != nil {
-				val, _ := strconv.Atoi(fmt.Sprint(v))
-				if val > 0 {
-					defaultStorageSize = val
-				} else if val, ok := v.(string); ok {
-					intVal, _ := strconv.Atoi(val)
-					if intVal > 0 {
-						defaultStorageSize = intVal
-					}
-				}
-			}
-		}
+	cfg, maxBytes, err := parseConfiguration(otterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultStorageSize, instanceKey, cost := cacheSettings(cfg, maxBytes)
+
+	compressor, err := core.CompressorFromConfiguration(otterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(otterConfiguration)
+	if err != nil {
+		return nil, err
 	}
 
-	if instance, ok := instanceMap.Load(defaultStorageSize); ok && instance != nil {
+	priorities, err := newPriorityRules(cfg.Priorities)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance, ok := instanceMap.Load(instanceKey); ok && instance != nil {
 		cache := instance.(otter.CacheWithVariableTTL[string, []byte])
 
 		return &Otter{
-			cache:       &cache,
-			stale:       stale,
-			logger:      logger,
-			instanceKey: defaultStorageSize,
+			cache:           &cache,
+			stale:           stale,
+			uuid:            core.InstanceID("otter", otterCfg, stale),
+			logger:          logger,
+			compressor:      compressor,
+			instanceKey:     instanceKey,
+			priorities:      priorities,
+			persistPath:     cfg.PersistPath,
+			persistInterval: cfg.PersistInterval,
+			limiter:         limiter,
 		}, nil
 	}
 
 	cache, err := otter.MustBuilder[string, []byte](defaultStorageSize).
 		CollectStats().
-		Cost(func(key string, value []byte) uint32 {
-			return 1
-		}).
+		Cost(cost).
+		DeletionListener(evictionListener(instanceKey)).
 		WithVariableTTL().
 		Build()
 	if err != nil {
 		logger.Error("Impossible to instantiate the Otter DB.", err)
 	}
 
-	instanceMap.Store(defaultStorageSize, cache)
+	instanceMap.Store(instanceKey, cache)
 	logger.Infof("otter.storage.size %d", defaultStorageSize)
 
-	return &Otter{cache: &cache, logger: logger, stale: stale, instanceKey: defaultStorageSize}, nil
+	return &Otter{
+		cache:           &cache,
+		logger:          logger,
+		stale:           stale,
+		uuid:            core.InstanceID("otter", otterCfg, stale),
+		compressor:      compressor,
+		instanceKey:     instanceKey,
+		priorities:      priorities,
+		persistPath:     cfg.PersistPath,
+		persistInterval: cfg.PersistInterval,
+		limiter:         limiter,
+	}, nil
+}
+
+// Reload applies the new size, compressor and background rate limit, the
+// persistence settings are kept until the next restart. A resized cache is
+// rebuilt and the entries are copied with their remaining TTL, the ones
+// exceeding the new capacity are evicted.
+func (provider *Otter) Reload(otterCfg core.CacheProvider) error {
+	cfg, maxBytes, err := parseConfiguration(otterCfg.Configuration)
+	if err != nil {
+		return err
+	}
+
+	compressor, err := core.CompressorFromConfiguration(otterCfg.Configuration)
+	if err != nil {
+		return err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(otterCfg.Configuration)
+	if err != nil {
+		return err
+	}
+
+	priorities, err := newPriorityRules(cfg.Priorities)
+	if err != nil {
+		return err
+	}
+
+	provider.limiter = limiter
+
+	size, instanceKey, cost := cacheSettings(cfg, maxBytes)
+	if instanceKey == provider.instanceKey {
+		provider.compressor = compressor
+		provider.priorities = priorities
+
+		return nil
+	}
+
+	cache, err := otter.MustBuilder[string, []byte](size).
+		CollectStats().
+		Cost(cost).
+		DeletionListener(evictionListener(instanceKey)).
+		WithVariableTTL().
+		Build()
+	if err != nil {
+		provider.logger.Errorf("Impossible to instantiate the Otter DB, %v", err)
+
+		return err
+	}
+
+	// The copied entries keep their priority.
+	if stored, found := priorityStores.Load(provider.instanceKey); found && priorities != nil {
+		priorityStores.Store(instanceKey, stored)
+	}
+
+	previous := provider.cache
+	previous.Range(func(key string, value []byte) bool {
+		if entry, found := previous.Extension().GetEntryQuietly(key); found && entry.TTL() > 0 {
+			cache.Set(key, value, entry.TTL())
+		}
+
+		return true
+	})
+
+	evictionCallbacks.Store(instanceKey, evictionCallbacksFor(provider.instanceKey))
+	instanceMap.Store(instanceKey, cache)
+	instanceMap.Delete(provider.instanceKey)
+	evictionCallbacks.Delete(provider.instanceKey)
+	priorityStores.Delete(provider.instanceKey)
+
+	provider.cache = &cache
+	provider.compressor = compressor
+	provider.priorities = priorities
+	provider.instanceKey = instanceKey
+	previous.Clear()
+
+	provider.logger.Infof("otter.storage.size %d", size)
+
+	return nil
+}
+
+// OnEvicted registers fn for the entries expired or evicted by the size
+// constraints of the cache, it's shared by the providers of the same size
+// and kept across the reloads. The values are the stored ones.
+func (provider *Otter) OnEvicted(fn func(key string, value []byte)) {
+	evictionCallbacksFor(provider.instanceKey).Add(fn)
 }
 
 // Name returns the storer name.
@@ -81,7 +336,7 @@ func (provider *Otter) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Otter) Uuid() string {
-	return fmt.Sprint(provider.stale)
+	return provider.uuid
 }
 
 // MapKeys method returns a map with the key and value.
@@ -120,14 +375,66 @@ func (provider *Otter) ListKeys() []string {
 	return keys
 }
 
+// WalkEntries streams every entry with its remaining TTL, zero when the
+// entry never expires. The TTL has a second granularity.
+func (provider *Otter) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	extension := provider.cache.Extension()
+
+	provider.cache.Range(func(key string, _ []byte) bool {
+		entry, found := extension.GetEntryQuietly(key)
+		if !found {
+			return true
+		}
+
+		ttl := entry.TTL()
+		if ttl == 0 {
+			return true
+		}
+
+		return walkFn(key, entry.Value(), max(ttl, 0))
+	})
+
+	return nil
+}
+
+// Snapshot writes every entry to w as a core.Dump export, so the cache
+// survives the process restarts.
+func (provider *Otter) Snapshot(w io.Writer) error {
+	_, err := core.Dump(provider, w)
+
+	return err
+}
+
+// Restore stores the entries of a Snapshot with their remaining TTL, the
+// current entries are kept.
+func (provider *Otter) Restore(r io.Reader) error {
+	_, err := core.Warmup(provider, r)
+
+	return err
+}
+
 // Get method returns the populated response if exists, empty response then.
 func (provider *Otter) Get(key string) []byte {
+	result, _ := provider.Lookup(key)
+
+	return result
+}
+
+// Lookup method returns a copy of the stored value, core.ErrKeyNotFound when
+// the key doesn't exist or is expired.
+func (provider *Otter) Lookup(key string) ([]byte, error) {
 	result, found := provider.cache.Get(key)
 	if !found {
-		provider.logger.Debugf("Impossible to get the key %s in Otter", key)
+		return nil, core.ErrKeyNotFound
 	}
 
-	return result
+	return bytes.Clone(result), nil
+}
+
+// GetNoCopy method returns the stored value itself, shared with the cache:
+// it must only be read, see core.NoCopyGetter.
+func (provider *Otter) GetNoCopy(key string) ([]byte, bool) {
+	return provider.cache.Get(key)
 }
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
@@ -139,7 +446,7 @@ func (provider *Otter) GetMultiLevel(key string, req *http.Request, validator *c
 		return
 	}
 
-	fresh, stale, _ = core.MappingElection(provider, val, req, validator, provider.logger)
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
 
 	return
 }
@@ -148,24 +455,16 @@ func (provider *Otter) GetMultiLevel(key string, req *http.Request, validator *c
 func (provider *Otter) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
 		provider.logger.Errorf("Impossible to compress the key %s into Otter, %v", variedKey, err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Otter, %v", variedKey, err)
-
-		return err
-	}
+	provider.setPriority(variedKey, value)
 
-	inserted := provider.cache.Set(variedKey, compressed.Bytes(), duration)
+	inserted := provider.cache.Set(variedKey, compressed, duration+provider.stale)
 	if !inserted {
 		provider.logger.Errorf("Impossible to set value into Otter, too large for the cost function")
 
@@ -175,7 +474,7 @@ func (provider *Otter) SetMultiLevel(baseKey, variedKey string, value []byte, va
 	mappingKey := core.MappingKeyPrefix + baseKey
 	item, _ := provider.cache.Get(mappingKey)
 
-	val, e := core.MappingUpdater(variedKey, item, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	val, evicted, e := core.MappingUpdaterWithEvictions(variedKey, item, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
 	if e != nil {
 		return e
 	}
@@ -187,6 +486,8 @@ func (provider *Otter) SetMultiLevel(baseKey, variedKey string, value []byte, va
 		return fmt.Errorf("impossible to generate the duration: %w", err)
 	}
 
+	provider.setPriority(mappingKey, nil)
+
 	inserted = provider.cache.Set(mappingKey, val, negativeNow)
 	if !inserted {
 		provider.logger.Errorf("Impossible to set value into Otter, too large for the cost function")
@@ -194,11 +495,15 @@ func (provider *Otter) SetMultiLevel(baseKey, variedKey string, value []byte, va
 		return nil
 	}
 
+	core.DeleteEvictedVariants(provider, evicted)
+
 	return nil
 }
 
 // Set method will store the response in Otter provider.
 func (provider *Otter) Set(key string, value []byte, duration time.Duration) error {
+	provider.setPriority(key, value)
+
 	inserted := provider.cache.Set(key, value, duration)
 	if !inserted {
 		provider.logger.Errorf("Impossible to set value into Otter, too large for the cost function")
@@ -207,6 +512,61 @@ func (provider *Otter) Set(key string, value []byte, duration time.Duration) err
 	return nil
 }
 
+// RefreshTTL stores the value of the key again for the duration, Otter can't
+// extend an expiration in place. The value isn't compressed again.
+func (provider *Otter) RefreshTTL(key string, duration time.Duration) error {
+	value, found := provider.cache.Get(key)
+	if !found {
+		return core.ErrKeyNotFound
+	}
+
+	if !provider.cache.Set(key, value, duration) {
+		provider.logger.Errorf("Impossible to refresh the TTL of the key %s into Otter, too large for the cost function", key)
+	}
+
+	return nil
+}
+
+// GetTTL returns the remaining lifetime of the key from its entry, with a
+// second granularity.
+func (provider *Otter) GetTTL(key string) (time.Duration, error) {
+	entry, found := provider.cache.Extension().GetEntryQuietly(key)
+	if !found || entry.HasExpired() {
+		return 0, core.ErrKeyNotFound
+	}
+
+	var expiresAt time.Time
+	if entry.Expiration() > 0 {
+		expiresAt = time.Unix(entry.Expiration(), 0)
+	}
+
+	return core.RemainingTTL(expiresAt, time.Now())
+}
+
+// Increment adds delta to the counter, the increments are serialized by a
+// lock held while the counter is read and stored back.
+func (provider *Otter) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	provider.counters.Lock()
+	defer provider.counters.Unlock()
+
+	current, _ := provider.cache.Get(key)
+
+	counter, err := core.AddToCounter(key, current, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl <= 0 {
+		ttl = counterRetention
+	}
+
+	if !provider.cache.Set(key, core.EncodeCounter(counter), ttl) {
+		return 0, fmt.Errorf("impossible to set the counter %s into Otter, too large for the cost function", key)
+	}
+
+	return counter, nil
+}
+
 // Delete method will delete the response in Otter provider if exists corresponding to key param.
 func (provider *Otter) Delete(key string) {
 	provider.cache.Delete(key)
@@ -224,13 +584,119 @@ func (provider *Otter) DeleteMany(key string) {
 	})
 }
 
-// Init method will.
+// Stats returns the otter cache stats, the bytes count the stored keys and
+// values.
+func (provider *Otter) Stats() core.StorerStats {
+	otterStats := provider.cache.Stats()
+	stats := core.NewStorerStats(otterStats.Hits(), otterStats.Misses())
+	stats.Entries = int64(provider.cache.Size())
+	stats.Evictions = otterStats.EvictedCount()
+
+	provider.cache.Range(func(key string, value []byte) bool {
+		stats.Bytes += int64(len(key) + len(value))
+
+		return true
+	})
+
+	return stats
+}
+
+// Init method will restore the persist_path file when it exists and start
+// the periodic flush of the cache to it.
 func (provider *Otter) Init() error {
+	if provider.persistPath == "" || provider.stop != nil {
+		return nil
+	}
+
+	if err := provider.restoreFile(); err != nil {
+		provider.logger.Errorf("Impossible to restore the Otter cache from %s, %v", provider.persistPath, err)
+	}
+
+	provider.stop = make(chan struct{})
+	provider.stopped = make(chan struct{})
+
+	go func(stop, stopped chan struct{}) {
+		ticker := time.NewTicker(provider.persistInterval)
+		defer ticker.Stop()
+		defer close(stopped)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(stop, "persist") {
+					return
+				}
+
+				if err := provider.Persist(); err != nil {
+					provider.logger.Errorf("Impossible to persist the Otter cache to %s, %v", provider.persistPath, err)
+				}
+			}
+		}
+	}(provider.stop, provider.stopped)
+
 	return nil
 }
 
-// Reset method will reset or close provider.
+// restoreFile restores the entries of the persist_path file, a missing file
+// is an empty cache.
+func (provider *Otter) restoreFile() error {
+	file, err := os.Open(provider.persistPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	return provider.Restore(file)
+}
+
+// Persist writes a Snapshot of the cache to the persist_path file. The
+// snapshot is written to a temporary file renamed once complete, so a crash
+// never leaves a truncated file behind.
+func (provider *Otter) Persist() error {
+	if provider.persistPath == "" {
+		return nil
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(provider.persistPath), filepath.Base(provider.persistPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	if err = provider.Snapshot(file); err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), provider.persistPath)
+}
+
+// Reset method will reset or close provider, the cache is flushed a last
+// time to the persist_path file before being cleared.
 func (provider *Otter) Reset() error {
+	if provider.stop != nil {
+		close(provider.stop)
+		<-provider.stopped
+		provider.stop = nil
+
+		if err := provider.Persist(); err != nil {
+			provider.logger.Errorf("Impossible to persist the Otter cache to %s, %v", provider.persistPath, err)
+		}
+	}
+
 	provider.cache.Clear()
 
 	// Only delete this instance from the cache
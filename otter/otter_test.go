@@ -2,11 +2,17 @@ package otter_test
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
 	"github.com/darkweak/storages/otter"
 	"github.com/pierrec/lz4/v4"
 	"go.uber.org/zap"
@@ -183,3 +189,398 @@ func TestOtter_SetMultiLevel_LargeValue(t *testing.T) {
 			len(largeValue), len(retrieved), float64(len(retrieved))/float64(len(largeValue))*100)
 	}
 }
+
+func TestOtter_NewStorer(t *testing.T) {
+	instance, err := core.NewStorer("otter", core.CacheProvider{}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("The otter storage should be registered: %v", err)
+	}
+
+	if instance.Name() != "OTTER" {
+		t.Errorf("The otter storage should be returned, %s given", instance.Name())
+	}
+}
+
+func TestOtter_Validate(t *testing.T) {
+	if err := otter.Validate(map[string]interface{}{"size": "1000"}); err != nil {
+		t.Errorf("The size given as a string should be valid, %v given", err)
+	}
+
+	if err := otter.Validate(map[string]interface{}{"size": -1}); err == nil {
+		t.Error("A negative size should be invalid")
+	}
+
+	if err := otter.Validate(map[string]interface{}{"sise": 1000}); err == nil || !strings.Contains(err.Error(), "sise") {
+		t.Errorf("The unknown key should be reported, %v given", err)
+	}
+
+	if _, err := otter.Factory(core.CacheProvider{Configuration: map[string]interface{}{"size": "abc"}}, zap.NewNop().Sugar(), 0); err == nil {
+		t.Error("The factory should reject the invalid configuration")
+	}
+}
+
+func TestOtter_MaxBytes(t *testing.T) {
+	client, err := otter.Factory(core.CacheProvider{Configuration: map[string]interface{}{"max_bytes": "4KB"}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create otter instance: %v", err)
+	}
+
+	_ = client.Init()
+
+	if err = client.Set(byteKey, []byte(baseValue), time.Minute); err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("%s not corresponding to %s", client.Get(byteKey), baseValue)
+	}
+
+	_ = client.Set("large", bytes.Repeat([]byte("a"), 8<<10), time.Minute)
+
+	if client.Get("large") != nil {
+		t.Error("A value larger than max_bytes shouldn't be stored")
+	}
+
+	if err = otter.Validate(map[string]interface{}{"max_bytes": "many"}); err == nil {
+		t.Error("A malformed max_bytes should be invalid")
+	}
+}
+
+func TestOtter_Priorities(t *testing.T) {
+	client, err := otter.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"max_bytes":  "4KB",
+		"priorities": map[string]interface{}{"high": []string{"^static-"}, "low": []string{"^search-"}},
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create otter instance: %v", err)
+	}
+
+	provider, _ := client.(*otter.Otter)
+	value := bytes.Repeat([]byte("a"), 300)
+
+	for key, priority := range map[string]otter.Priority{"static-asset": otter.PriorityHigh, "page": otter.PriorityNormal, "search-results": otter.PriorityLow} {
+		_ = client.Set(key, value, time.Minute)
+
+		if provider.Priority(key) != priority {
+			t.Errorf("The key %s should be %s priority, %s given", key, priority, provider.Priority(key))
+		}
+
+		stored := client.Get(key) != nil
+		if stored == (priority == otter.PriorityLow) {
+			t.Errorf("Only the low priority value should weigh more than an entry can, %s stored: %v", key, stored)
+		}
+
+		client.Delete(key)
+	}
+
+	response := []byte("HTTP/1.1 200 OK\r\nCache-Control: public, max-age=31536000, immutable\r\nContent-Length: 2\r\n\r\nOK")
+	_ = client.SetMultiLevel("asset", "asset-varied", response, http.Header{}, "", time.Minute, "asset")
+
+	if provider.Priority("asset-varied") != otter.PriorityHigh || provider.Priority(core.MappingKeyPrefix+"asset") != otter.PriorityHigh {
+		t.Error("The immutable responses and the mappings should be high priority")
+	}
+
+	if err = otter.Validate(map[string]interface{}{"priorities": map[string]interface{}{"low": []string{"("}}}); err == nil {
+		t.Error("A malformed priority pattern should be invalid")
+	}
+}
+
+func TestOtter_Reload(t *testing.T) {
+	client, err := otter.Factory(core.CacheProvider{Configuration: map[string]interface{}{"size": 123}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create otter instance: %v", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if err = core.Reload(client, core.CacheProvider{Configuration: map[string]interface{}{"size": 456}}); err != nil {
+		t.Fatalf("Failed to reload the otter instance: %v", err)
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should be kept after the reload", byteKey)
+	}
+
+	if err = core.Reload(client, core.CacheProvider{Configuration: map[string]interface{}{"size": -1}}); err == nil {
+		t.Error("A malformed configuration shouldn't be applied")
+	}
+}
+
+func TestOtter_Lookup(t *testing.T) {
+	client, _ := getOtterInstance()
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestOtter_GetNoCopy(t *testing.T) {
+	client, _ := getOtterInstance()
+	getter, ok := client.(core.NoCopyGetter)
+	if !ok {
+		t.Fatal("Otter should implement core.NoCopyGetter")
+	}
+
+	_ = client.Set("nocopy", []byte(baseValue), time.Minute)
+
+	shared, found := getter.GetNoCopy("nocopy")
+	if !found || string(shared) != baseValue {
+		t.Fatalf("The stored value should be returned, %s given", shared)
+	}
+
+	if again, _ := getter.GetNoCopy("nocopy"); &again[0] != &shared[0] {
+		t.Error("The value shouldn't be copied")
+	}
+
+	copied := client.Get("nocopy")
+	copied[0] = 'X'
+
+	if again, _ := getter.GetNoCopy("nocopy"); string(again) != baseValue {
+		t.Errorf("Get should return a copy owned by the caller, %s stored", again)
+	}
+
+	if _, found = getter.GetNoCopy(nonExistentKey); found {
+		t.Error("A missing key shouldn't be found")
+	}
+
+	client.Delete("nocopy")
+}
+
+func TestOtter_Increment(t *testing.T) {
+	client, _ := getOtterInstance()
+	counter, ok := client.(core.Counter)
+	if !ok {
+		t.Fatal("Otter should implement core.Counter")
+	}
+
+	var wg sync.WaitGroup
+
+	for range 50 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = counter.Increment("hits", 2, time.Minute)
+		}()
+	}
+
+	wg.Wait()
+
+	if value, err := counter.Increment("hits", -1, 0); err != nil || value != 99 {
+		t.Errorf("The concurrent increments should all be counted, %d given, %v", value, err)
+	}
+
+	if value := client.Get("hits"); string(value) != "99" {
+		t.Errorf("The counter should be stored as a decimal string, %s given", value)
+	}
+
+	client.Delete("hits")
+}
+
+func TestOtter_RefreshTTL(t *testing.T) {
+	client, _ := getOtterInstance()
+
+	_ = client.Set("refreshed", []byte(baseValue), 100*time.Millisecond)
+
+	if err := core.RefreshTTL(client, "refreshed", time.Minute); err != nil {
+		t.Fatalf("The TTL should be refreshed, %v given", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if value := client.Get("refreshed"); string(value) != baseValue {
+		t.Errorf("The refreshed key should be kept with its value, %s given", value)
+	}
+
+	if err := core.RefreshTTL(client, nonExistentKey, time.Minute); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	client.Delete("refreshed")
+}
+
+func TestOtter_GetTTL(t *testing.T) {
+	client, _ := getOtterInstance()
+	_ = client.Set("ttl", []byte(baseValue), time.Minute)
+
+	if ttl, err := core.GetTTL(client, "ttl"); err != nil || ttl <= 58*time.Second || ttl > time.Minute {
+		t.Errorf("The remaining lifetime should be returned, %s and %v given", ttl, err)
+	}
+
+	if _, err := core.GetTTL(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	client.Delete("ttl")
+}
+
+func TestOtter_Stats(t *testing.T) {
+	client, err := otter.Factory(core.CacheProvider{Configuration: map[string]interface{}{"size": 20}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create otter instance: %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Get(byteKey)
+	_ = client.Get(nonExistentKey)
+
+	stats, found := core.Stats(client)
+	if !found {
+		t.Fatal("The otter storer should report its stats")
+	}
+
+	if stats.Entries != 1 || stats.Bytes < int64(len(byteKey)) || stats.Hits != 1 || stats.Misses != 1 || stats.HitRatio != 0.5 {
+		t.Errorf("Unexpected stats, %+v given", stats)
+	}
+
+	for i := range 100 {
+		_ = client.Set(fmt.Sprintf("key-%d", i), []byte(baseValue), time.Minute)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if stats, _ = core.Stats(client); stats.Evictions == 0 || stats.Entries > 20 {
+		t.Errorf("The keys exceeding the size should be evicted, %+v given", stats)
+	}
+}
+
+func TestOtter_SnapshotRestore(t *testing.T) {
+	client, _ := getOtterInstance()
+	_ = client.Set("SNAPSHOT_KEY", []byte(baseValue), time.Minute)
+
+	var snapshot bytes.Buffer
+
+	if err := core.Snapshot(client, &snapshot); err != nil {
+		t.Fatalf("Impossible to snapshot the cache, %v", err)
+	}
+
+	if !bytes.Contains(snapshot.Bytes(), []byte(`"key":"SNAPSHOT_KEY"`)) {
+		t.Fatalf("The key should be in the snapshot, %s given", snapshot.String())
+	}
+
+	client.Delete("SNAPSHOT_KEY")
+
+	if err := core.Restore(client, &snapshot); err != nil {
+		t.Fatalf("Impossible to restore the cache, %v", err)
+	}
+
+	if string(client.Get("SNAPSHOT_KEY")) != baseValue {
+		t.Errorf("The snapshot key should be restored, %s given", client.Get("SNAPSHOT_KEY"))
+	}
+}
+
+func TestOtter_Persist(t *testing.T) {
+	configuration := core.CacheProvider{Configuration: map[string]interface{}{
+		"size":             321,
+		"persist_path":     filepath.Join(t.TempDir(), "otter.cache"),
+		"persist_interval": "10ms",
+	}}
+
+	client, _ := otter.Factory(configuration, zap.NewNop().Sugar(), 0)
+	if err := client.Init(); err != nil {
+		t.Fatalf("Impossible to init the cache, %v", err)
+	}
+
+	_ = client.Set("PERSISTED_KEY", []byte(baseValue), time.Minute)
+	// Otter expires the entries with a second granularity, wait past it.
+	_ = client.Set("EXPIRED_KEY", []byte(baseValue), time.Second)
+
+	time.Sleep(2 * time.Second)
+
+	if err := client.Reset(); err != nil {
+		t.Fatalf("Impossible to reset the cache, %v", err)
+	}
+
+	client, _ = otter.Factory(configuration, zap.NewNop().Sugar(), 0)
+	if len(client.Get("PERSISTED_KEY")) != 0 {
+		t.Fatal("The cache shouldn't be restored before Init")
+	}
+
+	_ = client.Init()
+	defer func() { _ = client.Reset() }()
+
+	if string(client.Get("PERSISTED_KEY")) != baseValue {
+		t.Errorf("The persisted key should be restored at Init, %s given", client.Get("PERSISTED_KEY"))
+	}
+
+	if len(client.Get("EXPIRED_KEY")) != 0 {
+		t.Error("The expired key shouldn't be restored")
+	}
+
+	if err := otter.Validate(map[string]interface{}{"persist_interval": "-1s"}); err == nil {
+		t.Error("The negative persist_interval should be rejected")
+	}
+}
+
+func TestOtter_MaxVariants(t *testing.T) {
+	core.SetMaxVariants(1)
+	defer core.SetMaxVariants(0)
+
+	client, _ := getOtterInstance()
+
+	_ = client.SetMultiLevel("VARIANTS_BASE", "VARIANTS_FIRST", []byte(baseValue), http.Header{}, "", time.Minute, "VARIANTS_FIRST")
+	time.Sleep(time.Millisecond)
+	_ = client.SetMultiLevel("VARIANTS_BASE", "VARIANTS_SECOND", []byte(baseValue), http.Header{}, "", time.Minute, "VARIANTS_SECOND")
+
+	if len(client.Get("VARIANTS_FIRST")) != 0 {
+		t.Error("The value of the evicted variant should be deleted")
+	}
+
+	if len(client.Get("VARIANTS_SECOND")) == 0 {
+		t.Error("The value of the newest variant should be kept")
+	}
+}
+
+func TestOtter_OnEvicted(t *testing.T) {
+	client, err := otter.Factory(core.CacheProvider{Configuration: map[string]interface{}{"size": 17}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the Otter provider: %v", err)
+	}
+
+	evicted := make(chan string, 1024)
+
+	client.(core.EvictionNotifier).OnEvicted(func(key string, _ []byte) {
+		evicted <- key
+	})
+
+	for i := range 200 {
+		_ = client.Set(fmt.Sprintf("%s-%d", byteKey, i), []byte(baseValue), time.Minute)
+	}
+
+	select {
+	case key := <-evicted:
+		if !strings.HasPrefix(key, byteKey) {
+			t.Errorf("The evicted key should be notified, %s given", key)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("The entries evicted by the size constraint should be notified")
+	}
+
+	client.Delete(byteKey + "-199")
+
+	for len(evicted) > 0 {
+		if key := <-evicted; key == byteKey+"-199" {
+			t.Error("The explicit deletions shouldn't be notified")
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := otter.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
@@ -0,0 +1,207 @@
+package otter
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/darkweak/storages/core"
+)
+
+// Priority ranks the entries under memory pressure, the low priority ones
+// are evicted before the high priority ones.
+type Priority uint8
+
+// The priorities, PriorityNormal when no rule matches the entry.
+const (
+	PriorityNormal Priority = iota
+	PriorityLow
+	PriorityHigh
+)
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// weight multiplies the cost of the entries, a low priority entry takes the
+// room of two normal ones and a high priority one of half a normal one.
+func (p Priority) weight() uint64 {
+	switch p {
+	case PriorityLow:
+		return 4
+	case PriorityHigh:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// priorityConfiguration weighs the cost of the entries by their priority.
+type priorityConfiguration struct {
+	// Enabled weighs the entries even without patterns, the responses with
+	// Cache-Control: immutable are then high priority.
+	Enabled bool `json:"enabled"`
+	// High lists the regular expressions of the keys evicted last.
+	High []string `json:"high"`
+	// Low lists the regular expressions of the keys evicted first.
+	Low []string `json:"low"`
+}
+
+func (c priorityConfiguration) enabled() bool {
+	return c.Enabled || len(c.High) > 0 || len(c.Low) > 0
+}
+
+// priorityRules derive the priority of the entries from their key and the
+// headers of their response.
+type priorityRules struct {
+	high []*regexp.Regexp
+	low  []*regexp.Regexp
+}
+
+func compilePatterns(name string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otter configuration: priorities.%s: %w", name, err)
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+// newPriorityRules compiles the patterns, it returns nil when the entries
+// aren't weighed.
+func newPriorityRules(cfg priorityConfiguration) (*priorityRules, error) {
+	if !cfg.enabled() {
+		return nil, nil
+	}
+
+	high, err := compilePatterns("high", cfg.High)
+	if err != nil {
+		return nil, err
+	}
+
+	low, err := compilePatterns("low", cfg.Low)
+	if err != nil {
+		return nil, err
+	}
+
+	return &priorityRules{high: high, low: low}, nil
+}
+
+// priority returns the priority of the key, the configured patterns win over
+// the headers. The mappings are high priority, evicting one would orphan its
+// variants.
+func (r *priorityRules) priority(key string, header http.Header) Priority {
+	if strings.HasPrefix(key, core.MappingKeyPrefix) {
+		return PriorityHigh
+	}
+
+	for _, re := range r.high {
+		if re.MatchString(key) {
+			return PriorityHigh
+		}
+	}
+
+	for _, re := range r.low {
+		if re.MatchString(key) {
+			return PriorityLow
+		}
+	}
+
+	for _, directive := range strings.Split(strings.Join(header.Values("Cache-Control"), ","), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "immutable") {
+			return PriorityHigh
+		}
+	}
+
+	return PriorityNormal
+}
+
+// priorityStores holds the priorities of the entries of each weighed cache
+// by instance key, the cost function reads them when an entry is stored.
+var priorityStores = sync.Map{}
+
+// prioritiesFor returns the priorities of the entries of the cache.
+func prioritiesFor(instanceKey string) *sync.Map {
+	priorities, _ := priorityStores.LoadOrStore(instanceKey, &sync.Map{})
+
+	return priorities.(*sync.Map)
+}
+
+// storedPriority returns the priority recorded for the key of the cache,
+// PriorityNormal when there is none. The store is looked up on each call so
+// a reloaded cache reads the priorities moved to its instance key.
+func storedPriority(instanceKey, key string) Priority {
+	priorities, found := priorityStores.Load(instanceKey)
+	if !found {
+		return PriorityNormal
+	}
+
+	stored, found := priorities.(*sync.Map).Load(key)
+	if !found {
+		return PriorityNormal
+	}
+
+	priority, _ := stored.(Priority)
+
+	return priority
+}
+
+// forgetPriority drops the priority of the entry deleted from the cache.
+func forgetPriority(instanceKey, key string) {
+	if priorities, found := priorityStores.Load(instanceKey); found {
+		priorities.(*sync.Map).Delete(key)
+	}
+}
+
+// weighedCost multiplies the cost of the entries by the weight of their
+// priority.
+func weighedCost(instanceKey string, cost func(string, []byte) uint32) func(string, []byte) uint32 {
+	return func(key string, value []byte) uint32 {
+		return uint32(min(uint64(cost(key, value))*storedPriority(instanceKey, key).weight(), math.MaxUint32))
+	}
+}
+
+// Priority returns the priority the entry was stored with, PriorityNormal
+// when the entries aren't weighed.
+func (provider *Otter) Priority(key string) Priority {
+	return storedPriority(provider.instanceKey, key)
+}
+
+// setPriority records the priority of the key before it's stored, so the
+// cost function weighs it. The priority is derived from the headers of the
+// value when it's a response.
+func (provider *Otter) setPriority(key string, value []byte) {
+	if provider.priorities == nil {
+		return
+	}
+
+	var header http.Header
+	if response, err := core.ReadHeaders(value); err == nil {
+		header = response.Header
+	}
+
+	priorities := prioritiesFor(provider.instanceKey)
+
+	if priority := provider.priorities.priority(key, header); priority != PriorityNormal {
+		priorities.Store(key, priority)
+	} else {
+		priorities.Delete(key)
+	}
+}
@@ -0,0 +1,113 @@
+package peers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ServeHTTP serves the peers protocol, mount it under the base_path when the
+// listen address isn't configured:
+//   - GET ?key= returns an owned value with its TTLHeader.
+//   - GET ?prefix= returns the owned keys starting with the prefix as JSON.
+//   - PUT ?key=&ttl= stores the request body.
+//   - DELETE ?key= deletes the owned value and the hot copy.
+//   - DELETE ?regex=&dry_run= deletes the matching keys and returns the
+//     owned ones as a core.DeleteManyResult.
+//   - HEAD answers the health checks.
+func (provider *Peers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if provider.secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(SecretHeader)), []byte(provider.secret)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodHead:
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		if query.Has("prefix") {
+			keys := map[string][]byte{}
+			for key, value := range provider.localKeys(query.Get("prefix")) {
+				keys[key] = []byte(value)
+			}
+
+			writeJSON(w, keys)
+
+			return
+		}
+
+		provider.serveValue(w, query.Get("key"))
+	case http.MethodPut:
+		var ttl time.Duration
+
+		if raw := query.Get("ttl"); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			ttl = parsed
+		}
+
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		provider.hot.Delete(query.Get("key"))
+		provider.setLocal(query.Get("key"), value, ttl)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if query.Has("regex") {
+			rgKey, err := regexp.Compile(query.Get("regex"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+			writeJSON(w, provider.deleteLocal(rgKey, dryRun))
+
+			return
+		}
+
+		provider.local.Delete(query.Get("key"))
+		provider.hot.Delete(query.Get("key"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (provider *Peers) serveValue(w http.ResponseWriter, key string) {
+	value, found := provider.local.Get(key)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	if entry, ok := provider.local.Extension().GetEntryQuietly(key); ok && entry.TTL() < noExpiration/2 {
+		w.Header().Set(TTLHeader, entry.TTL().String())
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(value)
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,37 @@
+package peers
+
+import (
+	"sync"
+	"time"
+)
+
+// hotness counts the reads of the remote keys within a window, a key read
+// threshold times is hot and replicated locally.
+type hotness struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	maxKeys   int
+	started   time.Time
+	reads     map[string]int
+}
+
+func newHotness(threshold int, window time.Duration, maxKeys int) *hotness {
+	return &hotness{threshold: threshold, window: window, maxKeys: maxKeys, reads: map[string]int{}}
+}
+
+// hit records a read of the key and reports whether it's hot. The counters
+// are reset every window, or once too many keys are tracked.
+func (h *hotness) hit(key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if now := time.Now(); now.Sub(h.started) > h.window || len(h.reads) >= h.maxKeys*10 {
+		h.started = now
+		clear(h.reads)
+	}
+
+	h.reads[key]++
+
+	return h.reads[key] >= h.threshold
+}
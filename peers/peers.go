@@ -0,0 +1,665 @@
+package peers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/maypok86/otter"
+)
+
+const (
+	// SecretHeader carries the shared secret of the peers when configured.
+	SecretHeader = "Storages-Peers-Secret"
+	// TTLHeader holds the remaining TTL of the value returned by a peer,
+	// absent when the value never expires.
+	TTLHeader = "Storages-Ttl"
+
+	defaultBasePath     = "/_storages/peers/"
+	defaultSize         = 10_000
+	defaultHotSize      = 1_000
+	defaultHotThreshold = 3
+	defaultHotTTL       = 5 * time.Second
+	defaultTimeout      = time.Second
+
+	// noExpiration is the TTL of the values stored without duration.
+	noExpiration = 10 * 365 * 24 * time.Hour
+)
+
+var errUnexpectedStatus = errors.New("unexpected peer response status")
+
+// Peers provider type. Every application instance runs one and owns the keys
+// the consistent hash of the peers list assigns to it, the others reach them
+// over HTTP. The keys read often from another peer are replicated in a local
+// hot cache for hot_ttl.
+type Peers struct {
+	self       string
+	basePath   string
+	secret     string
+	listen     string
+	ring       *ring
+	client     *http.Client
+	local      otter.CacheWithVariableTTL[string, []byte]
+	hot        otter.CacheWithVariableTTL[string, []byte]
+	hotness    *hotness
+	hotTTL     time.Duration
+	stale      time.Duration
+	uuid       string
+	logger     core.Logger
+	compressor core.Compressor
+	mu         sync.Mutex
+	server     *http.Server
+}
+
+// configuration is the typed Peers provider configuration.
+type configuration struct {
+	// Self is the URL the other peers reach this instance with.
+	Self string `json:"self"`
+	// Peers are the URLs of every peer, including Self.
+	Peers []string `json:"peers"`
+	// BasePath is the path the peers protocol is served under.
+	BasePath string `json:"base_path"`
+	// Listen is the address serving the peers protocol, the application
+	// mounts the provider handler itself when empty.
+	Listen string `json:"listen"`
+	// Secret is shared by the peers to authenticate their requests.
+	Secret string `json:"secret"`
+	// Size is the maximum number of owned entries.
+	Size int `json:"size"`
+	// HotSize is the maximum number of replicated hot entries.
+	HotSize int `json:"hot_size"`
+	// HotThreshold is the number of reads of a remote key within HotTTL
+	// replicating it locally.
+	HotThreshold int `json:"hot_threshold"`
+	// HotTTL is how long a replicated entry is served at most.
+	HotTTL time.Duration `json:"hot_ttl"`
+	// Timeout bounds each request to a peer.
+	Timeout time.Duration `json:"timeout"`
+}
+
+func parseConfiguration(peersConfiguration core.CacheProvider) (configuration, error) {
+	cfg := configuration{
+		BasePath:     defaultBasePath,
+		Size:         defaultSize,
+		HotSize:      defaultHotSize,
+		HotThreshold: defaultHotThreshold,
+		HotTTL:       defaultHotTTL,
+		Timeout:      defaultTimeout,
+	}
+
+	if peersConfiguration.URL != "" {
+		cfg.Peers = strings.Split(peersConfiguration.URL, ",")
+	}
+
+	if err := core.DecodeConfiguration(peersConfiguration.Configuration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid peers configuration: %w", err)
+	}
+
+	if cfg.Self == "" {
+		return cfg, errors.New("invalid peers configuration: the self URL is required")
+	}
+
+	for index, peer := range append([]string{cfg.Self}, cfg.Peers...) {
+		if parsed, err := url.Parse(peer); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return cfg, fmt.Errorf("invalid peers configuration: the peer %s must be an absolute URL", peer)
+		}
+
+		if index > 0 {
+			cfg.Peers[index-1] = strings.TrimSuffix(peer, "/")
+		}
+	}
+
+	cfg.Self = strings.TrimSuffix(cfg.Self, "/")
+	if !slices.Contains(cfg.Peers, cfg.Self) {
+		cfg.Peers = append(cfg.Peers, cfg.Self)
+	}
+
+	if !strings.HasPrefix(cfg.BasePath, "/") {
+		return cfg, fmt.Errorf("invalid peers configuration: the base_path must start with a slash, %s given", cfg.BasePath)
+	}
+
+	if cfg.Size <= 0 || cfg.HotSize <= 0 || cfg.HotThreshold <= 0 {
+		return cfg, errors.New("invalid peers configuration: the size, hot_size and hot_threshold must be positive")
+	}
+
+	if cfg.HotTTL < 0 || cfg.Timeout <= 0 {
+		return cfg, errors.New("invalid peers configuration: the hot_ttl can't be negative and the timeout must be positive")
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(peersConfiguration any) error {
+	_, err := parseConfiguration(core.CacheProvider{Configuration: peersConfiguration})
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("peers", Factory)
+}
+
+// Factory function create new Peers instance.
+func Factory(peersConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(peersConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, err := core.CompressorFromConfiguration(peersConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(peersConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	local, err := otter.MustBuilder[string, []byte](cfg.Size).CollectStats().WithVariableTTL().Build()
+	if err != nil {
+		logger.Errorf("Impossible to instantiate the Peers local cache, %v", err)
+
+		return nil, err
+	}
+
+	hot, err := otter.MustBuilder[string, []byte](cfg.HotSize).WithVariableTTL().Build()
+	if err != nil {
+		logger.Errorf("Impossible to instantiate the Peers hot cache, %v", err)
+
+		return nil, err
+	}
+
+	return &Peers{
+		self:       cfg.Self,
+		basePath:   cfg.BasePath,
+		secret:     cfg.Secret,
+		listen:     cfg.Listen,
+		ring:       newRing(cfg.Peers...),
+		client:     &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		local:      local,
+		hot:        hot,
+		hotness:    newHotness(cfg.HotThreshold, cfg.HotTTL, cfg.HotSize),
+		hotTTL:     cfg.HotTTL,
+		stale:      stale,
+		uuid:       core.InstanceID("peers", peersConfiguration, stale),
+		logger:     logger,
+		compressor: compressor,
+	}, nil
+}
+
+// Name returns the storer name.
+func (provider *Peers) Name() string {
+	return "PEERS"
+}
+
+// Uuid returns an unique identifier.
+func (provider *Peers) Uuid() string {
+	return provider.uuid
+}
+
+// others returns the peers except this instance.
+func (provider *Peers) others() []string {
+	others := make([]string, 0, len(provider.ring.peers))
+
+	for _, peer := range provider.ring.peers {
+		if peer != provider.self {
+			others = append(others, peer)
+		}
+	}
+
+	return others
+}
+
+// call sends the request to the peer, the response body is read and closed.
+func (provider *Peers) call(ctx context.Context, method, peer string, query url.Values, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, peer+provider.basePath+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if provider.secret != "" {
+		req.Header.Set(SecretHeader, provider.secret)
+	}
+
+	res, err := provider.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer res.Body.Close()
+
+	payload, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, payload, nil
+}
+
+// broadcast calls every other peer in parallel, it returns their joined
+// errors.
+func (provider *Peers) broadcast(method string, query url.Values, handle func(peer string, payload []byte) error) error {
+	others := provider.others()
+	errs := make([]error, len(others))
+
+	var wg sync.WaitGroup
+
+	for index, peer := range others {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			res, payload, err := provider.call(context.Background(), method, peer, query, nil)
+			if err == nil && res.StatusCode >= http.StatusBadRequest {
+				err = fmt.Errorf("%w %d from %s", errUnexpectedStatus, res.StatusCode, peer)
+			}
+
+			if err == nil && handle != nil {
+				err = handle(peer, payload)
+			}
+
+			errs[index] = err
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// localKeys returns the owned keys starting with the prefix, without it.
+func (provider *Peers) localKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	provider.local.Range(func(key string, val []byte) bool {
+		if k, found := strings.CutPrefix(key, prefix); found {
+			keys[k] = string(val)
+		}
+
+		return true
+	})
+
+	return keys
+}
+
+// MapKeys method returns a map with the key and value of every peer.
+func (provider *Peers) MapKeys(prefix string) map[string]string {
+	keys := provider.localKeys(prefix)
+
+	var mu sync.Mutex
+
+	err := provider.broadcast(http.MethodGet, url.Values{"prefix": {prefix}}, func(_ string, payload []byte) error {
+		// The values are sent as bytes, they aren't valid UTF-8.
+		remote := map[string][]byte{}
+		if err := json.Unmarshal(payload, &remote); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for key, value := range remote {
+			keys[key] = string(value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys of every peer, %v", err)
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Peers) ListKeys() []string {
+	keys := []string{}
+
+	for _, value := range provider.MapKeys(core.MappingKeyPrefix) {
+		mapping, err := core.DecodeMapping([]byte(value))
+		if err == nil {
+			for _, v := range mapping.GetMapping() {
+				keys = append(keys, v.GetRealKey())
+			}
+		}
+	}
+
+	return keys
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Peers) Get(key string) []byte {
+	result, _ := provider.Lookup(key)
+
+	return result
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired. The key is read from its owner, or from the
+// hot cache once it's replicated.
+func (provider *Peers) Lookup(key string) ([]byte, error) {
+	owner := provider.ring.owner(key)
+	if owner == provider.self {
+		if value, found := provider.local.Get(key); found {
+			return value, nil
+		}
+
+		return nil, core.ErrKeyNotFound
+	}
+
+	if value, found := provider.hot.Get(key); found {
+		return value, nil
+	}
+
+	res, payload, err := provider.call(context.Background(), http.MethodGet, owner, url.Values{"key": {key}}, nil)
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s from the peer %s, %v", key, owner, err)
+
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, core.ErrKeyNotFound
+	default:
+		return nil, fmt.Errorf("%w %d from %s", errUnexpectedStatus, res.StatusCode, owner)
+	}
+
+	if provider.hotTTL > 0 && provider.hotness.hit(key) {
+		ttl := provider.hotTTL
+		if remaining, parseErr := time.ParseDuration(res.Header.Get(TTLHeader)); parseErr == nil && remaining < ttl {
+			ttl = remaining
+		}
+
+		if ttl > 0 {
+			provider.hot.Set(key, payload, ttl)
+		}
+	}
+
+	return payload, nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Peers) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	val, err := provider.Lookup(core.MappingKeyPrefix + key)
+	if err != nil {
+		provider.logger.Debugf("Impossible to get the mapping key %s in Peers", core.MappingKeyPrefix+key)
+
+		return
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
+
+	return
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *Peers) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into Peers, %v", variedKey, err)
+
+		return err
+	}
+
+	if err = provider.Set(variedKey, compressed, duration+provider.stale); err != nil {
+		return err
+	}
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	// The mapping is read and written at once for the instance, the updates
+	// of the other peers may be lost.
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	item, _ := provider.Lookup(mappingKey)
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, item, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	provider.logger.Debugf("Store the new mapping for the key %s in Peers", variedKey)
+
+	if err = provider.Set(mappingKey, val, 0); err != nil {
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+func (provider *Peers) setLocal(key string, value []byte, duration time.Duration) {
+	if duration <= 0 {
+		duration = noExpiration
+	}
+
+	if !provider.local.Set(key, value, duration) {
+		provider.logger.Errorf("Impossible to set the key %s into Peers, too large for the cost function", key)
+	}
+}
+
+// Set method will store the response on the peer owning the key.
+func (provider *Peers) Set(key string, value []byte, duration time.Duration) error {
+	provider.hot.Delete(key)
+
+	owner := provider.ring.owner(key)
+	if owner == provider.self {
+		provider.setLocal(key, value, duration)
+
+		return nil
+	}
+
+	query := url.Values{"key": {key}}
+	if duration > 0 {
+		query.Set("ttl", duration.String())
+	}
+
+	res, _, err := provider.call(context.Background(), http.MethodPut, owner, query, value)
+	if err == nil && res.StatusCode >= http.StatusBadRequest {
+		err = fmt.Errorf("%w %d from %s", errUnexpectedStatus, res.StatusCode, owner)
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s on the peer %s, %v", key, owner, err)
+	}
+
+	return err
+}
+
+// Delete method will delete the response on every peer, the owner and the
+// hot copies.
+func (provider *Peers) Delete(key string) {
+	provider.local.Delete(key)
+	provider.hot.Delete(key)
+
+	if err := provider.broadcast(http.MethodDelete, url.Values{"key": {key}}, nil); err != nil {
+		provider.logger.Errorf("Impossible to delete the key %s on every peer, %v", key, err)
+	}
+}
+
+// DeleteMany method will delete the responses on every peer if exists corresponding to the regex key param.
+func (provider *Peers) DeleteMany(key string) {
+	_, _ = provider.DeleteManyCount(key, false)
+}
+
+// deleteLocal deletes the owned keys and the hot copies matching the regular
+// expression, only the owned keys are reported.
+func (provider *Peers) deleteLocal(rgKey *regexp.Regexp, dryRun bool) core.DeleteManyResult {
+	result := core.DeleteManyResult{}
+	keys := []string{}
+
+	provider.local.Range(func(key string, _ []byte) bool {
+		if rgKey.MatchString(key) {
+			keys = append(keys, key)
+		}
+
+		return true
+	})
+
+	for _, key := range keys {
+		if !dryRun {
+			provider.local.Delete(key)
+		}
+
+		result.Add(key, dryRun)
+	}
+
+	if !dryRun {
+		provider.hot.DeleteByFunc(func(key string, _ []byte) bool {
+			return rgKey.MatchString(key)
+		})
+	}
+
+	return result
+}
+
+// DeleteManyCount deletes the keys matching the regular expression on every
+// peer. In dry-run the matching keys are listed without being deleted.
+func (provider *Peers) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	rgKey, err := regexp.Compile(pattern)
+	if err != nil {
+		return core.DeleteManyResult{}, err
+	}
+
+	result := provider.deleteLocal(rgKey, dryRun)
+
+	var mu sync.Mutex
+
+	query := url.Values{"regex": {pattern}}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+
+	err = provider.broadcast(http.MethodDelete, query, func(_ string, payload []byte) error {
+		var remote core.DeleteManyResult
+		if err := json.Unmarshal(payload, &remote); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		result.Count += remote.Count
+		result.Keys = append(result.Keys, remote.Keys...)
+
+		return nil
+	})
+
+	slices.Sort(result.Keys)
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to delete the keys matching %s on every peer, %v", pattern, err)
+	}
+
+	return result, err
+}
+
+// Healthy reaches every other peer, the keys they own are unavailable when
+// they don't answer.
+func (provider *Peers) Healthy(ctx context.Context) error {
+	others := provider.others()
+	errs := make([]error, 0, len(others))
+
+	for _, peer := range others {
+		res, _, err := provider.call(ctx, http.MethodHead, peer, url.Values{}, nil)
+		if err == nil && res.StatusCode != http.StatusNoContent {
+			err = fmt.Errorf("%w %d from %s", errUnexpectedStatus, res.StatusCode, peer)
+		}
+
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stats returns the stats of the owned entries.
+func (provider *Peers) Stats() core.StorerStats {
+	otterStats := provider.local.Stats()
+	stats := core.NewStorerStats(otterStats.Hits(), otterStats.Misses())
+	stats.Entries = int64(provider.local.Size())
+	stats.Evictions = otterStats.EvictedCount()
+
+	provider.local.Range(func(key string, value []byte) bool {
+		stats.Bytes += int64(len(key) + len(value))
+
+		return true
+	})
+
+	return stats
+}
+
+// Init method starts serving the peers protocol when listen is configured.
+func (provider *Peers) Init() error {
+	if provider.listen == "" {
+		return nil
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", provider.listen)
+	if err != nil {
+		provider.logger.Errorf("Impossible to listen on %s for the peers, %v", provider.listen, err)
+
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(provider.basePath, provider)
+
+	provider.server = &http.Server{Handler: mux, ReadHeaderTimeout: provider.client.Timeout}
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			provider.logger.Errorf("Impossible to serve the peers protocol, %v", err)
+		}
+	}(provider.server)
+
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *Peers) Reset() error {
+	provider.mu.Lock()
+	server := provider.server
+	provider.server = nil
+	provider.mu.Unlock()
+
+	provider.local.Clear()
+	provider.hot.Clear()
+
+	if server != nil {
+		return server.Close()
+	}
+
+	return nil
+}
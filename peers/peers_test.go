@@ -0,0 +1,238 @@
+package peers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/peers"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+// getPeersInstances starts the peers on test servers, each one knows the
+// others.
+func getPeersInstances(t *testing.T, count int, configuration map[string]interface{}) []core.Storer {
+	t.Helper()
+
+	servers := make([]*httptest.Server, count)
+	handlers := make([]http.Handler, count)
+	urls := make([]string, count)
+
+	for index := range count {
+		servers[index] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlers[index].ServeHTTP(w, r)
+		}))
+		urls[index] = servers[index].URL
+
+		t.Cleanup(servers[index].Close)
+	}
+
+	instances := make([]core.Storer, count)
+
+	for index := range count {
+		cfg := map[string]interface{}{"self": urls[index], "peers": urls}
+		for key, value := range configuration {
+			cfg[key] = value
+		}
+
+		instance, err := peers.Factory(core.CacheProvider{Configuration: cfg}, zap.NewNop().Sugar(), 0)
+		if err != nil {
+			t.Fatalf("Impossible to create the peer: %v", err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/_storages/peers/", instance.(http.Handler))
+		handlers[index] = mux
+		instances[index] = instance
+	}
+
+	return instances
+}
+
+func TestPeers_SetGet(t *testing.T) {
+	instances := getPeersInstances(t, 3, nil)
+
+	for index := range 20 {
+		key := fmt.Sprintf("key-%d", index)
+		if err := instances[index%3].Set(key, []byte(baseValue), time.Minute); err != nil {
+			t.Fatalf("Impossible to set the key %s: %v", key, err)
+		}
+	}
+
+	owned := 0
+
+	for index := range 20 {
+		key := fmt.Sprintf("key-%d", index)
+
+		for _, instance := range instances {
+			if string(instance.Get(key)) != baseValue {
+				t.Errorf("The key %s should be read from every peer", key)
+			}
+		}
+	}
+
+	for _, instance := range instances {
+		stats, _ := core.Stats(instance)
+		owned += int(stats.Entries)
+	}
+
+	if owned != 20 {
+		t.Errorf("Every key should be owned by a single peer, %d owned", owned)
+	}
+
+	if _, err := core.Lookup(instances[0], nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	if keys := instances[1].MapKeys("key-"); len(keys) != 20 {
+		t.Errorf("The keys of every peer should be listed, %d given", len(keys))
+	}
+}
+
+func TestPeers_HotKeys(t *testing.T) {
+	instances := getPeersInstances(t, 2, map[string]interface{}{"hot_threshold": 2, "hot_ttl": "1m"})
+
+	// Find a key owned by the second peer.
+	key := ""
+
+	for index := 0; key == ""; index++ {
+		candidate := fmt.Sprintf("hot-%d", index)
+		_ = instances[1].Set(candidate, []byte(baseValue), time.Minute)
+
+		if stats, _ := core.Stats(instances[1]); stats.Entries > 0 {
+			key = candidate
+		}
+	}
+
+	_ = instances[0].Get(key)
+	_ = instances[0].Get(key)
+
+	// The owner value changes without reaching the hot copy.
+	_ = instances[1].Set(key, []byte("changed"), time.Minute)
+
+	if string(instances[0].Get(key)) != baseValue {
+		t.Error("The hot key should be served from the replicated copy")
+	}
+
+	instances[1].Delete(key)
+
+	if len(instances[0].Get(key)) != 0 {
+		t.Error("The deletion should reach the hot copies")
+	}
+
+	_ = instances[0].Set(key, []byte("other"), time.Minute)
+	_ = instances[0].Get(key)
+	_ = instances[0].Get(key)
+
+	if string(instances[0].Get(key)) != "other" || string(instances[1].Get(key)) != "other" {
+		t.Error("The new value should be served by every peer")
+	}
+}
+
+func TestPeers_DeleteMany(t *testing.T) {
+	instances := getPeersInstances(t, 3, nil)
+
+	for index := range 10 {
+		_ = instances[0].Set(fmt.Sprintf("PURGE_%d", index), []byte(baseValue), time.Minute)
+	}
+
+	_ = instances[0].Set("KEEP", []byte(baseValue), time.Minute)
+
+	result, err := core.DeleteManyCount(instances[1], "^PURGE_", true)
+	if err != nil || result.Count != 10 || len(result.Keys) != 10 {
+		t.Fatalf("The dry-run should list the matching keys of every peer, %+v given, %v", result, err)
+	}
+
+	instances[2].DeleteMany("^PURGE_")
+
+	if keys := instances[0].MapKeys(""); len(keys) != 1 {
+		t.Errorf("Only the unmatched key should remain, %v given", keys)
+	}
+}
+
+func TestPeers_SetMultiLevel(t *testing.T) {
+	instances := getPeersInstances(t, 2, nil)
+
+	if err := instances[0].SetMultiLevel("base", "varied", []byte("HTTP/1.1 200 OK\r\n\r\n"), http.Header{}, "", time.Minute, "varied"); err != nil {
+		t.Fatalf("Impossible to store the response: %v", err)
+	}
+
+	if keys := instances[1].ListKeys(); len(keys) != 1 || keys[0] != "varied" {
+		t.Errorf("The varied key should be listed by every peer, %v given", keys)
+	}
+}
+
+func TestPeers_Secret(t *testing.T) {
+	instances := getPeersInstances(t, 2, map[string]interface{}{"secret": "s3cr3t"})
+
+	if err := core.Healthy(context.Background(), instances[0]); err != nil {
+		t.Errorf("The peers sharing the secret should be healthy, %v given", err)
+	}
+
+	rec := httptest.NewRecorder()
+	instances[0].(http.Handler).ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/_storages/peers/", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("The requests without the secret should be rejected, %d given", rec.Code)
+	}
+}
+
+func TestPeers_Unavailable(t *testing.T) {
+	instance, err := peers.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"self":    "http://127.0.0.1:1",
+		"peers":   []string{"http://127.0.0.1:1", "http://127.0.0.1:2"},
+		"timeout": "100ms",
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the peer: %v", err)
+	}
+
+	if err = core.Healthy(context.Background(), instance); err == nil {
+		t.Error("The unreachable peer should be reported")
+	}
+}
+
+func TestPeers_Validate(t *testing.T) {
+	if err := peers.Validate(map[string]interface{}{"self": "http://a:80", "peers": "http://a:80,http://b:80"}); err != nil {
+		t.Errorf("The peers given as a string should be valid, %v given", err)
+	}
+
+	if err := peers.Validate(map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "self") {
+		t.Errorf("The self URL should be required, %v given", err)
+	}
+
+	if err := peers.Validate(map[string]interface{}{"self": "a:80"}); err == nil {
+		t.Error("A relative peer URL should be invalid")
+	}
+
+	if err := peers.Validate(map[string]interface{}{"self": "http://a:80", "hot_size": -1}); err == nil {
+		t.Error("A negative hot_size should be invalid")
+	}
+
+	if err := peers.Validate(map[string]interface{}{"self": "http://a:80", "peer": "http://b:80"}); err == nil || !strings.Contains(err.Error(), "peer") {
+		t.Errorf("The unknown key should be reported, %v given", err)
+	}
+}
+
+func TestPeers_NewStorer(t *testing.T) {
+	instance, err := core.NewStorer("peers", core.CacheProvider{Configuration: map[string]interface{}{"self": "http://localhost:80"}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("The peers storage should be registered: %v", err)
+	}
+
+	if instance.Name() != "PEERS" {
+		t.Errorf("The peers storage should be returned, %s given", instance.Name())
+	}
+}
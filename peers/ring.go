@@ -0,0 +1,57 @@
+package peers
+
+import (
+	"hash/crc32"
+	"slices"
+	"strconv"
+)
+
+// virtualNodes is the number of points each peer owns on the ring, it
+// smooths the keys distribution between the peers.
+const virtualNodes = 160
+
+// ring is the consistent hash of the peers: adding or removing a peer only
+// moves the keys it owns instead of reshuffling the whole keyspace.
+type ring struct {
+	points []uint32
+	owners map[uint32]string
+	peers  []string
+}
+
+func newRing(peers ...string) *ring {
+	r := &ring{owners: map[uint32]string{}}
+
+	for _, peer := range peers {
+		if slices.Contains(r.peers, peer) {
+			continue
+		}
+
+		r.peers = append(r.peers, peer)
+
+		for i := range virtualNodes {
+			point := crc32.ChecksumIEEE([]byte(peer + "-" + strconv.Itoa(i)))
+			if _, exists := r.owners[point]; !exists {
+				r.owners[point] = peer
+				r.points = append(r.points, point)
+			}
+		}
+	}
+
+	slices.Sort(r.points)
+
+	return r
+}
+
+// owner returns the peer owning the first point after the key hash.
+func (r *ring) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	idx, _ := slices.BinarySearch(r.points, crc32.ChecksumIEEE([]byte(key)))
+	if idx == len(r.points) {
+		idx = 0
+	}
+
+	return r.owners[r.points[idx]]
+}
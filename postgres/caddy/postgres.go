@@ -0,0 +1,62 @@
+//go:build !wasm && !wasi
+
+package caddy
+
+import (
+	"net/http"
+
+	caddy "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/postgres"
+)
+
+const moduleName = "postgres"
+
+// Postgres storage.
+type Postgres struct {
+	// Keep the handler configuration.
+	core.Configuration
+}
+
+//nolint:gochecknoinits
+func init() {
+	caddy.RegisterModule(Postgres{})
+}
+
+// CaddyModule returns the Caddy module information.
+func (Postgres) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "storages.cache.postgres",
+		New: func() caddy.Module { return new(Postgres) },
+	}
+}
+
+// Provision to do the provisioning part.
+func (b *Postgres) Provision(ctx caddy.Context) error {
+	logger := ctx.Logger(b)
+	storer, err := postgres.Factory(b.Configuration.Provider, logger.Sugar(), b.Configuration.Stale)
+
+	if err != nil {
+		return err
+	}
+
+	storer, err = core.EncryptedStorerFromConfiguration(storer, b.Configuration.Provider, b.Configuration.Stale, logger.Sugar())
+	if err != nil {
+		return err
+	}
+
+	core.RegisterStorage(storer)
+
+	return nil
+}
+
+func (b *Postgres) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyhttp.Handler) error {
+	return next.ServeHTTP(rw, rq)
+}
+
+// Interface guards.
+var (
+	_ caddy.Provisioner           = (*Postgres)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Postgres)(nil)
+)
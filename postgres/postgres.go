@@ -0,0 +1,596 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultTable         = "cache_entries"
+	defaultPurgeInterval = time.Minute
+
+	// purgeBatchSize bounds the rows deleted by a single purge statement so
+	// the table is never locked for long.
+	purgeBatchSize = 1000
+	// deleteBatchSize bounds the keys deleted at once by DeleteMany.
+	deleteBatchSize = 500
+)
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Postgres provider type.
+type Postgres struct {
+	pool          *pgxpool.Pool
+	table         string
+	indexName     string
+	purgeInterval time.Duration
+	limiter       *core.BackgroundLimiter
+	stop          chan struct{}
+	stale         time.Duration
+	uuid          string
+	ctx           context.Context
+	logger        core.Logger
+	compressor    core.Compressor
+}
+
+// configuration is the typed Postgres provider configuration.
+type configuration struct {
+	// DSN is the connection string, the provider URL is used when empty.
+	DSN string `json:"dsn"`
+	// Table is the name of the table holding the entries, it may be schema
+	// qualified.
+	Table string `json:"table"`
+	// PurgeInterval is the delay between two removals of the expired rows.
+	PurgeInterval time.Duration `json:"purge_interval"`
+	MaxConns      int32         `json:"max_conns"`
+}
+
+func parseConfiguration(postgresConfiguration core.CacheProvider) (configuration, error) {
+	cfg := configuration{
+		DSN:           postgresConfiguration.URL,
+		Table:         defaultTable,
+		PurgeInterval: defaultPurgeInterval,
+	}
+
+	if err := core.DecodeConfiguration(postgresConfiguration.Configuration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid postgres configuration: %w", err)
+	}
+
+	if !identifierRegexp.MatchString(cfg.Table) {
+		return cfg, fmt.Errorf("invalid postgres configuration: the table %q is not a valid identifier", cfg.Table)
+	}
+
+	if cfg.PurgeInterval <= 0 {
+		return cfg, fmt.Errorf("invalid postgres configuration: the purge_interval must be positive, %s given", cfg.PurgeInterval)
+	}
+
+	if cfg.MaxConns < 0 {
+		return cfg, fmt.Errorf("invalid postgres configuration: the max_conns can't be negative, %d given", cfg.MaxConns)
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(postgresConfiguration any) error {
+	_, err := parseConfiguration(core.CacheProvider{Configuration: postgresConfiguration})
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("postgres", Factory)
+}
+
+// Factory function create new Postgres instance.
+func Factory(postgresConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(postgresConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.DSN == "" {
+		return nil, errors.New("no postgres dsn given")
+	}
+
+	compressor, err := core.CompressorFromConfiguration(postgresConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(postgresConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(postgresConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		logger.Errorf("Impossible to parse the Postgres dsn, %v", err)
+
+		return nil, err
+	}
+
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+
+	if tlsConfig != nil {
+		poolConfig.ConnConfig.TLSConfig = tlsConfig
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		logger.Error("Impossible to initialize the Postgres pool.", err)
+
+		return nil, err
+	}
+
+	parts := strings.Split(cfg.Table, ".")
+
+	return &Postgres{
+		pool:          pool,
+		table:         pgx.Identifier(parts).Sanitize(),
+		indexName:     pgx.Identifier{parts[len(parts)-1] + "_expires_at_idx"}.Sanitize(),
+		purgeInterval: cfg.PurgeInterval,
+		stale:         stale,
+		uuid:          core.InstanceID("postgres", postgresConfiguration, stale),
+		ctx:           context.Background(),
+		logger:        logger,
+		compressor:    compressor,
+		limiter:       limiter,
+	}, nil
+}
+
+// expiresAt returns the expiration of a row stored for the given duration,
+// nil means it never expires.
+func expiresAt(duration time.Duration) *time.Time {
+	if duration <= 0 {
+		return nil
+	}
+
+	at := time.Now().Add(duration)
+
+	return &at
+}
+
+// escapeLike escapes the LIKE wildcards of the prefix.
+func escapeLike(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+}
+
+// Name returns the storer name.
+func (provider *Postgres) Name() string {
+	return "POSTGRES"
+}
+
+// Uuid returns an unique identifier.
+func (provider *Postgres) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *Postgres) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	rows, err := provider.pool.Query(
+		provider.ctx,
+		`SELECT key, value FROM `+provider.table+` WHERE key LIKE $1 ESCAPE '\' AND (expires_at IS NULL OR expires_at > now())`,
+		escapeLike(prefix)+"%",
+	)
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in Postgres, %v", err)
+
+		return keys
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key   string
+			value []byte
+		)
+
+		if err = rows.Scan(&key, &value); err != nil {
+			provider.logger.Errorf("Impossible to read the Postgres row, %v", err)
+
+			return keys
+		}
+
+		k, _ := strings.CutPrefix(key, prefix)
+		keys[k] = string(value)
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Postgres) ListKeys() []string {
+	keys := []string{}
+
+	_ = provider.WalkKeys(func(key string) bool {
+		keys = append(keys, key)
+
+		return true
+	})
+
+	return keys
+}
+
+// WalkKeys streams the keys returned by ListKeys, the mappings are read
+// with a single query without loading them all in memory.
+func (provider *Postgres) WalkKeys(walkFn func(key string) bool) error {
+	rows, err := provider.pool.Query(
+		provider.ctx,
+		`SELECT value FROM `+provider.table+` WHERE key LIKE $1 ESCAPE '\' AND (expires_at IS NULL OR expires_at > now())`,
+		escapeLike(core.MappingKeyPrefix)+"%",
+	)
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in Postgres, %v", err)
+
+		return err
+	}
+
+	defer rows.Close()
+
+	now := time.Now()
+
+	for rows.Next() {
+		var value []byte
+		if err = rows.Scan(&value); err != nil {
+			return err
+		}
+
+		for _, key := range core.MappingRealKeys(value, now) {
+			if !walkFn(key) {
+				return nil
+			}
+		}
+	}
+
+	return rows.Err()
+}
+
+// ListKeysPaginated returns a page of the keys returned by ListKeys, the
+// cursor is the last mapping key of the previous page.
+func (provider *Postgres) ListKeysPaginated(cursor string, limit int) ([]string, string) {
+	keys := []string{}
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	if cursor == "" {
+		cursor = core.MappingKeyPrefix
+	}
+
+	rows, err := provider.pool.Query(
+		provider.ctx,
+		`SELECT key, value FROM `+provider.table+` WHERE key LIKE $1 ESCAPE '\' AND key > $2 AND (expires_at IS NULL OR expires_at > now()) ORDER BY key LIMIT $3`,
+		escapeLike(core.MappingKeyPrefix)+"%",
+		cursor,
+		limit,
+	)
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in Postgres, %v", err)
+
+		return keys, ""
+	}
+
+	defer rows.Close()
+
+	now := time.Now()
+	count := 0
+	last := ""
+
+	for rows.Next() {
+		var value []byte
+		if err = rows.Scan(&last, &value); err != nil {
+			provider.logger.Errorf("Impossible to read the Postgres row, %v", err)
+
+			return keys, ""
+		}
+
+		count++
+
+		keys = append(keys, core.MappingRealKeys(value, now)...)
+	}
+
+	if count < limit {
+		return keys, ""
+	}
+
+	return keys, last
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Postgres) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Postgres) Lookup(key string) ([]byte, error) {
+	var value []byte
+
+	err := provider.pool.QueryRow(
+		provider.ctx,
+		`SELECT value FROM `+provider.table+` WHERE key = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		key,
+	).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s in Postgres, %v", key, err)
+
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Postgres) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	result := provider.Get(core.MappingKeyPrefix + key)
+	if len(result) == 0 {
+		return fresh, stale
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, result, req, validator, provider.logger)
+
+	return fresh, stale
+}
+
+func (provider *Postgres) upsertQuery() string {
+	return `INSERT INTO ` + provider.table + ` (key, value, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at`
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+// The mapping is locked while it's updated and both rows are upserted in a
+// single batch.
+func (provider *Postgres) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into Postgres, %v", variedKey, err)
+
+		return err
+	}
+
+	tx, err := provider.pool.Begin(provider.ctx)
+	if err != nil {
+		provider.logger.Errorf("Impossible to begin the Postgres transaction, %v", err)
+
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback(provider.ctx)
+	}()
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	var current []byte
+
+	err = tx.QueryRow(
+		provider.ctx,
+		`SELECT value FROM `+provider.table+` WHERE key = $1 AND (expires_at IS NULL OR expires_at > now()) FOR UPDATE`,
+		mappingKey,
+	).Scan(&current)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		provider.logger.Errorf("Impossible to get the mapping key %s in Postgres, %v", mappingKey, err)
+
+		return err
+	}
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, current, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(provider.upsertQuery(), variedKey, compressed, expiresAt(duration+provider.stale))
+	// The mapping lives as long as its longest variant.
+	batch.Queue(
+		`INSERT INTO `+provider.table+` (key, value, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = GREATEST(`+provider.table+`.expires_at, EXCLUDED.expires_at)`,
+		mappingKey, val, expiresAt(duration+provider.stale),
+	)
+
+	if err = tx.SendBatch(provider.ctx, batch).Close(); err != nil {
+		provider.logger.Errorf("Impossible to set value into Postgres, %v", err)
+
+		return err
+	}
+
+	if err = tx.Commit(provider.ctx); err != nil {
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in Postgres provider.
+func (provider *Postgres) Set(key string, value []byte, duration time.Duration) error {
+	if _, err := provider.pool.Exec(provider.ctx, provider.upsertQuery(), key, value, expiresAt(duration)); err != nil {
+		provider.logger.Errorf("Impossible to set value into Postgres, %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Touch method makes the key expire after ttl, updating its expiration in
+// place.
+func (provider *Postgres) Touch(key string, ttl time.Duration) error {
+	tag, err := provider.pool.Exec(
+		provider.ctx,
+		`UPDATE `+provider.table+` SET expires_at = $2 WHERE key = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		key, expiresAt(ttl),
+	)
+	if err != nil {
+		provider.logger.Errorf("Impossible to touch the key %s in Postgres, %v", key, err)
+
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return core.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// Delete method will delete the response in Postgres provider if exists corresponding to key param.
+func (provider *Postgres) Delete(key string) {
+	if _, err := provider.pool.Exec(provider.ctx, `DELETE FROM `+provider.table+` WHERE key = $1`, key); err != nil {
+		provider.logger.Errorf("Impossible to delete the key %s in Postgres, %v", key, err)
+	}
+}
+
+// DeleteMany method will delete the responses in Postgres provider if exists corresponding to the regex key param.
+// The keys are matched with the Go regexp syntax, not the Postgres one.
+func (provider *Postgres) DeleteMany(key string) {
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	rows, err := provider.pool.Query(provider.ctx, `SELECT key FROM `+provider.table)
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in Postgres, %v", err)
+
+		return
+	}
+
+	matched := []string{}
+
+	for rows.Next() {
+		var k string
+		if err = rows.Scan(&k); err == nil && rgKey.MatchString(k) {
+			matched = append(matched, k)
+		}
+	}
+
+	rows.Close()
+
+	for start := 0; start < len(matched); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(matched))
+
+		if _, err = provider.pool.Exec(provider.ctx, `DELETE FROM `+provider.table+` WHERE key = ANY($1)`, matched[start:end]); err != nil {
+			provider.logger.Errorf("Impossible to delete the keys in Postgres, %v", err)
+
+			return
+		}
+	}
+}
+
+// purge removes the expired rows by batches.
+func (provider *Postgres) purge() error {
+	for {
+		tag, err := provider.pool.Exec(
+			provider.ctx,
+			`DELETE FROM `+provider.table+` WHERE key IN (SELECT key FROM `+provider.table+` WHERE expires_at <= now() LIMIT $1)`,
+			purgeBatchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// Healthy acquires a connection from the pool and pings Postgres.
+func (provider *Postgres) Healthy(ctx context.Context) error {
+	return provider.pool.Ping(ctx)
+}
+
+// Init method will create the table if needed and start the background
+// purge of the expired rows.
+func (provider *Postgres) Init() error {
+	if provider.stop != nil {
+		return nil
+	}
+
+	if _, err := provider.pool.Exec(provider.ctx, `CREATE TABLE IF NOT EXISTS `+provider.table+` (
+	key TEXT PRIMARY KEY,
+	value BYTEA NOT NULL,
+	expires_at TIMESTAMPTZ
+)`); err != nil {
+		provider.logger.Errorf("Impossible to create the Postgres table %s, %v", provider.table, err)
+
+		return err
+	}
+
+	if _, err := provider.pool.Exec(
+		provider.ctx,
+		`CREATE INDEX IF NOT EXISTS `+provider.indexName+` ON `+provider.table+` (expires_at) WHERE expires_at IS NOT NULL`,
+	); err != nil {
+		provider.logger.Errorf("Impossible to create the Postgres expiration index, %v", err)
+
+		return err
+	}
+
+	provider.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(provider.purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(stop, "purge") {
+					return
+				}
+
+				if err := provider.purge(); err != nil {
+					provider.logger.Errorf("Impossible to purge the expired keys in Postgres, %v", err)
+				}
+			}
+		}
+	}(provider.stop)
+
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *Postgres) Reset() error {
+	if provider.stop != nil {
+		close(provider.stop)
+		provider.stop = nil
+	}
+
+	provider.pool.Close()
+
+	return nil
+}
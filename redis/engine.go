@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/darkweak/storages/core"
+)
+
+// The clients of the Redis provider, selected with the client configuration
+// key. rueidis is the default, go-redis talks RESP2 only to the servers and
+// proxies rejecting the rueidis handshake, e.g. old Redis or some managed
+// clouds.
+const (
+	clientRueidis = "rueidis"
+	clientGoRedis = "go-redis"
+)
+
+// parseClient returns the client selected by the configuration.
+func parseClient(configuration any) (string, error) {
+	redisConfig, ok := configuration.(map[string]interface{})
+	if !ok {
+		return clientRueidis, nil
+	}
+
+	switch client := redisConfig["client"]; client {
+	case nil, "", clientRueidis:
+		return clientRueidis, nil
+	case clientGoRedis:
+		return clientGoRedis, nil
+	default:
+		return "", fmt.Errorf("invalid redis configuration: unknown client %v, expected go-redis or rueidis", client)
+	}
+}
+
+// goRedisKeys are the rueidis configuration keys renamed in the go-redis
+// UniversalOptions.
+var goRedisKeys = map[string]string{
+	"InitAddress": "Addrs",
+	"SelectDB":    "DB",
+}
+
+// goRedisConfiguration adapts the configuration written for rueidis to the
+// go-redis provider, so switching the client keeps the addresses, the
+// database and the sentinels. The go-redis keys given as is are kept.
+func goRedisConfiguration(redisConfiguration core.CacheProvider) (core.CacheProvider, error) {
+	redisConfig, ok := redisConfiguration.Configuration.(map[string]interface{})
+	if !ok {
+		return redisConfiguration, nil
+	}
+
+	if value, ok := redisConfig["client_side_cache"].(map[string]interface{}); ok && value["enabled"] == true {
+		return redisConfiguration, fmt.Errorf("invalid redis configuration: the client_side_cache requires the %s client", clientRueidis)
+	}
+
+	if value, ok := redisConfig["purge_channel"].(string); ok && value != "" {
+		return redisConfiguration, fmt.Errorf("invalid redis configuration: the purge_channel requires the %s client", clientRueidis)
+	}
+
+	adapted := make(map[string]interface{}, len(redisConfig))
+
+	for key, value := range redisConfig {
+		switch key {
+		case "client", "cluster", "client_side_cache", "purge_channel", "sentinel", "flavor":
+			continue
+		}
+
+		if renamed, ok := goRedisKeys[key]; ok {
+			if _, given := redisConfig[renamed]; given {
+				continue
+			}
+
+			key = renamed
+		}
+
+		adapted[key] = value
+	}
+
+	// The sentinel addresses replace the init addresses.
+	adaptSentinel(redisConfig["sentinel"], adapted)
+
+	if address, ok := adapted["Addrs"].(string); ok {
+		adapted["Addrs"] = strings.Split(address, ",")
+	}
+
+	if _, ok := adapted["Addrs"]; !ok && redisConfiguration.URL != "" {
+		adapted["Addrs"] = strings.Split(redisConfiguration.URL, ",")
+	}
+
+	redisConfiguration.Configuration = adapted
+
+	return redisConfiguration, nil
+}
+
+// adaptSentinel sets the go-redis failover options from the sentinel block.
+func adaptSentinel(sentinel any, adapted map[string]interface{}) {
+	values, ok := sentinel.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for key, renamed := range map[string]string{
+		"master_name": "MasterName",
+		"username":    "SentinelUsername",
+		"password":    "SentinelPassword",
+		"addresses":   "Addrs",
+	} {
+		if value, ok := values[key]; ok {
+			adapted[renamed] = value
+		}
+	}
+}
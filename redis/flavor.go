@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	redis "github.com/redis/rueidis"
+)
+
+// The servers speaking the Redis protocol, selected with the flavor
+// configuration key to work around their differences.
+const (
+	flavorRedis = "redis"
+	// flavorKeyDB nodes may all be masters with the active replication,
+	// each of them holding the whole keyspace.
+	flavorKeyDB = "keydb"
+	// flavorDragonfly only sends the expired keyevent notifications.
+	flavorDragonfly = "dragonfly"
+)
+
+// parseFlavor returns the flavor set in the configuration.
+func parseFlavor(value any) (string, error) {
+	switch value {
+	case nil, "", flavorRedis:
+		return flavorRedis, nil
+	case flavorKeyDB, flavorDragonfly:
+		return value.(string), nil
+	default:
+		return "", fmt.Errorf("invalid redis configuration: unknown flavor %v, expected redis, keydb or dragonfly", value)
+	}
+}
+
+// isReplica reports whether the INFO role is a replica one, its keys are
+// counted by its master. Dragonfly reports "replica" where Redis and KeyDB
+// report "slave".
+func isReplica(role string) bool {
+	return role == "slave" || role == "replica"
+}
+
+// watchExpired streams the expirations of the keys starting with the prefix
+// from the expired keyevent notifications, the only ones Dragonfly sends.
+// They must be enabled on the server with --notify_keyspace_events=Ex.
+func (provider *Redis) watchExpired(ctx context.Context, prefix string) (<-chan core.Event, error) {
+	channel := fmt.Sprintf("__keyevent@%d__:expired", provider.configuration.SelectDB)
+	events := make(chan core.Event, core.EventsBufferSize)
+
+	go func() {
+		defer close(events)
+
+		for ctx.Err() == nil {
+			client := provider.inClient
+
+			err := client.Receive(ctx, client.B().Subscribe().Channel(channel).Build(), func(message redis.PubSubMessage) {
+				if strings.HasPrefix(message.Message, prefix) {
+					core.SendEvent(ctx, events, core.Event{Kind: core.EventExpire, Key: message.Message})
+				}
+			})
+			if err != nil && !errors.Is(err, context.Canceled) {
+				provider.logger.Errorf("Impossible to receive the Redis expired keyevent notifications, %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -1,18 +1,31 @@
 module github.com/darkweak/storages/redis
 
-go 1.22.1
+go 1.24.9
 
-replace github.com/darkweak/storages/core => ../core
+replace (
+	github.com/darkweak/storages/core => ../core
+	github.com/darkweak/storages/go-redis => ../go-redis
+)
 
 require (
 	github.com/darkweak/storages/core v0.0.19
-	github.com/pierrec/lz4/v4 v4.1.23
+	github.com/darkweak/storages/go-redis v0.0.19
 	github.com/redis/rueidis v1.0.73
 	go.uber.org/zap v1.27.0
 )
 
 require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/go-viper/mapstructure/v2 v2.2.1 // indirect
+	github.com/klauspost/compress v1.18.4 // indirect
+	github.com/pierrec/lz4/v4 v4.1.23 // indirect
+	github.com/redis/go-redis/v9 v9.18.0 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	go.uber.org/atomic v1.11.0 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
 	google.golang.org/protobuf v1.36.5 // indirect
 )
@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	redis "github.com/redis/rueidis"
+)
+
+// PublishInvalidation publishes the event on the channel.
+func (provider *Redis) PublishInvalidation(channel string, event core.InvalidationEvent) error {
+	payload, err := event.Encode()
+	if err != nil {
+		return err
+	}
+
+	return provider.write(provider.inClient.B().Publish().Channel(channel).Message(string(payload)).Build()).Error()
+}
+
+// SubscribeInvalidations subscribes to the channel and calls fn with every
+// event published on it. The subscription is made again on the current
+// client when it's lost, e.g. by a reload.
+func (provider *Redis) SubscribeInvalidations(channel string, fn func(core.InvalidationEvent)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		for ctx.Err() == nil {
+			client := provider.inClient
+
+			err := client.Receive(ctx, client.B().Subscribe().Channel(channel).Build(), func(message redis.PubSubMessage) {
+				event, err := core.DecodeInvalidationEvent([]byte(message.Message))
+				if err != nil {
+					provider.logger.Errorf("Impossible to decode the Redis invalidation, %v", err)
+
+					return
+				}
+
+				fn(event)
+			})
+			if err != nil && !errors.Is(err, context.Canceled) {
+				provider.logger.Errorf("Impossible to receive the Redis invalidations, %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	return cancel, nil
+}
+
+// publishPurge publishes the purge on the purge channel when configured.
+func (provider *Redis) publishPurge(kind core.InvalidationKind, key string) {
+	if provider.purgeChannel == "" {
+		return
+	}
+
+	err := provider.PublishInvalidation(provider.purgeChannel, core.InvalidationEvent{Kind: kind, Key: key, Origin: provider.origin})
+	if err != nil {
+		provider.logger.Errorf("Impossible to publish the purge of %s on the Redis channel %s, %v", key, provider.purgeChannel, err)
+	}
+}
+
+// subscribePurges applies the purges published by the other instances on
+// the purge channel when configured.
+func (provider *Redis) subscribePurges() error {
+	if provider.purgeChannel == "" || provider.stopPurges != nil {
+		return nil
+	}
+
+	stop, err := provider.SubscribeInvalidations(provider.purgeChannel, func(event core.InvalidationEvent) {
+		if event.Origin != provider.origin {
+			provider.purge(event)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	provider.stopPurges = stop
+
+	return nil
+}
+
+// purge applies the purge without publishing it.
+func (provider *Redis) purge(event core.InvalidationEvent) {
+	switch event.Kind {
+	case core.InvalidateKey:
+		_ = provider.write(provider.inClient.B().Del().Key(event.Key).Build())
+	case core.InvalidatePattern:
+		_, _ = provider.deleteMany(event.Key, false)
+	case core.InvalidatePrefix:
+		_, _ = provider.deleteMany("^"+regexp.QuoteMeta(event.Key), false)
+	case core.InvalidateTag:
+		_ = provider.unlink(append(core.SurrogateKeys(provider, event.Key), core.SurrogateKeyPrefix+event.Key))
+	}
+}
+
+// PurgePrefix deletes the keys starting with the prefix and publishes the
+// purge on the purge channel.
+func (provider *Redis) PurgePrefix(prefix string) {
+	provider.purge(core.InvalidationEvent{Kind: core.InvalidatePrefix, Key: prefix})
+	provider.publishPurge(core.InvalidatePrefix, prefix)
+}
+
+// PurgeTag deletes the keys listed by the surrogate key of the tag with the
+// surrogate key itself, then publishes the purge on the purge channel.
+func (provider *Redis) PurgeTag(tag string) {
+	provider.purge(core.InvalidationEvent{Kind: core.InvalidateTag, Key: tag})
+	provider.publishPurge(core.InvalidateTag, tag)
+}
@@ -1,18 +1,21 @@
 package redis
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/darkweak/storages/core"
-	"github.com/pierrec/lz4/v4"
+	goredis "github.com/darkweak/storages/go-redis"
 	redis "github.com/redis/rueidis"
 )
 
@@ -20,27 +23,216 @@ import (
 type Redis struct {
 	inClient      redis.Client
 	stale         time.Duration
+	uuid          string
 	ctx           context.Context
 	logger        core.Logger
+	compressor    core.Compressor
 	configuration redis.ClientOption
 	close         func()
 	hashtags      string
+	cluster       bool
+	streamer      *core.ChunkedStreamer
+	// cacheTTL bounds the client-side cached reads, they're disabled when 0.
+	cacheTTL time.Duration
+	timeouts core.Timeouts
+	// locks keeps the token of each lock held by this instance.
+	locks sync.Map
+	// purgeChannel carries the purges between the instances, they're not
+	// published when empty.
+	purgeChannel string
+	origin       string
+	flavor       string
+	stopPurges   func()
+	// deleteWorkers bounds the nodes scanned and purged at once by
+	// DeleteMany.
+	deleteWorkers int
+	// limiter spaces the SCAN calls of the key listings and the purges.
+	limiter *core.BackgroundLimiter
+	// evictions are notified with the expired keyspace notifications.
+	evictions     core.EvictionCallbacks
+	evictionMu    sync.Mutex
+	stopEvictions func()
 }
 
-// Factory function create new Redis instance.
-func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+// unlockScript deletes the lock only if it still holds the owner token, a
+// lock expired then acquired by another instance is never released.
+var unlockScript = redis.NewLuaScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+
+// mappingScript writes the mapping only if it still holds the value read
+// before the update, the empty string standing for a missing mapping. The
+// value KEYS[2] is written with its PX expiration in the same script so it
+// never exists without its mapping.
+var mappingScript = redis.NewLuaScript(`if (redis.call("GET", KEYS[1]) or "") ~= ARGV[1] then return 0 end
+redis.call("SET", KEYS[2], ARGV[3], "PX", ARGV[4])
+redis.call("SET", KEYS[1], ARGV[2])
+return 1`)
+
+// incrementScript increments the counter with INCRBY then sets its PX
+// expiration when ARGV[2] is positive, or removes it.
+var incrementScript = redis.NewLuaScript(`local counter = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 then redis.call("PEXPIRE", KEYS[1], ARGV[2]) else redis.call("PERSIST", KEYS[1]) end
+return counter`)
+
+// compareAndSwapScript replaces the value only if it still equals the old
+// one, with the PX expiration when ARGV[3] is positive.
+var compareAndSwapScript = redis.NewLuaScript(`if redis.call("GET", KEYS[1]) ~= ARGV[1] then return 0 end
+if tonumber(ARGV[3]) > 0 then redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3]) else redis.call("SET", KEYS[1], ARGV[2]) end
+return 1`)
+
+// parseSentinel configures the client to discover the master through the
+// sentinels, the sentinel addresses replace the init addresses.
+func parseSentinel(sentinel map[string]interface{}, options *redis.ClientOption) {
+	if value, ok := sentinel["master_name"].(string); ok {
+		options.Sentinel.MasterSet = value
+	}
+
+	if value, ok := sentinel["username"].(string); ok {
+		options.Sentinel.Username = value
+	}
+
+	if value, ok := sentinel["password"].(string); ok {
+		options.Sentinel.Password = value
+	}
+
+	switch addresses := sentinel["addresses"].(type) {
+	case string:
+		options.InitAddress = strings.Split(addresses, ",")
+	case []interface{}:
+		options.InitAddress = []string{}
+
+		for _, address := range addresses {
+			if v, ok := address.(string); ok {
+				options.InitAddress = append(options.InitAddress, v)
+			}
+		}
+	}
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("redis", Factory)
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(redisConfiguration any) error {
+	client, err := parseClient(redisConfiguration)
+	if err != nil {
+		return err
+	}
+
+	if client == clientGoRedis {
+		adapted, err := goRedisConfiguration(core.CacheProvider{Configuration: redisConfiguration})
+		if err != nil {
+			return err
+		}
+
+		return goredis.Validate(adapted.Configuration)
+	}
+
+	bc, err := json.Marshal(redisConfiguration)
+	if err == nil {
+		err = json.Unmarshal(bc, &redis.ClientOption{})
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid redis configuration: %w", err)
+	}
+
+	if cfg, ok := redisConfiguration.(map[string]interface{}); ok {
+		if value, ok := cfg["client_side_cache"]; ok {
+			if _, err = parseClientSideCache(value, &redis.ClientOption{}); err != nil {
+				return err
+			}
+		}
+
+		_, err = parseFlavor(cfg["flavor"])
+	}
+
+	return err
+}
+
+// settings are the client options and the provider settings read from the
+// configuration.
+type settings struct {
+	options    redis.ClientOption
+	hashtags   string
+	cluster    bool
+	compressor core.Compressor
+	cacheTTL   time.Duration
+	timeouts   core.Timeouts
+	// purgeChannel is the pub/sub channel of the purges.
+	purgeChannel string
+	flavor       string
+	// deleteWorkers bounds the nodes scanned and purged at once by
+	// DeleteMany.
+	deleteWorkers int
+	// limiter spaces the SCAN calls.
+	limiter *core.BackgroundLimiter
+}
+
+const defaultClientSideCacheTTL = time.Minute
+
+// clientSideCache enables the RESP3 client-side caching: the reads are
+// served from the connection memory and Redis invalidates them once the
+// keys are updated.
+type clientSideCache struct {
+	Enabled bool `json:"enabled"`
+	// TTL bounds how long a read is cached, 1m by default.
+	TTL time.Duration `json:"ttl"`
+	// SizeEachConn is the cache size of each connection in bytes, the
+	// rueidis default (128MB) when 0.
+	SizeEachConn int `json:"size_each_conn"`
+}
+
+func parseClientSideCache(configuration any, options *redis.ClientOption) (time.Duration, error) {
+	cacheConfiguration := clientSideCache{TTL: defaultClientSideCacheTTL}
+	if err := core.DecodeConfiguration(configuration, &cacheConfiguration); err != nil {
+		return 0, fmt.Errorf("invalid redis configuration: client_side_cache: %w", err)
+	}
+
+	if !cacheConfiguration.Enabled {
+		return 0, nil
+	}
+
+	if cacheConfiguration.TTL <= 0 {
+		return 0, fmt.Errorf("invalid redis configuration: client_side_cache: the ttl must be positive, %s given", cacheConfiguration.TTL)
+	}
+
+	if cacheConfiguration.SizeEachConn < 0 {
+		return 0, fmt.Errorf("invalid redis configuration: client_side_cache: the size_each_conn can't be negative, %d given", cacheConfiguration.SizeEachConn)
+	}
+
+	options.DisableCache = false
+	if cacheConfiguration.SizeEachConn > 0 {
+		options.CacheSizeEachConn = cacheConfiguration.SizeEachConn
+	}
+
+	return cacheConfiguration.TTL, nil
+}
+
+func parseSettings(redisConfiguration core.CacheProvider, logger core.Logger) (settings, error) {
 	var options redis.ClientOption
 
 	var hashtags string
 
+	var cluster bool
+
+	var cacheTTL time.Duration
+
+	var purgeChannel string
+
+	flavor := flavorRedis
+
 	redisConfig, err := json.Marshal(redisConfiguration.Configuration)
 	if err != nil {
-		return nil, err
+		return settings{}, err
 	}
 
 	if redisConfiguration.Configuration != nil {
 		if err := json.Unmarshal(redisConfig, &options); err != nil {
-			logger.Infof("Cannot parse your redis configuration: %+v", err)
+			logger.Errorf("Cannot parse your redis configuration: %+v", err)
+
+			return settings{}, fmt.Errorf("invalid redis configuration: %w", err)
 		}
 
 		if redisConfig, ok := redisConfiguration.Configuration.(map[string]interface{}); ok && redisConfig != nil {
@@ -49,6 +241,37 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 					hashtags = v
 				}
 			}
+
+			if value, ok := redisConfig["cluster"]; ok {
+				switch v := value.(type) {
+				case bool:
+					cluster = v
+				case string:
+					cluster, _ = strconv.ParseBool(v)
+				}
+			}
+
+			if value, ok := redisConfig["purge_channel"].(string); ok {
+				purgeChannel = value
+			}
+
+			if flavor, err = parseFlavor(redisConfig["flavor"]); err != nil {
+				return settings{}, err
+			}
+
+			if value, ok := redisConfig["sentinel"].(map[string]interface{}); ok {
+				parseSentinel(value, &options)
+			}
+
+			if value, ok := redisConfig["client_side_cache"]; ok {
+				if cacheTTL, err = parseClientSideCache(value, &options); err != nil {
+					return settings{}, err
+				}
+			}
+		}
+
+		if len(options.InitAddress) == 0 && redisConfiguration.URL != "" {
+			options.InitAddress = strings.Split(redisConfiguration.URL, ",")
 		}
 	} else {
 		options = redis.ClientOption{
@@ -63,23 +286,279 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 	}
 
 	if len(options.InitAddress) == 0 {
-		return nil, errors.New("no redis addresses given")
+		return settings{}, errors.New("no redis addresses given")
+	}
+
+	if cluster {
+		options.ShuffleInit = true
+		options.ForceSingleClient = false
+	}
+
+	compressor, err := core.CompressorFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
+
+	if tlsConfig != nil {
+		options.TLSConfig = tlsConfig
+		options.Sentinel.TLSConfig = tlsConfig
+	}
+
+	timeouts, err := core.TimeoutsFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
+
+	deleteWorkers, err := core.DeleteManyWorkersFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
 	}
 
-	cli, err := redis.NewClient(options)
+	limiter, err := core.BackgroundLimiterFromConfiguration(redisConfiguration.Configuration)
+	if err != nil {
+		return settings{}, err
+	}
+
+	return settings{options: options, hashtags: hashtags, cluster: cluster, compressor: compressor, cacheTTL: cacheTTL, timeouts: timeouts, purgeChannel: purgeChannel, flavor: flavor, deleteWorkers: deleteWorkers, limiter: limiter}, nil
+}
+
+// Factory function create new Redis instance, or a go-redis one when the
+// go-redis client is selected.
+func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	client, err := parseClient(redisConfiguration.Configuration)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Redis{
+	if client == clientGoRedis {
+		adapted, err := goRedisConfiguration(redisConfiguration)
+		if err != nil {
+			return nil, err
+		}
+
+		return goredis.Factory(adapted, logger, stale)
+	}
+
+	parsed, err := parseSettings(redisConfiguration, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := redis.NewClient(parsed.options)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &Redis{
 		inClient:      cli,
 		ctx:           context.Background(),
 		stale:         stale,
-		configuration: options,
+		uuid:          core.InstanceID("redis", redisConfiguration, stale),
+		configuration: parsed.options,
 		logger:        logger,
+		compressor:    parsed.compressor,
 		close:         cli.Close,
-		hashtags:      hashtags,
-	}, err
+		hashtags:      parsed.hashtags,
+		cluster:       parsed.cluster,
+		cacheTTL:      parsed.cacheTTL,
+		timeouts:      parsed.timeouts,
+		purgeChannel:  parsed.purgeChannel,
+		flavor:        parsed.flavor,
+		deleteWorkers: parsed.deleteWorkers,
+		limiter:       parsed.limiter,
+		origin:        core.LockToken(),
+	}
+	instance.streamer = core.NewChunkedStreamer(instance, core.ChunkSizeFromConfiguration(redisConfiguration.Configuration))
+
+	return instance, err
+}
+
+// Reload connects a new client with the given configuration then closes the
+// previous one, the stored keys live in Redis and are kept. The client
+// can't be switched by a reload.
+func (provider *Redis) Reload(redisConfiguration core.CacheProvider) error {
+	client, err := parseClient(redisConfiguration.Configuration)
+	if err != nil {
+		return err
+	}
+
+	if client != clientRueidis {
+		return fmt.Errorf("the redis client can't be switched to %s by a reload", client)
+	}
+
+	parsed, err := parseSettings(redisConfiguration, provider.logger)
+	if err != nil {
+		return err
+	}
+
+	cli, err := redis.NewClient(parsed.options)
+	if err != nil {
+		provider.logger.Errorf("Impossible to reload the Redis client, %v", err)
+
+		return err
+	}
+
+	previous := provider.close
+	provider.inClient = cli
+	provider.close = cli.Close
+	provider.configuration = parsed.options
+	provider.compressor = parsed.compressor
+	provider.hashtags = parsed.hashtags
+	provider.cluster = parsed.cluster
+	provider.cacheTTL = parsed.cacheTTL
+	provider.timeouts = parsed.timeouts
+	provider.flavor = parsed.flavor
+	provider.deleteWorkers = parsed.deleteWorkers
+	provider.limiter = parsed.limiter
+	provider.streamer = core.NewChunkedStreamer(provider, core.ChunkSizeFromConfiguration(redisConfiguration.Configuration))
+
+	if previous != nil {
+		previous()
+	}
+
+	return nil
+}
+
+// read runs the command bounded by the read timeout.
+func (provider *Redis) read(client redis.Client, cmd redis.Completed) redis.RedisResult {
+	ctx, cancel := provider.timeouts.ReadContext(provider.ctx)
+	defer cancel()
+
+	return client.Do(ctx, cmd)
+}
+
+// write runs the command bounded by the write timeout.
+func (provider *Redis) write(cmd redis.Completed) redis.RedisResult {
+	ctx, cancel := provider.timeouts.WriteContext(provider.ctx)
+	defer cancel()
+
+	return provider.inClient.Do(ctx, cmd)
+}
+
+// get reads the key from the client-side cache when it's enabled.
+func (provider *Redis) get(key string) redis.RedisResult {
+	if provider.cacheTTL > 0 {
+		ctx, cancel := provider.timeouts.ReadContext(provider.ctx)
+		defer cancel()
+
+		return provider.inClient.DoCache(ctx, provider.inClient.B().Get().Key(key).Cache(), provider.cacheTTL)
+	}
+
+	return provider.read(provider.inClient, provider.inClient.B().Get().Key(key).Build())
+}
+
+// hashTag returns the prefix that makes the value and the mapping of the
+// given base key land on the same cluster slot. The configured HashTag wins,
+// otherwise the base key is used as hash tag in cluster mode.
+func (provider *Redis) hashTag(baseKey string) string {
+	if provider.hashtags != "" || !provider.cluster {
+		return provider.hashtags
+	}
+
+	return "{" + baseKey + "}"
+}
+
+// MappingKey returns the key the mapping of the base key is stored under,
+// behind its hash tag.
+func (provider *Redis) MappingKey(baseKey string) string {
+	return provider.hashTag(baseKey) + core.MappingKeyPrefix + baseKey
+}
+
+// mappingPattern returns the SCAN pattern matching every mapping key.
+func (provider *Redis) mappingPattern() string {
+	if provider.hashtags == "" && provider.cluster {
+		return "{*}" + core.MappingKeyPrefix + "*"
+	}
+
+	return provider.hashtags + core.MappingKeyPrefix + "*"
+}
+
+// WalkMappings streams the keys starting with the prefix behind their hash
+// tag, scanning node by node in bounded batches. The keys given to fn are
+// stripped of the hash tag and of the prefix.
+func (provider *Redis) WalkMappings(prefix string, walkFn func(key string, value []byte) bool) error {
+	pattern := provider.hashtags + prefix + "*"
+	if provider.hashtags == "" && provider.cluster {
+		pattern = "{*}" + prefix + "*"
+	}
+
+	provider.scan(pattern, func(keys []string) bool {
+		for _, element := range keys {
+			key := strings.TrimPrefix(element, provider.hashtags)
+			if provider.hashtags == "" && provider.cluster {
+				_, key, _ = strings.Cut(key, "}")
+			}
+
+			value := provider.Get(element)
+			if value == nil {
+				continue
+			}
+
+			if !walkFn(strings.TrimPrefix(key, prefix), value) {
+				return false
+			}
+		}
+
+		return true
+	})
+
+	return nil
+}
+
+// scan walks the keys matching the pattern on every node, each cluster
+// master only owns a part of the keyspace. The keys seen on several nodes,
+// like on the replicas, are only given once. The walk stops when fn returns
+// false.
+func (provider *Redis) scan(pattern string, fn func(keys []string) bool) {
+	seen := map[string]struct{}{}
+
+	for _, node := range provider.inClient.Nodes() {
+		more, _ := provider.scanNode(node, pattern, func(elements []string) bool {
+			keys := make([]string, 0, len(elements))
+
+			for _, element := range elements {
+				if _, found := seen[element]; !found {
+					seen[element] = struct{}{}
+					keys = append(keys, element)
+				}
+			}
+
+			return fn(keys)
+		})
+
+		if !more {
+			return
+		}
+	}
+}
+
+// scanNode walks the keys matching the pattern on the node, it returns false
+// when fn stopped the walk. Each SCAN call waits for the background limiter.
+func (provider *Redis) scanNode(node redis.Client, pattern string, fn func(keys []string) bool) (bool, error) {
+	var scan redis.ScanEntry
+
+	var err error
+
+	for more := true; more; more = scan.Cursor != 0 {
+		provider.limiter.Wait(provider.ctx.Done(), "scan")
+
+		if scan, err = provider.read(node, node.B().Scan().Cursor(scan.Cursor).Match(pattern).Count(100).Build()).AsScanEntry(); err != nil {
+			provider.logger.Errorf("Cannot scan: %v", err)
+
+			return true, err
+		}
+
+		if !fn(scan.Elements) {
+			return false, nil
+		}
+	}
+
+	return true, nil
 }
 
 // Name returns the storer name.
@@ -89,70 +568,113 @@ func (provider *Redis) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Redis) Uuid() string {
-	return fmt.Sprintf(
-		"%s-%s-%d-%s-%s",
-		strings.Join(provider.configuration.InitAddress, ","),
-		provider.configuration.Username,
-		provider.configuration.SelectDB,
-		provider.configuration.ClientName,
-		provider.stale,
-	)
+	return provider.uuid
 }
 
 // ListKeys method returns the list of existing keys.
 func (provider *Redis) ListKeys() []string {
-	var scan redis.ScanEntry
-
-	var err error
-
 	elements := []string{}
 
 	provider.logger.Debugf("Call the ListKeys function in redis")
 
-	for more := true; more; more = scan.Cursor != 0 {
-		if scan, err = provider.inClient.Do(provider.ctx, provider.inClient.B().Scan().Cursor(scan.Cursor).Match(provider.hashtags+core.MappingKeyPrefix+"*").Count(100).Build()).AsScanEntry(); err != nil {
-			provider.logger.Errorf("Cannot scan: %v", err)
-		}
+	_ = provider.WalkKeys(func(key string) bool {
+		elements = append(elements, key)
 
-		for _, element := range scan.Elements {
-			value := provider.Get(element)
+		return true
+	})
 
-			mapping, err := core.DecodeMapping(value)
-			if err != nil {
-				continue
-			}
+	return elements
+}
 
-			for _, v := range mapping.GetMapping() {
-				if v.GetFreshTime().AsTime().Before(time.Now()) && v.GetStaleTime().AsTime().Before(time.Now()) {
-					continue
+// WalkKeys streams the keys returned by ListKeys, scanning the mappings node
+// by node in bounded batches.
+func (provider *Redis) WalkKeys(walkFn func(key string) bool) error {
+	provider.scan(provider.mappingPattern(), func(keys []string) bool {
+		for _, element := range keys {
+			for _, key := range core.MappingRealKeys(provider.Get(element), time.Now()) {
+				if !walkFn(key) {
+					return false
 				}
-
-				elements = append(elements, v.GetRealKey())
 			}
 		}
+
+		return true
+	})
+
+	return nil
+}
+
+// ListKeysPaginated returns a page of the keys returned by ListKeys. The
+// cursor holds the index of the scanned node, in the sorted node addresses
+// order, and its SCAN cursor.
+func (provider *Redis) ListKeysPaginated(cursor string, limit int) ([]string, string) {
+	keys := []string{}
+
+	var (
+		node     int
+		position uint64
+	)
+
+	if cursor != "" {
+		if _, err := fmt.Sscanf(cursor, "%d:%d", &node, &position); err != nil {
+			provider.logger.Errorf("Invalid redis keys cursor %s, %v", cursor, err)
+
+			return keys, ""
+		}
 	}
 
-	return elements
+	nodes := provider.inClient.Nodes()
+	addresses := make([]string, 0, len(nodes))
+
+	for address := range nodes {
+		addresses = append(addresses, address)
+	}
+
+	slices.Sort(addresses)
+
+	if node < 0 || node >= len(addresses) {
+		return keys, ""
+	}
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	client := nodes[addresses[node]]
+
+	scan, err := provider.read(client, client.B().Scan().Cursor(position).Match(provider.mappingPattern()).Count(int64(limit)).Build()).AsScanEntry()
+	if err != nil {
+		provider.logger.Errorf("Cannot scan: %v", err)
+
+		return keys, cursor
+	}
+
+	for _, element := range scan.Elements {
+		keys = append(keys, core.MappingRealKeys(provider.Get(element), time.Now())...)
+	}
+
+	switch {
+	case scan.Cursor != 0:
+		return keys, fmt.Sprintf("%d:%d", node, scan.Cursor)
+	case node+1 < len(addresses):
+		return keys, fmt.Sprintf("%d:0", node+1)
+	default:
+		return keys, ""
+	}
 }
 
 // MapKeys method returns the list of existing keys.
 func (provider *Redis) MapKeys(prefix string) map[string]string {
-	var scan redis.ScanEntry
-
-	var err error
-
 	kvStore := map[string]string{}
 	elements := []string{}
 
 	provider.logger.Debugf("Call the MapKeys in redis with the prefix %s", prefix)
 
-	for more := true; more; more = scan.Cursor != 0 {
-		if scan, err = provider.inClient.Do(provider.ctx, provider.inClient.B().Scan().Cursor(scan.Cursor).Match(prefix+"*").Count(100).Build()).AsScanEntry(); err != nil {
-			provider.logger.Errorf("Cannot scan: %v", err)
-		}
+	provider.scan(prefix+"*", func(keys []string) bool {
+		elements = append(elements, keys...)
 
-		elements = append(elements, scan.Elements...)
-	}
+		return true
+	})
 
 	for _, key := range elements {
 		k, _ := strings.CutPrefix(key, prefix)
@@ -164,72 +686,145 @@ func (provider *Redis) MapKeys(prefix string) map[string]string {
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Redis) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	b, e := provider.inClient.Do(provider.ctx, provider.inClient.B().Get().Key(provider.hashtags+core.MappingKeyPrefix+key).Build()).AsBytes()
+	b, e := provider.get(provider.MappingKey(key)).AsBytes()
 	if e != nil {
 		return
 	}
 
-	fresh, stale, _ = core.MappingElection(provider, b, req, validator, provider.logger)
+	fresh, stale, _ = core.MappingElectionFor(provider, provider.MappingKey(key), b, req, validator, provider.logger)
 
 	return
 }
 
+// GetMetadata returns the metadata of the varied keys stored for the base
+// key, its mapping is stored behind the hash tag.
+func (provider *Redis) GetMetadata(key string) ([]core.KeyMetadata, error) {
+	b, err := provider.get(provider.MappingKey(key)).AsBytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	return core.DecodeMetadata(b)
+}
+
 // SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
 func (provider *Redis) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
+	hashTag := provider.hashTag(baseKey)
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
 		provider.logger.Errorf("Impossible to compress the key %s into Redis, %v", variedKey, err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Redis, %v", variedKey, err)
+	mappingKey := provider.MappingKey(baseKey)
 
-		return err
-	}
+	var evicted []string
 
-	if err := provider.inClient.Do(provider.ctx, provider.inClient.B().Set().Key(provider.hashtags+variedKey).Value(compressed.String()).Ex(duration+provider.stale).Build()).Error(); err != nil {
-		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
+	err = core.UpdateMapping(func() error {
+		v, err := provider.read(provider.inClient, provider.inClient.B().Get().Key(mappingKey).Build()).AsBytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
 
-		return err
-	}
+		var val []byte
 
-	mappingKey := provider.hashtags + core.MappingKeyPrefix + baseKey
+		val, evicted, err = core.MappingUpdaterWithEvictions(hashTag+variedKey, v, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+		if err != nil {
+			return err
+		}
 
-	v, err := provider.inClient.Do(provider.ctx, provider.inClient.B().Get().Key(mappingKey).Build()).AsBytes()
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return err
-	}
+		ctx, cancel := provider.timeouts.WriteContext(provider.ctx)
+		defer cancel()
+
+		written, err := mappingScript.Exec(
+			ctx,
+			provider.inClient,
+			[]string{mappingKey, hashTag + variedKey},
+			[]string{string(v), string(val), string(compressed), strconv.FormatInt((duration + provider.stale).Milliseconds(), 10)},
+		).AsInt64()
+		if err != nil {
+			return err
+		}
 
-	val, err := core.MappingUpdater(provider.hashtags+variedKey, v, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+		if written == 0 {
+			return core.ErrMappingConflict
+		}
+
+		return nil
+	})
 	if err != nil {
+		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
+
 		return err
 	}
 
-	if err = provider.inClient.Do(provider.ctx, provider.inClient.B().Set().Key(mappingKey).Value(string(val)).Build()).Error(); err != nil {
-		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
+	// The evicted variants share the hash tag of the mapping.
+	if len(evicted) > 0 {
+		_ = provider.unlink(evicted)
 	}
 
-	return err
+	return nil
 }
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Redis) Get(key string) []byte {
-	r, e := provider.inClient.Do(provider.ctx, provider.inClient.B().Get().Key(key).Build()).AsBytes()
-	if e != nil && !errors.Is(e, redis.Nil) {
-		return nil
-	}
+	r, _ := provider.Lookup(key)
 
 	return r
 }
 
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist.
+func (provider *Redis) Lookup(key string) ([]byte, error) {
+	r, err := provider.get(key).AsBytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetMany method returns the values of the found keys in one MGET per slot,
+// from the client-side cache when it's enabled.
+func (provider *Redis) GetMany(keys []string) map[string][]byte {
+	ctx, cancel := provider.timeouts.ReadContext(provider.ctx)
+	defer cancel()
+
+	var (
+		messages map[string]redis.RedisMessage
+		err      error
+	)
+
+	if provider.cacheTTL > 0 {
+		messages, err = redis.MGetCache(provider.inClient, ctx, provider.cacheTTL, keys)
+	} else {
+		messages, err = redis.MGet(provider.inClient, ctx, keys)
+	}
+
+	values := make(map[string][]byte, len(messages))
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the keys from Redis, %v", err)
+
+		return values
+	}
+
+	for key, message := range messages {
+		if value, err := message.AsBytes(); err == nil {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
 // Set method will store the response in Redis provider.
 func (provider *Redis) Set(key string, value []byte, duration time.Duration) error {
 	var cmd redis.Completed
@@ -239,7 +834,7 @@ func (provider *Redis) Set(key string, value []byte, duration time.Duration) err
 		cmd = provider.inClient.B().Set().Key(key).Value(string(value)).Ex(duration + provider.stale).Build()
 	}
 
-	err := provider.inClient.Do(provider.ctx, cmd).Error()
+	err := provider.write(cmd).Error()
 	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
 	}
@@ -247,53 +842,370 @@ func (provider *Redis) Set(key string, value []byte, duration time.Duration) err
 	return err
 }
 
+// SetReader method will store the value read from r in chunks.
+func (provider *Redis) SetReader(key string, r io.Reader, ttl time.Duration) error {
+	return provider.streamer.SetReader(key, r, ttl)
+}
+
+// GetReader method returns a reader loading the chunks on demand.
+func (provider *Redis) GetReader(key string) (io.ReadCloser, error) {
+	return provider.streamer.GetReader(key)
+}
+
 // Delete method will delete the response in Redis provider if exists corresponding to key param.
 func (provider *Redis) Delete(key string) {
-	_ = provider.inClient.Do(provider.ctx, provider.inClient.B().Del().Key(key).Build())
+	_ = provider.write(provider.inClient.B().Del().Key(key).Build())
+
+	provider.publishPurge(core.InvalidateKey, key)
 }
 
 // DeleteMany method will delete the responses in Redis provider if exists corresponding to the regex key param.
 func (provider *Redis) DeleteMany(key string) {
 	provider.logger.Debugf("Call the DeleteMany function in redis")
 
-	var err error
+	_, _ = provider.deleteMany(key, false)
 
-	var scan redis.ScanEntry
+	provider.publishPurge(core.InvalidatePattern, key)
+}
+
+// DeleteManyCount deletes the keys matching the regular expression, the SCAN
+// only matches the keys starting with its literal prefix. In dry-run the
+// matching keys are listed without being deleted, otherwise the purge is
+// published on the purge channel.
+func (provider *Redis) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result, err := provider.deleteMany(pattern, dryRun)
+	if !dryRun && (err == nil || result.Count > 0) {
+		provider.publishPurge(core.InvalidatePattern, pattern)
+	}
 
-	rgKey, err := regexp.Compile(key)
+	return result, err
+}
+
+func (provider *Redis) deleteMany(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result := core.DeleteManyResult{}
+
+	rgKey, err := regexp.Compile(pattern)
 	if err != nil {
-		return
+		return result, err
 	}
 
-	for more := true; more; more = scan.Cursor != 0 {
-		if scan, err = provider.inClient.Do(provider.ctx, provider.inClient.B().Scan().Cursor(scan.Cursor).Match("*").Count(100).Build()).AsScanEntry(); err != nil {
-			provider.logger.Errorf("Cannot scan: %v", err)
+	match := escapeGlob(core.PatternPrefix(pattern)) + "*"
+
+	// The nodes are scanned and purged in parallel, the keys seen on several
+	// nodes, like on the replicas, are only counted once.
+	var mu sync.Mutex
+
+	seen := map[string]struct{}{}
+	tasks := []func() error{}
+
+	for _, node := range provider.inClient.Nodes() {
+		tasks = append(tasks, func() error {
+			nodeResult := core.DeleteManyResult{}
+			errs := []error{}
+
+			_, scanErr := provider.scanNode(node, match, func(keys []string) bool {
+				elements := []string{}
+
+				mu.Lock()
+
+				for _, element := range keys {
+					if _, found := seen[element]; !found && rgKey.MatchString(element) {
+						seen[element] = struct{}{}
+						elements = append(elements, element)
+					}
+				}
+
+				mu.Unlock()
+
+				for _, element := range elements {
+					nodeResult.Add(element, dryRun)
+				}
+
+				// only unlink item if elements are found in the current iteration
+				if !dryRun && len(elements) > 0 {
+					errs = append(errs, provider.unlink(elements))
+				}
+
+				return true
+			})
+
+			mu.Lock()
+			result.Merge(nodeResult)
+			mu.Unlock()
+
+			return errors.Join(append(errs, scanErr)...)
+		})
+	}
+
+	err = core.RunBounded(provider.deleteWorkers, tasks)
+	slices.Sort(result.Keys)
+
+	return result, err
+}
+
+// escapeGlob escapes the SCAN MATCH special characters.
+func escapeGlob(value string) string {
+	var escaped strings.Builder
+
+	for _, r := range value {
+		if strings.ContainsRune(`*?[]\`, r) {
+			escaped.WriteRune('\\')
 		}
 
-		elements := []string{}
+		escaped.WriteRune(r)
+	}
 
-		for _, element := range scan.Elements {
-			if rgKey.MatchString(element) {
-				elements = append(elements, element)
-			}
+	return escaped.String()
+}
+
+// unlink removes the keys at once, or one by one in cluster mode because a
+// multi keys command must target a single slot. It returns the joined
+// errors of the commands.
+func (provider *Redis) unlink(keys []string) error {
+	if !provider.cluster {
+		err := provider.write(provider.inClient.B().Unlink().Key(keys...).Build()).Error()
+		if err != nil {
+			provider.logger.Errorf("Cannot unlink: %v", err)
 		}
 
-		// only unlink item if elements are found in the current iteration
-		if len(elements) > 0 {
-			if err = provider.inClient.Do(provider.ctx, provider.inClient.B().Unlink().Key(elements...).Build()).Error(); err != nil {
-				provider.logger.Errorf("Cannot unlink: %v", err)
-			}
+		return err
+	}
+
+	cmds := make(redis.Commands, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, provider.inClient.B().Unlink().Key(key).Build())
+	}
+
+	ctx, cancel := provider.timeouts.WriteContext(provider.ctx)
+	defer cancel()
+
+	errs := []error{}
+
+	for _, result := range provider.inClient.DoMulti(ctx, cmds...) {
+		if err := result.Error(); err != nil {
+			provider.logger.Errorf("Cannot unlink: %v", err)
+
+			errs = append(errs, err)
 		}
 	}
+
+	return errors.Join(errs...)
 }
 
-// Init method will.
-func (provider *Redis) Init() error {
+// TryLock acquires the lock with SET NX PX, it fails without waiting if
+// another instance holds it.
+func (provider *Redis) TryLock(key string, ttl time.Duration) (bool, error) {
+	token := core.LockToken()
+	cmd := provider.inClient.B().Set().Key(core.LockKeyPrefix + key).Value(token).Nx().PxMilliseconds(ttl.Milliseconds()).Build()
+
+	err := provider.write(cmd).Error()
+	if redis.IsRedisNil(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to acquire the lock %s in Redis, %v", key, err)
+
+		return false, err
+	}
+
+	provider.locks.Store(key, token)
+
+	return true, nil
+}
+
+// Unlock releases the lock if this instance still holds it.
+func (provider *Redis) Unlock(key string) error {
+	token, held := provider.locks.LoadAndDelete(key)
+	if !held {
+		return nil
+	}
+
+	ctx, cancel := provider.timeouts.WriteContext(provider.ctx)
+	defer cancel()
+
+	err := unlockScript.Exec(ctx, provider.inClient, []string{core.LockKeyPrefix + key}, []string{token.(string)}).Error()
+	if err != nil {
+		provider.logger.Errorf("Impossible to release the lock %s in Redis, %v", key, err)
+	}
+
+	return err
+}
+
+// SetNX stores the value with SET NX only if the key doesn't exist.
+func (provider *Redis) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	var cmd redis.Completed
+	if ttl > 0 {
+		cmd = provider.inClient.B().Set().Key(key).Value(string(value)).Nx().PxMilliseconds(ttl.Milliseconds()).Build()
+	} else {
+		cmd = provider.inClient.B().Set().Key(key).Value(string(value)).Nx().Build()
+	}
+
+	err := provider.write(cmd).Error()
+	if redis.IsRedisNil(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s if absent into Redis, %v", key, err)
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CompareAndSwap replaces the value with a Lua script only if the stored one
+// equals old.
+func (provider *Redis) CompareAndSwap(key string, old, value []byte, ttl time.Duration) (bool, error) {
+	ctx, cancel := provider.timeouts.WriteContext(provider.ctx)
+	defer cancel()
+
+	swapped, err := compareAndSwapScript.Exec(ctx, provider.inClient, []string{key}, []string{string(old), string(value), strconv.FormatInt(ttl.Milliseconds(), 10)}).AsInt64()
+	if err != nil {
+		provider.logger.Errorf("Impossible to compare and swap the key %s into Redis, %v", key, err)
+
+		return false, err
+	}
+
+	return swapped == 1, nil
+}
+
+// RefreshTTL extends the expiration of the key in place with PEXPIRE.
+func (provider *Redis) RefreshTTL(key string, duration time.Duration) error {
+	refreshed, err := provider.write(provider.inClient.B().Pexpire().Key(key).Milliseconds(duration.Milliseconds()).Build()).AsInt64()
+	if err != nil {
+		provider.logger.Errorf("Impossible to refresh the TTL of the key %s into Redis, %v", key, err)
+
+		return err
+	}
+
+	if refreshed == 0 {
+		return core.ErrKeyNotFound
+	}
+
 	return nil
 }
 
+// GetTTL returns the remaining lifetime of the key with PTTL.
+func (provider *Redis) GetTTL(key string) (time.Duration, error) {
+	ttl, err := provider.read(provider.inClient, provider.inClient.B().Pttl().Key(key).Build()).AsInt64()
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the TTL of the key %s in Redis, %v", key, err)
+
+		return 0, err
+	}
+
+	switch ttl {
+	case -2:
+		return 0, core.ErrKeyNotFound
+	case -1:
+		return 0, nil
+	}
+
+	return time.Duration(ttl) * time.Millisecond, nil
+}
+
+// Increment adds delta to the counter with INCRBY in a Lua script setting
+// its expiration.
+func (provider *Redis) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	ctx, cancel := provider.timeouts.WriteContext(provider.ctx)
+	defer cancel()
+
+	counter, err := incrementScript.Exec(ctx, provider.inClient, []string{key}, []string{strconv.FormatInt(delta, 10), strconv.FormatInt(ttl.Milliseconds(), 10)}).AsInt64()
+	if err != nil {
+		provider.logger.Errorf("Impossible to increment the counter %s in Redis, %v", key, err)
+	}
+
+	return counter, err
+}
+
+// Healthy sends a PING to Redis.
+func (provider *Redis) Healthy(ctx context.Context) error {
+	return provider.inClient.Do(ctx, provider.inClient.B().Ping().Build()).Error()
+}
+
+// parseInfo returns the fields of an INFO reply.
+func parseInfo(info string) map[string]string {
+	fields := map[string]string{}
+
+	for _, line := range strings.Split(info, "\r\n") {
+		if name, value, found := strings.Cut(line, ":"); found && !strings.HasPrefix(line, "#") {
+			fields[name] = value
+		}
+	}
+
+	return fields
+}
+
+// Stats sums the INFO memory and stats subsets with the keys count of each
+// primary node. The hits and the misses are the server ones, the other
+// databases of the server are counted too. The KeyDB active replicas hold
+// the whole keyspace, the largest of them is counted once.
+func (provider *Redis) Stats() core.StorerStats {
+	var entries, used, evictions, hits, misses, activeEntries, activeUsed int64
+
+	for _, node := range provider.inClient.Nodes() {
+		info, err := provider.read(node, node.B().Info().Build()).ToString()
+		if err != nil {
+			provider.logger.Errorf("Impossible to get the Redis node info, %v", err)
+
+			continue
+		}
+
+		fields := parseInfo(info)
+		if isReplica(fields["role"]) {
+			continue
+		}
+
+		size, _ := provider.read(node, node.B().Dbsize().Build()).AsInt64()
+		memory, _ := strconv.ParseInt(fields["used_memory"], 10, 64)
+
+		if provider.flavor == flavorKeyDB && fields["role"] == "active-replica" {
+			activeEntries = max(activeEntries, size)
+			activeUsed = max(activeUsed, memory)
+		} else {
+			entries += size
+			used += memory
+		}
+
+		for field, counter := range map[string]*int64{
+			"evicted_keys":    &evictions,
+			"keyspace_hits":   &hits,
+			"keyspace_misses": &misses,
+		} {
+			value, _ := strconv.ParseInt(fields[field], 10, 64)
+			*counter += value
+		}
+	}
+
+	stats := core.NewStorerStats(hits, misses)
+	stats.Entries = entries + activeEntries
+	stats.Bytes = used + activeUsed
+	stats.Evictions = evictions
+
+	return stats
+}
+
+// Init method subscribes to the purge channel when configured.
+func (provider *Redis) Init() error {
+	return provider.subscribePurges()
+}
+
 // Reset method will reset or close provider.
 func (provider *Redis) Reset() error {
+	if provider.stopPurges != nil {
+		provider.stopPurges()
+		provider.stopPurges = nil
+	}
+
+	provider.evictionMu.Lock()
+	if provider.stopEvictions != nil {
+		provider.stopEvictions()
+		provider.stopEvictions = nil
+	}
+	provider.evictionMu.Unlock()
+
 	if provider.close != nil {
 		provider.close()
 	}
@@ -1,12 +1,19 @@
 package redis_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/core"
 	"github.com/darkweak/storages/redis"
+	"github.com/redis/rueidis"
 	"go.uber.org/zap"
 )
 
@@ -150,3 +157,570 @@ func TestRedis_DeleteMany(t *testing.T) {
 		t.Errorf("The map should be empty, %d given", len(client.MapKeys("")))
 	}
 }
+
+func TestRedis_ClusterHashTag(t *testing.T) {
+	client, err := redis.Factory(core.CacheProvider{
+		URL: "localhost:6379",
+		Configuration: map[string]interface{}{
+			"cluster": true,
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Redis should be instanciated: %v", err)
+	}
+
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+	if err = client.SetMultiLevel("base", "varied", value, http.Header{}, "", time.Minute, "real"); err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	if len(client.Get("{base}varied")) == 0 || len(client.Get("{base}"+core.MappingKeyPrefix+"base")) == 0 {
+		t.Error("The value and the mapping should share the base key hash tag")
+	}
+
+	if keys := client.ListKeys(); len(keys) != 1 || keys[0] != "real" {
+		t.Errorf("The listed keys should only contain real, %v given", keys)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com", nil)
+
+	fresh, _ := client.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The fresh response should exist")
+	}
+
+	_ = fresh.Body.Close()
+
+	client.DeleteMany(".+")
+}
+
+func TestRedis_TryLock(t *testing.T) {
+	client, _ := getRedisInstance()
+	locker, ok := client.(core.Locker)
+	if !ok {
+		t.Fatal("Redis should implement core.Locker")
+	}
+
+	acquired, err := locker.TryLock("revalidation", 5*time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("The lock should be acquired, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); acquired {
+		t.Error("The lock should already be held")
+	}
+
+	if err = locker.Unlock("revalidation"); err != nil {
+		t.Errorf("Impossible to release the lock, %v", err)
+	}
+
+	if acquired, _ = locker.TryLock("revalidation", 5*time.Second); !acquired {
+		t.Error("The lock should be acquired once released")
+	}
+
+	_ = locker.Unlock("revalidation")
+}
+
+func TestRedis_ConditionalSet(t *testing.T) {
+	client, _ := getRedisInstance()
+	setter, ok := client.(core.ConditionalSetter)
+	if !ok {
+		t.Fatal("Redis should implement core.ConditionalSetter")
+	}
+
+	client.Delete("leader")
+
+	if stored, err := setter.SetNX("leader", []byte("first"), 5*time.Second); err != nil || !stored {
+		t.Fatalf("The absent key should be stored, %v", err)
+	}
+
+	if stored, _ := setter.SetNX("leader", []byte("second"), 5*time.Second); stored {
+		t.Error("The existing key shouldn't be replaced")
+	}
+
+	if swapped, _ := setter.CompareAndSwap("leader", []byte("second"), []byte("third"), 5*time.Second); swapped {
+		t.Error("The value shouldn't be swapped when the old one differs")
+	}
+
+	if swapped, err := setter.CompareAndSwap("leader", []byte("first"), []byte("third"), 5*time.Second); err != nil || !swapped {
+		t.Errorf("The value should be swapped, %v", err)
+	}
+
+	if value := client.Get("leader"); string(value) != "third" {
+		t.Errorf("The swapped value should be stored, %s given", value)
+	}
+
+	client.Delete("leader")
+}
+
+func TestRedis_Transact(t *testing.T) {
+	client, _ := getRedisInstance()
+	client.Delete("tx-value")
+	client.Delete("tx-mapping")
+
+	attempts := 0
+
+	err := core.Transact(client, func(tx core.Tx) error {
+		attempts++
+
+		if _, err := tx.Get("tx-mapping"); !errors.Is(err, core.ErrKeyNotFound) && attempts == 1 {
+			t.Errorf("The absent key should be reported, %v given", err)
+		}
+
+		// The watched key written concurrently aborts the first attempt.
+		if attempts == 1 {
+			_ = client.Set("tx-mapping", []byte("concurrent"), time.Minute)
+		}
+
+		_ = tx.Set("tx-value", []byte("body"), time.Minute)
+		_ = tx.Set("tx-mapping", []byte("index"), 0)
+
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("The conflicting transaction should be retried, %d attempts, %v", attempts, err)
+	}
+
+	if string(client.Get("tx-value")) != "body" || string(client.Get("tx-mapping")) != "index" {
+		t.Error("Both keys should be written")
+	}
+
+	failure := errors.New("rollback")
+
+	if err = core.Transact(client, func(tx core.Tx) error {
+		_ = tx.Delete("tx-value")
+
+		return failure
+	}); !errors.Is(err, failure) || client.Get("tx-value") == nil {
+		t.Errorf("The writes should be discarded on error, %v", err)
+	}
+
+	client.Delete("tx-value")
+	client.Delete("tx-mapping")
+}
+func TestRedis_Increment(t *testing.T) {
+	client, _ := getRedisInstance()
+	counter, ok := client.(core.Counter)
+	if !ok {
+		t.Fatal("Redis should implement core.Counter")
+	}
+
+	client.Delete("hits")
+
+	if value, err := counter.Increment("hits", 2, 5*time.Second); err != nil || value != 2 {
+		t.Fatalf("The missing counter should start at zero, %d given, %v", value, err)
+	}
+
+	if value, err := counter.Increment("hits", -3, 5*time.Second); err != nil || value != -1 {
+		t.Errorf("The counter should be decremented, %d given, %v", value, err)
+	}
+
+	if value := client.Get("hits"); string(value) != "-1" {
+		t.Errorf("The counter should be stored as a decimal string, %s given", value)
+	}
+
+	client.Delete("hits")
+}
+
+func TestRedis_SetReader(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	streamer, ok := client.(core.StreamStorer)
+	if !ok {
+		t.Fatal("Redis should implement core.StreamStorer")
+	}
+
+	// Larger than the value size limit, it must be split in chunks.
+	value := bytes.Repeat([]byte(baseValue), 200*1024)
+
+	if err := streamer.SetReader("large_value_test", bytes.NewReader(value), 20*time.Second); err != nil {
+		t.Fatalf("Impossible to stream the value: %v", err)
+	}
+
+	reader, err := streamer.GetReader("large_value_test")
+	if err != nil {
+		t.Fatalf("Impossible to get the reader: %v", err)
+	}
+
+	defer reader.Close()
+
+	res, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(res, value) {
+		t.Errorf("Expected %d bytes, got %d bytes, %v", len(value), len(res), err)
+	}
+}
+
+func TestRedis_ListKeysPaginated(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	paginator, ok := client.(core.KeyPaginator)
+	if !ok {
+		t.Fatal("Redis should implement core.KeyPaginator")
+	}
+
+	expected := []string{"page-1", "page-2", "page-3", "page-4", "page-5"}
+	for _, key := range expected {
+		if err := client.SetMultiLevel(key, key, []byte(baseValue), http.Header{}, "", time.Minute, key); err != nil {
+			t.Fatalf("Impossible to set the key %s, %v", key, err)
+		}
+	}
+
+	listed := map[string]bool{}
+	cursor := ""
+
+	for range 1000 {
+		var keys []string
+
+		keys, cursor = paginator.ListKeysPaginated(cursor, 2)
+		for _, key := range keys {
+			listed[key] = true
+		}
+
+		if cursor == "" {
+			break
+		}
+	}
+
+	if cursor != "" {
+		t.Fatal("The pagination should end")
+	}
+
+	for _, key := range expected {
+		if !listed[key] {
+			t.Errorf("The key %s should be listed, %v given", key, listed)
+		}
+	}
+}
+
+func TestRedis_Reload(t *testing.T) {
+	client, _ := getRedisInstance()
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if err := core.Reload(client, core.CacheProvider{URL: "127.0.0.1:6379"}); err != nil {
+		t.Fatalf("Impossible to reload the Redis provider, %v", err)
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should be kept after the reload", byteKey)
+	}
+
+	if err := core.Reload(client, core.CacheProvider{}); err == nil {
+		t.Error("A configuration without address shouldn't be applied")
+	}
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("The previous client should be kept when the reload fails")
+	}
+}
+
+func TestRedis_Healthy(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The reachable server should be healthy, %v given", err)
+	}
+}
+
+func TestRedis_ClientSideCache(t *testing.T) {
+	client, err := redis.Factory(core.CacheProvider{
+		URL: "localhost:6379",
+		Configuration: map[string]interface{}{
+			"client_side_cache": map[string]interface{}{"enabled": true, "ttl": "1m"},
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the Redis provider, %v", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should be cached", byteKey)
+	}
+
+	_ = client.Set(byteKey, []byte("updated"), time.Minute)
+	time.Sleep(100 * time.Millisecond)
+
+	if string(client.Get(byteKey)) != "updated" {
+		t.Errorf("The cached key %s should be invalidated once updated", byteKey)
+	}
+}
+
+func TestRedis_ValidateClientSideCache(t *testing.T) {
+	if err := redis.Validate(map[string]interface{}{
+		"client_side_cache": map[string]interface{}{"enabled": true, "ttl": "30s", "size_each_conn": 1 << 20},
+	}); err != nil {
+		t.Errorf("The client_side_cache configuration should be valid, %v", err)
+	}
+
+	if err := redis.Validate(map[string]interface{}{
+		"client_side_cache": map[string]interface{}{"enabled": true, "ttl": "-1s"},
+	}); err == nil {
+		t.Error("A negative ttl should be invalid")
+	}
+
+	if err := redis.Validate(map[string]interface{}{
+		"client_side_cache": map[string]interface{}{"enable": true},
+	}); err == nil {
+		t.Error("An unknown client_side_cache key should be invalid")
+	}
+}
+
+func TestRedis_ValidateFlavor(t *testing.T) {
+	for _, flavor := range []string{"redis", "keydb", "dragonfly"} {
+		if err := redis.Validate(map[string]interface{}{"flavor": flavor}); err != nil {
+			t.Errorf("The %s flavor should be valid, %v", flavor, err)
+		}
+	}
+
+	if err := redis.Validate(map[string]interface{}{"flavor": "valkey"}); err == nil {
+		t.Error("An unknown flavor should be invalid")
+	}
+}
+
+func TestRedis_Client(t *testing.T) {
+	if err := redis.Validate(map[string]interface{}{"client": "memcached"}); err == nil {
+		t.Error("An unknown client should be invalid")
+	}
+
+	if err := redis.Validate(map[string]interface{}{
+		"client":            "go-redis",
+		"client_side_cache": map[string]interface{}{"enabled": true},
+	}); err == nil {
+		t.Error("The client_side_cache should require rueidis")
+	}
+
+	storer, err := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"client":      "go-redis",
+		"InitAddress": []string{"localhost:6379"},
+		"SelectDB":    1,
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("The go-redis client should be created, %v", err)
+	}
+
+	defer func() { _ = storer.Reset() }()
+
+	if _, ok := storer.(*redis.Redis); ok || storer.Name() != "REDIS" {
+		t.Errorf("The go-redis storer should be returned, %T given", storer)
+	}
+}
+
+func TestRedis_Stats(t *testing.T) {
+	client, _ := getRedisInstance()
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	stats, found := core.Stats(client)
+	if !found {
+		t.Fatal("The redis storer should report its stats")
+	}
+
+	if stats.Entries < 1 || stats.Bytes <= 0 {
+		t.Errorf("The keys count and the used memory should be reported, %+v given", stats)
+	}
+}
+
+func TestRedis_Invalidation(t *testing.T) {
+	client, _ := getRedisInstance()
+	bus, ok := client.(core.InvalidationBus)
+	if !ok {
+		t.Fatal("Redis should implement core.InvalidationBus")
+	}
+
+	received := make(chan core.InvalidationEvent, 1)
+
+	unsubscribe, err := bus.SubscribeInvalidations("redis-test", func(event core.InvalidationEvent) {
+		received <- event
+	})
+	if err != nil {
+		t.Fatalf("Impossible to subscribe to the invalidations, %v", err)
+	}
+
+	defer unsubscribe()
+
+	time.Sleep(100 * time.Millisecond)
+
+	event := core.InvalidationEvent{Kind: core.InvalidatePattern, Key: "^GET-", Origin: "other"}
+	if err = bus.PublishInvalidation("redis-test", event); err != nil {
+		t.Fatalf("Impossible to publish the invalidation, %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got != event {
+			t.Errorf("The published event should be received, %+v given", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("The published event should be received")
+	}
+}
+
+func TestRedis_PurgeChannel(t *testing.T) {
+	configuration := core.CacheProvider{
+		URL:           "localhost:6379",
+		Configuration: map[string]interface{}{"InitAddress": []string{"localhost:6379"}, "purge_channel": "redis-purges"},
+	}
+
+	first, _ := redis.Factory(configuration, zap.NewNop().Sugar(), 0)
+	second, _ := redis.Factory(configuration, zap.NewNop().Sugar(), 0)
+
+	_ = first.Init()
+	_ = second.Init()
+
+	defer func() {
+		_ = first.Reset()
+		_ = second.Reset()
+	}()
+
+	received := make(chan core.InvalidationEvent, 4)
+
+	unsubscribe, _ := second.(core.InvalidationBus).SubscribeInvalidations("redis-purges", func(event core.InvalidationEvent) {
+		received <- event
+	})
+	defer unsubscribe()
+
+	time.Sleep(100 * time.Millisecond)
+
+	_ = first.Set("tagged-1", []byte(baseValue), time.Minute)
+	_ = first.Set(core.SurrogateKeyPrefix+"products", []byte("tagged-1"), time.Minute)
+
+	first.(*redis.Redis).PurgeTag("products")
+
+	if len(second.Get("tagged-1")) != 0 {
+		t.Error("The tagged key should be purged")
+	}
+
+	select {
+	case event := <-received:
+		if event.Kind != core.InvalidateTag || event.Key != "products" {
+			t.Errorf("The tag purge should be published, %+v given", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("The tag purge should be published")
+	}
+}
+
+func enableKeyspaceNotifications(t *testing.T, address string) {
+	t.Helper()
+
+	admin, err := rueidis.NewClient(rueidis.ClientOption{InitAddress: []string{address}})
+	if err != nil {
+		t.Fatalf("Impossible to connect to Redis, %v", err)
+	}
+
+	defer admin.Close()
+
+	err = admin.Do(context.Background(), admin.B().ConfigSet().ParameterValue().ParameterValue("notify-keyspace-events", "Kg$x").Build()).Error()
+	if err != nil {
+		t.Fatalf("Impossible to enable the keyspace notifications, %v", err)
+	}
+}
+
+func TestRedis_Watch(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enableKeyspaceNotifications(t, "localhost:6379")
+
+	events, err := client.(core.Watcher).Watch(ctx, "WATCHED_")
+	if err != nil {
+		t.Fatalf("Impossible to watch the keys, %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	_ = client.Set("WATCHED_"+byteKey, []byte(baseValue), time.Minute)
+	client.Delete("WATCHED_" + byteKey)
+
+	for _, expected := range []core.EventKind{core.EventSet, core.EventDelete} {
+		select {
+		case event := <-events:
+			if event.Kind != expected || event.Key != "WATCHED_"+byteKey {
+				t.Errorf("The %s event of the watched key should be received, %+v given", expected, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The %s event wasn't received", expected)
+		}
+	}
+}
+
+func TestRedis_OnEvicted(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	enableKeyspaceNotifications(t, "localhost:6379")
+
+	evicted := make(chan string, 1)
+
+	client.(core.EvictionNotifier).OnEvicted(func(key string, _ []byte) {
+		if key == "EXPIRED_"+byteKey {
+			evicted <- key
+		}
+	})
+
+	time.Sleep(100 * time.Millisecond)
+
+	_ = client.Set("EXPIRED_"+byteKey, []byte(baseValue), time.Second)
+
+	select {
+	case <-evicted:
+	case <-time.After(5 * time.Second):
+		t.Error("The expired key should be notified")
+	}
+}
+
+func TestRedis_Flavors(t *testing.T) {
+	for flavor, address := range map[string]string{"dragonfly": "localhost:6380", "keydb": "localhost:6381"} {
+		t.Run(flavor, func(t *testing.T) {
+			client, err := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+				"InitAddress": []string{address},
+				"flavor":      flavor,
+			}}, zap.NewNop().Sugar(), 0)
+			if err != nil {
+				t.Skipf("The %s server isn't reachable, %v", flavor, err)
+			}
+
+			defer func() { _ = client.Reset() }()
+
+			// Dragonfly only sends the expired keyevents, enabled by its flags.
+			if flavor == "keydb" {
+				enableKeyspaceNotifications(t, address)
+			}
+
+			evicted := make(chan string, 1)
+
+			client.(core.EvictionNotifier).OnEvicted(func(key string, _ []byte) {
+				if key == "FLAVOR_EXPIRED" {
+					evicted <- key
+				}
+			})
+
+			_ = client.SetMultiLevel("FLAVOR_base", "FLAVOR_varied", []byte(baseValue), http.Header{}, "", time.Minute, "FLAVOR_real")
+
+			if keys := client.ListKeys(); !slices.Contains(keys, "FLAVOR_real") {
+				t.Errorf("The real key should be listed, %v given", keys)
+			}
+
+			if stats, _ := core.Stats(client); stats.Entries < 2 || stats.Bytes <= 0 {
+				t.Errorf("The keys count and the used memory should be reported, %+v given", stats)
+			}
+
+			client.DeleteMany("^FLAVOR_")
+
+			if len(client.Get("FLAVOR_varied")) != 0 {
+				t.Error("The matching keys should be deleted")
+			}
+
+			time.Sleep(100 * time.Millisecond)
+
+			_ = client.Set("FLAVOR_EXPIRED", []byte(baseValue), time.Second)
+
+			select {
+			case <-evicted:
+			case <-time.After(5 * time.Second):
+				t.Error("The expired key should be notified")
+			}
+		})
+	}
+}
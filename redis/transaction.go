@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	redis "github.com/redis/rueidis"
+)
+
+// Transact runs fn on a dedicated connection: the keys it reads are
+// watched and its writes are sent in a MULTI/EXEC block, aborted by Redis
+// when a watched key was written since. fn runs again then. In cluster mode
+// the keys of a transaction must share a hash tag.
+func (provider *Redis) Transact(fn func(tx core.Tx) error) error {
+	return core.RetryTransaction(func() error {
+		ctx, cancel := provider.timeouts.WriteContext(provider.ctx)
+		defer cancel()
+
+		return provider.inClient.Dedicated(func(client redis.DedicatedClient) error {
+			tx := &redisTx{ctx: ctx, client: client, written: map[string][]byte{}}
+
+			if err := fn(tx); err != nil || len(tx.commands) == 0 {
+				if tx.watching {
+					_ = client.Do(ctx, client.B().Unwatch().Build()).Error()
+				}
+
+				return err
+			}
+
+			commands := make(redis.Commands, 0, len(tx.commands)+2)
+			commands = append(commands, client.B().Multi().Build())
+			commands = append(commands, tx.commands...)
+			commands = append(commands, client.B().Exec().Build())
+
+			results := client.DoMulti(ctx, commands...)
+
+			err := results[len(results)-1].Error()
+			if redis.IsRedisNil(err) {
+				return core.ErrTransactionConflict
+			}
+
+			if err != nil {
+				provider.logger.Errorf("Impossible to commit the transaction into Redis, %v", err)
+			}
+
+			return err
+		})
+	})
+}
+
+// redisTx is the core.Tx of a WATCH/MULTI/EXEC transaction. written holds
+// the values set by the transaction, nil for the deleted keys, since the
+// writes are only sent on commit.
+type redisTx struct {
+	ctx      context.Context
+	client   redis.DedicatedClient
+	commands redis.Commands
+	written  map[string][]byte
+	watching bool
+}
+
+func (r *redisTx) Get(key string) ([]byte, error) {
+	if value, found := r.written[key]; found {
+		if value == nil {
+			return nil, core.ErrKeyNotFound
+		}
+
+		return value, nil
+	}
+
+	if err := r.client.Do(r.ctx, r.client.B().Watch().Key(key).Build()).Error(); err != nil {
+		return nil, err
+	}
+
+	r.watching = true
+
+	value, err := r.client.Do(r.ctx, r.client.B().Get().Key(key).Build()).AsBytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return value, err
+}
+
+func (r *redisTx) Set(key string, value []byte, ttl time.Duration) error {
+	if ttl > 0 {
+		r.commands = append(r.commands, r.client.B().Set().Key(key).Value(string(value)).PxMilliseconds(ttl.Milliseconds()).Build())
+	} else {
+		r.commands = append(r.commands, r.client.B().Set().Key(key).Value(string(value)).Build())
+	}
+
+	r.written[key] = append([]byte{}, value...)
+
+	return nil
+}
+
+func (r *redisTx) Delete(key string) error {
+	r.commands = append(r.commands, r.client.B().Del().Key(key).Build())
+	r.written[key] = nil
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	redis "github.com/redis/rueidis"
+)
+
+// keyspaceEvents maps the keyspace notifications to the event kinds, the
+// other notifications are ignored.
+var keyspaceEvents = map[string]core.EventKind{
+	"set":     core.EventSet,
+	"del":     core.EventDelete,
+	"unlink":  core.EventDelete,
+	"expired": core.EventExpire,
+	"evicted": core.EventExpire,
+}
+
+// globEscaper escapes the glob special characters of a pattern.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Watch streams the changes of the keys starting with the prefix with the
+// Redis keyspace notifications, they must be enabled on the server, e.g.
+// notify-keyspace-events "Kg$x". The notifications don't carry the values
+// and a cluster node only notifies its own keys. The subscription is made
+// again on the current client when it's lost, e.g. by a reload. Dragonfly
+// only notifies the expirations.
+func (provider *Redis) Watch(ctx context.Context, prefix string) (<-chan core.Event, error) {
+	if provider.flavor == flavorDragonfly {
+		return provider.watchExpired(ctx, prefix)
+	}
+
+	keyspace := fmt.Sprintf("__keyspace@%d__:", provider.configuration.SelectDB)
+	pattern := keyspace + globEscaper.Replace(prefix) + "*"
+	events := make(chan core.Event, core.EventsBufferSize)
+
+	go func() {
+		defer close(events)
+
+		for ctx.Err() == nil {
+			client := provider.inClient
+
+			err := client.Receive(ctx, client.B().Psubscribe().Pattern(pattern).Build(), func(message redis.PubSubMessage) {
+				kind, ok := keyspaceEvents[message.Message]
+				if !ok {
+					return
+				}
+
+				core.SendEvent(ctx, events, core.Event{Kind: kind, Key: strings.TrimPrefix(message.Channel, keyspace)})
+			})
+			if err != nil && !errors.Is(err, context.Canceled) {
+				provider.logger.Errorf("Impossible to receive the Redis keyspace notifications, %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// OnEvicted registers fn for the keys expired or evicted by the server, they
+// are received with the keyspace notifications, see Watch. The values are
+// already gone and passed as nil.
+func (provider *Redis) OnEvicted(fn func(key string, value []byte)) {
+	provider.evictions.Add(fn)
+
+	provider.evictionMu.Lock()
+	defer provider.evictionMu.Unlock()
+
+	if provider.stopEvictions != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	provider.stopEvictions = cancel
+
+	events, _ := provider.Watch(ctx, "")
+
+	go func() {
+		for event := range events {
+			if event.Kind == core.EventExpire {
+				provider.evictions.Notify(event.Key, nil)
+			}
+		}
+	}()
+}
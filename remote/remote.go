@@ -0,0 +1,362 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/dustin/go-humanize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const defaultAddress = "127.0.0.1:9090"
+
+// Remote provider type, a Storer served by a remote Server.
+type Remote struct {
+	client   StorerClient
+	conn     *grpc.ClientConn
+	address  string
+	stale    time.Duration
+	uuid     string
+	logger   core.Logger
+	timeouts core.Timeouts
+	// ctx is the context bound with WithContext, nil until then.
+	ctx context.Context
+}
+
+// configuration is the typed Remote provider configuration.
+type configuration struct {
+	// MaxMessageSize bounds the messages received from the server ("16MB"),
+	// the values larger than 4MB need it.
+	MaxMessageSize string `json:"max_message_size"`
+}
+
+func parseConfiguration(remoteConfiguration any) (configuration, int, error) {
+	var cfg configuration
+
+	if err := core.DecodeConfiguration(remoteConfiguration, &cfg); err != nil {
+		return cfg, 0, fmt.Errorf("invalid remote configuration: %w", err)
+	}
+
+	if cfg.MaxMessageSize == "" {
+		return cfg, 0, nil
+	}
+
+	size, err := humanize.ParseBytes(cfg.MaxMessageSize)
+	if err != nil {
+		return cfg, 0, fmt.Errorf("invalid remote configuration: max_message_size: %w", err)
+	}
+
+	if size == 0 || size > math.MaxInt32 {
+		return cfg, 0, fmt.Errorf("invalid remote configuration: the max_message_size must be positive, %s given", cfg.MaxMessageSize)
+	}
+
+	return cfg, int(size), nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(remoteConfiguration any) error {
+	_, _, err := parseConfiguration(remoteConfiguration)
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("remote", Factory)
+}
+
+// Factory function create new Remote instance, the url is the address of
+// the server.
+func Factory(remoteConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	_, maxMessageSize, err := parseConfiguration(remoteConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts, err := core.TimeoutsFromConfiguration(remoteConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(remoteConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	address := remoteConfiguration.URL
+	if address == "" {
+		address = defaultAddress
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if tlsConfig != nil {
+		transportCredentials = credentials.NewTLS(tlsConfig)
+	}
+
+	options := []grpc.DialOption{grpc.WithTransportCredentials(transportCredentials)}
+	if maxMessageSize > 0 {
+		options = append(options, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize), grpc.MaxCallSendMsgSize(maxMessageSize)))
+	}
+
+	conn, err := grpc.NewClient(address, options...)
+	if err != nil {
+		logger.Errorf("Impossible to create the remote client for %s, %v", address, err)
+
+		return nil, err
+	}
+
+	return &Remote{
+		client:   NewStorerClient(conn),
+		conn:     conn,
+		address:  address,
+		stale:    stale,
+		uuid:     core.InstanceID("remote", remoteConfiguration, stale),
+		logger:   logger,
+		timeouts: timeouts,
+	}, nil
+}
+
+// Name returns the storer name.
+func (provider *Remote) Name() string {
+	return "REMOTE"
+}
+
+// WithContext returns the provider sending its requests with the context,
+// e.g. to propagate the span of the caller to the remote storage.
+func (provider *Remote) WithContext(ctx context.Context) core.Storer {
+	bound := *provider
+	bound.ctx = ctx
+
+	return &bound
+}
+
+func (provider *Remote) context() context.Context {
+	if provider.ctx == nil {
+		return context.Background()
+	}
+
+	return provider.ctx
+}
+
+// Uuid returns an unique identifier.
+func (provider *Remote) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *Remote) MapKeys(prefix string) map[string]string {
+	ctx, cancel := provider.timeouts.ReadContext(provider.context())
+	defer cancel()
+
+	response, err := provider.client.MapKeys(ctx, &KeyRequest{Key: prefix})
+	if err != nil {
+		provider.logger.Errorf("Impossible to map the keys %s from the remote storage, %v", prefix, err)
+
+		return map[string]string{}
+	}
+
+	return response.GetKeys()
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Remote) ListKeys() []string {
+	ctx, cancel := provider.timeouts.ReadContext(provider.context())
+	defer cancel()
+
+	response, err := provider.client.ListKeys(ctx, &emptypb.Empty{})
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys from the remote storage, %v", err)
+
+		return []string{}
+	}
+
+	return response.GetKeys()
+}
+
+// WalkEntries streams the entries of the remote storer with their remaining
+// TTL.
+func (provider *Remote) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	ctx, cancel := context.WithCancel(provider.context())
+	defer cancel()
+
+	stream, err := provider.client.Scan(ctx, &KeyRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		entry, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !walkFn(entry.GetKey(), entry.GetValue(), entry.GetTtl().AsDuration()) {
+			return nil
+		}
+	}
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Remote) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Remote) Lookup(key string) ([]byte, error) {
+	ctx, cancel := provider.timeouts.ReadContext(provider.context())
+	defer cancel()
+
+	response, err := provider.client.Get(ctx, &KeyRequest{Key: key})
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s from the remote storage, %v", key, err)
+
+		return nil, err
+	}
+
+	if !response.GetFound() {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return response.GetValue(), nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Remote) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	encodedValidator, err := json.Marshal(validator)
+	if err != nil {
+		provider.logger.Errorf("Impossible to encode the validator of the key %s, %v", key, err)
+
+		return fresh, stale
+	}
+
+	ctx, cancel := provider.timeouts.ReadContext(req.Context())
+	defer cancel()
+
+	response, err := provider.client.GetMultiLevel(ctx, &GetMultiLevelRequest{
+		Key:       key,
+		Method:    req.Method,
+		Url:       req.URL.String(),
+		Headers:   fromHeader(req.Header),
+		Validator: encodedValidator,
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s from the remote storage, %v", key, err)
+
+		return fresh, stale
+	}
+
+	if err = json.Unmarshal(response.GetValidator(), validator); err != nil {
+		provider.logger.Errorf("Impossible to decode the validator of the key %s, %v", key, err)
+	}
+
+	fresh = provider.readResponse(key, response.GetFresh(), req)
+	stale = provider.readResponse(key, response.GetStale(), req)
+
+	return fresh, stale
+}
+
+func (provider *Remote) readResponse(key string, raw []byte, req *http.Request) *http.Response {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), req)
+	if err != nil {
+		provider.logger.Errorf("An error occurred while reading response for the key %s: %v", key, err)
+
+		return nil
+	}
+
+	return response
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *Remote) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	_, err := provider.client.SetMultiLevel(ctx, &SetMultiLevelRequest{
+		BaseKey:       baseKey,
+		VariedKey:     variedKey,
+		Value:         value,
+		VariedHeaders: fromHeader(variedHeaders),
+		Etag:          etag,
+		Duration:      durationpb.New(duration),
+		RealKey:       realKey,
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s into the remote storage, %v", variedKey, err)
+	}
+
+	return err
+}
+
+// Set method will store the response in the remote storage.
+func (provider *Remote) Set(key string, value []byte, duration time.Duration) error {
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	_, err := provider.client.Set(ctx, &SetRequest{Key: key, Value: value, Duration: durationpb.New(duration)})
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s into the remote storage, %v", key, err)
+	}
+
+	return err
+}
+
+// Delete method will delete the response in the remote storage if exists corresponding to key param.
+func (provider *Remote) Delete(key string) {
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	if _, err := provider.client.Delete(ctx, &KeyRequest{Key: key}); err != nil {
+		provider.logger.Errorf("Impossible to delete the key %s from the remote storage, %v", key, err)
+	}
+}
+
+// DeleteMany method will delete the responses in the remote storage if exists corresponding to the regex key param.
+func (provider *Remote) DeleteMany(key string) {
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	if _, err := provider.client.DeleteMany(ctx, &KeyRequest{Key: key}); err != nil {
+		provider.logger.Errorf("Impossible to delete the keys %s from the remote storage, %v", key, err)
+	}
+}
+
+// Healthy pings the server, it checks the health of the served storer.
+func (provider *Remote) Healthy(ctx context.Context) error {
+	_, err := provider.client.Ping(ctx, &emptypb.Empty{})
+
+	return err
+}
+
+// Init method will.
+func (provider *Remote) Init() error {
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *Remote) Reset() error {
+	return provider.conn.Close()
+}
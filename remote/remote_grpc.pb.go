@@ -0,0 +1,468 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: remote.proto
+
+package remote
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Storer_Get_FullMethodName           = "/darkweak.storages.remote.Storer/Get"
+	Storer_Set_FullMethodName           = "/darkweak.storages.remote.Storer/Set"
+	Storer_Delete_FullMethodName        = "/darkweak.storages.remote.Storer/Delete"
+	Storer_DeleteMany_FullMethodName    = "/darkweak.storages.remote.Storer/DeleteMany"
+	Storer_MapKeys_FullMethodName       = "/darkweak.storages.remote.Storer/MapKeys"
+	Storer_ListKeys_FullMethodName      = "/darkweak.storages.remote.Storer/ListKeys"
+	Storer_Scan_FullMethodName          = "/darkweak.storages.remote.Storer/Scan"
+	Storer_GetMultiLevel_FullMethodName = "/darkweak.storages.remote.Storer/GetMultiLevel"
+	Storer_SetMultiLevel_FullMethodName = "/darkweak.storages.remote.Storer/SetMultiLevel"
+	Storer_Ping_FullMethodName          = "/darkweak.storages.remote.Storer/Ping"
+)
+
+// StorerClient is the client API for Storer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StorerClient interface {
+	Get(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*ValueResponse, error)
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Delete(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteMany(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	MapKeys(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*MapKeysResponse, error)
+	ListKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListKeysResponse, error)
+	Scan(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entry], error)
+	GetMultiLevel(ctx context.Context, in *GetMultiLevelRequest, opts ...grpc.CallOption) (*GetMultiLevelResponse, error)
+	SetMultiLevel(ctx context.Context, in *SetMultiLevelRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type storerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStorerClient(cc grpc.ClientConnInterface) StorerClient {
+	return &storerClient{cc}
+}
+
+func (c *storerClient) Get(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*ValueResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValueResponse)
+	err := c.cc.Invoke(ctx, Storer_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Storer_Set_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) Delete(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Storer_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) DeleteMany(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Storer_DeleteMany_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) MapKeys(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*MapKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MapKeysResponse)
+	err := c.cc.Invoke(ctx, Storer_MapKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) ListKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListKeysResponse)
+	err := c.cc.Invoke(ctx, Storer_ListKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) Scan(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Storer_ServiceDesc.Streams[0], Storer_Scan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[KeyRequest, Entry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Storer_ScanClient = grpc.ServerStreamingClient[Entry]
+
+func (c *storerClient) GetMultiLevel(ctx context.Context, in *GetMultiLevelRequest, opts ...grpc.CallOption) (*GetMultiLevelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMultiLevelResponse)
+	err := c.cc.Invoke(ctx, Storer_GetMultiLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) SetMultiLevel(ctx context.Context, in *SetMultiLevelRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Storer_SetMultiLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storerClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Storer_Ping_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StorerServer is the server API for Storer service.
+// All implementations must embed UnimplementedStorerServer
+// for forward compatibility.
+type StorerServer interface {
+	Get(context.Context, *KeyRequest) (*ValueResponse, error)
+	Set(context.Context, *SetRequest) (*emptypb.Empty, error)
+	Delete(context.Context, *KeyRequest) (*emptypb.Empty, error)
+	DeleteMany(context.Context, *KeyRequest) (*emptypb.Empty, error)
+	MapKeys(context.Context, *KeyRequest) (*MapKeysResponse, error)
+	ListKeys(context.Context, *emptypb.Empty) (*ListKeysResponse, error)
+	Scan(*KeyRequest, grpc.ServerStreamingServer[Entry]) error
+	GetMultiLevel(context.Context, *GetMultiLevelRequest) (*GetMultiLevelResponse, error)
+	SetMultiLevel(context.Context, *SetMultiLevelRequest) (*emptypb.Empty, error)
+	Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	mustEmbedUnimplementedStorerServer()
+}
+
+// UnimplementedStorerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStorerServer struct{}
+
+func (UnimplementedStorerServer) Get(context.Context, *KeyRequest) (*ValueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedStorerServer) Set(context.Context, *SetRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedStorerServer) Delete(context.Context, *KeyRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedStorerServer) DeleteMany(context.Context, *KeyRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMany not implemented")
+}
+func (UnimplementedStorerServer) MapKeys(context.Context, *KeyRequest) (*MapKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MapKeys not implemented")
+}
+func (UnimplementedStorerServer) ListKeys(context.Context, *emptypb.Empty) (*ListKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
+}
+func (UnimplementedStorerServer) Scan(*KeyRequest, grpc.ServerStreamingServer[Entry]) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedStorerServer) GetMultiLevel(context.Context, *GetMultiLevelRequest) (*GetMultiLevelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMultiLevel not implemented")
+}
+func (UnimplementedStorerServer) SetMultiLevel(context.Context, *SetMultiLevelRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMultiLevel not implemented")
+}
+func (UnimplementedStorerServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedStorerServer) mustEmbedUnimplementedStorerServer() {}
+func (UnimplementedStorerServer) testEmbeddedByValue()                {}
+
+// UnsafeStorerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StorerServer will
+// result in compilation errors.
+type UnsafeStorerServer interface {
+	mustEmbedUnimplementedStorerServer()
+}
+
+func RegisterStorerServer(s grpc.ServiceRegistrar, srv StorerServer) {
+	// If the following call pancis, it indicates UnimplementedStorerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Storer_ServiceDesc, srv)
+}
+
+func _Storer_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).Get(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).Delete(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_DeleteMany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).DeleteMany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_DeleteMany_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).DeleteMany(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_MapKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).MapKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_MapKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).MapKeys(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).ListKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_ListKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).ListKeys(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(KeyRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StorerServer).Scan(m, &grpc.GenericServerStream[KeyRequest, Entry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Storer_ScanServer = grpc.ServerStreamingServer[Entry]
+
+func _Storer_GetMultiLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMultiLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).GetMultiLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_GetMultiLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).GetMultiLevel(ctx, req.(*GetMultiLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_SetMultiLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMultiLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).SetMultiLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_SetMultiLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).SetMultiLevel(ctx, req.(*SetMultiLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storer_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorerServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storer_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorerServer).Ping(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Storer_ServiceDesc is the grpc.ServiceDesc for Storer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Storer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "darkweak.storages.remote.Storer",
+	HandlerType: (*StorerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _Storer_Get_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _Storer_Set_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Storer_Delete_Handler,
+		},
+		{
+			MethodName: "DeleteMany",
+			Handler:    _Storer_DeleteMany_Handler,
+		},
+		{
+			MethodName: "MapKeys",
+			Handler:    _Storer_MapKeys_Handler,
+		},
+		{
+			MethodName: "ListKeys",
+			Handler:    _Storer_ListKeys_Handler,
+		},
+		{
+			MethodName: "GetMultiLevel",
+			Handler:    _Storer_GetMultiLevel_Handler,
+		},
+		{
+			MethodName: "SetMultiLevel",
+			Handler:    _Storer_SetMultiLevel_Handler,
+		},
+		{
+			MethodName: "Ping",
+			Handler:    _Storer_Ping_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _Storer_Scan_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "remote.proto",
+}
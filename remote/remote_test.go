@@ -0,0 +1,188 @@
+package remote_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/otter"
+	"github.com/darkweak/storages/remote"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getRemoteInstance(t *testing.T) core.Storer {
+	t.Helper()
+
+	local, err := otter.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the local storer: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Impossible to listen: %v", err)
+	}
+
+	server := grpc.NewServer()
+	remote.Register(server, local)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	instance, err := remote.Factory(core.CacheProvider{URL: listener.Addr().String()}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to create the remote storer: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = instance.Reset()
+	})
+
+	return instance
+}
+
+func TestRemote_SetGet(t *testing.T) {
+	client := getRemoteInstance(t)
+
+	if err := client.Set(byteKey, []byte(baseValue), time.Minute); err != nil {
+		t.Fatalf("Impossible to set the key %s: %v", byteKey, err)
+	}
+
+	if value := client.Get(byteKey); string(value) != baseValue {
+		t.Errorf("%s not corresponding to %s", value, baseValue)
+	}
+
+	if _, err := core.Lookup(client, nonExistentKey); err != core.ErrKeyNotFound {
+		t.Errorf("Lookup should return core.ErrKeyNotFound on a miss, %v given", err)
+	}
+
+	client.Delete(byteKey)
+
+	if value := client.Get(byteKey); len(value) != 0 {
+		t.Errorf("The key %s should be deleted, %s given", byteKey, value)
+	}
+}
+
+func TestRemote_MultiLevel(t *testing.T) {
+	client := getRemoteInstance(t)
+
+	res := httptest.NewRecorder()
+	res.Header().Set("Content-Type", "text/plain")
+	_, _ = res.WriteString(baseValue)
+
+	var buf bytes.Buffer
+
+	response := res.Result()
+	response.Request = httptest.NewRequest(http.MethodGet, "http://domain.com/path", nil)
+
+	if err := response.Write(&buf); err != nil {
+		t.Fatalf("Impossible to write the response: %v", err)
+	}
+
+	variedHeaders := http.Header{"Accept-Encoding": []string{"gzip"}}
+	if err := client.SetMultiLevel(byteKey, byteKey+"-varied", buf.Bytes(), variedHeaders, "", time.Minute, byteKey); err != nil {
+		t.Fatalf("Impossible to set the multi level key %s: %v", byteKey, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://domain.com/path", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	validator := &core.Revalidator{}
+
+	fresh, _ := client.GetMultiLevel(byteKey, req, validator)
+	if fresh == nil {
+		t.Fatal("The fresh response should be elected")
+	}
+
+	if fresh.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("The Content-Type header should be text/plain, %s given", fresh.Header.Get("Content-Type"))
+	}
+
+	if !validator.Matched {
+		t.Error("The validator should be updated by the server")
+	}
+
+	req.Header.Set("Accept-Encoding", "br")
+
+	if fresh, stale := client.GetMultiLevel(byteKey, req, &core.Revalidator{}); fresh != nil || stale != nil {
+		t.Error("No response should be elected for another variant")
+	}
+}
+
+func TestRemote_DeleteManyAndWalk(t *testing.T) {
+	client := getRemoteInstance(t)
+
+	for _, key := range []string{"first", "second", "third"} {
+		_ = client.Set(key, []byte(baseValue), time.Minute)
+	}
+
+	client.DeleteMany("^(first|second)$")
+
+	for _, key := range []string{"first", "second"} {
+		if value := client.Get(key); len(value) != 0 {
+			t.Errorf("The key %s should be deleted, %s given", key, value)
+		}
+	}
+
+	if value := client.Get("third"); string(value) != baseValue {
+		t.Errorf("The key third should remain, %s given", value)
+	}
+
+	walked := map[string]time.Duration{}
+
+	err := core.WalkEntries(client, func(key string, _ []byte, ttl time.Duration) bool {
+		walked[key] = ttl
+
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Impossible to walk the entries: %v", err)
+	}
+
+	if ttl, ok := walked["third"]; !ok || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("The key third should be walked with its remaining TTL, %v given", walked)
+	}
+}
+
+func TestRemote_Healthy(t *testing.T) {
+	client := getRemoteInstance(t)
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The remote storer should be healthy: %v", err)
+	}
+}
+
+func TestRemote_WithContext(t *testing.T) {
+	client := getRemoteInstance(t)
+
+	binder, ok := client.(core.ContextBinder)
+	if !ok {
+		t.Fatal("Remote should implement core.ContextBinder")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := binder.WithContext(ctx).Set(byteKey, []byte(baseValue), time.Minute); err == nil {
+		t.Error("The request should be sent with the canceled context")
+	}
+
+	if err := client.Set(byteKey, []byte(baseValue), time.Minute); err != nil {
+		t.Errorf("The provider shouldn't be bound to the context, %v", err)
+	}
+}
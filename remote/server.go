@@ -0,0 +1,209 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Server exposes a local Storer, e.g. a Badger or an Otter instance, to the
+// Remote clients.
+type Server struct {
+	UnimplementedStorerServer
+
+	storer core.Storer
+}
+
+// NewServer returns the gRPC server of the storer, registered with Register.
+func NewServer(storer core.Storer) *Server {
+	return &Server{storer: storer}
+}
+
+// Register registers the server of the storer on the gRPC server.
+func Register(grpcServer *grpc.Server, storer core.Storer) {
+	RegisterStorerServer(grpcServer, NewServer(storer))
+}
+
+// Get returns the value of the key, found is false on a miss.
+func (s *Server) Get(_ context.Context, request *KeyRequest) (*ValueResponse, error) {
+	value, err := core.Lookup(s.storer, request.GetKey())
+	if errors.Is(err, core.ErrKeyNotFound) {
+		return &ValueResponse{}, nil
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &ValueResponse{Value: value, Found: true}, nil
+}
+
+// Set stores the value for the duration.
+func (s *Server) Set(_ context.Context, request *SetRequest) (*emptypb.Empty, error) {
+	if err := s.storer.Set(request.GetKey(), request.GetValue(), request.GetDuration().AsDuration()); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Delete deletes the key.
+func (s *Server) Delete(_ context.Context, request *KeyRequest) (*emptypb.Empty, error) {
+	s.storer.Delete(request.GetKey())
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteMany deletes the keys matching the pattern.
+func (s *Server) DeleteMany(_ context.Context, request *KeyRequest) (*emptypb.Empty, error) {
+	if _, err := regexp.Compile(request.GetKey()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.storer.DeleteMany(request.GetKey())
+
+	return &emptypb.Empty{}, nil
+}
+
+// MapKeys returns the values of the keys starting with the prefix.
+func (s *Server) MapKeys(_ context.Context, request *KeyRequest) (*MapKeysResponse, error) {
+	return &MapKeysResponse{Keys: s.storer.MapKeys(request.GetKey())}, nil
+}
+
+// ListKeys returns the stored keys.
+func (s *Server) ListKeys(context.Context, *emptypb.Empty) (*ListKeysResponse, error) {
+	return &ListKeysResponse{Keys: s.storer.ListKeys()}, nil
+}
+
+// Scan streams the entries whose key starts with the prefix with their
+// remaining TTL, see core.WalkEntries.
+func (s *Server) Scan(request *KeyRequest, stream grpc.ServerStreamingServer[Entry]) error {
+	var sendErr error
+
+	err := core.WalkEntries(s.storer, func(key string, value []byte, ttl time.Duration) bool {
+		if !strings.HasPrefix(key, request.GetKey()) {
+			return true
+		}
+
+		sendErr = stream.Send(&Entry{Key: key, Value: value, Ttl: durationpb.New(ttl)})
+
+		return sendErr == nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return nil
+}
+
+// GetMultiLevel elects the fresh and the stale responses of the request, they
+// are returned as raw HTTP responses.
+func (s *Server) GetMultiLevel(ctx context.Context, request *GetMultiLevelRequest) (*GetMultiLevelResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, request.GetMethod(), request.GetUrl(), nil)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	req.Header = toHeader(request.GetHeaders())
+
+	validator := &core.Revalidator{}
+	if len(request.GetValidator()) > 0 {
+		if err = json.Unmarshal(request.GetValidator(), validator); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	fresh, stale := s.storer.GetMultiLevel(request.GetKey(), req, validator)
+	response := &GetMultiLevelResponse{}
+
+	if response.Fresh, err = dumpResponse(fresh); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if response.Stale, err = dumpResponse(stale); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if response.Validator, err = json.Marshal(validator); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return response, nil
+}
+
+// SetMultiLevel stores the varied response and updates its mapping.
+func (s *Server) SetMultiLevel(_ context.Context, request *SetMultiLevelRequest) (*emptypb.Empty, error) {
+	err := s.storer.SetMultiLevel(
+		request.GetBaseKey(),
+		request.GetVariedKey(),
+		request.GetValue(),
+		toHeader(request.GetVariedHeaders()),
+		request.GetEtag(),
+		request.GetDuration().AsDuration(),
+		request.GetRealKey(),
+	)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Ping checks the health of the storer.
+func (s *Server) Ping(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := core.Healthy(ctx, s.storer); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func dumpResponse(response *http.Response) ([]byte, error) {
+	if response == nil {
+		return nil, nil
+	}
+
+	return httputil.DumpResponse(response, true)
+}
+
+func toHeader(headers map[string]*HeaderValues) http.Header {
+	if headers == nil {
+		return nil
+	}
+
+	header := make(http.Header, len(headers))
+	for name, values := range headers {
+		header[name] = values.GetValues()
+	}
+
+	return header
+}
+
+func fromHeader(header http.Header) map[string]*HeaderValues {
+	if header == nil {
+		return nil
+	}
+
+	headers := make(map[string]*HeaderValues, len(header))
+	for name, values := range header {
+		headers[name] = &HeaderValues{Values: values}
+	}
+
+	return headers
+}
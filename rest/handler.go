@@ -0,0 +1,291 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// Handler serves a local Storer over HTTP and JSON, mount it under the URL
+// the Rest clients are configured with:
+//   - GET ?key= returns the raw value, 404 on a miss.
+//   - GET ?prefix= returns the keys starting with the prefix and their value
+//     as JSON.
+//   - GET ?walk= streams the entries starting with the prefix as JSON lines
+//     with their remaining TTL.
+//   - GET returns the stored keys as JSON.
+//   - PUT ?key=&ttl= stores the request body.
+//   - DELETE ?key= deletes the key.
+//   - DELETE ?regex= deletes the matching keys.
+//   - POST ?action=get_multi_level elects the fresh and stale responses.
+//   - POST ?action=set_multi_level stores a varied response.
+//   - HEAD answers the health checks.
+//
+// The requests must carry the token as a bearer Authorization when it's set.
+type Handler struct {
+	storer core.Storer
+	token  string
+}
+
+// NewHandler returns the handler serving the storer, an empty token disables
+// the authentication.
+func NewHandler(storer core.Storer, token string) *Handler {
+	return &Handler{storer: storer, token: token}
+}
+
+// entry is a JSON line streamed by GET ?walk=.
+type entry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+	TTL   string `json:"ttl"`
+}
+
+// getMultiLevelRequest is the body of POST ?action=get_multi_level.
+type getMultiLevelRequest struct {
+	Key       string            `json:"key"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Header    http.Header       `json:"header"`
+	Validator *core.Revalidator `json:"validator"`
+}
+
+// getMultiLevelResponse holds the elected raw HTTP responses.
+type getMultiLevelResponse struct {
+	Fresh     []byte            `json:"fresh,omitempty"`
+	Stale     []byte            `json:"stale,omitempty"`
+	Validator *core.Revalidator `json:"validator"`
+}
+
+// setMultiLevelRequest is the body of POST ?action=set_multi_level.
+type setMultiLevelRequest struct {
+	BaseKey       string      `json:"base_key"`
+	VariedKey     string      `json:"varied_key"`
+	Value         []byte      `json:"value"`
+	VariedHeaders http.Header `json:"varied_headers"`
+	ETag          string      `json:"etag"`
+	Duration      string      `json:"duration"`
+	RealKey       string      `json:"real_key"`
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodHead:
+		if err := core.Healthy(r.Context(), h.storer); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		switch {
+		case query.Has("key"):
+			h.serveValue(w, query.Get("key"))
+		case query.Has("prefix"):
+			keys := map[string][]byte{}
+			for key, value := range h.storer.MapKeys(query.Get("prefix")) {
+				keys[key] = []byte(value)
+			}
+
+			writeJSON(w, keys)
+		case query.Has("walk"):
+			h.serveEntries(w, query.Get("walk"))
+		default:
+			writeJSON(w, h.storer.ListKeys())
+		}
+	case http.MethodPut:
+		ttl, err := parseDuration(query.Get("ttl"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err = h.storer.Set(query.Get("key"), value, ttl); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if query.Has("regex") {
+			if _, err := regexp.Compile(query.Get("regex")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			h.storer.DeleteMany(query.Get("regex"))
+		} else {
+			h.storer.Delete(query.Get("key"))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		switch query.Get("action") {
+		case "get_multi_level":
+			h.getMultiLevel(w, r)
+		case "set_multi_level":
+			h.setMultiLevel(w, r)
+		default:
+			http.Error(w, "unknown action "+query.Get("action"), http.StatusBadRequest)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) serveValue(w http.ResponseWriter, key string) {
+	value, err := core.Lookup(h.storer, key)
+	if errors.Is(err, core.ErrKeyNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(value)
+}
+
+func (h *Handler) serveEntries(w http.ResponseWriter, prefix string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	encoder := json.NewEncoder(w)
+
+	_ = core.WalkEntries(h.storer, func(key string, value []byte, ttl time.Duration) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return true
+		}
+
+		return encoder.Encode(entry{Key: key, Value: value, TTL: ttl.String()}) == nil
+	})
+}
+
+func (h *Handler) getMultiLevel(w http.ResponseWriter, r *http.Request) {
+	var body getMultiLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), body.Method, body.URL, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	req.Header = body.Header
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+
+	if body.Validator == nil {
+		body.Validator = &core.Revalidator{}
+	}
+
+	fresh, stale := h.storer.GetMultiLevel(body.Key, req, body.Validator)
+	response := getMultiLevelResponse{Validator: body.Validator}
+
+	if response.Fresh, err = dumpResponse(fresh); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if response.Stale, err = dumpResponse(stale); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+func (h *Handler) setMultiLevel(w http.ResponseWriter, r *http.Request) {
+	var body setMultiLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	duration, err := parseDuration(body.Duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	err = h.storer.SetMultiLevel(body.BaseKey, body.VariedKey, body.Value, body.VariedHeaders, body.ETag, duration, body.RealKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func dumpResponse(response *http.Response) ([]byte, error) {
+	if response == nil {
+		return nil, nil
+	}
+
+	return httputil.DumpResponse(response, true)
+}
+
+func parseDuration(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(raw)
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
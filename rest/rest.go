@@ -0,0 +1,437 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const defaultTimeout = 5 * time.Second
+
+var errUnexpectedStatus = errors.New("unexpected rest response status")
+
+// Rest provider type, a Storer served by a remote Handler over HTTP.
+type Rest struct {
+	url      string
+	token    string
+	client   *http.Client
+	stale    time.Duration
+	uuid     string
+	logger   core.Logger
+	timeouts core.Timeouts
+	// ctx is the context bound with WithContext, nil until then.
+	ctx context.Context
+}
+
+// configuration is the typed Rest provider configuration.
+type configuration struct {
+	// Token is sent as a bearer Authorization to the handler.
+	Token string `json:"token"`
+	// TokenEnv is the environment variable holding the token.
+	TokenEnv string `json:"token_env"`
+	// Timeout bounds each request to the handler.
+	Timeout time.Duration `json:"timeout"`
+}
+
+func parseConfiguration(restConfiguration core.CacheProvider) (configuration, error) {
+	cfg := configuration{Timeout: defaultTimeout}
+
+	if err := core.DecodeConfiguration(restConfiguration.Configuration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid rest configuration: %w", err)
+	}
+
+	if restConfiguration.URL != "" {
+		if parsed, err := url.Parse(restConfiguration.URL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return cfg, fmt.Errorf("invalid rest configuration: the url %s must be absolute", restConfiguration.URL)
+		}
+	}
+
+	if cfg.TokenEnv != "" {
+		if cfg.Token != "" {
+			return cfg, errors.New("invalid rest configuration: the token and token_env can't be both set")
+		}
+
+		cfg.Token = os.Getenv(cfg.TokenEnv)
+		if cfg.Token == "" {
+			return cfg, fmt.Errorf("invalid rest configuration: the environment variable %s is empty", cfg.TokenEnv)
+		}
+	}
+
+	if cfg.Timeout <= 0 {
+		return cfg, errors.New("invalid rest configuration: the timeout must be positive")
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(restConfiguration any) error {
+	_, err := parseConfiguration(core.CacheProvider{Configuration: restConfiguration})
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("rest", Factory)
+}
+
+// Factory function create new Rest instance, the url is the one the Handler
+// is served under.
+func Factory(restConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(restConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	if restConfiguration.URL == "" {
+		return nil, errors.New("invalid rest configuration: the url is required")
+	}
+
+	timeouts, err := core.TimeoutsFromConfiguration(restConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := core.TLSConfigFromConfiguration(restConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &Rest{
+		url:      restConfiguration.URL,
+		token:    cfg.Token,
+		client:   &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		stale:    stale,
+		uuid:     core.InstanceID("rest", restConfiguration, stale),
+		logger:   logger,
+		timeouts: timeouts,
+	}, nil
+}
+
+// Name returns the storer name.
+func (provider *Rest) Name() string {
+	return "REST"
+}
+
+// WithContext returns the provider sending its requests with the context,
+// e.g. to propagate the span of the caller to the rest storage.
+func (provider *Rest) WithContext(ctx context.Context) core.Storer {
+	bound := *provider
+	bound.ctx = ctx
+
+	return &bound
+}
+
+func (provider *Rest) context() context.Context {
+	if provider.ctx == nil {
+		return context.Background()
+	}
+
+	return provider.ctx
+}
+
+// Uuid returns an unique identifier.
+func (provider *Rest) Uuid() string {
+	return provider.uuid
+}
+
+// do sends the request to the handler, the caller closes the response body.
+func (provider *Rest) do(ctx context.Context, method string, query url.Values, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, provider.url+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if provider.token != "" {
+		req.Header.Set("Authorization", "Bearer "+provider.token)
+	}
+
+	return provider.client.Do(req)
+}
+
+// call sends the request to the handler, the response body is read and
+// closed. The statuses other than the expected one are errors.
+func (provider *Rest) call(ctx context.Context, method string, query url.Values, body []byte, expected int) ([]byte, error) {
+	res, err := provider.do(ctx, method, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	payload, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != expected {
+		return payload, fmt.Errorf("%w %d", errUnexpectedStatus, res.StatusCode)
+	}
+
+	return payload, nil
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *Rest) MapKeys(prefix string) map[string]string {
+	ctx, cancel := provider.timeouts.ReadContext(provider.context())
+	defer cancel()
+
+	keys := map[string]string{}
+
+	payload, err := provider.call(ctx, http.MethodGet, url.Values{"prefix": {prefix}}, nil, http.StatusOK)
+	if err != nil {
+		provider.logger.Errorf("Impossible to map the keys %s from the rest storage, %v", prefix, err)
+
+		return keys
+	}
+
+	values := map[string][]byte{}
+	if err = json.Unmarshal(payload, &values); err != nil {
+		provider.logger.Errorf("Impossible to decode the keys %s from the rest storage, %v", prefix, err)
+
+		return keys
+	}
+
+	for key, value := range values {
+		keys[key] = string(value)
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Rest) ListKeys() []string {
+	ctx, cancel := provider.timeouts.ReadContext(provider.context())
+	defer cancel()
+
+	keys := []string{}
+
+	payload, err := provider.call(ctx, http.MethodGet, url.Values{}, nil, http.StatusOK)
+	if err == nil {
+		err = json.Unmarshal(payload, &keys)
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys from the rest storage, %v", err)
+
+		return []string{}
+	}
+
+	return keys
+}
+
+// WalkEntries streams the entries of the remote storer with their remaining
+// TTL.
+func (provider *Rest) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	ctx, cancel := context.WithCancel(provider.context())
+	defer cancel()
+
+	res, err := provider.do(ctx, http.MethodGet, url.Values{"walk": {""}}, nil)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w %d", errUnexpectedStatus, res.StatusCode)
+	}
+
+	decoder := json.NewDecoder(res.Body)
+
+	for {
+		var current entry
+		if err = decoder.Decode(&current); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		ttl, err := parseDuration(current.TTL)
+		if err != nil {
+			return err
+		}
+
+		if !walkFn(current.Key, current.Value, ttl) {
+			return nil
+		}
+	}
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Rest) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Rest) Lookup(key string) ([]byte, error) {
+	ctx, cancel := provider.timeouts.ReadContext(provider.context())
+	defer cancel()
+
+	res, err := provider.do(ctx, http.MethodGet, url.Values{"key": {key}}, nil)
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s from the rest storage, %v", key, err)
+
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, core.ErrKeyNotFound
+	default:
+		return nil, fmt.Errorf("%w %d", errUnexpectedStatus, res.StatusCode)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Rest) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	body, err := json.Marshal(getMultiLevelRequest{
+		Key:       key,
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Header:    req.Header,
+		Validator: validator,
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to encode the multi level request of the key %s, %v", key, err)
+
+		return fresh, stale
+	}
+
+	ctx, cancel := provider.timeouts.ReadContext(req.Context())
+	defer cancel()
+
+	payload, err := provider.call(ctx, http.MethodPost, url.Values{"action": {"get_multi_level"}}, body, http.StatusOK)
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s from the rest storage, %v", key, err)
+
+		return fresh, stale
+	}
+
+	response := getMultiLevelResponse{Validator: validator}
+	if err = json.Unmarshal(payload, &response); err != nil {
+		provider.logger.Errorf("Impossible to decode the multi level response of the key %s, %v", key, err)
+
+		return fresh, stale
+	}
+
+	fresh = provider.readResponse(key, response.Fresh, req)
+	stale = provider.readResponse(key, response.Stale, req)
+
+	return fresh, stale
+}
+
+func (provider *Rest) readResponse(key string, raw []byte, req *http.Request) *http.Response {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), req)
+	if err != nil {
+		provider.logger.Errorf("An error occurred while reading response for the key %s: %v", key, err)
+
+		return nil
+	}
+
+	return response
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *Rest) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	body, err := json.Marshal(setMultiLevelRequest{
+		BaseKey:       baseKey,
+		VariedKey:     variedKey,
+		Value:         value,
+		VariedHeaders: variedHeaders,
+		ETag:          etag,
+		Duration:      duration.String(),
+		RealKey:       realKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	_, err = provider.call(ctx, http.MethodPost, url.Values{"action": {"set_multi_level"}}, body, http.StatusNoContent)
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s into the rest storage, %v", variedKey, err)
+	}
+
+	return err
+}
+
+// Set method will store the response in the rest storage.
+func (provider *Rest) Set(key string, value []byte, duration time.Duration) error {
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	_, err := provider.call(ctx, http.MethodPut, url.Values{"key": {key}, "ttl": {duration.String()}}, value, http.StatusNoContent)
+	if err != nil {
+		provider.logger.Errorf("Impossible to set the key %s into the rest storage, %v", key, err)
+	}
+
+	return err
+}
+
+// Delete method will delete the response in the rest storage if exists corresponding to key param.
+func (provider *Rest) Delete(key string) {
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	if _, err := provider.call(ctx, http.MethodDelete, url.Values{"key": {key}}, nil, http.StatusNoContent); err != nil {
+		provider.logger.Errorf("Impossible to delete the key %s from the rest storage, %v", key, err)
+	}
+}
+
+// DeleteMany method will delete the responses in the rest storage if exists corresponding to the regex key param.
+func (provider *Rest) DeleteMany(key string) {
+	ctx, cancel := provider.timeouts.WriteContext(provider.context())
+	defer cancel()
+
+	if _, err := provider.call(ctx, http.MethodDelete, url.Values{"regex": {key}}, nil, http.StatusNoContent); err != nil {
+		provider.logger.Errorf("Impossible to delete the keys %s from the rest storage, %v", key, err)
+	}
+}
+
+// Healthy reaches the handler, it checks the health of the served storer.
+func (provider *Rest) Healthy(ctx context.Context) error {
+	_, err := provider.call(ctx, http.MethodHead, url.Values{}, nil, http.StatusNoContent)
+
+	return err
+}
+
+// Init method will.
+func (provider *Rest) Init() error {
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *Rest) Reset() error {
+	provider.client.CloseIdleConnections()
+
+	return nil
+}
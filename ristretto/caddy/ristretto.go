@@ -0,0 +1,60 @@
+package caddy
+
+import (
+	"net/http"
+
+	caddy "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/ristretto"
+)
+
+const moduleName = "ristretto"
+
+// Ristretto storage.
+type Ristretto struct {
+	// Keep the handler configuration.
+	core.Configuration
+}
+
+//nolint:gochecknoinits
+func init() {
+	caddy.RegisterModule(Ristretto{})
+}
+
+// CaddyModule returns the Caddy module information.
+func (Ristretto) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "storages.cache.ristretto",
+		New: func() caddy.Module { return new(Ristretto) },
+	}
+}
+
+// Provision to do the provisioning part.
+func (b *Ristretto) Provision(ctx caddy.Context) error {
+	logger := ctx.Logger(b)
+	storer, err := ristretto.Factory(b.Configuration.Provider, logger.Sugar(), b.Configuration.Stale)
+
+	if err != nil {
+		return err
+	}
+
+	storer, err = core.EncryptedStorerFromConfiguration(storer, b.Configuration.Provider, b.Configuration.Stale, logger.Sugar())
+	if err != nil {
+		return err
+	}
+
+	core.RegisterStorage(storer)
+
+	return nil
+}
+
+func (b *Ristretto) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyhttp.Handler) error {
+	return next.ServeHTTP(rw, rq)
+}
+
+// Interface guards.
+var (
+	_ caddy.Provisioner           = (*Ristretto)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Ristretto)(nil)
+)
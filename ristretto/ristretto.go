@@ -0,0 +1,472 @@
+package ristretto
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/dgraph-io/ristretto/v2"
+	"github.com/dustin/go-humanize"
+)
+
+// Ristretto provider type.
+type Ristretto struct {
+	store       *store
+	stale       time.Duration
+	uuid        string
+	logger      core.Logger
+	compressor  core.Compressor
+	instanceKey string
+	mu          sync.Mutex
+	counters    sync.Mutex
+}
+
+// entry is the stored value with its key, Ristretto only keeps the key hash.
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !e.expiresAt.After(now)
+}
+
+// store is the Ristretto cache along with the index of its keys, Ristretto
+// can't list them. The evicted and rejected entries leave the index.
+type store struct {
+	cache *ristretto.Cache[string, *entry]
+	keys  sync.Map
+}
+
+func (s *store) forget(item *ristretto.Item[*entry]) {
+	if item.Value != nil {
+		s.keys.CompareAndDelete(item.Value.key, item.Value)
+	}
+}
+
+// rangeKeys calls fn with the live entries until it returns false.
+func (s *store) rangeKeys(fn func(current *entry) bool) {
+	now := time.Now()
+
+	s.keys.Range(func(_, value any) bool {
+		current, _ := value.(*entry)
+		if current == nil || current.expired(now) {
+			return true
+		}
+
+		return fn(current)
+	})
+}
+
+const (
+	// entryOverhead approximates the memory used by an entry besides its key
+	// and value.
+	entryOverhead = 64
+
+	defaultMaxBytes    = "64MB"
+	defaultNumCounters = 100_000
+	defaultBufferItems = 64
+)
+
+var instanceMap = sync.Map{}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("ristretto", Factory)
+}
+
+// configuration is the typed Ristretto provider configuration.
+type configuration struct {
+	// MaxBytes is the maximum size of the stored keys and values ("256MB").
+	MaxBytes string `json:"max_bytes"`
+	// NumCounters is the number of keys whose access frequency is tracked
+	// by the admission policy, about ten times the expected entries.
+	NumCounters int64 `json:"num_counters"`
+	// BufferItems is the size of the buffers batching the accesses.
+	BufferItems int64 `json:"buffer_items"`
+}
+
+func parseConfiguration(ristrettoConfiguration any) (configuration, int64, error) {
+	cfg := configuration{
+		MaxBytes:    defaultMaxBytes,
+		NumCounters: defaultNumCounters,
+		BufferItems: defaultBufferItems,
+	}
+
+	if err := core.DecodeConfiguration(ristrettoConfiguration, &cfg); err != nil {
+		return cfg, 0, fmt.Errorf("invalid ristretto configuration: %w", err)
+	}
+
+	if cfg.NumCounters <= 0 {
+		return cfg, 0, fmt.Errorf("invalid ristretto configuration: the num_counters must be positive, %d given", cfg.NumCounters)
+	}
+
+	if cfg.BufferItems <= 0 {
+		return cfg, 0, fmt.Errorf("invalid ristretto configuration: the buffer_items must be positive, %d given", cfg.BufferItems)
+	}
+
+	maxBytes, err := humanize.ParseBytes(cfg.MaxBytes)
+	if err != nil {
+		return cfg, 0, fmt.Errorf("invalid ristretto configuration: max_bytes: %w", err)
+	}
+
+	if maxBytes == 0 || maxBytes > math.MaxInt64 {
+		return cfg, 0, fmt.Errorf("invalid ristretto configuration: the max_bytes must be positive, %s given", cfg.MaxBytes)
+	}
+
+	return cfg, int64(maxBytes), nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(ristrettoConfiguration any) error {
+	_, _, err := parseConfiguration(ristrettoConfiguration)
+
+	return err
+}
+
+// entryCost is the cost of an entry, the cache is sized in bytes.
+func entryCost(key string, value []byte) int64 {
+	return int64(len(key) + len(value) + entryOverhead)
+}
+
+func newStore(cfg configuration, maxBytes int64) (*store, error) {
+	s := &store{}
+
+	cache, err := ristretto.NewCache(&ristretto.Config[string, *entry]{
+		NumCounters:        cfg.NumCounters,
+		MaxCost:            maxBytes,
+		BufferItems:        cfg.BufferItems,
+		Metrics:            true,
+		IgnoreInternalCost: true,
+		OnEvict:            s.forget,
+		OnReject:           s.forget,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache = cache
+
+	return s, nil
+}
+
+// Factory function create new Ristretto instance.
+func Factory(ristrettoCfg core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	ristrettoConfiguration := ristrettoCfg.Configuration
+
+	cfg, maxBytes, err := parseConfiguration(ristrettoConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, err := core.CompressorFromConfiguration(ristrettoConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceKey := fmt.Sprintf("%d-%d-%d", maxBytes, cfg.NumCounters, cfg.BufferItems)
+
+	if instance, ok := instanceMap.Load(instanceKey); ok && instance != nil {
+		return &Ristretto{
+			store:       instance.(*store),
+			stale:       stale,
+			uuid:        core.InstanceID("ristretto", ristrettoCfg, stale),
+			logger:      logger,
+			compressor:  compressor,
+			instanceKey: instanceKey,
+		}, nil
+	}
+
+	s, err := newStore(cfg, maxBytes)
+	if err != nil {
+		logger.Errorf("Impossible to instantiate the Ristretto DB, %v", err)
+
+		return nil, err
+	}
+
+	instance, _ := instanceMap.LoadOrStore(instanceKey, s)
+	if instance != s {
+		s.cache.Close()
+	}
+
+	logger.Infof("ristretto.storage.max_bytes %d", maxBytes)
+
+	return &Ristretto{
+		store:       instance.(*store),
+		stale:       stale,
+		uuid:        core.InstanceID("ristretto", ristrettoCfg, stale),
+		logger:      logger,
+		compressor:  compressor,
+		instanceKey: instanceKey,
+	}, nil
+}
+
+// Name returns the storer name.
+func (provider *Ristretto) Name() string {
+	return "RISTRETTO"
+}
+
+// Uuid returns an unique identifier.
+func (provider *Ristretto) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *Ristretto) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	provider.store.rangeKeys(func(current *entry) bool {
+		if k, found := strings.CutPrefix(current.key, prefix); found {
+			keys[k] = string(current.value)
+		}
+
+		return true
+	})
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *Ristretto) ListKeys() []string {
+	keys := []string{}
+
+	provider.store.rangeKeys(func(current *entry) bool {
+		if strings.HasPrefix(current.key, core.MappingKeyPrefix) {
+			mapping, err := core.DecodeMapping(current.value)
+			if err == nil {
+				for _, v := range mapping.GetMapping() {
+					keys = append(keys, v.GetRealKey())
+				}
+			}
+		}
+
+		return true
+	})
+
+	return keys
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *Ristretto) Get(key string) []byte {
+	result, _ := provider.Lookup(key)
+
+	return result
+}
+
+// Lookup method returns a copy of the stored value, core.ErrKeyNotFound when
+// the key doesn't exist or is expired.
+func (provider *Ristretto) Lookup(key string) ([]byte, error) {
+	value, found := provider.GetNoCopy(key)
+	if !found {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return bytes.Clone(value), nil
+}
+
+// GetNoCopy method returns the stored value itself, shared with the cache:
+// it must only be read, see core.NoCopyGetter.
+func (provider *Ristretto) GetNoCopy(key string) ([]byte, bool) {
+	current, found := provider.store.cache.Get(key)
+	if !found || current == nil {
+		return nil, false
+	}
+
+	return current.value, true
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Ristretto) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	val, found := provider.GetNoCopy(core.MappingKeyPrefix + key)
+	if !found {
+		provider.logger.Debugf("Impossible to get the mapping key %s in Ristretto", core.MappingKeyPrefix+key)
+
+		return
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
+
+	return
+}
+
+// set stores the entry and waits for the admission policy, so the value can
+// be read right after. It returns false when the entry is dropped or
+// rejected.
+func (provider *Ristretto) set(key string, value []byte, duration time.Duration) bool {
+	current := &entry{key: key, value: value}
+	if duration > 0 {
+		current.expiresAt = time.Now().Add(duration)
+	}
+
+	provider.store.keys.Store(key, current)
+
+	if !provider.store.cache.SetWithTTL(key, current, entryCost(key, value), duration) {
+		provider.store.keys.CompareAndDelete(key, current)
+
+		return false
+	}
+
+	provider.store.cache.Wait()
+
+	_, found := provider.store.keys.Load(key)
+
+	return found
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *Ristretto) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into Ristretto, %v", variedKey, err)
+
+		return err
+	}
+
+	if !provider.set(variedKey, compressed, duration+provider.stale) {
+		provider.logger.Errorf("Impossible to set the key %s into Ristretto, rejected by the admission policy", variedKey)
+
+		return nil
+	}
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	// The mapping is read and written at once for the instance.
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	item, _ := provider.GetNoCopy(mappingKey)
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, item, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	provider.logger.Debugf("Store the new mapping for the key %s in Ristretto", variedKey)
+
+	if !provider.set(mappingKey, val, 0) {
+		provider.logger.Errorf("Impossible to set the mapping %s into Ristretto, rejected by the admission policy", mappingKey)
+
+		return nil
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in Ristretto provider.
+func (provider *Ristretto) Set(key string, value []byte, duration time.Duration) error {
+	if !provider.set(key, value, duration) {
+		provider.logger.Errorf("Impossible to set the key %s into Ristretto, rejected by the admission policy", key)
+	}
+
+	return nil
+}
+
+// Increment adds delta to the counter, the increments are serialized by a
+// lock held while the counter is read and stored back.
+func (provider *Ristretto) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	provider.counters.Lock()
+	defer provider.counters.Unlock()
+
+	current, _ := provider.GetNoCopy(key)
+
+	counter, err := core.AddToCounter(key, current, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	if !provider.set(key, core.EncodeCounter(counter), ttl) {
+		return 0, fmt.Errorf("impossible to set the counter %s into Ristretto, rejected by the admission policy", key)
+	}
+
+	return counter, nil
+}
+
+// Delete method will delete the response in Ristretto provider if exists corresponding to key param.
+func (provider *Ristretto) Delete(key string) {
+	provider.store.cache.Del(key)
+	provider.store.keys.Delete(key)
+}
+
+// DeleteMany method will delete the responses in Ristretto provider if exists corresponding to the regex key param.
+func (provider *Ristretto) DeleteMany(key string) {
+	_, _ = provider.DeleteManyCount(key, false)
+}
+
+// DeleteManyCount deletes the keys matching the regular expression, listed
+// from the keys index. In dry-run the matching keys are listed without being
+// deleted.
+func (provider *Ristretto) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result := core.DeleteManyResult{}
+
+	rgKey, err := regexp.Compile(pattern)
+	if err != nil {
+		return result, err
+	}
+
+	keys := []string{}
+
+	provider.store.rangeKeys(func(current *entry) bool {
+		if rgKey.MatchString(current.key) {
+			keys = append(keys, current.key)
+		}
+
+		return true
+	})
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if !dryRun {
+			provider.Delete(key)
+		}
+
+		result.Add(key, dryRun)
+	}
+
+	return result, nil
+}
+
+// Stats returns the ristretto cache stats, the bytes count the stored keys
+// and values.
+func (provider *Ristretto) Stats() core.StorerStats {
+	metrics := provider.store.cache.Metrics
+	stats := core.NewStorerStats(int64(metrics.Hits()), int64(metrics.Misses()))
+	stats.Evictions = int64(metrics.KeysEvicted())
+
+	provider.store.rangeKeys(func(current *entry) bool {
+		stats.Entries++
+		stats.Bytes += int64(len(current.key) + len(current.value))
+
+		return true
+	})
+
+	return stats
+}
+
+// Init method will.
+func (provider *Ristretto) Init() error {
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *Ristretto) Reset() error {
+	provider.store.cache.Clear()
+	provider.store.keys.Clear()
+
+	// Only delete this instance from the cache
+	instanceMap.Delete(provider.instanceKey)
+
+	return nil
+}
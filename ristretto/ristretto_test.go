@@ -0,0 +1,322 @@
+package ristretto_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
+	"github.com/darkweak/storages/ristretto"
+	"github.com/pierrec/lz4/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getRistrettoInstance() (core.Storer, error) {
+	return ristretto.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), 0)
+}
+
+func TestRistrettoConnectionFactory(t *testing.T) {
+	instance, err := getRistrettoInstance()
+
+	if nil != err {
+		t.Error("Shouldn't have panic")
+	}
+
+	if nil == instance {
+		t.Error("Ristretto should be instanciated")
+	}
+}
+
+func TestIShouldBeAbleToReadAndWriteDataInRistretto(t *testing.T) {
+	client, _ := getRistrettoInstance()
+
+	_ = client.Set("Test", []byte(baseValue), time.Duration(20)*time.Second)
+
+	res := client.Get("Test")
+	if len(res) == 0 {
+		t.Errorf("Key %s should exist", baseValue)
+	}
+
+	if baseValue != string(res) {
+		t.Errorf("%s not corresponding to %s", string(res), baseValue)
+	}
+}
+
+func TestRistretto_GetRequestInCache(t *testing.T) {
+	client, _ := getRistrettoInstance()
+	res := client.Get(nonExistentKey)
+
+	if 0 < len(res) {
+		t.Errorf("Key %s should not exist", nonExistentKey)
+	}
+}
+
+func TestRistretto_SetRequestInCache_TTL(t *testing.T) {
+	client, _ := getRistrettoInstance()
+	_ = client.Set("ShortLived", []byte(baseValue), time.Second)
+
+	if string(client.Get("ShortLived")) != baseValue {
+		t.Error("The key should exist before its expiration")
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	if len(client.Get("ShortLived")) != 0 {
+		t.Error("The key should expire with its TTL")
+	}
+
+	if _, found := client.MapKeys("Short")["Lived"]; found {
+		t.Error("The expired key shouldn't be listed")
+	}
+}
+
+func TestRistretto_DeleteRequestInCache(t *testing.T) {
+	client, _ := getRistrettoInstance()
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	client.Delete(byteKey)
+
+	if 0 < len(client.Get(byteKey)) {
+		t.Errorf("Key %s should not exist", byteKey)
+	}
+}
+
+func TestRistretto_DeleteMany(t *testing.T) {
+	client, _ := getRistrettoInstance()
+	_ = client.Set("PURGE_first", []byte(baseValue), time.Minute)
+	_ = client.Set("PURGE_second", []byte(baseValue), time.Minute)
+	_ = client.Set("KEEP_PURGE_third", []byte(baseValue), time.Minute)
+
+	result, err := core.DeleteManyCount(client, "^PURGE_", true)
+	if err != nil || result.Count != 2 || result.Keys[0] != "PURGE_first" {
+		t.Fatalf("The dry-run should list the two matching keys, %+v given, %v", result, err)
+	}
+
+	client.DeleteMany("^PURGE_")
+
+	if len(client.Get("PURGE_first")) != 0 || len(client.Get("KEEP_PURGE_third")) == 0 {
+		t.Error("Only the matching keys should be deleted")
+	}
+
+	if keys := client.MapKeys("PURGE_"); len(keys) != 0 {
+		t.Errorf("The deleted keys shouldn't be listed, %v given", keys)
+	}
+}
+
+func TestRistretto_SetMultiLevel(t *testing.T) {
+	client, _ := getRistrettoInstance()
+
+	// Create a large value (5MB) to ensure it's not truncated.
+	largeValue := make([]byte, 5*1024*1024)
+	for i := range largeValue {
+		largeValue[i] = byte(i % 256)
+	}
+
+	key := "large_value_test"
+
+	if err := client.SetMultiLevel(key, key, largeValue, http.Header{}, "", time.Minute, key); err != nil {
+		t.Fatalf("Failed to set large value: %v", err)
+	}
+
+	compressed := client.Get(key)
+	if compressed == nil {
+		t.Fatal("Retrieved value is nil")
+	}
+
+	decompressed := new(bytes.Buffer)
+	if _, err := lz4.NewReader(bytes.NewBuffer(compressed)).WriteTo(decompressed); err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	if decompressed.Len() != len(largeValue) {
+		t.Errorf("Data truncation: expected %d bytes, got %d bytes", len(largeValue), decompressed.Len())
+	}
+
+	if keys := client.ListKeys(); !strings.Contains(strings.Join(keys, ","), key) {
+		t.Errorf("The key should be listed from its mapping, %v given", keys)
+	}
+}
+
+func TestRistretto_GetNoCopy(t *testing.T) {
+	client, _ := getRistrettoInstance()
+	getter, ok := client.(core.NoCopyGetter)
+	if !ok {
+		t.Fatal("Ristretto should implement core.NoCopyGetter")
+	}
+
+	_ = client.Set("nocopy", []byte(baseValue), time.Minute)
+
+	shared, found := getter.GetNoCopy("nocopy")
+	if !found || string(shared) != baseValue {
+		t.Fatalf("The stored value should be returned, %s given", shared)
+	}
+
+	if again, _ := getter.GetNoCopy("nocopy"); &again[0] != &shared[0] {
+		t.Error("The value shouldn't be copied")
+	}
+
+	copied := client.Get("nocopy")
+	copied[0] = 'X'
+
+	if again, _ := getter.GetNoCopy("nocopy"); string(again) != baseValue {
+		t.Errorf("Get should return a copy owned by the caller, %s stored", again)
+	}
+
+	if _, found = getter.GetNoCopy(nonExistentKey); found {
+		t.Error("A missing key shouldn't be found")
+	}
+
+	client.Delete("nocopy")
+}
+
+func TestRistretto_NewStorer(t *testing.T) {
+	instance, err := core.NewStorer("ristretto", core.CacheProvider{}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("The ristretto storage should be registered: %v", err)
+	}
+
+	if instance.Name() != "RISTRETTO" {
+		t.Errorf("The ristretto storage should be returned, %s given", instance.Name())
+	}
+}
+
+func TestRistretto_Validate(t *testing.T) {
+	if err := ristretto.Validate(map[string]interface{}{"max_bytes": "1MB", "num_counters": "1000"}); err != nil {
+		t.Errorf("The num_counters given as a string should be valid, %v given", err)
+	}
+
+	if err := ristretto.Validate(map[string]interface{}{"num_counters": -1}); err == nil {
+		t.Error("A negative num_counters should be invalid")
+	}
+
+	if err := ristretto.Validate(map[string]interface{}{"max_bytes": "many"}); err == nil {
+		t.Error("A malformed max_bytes should be invalid")
+	}
+
+	if err := ristretto.Validate(map[string]interface{}{"size": 1000}); err == nil || !strings.Contains(err.Error(), "size") {
+		t.Errorf("The unknown key should be reported, %v given", err)
+	}
+}
+
+func TestRistretto_MaxBytes(t *testing.T) {
+	client, err := ristretto.Factory(core.CacheProvider{Configuration: map[string]interface{}{"max_bytes": "4KB"}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create ristretto instance: %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("%s not corresponding to %s", client.Get(byteKey), baseValue)
+	}
+
+	_ = client.Set("large", bytes.Repeat([]byte("a"), 8<<10), time.Minute)
+
+	if client.Get("large") != nil {
+		t.Error("A value larger than max_bytes shouldn't be stored")
+	}
+
+	if _, found := client.MapKeys("")["large"]; found {
+		t.Error("The rejected key shouldn't be listed")
+	}
+}
+
+func TestRistretto_Lookup(t *testing.T) {
+	client, _ := getRistrettoInstance()
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestRistretto_Increment(t *testing.T) {
+	client, _ := getRistrettoInstance()
+	counter, ok := client.(core.Counter)
+	if !ok {
+		t.Fatal("Ristretto should implement core.Counter")
+	}
+
+	var wg sync.WaitGroup
+
+	for range 50 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = counter.Increment("hits", 2, time.Minute)
+		}()
+	}
+
+	wg.Wait()
+
+	if value, err := counter.Increment("hits", -1, 0); err != nil || value != 99 {
+		t.Errorf("The concurrent increments should all be counted, %d given, %v", value, err)
+	}
+
+	if value := client.Get("hits"); string(value) != "99" {
+		t.Errorf("The counter should be stored as a decimal string, %s given", value)
+	}
+
+	client.Delete("hits")
+}
+
+func TestRistretto_Stats(t *testing.T) {
+	client, err := ristretto.Factory(core.CacheProvider{Configuration: map[string]interface{}{"max_bytes": "2KB"}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create ristretto instance: %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Get(byteKey)
+	_ = client.Get(nonExistentKey)
+
+	stats, found := core.Stats(client)
+	if !found {
+		t.Fatal("The ristretto storer should report its stats")
+	}
+
+	if stats.Entries != 1 || stats.Bytes < int64(len(byteKey)) || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Unexpected stats, %+v given", stats)
+	}
+
+	for i := range 100 {
+		_ = client.Set(fmt.Sprintf("key-%d", i), []byte(baseValue), time.Minute)
+	}
+
+	if stats, _ = core.Stats(client); stats.Bytes > 2<<10 {
+		t.Errorf("The keys exceeding max_bytes shouldn't be kept, %+v given", stats)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := ristretto.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
@@ -0,0 +1,378 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+// S3 provider type.
+type S3 struct {
+	client         *minio.Client
+	bucket         string
+	endpoint       string
+	stale          time.Duration
+	uuid           string
+	ctx            context.Context
+	logger         core.Logger
+	compressor     core.Compressor
+	expirationDays int
+}
+
+const (
+	defaultBucket         = "souin-bucket"
+	defaultExpirationDays = 1
+	lifecycleRuleID       = "souin-expiration"
+	// expiresMetadata is the user metadata holding the expiration timestamp
+	// because the lifecycle rules only expire the objects with a day
+	// granularity.
+	expiresMetadata = "Souin-Expires"
+)
+
+// configuration is the typed S3 provider configuration.
+type configuration struct {
+	Endpoint     string `json:"endpoint"`
+	Bucket       string `json:"bucket"`
+	AccessKey    string `json:"access_key"`
+	SecretKey    string `json:"secret_key"`
+	SessionToken string `json:"session_token"`
+	Region       string `json:"region"`
+	Secure       bool   `json:"secure"`
+	// ExpirationDays is the lifecycle rule expiration of the objects.
+	ExpirationDays int `json:"expiration_days"`
+}
+
+func parseConfiguration(s3Configuration core.CacheProvider) (configuration, error) {
+	cfg := configuration{
+		Endpoint:       s3Configuration.URL,
+		Bucket:         defaultBucket,
+		ExpirationDays: defaultExpirationDays,
+	}
+
+	if err := core.DecodeConfiguration(s3Configuration.Configuration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid s3 configuration: %w", err)
+	}
+
+	if cfg.Bucket == "" {
+		return cfg, errors.New("invalid s3 configuration: the bucket can't be empty")
+	}
+
+	if cfg.ExpirationDays <= 0 {
+		return cfg, fmt.Errorf("invalid s3 configuration: the expiration_days must be positive, %d given", cfg.ExpirationDays)
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(s3Configuration any) error {
+	_, err := parseConfiguration(core.CacheProvider{Configuration: s3Configuration})
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("s3", Factory)
+}
+
+// Factory function create new S3 instance.
+func Factory(s3Configuration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(s3Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Endpoint == "" {
+		return nil, errors.New("no s3 endpoint given")
+	}
+
+	compressor, err := core.CompressorFromConfiguration(s3Configuration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, cfg.SessionToken),
+		Secure: cfg.Secure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		logger.Error("Impossible to initialize the S3 client.", err)
+
+		return nil, err
+	}
+
+	return &S3{
+		client:         client,
+		bucket:         cfg.Bucket,
+		endpoint:       cfg.Endpoint,
+		stale:          stale,
+		uuid:           core.InstanceID("s3", s3Configuration, stale),
+		ctx:            context.Background(),
+		logger:         logger,
+		compressor:     compressor,
+		expirationDays: cfg.ExpirationDays,
+	}, nil
+}
+
+// Name returns the storer name.
+func (provider *S3) Name() string {
+	return "S3"
+}
+
+// Uuid returns an unique identifier.
+func (provider *S3) Uuid() string {
+	return provider.uuid
+}
+
+func isNotFound(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
+func (provider *S3) listObjects(prefix string) <-chan minio.ObjectInfo {
+	return provider.client.ListObjects(provider.ctx, provider.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *S3) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	for object := range provider.listObjects(prefix) {
+		if object.Err != nil {
+			provider.logger.Errorf("Impossible to list the S3 objects, %v", object.Err)
+
+			return keys
+		}
+
+		if value := provider.Get(object.Key); value != nil {
+			k, _ := strings.CutPrefix(object.Key, prefix)
+			keys[k] = string(value)
+		}
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *S3) ListKeys() []string {
+	keys := []string{}
+
+	for _, value := range provider.MapKeys(core.MappingKeyPrefix) {
+		mapping, err := core.DecodeMapping([]byte(value))
+		if err == nil {
+			for _, v := range mapping.GetMapping() {
+				keys = append(keys, v.GetRealKey())
+			}
+		}
+	}
+
+	return keys
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *S3) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *S3) Lookup(key string) ([]byte, error) {
+	object, err := provider.client.GetObject(provider.ctx, provider.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s in S3, %v", key, err)
+
+		return nil, err
+	}
+
+	defer object.Close()
+
+	info, err := object.Stat()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, core.ErrKeyNotFound
+		}
+
+		provider.logger.Errorf("Impossible to stat the key %s in S3, %v", key, err)
+
+		return nil, err
+	}
+
+	if expires := info.Metadata.Get("X-Amz-Meta-" + expiresMetadata); expires != "" {
+		if at, err := strconv.ParseInt(expires, 10, 64); err == nil && at < time.Now().Unix() {
+			provider.Delete(key)
+
+			return nil, core.ErrKeyNotFound
+		}
+	}
+
+	value, err := io.ReadAll(object)
+	if err != nil {
+		provider.logger.Errorf("Impossible to read the key %s in S3, %v", key, err)
+
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *S3) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	val := provider.Get(core.MappingKeyPrefix + key)
+	if val == nil {
+		provider.logger.Debugf("Impossible to get the mapping key %s in S3", core.MappingKeyPrefix+key)
+
+		return fresh, stale
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
+
+	return fresh, stale
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *S3) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into S3, %v", variedKey, err)
+
+		return err
+	}
+
+	if err = provider.Set(variedKey, compressed, duration+provider.stale); err != nil {
+		return err
+	}
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, provider.Get(mappingKey), provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	provider.logger.Debugf("Store the new mapping for the key %s in S3", variedKey)
+
+	if err = provider.Set(mappingKey, val, 0); err != nil {
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in S3 provider.
+func (provider *S3) Set(key string, value []byte, duration time.Duration) error {
+	opts := minio.PutObjectOptions{
+		ContentType:  "application/octet-stream",
+		UserMetadata: map[string]string{},
+	}
+
+	if duration > 0 {
+		opts.UserMetadata[expiresMetadata] = strconv.FormatInt(time.Now().Add(duration).Unix(), 10)
+	}
+
+	_, err := provider.client.PutObject(provider.ctx, provider.bucket, key, bytes.NewReader(value), int64(len(value)), opts)
+	if err != nil {
+		provider.logger.Errorf("Impossible to set value into S3, %v", err)
+	}
+
+	return err
+}
+
+// Delete method will delete the response in S3 provider if exists corresponding to key param.
+func (provider *S3) Delete(key string) {
+	if err := provider.client.RemoveObject(provider.ctx, provider.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		provider.logger.Errorf("Impossible to delete the key %s in S3, %v", key, err)
+	}
+}
+
+// DeleteMany method will delete the responses in S3 provider if exists corresponding to the regex key param.
+func (provider *S3) DeleteMany(key string) {
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	objects := make(chan minio.ObjectInfo)
+
+	go func() {
+		defer close(objects)
+
+		for object := range provider.listObjects("") {
+			if object.Err != nil {
+				provider.logger.Errorf("Impossible to list the S3 objects, %v", object.Err)
+
+				return
+			}
+
+			if rgKey.MatchString(object.Key) {
+				objects <- object
+			}
+		}
+	}()
+
+	for removeErr := range provider.client.RemoveObjects(provider.ctx, provider.bucket, objects, minio.RemoveObjectsOptions{}) {
+		provider.logger.Errorf("Impossible to delete the key %s in S3, %v", removeErr.ObjectName, removeErr.Err)
+	}
+}
+
+// Init method will create the bucket if needed and apply the lifecycle
+// rule that eventually purges the expired objects.
+func (provider *S3) Init() error {
+	exists, err := provider.client.BucketExists(provider.ctx, provider.bucket)
+	if err != nil {
+		provider.logger.Errorf("Impossible to check if the S3 bucket %s exists, %v", provider.bucket, err)
+
+		return err
+	}
+
+	if !exists {
+		if err = provider.client.MakeBucket(provider.ctx, provider.bucket, minio.MakeBucketOptions{}); err != nil {
+			provider.logger.Errorf("Impossible to create the S3 bucket %s, %v", provider.bucket, err)
+
+			return err
+		}
+	}
+
+	config := lifecycle.NewConfiguration()
+	config.Rules = []lifecycle.Rule{
+		{
+			ID:     lifecycleRuleID,
+			Status: "Enabled",
+			Expiration: lifecycle.Expiration{
+				Days: lifecycle.ExpirationDays(provider.expirationDays),
+			},
+		},
+	}
+
+	if err = provider.client.SetBucketLifecycle(provider.ctx, provider.bucket, config); err != nil {
+		provider.logger.Warnf("Impossible to set the lifecycle rule on the S3 bucket %s, %v", provider.bucket, err)
+	}
+
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *S3) Reset() error {
+	return nil
+}
@@ -0,0 +1,140 @@
+package s3_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/s3"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getS3Instance() (core.Storer, error) {
+	instance, err := s3.Factory(core.CacheProvider{
+		URL: "localhost:9000",
+		Configuration: map[string]interface{}{
+			"access_key": "minioadmin",
+			"secret_key": "minioadmin",
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance, instance.Init()
+}
+
+func TestS3ConnectionFactory(t *testing.T) {
+	instance, err := getS3Instance()
+	if nil != err {
+		t.Error("Shouldn't have panic", err)
+	}
+
+	if nil == instance {
+		t.Error("S3 should be instanciated")
+	}
+}
+
+func TestS3ConnectionFactory_NoEndpoint(t *testing.T) {
+	if _, err := s3.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), 0); err == nil {
+		t.Error("The factory should fail without endpoint")
+	}
+}
+
+func TestIShouldBeAbleToReadAndWriteDataInS3(t *testing.T) {
+	client, _ := getS3Instance()
+
+	_ = client.Set("Test", []byte(baseValue), 20*time.Second)
+
+	res := client.Get("Test")
+	if baseValue != string(res) {
+		t.Errorf("%s not corresponding to %s", string(res), baseValue)
+	}
+}
+
+func TestS3_GetRequestInCache(t *testing.T) {
+	client, _ := getS3Instance()
+
+	if res := client.Get(nonExistentKey); 0 < len(res) {
+		t.Errorf("Key %s should not exist", nonExistentKey)
+	}
+}
+
+func TestS3_SetRequestInCache_TTL(t *testing.T) {
+	client, _ := getS3Instance()
+	value := []byte("Hello world")
+
+	_ = client.Set(byteKey, value, time.Second)
+	time.Sleep(2 * time.Second)
+
+	if newValue := client.Get(byteKey); len(newValue) != 0 {
+		t.Errorf("Key %s should be expired, %s provided", byteKey, newValue)
+	}
+}
+
+func TestS3_DeleteRequestInCache(t *testing.T) {
+	client, _ := getS3Instance()
+
+	_ = client.Set(byteKey, []byte("A"), 20*time.Second)
+	client.Delete(byteKey)
+
+	if 0 < len(client.Get(byteKey)) {
+		t.Errorf("Key %s should not exist", byteKey)
+	}
+}
+
+func TestS3_DeleteMany(t *testing.T) {
+	client, _ := getS3Instance()
+
+	for _, key := range []string{"many-first", "many-second", "many-third"} {
+		_ = client.Set(key, []byte(key), 20*time.Second)
+	}
+
+	client.DeleteMany("^many-(first|second)$")
+
+	if keys := client.MapKeys("many-"); len(keys) != 1 || keys["third"] != "many-third" {
+		t.Errorf("Only the third key should remain, %v given", keys)
+	}
+}
+
+func TestS3_SetMultiLevel(t *testing.T) {
+	client, _ := getS3Instance()
+	value := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nHello")
+
+	if err := client.SetMultiLevel("base", "varied", value, http.Header{}, "", time.Minute, "real"); err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	stored := client.Get("varied")
+
+	reader, err := core.DetectCompressor(stored).Decompress(stored)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	decompressed := new(bytes.Buffer)
+	if _, err := decompressed.ReadFrom(reader); err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	if !bytes.Equal(decompressed.Bytes(), value) {
+		t.Errorf("The stored value doesn't match, %s given", decompressed.Bytes())
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://domain.com", nil)
+
+	fresh, _ := client.GetMultiLevel("base", req, &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The fresh response should exist")
+	}
+
+	_ = fresh.Body.Close()
+}
@@ -0,0 +1,60 @@
+package caddy
+
+import (
+	"net/http"
+
+	caddy "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/shardedmap"
+)
+
+const moduleName = "shardedmap"
+
+// ShardedMap storage.
+type ShardedMap struct {
+	// Keep the handler configuration.
+	core.Configuration
+}
+
+//nolint:gochecknoinits
+func init() {
+	caddy.RegisterModule(ShardedMap{})
+}
+
+// CaddyModule returns the Caddy module information.
+func (ShardedMap) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "storages.cache.shardedmap",
+		New: func() caddy.Module { return new(ShardedMap) },
+	}
+}
+
+// Provision to do the provisioning part.
+func (b *ShardedMap) Provision(ctx caddy.Context) error {
+	logger := ctx.Logger(b)
+	storer, err := shardedmap.Factory(b.Configuration.Provider, logger.Sugar(), b.Configuration.Stale)
+
+	if err != nil {
+		return err
+	}
+
+	storer, err = core.EncryptedStorerFromConfiguration(storer, b.Configuration.Provider, b.Configuration.Stale, logger.Sugar())
+	if err != nil {
+		return err
+	}
+
+	core.RegisterStorage(storer)
+
+	return nil
+}
+
+func (b *ShardedMap) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyhttp.Handler) error {
+	return next.ServeHTTP(rw, rq)
+}
+
+// Interface guards.
+var (
+	_ caddy.Provisioner           = (*ShardedMap)(nil)
+	_ caddyhttp.MiddlewareHandler = (*ShardedMap)(nil)
+)
@@ -0,0 +1,187 @@
+package shardedmap
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// entry is an immutable stored value, a write replaces the pointer so the
+// readers never lock.
+type entry struct {
+	value []byte
+	// expiresAt is the expiration in unix nanoseconds, zero when the entry
+	// never expires.
+	expiresAt int64
+}
+
+func (e *entry) expired(now int64) bool {
+	return e.expiresAt != 0 && e.expiresAt <= now
+}
+
+// expiration is a deadline of the TTL heap, it's stale once the key is
+// deleted or stored again.
+type expiration struct {
+	key   string
+	entry *entry
+}
+
+// expirations is a min-heap of the deadlines ordered by expiration.
+type expirations []expiration
+
+func (h expirations) Len() int           { return len(h) }
+func (h expirations) Less(i, j int) bool { return h[i].entry.expiresAt < h[j].entry.expiresAt }
+func (h expirations) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expirations) Push(x any) {
+	*h = append(*h, x.(expiration))
+}
+
+func (h *expirations) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = expiration{}
+	*h = old[:len(old)-1]
+
+	return last
+}
+
+// shard holds a part of the keys. The reads load the entries through the
+// sync.Map atomic pointers without locking, the writes are serialized by
+// the shard mutex which guards the TTL heap too.
+type shard struct {
+	entries sync.Map
+	mu      sync.Mutex
+	heap    expirations
+	// size is the number of stored keys, the stale heap deadlines are
+	// compacted once they outnumber it.
+	size int
+}
+
+func (s *shard) load(key string, now int64) (*entry, bool) {
+	value, found := s.entries.Load(key)
+	if !found {
+		return nil, false
+	}
+
+	current := value.(*entry)
+	if current.expired(now) {
+		return nil, false
+	}
+
+	return current, true
+}
+
+func newEntry(value []byte, duration time.Duration) *entry {
+	current := &entry{value: value}
+	if duration > 0 {
+		current.expiresAt = time.Now().Add(duration).UnixNano()
+	}
+
+	return current
+}
+
+func (s *shard) store(key string, value []byte, duration time.Duration) {
+	current := newEntry(value, duration)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(key, current)
+}
+
+// update stores the value returned by fn given the current one, nil when
+// the key doesn't exist, while the mutex is held so the concurrent updates
+// of the key are serialized.
+func (s *shard) update(key string, duration time.Duration, fn func(value []byte) ([]byte, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var value []byte
+	if current, found := s.load(key, time.Now().UnixNano()); found {
+		value = current.value
+	}
+
+	value, err := fn(value)
+	if err != nil {
+		return err
+	}
+
+	s.put(key, newEntry(value, duration))
+
+	return nil
+}
+
+// put swaps the entry of the key and records its deadline. The caller holds
+// the mutex.
+func (s *shard) put(key string, current *entry) {
+	if _, loaded := s.entries.Swap(key, current); !loaded {
+		s.size++
+	}
+
+	if current.expiresAt != 0 {
+		heap.Push(&s.heap, expiration{key: key, entry: current})
+		s.compact()
+	}
+}
+
+func (s *shard) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, loaded := s.entries.LoadAndDelete(key); loaded {
+		s.size--
+	}
+}
+
+// compact drops the stale deadlines when they outnumber the stored keys,
+// e.g. after a burst of rewrites. The caller holds the mutex.
+func (s *shard) compact() {
+	if len(s.heap) < 2*s.size+minCompaction {
+		return
+	}
+
+	live := s.heap[:0]
+
+	for _, deadline := range s.heap {
+		if current, found := s.entries.Load(deadline.key); found && current.(*entry) == deadline.entry {
+			live = append(live, deadline)
+		}
+	}
+
+	clear(s.heap[len(live):])
+	s.heap = live
+	heap.Init(&s.heap)
+}
+
+// expire deletes the entries expired at now and calls fn with each of them.
+func (s *shard) expire(now int64, fn func(key string, value []byte)) {
+	s.mu.Lock()
+
+	expired := []expiration{}
+
+	for len(s.heap) > 0 && s.heap[0].entry.expiresAt <= now {
+		deadline := heap.Pop(&s.heap).(expiration)
+		if s.entries.CompareAndDelete(deadline.key, deadline.entry) {
+			s.size--
+
+			expired = append(expired, deadline)
+		}
+	}
+
+	s.mu.Unlock()
+
+	for _, deadline := range expired {
+		fn(deadline.key, deadline.entry.value)
+	}
+}
+
+// clear deletes every entry.
+func (s *shard) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries.Clear()
+	s.heap = nil
+	s.size = 0
+}
@@ -0,0 +1,426 @@
+package shardedmap
+
+import (
+	"fmt"
+	"hash/maphash"
+	"net/http"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+// ShardedMap provider type, an in-memory storage split in independent
+// shards to spread the write contention.
+type ShardedMap struct {
+	store       *store
+	stale       time.Duration
+	uuid        string
+	logger      core.Logger
+	compressor  core.Compressor
+	instanceKey string
+}
+
+const (
+	// defaultCleanupInterval is the delay between two collections of the
+	// expired entries.
+	defaultCleanupInterval = time.Second
+	// minCompaction is the number of stale deadlines a shard keeps before
+	// compacting its TTL heap.
+	minCompaction = 1024
+)
+
+// store is the set of shards shared by the providers of the same
+// configuration.
+type store struct {
+	shards []shard
+	seed   maphash.Seed
+	hits   atomic.Int64
+	misses atomic.Int64
+	// mappings serializes the read-modify-write of the mappings.
+	mappings  sync.Mutex
+	evictions core.EvictionCallbacks
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newStore(shards int, cleanupInterval time.Duration) *store {
+	s := &store{
+		shards: make([]shard, shards),
+		seed:   maphash.MakeSeed(),
+		stop:   make(chan struct{}),
+	}
+
+	go s.collect(cleanupInterval)
+
+	return s
+}
+
+func (s *store) shard(key string) *shard {
+	return &s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+}
+
+// collect deletes the expired entries of every shard until the store is
+// closed.
+func (s *store) collect(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			now := time.Now().UnixNano()
+			for i := range s.shards {
+				s.shards[i].expire(now, s.evictions.Notify)
+			}
+		}
+	}
+}
+
+// rangeEntries calls fn with the live entries until it returns false.
+func (s *store) rangeEntries(fn func(key string, current *entry) bool) {
+	now := time.Now().UnixNano()
+
+	for i := range s.shards {
+		next := true
+
+		s.shards[i].entries.Range(func(key, value any) bool {
+			current := value.(*entry)
+			if current.expired(now) {
+				return true
+			}
+
+			next = fn(key.(string), current)
+
+			return next
+		})
+
+		if !next {
+			return
+		}
+	}
+}
+
+func (s *store) close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+var instanceMap = sync.Map{}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("shardedmap", Factory)
+}
+
+// configuration is the typed ShardedMap provider configuration.
+type configuration struct {
+	// Shards is the number of independent shards, four per CPU by default.
+	Shards int `json:"shards"`
+	// CleanupInterval is the delay between two collections of the expired
+	// entries, they're never returned meanwhile.
+	CleanupInterval time.Duration `json:"cleanup_interval"`
+}
+
+func parseConfiguration(shardedConfiguration any) (configuration, error) {
+	cfg := configuration{Shards: 4 * runtime.GOMAXPROCS(0), CleanupInterval: defaultCleanupInterval}
+
+	if err := core.DecodeConfiguration(shardedConfiguration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid shardedmap configuration: %w", err)
+	}
+
+	if cfg.Shards <= 0 {
+		return cfg, fmt.Errorf("invalid shardedmap configuration: the shards must be positive, %d given", cfg.Shards)
+	}
+
+	if cfg.CleanupInterval <= 0 {
+		return cfg, fmt.Errorf("invalid shardedmap configuration: the cleanup_interval must be positive, %s given", cfg.CleanupInterval)
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(shardedConfiguration any) error {
+	_, err := parseConfiguration(shardedConfiguration)
+
+	return err
+}
+
+// Factory function create new ShardedMap instance.
+func Factory(shardedCfg core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(shardedCfg.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, err := core.CompressorFromConfiguration(shardedCfg.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceKey := fmt.Sprintf("%d-%s", cfg.Shards, cfg.CleanupInterval)
+
+	instance, ok := instanceMap.Load(instanceKey)
+	if !ok {
+		s := newStore(cfg.Shards, cfg.CleanupInterval)
+
+		var loaded bool
+		if instance, loaded = instanceMap.LoadOrStore(instanceKey, s); loaded {
+			s.close()
+		} else {
+			logger.Infof("shardedmap.storage.shards %d", cfg.Shards)
+		}
+	}
+
+	return &ShardedMap{
+		store:       instance.(*store),
+		stale:       stale,
+		uuid:        core.InstanceID("shardedmap", shardedCfg, stale),
+		logger:      logger,
+		compressor:  compressor,
+		instanceKey: instanceKey,
+	}, nil
+}
+
+// Name returns the storer name.
+func (provider *ShardedMap) Name() string {
+	return "SHARDEDMAP"
+}
+
+// Uuid returns an unique identifier.
+func (provider *ShardedMap) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *ShardedMap) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	provider.store.rangeEntries(func(key string, current *entry) bool {
+		if k, found := strings.CutPrefix(key, prefix); found {
+			keys[k] = string(current.value)
+		}
+
+		return true
+	})
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *ShardedMap) ListKeys() []string {
+	keys := []string{}
+
+	provider.store.rangeEntries(func(key string, current *entry) bool {
+		if strings.HasPrefix(key, core.MappingKeyPrefix) {
+			mapping, err := core.DecodeMapping(current.value)
+			if err == nil {
+				for _, v := range mapping.GetMapping() {
+					keys = append(keys, v.GetRealKey())
+				}
+			}
+		}
+
+		return true
+	})
+
+	return keys
+}
+
+// WalkEntries streams every expiring entry with its remaining TTL.
+func (provider *ShardedMap) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	provider.store.rangeEntries(func(key string, current *entry) bool {
+		if current.expiresAt == 0 {
+			return true
+		}
+
+		return walkFn(key, current.value, max(time.Until(time.Unix(0, current.expiresAt)), 0))
+	})
+
+	return nil
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *ShardedMap) Get(key string) []byte {
+	result, _ := provider.Lookup(key)
+
+	return result
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired. It never locks.
+func (provider *ShardedMap) Lookup(key string) ([]byte, error) {
+	current, found := provider.store.shard(key).load(key, time.Now().UnixNano())
+	if !found {
+		provider.store.misses.Add(1)
+
+		return nil, core.ErrKeyNotFound
+	}
+
+	provider.store.hits.Add(1)
+
+	return current.value, nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *ShardedMap) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	val, err := provider.Lookup(core.MappingKeyPrefix + key)
+	if err != nil {
+		provider.logger.Debugf("Impossible to get the mapping key %s in ShardedMap", core.MappingKeyPrefix+key)
+
+		return
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val, req, validator, provider.logger)
+
+	return
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+func (provider *ShardedMap) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into ShardedMap, %v", variedKey, err)
+
+		return err
+	}
+
+	provider.store.shard(variedKey).store(variedKey, compressed, duration+provider.stale)
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	provider.store.mappings.Lock()
+	defer provider.store.mappings.Unlock()
+
+	item, _ := provider.Lookup(mappingKey)
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, item, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	provider.logger.Debugf("Store the new mapping for the key %s in ShardedMap", variedKey)
+	provider.store.shard(mappingKey).store(mappingKey, val, 0)
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in ShardedMap provider, a zero
+// duration never expires.
+func (provider *ShardedMap) Set(key string, value []byte, duration time.Duration) error {
+	provider.store.shard(key).store(key, value, duration)
+
+	return nil
+}
+
+// Increment adds delta to the counter while its shard is locked.
+func (provider *ShardedMap) Increment(key string, delta int64, ttl time.Duration) (int64, error) {
+	var counter int64
+
+	err := provider.store.shard(key).update(key, ttl, func(value []byte) ([]byte, error) {
+		var err error
+
+		counter, err = core.AddToCounter(key, value, delta)
+
+		return core.EncodeCounter(counter), err
+	})
+
+	return counter, err
+}
+
+// Delete method will delete the response in ShardedMap provider if exists corresponding to key param.
+func (provider *ShardedMap) Delete(key string) {
+	provider.store.shard(key).delete(key)
+}
+
+// DeleteMany method will delete the responses in ShardedMap provider if exists corresponding to the regex key param.
+func (provider *ShardedMap) DeleteMany(key string) {
+	_, _ = provider.DeleteManyCount(key, false)
+}
+
+// DeleteManyCount deletes the keys matching the regular expression. In
+// dry-run the matching keys are listed without being deleted.
+func (provider *ShardedMap) DeleteManyCount(pattern string, dryRun bool) (core.DeleteManyResult, error) {
+	result := core.DeleteManyResult{}
+
+	rgKey, err := regexp.Compile(pattern)
+	if err != nil {
+		return result, err
+	}
+
+	keys := []string{}
+
+	provider.store.rangeEntries(func(key string, _ *entry) bool {
+		if rgKey.MatchString(key) {
+			keys = append(keys, key)
+		}
+
+		return true
+	})
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if !dryRun {
+			provider.Delete(key)
+		}
+
+		result.Add(key, dryRun)
+	}
+
+	return result, nil
+}
+
+// OnEvicted registers fn for the entries collected once expired, it's
+// shared by the providers of the same configuration. The values are the
+// stored ones.
+func (provider *ShardedMap) OnEvicted(fn func(key string, value []byte)) {
+	provider.store.evictions.Add(fn)
+}
+
+// Stats returns the shardedmap stats, the bytes count the stored keys and
+// values.
+func (provider *ShardedMap) Stats() core.StorerStats {
+	stats := core.NewStorerStats(provider.store.hits.Load(), provider.store.misses.Load())
+
+	provider.store.rangeEntries(func(key string, current *entry) bool {
+		stats.Entries++
+		stats.Bytes += int64(len(key) + len(current.value))
+
+		return true
+	})
+
+	return stats
+}
+
+// Init method will.
+func (provider *ShardedMap) Init() error {
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *ShardedMap) Reset() error {
+	for i := range provider.store.shards {
+		provider.store.shards[i].clear()
+	}
+
+	provider.store.close()
+
+	// Only delete this instance from the cache
+	instanceMap.CompareAndDelete(provider.instanceKey, provider.store)
+
+	return nil
+}
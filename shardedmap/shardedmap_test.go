@@ -0,0 +1,309 @@
+package shardedmap_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
+	"github.com/darkweak/storages/shardedmap"
+	"github.com/pierrec/lz4/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getShardedMapInstance() (core.Storer, error) {
+	return shardedmap.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), 0)
+}
+
+func TestShardedMapConnectionFactory(t *testing.T) {
+	instance, err := getShardedMapInstance()
+
+	if nil != err {
+		t.Error("Shouldn't have panic")
+	}
+
+	if nil == instance {
+		t.Error("ShardedMap should be instanciated")
+	}
+}
+
+func TestIShouldBeAbleToReadAndWriteDataInShardedMap(t *testing.T) {
+	client, _ := getShardedMapInstance()
+
+	_ = client.Set("Test", []byte(baseValue), time.Duration(20)*time.Second)
+
+	res := client.Get("Test")
+	if len(res) == 0 {
+		t.Errorf("Key %s should exist", baseValue)
+	}
+
+	if baseValue != string(res) {
+		t.Errorf("%s not corresponding to %s", string(res), baseValue)
+	}
+}
+
+func TestShardedMap_GetRequestInCache(t *testing.T) {
+	client, _ := getShardedMapInstance()
+	res := client.Get(nonExistentKey)
+
+	if 0 < len(res) {
+		t.Errorf("Key %s should not exist", nonExistentKey)
+	}
+}
+
+func TestShardedMap_SetRequestInCache_TTL(t *testing.T) {
+	client, _ := getShardedMapInstance()
+	_ = client.Set("ShortLived", []byte(baseValue), 100*time.Millisecond)
+
+	if string(client.Get("ShortLived")) != baseValue {
+		t.Error("The key should exist before its expiration")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if len(client.Get("ShortLived")) != 0 {
+		t.Error("The key should expire with its TTL")
+	}
+
+	if _, found := client.MapKeys("Short")["Lived"]; found {
+		t.Error("The expired key shouldn't be listed")
+	}
+}
+
+func TestShardedMap_DeleteRequestInCache(t *testing.T) {
+	client, _ := getShardedMapInstance()
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	client.Delete(byteKey)
+
+	if 0 < len(client.Get(byteKey)) {
+		t.Errorf("Key %s should not exist", byteKey)
+	}
+}
+
+func TestShardedMap_DeleteMany(t *testing.T) {
+	client, _ := getShardedMapInstance()
+	_ = client.Set("PURGE_first", []byte(baseValue), time.Minute)
+	_ = client.Set("PURGE_second", []byte(baseValue), time.Minute)
+	_ = client.Set("KEEP_PURGE_third", []byte(baseValue), time.Minute)
+
+	result, err := core.DeleteManyCount(client, "^PURGE_", true)
+	if err != nil || result.Count != 2 || result.Keys[0] != "PURGE_first" {
+		t.Fatalf("The dry-run should list the two matching keys, %+v given, %v", result, err)
+	}
+
+	client.DeleteMany("^PURGE_")
+
+	if len(client.Get("PURGE_first")) != 0 || len(client.Get("KEEP_PURGE_third")) == 0 {
+		t.Error("Only the matching keys should be deleted")
+	}
+}
+
+func TestShardedMap_SetMultiLevel(t *testing.T) {
+	client, _ := getShardedMapInstance()
+
+	key := "multi_level_test"
+
+	if err := client.SetMultiLevel(key, key, []byte(baseValue), http.Header{}, "", time.Minute, key); err != nil {
+		t.Fatalf("Failed to set the value: %v", err)
+	}
+
+	decompressed := new(bytes.Buffer)
+	if _, err := lz4.NewReader(bytes.NewBuffer(client.Get(key))).WriteTo(decompressed); err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+
+	if decompressed.String() != baseValue {
+		t.Errorf("%s not corresponding to %s", decompressed.String(), baseValue)
+	}
+
+	if keys := client.ListKeys(); !strings.Contains(strings.Join(keys, ","), key) {
+		t.Errorf("The key should be listed from its mapping, %v given", keys)
+	}
+}
+
+func TestShardedMap_NewStorer(t *testing.T) {
+	instance, err := core.NewStorer("shardedmap", core.CacheProvider{}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("The shardedmap storage should be registered: %v", err)
+	}
+
+	if instance.Name() != "SHARDEDMAP" {
+		t.Errorf("The shardedmap storage should be returned, %s given", instance.Name())
+	}
+}
+
+func TestShardedMap_Validate(t *testing.T) {
+	if err := shardedmap.Validate(map[string]interface{}{"shards": "16", "cleanup_interval": "500ms"}); err != nil {
+		t.Errorf("The shards given as a string should be valid, %v given", err)
+	}
+
+	if err := shardedmap.Validate(map[string]interface{}{"shards": 0}); err == nil {
+		t.Error("A zero shards should be invalid")
+	}
+
+	if err := shardedmap.Validate(map[string]interface{}{"cleanup_interval": "-1s"}); err == nil {
+		t.Error("A negative cleanup_interval should be invalid")
+	}
+}
+
+func TestShardedMap_Lookup(t *testing.T) {
+	client, _ := getShardedMapInstance()
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestShardedMap_Increment(t *testing.T) {
+	client, _ := getShardedMapInstance()
+	counter, ok := client.(core.Counter)
+	if !ok {
+		t.Fatal("ShardedMap should implement core.Counter")
+	}
+
+	var wg sync.WaitGroup
+
+	for range 50 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = counter.Increment("hits", 2, time.Minute)
+		}()
+	}
+
+	wg.Wait()
+
+	if value, err := counter.Increment("hits", -1, 0); err != nil || value != 99 {
+		t.Errorf("The concurrent increments should all be counted, %d given, %v", value, err)
+	}
+
+	if value := client.Get("hits"); string(value) != "99" {
+		t.Errorf("The counter should be stored as a decimal string, %s given", value)
+	}
+
+	client.Delete("hits")
+}
+
+func TestShardedMap_OnEvicted(t *testing.T) {
+	client, err := shardedmap.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"shards":           4,
+		"cleanup_interval": "50ms",
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create the shardedmap instance: %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	evicted := make(chan string, 16)
+
+	client.(core.EvictionNotifier).OnEvicted(func(key string, value []byte) {
+		if string(value) == baseValue {
+			evicted <- key
+		}
+	})
+
+	_ = client.Set("EXPIRED_"+byteKey, []byte(baseValue), 100*time.Millisecond)
+	_ = client.Set("REWRITTEN_"+byteKey, []byte(baseValue), 100*time.Millisecond)
+	_ = client.Set("REWRITTEN_"+byteKey, []byte(baseValue), time.Minute)
+
+	select {
+	case key := <-evicted:
+		if key != "EXPIRED_"+byteKey {
+			t.Errorf("Only the expired key should be notified, %s given", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The expired key should be notified")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if len(evicted) != 0 || string(client.Get("REWRITTEN_"+byteKey)) != baseValue {
+		t.Error("The rewritten key should be kept")
+	}
+}
+
+func TestShardedMap_ConcurrentWrites(t *testing.T) {
+	client, err := shardedmap.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"shards":           8,
+		"cleanup_interval": "10ms",
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create the shardedmap instance: %v", err)
+	}
+
+	defer func() { _ = client.Reset() }()
+
+	var wg sync.WaitGroup
+
+	for worker := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				key := fmt.Sprintf("key-%d", i%100)
+				_ = client.Set(key, []byte(fmt.Sprint(worker)), time.Duration(1+i%3)*time.Millisecond)
+				_ = client.Get(key)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	stats, found := core.Stats(client)
+	if !found {
+		t.Fatal("The shardedmap storer should report its stats")
+	}
+
+	if stats.Entries == 0 || stats.Hits+stats.Misses != 8000 {
+		t.Errorf("Unexpected stats, %+v given", stats)
+	}
+}
+
+func BenchmarkShardedMap_SetParallel(b *testing.B) {
+	client, _ := getShardedMapInstance()
+	value := []byte(baseValue)
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_ = client.Set(fmt.Sprintf("key-%d", i%10_000), value, time.Minute)
+			i++
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := shardedmap.Factory(core.CacheProvider{}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
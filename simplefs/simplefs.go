@@ -1,7 +1,6 @@
 package simplefs
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -10,7 +9,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,16 +16,17 @@ import (
 	"github.com/darkweak/storages/core"
 	"github.com/dustin/go-humanize"
 	"github.com/jellydator/ttlcache/v3"
-	"github.com/pierrec/lz4/v4"
 )
 
 // Simplefs provider type.
 type Simplefs struct {
 	cache         *ttlcache.Cache[string, []byte]
 	stale         time.Duration
+	uuid          string
 	size          int
 	path          string
 	logger        core.Logger
+	compressor    core.Compressor
 	actualSize    int64
 	directorySize int64
 	mu            sync.Mutex
@@ -37,48 +36,74 @@ func onEvict(path string) error {
 	return os.Remove(path)
 }
 
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("simplefs", Factory)
+}
+
+// configuration is the typed Simplefs provider configuration.
+type configuration struct {
+	// Size is the maximum number of entries, zero means unlimited.
+	Size int    `json:"size"`
+	Path string `json:"path"`
+	// DirectorySize is the maximum size of the directory ("100MB"), the
+	// oldest entries are evicted above.
+	DirectorySize string `json:"directory_size"`
+}
+
+func parseConfiguration(simplefsConfiguration any) (configuration, int64, error) {
+	var cfg configuration
+
+	directorySize := int64(-1)
+
+	if err := core.DecodeConfiguration(simplefsConfiguration, &cfg); err != nil {
+		return cfg, directorySize, fmt.Errorf("invalid simplefs configuration: %w", err)
+	}
+
+	if cfg.Size < 0 {
+		return cfg, directorySize, fmt.Errorf("invalid simplefs configuration: the size must be positive, %d given", cfg.Size)
+	}
+
+	if cfg.DirectorySize != "" {
+		size, err := humanize.ParseBytes(cfg.DirectorySize)
+		if err != nil {
+			return cfg, directorySize, fmt.Errorf("invalid simplefs configuration: directory_size: %w", err)
+		}
+
+		//nolint:gosec
+		directorySize = int64(size)
+	}
+
+	return cfg, directorySize, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(simplefsConfiguration any) error {
+	_, _, err := parseConfiguration(simplefsConfiguration)
+
+	return err
+}
+
 // Factory function create new Simplefs instance.
 func Factory(simplefsCfg core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
-	var directorySize int64
+	simplefsConfiguration := simplefsCfg.Configuration
 
-	storagePath := simplefsCfg.Path
-	size := 0
-	directorySize = -1
+	cfg, directorySize, err := parseConfiguration(simplefsConfiguration)
+	if err != nil {
+		return nil, err
+	}
 
-	simplefsConfiguration := simplefsCfg.Configuration
-	if simplefsConfiguration != nil {
-		if sfsconfig, ok := simplefsConfiguration.(map[string]interface{}); ok {
-			if v, found := sfsconfig["size"]; found && v != nil {
-				if val, ok := v.(int); ok && val > 0 {
-					size = val
-				} else if val, ok := v.(float64); ok && val > 0 {
-					size = int(val)
-				} else if val, ok := v.(string); ok {
-					size, _ = strconv.Atoi(val)
-				}
-			}
-
-			if v, found := sfsconfig["path"]; found && v != nil {
-				if val, ok := v.(string); ok {
-					storagePath = val
-				}
-			}
-
-			if v, found := sfsconfig["directory_size"]; found && v != nil {
-				if val, ok := v.(int64); ok && val > 0 {
-					directorySize = val
-				} else if val, ok := v.(float64); ok && val > 0 {
-					directorySize = int64(val)
-				} else if val, ok := v.(string); ok && val != "" {
-					s, _ := humanize.ParseBytes(val)
-					//nolint:gosec
-					directorySize = int64(s)
-				}
-			}
-		}
+	storagePath := simplefsCfg.Path
+	if cfg.Path != "" {
+		storagePath = cfg.Path
 	}
 
-	var err error
+	size := cfg.Size
+
+	compressor, err := core.CompressorFromConfiguration(simplefsConfiguration)
+	if err != nil {
+		return nil, err
+	}
 
 	if storagePath == "" {
 		logger.Info("No configuration path given, fallback to the current working directory.")
@@ -110,7 +135,7 @@ func Factory(simplefsCfg core.CacheProvider, logger core.Logger, stale time.Dura
 
 	logger.Infof("Created the storage directory %s if needed", storagePath)
 
-	store := Simplefs{cache: cache, directorySize: directorySize, logger: logger, mu: sync.Mutex{}, path: storagePath, size: size, stale: stale}
+	store := Simplefs{cache: cache, compressor: compressor, directorySize: directorySize, logger: logger, mu: sync.Mutex{}, path: storagePath, size: size, stale: stale, uuid: core.InstanceID("simplefs", simplefsCfg, stale)}
 
 	defer func() {
 		go store.cache.Start()
@@ -126,7 +151,7 @@ func (provider *Simplefs) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Simplefs) Uuid() string {
-	return fmt.Sprintf("%s-%d", provider.path, provider.size)
+	return provider.uuid
 }
 
 // MapKeys method returns a map with the key and value.
@@ -158,28 +183,36 @@ func (provider *Simplefs) ListKeys() []string {
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Simplefs) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *Simplefs) Lookup(key string) ([]byte, error) {
 	provider.mu.Lock()
 	defer provider.mu.Unlock()
 
 	result := provider.cache.Get(key)
 	if result == nil {
-		provider.logger.Warnf("Impossible to get the key %s in Simplefs", key)
+		provider.logger.Debugf("Impossible to get the key %s in Simplefs", key)
 
-		return nil
+		return nil, core.ErrKeyNotFound
 	}
 
 	if strings.HasPrefix(key, core.SurrogateKeyPrefix) {
-		return result.Value()
+		return result.Value(), nil
 	}
 
 	byteValue, err := os.ReadFile(strings.Trim(string(result.Value()), ","))
 	if err != nil {
 		provider.logger.Errorf("Impossible to read the file %s from Simplefs: %#v", result.Value(), err)
 
-		return result.Value()
+		return result.Value(), nil
 	}
 
-	return byteValue
+	return byteValue, nil
 }
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
@@ -196,7 +229,7 @@ func (provider *Simplefs) GetMultiLevel(key string, req *http.Request, validator
 		return fresh, stale
 	}
 
-	fresh, stale, _ = core.MappingElection(provider, val.Value(), req, validator, provider.logger)
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, val.Value(), req, validator, provider.logger)
 
 	return fresh, stale
 }
@@ -205,28 +238,18 @@ func (provider *Simplefs) GetMultiLevel(key string, req *http.Request, validator
 func (provider *Simplefs) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
 
-	compressed := new(bytes.Buffer)
-	writer := lz4.NewWriter(compressed)
-
-	if _, err := writer.Write(value); err != nil {
-		_ = writer.Close()
-
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
 		provider.logger.Errorf("Impossible to compress the key %s into Simplefs, %v", variedKey, err)
 
 		return err
 	}
 
-	if err := writer.Close(); err != nil {
-		provider.logger.Errorf("Impossible to close the compressor for key %s into Simplefs, %v", variedKey, err)
-
-		return err
-	}
-
-	provider.recoverEnoughSpaceIfNeeded(int64(compressed.Len()))
+	provider.recoverEnoughSpaceIfNeeded(int64(len(compressed)))
 
 	joinedFP := filepath.Join(provider.path, url.PathEscape(variedKey))
 	//nolint:gosec
-	if err := os.WriteFile(joinedFP, compressed.Bytes(), 0o644); err != nil {
+	if err := os.WriteFile(joinedFP, compressed, 0o644); err != nil {
 		provider.logger.Errorf("Impossible to write the file %s from Simplefs: %#v", variedKey, err)
 
 		return nil
@@ -235,7 +258,7 @@ func (provider *Simplefs) SetMultiLevel(baseKey, variedKey string, value []byte,
 	provider.mu.Lock()
 	defer provider.mu.Unlock()
 
-	_ = provider.cache.Set(variedKey, []byte(joinedFP), duration)
+	_ = provider.cache.Set(variedKey, []byte(joinedFP), duration+provider.stale)
 
 	mappingKey := core.MappingKeyPrefix + baseKey
 	item := provider.cache.Get(mappingKey)
@@ -246,7 +269,7 @@ func (provider *Simplefs) SetMultiLevel(baseKey, variedKey string, value []byte,
 		item = &ttlcache.Item[string, []byte]{}
 	}
 
-	val, e := core.MappingUpdater(variedKey, item.Value(), provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	val, evicted, e := core.MappingUpdaterWithEvictions(variedKey, item.Value(), provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
 	if e != nil {
 		return e
 	}
@@ -259,6 +282,7 @@ func (provider *Simplefs) SetMultiLevel(baseKey, variedKey string, value []byte,
 	}
 
 	_ = provider.cache.Set(mappingKey, val, negativeNow)
+	core.DeleteEvictedVariants(provider, evicted)
 
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
 	"github.com/darkweak/storages/simplefs"
 	"github.com/pierrec/lz4/v4"
 	"go.uber.org/zap"
@@ -167,7 +168,7 @@ func TestSimplefs_EvictAfterXSeconds(t *testing.T) {
 // when stored and retrieved via SetMultiLevel. This reproduces issue #41.
 // See: https://github.com/darkweak/storages/issues/41
 func TestSimplefs_SetMultiLevel_LargeValue(t *testing.T) {
-	client, err := getSimplefsInstance()
+	client, err := simplefs.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), 0)
 	if err != nil {
 		t.Fatalf("Failed to create simplefs instance: %v", err)
 	}
@@ -209,3 +210,14 @@ func TestSimplefs_SetMultiLevel_LargeValue(t *testing.T) {
 			len(largeValue), len(retrieved), float64(len(retrieved))/float64(len(largeValue))*100)
 	}
 }
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := simplefs.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}
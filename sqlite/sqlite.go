@@ -0,0 +1,662 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	defaultPath          = "souin.db"
+	defaultTable         = "cache_entries"
+	defaultPurgeInterval = time.Minute
+	defaultBusyTimeout   = 5 * time.Second
+
+	// purgeBatchSize bounds the rows deleted by a single purge statement so
+	// the writers are never blocked for long.
+	purgeBatchSize = 1000
+)
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// statements are prepared once the table exists.
+type statements struct {
+	get     *sql.Stmt
+	getAll  *sql.Stmt
+	entries *sql.Stmt
+	set     *sql.Stmt
+	mapping *sql.Stmt
+	touch   *sql.Stmt
+	delete  *sql.Stmt
+	keys    *sql.Stmt
+	page    *sql.Stmt
+	purge   *sql.Stmt
+}
+
+func (s *statements) close() {
+	for _, stmt := range []*sql.Stmt{s.get, s.getAll, s.entries, s.set, s.mapping, s.touch, s.delete, s.keys, s.page, s.purge} {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}
+}
+
+// SQLite provider type.
+type SQLite struct {
+	db            *sql.DB
+	stmts         *statements
+	path          string
+	table         string
+	purgeInterval time.Duration
+	limiter       *core.BackgroundLimiter
+	stop          chan struct{}
+	stale         time.Duration
+	uuid          string
+	logger        core.Logger
+	compressor    core.Compressor
+}
+
+// configuration is the typed SQLite provider configuration.
+type configuration struct {
+	// Path is the database file, the provider path is used when empty.
+	Path  string `json:"path"`
+	Table string `json:"table"`
+	// PurgeInterval is the delay between two removals of the expired rows.
+	PurgeInterval time.Duration `json:"purge_interval"`
+	// BusyTimeout is the delay a write waits for the database lock.
+	BusyTimeout time.Duration `json:"busy_timeout"`
+}
+
+func parseConfiguration(sqliteConfiguration core.CacheProvider) (configuration, error) {
+	cfg := configuration{
+		Path:          sqliteConfiguration.Path,
+		Table:         defaultTable,
+		PurgeInterval: defaultPurgeInterval,
+		BusyTimeout:   defaultBusyTimeout,
+	}
+
+	if err := core.DecodeConfiguration(sqliteConfiguration.Configuration, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid sqlite configuration: %w", err)
+	}
+
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+
+	if !identifierRegexp.MatchString(cfg.Table) {
+		return cfg, fmt.Errorf("invalid sqlite configuration: the table %q is not a valid identifier", cfg.Table)
+	}
+
+	if cfg.PurgeInterval <= 0 {
+		return cfg, fmt.Errorf("invalid sqlite configuration: the purge_interval must be positive, %s given", cfg.PurgeInterval)
+	}
+
+	if cfg.BusyTimeout < 0 {
+		return cfg, fmt.Errorf("invalid sqlite configuration: the busy_timeout can't be negative, %s given", cfg.BusyTimeout)
+	}
+
+	return cfg, nil
+}
+
+// Validate returns an error describing the malformed configuration keys.
+func Validate(sqliteConfiguration any) error {
+	_, err := parseConfiguration(core.CacheProvider{Configuration: sqliteConfiguration})
+
+	return err
+}
+
+//nolint:gochecknoinits
+func init() {
+	core.RegisterFactory("sqlite", Factory)
+}
+
+// dsn enables the WAL journal so the readers never block the writer.
+func dsn(path string, busyTimeout time.Duration) string {
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+
+	return "file:" + path + "?" + params.Encode()
+}
+
+// Factory function create new SQLite instance.
+func Factory(sqliteConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	cfg, err := parseConfiguration(sqliteConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	compressor, err := core.CompressorFromConfiguration(sqliteConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := core.BackgroundLimiterFromConfiguration(sqliteConfiguration.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir := filepath.Dir(cfg.Path); dir != "" {
+		if err = os.MkdirAll(dir, 0o750); err != nil {
+			logger.Errorf("Impossible to create the SQLite directory %s, %v", dir, err)
+
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite", dsn(cfg.Path, cfg.BusyTimeout))
+	if err != nil {
+		logger.Error("Impossible to open the SQLite DB.", err)
+
+		return nil, err
+	}
+
+	provider := &SQLite{
+		db:            db,
+		path:          cfg.Path,
+		table:         `"` + cfg.Table + `"`,
+		purgeInterval: cfg.PurgeInterval,
+		stale:         stale,
+		uuid:          core.InstanceID("sqlite", sqliteConfiguration, stale),
+		logger:        logger,
+		compressor:    compressor,
+		limiter:       limiter,
+	}
+
+	if err = provider.prepare(cfg.Table); err != nil {
+		logger.Errorf("Impossible to prepare the SQLite table %s, %v", cfg.Table, err)
+
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// prepare creates the table and its expiration index if needed, then
+// prepares the statements.
+func (provider *SQLite) prepare(table string) error {
+	if _, err := provider.db.Exec(`CREATE TABLE IF NOT EXISTS ` + provider.table + ` (
+	key TEXT PRIMARY KEY,
+	value BLOB NOT NULL,
+	expires_at INTEGER
+)`); err != nil {
+		return err
+	}
+
+	if _, err := provider.db.Exec(
+		`CREATE INDEX IF NOT EXISTS "` + table + `_expires_at_idx" ON ` + provider.table + ` (expires_at) WHERE expires_at IS NOT NULL`,
+	); err != nil {
+		return err
+	}
+
+	notExpired := `(expires_at IS NULL OR expires_at > ?)`
+	upsert := `INSERT INTO ` + provider.table + ` (key, value, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (key) DO UPDATE SET value = excluded.value, `
+
+	provider.stmts = &statements{}
+
+	for target, query := range map[**sql.Stmt]string{
+		&provider.stmts.get:     `SELECT value FROM ` + provider.table + ` WHERE key = ? AND ` + notExpired,
+		&provider.stmts.getAll:  `SELECT key, value FROM ` + provider.table + ` WHERE key >= ? AND key < ? AND ` + notExpired,
+		&provider.stmts.entries: `SELECT key, value, expires_at FROM ` + provider.table + ` WHERE ` + notExpired,
+		&provider.stmts.set:     upsert + `expires_at = excluded.expires_at`,
+		// The mapping lives as long as its longest variant.
+		&provider.stmts.mapping: upsert + `expires_at = max(coalesce(expires_at, excluded.expires_at), coalesce(excluded.expires_at, expires_at))`,
+		&provider.stmts.touch:   `UPDATE ` + provider.table + ` SET expires_at = ? WHERE key = ? AND ` + notExpired,
+		&provider.stmts.delete:  `DELETE FROM ` + provider.table + ` WHERE key = ?`,
+		&provider.stmts.keys:    `SELECT key FROM ` + provider.table,
+		&provider.stmts.page:    `SELECT key, value FROM ` + provider.table + ` WHERE key > ? AND key < ? AND ` + notExpired + ` ORDER BY key LIMIT ?`,
+		&provider.stmts.purge:   `DELETE FROM ` + provider.table + ` WHERE key IN (SELECT key FROM ` + provider.table + ` WHERE expires_at <= ? LIMIT ?)`,
+	} {
+		stmt, err := provider.db.Prepare(query)
+		if err != nil {
+			provider.stmts.close()
+
+			return err
+		}
+
+		*target = stmt
+	}
+
+	return nil
+}
+
+// expiresAt returns the expiration of a row stored for the given duration as
+// an unix timestamp in nanoseconds, nil means it never expires.
+func expiresAt(duration time.Duration) any {
+	if duration <= 0 {
+		return nil
+	}
+
+	return time.Now().Add(duration).UnixNano()
+}
+
+// prefixUpperBound returns the smallest string greater than every string
+// starting with the prefix, so a prefix lookup uses the primary key index.
+func prefixUpperBound(prefix string) string {
+	bound := []byte(prefix)
+
+	for i := len(bound) - 1; i >= 0; i-- {
+		if bound[i] < 0xff {
+			bound[i]++
+
+			return string(bound[:i+1])
+		}
+	}
+
+	return string([]byte{0xff})
+}
+
+// Name returns the storer name.
+func (provider *SQLite) Name() string {
+	return "SQLITE"
+}
+
+// Uuid returns an unique identifier.
+func (provider *SQLite) Uuid() string {
+	return provider.uuid
+}
+
+// MapKeys method returns a map with the key and value.
+func (provider *SQLite) MapKeys(prefix string) map[string]string {
+	keys := map[string]string{}
+
+	rows, err := provider.stmts.getAll.Query(prefix, prefixUpperBound(prefix), time.Now().UnixNano())
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in SQLite, %v", err)
+
+		return keys
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key   string
+			value []byte
+		)
+
+		if err = rows.Scan(&key, &value); err != nil {
+			provider.logger.Errorf("Impossible to read the SQLite row, %v", err)
+
+			return keys
+		}
+
+		k, _ := strings.CutPrefix(key, prefix)
+		keys[k] = string(value)
+	}
+
+	return keys
+}
+
+// ListKeys method returns the list of existing keys.
+func (provider *SQLite) ListKeys() []string {
+	keys := []string{}
+
+	_ = provider.WalkKeys(func(key string) bool {
+		keys = append(keys, key)
+
+		return true
+	})
+
+	return keys
+}
+
+// WalkKeys streams the keys returned by ListKeys.
+func (provider *SQLite) WalkKeys(walkFn func(key string) bool) error {
+	now := time.Now()
+
+	rows, err := provider.stmts.getAll.Query(core.MappingKeyPrefix, prefixUpperBound(core.MappingKeyPrefix), now.UnixNano())
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in SQLite, %v", err)
+
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key   string
+			value []byte
+		)
+
+		if err = rows.Scan(&key, &value); err != nil {
+			return err
+		}
+
+		for _, realKey := range core.MappingRealKeys(value, now) {
+			if !walkFn(realKey) {
+				return nil
+			}
+		}
+	}
+
+	return rows.Err()
+}
+
+// WalkEntries streams every row with its remaining TTL, zero when the row
+// never expires.
+func (provider *SQLite) WalkEntries(walkFn func(key string, value []byte, ttl time.Duration) bool) error {
+	rows, err := provider.stmts.entries.Query(time.Now().UnixNano())
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the entries in SQLite, %v", err)
+
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key        string
+			value      []byte
+			expiration sql.NullInt64
+		)
+
+		if err = rows.Scan(&key, &value, &expiration); err != nil {
+			return err
+		}
+
+		var ttl time.Duration
+		if expiration.Valid {
+			if ttl = time.Until(time.Unix(0, expiration.Int64)); ttl <= 0 {
+				continue
+			}
+		}
+
+		if !walkFn(key, value, ttl) {
+			return nil
+		}
+	}
+
+	return rows.Err()
+}
+
+// ListKeysPaginated returns a page of the keys returned by ListKeys, the
+// cursor is the last mapping key of the previous page.
+func (provider *SQLite) ListKeysPaginated(cursor string, limit int) ([]string, string) {
+	keys := []string{}
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	if cursor == "" {
+		cursor = core.MappingKeyPrefix
+	}
+
+	now := time.Now()
+
+	rows, err := provider.stmts.page.Query(cursor, prefixUpperBound(core.MappingKeyPrefix), now.UnixNano(), limit)
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in SQLite, %v", err)
+
+		return keys, ""
+	}
+
+	defer rows.Close()
+
+	count := 0
+	last := ""
+
+	for rows.Next() {
+		var value []byte
+		if err = rows.Scan(&last, &value); err != nil {
+			provider.logger.Errorf("Impossible to read the SQLite row, %v", err)
+
+			return keys, ""
+		}
+
+		count++
+
+		keys = append(keys, core.MappingRealKeys(value, now)...)
+	}
+
+	if count < limit {
+		return keys, ""
+	}
+
+	return keys, last
+}
+
+// Get method returns the populated response if exists, empty response then.
+func (provider *SQLite) Get(key string) []byte {
+	value, _ := provider.Lookup(key)
+
+	return value
+}
+
+// Lookup method returns the stored value, core.ErrKeyNotFound when the key
+// doesn't exist or is expired.
+func (provider *SQLite) Lookup(key string) ([]byte, error) {
+	var value []byte
+
+	err := provider.stmts.get.QueryRow(key, time.Now().UnixNano()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrKeyNotFound
+	}
+
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the key %s in SQLite, %v", key, err)
+
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *SQLite) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	result := provider.Get(core.MappingKeyPrefix + key)
+	if len(result) == 0 {
+		return fresh, stale
+	}
+
+	fresh, stale, _ = core.MappingElectionFor(provider, core.MappingKeyPrefix+key, result, req, validator, provider.logger)
+
+	return fresh, stale
+}
+
+// SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
+// Both rows are written in the same transaction.
+func (provider *SQLite) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	now := time.Now()
+
+	compressed, err := provider.compressor.Compress(value)
+	if err != nil {
+		provider.logger.Errorf("Impossible to compress the key %s into SQLite, %v", variedKey, err)
+
+		return err
+	}
+
+	ctx := context.Background()
+
+	tx, err := provider.db.BeginTx(ctx, nil)
+	if err != nil {
+		provider.logger.Errorf("Impossible to begin the SQLite transaction, %v", err)
+
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	var current []byte
+
+	err = tx.StmtContext(ctx, provider.stmts.get).QueryRowContext(ctx, mappingKey, now.UnixNano()).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		provider.logger.Errorf("Impossible to get the mapping key %s in SQLite, %v", mappingKey, err)
+
+		return err
+	}
+
+	val, evicted, err := core.MappingUpdaterWithEvictions(variedKey, current, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.StmtContext(ctx, provider.stmts.set).ExecContext(ctx, variedKey, compressed, expiresAt(duration+provider.stale)); err != nil {
+		provider.logger.Errorf("Impossible to set value into SQLite, %v", err)
+
+		return err
+	}
+
+	if _, err = tx.StmtContext(ctx, provider.stmts.mapping).ExecContext(ctx, mappingKey, val, expiresAt(duration+provider.stale)); err != nil {
+		provider.logger.Errorf("Impossible to set value into SQLite, %v", err)
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	core.DeleteEvictedVariants(provider, evicted)
+
+	return nil
+}
+
+// Set method will store the response in SQLite provider.
+func (provider *SQLite) Set(key string, value []byte, duration time.Duration) error {
+	if _, err := provider.stmts.set.Exec(key, value, expiresAt(duration)); err != nil {
+		provider.logger.Errorf("Impossible to set value into SQLite, %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Touch method makes the key expire after ttl, updating its expiration in
+// place.
+func (provider *SQLite) Touch(key string, ttl time.Duration) error {
+	result, err := provider.stmts.touch.Exec(expiresAt(ttl), key, time.Now().UnixNano())
+	if err != nil {
+		provider.logger.Errorf("Impossible to touch the key %s in SQLite, %v", key, err)
+
+		return err
+	}
+
+	if touched, err := result.RowsAffected(); err == nil && touched == 0 {
+		return core.ErrKeyNotFound
+	}
+
+	return nil
+}
+
+// Delete method will delete the response in SQLite provider if exists corresponding to key param.
+func (provider *SQLite) Delete(key string) {
+	if _, err := provider.stmts.delete.Exec(key); err != nil {
+		provider.logger.Errorf("Impossible to delete the key %s in SQLite, %v", key, err)
+	}
+}
+
+// DeleteMany method will delete the responses in SQLite provider if exists corresponding to the regex key param.
+func (provider *SQLite) DeleteMany(key string) {
+	rgKey, err := regexp.Compile(key)
+	if err != nil {
+		return
+	}
+
+	rows, err := provider.stmts.keys.Query()
+	if err != nil {
+		provider.logger.Errorf("Impossible to list the keys in SQLite, %v", err)
+
+		return
+	}
+
+	matched := []string{}
+
+	for rows.Next() {
+		var k string
+		if err = rows.Scan(&k); err == nil && rgKey.MatchString(k) {
+			matched = append(matched, k)
+		}
+	}
+
+	_ = rows.Close()
+
+	for _, k := range matched {
+		provider.Delete(k)
+	}
+}
+
+// purge removes the expired rows by batches.
+func (provider *SQLite) purge() error {
+	for {
+		result, err := provider.stmts.purge.Exec(time.Now().UnixNano(), purgeBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if deleted, _ := result.RowsAffected(); deleted < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// Healthy checks the database file is still reachable.
+func (provider *SQLite) Healthy(ctx context.Context) error {
+	return provider.db.PingContext(ctx)
+}
+
+// Init method will start the background purge of the expired rows.
+func (provider *SQLite) Init() error {
+	if provider.stop != nil {
+		return nil
+	}
+
+	provider.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(provider.purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !provider.limiter.Wait(stop, "purge") {
+					return
+				}
+
+				if err := provider.purge(); err != nil {
+					provider.logger.Errorf("Impossible to purge the expired keys in SQLite, %v", err)
+				}
+			}
+		}
+	}(provider.stop)
+
+	return nil
+}
+
+// Reset method will reset or close provider.
+func (provider *SQLite) Reset() error {
+	if provider.stop != nil {
+		close(provider.stop)
+		provider.stop = nil
+	}
+
+	provider.stmts.close()
+
+	return provider.db.Close()
+}
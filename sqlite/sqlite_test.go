@@ -0,0 +1,244 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	storertestsuite "github.com/darkweak/storages/core/storer_test_suite"
+	"github.com/darkweak/storages/sqlite"
+	"go.uber.org/zap"
+)
+
+const (
+	byteKey        = "MyByteKey"
+	nonExistentKey = "NonExistentKey"
+	baseValue      = "My first data"
+)
+
+func getSQLiteInstance(t *testing.T) (core.Storer, error) {
+	t.Helper()
+
+	client, err := sqlite.Factory(core.CacheProvider{Path: filepath.Join(t.TempDir(), "souin.db")}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Cleanup(func() {
+		_ = client.Reset()
+	})
+
+	return client, client.Init()
+}
+
+func TestSQLiteConnectionFactory(t *testing.T) {
+	instance, err := getSQLiteInstance(t)
+
+	if nil != err {
+		t.Error("Shouldn't have panic", err)
+	}
+
+	if nil == instance {
+		t.Error("SQLite should be instanciated")
+	}
+}
+
+func TestIShouldBeAbleToReadAndWriteDataInSQLite(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+
+	_ = client.Set("Test", []byte(baseValue), time.Duration(20)*time.Second)
+
+	res := client.Get("Test")
+	if len(res) == 0 {
+		t.Errorf("Key %s should exist", baseValue)
+	}
+
+	if baseValue != string(res) {
+		t.Errorf("%s not corresponding to %s", string(res), baseValue)
+	}
+}
+
+func TestSQLite_GetRequestInCache(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+	res := client.Get(nonExistentKey)
+
+	if 0 < len(res) {
+		t.Errorf("Key %s should not exist", nonExistentKey)
+	}
+}
+
+func TestSQLite_SetRequestInCache_TTL(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+	_ = client.Set(byteKey, []byte(baseValue), 100*time.Millisecond)
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should exist", byteKey)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if client.Get(byteKey) != nil {
+		t.Errorf("Key %s should be expired", byteKey)
+	}
+}
+
+func TestSQLite_DeleteMany(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+
+	_ = client.Set("DELETE_MANY_1", []byte(baseValue), time.Minute)
+	_ = client.Set("DELETE_MANY_2", []byte(baseValue), time.Minute)
+	_ = client.Set("KEEP_MANY_3", []byte(baseValue), time.Minute)
+
+	client.DeleteMany("^DELETE_MANY_.+")
+
+	if client.Get("DELETE_MANY_1") != nil || client.Get("DELETE_MANY_2") != nil {
+		t.Error("The matching keys should be deleted")
+	}
+
+	if client.Get("KEEP_MANY_3") == nil {
+		t.Error("The other keys should be kept")
+	}
+}
+
+func TestSQLite_SetMultiLevel(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+
+	for _, variant := range []string{"gzip", "br"} {
+		variedKey := "multi-level-" + variant
+
+		err := client.SetMultiLevel("multi-level", variedKey, []byte(baseValue), http.Header{"Accept-Encoding": []string{variant}}, "", time.Minute, variedKey)
+		if err != nil {
+			t.Fatalf("Failed to set the value: %v", err)
+		}
+	}
+
+	mapping, err := core.DecodeMapping(client.Get(core.MappingKeyPrefix + "multi-level"))
+	if err != nil {
+		t.Fatalf("Impossible to decode the mapping, %v", err)
+	}
+
+	if len(mapping.GetMapping()) != 2 {
+		t.Errorf("The mapping should hold both variants, %d given", len(mapping.GetMapping()))
+	}
+
+	keys, _ := core.ListKeysPaginated(client, "", 1000)
+	if len(keys) < 2 {
+		t.Errorf("The variants should be listed, %v given", keys)
+	}
+}
+
+func TestSQLite_Validate(t *testing.T) {
+	if err := sqlite.Validate(map[string]interface{}{"table": "cache_entries", "purge_interval": "5m", "busy_timeout": "1s"}); err != nil {
+		t.Errorf("The configuration should be valid, %v given", err)
+	}
+
+	if err := sqlite.Validate(map[string]interface{}{"table": "entries; DROP TABLE users"}); err == nil {
+		t.Error("A malformed table name should be invalid")
+	}
+}
+
+func TestSQLite_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "souin.db")
+
+	client, _ := sqlite.Factory(core.CacheProvider{Path: path}, zap.NewNop().Sugar(), 0)
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Reset()
+
+	client, err := sqlite.Factory(core.CacheProvider{Path: path}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to reopen the database, %v", err)
+	}
+
+	defer func() {
+		_ = client.Reset()
+	}()
+
+	if string(client.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should be persisted", byteKey)
+	}
+}
+
+func TestSQLite_Healthy(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+
+	if err := core.Healthy(context.Background(), client); err != nil {
+		t.Errorf("The opened database should be healthy, %v given", err)
+	}
+
+	_ = client.Reset()
+
+	if err := core.Healthy(context.Background(), client); err == nil {
+		t.Error("The closed database shouldn't be healthy")
+	}
+}
+
+func TestSQLite_Lookup(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+
+	if _, err := core.Lookup(client, nonExistentKey); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+
+	if value, err := core.Lookup(client, byteKey); err != nil || string(value) != baseValue {
+		t.Errorf("The stored value should be returned, %s and %v given", value, err)
+	}
+}
+
+func TestSQLite_Touch(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+	_ = client.Set(byteKey, []byte(baseValue), 100*time.Millisecond)
+
+	if err := core.Touch(client, byteKey, time.Minute); err != nil {
+		t.Fatalf("The key should be touched, %v given", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if value := client.Get(byteKey); string(value) != baseValue {
+		t.Errorf("The touched key should outlive its former TTL, %s given", value)
+	}
+
+	if err := core.Touch(client, nonExistentKey, time.Minute); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Errorf("A missing key should return core.ErrKeyNotFound, %v given", err)
+	}
+}
+
+func TestSQLite_DumpWarmup(t *testing.T) {
+	client, _ := getSQLiteInstance(t)
+	_ = client.Set(byteKey, []byte(baseValue), time.Minute)
+	_ = client.Set("persistent", []byte(baseValue), 0)
+
+	var dump bytes.Buffer
+
+	if count, err := core.Dump(client, &dump); err != nil || count != 2 {
+		t.Fatalf("Every row should be dumped, %d entries and %v given", count, err)
+	}
+
+	target, _ := getSQLiteInstance(t)
+
+	if _, err := core.Warmup(target, &dump); err != nil {
+		t.Fatalf("Impossible to warm up the entries, %v", err)
+	}
+
+	if string(target.Get(byteKey)) != baseValue || string(target.Get("persistent")) != baseValue {
+		t.Error("The dumped rows should be restored")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storertestsuite.RunStorerConformanceTests(t, func(t *testing.T, stale time.Duration) core.Storer {
+		storer, err := sqlite.Factory(core.CacheProvider{Path: filepath.Join(t.TempDir(), "souin.db")}, zap.NewNop().Sugar(), stale)
+		if err != nil {
+			t.Fatalf("Impossible to create the storer, %v", err)
+		}
+
+		return storer
+	}, storertestsuite.Options{})
+}